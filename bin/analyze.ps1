@@ -7,7 +7,11 @@ param(
     [Parameter(Position = 0)]
     [string]$Path,
     
-    [switch]$Help
+    [switch]$Help,
+    
+    # Flags passed through to analyze.exe (e.g. --redact)
+    [Parameter(ValueFromRemainingArguments)]
+    [string[]]$ToolArgs
 )
 
 $ErrorActionPreference = "Stop"
@@ -20,6 +24,7 @@ $script:WINMOLE_CMD = Join-Path $script:WINMOLE_ROOT "cmd"
 
 # Import core
 . "$script:WINMOLE_LIB\core\common.ps1"
+Assert-CommandEnabled -Name "analyze"
 
 # ============================================================================
 # Help
@@ -38,11 +43,51 @@ function Show-AnalyzeHelp {
     Write-Host ""
     Write-Host "  ${green}USAGE:${nc}"
     Write-Host ""
-    Write-Host "    winmole analyze [path]"
+    Write-Host "    winmole analyze [--redact] [--profile <name>] [--export <file> [--depth <n>]] [path]"
+    Write-Host "    winmole analyze [--redact] [--profile <name>] <path> <path>..."
+    Write-Host "    winmole analyze --no-tui [--top <n>] [--depth <n>] [--format text|json|csv] [--timeout <duration>] [path]"
+    Write-Host "    winmole analyze --baseline <file> [--no-tui] [--depth <n>] [path]"
+    Write-Host "    winmole analyze --import <report> [--baseline <file>] [--no-tui]"
+    Write-Host "    winmole analyze --snapshot [--keep <n> | --prune] [--push] [--depth <n>] [path]"
+    Write-Host "    winmole analyze schedule [--at <HH:mm>] [--depth <n>] [--keep <n>] [--push] [path]..."
+    Write-Host "    winmole analyze schedule --status | --remove"
     Write-Host ""
     Write-Host "  ${green}ARGUMENTS:${nc}"
     Write-Host ""
-    Write-Host "    ${cyan}path${nc}    Directory to analyze (default: current directory)"
+    Write-Host "    ${cyan}path${nc}    Directory to analyze (default: pick a drive; the current directory with --no-tui/--export). Several are scanned at once and listed side by side"
+    Write-Host ""
+    Write-Host "  ${green}OPTIONS:${nc}"
+    Write-Host ""
+    Write-Host "    ${cyan}--redact${nc}  Mask computer name, user names and IP addresses (for screenshots)"
+    Write-Host "    ${cyan}--profile${nc} Use a named settings profile from config.json"
+    Write-Host "    ${cyan}--export${nc}  Scan without the TUI and write results to a .json or .csv file"
+    Write-Host "    ${cyan}--depth${nc}   Folder levels to include in --export and --no-tui (default: 1)"
+    Write-Host "    ${cyan}--no-tui${nc}  Print the largest files and folders instead of starting the TUI"
+    Write-Host "    ${cyan}--top${nc}     Entries to print with --no-tui (default: 20, 0 for all)"
+    Write-Host "    ${cyan}--format${nc}  --no-tui output: text, json or csv (default: text)"
+    Write-Host "    ${cyan}--baseline${nc} Show what is new or larger than in an export or report of a reference machine"
+    Write-Host "    ${cyan}--import${nc}  Open a WinMole export, Sysinternals du -c/-ct output or WinDirStat CSV (read-only)"
+    Write-Host "    ${cyan}--timeout${nc} Stop --no-tui/--export scans after this long (e.g. 5m), write partial results, exit 124"
+    Write-Host "    ${cyan}--user${nc}    Sign in to a \\server\share path as this user (the password is asked for)"
+    Write-Host "    ${cyan}--snapshot${nc} Scan without the TUI and save a snapshot for c and T to compare with"
+    Write-Host "    ${cyan}--keep${nc}    With --snapshot, delete all but this many newest snapshots of the folder"
+    Write-Host "    ${cyan}--prune${nc}   With --snapshot, thin out old snapshots by analyze.snapshots in config.json"
+    Write-Host "    ${cyan}--push${nc}    With --snapshot, send what changed since the last snapshot to the server in config.json"
+    Write-Host ""
+    Write-Host "  ${green}SCHEDULE:${nc}"
+    Write-Host ""
+    Write-Host "    ${gray}Registers a nightly Task Scheduler job that saves a snapshot of each path${nc}"
+    Write-Host "    ${gray}(default: the system drive) while you are signed in; runs missed while the${nc}"
+    Write-Host "    ${gray}PC was off happen at the next chance. T in the TUI charts them${nc}"
+    Write-Host ""
+    Write-Host "    ${cyan}--at${nc}      Time of day to scan (default: 02:00)"
+    Write-Host "    ${cyan}--depth${nc}   Folder levels to save (default: 3)"
+    Write-Host "    ${cyan}--keep${nc}    Keep just this many newest snapshots per path instead of thinning them out"
+    Write-Host "    ${gray}By default each night keeps the newest snapshot of the last 7 days, 4 weeks and${nc}"
+    Write-Host "    ${gray}12 months, within 2 GB for all of them; analyze.snapshots in config.json changes it${nc}"
+    Write-Host "    ${cyan}--push${nc}    Also send each night's changes to the server in config.json"
+    Write-Host "    ${cyan}--status${nc}  Show the job, its last and next run and the snapshots saved"
+    Write-Host "    ${cyan}--remove${nc}  Remove the job; the snapshots are kept"
     Write-Host ""
     Write-Host "  ${green}CONTROLS:${nc}"
     Write-Host ""
@@ -50,6 +95,38 @@ function Show-AnalyzeHelp {
     Write-Host "    ${cyan}Down/j${nc}  Move down"
     Write-Host "    ${cyan}Enter${nc}   Expand/collapse directory"
     Write-Host "    ${cyan}Backspace${nc} Go to parent directory"
+    Write-Host "    ${cyan}d${nc}       Move to Recycle Bin (asks first)"
+    Write-Host "    ${cyan}D${nc}       Delete permanently (type the name to confirm)"
+    Write-Host "    ${cyan}G${nc}       Relocate a folder to another drive, leaving a junction so programs still find it"
+    Write-Host "    ${cyan}e/E${nc}     Export scanned folders to JSON/CSV"
+    Write-Host "    ${cyan}S${nc}       Save a snapshot of the scan for comparing later"
+    Write-Host "    ${cyan}c/C${nc}     What grew or shrank since a snapshot / pick another snapshot"
+    Write-Host "    ${cyan}T${nc}       The folder's size in each snapshot, oldest first"
+    Write-Host "    ${cyan}t${nc}       Toggle treemap view (arrows move between blocks)"
+    Write-Host "    ${cyan}w${nc}       Watch the folder: sizes update live, changed rows light up"
+    Write-Host "    ${cyan}x${nc}       Totals by file extension for everything below the folder"
+    Write-Host "    ${cyan}U${nc}       Totals by owner for everything below the folder: who uses the space"
+    Write-Host "    ${cyan}Y${nc}       Toggle a column with the owner of each entry"
+    Write-Host "    ${cyan}f${nc}       Largest files anywhere below the folder (Enter opens its folder)"
+    Write-Host "    ${cyan}u${nc}       Duplicate files below the folder; recycle or hard-link the extra copies"
+    Write-Host "    ${cyan}g${nc}       Large files untouched for a year or more; +/- change the age"
+    Write-Host "    ${cyan}v${nc}       Photos and videos: cameras, bursts, repeated exports, video re-encode savings"
+    Write-Host "    ${cyan}Z${nc}       Estimate what compressing the folder would save, per extension, then compress (c: whole folder)"
+    Write-Host "    ${cyan}R${nc}       Suggestions: temp folders, browser caches, crash dumps; clean one at a time"
+    Write-Host "    ${cyan}B${nc}       Build output and dependency folders by kind; dry run, then delete in bulk"
+    Write-Host "    ${cyan}W${nc}       Component store (WinSxS): actual and reclaimable size from DISM; clean up"
+    Write-Host "    ${cyan}I${nc}       Windows Installer cache: packages no installed product or patch uses"
+    Write-Host "    ${cyan}V${nc}       Shadow copies per volume: restore points and previous versions; shrink or delete oldest"
+    Write-Host "    ${cyan}H${nc}       Paging, hibernation and swap files: what they take and what changing them frees"
+    Write-Host "    ${cyan}/${nc}       Filter by name or glob (*.iso); Esc clears"
+    Write-Host "    ${cyan}n/N${nc}     Next/previous match in all scanned folders"
+    Write-Host "    ${cyan}s${nc}       Sort by size, name, file count or last modified"
+    Write-Host "    ${cyan}F${nc}       Toggle counting NTFS alternate data streams in sizes (opens every file)"
+    Write-Host "    ${cyan}:${nc}       Alternate data streams of the selected file or folder"
+    Write-Host "    ${cyan}b${nc}       Toggle linear/log bar scale"
+    Write-Host "    ${cyan}z${nc}       Toggle coloring the bars by last-modified age"
+    Write-Host "    ${cyan}p${nc}       Toggle redaction"
+    Write-Host "    ${cyan}P${nc}       Switch settings profile"
     Write-Host "    ${cyan}r${nc}       Refresh"
     Write-Host "    ${cyan}q/Esc${nc}   Quit"
     Write-Host ""
@@ -58,6 +135,10 @@ function Show-AnalyzeHelp {
     Write-Host "    ${gray}winmole analyze${nc}              ${gray}# Analyze current directory${nc}"
     Write-Host "    ${gray}winmole analyze C:\Users${nc}     ${gray}# Analyze specific path${nc}"
     Write-Host "    ${gray}winmole analyze D:\${nc}          ${gray}# Analyze entire drive${nc}"
+    Write-Host "    ${gray}winmole analyze --export usage.csv --depth 3 D:\${nc}"
+    Write-Host "    ${gray}winmole analyze --no-tui --top 10 --depth 2 C:\Users${nc}"
+    Write-Host "    ${gray}winmole analyze --no-tui --baseline golden.json --depth 3 C:\${nc}"
+    Write-Host "    ${gray}winmole analyze schedule --at 03:30 C:\ D:\Projects${nc}"
     Write-Host ""
 }
 
@@ -106,6 +187,15 @@ function Build-AnalyzeTool {
             return $false
         }
         
+        # The elevated helper (W, I, V and H) lives next to analyze.exe
+        $helperPath = Join-Path (Split-Path -Parent $binaryPath) "helper.exe"
+        $buildOutput = & go build -ldflags="-s -w" -o $helperPath ..\helper 2>&1
+        
+        if ($LASTEXITCODE -ne 0) {
+            Write-Host "  ERROR: Build failed: $buildOutput" -ForegroundColor Red
+            return $false
+        }
+        
         Write-Success "Build complete"
         return $true
     }
@@ -119,35 +209,246 @@ function Build-AnalyzeTool {
     }
 }
 
-function Invoke-AnalyzeTool {
-    param([string]$TargetPath)
-    
+function Update-AnalyzeTool {
+    <#
+    .SYNOPSIS
+        Build analyze.exe and the elevated helper if either is missing or older than their sources
+    #>
     $binaryPath = Get-GoBinaryPath
     
-    # Build if binary doesn't exist or source is newer
-    $srcPath = Join-Path $script:WINMOLE_CMD "analyze\main.go"
+    # Build if binary doesn't exist or any source file is newer
+    $srcDirs = @(
+        (Join-Path $script:WINMOLE_CMD "analyze"),
+        (Join-Path $script:WINMOLE_CMD "helper"),
+        (Join-Path $script:WINMOLE_ROOT "internal"),
+        (Join-Path $script:WINMOLE_ROOT "pkg")
+    )
+    $helperPath = Join-Path (Split-Path -Parent $binaryPath) "helper.exe"
     $needsBuild = $false
     
-    if (-not (Test-Path $binaryPath)) {
+    if (-not (Test-Path $binaryPath) -or -not (Test-Path $helperPath)) {
         $needsBuild = $true
     }
-    elseif ((Get-Item $srcPath).LastWriteTime -gt (Get-Item $binaryPath).LastWriteTime) {
-        $needsBuild = $true
+    else {
+        $binaryTime = (Get-Item $binaryPath).LastWriteTime
+        $newer = Get-ChildItem -Path $srcDirs -Filter *.go -Recurse -ErrorAction SilentlyContinue |
+            Where-Object { $_.LastWriteTime -gt $binaryTime }
+        if ($newer) {
+            $needsBuild = $true
+        }
     }
     
     if ($needsBuild) {
-        if (-not (Build-AnalyzeTool)) {
-            return
-        }
+        return (Build-AnalyzeTool)
     }
+    return $true
+}
+
+function Invoke-AnalyzeTool {
+    param(
+        [string[]]$TargetPaths,
+        [string[]]$Arguments
+    )
+    
+    if (-not (Update-AnalyzeTool)) {
+        return
+    }
+    $binaryPath = Get-GoBinaryPath
     
     # Run the analyzer
     $analyzeArgs = @()
-    if ($TargetPath) {
-        $analyzeArgs += $TargetPath
+    if ($Arguments) {
+        $analyzeArgs += $Arguments
+    }
+    if ($TargetPaths) {
+        $analyzeArgs += $TargetPaths
     }
     
     & $binaryPath @analyzeArgs
+    # 124 = timed out, 130 = interrupted; scripts rely on these
+    if ($LASTEXITCODE -ne 0) {
+        exit $LASTEXITCODE
+    }
+}
+
+# ============================================================================
+# Scheduled Scans
+# ============================================================================
+
+$script:ScheduleTaskName = "Nightly scan"
+$script:ScheduleTaskPath = "\WinMole\"
+
+function Get-ScanSchedule {
+    return Get-ScheduledTask -TaskName $script:ScheduleTaskName -TaskPath $script:ScheduleTaskPath -ErrorAction SilentlyContinue
+}
+
+function Register-ScanSchedule {
+    <#
+    .SYNOPSIS
+        Register (or replace) the nightly job that saves a snapshot of each path
+    .DESCRIPTION
+        The job runs as the signed-in user, so it needs no password and no
+        admin rights, and only while the user is signed in. StartWhenAvailable
+        catches up on a night the PC was off or asleep. Each path gets its
+        own action; Task Scheduler runs them one after another.
+    #>
+    param(
+        [string[]]$TargetPaths,
+        [string]$At,
+        [int]$Depth,
+        [int]$Keep,
+        [switch]$Push
+    )
+    
+    $time = [datetime]::MinValue
+    if (-not [datetime]::TryParseExact($At, "HH:mm", [cultureinfo]::InvariantCulture, "None", [ref]$time)) {
+        Write-Host "  ERROR: --at takes a time of day like 02:00, not $At" -ForegroundColor Red
+        return
+    }
+    if (-not (Update-AnalyzeTool)) {
+        return
+    }
+    $binaryPath = Get-GoBinaryPath
+    
+    # Without --keep the job thins out old snapshots by the retention
+    # settings in config.json, read at each run
+    $retention = if ($Keep -gt 0) { "--keep $Keep" } else { "--prune" }
+    if ($Push) {
+        $retention += " --push"
+    }
+    
+    # analyze.exe is started through a hidden PowerShell so no console
+    # window opens in the middle of the night
+    $actions = foreach ($targetPath in $TargetPaths) {
+        $command = "& '{0}' --snapshot {1} --depth {2} '{3}'" -f ($binaryPath -replace "'", "''"), $retention, $Depth, ($targetPath -replace "'", "''")
+        New-ScheduledTaskAction -Execute "powershell.exe" -Argument "-NoProfile -NonInteractive -WindowStyle Hidden -Command `"$command`""
+    }
+    $trigger = New-ScheduledTaskTrigger -Daily -At $time
+    $settings = New-ScheduledTaskSettingsSet -StartWhenAvailable -ExecutionTimeLimit (New-TimeSpan -Hours 3)
+    $principal = New-ScheduledTaskPrincipal -UserId "$env:USERDOMAIN\$env:USERNAME" -LogonType Interactive -RunLevel Limited
+    
+    Register-ScheduledTask -TaskName $script:ScheduleTaskName -TaskPath $script:ScheduleTaskPath `
+        -Action $actions -Trigger $trigger -Settings $settings -Principal $principal `
+        -Description "Saves a WinMole disk usage snapshot of $($TargetPaths -join ', ') every night" -Force | Out-Null
+    
+    Write-Success "Scanning $($TargetPaths -join ', ') every night at $At"
+    Write-Info "Snapshots go to $(Join-Path (Get-CachePath) 'snapshots'); T and c in the TUI compare them"
+    if ($Push) {
+        Write-Info "Each night's changes go to the server set in config.json; batches it cannot take wait in $(Join-Path (Get-CachePath) 'outbox')"
+    }
+}
+
+function Show-ScanSchedule {
+    $task = Get-ScanSchedule
+    if (-not $task) {
+        Write-Info "No scan is scheduled; winmole analyze schedule sets one up"
+        return
+    }
+    $info = $task | Get-ScheduledTaskInfo
+    $gray = $script:Colors.Gray
+    $nc = $script:Colors.NC
+    
+    Write-Host ""
+    Write-Host "  $($task.Description)"
+    Write-Host "  ${gray}State:${nc}    $($task.State)"
+    if ($info.LastRunTime -and $info.LastRunTime.Year -gt 2000) {
+        # 0 = done, 124 = stopped with partial results
+        Write-Host "  ${gray}Last run:${nc} $($info.LastRunTime) (result $($info.LastTaskResult))"
+    }
+    else {
+        Write-Host "  ${gray}Last run:${nc} never"
+    }
+    if ($info.NextRunTime) {
+        Write-Host "  ${gray}Next run:${nc} $($info.NextRunTime)"
+    }
+    $snapshotDir = Join-Path (Get-CachePath) "snapshots"
+    $snapshots = @(Get-ChildItem -Path $snapshotDir -Filter *.json -File -ErrorAction SilentlyContinue)
+    $size = ($snapshots | Measure-Object -Property Length -Sum).Sum
+    Write-Host "  ${gray}Saved:${nc}    $($snapshots.Count) snapshots, $(Format-ByteSize ([long]$size)) in $snapshotDir"
+    if ($task.Actions[0].Arguments -match '--keep (\d+)') {
+        Write-Host "  ${gray}Kept:${nc}     the newest $($Matches[1]) of each path"
+    }
+    else {
+        Write-Host "  ${gray}Kept:${nc}     by analyze.snapshots in config.json (default: 7 daily, 4 weekly, 12 monthly, 2 GB)"
+    }
+    if ($task.Actions[0].Arguments -match '--push') {
+        $outbox = @(Get-ChildItem -Path (Join-Path (Get-CachePath) "outbox") -Filter *.json.gz -File -ErrorAction SilentlyContinue)
+        Write-Host "  ${gray}Pushed:${nc}   to the server in config.json; $($outbox.Count) batches waiting to be sent"
+    }
+    Write-Host ""
+}
+
+function Invoke-ScanSchedule {
+    <#
+    .SYNOPSIS
+        winmole analyze schedule: register, show or remove the nightly scan
+    #>
+    param(
+        [string[]]$TargetPaths,
+        [string[]]$Flags
+    )
+    
+    $at = "02:00"
+    $depth = 3
+    $keep = 0
+    $push = $false
+    for ($i = 0; $i -lt $Flags.Count; $i++) {
+        $flag = $Flags[$i].TrimStart("-")
+        switch ($flag) {
+            "status" {
+                Show-ScanSchedule
+                return
+            }
+            "push" {
+                $push = $true
+            }
+            "remove" {
+                if (-not (Get-ScanSchedule)) {
+                    Write-Info "No scan is scheduled"
+                    return
+                }
+                Unregister-ScheduledTask -TaskName $script:ScheduleTaskName -TaskPath $script:ScheduleTaskPath -Confirm:$false
+                Write-Success "Removed the nightly scan; the snapshots it saved are kept"
+                return
+            }
+            { $_ -in "at", "depth", "keep" } {
+                if ($i + 1 -ge $Flags.Count) {
+                    Write-Host "  ERROR: --$flag needs a value" -ForegroundColor Red
+                    return
+                }
+                $i++
+                switch ($flag) {
+                    "at" { $at = $Flags[$i] }
+                    "depth" { $depth = [int]$Flags[$i] }
+                    "keep" { $keep = [int]$Flags[$i] }
+                }
+            }
+            default {
+                Write-Host "  ERROR: schedule does not take $($Flags[$i])" -ForegroundColor Red
+                return
+            }
+        }
+    }
+    
+    if (-not $TargetPaths) {
+        $TargetPaths = @("$env:SystemDrive\")
+    }
+    # The job has no network sign-in of its own, and a relative path would
+    # resolve against the job's working directory
+    $resolved = @()
+    foreach ($targetPath in $TargetPaths) {
+        if ($targetPath.StartsWith("\\")) {
+            Write-Host "  ERROR: Shares cannot be scheduled: $targetPath" -ForegroundColor Red
+            return
+        }
+        if (-not (Test-Path $targetPath)) {
+            Write-Host "  ERROR: Path does not exist: $targetPath" -ForegroundColor Red
+            return
+        }
+        $resolved += (Resolve-Path $targetPath).ProviderPath
+    }
+    
+    Register-ScanSchedule -TargetPaths $resolved -At $at -Depth $depth -Keep $keep -Push:$push
 }
 
 # ============================================================================
@@ -163,21 +464,41 @@ function Main {
         return
     }
     
-    # Determine target path
-    $targetPath = if ($Path) { 
-        $Path 
-    } else { 
-        Get-Location 
+    # Split flags for analyze.exe from the path; a leading flag lands in $Path
+    $valueFlags = @("--profile", "-profile", "--export", "-export", "--depth", "-depth", "--top", "-top", "--format", "-format", "--baseline", "-baseline", "--import", "-import", "--timeout", "-timeout", "--user", "-user", "--keep", "-keep", "--at", "-at")
+    $allArgs = @(@($Path) + @($ToolArgs) | Where-Object { $_ })
+    $flags = @()
+    $paths = @()
+    for ($i = 0; $i -lt $allArgs.Count; $i++) {
+        $arg = $allArgs[$i]
+        if (-not $arg.StartsWith("-")) {
+            $paths += $arg
+            continue
+        }
+        $flags += $arg
+        if ($arg -in $valueFlags -and $i + 1 -lt $allArgs.Count) {
+            $i++
+            $flags += $allArgs[$i]
+        }
     }
     
-    # Validate path
-    if (-not (Test-Path $targetPath)) {
-        Write-Host "  ERROR: Path does not exist: $targetPath" -ForegroundColor Red
+    if ($paths.Count -gt 0 -and $paths[0] -eq "schedule") {
+        Invoke-ScanSchedule -TargetPaths @($paths | Select-Object -Skip 1) -Flags $flags
         return
     }
     
+    # Without a path, analyze.exe lists the drives to pick from; several
+    # are scanned side by side. Shares are checked by analyze.exe, which can
+    # sign in to them first
+    foreach ($targetPath in $paths) {
+        if (-not $targetPath.StartsWith("\\") -and -not (Test-Path $targetPath)) {
+            Write-Host "  ERROR: Path does not exist: $targetPath" -ForegroundColor Red
+            return
+        }
+    }
+    
     # Run the analyzer
-    Invoke-AnalyzeTool -TargetPath $targetPath
+    Invoke-AnalyzeTool -TargetPaths $paths -Arguments $flags
 }
 
 # Run
@@ -9,9 +9,13 @@ param(
     [switch]$Browsers,
     [switch]$Apps,
     [switch]$Dev,
+    [switch]$Packages,
+    [int]$KeepDays = -1,
     [switch]$System,
     [switch]$RecycleBin,
     [switch]$WindowsUpdate,
+    [switch]$Mail,
+    [int]$Timeout = 0,
     [switch]$Help
 )
 
@@ -24,9 +28,12 @@ $libDir = Join-Path (Split-Path -Parent $scriptDir) "lib"
 
 # Import modules
 . "$libDir\core\common.ps1"
+Assert-CommandEnabled -Name "clean"
 . "$libDir\clean\user.ps1"
 . "$libDir\clean\dev.ps1"
+. "$libDir\clean\packages.ps1"
 . "$libDir\clean\system.ps1"
+. "$libDir\clean\mail.ps1"
 
 # ============================================================================
 # Help
@@ -50,9 +57,13 @@ function Show-CleanHelp {
     Write-Host "    -Browsers       Clean browser caches"
     Write-Host "    -Apps           Clean application caches"
     Write-Host "    -Dev            Clean developer tool caches"
+    Write-Host "    -Packages       Trim package caches (npm, pip, NuGet, Maven, winget...) by age"
+    Write-Host "    -KeepDays <n>   Keep package cache entries used in the last n days (0 clears them)"
     Write-Host "    -System         Clean system caches (requires admin)"
     Write-Host "    -RecycleBin     Empty Recycle Bin"
     Write-Host "    -WindowsUpdate  Clean Windows Update cache (requires admin)"
+    Write-Host "    -Mail           Outlook data files by profile; clear unused OSTs and Teams/Outlook caches"
+    Write-Host "    -Timeout <sec>  Start no new step after this many seconds (exit 124)"
     Write-Host "    -Help           Show this help"
     Write-Host ""
     Write-Host "  ${gray}EXAMPLES:${nc}"
@@ -60,6 +71,7 @@ function Show-CleanHelp {
     Write-Host "    winmole clean -All               # Full cleanup"
     Write-Host "    winmole clean -User -Browsers    # User + Browser cleanup"
     Write-Host "    winmole clean -All -DryRun       # Preview all changes"
+    Write-Host "    winmole clean -Packages -KeepDays 7  # Package caches unused for a week"
     Write-Host ""
 }
 
@@ -73,7 +85,9 @@ function Show-CleanMenu {
         @{ Name = "Browser Clean"; Description = "All browser caches"; Action = "browsers" }
         @{ Name = "App Clean"; Description = "Application caches"; Action = "apps" }
         @{ Name = "Developer Clean"; Description = "Dev tool caches (npm, pip, etc.)"; Action = "dev" }
+        @{ Name = "Package Caches"; Description = "Package and installer downloads not used lately"; Action = "packages" }
         @{ Name = "System Clean"; Description = "System caches (requires admin)"; Action = "system" }
+        @{ Name = "Mail Clean"; Description = "Outlook data files, unused OSTs, Teams caches"; Action = "mail" }
         @{ Name = "Full Clean"; Description = "Everything above"; Action = "all" }
     )
     
@@ -117,12 +131,14 @@ function Main {
     $cleanBrowsers = $false
     $cleanApps = $false
     $cleanDev = $false
+    $cleanPackages = $false
     $cleanSystem = $false
     $cleanRecycleBin = $false
     $cleanWinUpdate = $false
+    $cleanMail = $false
     
     # If no flags specified, run interactive mode
-    $noFlags = -not ($All -or $User -or $Browsers -or $Apps -or $Dev -or $System -or $RecycleBin -or $WindowsUpdate)
+    $noFlags = -not ($All -or $User -or $Browsers -or $Apps -or $Dev -or $Packages -or $System -or $RecycleBin -or $WindowsUpdate -or $Mail)
     
     if ($noFlags) {
         Clear-Host
@@ -140,12 +156,15 @@ function Main {
             "browsers" { $cleanBrowsers = $true }
             "apps" { $cleanApps = $true }
             "dev" { $cleanDev = $true }
+            "packages" { $cleanPackages = $true }
             "system" { $cleanSystem = $true }
+            "mail" { $cleanMail = $true }
             "all" { 
                 $cleanUser = $true
                 $cleanBrowsers = $true
                 $cleanApps = $true
                 $cleanDev = $true
+                $cleanPackages = $true
                 $cleanSystem = $true
                 $cleanRecycleBin = $true
             }
@@ -166,6 +185,7 @@ function Main {
             $cleanBrowsers = $true
             $cleanApps = $true
             $cleanDev = $true
+            $cleanPackages = $true
             $cleanSystem = $true
             $cleanRecycleBin = $true
             $cleanWinUpdate = $true
@@ -175,9 +195,11 @@ function Main {
             $cleanBrowsers = $Browsers
             $cleanApps = $Apps
             $cleanDev = $Dev
+            $cleanPackages = $Packages
             $cleanSystem = $System
             $cleanRecycleBin = $RecycleBin
             $cleanWinUpdate = $WindowsUpdate
+            $cleanMail = $Mail
         }
     }
     
@@ -186,54 +208,80 @@ function Main {
     
     Write-Host ""
     
-    # Run cleanups
+    # Run cleanups as steps so -Timeout can stop between them
+    $steps = @()
     if ($cleanUser) {
-        Clear-UserCaches
-        Clear-UserLogs
+        $steps += { Clear-UserCaches; Clear-UserLogs }
     }
     
     if ($cleanBrowsers) {
-        Clear-BrowserCaches
+        $steps += { Clear-BrowserCaches }
     }
     
     if ($cleanApps) {
-        Clear-ApplicationCaches
+        $steps += { Clear-ApplicationCaches }
     }
     
     if ($cleanDev) {
-        Invoke-DevCleanup -All
+        $steps += { Invoke-DevCleanup -All }
+    }
+    
+    # Developer cleanup includes the package caches
+    if ($cleanPackages -or $cleanDev) {
+        $steps += { Clear-PackageCaches -KeepDays $KeepDays }
     }
     
     if ($cleanSystem) {
-        if (Test-IsAdmin) {
-            Invoke-SystemCleanup -All
-        }
-        else {
-            Write-Warning "System cleanup requires admin - skipping"
-            Write-Info "Run 'winmole clean -System' as Administrator"
+        $steps += {
+            if (Test-IsAdmin) {
+                Invoke-SystemCleanup -All
+            }
+            else {
+                Write-Warning "System cleanup requires admin - skipping"
+                Write-Info "Run 'winmole clean -System' as Administrator"
+            }
         }
     }
     
     if ($cleanRecycleBin) {
-        Clear-RecycleBin
+        $steps += { Clear-RecycleBin }
     }
     
     if ($cleanWinUpdate) {
-        if (Test-IsAdmin) {
-            Clear-WindowsUpdateCache
-        }
-        else {
-            Write-Warning "Windows Update cleanup requires admin - skipping"
+        $steps += {
+            if (Test-IsAdmin) {
+                Clear-WindowsUpdateCache
+            }
+            else {
+                Write-Warning "Windows Update cleanup requires admin - skipping"
+            }
         }
     }
     
+    if ($cleanMail) {
+        $steps += { Invoke-MailCleanup }
+    }
+    
     # Clean empty directories
-    Start-Section "Empty Directories"
-    Remove-EmptyDirectories -Path "$env:LOCALAPPDATA" -Description "Empty folders (LocalAppData)"
-    Stop-Section
+    $steps += {
+        Start-Section "Empty Directories"
+        Remove-EmptyDirectories -Path "$env:LOCALAPPDATA" -Description "Empty folders (LocalAppData)"
+        Stop-Section
+    }
+    
+    $deadline = if ($Timeout -gt 0) { (Get-Date).AddSeconds($Timeout) } else { $null }
+    $timedOut = $false
+    foreach ($step in $steps) {
+        if ($deadline -and (Get-Date) -ge $deadline) {
+            $timedOut = $true
+            break
+        }
+        . $step
+    }
     
     # Show final summary
     $stats = Get-CleanupStats
+    Add-UsageRecord -Feature "clean" -Kind freed -Bytes ($stats.TotalSizeKB * 1024)
     if ($stats.TotalItems -gt 0 -or (Test-DryRunMode)) {
         Show-Summary -SizeBytes ($stats.TotalSizeKB * 1024) -ItemCount $stats.TotalItems -Action $(if (Test-DryRunMode) { "Would clean" } else { "Cleaned" })
     }
@@ -247,6 +295,11 @@ function Main {
     $freeSpace = Get-FreeSpace
     Write-Host "  Free space on $($env:SystemDrive): $freeSpace"
     Write-Host ""
+    
+    if ($timedOut) {
+        Write-Warning "Stopped after $Timeout seconds - remaining steps were skipped"
+        exit 124
+    }
 }
 
 # Run
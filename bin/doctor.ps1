@@ -0,0 +1,863 @@
+# WinMole - Doctor Command
+# Guided troubleshooting: finds common causes of a slow or full PC and
+# offers a one-key fix for each from the other winmole commands
+
+#Requires -Version 5.1
+param(
+    [switch]$Report,
+    [switch]$DryRun,
+    [switch]$Help
+)
+
+$ErrorActionPreference = "Stop"
+Set-StrictMode -Version Latest
+
+# Get script location
+$scriptDir = Split-Path -Parent $MyInvocation.MyCommand.Path
+$libDir = Join-Path (Split-Path -Parent $scriptDir) "lib"
+
+# Import modules
+. "$libDir\core\common.ps1"
+Assert-CommandEnabled -Name "doctor"
+
+# ============================================================================
+# Help
+# ============================================================================
+
+function Show-DoctorHelp {
+    $cyan = $script:Colors.Cyan
+    $gray = $script:Colors.Gray
+    $nc = $script:Colors.NC
+
+    Write-Host ""
+    Write-Host "  ${cyan}WinMole Doctor${nc} - Guided troubleshooting"
+    Write-Host ""
+    Write-Host "  ${gray}USAGE:${nc}"
+    Write-Host "    winmole doctor [options]"
+    Write-Host ""
+    Write-Host "  ${gray}CHECKS:${nc}"
+    Write-Host "    Disks nearly full, failing disks (SMART), BitLocker suspended or without"
+    Write-Host "    a backed-up recovery key, degraded Storage Spaces and RAID volumes,"
+    Write-Host "    thermal throttling, pending Windows updates, startup bloat, background"
+    Write-Host "    indexing and an oversized search index"
+    Write-Host ""
+    Write-Host "  ${gray}OPTIONS:${nc}"
+    Write-Host "    -Report         List the findings and exit without offering fixes"
+    Write-Host "    -DryRun         Run fixes in preview mode"
+    Write-Host "    -Help           Show this help"
+    Write-Host ""
+    Write-Host "  ${gray}EXAMPLES:${nc}"
+    Write-Host "    winmole doctor            # Check, then press a number to fix"
+    Write-Host "    winmole doctor -Report    # Findings only (for scripts and tickets)"
+    Write-Host ""
+}
+
+# ============================================================================
+# Findings
+# ============================================================================
+
+# Severities, most urgent first; findings are listed in this order
+$script:SeverityCritical = 0
+$script:SeverityWarning = 1
+$script:SeverityAdvice = 2
+
+function New-Finding {
+    <#
+    .SYNOPSIS
+        Create a finding with an optional fix
+    .PARAMETER Command
+        winmole command the fix runs (clean, analyze, optimize, status)
+    .PARAMETER Arguments
+        Arguments for the command, or for the script block
+    .PARAMETER Action
+        Script block run as the fix instead of a winmole command
+    #>
+    param(
+        [int]$Severity,
+        [string]$Title,
+        [string]$Detail,
+        [string]$Fix = "",
+        [string]$Command = "",
+        [string[]]$Arguments = @(),
+        [scriptblock]$Action = $null
+    )
+
+    return @{
+        Severity  = $Severity
+        Title     = $Title
+        Detail    = $Detail
+        Fix       = $Fix
+        Command   = $Command
+        Arguments = $Arguments
+        Action    = $Action
+        Done      = $false
+    }
+}
+
+# ============================================================================
+# Checks
+# ============================================================================
+
+function Test-DiskSpace {
+    <#
+    .SYNOPSIS
+        Fixed drives with less than 10% (or 5 GB) free
+    #>
+    $findings = @()
+    $drives = Get-WmiObject Win32_LogicalDisk -Filter "DriveType=3" | Where-Object { $_.Size -gt 0 }
+
+    foreach ($drive in $drives) {
+        $freePercent = [Math]::Round(($drive.FreeSpace / $drive.Size) * 100)
+        if ($freePercent -ge 10 -and $drive.FreeSpace -ge 5GB) {
+            continue
+        }
+
+        $severity = if ($freePercent -lt 5) { $script:SeverityCritical } else { $script:SeverityWarning }
+        $detail = "$(Format-ByteSize $drive.FreeSpace) free ($freePercent%)"
+
+        # Caches and temp files live on the system drive; elsewhere the
+        # space is the user's own files
+        if ($drive.DeviceID -eq $env:SystemDrive) {
+            $findings += New-Finding -Severity $severity -Title "$($drive.DeviceID) is nearly full" -Detail $detail `
+                -Fix "Clean caches, temp files and logs" -Command "clean"
+        }
+        else {
+            $findings += New-Finding -Severity $severity -Title "$($drive.DeviceID) is nearly full" -Detail $detail `
+                -Fix "Find what is using the space" -Command "analyze" -Arguments @("$($drive.DeviceID)\")
+        }
+    }
+
+    return $findings
+}
+
+function Test-DiskHealth {
+    <#
+    .SYNOPSIS
+        Disks that report a SMART failure prediction or heavy wear
+    #>
+    $findings = @()
+
+    try {
+        $disks = Get-PhysicalDisk -ErrorAction Stop
+    }
+    catch {
+        Write-Debug "Could not query physical disks: $_"
+        return $findings
+    }
+
+    foreach ($disk in $disks) {
+        $name = $disk.FriendlyName
+        if ($disk.HealthStatus -ne "Healthy") {
+            $findings += New-Finding -Severity $script:SeverityCritical -Title "$name reports $($disk.HealthStatus)" `
+                -Detail "The disk predicts its own failure; back up what matters now" `
+                -Fix "See what is on it" -Command "analyze"
+            continue
+        }
+
+        # Wear needs admin rights and is not reported by every drive
+        try {
+            $counters = $disk | Get-StorageReliabilityCounter -ErrorAction Stop
+            if ($null -ne $counters.Wear -and $counters.Wear -ge 90) {
+                $findings += New-Finding -Severity $script:SeverityWarning -Title "$name is $($counters.Wear)% worn" `
+                    -Detail "Plan to replace it and keep backups current"
+            }
+        }
+        catch {
+            Write-Debug "No reliability counters for ${name}: $_"
+        }
+    }
+
+    return $findings
+}
+
+# Per-volume BitLocker status, read once by Test-Encryption and shown above
+# the findings; $null when it could not be read
+$script:EncryptionStatus = $null
+
+function Get-EncryptionStatus {
+    <#
+    .SYNOPSIS
+        BitLocker status, protection method and recovery key backup of each volume
+    .NOTES
+        Needs admin rights, and BitLocker is missing from Home editions. The
+        recovery key itself is never read, only whether one exists and
+        whether a backup of it was logged
+    #>
+    if (-not (Get-Command Get-BitLockerVolume -ErrorAction SilentlyContinue)) {
+        return $null
+    }
+    $volumes = Get-BitLockerVolume -ErrorAction Stop
+
+    # Event 845: recovery information backed up to a Microsoft account or
+    # Entra ID
+    $backups = @(Get-WinEvent -FilterHashtable @{ LogName = "Microsoft-Windows-BitLocker/BitLocker Management"; Id = 845 } `
+                     -ErrorAction SilentlyContinue | ForEach-Object { $_.Message })
+
+    $methods = @{
+        Tpm              = "TPM"
+        TpmPin           = "TPM + PIN"
+        TpmStartupKey    = "TPM + startup key"
+        TpmPinStartupKey = "TPM + PIN + startup key"
+        ExternalKey      = "startup key"
+        Password         = "password"
+        AdAccountOrGroup = "domain account"
+    }
+
+    return @(foreach ($volume in $volumes) {
+        $protectors = @($volume.KeyProtector | ForEach-Object { [string]$_.KeyProtectorType })
+        $method = @($protectors | Where-Object { $methods.ContainsKey($_) } | ForEach-Object { $methods[$_] }) -join ", "
+        $mount = $volume.MountPoint
+        [pscustomobject]@{
+            MountPoint  = $mount
+            Status      = [string]$volume.VolumeStatus
+            Encrypted   = [string]$volume.VolumeStatus -ne "FullyDecrypted"
+            Percent     = $volume.EncryptionPercentage
+            Protected   = [string]$volume.ProtectionStatus -eq "On"
+            Method      = $method
+            RecoveryKey = $protectors -contains "RecoveryPassword"
+            BackedUp    = [bool]($backups | Where-Object { $_ -match [regex]::Escape($mount) })
+        }
+    })
+}
+
+function Test-Encryption {
+    <#
+    .SYNOPSIS
+        BitLocker volumes with protection suspended or without a safe recovery key
+    #>
+    $findings = @()
+
+    try {
+        $script:EncryptionStatus = Get-EncryptionStatus
+    }
+    catch {
+        Write-Debug "Could not query BitLocker: $_"
+        return $findings
+    }
+
+    foreach ($volume in @($script:EncryptionStatus | Where-Object { $_.Encrypted })) {
+        $mount = $volume.MountPoint
+
+        if (-not $volume.Protected -and $volume.Percent -eq 100) {
+            $resume = {
+                param([string]$MountPoint)
+                if (Test-DryRunMode) {
+                    Write-DryRun "Would resume BitLocker protection on $MountPoint"
+                    return
+                }
+                Resume-BitLocker -MountPoint $MountPoint | Out-Null
+                Write-Success "BitLocker protection resumed on $MountPoint"
+            }
+            $findings += New-Finding -Severity $script:SeverityWarning -Title "BitLocker protection is suspended on $mount" `
+                -Detail "The disk is encrypted, but its key is stored in the clear until protection resumes" `
+                -Fix "Resume BitLocker protection" -Action $resume -Arguments @($mount)
+        }
+
+        if (-not $volume.RecoveryKey) {
+            $findings += New-Finding -Severity $script:SeverityWarning -Title "$mount has no recovery key" `
+                -Detail "If the TPM or PIN stops working the data cannot be unlocked; add one with manage-bde -protectors -add $mount -RecoveryPassword"
+        }
+        elseif (-not $volume.BackedUp) {
+            $findings += New-Finding -Severity $script:SeverityAdvice -Title "No backup of the $mount recovery key is recorded" `
+                -Detail "Make sure the key is saved in your Microsoft account, with your organization or on paper" `
+                -Fix "Open the recovery keys in your Microsoft account" -Action { Start-Process "https://aka.ms/myrecoverykey" }
+        }
+    }
+
+    return $findings
+}
+
+function Show-EncryptionStatus {
+    <#
+    .SYNOPSIS
+        List each volume's BitLocker status and protection method
+    #>
+    $green = $script:Colors.Green
+    $yellow = $script:Colors.Yellow
+    $gray = $script:Colors.Gray
+    $nc = $script:Colors.NC
+
+    Start-Section "Encryption"
+    Set-SectionActivity
+
+    if ($null -eq $script:EncryptionStatus) {
+        $reason = if (-not (Get-Command Get-BitLockerVolume -ErrorAction SilentlyContinue)) {
+            "BitLocker is not available on this edition of Windows"
+        }
+        else {
+            "BitLocker status needs admin rights"
+        }
+        Write-Host "  ${gray}${reason}${nc}"
+        Stop-Section
+        return
+    }
+
+    foreach ($volume in $script:EncryptionStatus) {
+        $state = if (-not $volume.Encrypted) {
+            "${gray}not encrypted${nc}"
+        }
+        elseif ($volume.Status -eq "EncryptionInProgress") {
+            "${yellow}encrypting, $($volume.Percent)% done${nc}"
+        }
+        elseif ($volume.Status -eq "DecryptionInProgress") {
+            "${yellow}decrypting, $($volume.Percent)% still encrypted${nc}"
+        }
+        elseif (-not $volume.Protected) {
+            "${yellow}encrypted, protection suspended${nc}"
+        }
+        else {
+            "${green}protected${nc}"
+        }
+        $method = if ($volume.Encrypted -and $volume.Method) { " ${gray}($($volume.Method))${nc}" } else { "" }
+        Write-Host "  $($volume.MountPoint.PadRight(4)) $state$method"
+    }
+
+    Stop-Section
+}
+
+# Storage Spaces pools with their virtual disks, and RAID volumes presented
+# by a controller, read once by Test-StorageRedundancy and shown above the
+# findings; empty when the PC has neither
+$script:StorageStatus = @()
+
+function Get-ResiliencyName {
+    <#
+    .SYNOPSIS
+        Describe a virtual disk's layout ("two-way mirror", "dual parity")
+    #>
+    param($VirtualDisk)
+
+    switch ($VirtualDisk.ResiliencySettingName) {
+        "Simple" { return "simple, no redundancy" }
+        "Mirror" {
+            if ($VirtualDisk.NumberOfDataCopies -ge 3) { return "three-way mirror" }
+            return "two-way mirror"
+        }
+        "Parity" {
+            if ($VirtualDisk.PhysicalDiskRedundancy -ge 2) { return "dual parity" }
+            return "single parity"
+        }
+    }
+    return [string]$VirtualDisk.ResiliencySettingName
+}
+
+function Get-StorageStatus {
+    <#
+    .SYNOPSIS
+        Health, redundancy and capacity of Storage Spaces pools and RAID volumes
+    .NOTES
+        Intel RST and other controller RAID only shows up when the controller
+        presents its volumes with the RAID bus type; its member disks and
+        redundancy stay hidden behind the controller
+    #>
+    if (-not (Get-Command Get-StoragePool -ErrorAction SilentlyContinue)) {
+        return @()
+    }
+
+    $status = @()
+    foreach ($pool in @(Get-StoragePool -IsPrimordial $false -ErrorAction Stop)) {
+        $disks = @($pool | Get-PhysicalDisk -ErrorAction SilentlyContinue)
+        $volumes = @(foreach ($vd in @($pool | Get-VirtualDisk -ErrorAction SilentlyContinue)) {
+            [pscustomobject]@{
+                Name       = $vd.FriendlyName
+                Health     = [string]$vd.HealthStatus
+                State      = @($vd.OperationalStatus | ForEach-Object { [string]$_ }) -join ", "
+                Resiliency = Get-ResiliencyName -VirtualDisk $vd
+                Size       = $vd.Size
+                # Capacity efficiency: usable size against the pool space it takes
+                Efficiency = if ($vd.FootprintOnPool -gt 0) { [Math]::Round($vd.Size / $vd.FootprintOnPool * 100) } else { $null }
+            }
+        })
+        $status += [pscustomobject]@{
+            Kind      = "Pool"
+            Name      = $pool.FriendlyName
+            Health    = [string]$pool.HealthStatus
+            State     = @($pool.OperationalStatus | ForEach-Object { [string]$_ }) -join ", "
+            ReadOnly  = [bool]$pool.IsReadOnly
+            Size      = $pool.Size
+            Allocated = $pool.AllocatedSize
+            Disks     = $disks.Count
+            Failed    = @($disks | Where-Object { [string]$_.HealthStatus -ne "Healthy" }).Count
+            Volumes   = $volumes
+        }
+    }
+
+    foreach ($disk in @(Get-PhysicalDisk -ErrorAction SilentlyContinue | Where-Object { [string]$_.BusType -eq "RAID" })) {
+        $status += [pscustomobject]@{
+            Kind   = "RAID"
+            Name   = $disk.FriendlyName
+            Health = [string]$disk.HealthStatus
+            State  = @($disk.OperationalStatus | ForEach-Object { [string]$_ }) -join ", "
+            Size   = $disk.Size
+        }
+    }
+
+    return $status
+}
+
+function Test-StorageRedundancy {
+    <#
+    .SYNOPSIS
+        Storage Spaces and RAID volumes that are degraded, failed or running out of pool space
+    #>
+    $findings = @()
+
+    try {
+        $script:StorageStatus = @(Get-StorageStatus)
+    }
+    catch {
+        Write-Debug "Could not query Storage Spaces: $_"
+        return $findings
+    }
+
+    foreach ($item in $script:StorageStatus) {
+        if ($item.Kind -eq "RAID") {
+            if ($item.Health -ne "Healthy") {
+                $findings += New-Finding -Severity $script:SeverityCritical -Title "RAID volume $($item.Name) is $($item.State.ToLower())" `
+                    -Detail "Check the controller's own tool (Intel RST, vendor RAID manager) for the failed member and rebuild"
+            }
+            continue
+        }
+
+        foreach ($volume in $item.Volumes) {
+            if ($volume.Health -eq "Healthy") {
+                continue
+            }
+            if ($volume.Health -eq "Unhealthy") {
+                $findings += New-Finding -Severity $script:SeverityCritical -Title "Storage space $($volume.Name) is $($volume.State.ToLower())" `
+                    -Detail "More disks failed than its $($volume.Resiliency) layout tolerates; restore from backup once the pool's disks are back"
+                continue
+            }
+
+            # Warning: redundancy is reduced, or a repair is under way
+            $repair = {
+                param([string]$Name)
+                if (Test-DryRunMode) {
+                    Write-DryRun "Would start a repair of storage space $Name"
+                    return
+                }
+                Repair-VirtualDisk -FriendlyName $Name -AsJob | Out-Null
+                Write-Success "Repair of $Name started; it continues in the background"
+            }
+            $findings += New-Finding -Severity $script:SeverityCritical -Title "Storage space $($volume.Name) is degraded ($($volume.State))" `
+                -Detail "Its $($volume.Resiliency) has lost redundancy; another disk failure can lose data. Replace the failed disk in pool $($item.Name), then repair" `
+                -Fix "Start a repair onto the pool's remaining disks" -Action $repair -Arguments @($volume.Name)
+        }
+
+        if ($item.Health -ne "Healthy" -and @($item.Volumes | Where-Object { $_.Health -ne "Healthy" }).Count -eq 0) {
+            $detail = if ($item.Failed -gt 0) { "$($item.Failed) of its $($item.Disks) disks report a problem" } else { "State: $($item.State)" }
+            $findings += New-Finding -Severity $script:SeverityWarning -Title "Storage pool $($item.Name) is $($item.Health.ToLower())" -Detail $detail
+        }
+
+        if ($item.Size -gt 0) {
+            $used = [Math]::Round($item.Allocated / $item.Size * 100)
+            if ($used -ge 90) {
+                $findings += New-Finding -Severity $script:SeverityWarning -Title "Storage pool $($item.Name) is $used% allocated" `
+                    -Detail "Thinly provisioned spaces go offline when the pool runs out; add a disk to the pool"
+            }
+        }
+    }
+
+    return $findings
+}
+
+function Show-StorageStatus {
+    <#
+    .SYNOPSIS
+        List each pool, its storage spaces and any RAID volumes
+    #>
+    if ($script:StorageStatus.Count -eq 0) {
+        return
+    }
+
+    $green = $script:Colors.Green
+    $yellow = $script:Colors.Yellow
+    $red = $script:Colors.Red
+    $gray = $script:Colors.Gray
+    $nc = $script:Colors.NC
+
+    $healthColor = @{ Healthy = $green; Warning = $yellow; Unhealthy = $red }
+
+    Start-Section "Storage Spaces and RAID"
+    Set-SectionActivity
+
+    foreach ($item in $script:StorageStatus) {
+        $color = if ($healthColor.ContainsKey($item.Health)) { $healthColor[$item.Health] } else { $gray }
+        $state = if ($item.Health -eq "Healthy") { "healthy" } else { $item.State.ToLower() }
+
+        if ($item.Kind -eq "RAID") {
+            Write-Host "  $($item.Name)  ${color}${state}${nc}  ${gray}RAID volume, $(Format-ByteSize $item.Size)${nc}"
+            continue
+        }
+
+        $capacity = "$($item.Disks) disks, $(Format-ByteSize $item.Size)"
+        if ($item.Size -gt 0) {
+            $capacity += ", $([Math]::Round($item.Allocated / $item.Size * 100))% allocated"
+        }
+        if ($item.ReadOnly) {
+            $capacity += ", read-only"
+        }
+        Write-Host "  Pool $($item.Name)  ${color}${state}${nc}  ${gray}${capacity}${nc}"
+
+        foreach ($volume in $item.Volumes) {
+            $color = if ($healthColor.ContainsKey($volume.Health)) { $healthColor[$volume.Health] } else { $gray }
+            $state = if ($volume.Health -eq "Healthy") { "healthy" } else { $volume.State.ToLower() }
+            $efficiency = if ($null -ne $volume.Efficiency) { ", $($volume.Efficiency)% efficient" } else { "" }
+            Write-Host "    $($volume.Name)  ${color}${state}${nc}  ${gray}$($volume.Resiliency), $(Format-ByteSize $volume.Size)${efficiency}${nc}"
+        }
+    }
+
+    Stop-Section
+}
+
+function Test-ThermalThrottling {
+    <#
+    .SYNOPSIS
+        Thermal zones that are slowing the processor down
+    #>
+    $findings = @()
+
+    try {
+        $samples = (Get-Counter '\Thermal Zone Information(*)\% Passive Limit' -ErrorAction Stop).CounterSamples
+    }
+    catch {
+        # Many desktops expose no thermal zones
+        Write-Debug "No thermal zone counters: $_"
+        return $findings
+    }
+
+    $limit = ($samples | Measure-Object -Property CookedValue -Minimum).Minimum
+    if ($null -ne $limit -and $limit -lt 100) {
+        $findings += New-Finding -Severity $script:SeverityWarning -Title "The processor is thermally throttled" `
+            -Detail "Held to $([Math]::Round($limit))% of its speed; check vents and fans, and what keeps it busy" `
+            -Fix "Watch temperatures and busy processes" -Command "status"
+    }
+
+    return $findings
+}
+
+function Test-PendingUpdates {
+    <#
+    .SYNOPSIS
+        Updates waiting for a restart, or no updates for over a month
+    #>
+    $findings = @()
+    $openUpdates = { Start-Process "ms-settings:windowsupdate" }
+
+    if (Test-Path "HKLM:\SOFTWARE\Microsoft\Windows\CurrentVersion\WindowsUpdate\Auto Update\RebootRequired") {
+        $findings += New-Finding -Severity $script:SeverityWarning -Title "Updates are waiting for a restart" `
+            -Detail "Windows runs partly updated until it restarts" `
+            -Fix "Open Windows Update" -Action $openUpdates
+        return $findings
+    }
+
+    $lastUpdate = (Get-HotFix -ErrorAction SilentlyContinue | Where-Object { $_.InstalledOn } |
+                   Sort-Object InstalledOn -Descending | Select-Object -First 1)
+    if ($lastUpdate) {
+        $daysSinceUpdate = ((Get-Date) - $lastUpdate.InstalledOn).Days
+        if ($daysSinceUpdate -gt 30) {
+            $findings += New-Finding -Severity $script:SeverityAdvice -Title "No updates for $daysSinceUpdate days" `
+                -Detail "Last installed: $($lastUpdate.HotFixID)" `
+                -Fix "Open Windows Update" -Action $openUpdates
+        }
+    }
+
+    return $findings
+}
+
+function Test-StartupBloat {
+    <#
+    .SYNOPSIS
+        Many programs started at sign-in
+    #>
+    $findings = @()
+    $count = 0
+
+    # Same locations as winmole optimize -Startup, less scheduled tasks,
+    # which are slow to enumerate
+    $regPaths = @(
+        "HKCU:\SOFTWARE\Microsoft\Windows\CurrentVersion\Run"
+        "HKLM:\SOFTWARE\Microsoft\Windows\CurrentVersion\Run"
+        "HKLM:\SOFTWARE\WOW6432Node\Microsoft\Windows\CurrentVersion\Run"
+    )
+    foreach ($path in $regPaths) {
+        if (Test-Path $path) {
+            $entries = Get-ItemProperty $path -ErrorAction SilentlyContinue
+            $count += @($entries.PSObject.Properties | Where-Object { $_.Name -notlike "PS*" }).Count
+        }
+    }
+
+    $startupFolders = @(
+        "$env:APPDATA\Microsoft\Windows\Start Menu\Programs\Startup"
+        "$env:ProgramData\Microsoft\Windows\Start Menu\Programs\Startup"
+    )
+    foreach ($folder in $startupFolders) {
+        if (Test-Path $folder) {
+            $count += @(Get-ChildItem $folder -File -ErrorAction SilentlyContinue).Count
+        }
+    }
+
+    if ($count -ge 8) {
+        $severity = if ($count -ge 15) { $script:SeverityWarning } else { $script:SeverityAdvice }
+        $findings += New-Finding -Severity $severity -Title "$count programs start at sign-in" `
+            -Detail "Each one adds to boot time and runs in the background" `
+            -Fix "Review startup programs" -Command "optimize" -Arguments @("-Startup")
+    }
+
+    return $findings
+}
+
+function Test-BackgroundIndexing {
+    <#
+    .SYNOPSIS
+        Windows Search indexer keeping the CPU busy
+    #>
+    $findings = @()
+
+    $indexer = Get-Process SearchIndexer -ErrorAction SilentlyContinue | Select-Object -First 1
+    if (-not $indexer) {
+        return $findings
+    }
+
+    # CPU time over a short window, as a share of one core
+    try {
+        $before = $indexer.TotalProcessorTime
+        Start-Sleep -Seconds 2
+        $indexer.Refresh()
+        $busy = ($indexer.TotalProcessorTime - $before).TotalSeconds / 2 * 100
+    }
+    catch {
+        Write-Debug "Could not sample SearchIndexer: $_"
+        return $findings
+    }
+
+    if ($busy -ge 20) {
+        $findings += New-Finding -Severity $script:SeverityAdvice -Title "Search indexing is busy" `
+            -Detail "SearchIndexer is using $([Math]::Round($busy))% of a core; it settles once indexing catches up" `
+            -Fix "Review background services" -Command "optimize" -Arguments @("-Services")
+    }
+
+    return $findings
+}
+
+function Test-SearchIndexSize {
+    <#
+    .SYNOPSIS
+        Windows Search database grown to many gigabytes
+    #>
+    $findings = @()
+
+    # The database folder is readable by administrators only
+    $dataDir = (Get-ItemProperty -Path "HKLM:\SOFTWARE\Microsoft\Windows Search" -Name DataDirectory -ErrorAction SilentlyContinue).DataDirectory
+    if (-not $dataDir) {
+        $dataDir = "$env:ProgramData\Microsoft\Search\Data\"
+    }
+    $dbDir = Join-Path ([Environment]::ExpandEnvironmentVariables($dataDir)) "Applications\Windows"
+    try {
+        $size = [long](Get-ChildItem -Path $dbDir -File -Recurse -ErrorAction Stop | Measure-Object -Property Length -Sum).Sum
+    }
+    catch {
+        Write-Debug "Could not read the search database folder: $_"
+        return $findings
+    }
+
+    # A few GB is normal for a busy profile; tens of GB means the indexer is
+    # churning through build output, mail archives or the like
+    if ($size -ge 8GB) {
+        $severity = if ($size -ge 20GB) { $script:SeverityWarning } else { $script:SeverityAdvice }
+        $findings += New-Finding -Severity $severity -Title "Search index is $(Format-ByteSize $size)" `
+            -Detail "Excluding folders that change constantly and rebuilding the index frees most of it" `
+            -Fix "Review the search index" -Command "optimize" -Arguments @("-Search")
+    }
+
+    return $findings
+}
+
+function Get-DoctorFindings {
+    <#
+    .SYNOPSIS
+        Run every check and return the findings, most urgent first
+    #>
+    $checks = @(
+        @{ Name = "Disk space"; Run = { Test-DiskSpace } }
+        @{ Name = "Disk health"; Run = { Test-DiskHealth } }
+        @{ Name = "Encryption"; Run = { Test-Encryption } }
+        @{ Name = "Storage redundancy"; Run = { Test-StorageRedundancy } }
+        @{ Name = "Thermal throttling"; Run = { Test-ThermalThrottling } }
+        @{ Name = "Windows updates"; Run = { Test-PendingUpdates } }
+        @{ Name = "Startup programs"; Run = { Test-StartupBloat } }
+        @{ Name = "Background indexing"; Run = { Test-BackgroundIndexing } }
+        @{ Name = "Search index size"; Run = { Test-SearchIndexSize } }
+    )
+
+    $gray = $script:Colors.Gray
+    $nc = $script:Colors.NC
+    $findings = @()
+
+    foreach ($check in $checks) {
+        Write-Host "  ${gray}Checking $($check.Name.ToLower())...${nc}"
+        try {
+            $findings += @(& $check.Run)
+        }
+        catch {
+            Write-Debug "$($check.Name) check failed: $_"
+        }
+    }
+
+    # Most urgent first, keeping the order of the checks within a severity
+    return @(foreach ($severity in $script:SeverityCritical..$script:SeverityAdvice) {
+        $findings | Where-Object { $_.Severity -eq $severity }
+    })
+}
+
+# ============================================================================
+# Report and Fixes
+# ============================================================================
+
+function Show-DoctorFindings {
+    <#
+    .SYNOPSIS
+        List the findings, numbering those with a fix
+    #>
+    param([array]$Findings)
+
+    $red = $script:Colors.Red
+    $yellow = $script:Colors.Yellow
+    $cyan = $script:Colors.Cyan
+    $green = $script:Colors.Green
+    $gray = $script:Colors.Gray
+    $nc = $script:Colors.NC
+
+    Start-Section "Findings"
+
+    if ($Findings.Count -eq 0) {
+        Write-Success "No problems found"
+        Stop-Section
+        return
+    }
+    Set-SectionActivity
+
+    for ($i = 0; $i -lt $Findings.Count; $i++) {
+        $finding = $Findings[$i]
+        $color = switch ($finding.Severity) {
+            $script:SeverityCritical { $red }
+            $script:SeverityWarning { $yellow }
+            default { $cyan }
+        }
+        $key = if ($finding.Fix -and ($finding.Command -or $finding.Action)) { "[$($i + 1)]" } else { "   " }
+
+        Write-Host ""
+        Write-Host "  ${cyan}$key${nc} ${color}$($script:Icons.Warning) $($finding.Title)${nc}"
+        Write-Host "      ${gray}$($finding.Detail)${nc}"
+        if ($finding.Fix) {
+            $mark = if ($finding.Done) { " ${green}$($script:Icons.Success)${nc}" } else { "" }
+            Write-Host "      ${gray}Fix:${nc} $($finding.Fix)$mark"
+        }
+    }
+
+    Stop-Section
+}
+
+function Invoke-DoctorFix {
+    <#
+    .SYNOPSIS
+        Run a finding's fix
+    #>
+    param([hashtable]$Finding)
+
+    if ($Finding.Action) {
+        $arguments = @($Finding.Arguments)
+        & $Finding.Action @arguments
+        return
+    }
+
+    if (Test-CommandDisabled -Name $Finding.Command) {
+        Write-Error "The $($Finding.Command) command is disabled by your administrator (Group Policy)"
+        return
+    }
+
+    $arguments = @($Finding.Arguments)
+    if ((Test-DryRunMode) -and $Finding.Command -in @("clean", "optimize")) {
+        $arguments += "-DryRun"
+    }
+
+    Add-UsageRecord -Feature $Finding.Command
+    & (Join-Path $scriptDir "$($Finding.Command).ps1") @arguments
+}
+
+# ============================================================================
+# Main
+# ============================================================================
+
+function Main {
+    Initialize-WinMole
+
+    if ($Help) {
+        Show-DoctorHelp
+        return
+    }
+
+    if ($DryRun -or $env:WINMOLE_DRY_RUN -eq "1") {
+        Set-DryRunMode -Enabled $true
+        Write-Host ""
+        Write-Warning "DRY RUN MODE - Fixes will only be previewed"
+    }
+
+    Write-Host ""
+    $findings = @(Get-DoctorFindings)
+
+    if ($Report) {
+        Show-EncryptionStatus
+        Show-StorageStatus
+        Show-DoctorFindings -Findings $findings
+        Write-Host ""
+        return
+    }
+
+    $gray = $script:Colors.Gray
+    $nc = $script:Colors.NC
+
+    while ($true) {
+        Clear-Host
+        Show-EncryptionStatus
+        Show-StorageStatus
+        Show-DoctorFindings -Findings $findings
+
+        $fixable = @(for ($i = 0; $i -lt $findings.Count; $i++) {
+            if ($findings[$i].Fix -and ($findings[$i].Command -or $findings[$i].Action)) { $i + 1 }
+        })
+        if ($fixable.Count -eq 0) {
+            Write-Host ""
+            return
+        }
+
+        Write-Host ""
+        Write-Host "  ${gray}Press a number to run its fix, q to quit${nc}"
+
+        $key = $Host.UI.RawUI.ReadKey("NoEcho,IncludeKeyDown")
+        if ($key.Character -eq 'q' -or $key.VirtualKeyCode -eq 27) {
+            Write-Host ""
+            return
+        }
+
+        $choice = 0
+        if ([int]::TryParse([string]$key.Character, [ref]$choice) -and $choice -in $fixable) {
+            $finding = $findings[$choice - 1]
+            Clear-Host
+            Invoke-DoctorFix -Finding $finding
+            $finding.Done = $true
+
+            Write-Host ""
+            Write-Host "  ${gray}Press any key to return to the findings${nc}"
+            [void]$Host.UI.RawUI.ReadKey("NoEcho,IncludeKeyDown")
+        }
+    }
+}
+
+# Run
+try {
+    Main
+}
+finally {
+    Clear-TempFiles
+}
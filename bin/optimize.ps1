@@ -8,6 +8,9 @@ param(
     [switch]$Services,
     [switch]$Startup,
     [switch]$Network,
+    [switch]$Search,
+    [string[]]$Exclude,
+    [switch]$Rebuild,
     [switch]$DryRun,
     [switch]$Help
 )
@@ -21,6 +24,7 @@ $libDir = Join-Path (Split-Path -Parent $scriptDir) "lib"
 
 # Import modules
 . "$libDir\core\common.ps1"
+Assert-CommandEnabled -Name "optimize"
 
 # ============================================================================
 # Help
@@ -43,6 +47,9 @@ function Show-OptimizeHelp {
     Write-Host "    -Services       Optimize Windows services"
     Write-Host "    -Startup        Manage startup programs"
     Write-Host "    -Network        Reset network configuration"
+    Write-Host "    -Search         Windows Search index size and locations"
+    Write-Host "      -Exclude <path>  Stop indexing a folder (with -Search)"
+    Write-Host "      -Rebuild         Rebuild the index from scratch (with -Search)"
     Write-Host "    -DryRun         Preview changes without applying"
     Write-Host "    -Help           Show this help"
     Write-Host ""
@@ -50,6 +57,7 @@ function Show-OptimizeHelp {
     Write-Host "    winmole optimize           # Interactive mode"
     Write-Host "    winmole optimize -All      # Run all optimizations"
     Write-Host "    winmole optimize -Startup  # Manage startup items"
+    Write-Host "    winmole optimize -Search -Exclude D:\Builds"
     Write-Host ""
 }
 
@@ -410,6 +418,219 @@ function Reset-NetworkConfig {
     Stop-Section
 }
 
+# ============================================================================
+# Search Index
+# ============================================================================
+
+$script:SearchRegPath = "HKLM:\SOFTWARE\Microsoft\Windows Search"
+
+function Get-SearchIndexStatus {
+    <#
+    .SYNOPSIS
+        Windows Search database size and the locations it covers
+    .DESCRIPTION
+        The database is Windows.edb up to Windows 10 and a set of Windows*.db
+        files on Windows 11, all under Applications\Windows in the data
+        directory. That folder is readable by administrators only, so Size
+        is $null otherwise. Locations come from the crawl scope rules the
+        Indexing Options dialog edits.
+    #>
+    $status = [pscustomobject]@{
+        Service   = $null
+        Directory = $null
+        Database  = $null
+        Size      = $null
+        Locations = @()
+    }
+
+    $service = Get-Service -Name WSearch -ErrorAction SilentlyContinue
+    if ($service) {
+        $status.Service = "$($service.Status)"
+    }
+
+    $dataDir = (Get-ItemProperty -Path $script:SearchRegPath -Name DataDirectory -ErrorAction SilentlyContinue).DataDirectory
+    if (-not $dataDir) {
+        $dataDir = "$env:ProgramData\Microsoft\Search\Data\"
+    }
+    $status.Directory = Join-Path ([Environment]::ExpandEnvironmentVariables($dataDir)) "Applications\Windows"
+    try {
+        $files = @(Get-ChildItem -Path $status.Directory -File -Recurse -ErrorAction Stop)
+        $status.Size = [long]($files | Measure-Object -Property Length -Sum).Sum
+        $main = $files | Where-Object { $_.Name -in "Windows.edb", "Windows.db" } | Select-Object -First 1
+        if ($main) {
+            $status.Database = $main.FullName
+        }
+    }
+    catch {
+        Write-Debug "Could not read the search database folder: $_"
+    }
+
+    $rules = "$script:SearchRegPath\CrawlScopeManager\Windows\SystemIndex\WorkingSetRules"
+    $status.Locations = @(Get-ChildItem -Path $rules -ErrorAction SilentlyContinue | ForEach-Object {
+        $rule = Get-ItemProperty -Path $_.PSPath -ErrorAction SilentlyContinue
+        if ($rule -and $rule.PSObject.Properties["URL"] -and $rule.URL -like "file:*") {
+            [pscustomobject]@{
+                Path    = ($rule.URL -replace "^file:///", "").TrimEnd("*")
+                Include = $rule.PSObject.Properties["Include"] -and $rule.Include -eq 1
+            }
+        }
+    })
+
+    return $status
+}
+
+function Show-SearchIndex {
+    <#
+    .SYNOPSIS
+        Display the search index size and indexed locations
+    #>
+    param($Status)
+
+    $cyan = $script:Colors.Cyan
+    $gray = $script:Colors.Gray
+    $nc = $script:Colors.NC
+
+    Start-Section "Search Index"
+
+    if (-not $Status.Service) {
+        Write-Info "Windows Search is not installed"
+        Stop-Section
+        return
+    }
+    Write-Host "  Windows Search service: $($Status.Service)"
+    if ($null -ne $Status.Size) {
+        $where = if ($Status.Database) { $Status.Database } else { $Status.Directory }
+        Write-Host "  Index size: ${cyan}$(Format-ByteSize $Status.Size)${nc} ${gray}($where)${nc}"
+        if ($Status.Size -ge 20GB) {
+            Write-Warning "The index has grown far beyond its usual size; excluding busy folders and rebuilding shrinks it"
+        }
+    }
+    else {
+        Write-Host "  Index size: ${gray}run as administrator to read it${nc}"
+    }
+
+    $included = @($Status.Locations | Where-Object { $_.Include })
+    $excluded = @($Status.Locations | Where-Object { -not $_.Include })
+    Write-Host ""
+    Write-Host "  ${cyan}Indexed:${nc}"
+    foreach ($location in $included) {
+        Write-Host "    $($location.Path)"
+    }
+    if ($excluded.Count -gt 0) {
+        Write-Host "  ${cyan}Excluded:${nc}"
+        foreach ($location in $excluded) {
+            Write-Host "    ${gray}$($location.Path)${nc}"
+        }
+    }
+
+    Stop-Section
+}
+
+function Add-SearchExclusion {
+    <#
+    .SYNOPSIS
+        Stop Windows Search from indexing a folder
+    .DESCRIPTION
+        Sets the "not content indexed" attribute on the folder and everything
+        in it, which is what clearing "Allow files in this folder to have
+        contents indexed" in its properties does. The indexer drops the
+        files as it notices the change; no admin rights are needed for
+        folders you own.
+    #>
+    param([string]$Path)
+
+    if (-not (Test-Path -LiteralPath $Path -PathType Container)) {
+        Write-Warning "Not a folder: $Path"
+        return
+    }
+    $folder = (Resolve-Path -LiteralPath $Path).ProviderPath
+
+    if (Test-DryRunMode) {
+        Write-DryRun "Would stop indexing $folder"
+        return
+    }
+
+    Write-Info "Excluding $folder from the search index..."
+    & attrib.exe +I $folder | Out-Null
+    & attrib.exe +I (Join-Path $folder "*") /S /D | Out-Null
+    if ($LASTEXITCODE -ne 0) {
+        Write-Warning "Some items in $folder could not be excluded"
+        return
+    }
+    Write-Success "Excluded $folder; the index shrinks as Windows Search catches up"
+}
+
+function Reset-SearchIndex {
+    <#
+    .SYNOPSIS
+        Rebuild the Windows Search index from scratch
+    .DESCRIPTION
+        Clearing SetupCompletedSuccessfully makes the service delete its
+        database and start over when it next starts. Searches return little
+        until indexing has caught up, which can take hours.
+    #>
+    if (-not (Test-IsAdmin)) {
+        Write-Warning "Rebuilding the search index requires administrator privileges"
+        return
+    }
+
+    if (Test-DryRunMode) {
+        Write-DryRun "Would rebuild the Windows Search index"
+        return
+    }
+
+    try {
+        Write-Info "Stopping Windows Search..."
+        Stop-Service -Name WSearch -Force -ErrorAction Stop
+        Set-ItemProperty -Path $script:SearchRegPath -Name SetupCompletedSuccessfully -Value 0 -Type DWord -ErrorAction Stop
+        Start-Service -Name WSearch -ErrorAction Stop
+        Write-Success "Search index reset; Windows Search is rebuilding it in the background"
+    }
+    catch {
+        Write-Warning "Could not rebuild the search index: $_"
+        Start-Service -Name WSearch -ErrorAction SilentlyContinue
+    }
+}
+
+function Optimize-SearchIndex {
+    <#
+    .SYNOPSIS
+        Show the search index, then exclude folders or rebuild it
+    #>
+    param(
+        [string[]]$ExcludePaths,
+        [switch]$RebuildIndex,
+        [switch]$Interactive
+    )
+
+    $status = Get-SearchIndexStatus
+    Show-SearchIndex -Status $status
+    if (-not $status.Service) {
+        return
+    }
+
+    foreach ($path in $ExcludePaths) {
+        Add-SearchExclusion -Path $path
+    }
+    if ($RebuildIndex) {
+        Reset-SearchIndex
+    }
+    if (-not $Interactive -or $ExcludePaths -or $RebuildIndex) {
+        return
+    }
+
+    while (Read-Confirmation -Prompt "Exclude a folder from the index?" -Default $false) {
+        $path = (Read-Host "  Folder").Trim().Trim('"')
+        if ($path) {
+            Add-SearchExclusion -Path $path
+        }
+    }
+    $bloated = $null -ne $status.Size -and $status.Size -ge 20GB
+    if (Read-Confirmation -Prompt "Rebuild the index from scratch?" -Default $bloated) {
+        Reset-SearchIndex
+    }
+}
+
 # ============================================================================
 # System Health Check
 # ============================================================================
@@ -468,6 +689,7 @@ function Show-OptimizeMenu {
         @{ Name = "Service Optimization"; Description = "Disable unnecessary services"; Action = "services" }
         @{ Name = "Startup Management"; Description = "View/disable startup programs"; Action = "startup" }
         @{ Name = "Network Reset"; Description = "Reset network configuration"; Action = "network" }
+        @{ Name = "Search Index"; Description = "Index size and locations, exclude or rebuild"; Action = "search" }
         @{ Name = "System Health Check"; Description = "Check system status"; Action = "health" }
         @{ Name = "Run All"; Description = "All optimizations"; Action = "all" }
     )
@@ -504,9 +726,10 @@ function Main {
     $runServices = $false
     $runStartup = $false
     $runNetwork = $false
+    $runSearch = $false
     $runHealth = $false
     
-    $noFlags = -not ($All -or $Defrag -or $Services -or $Startup -or $Network)
+    $noFlags = -not ($All -or $Defrag -or $Services -or $Startup -or $Network -or $Search)
     
     if ($noFlags) {
         Clear-Host
@@ -524,6 +747,7 @@ function Main {
             "services" { $runServices = $true }
             "startup" { $runStartup = $true }
             "network" { $runNetwork = $true }
+            "search" { $runSearch = $true }
             "health" { $runHealth = $true }
             "all" {
                 $runDefrag = $true
@@ -539,6 +763,7 @@ function Main {
             $runServices = $true
             $runStartup = $true
             $runNetwork = $true
+            $runSearch = $true
             $runHealth = $true
         }
         else {
@@ -546,6 +771,7 @@ function Main {
             $runServices = $Services
             $runStartup = $Startup
             $runNetwork = $Network
+            $runSearch = $Search
         }
     }
     
@@ -561,6 +787,7 @@ function Main {
         }
     }
     if ($runNetwork) { Reset-NetworkConfig }
+    if ($runSearch) { Optimize-SearchIndex -ExcludePaths $Exclude -RebuildIndex:$Rebuild -Interactive:($noFlags -or $Search) }
     
     Write-Host ""
     Write-Success "Optimization complete"
@@ -18,6 +18,7 @@ $libDir = Join-Path (Split-Path -Parent $scriptDir) "lib"
 
 # Import modules
 . "$libDir\core\common.ps1"
+Assert-CommandEnabled -Name "purge"
 
 # ============================================================================
 # Project Artifact Definitions
@@ -426,6 +427,7 @@ function Main {
     
     # Summary
     $stats = Get-CleanupStats
+    Add-UsageRecord -Feature "purge" -Kind freed -Bytes ($stats.TotalSizeKB * 1024)
     Show-Summary -SizeBytes ($stats.TotalSizeKB * 1024) -ItemCount $stats.TotalItems -Action $(if (Test-DryRunMode) { "Would clean" } else { "Cleaned" })
 }
 
@@ -0,0 +1,196 @@
+#!/usr/bin/env pwsh
+# WinMole - Fleet Server
+# Wrapper for Go TUI application
+
+#Requires -Version 5.1
+param(
+    [switch]$Help,
+    
+    # Flags passed through to server.exe (e.g. --no-tui)
+    [Parameter(ValueFromRemainingArguments)]
+    [string[]]$ToolArgs
+)
+
+$ErrorActionPreference = "Stop"
+Set-StrictMode -Version Latest
+
+# Get script directory
+$script:WINMOLE_ROOT = Split-Path -Parent (Split-Path -Parent $MyInvocation.MyCommand.Path)
+$script:WINMOLE_LIB = Join-Path $script:WINMOLE_ROOT "lib"
+$script:WINMOLE_CMD = Join-Path $script:WINMOLE_ROOT "cmd"
+
+# Import core
+. "$script:WINMOLE_LIB\core\common.ps1"
+Assert-CommandEnabled -Name "server"
+
+# ============================================================================
+# Help
+# ============================================================================
+
+function Show-ServerHelp {
+    $cyan = $script:Colors.Cyan
+    $gray = $script:Colors.Gray
+    $green = $script:Colors.Green
+    $nc = $script:Colors.NC
+    
+    Write-Host ""
+    Write-Host "  ${green}SERVER${nc} - Fleet Server"
+    Write-Host ""
+    Write-Host "  ${gray}Collects the scans and metrics other PCs push and shows them in one place${nc}"
+    Write-Host ""
+    Write-Host "  ${green}USAGE:${nc}"
+    Write-Host ""
+    Write-Host "    winmole server [--listen <address>] [--data <dir>] [--no-tui] [--profile <name>]"
+    Write-Host ""
+    Write-Host "  ${green}OPTIONS:${nc}"
+    Write-Host ""
+    Write-Host "    ${cyan}--listen${nc}      Address to listen on (default: server.listen in config.json, else :8740)"
+    Write-Host "    ${cyan}--data${nc}        Directory the per-host history is kept in (default: server in the cache directory)"
+    Write-Host "    ${cyan}--no-tui${nc}      Log each received batch instead of showing the fleet view (for a service)"
+    Write-Host "    ${cyan}--profile${nc}     Use a named settings profile from config.json"
+    Write-Host ""
+    Write-Host "  ${gray}Agents send server.token from their config.json; the server refuses to start without one.${nc}"
+    Write-Host "  ${gray}The web page at http://<server>:8740/ asks for it as the password, with any user name.${nc}"
+    Write-Host ""
+    Write-Host "  ${green}CONTROLS:${nc}"
+    Write-Host ""
+    Write-Host "    ${cyan}Up/Down${nc}         Select a host"
+    Write-Host "    ${cyan}Enter${nc}           Cards, CPU history and the biggest changes of each scanned folder"
+    Write-Host "    ${cyan}Esc${nc}             Back to the host list"
+    Write-Host "    ${cyan}r${nc}               Refresh"
+    Write-Host "    ${cyan}q${nc}               Quit"
+    Write-Host ""
+    Write-Host "  ${green}EXAMPLES:${nc}"
+    Write-Host ""
+    Write-Host "    ${gray}winmole server${nc}                       ${gray}# Fleet view, listening on :8740${nc}"
+    Write-Host "    ${gray}winmole server --no-tui --listen :9000${nc} ${gray}# Headless, for a scheduled task${nc}"
+    Write-Host ""
+}
+
+# ============================================================================
+# Build and Run
+# ============================================================================
+
+function Get-GoBinaryPath {
+    $binaryName = "server.exe"
+    $binPath = Join-Path $script:WINMOLE_ROOT "bin"
+    return Join-Path $binPath $binaryName
+}
+
+function Build-ServerTool {
+    $srcPath = Join-Path $script:WINMOLE_CMD "server"
+    $binaryPath = Get-GoBinaryPath
+    
+    Write-Info "Building fleet server..."
+    
+    # Check if Go is installed
+    $goCmd = Get-Command "go" -ErrorAction SilentlyContinue
+    if (-not $goCmd) {
+        Write-Error "Go is not installed or not in PATH"
+        Write-Host ""
+        Write-Host "  Install Go from: https://go.dev/dl/"
+        Write-Host ""
+        return $false
+    }
+    
+    # Build the binary
+    try {
+        Push-Location $srcPath
+        
+        # Download dependencies if needed
+        if (-not (Test-Path (Join-Path $script:WINMOLE_ROOT "go.sum"))) {
+            Write-Info "Downloading dependencies..."
+            & go mod tidy 2>&1 | Out-Null
+        }
+        
+        # Build
+        $env:CGO_ENABLED = "0"
+        $buildOutput = & go build -ldflags="-s -w" -o $binaryPath . 2>&1
+        
+        if ($LASTEXITCODE -ne 0) {
+            Write-Error "Build failed: $buildOutput"
+            return $false
+        }
+        
+        Write-Success "Build complete"
+        return $true
+    }
+    catch {
+        Write-Error "Build failed: $_"
+        return $false
+    }
+    finally {
+        Pop-Location
+    }
+}
+
+function Invoke-ServerTool {
+    param([string[]]$Arguments)
+    
+    $binaryPath = Get-GoBinaryPath
+    
+    # Build if binary doesn't exist or any source file is newer
+    $srcDirs = @(
+        (Join-Path $script:WINMOLE_CMD "server"),
+        (Join-Path $script:WINMOLE_ROOT "internal"),
+        (Join-Path $script:WINMOLE_ROOT "pkg")
+    )
+    $needsBuild = $false
+    
+    if (-not (Test-Path $binaryPath)) {
+        $needsBuild = $true
+    }
+    else {
+        $binaryTime = (Get-Item $binaryPath).LastWriteTime
+        $newer = Get-ChildItem -Path $srcDirs -Filter *.go -Recurse -ErrorAction SilentlyContinue |
+            Where-Object { $_.LastWriteTime -gt $binaryTime }
+        if ($newer) {
+            $needsBuild = $true
+        }
+    }
+    
+    if ($needsBuild) {
+        if (-not (Build-ServerTool)) {
+            return
+        }
+    }
+    
+    # Run the server
+    $serverArgs = @()
+    if ($Arguments) {
+        $serverArgs += $Arguments
+    }
+    
+    & $binaryPath @serverArgs
+    if ($LASTEXITCODE -ne 0) {
+        exit $LASTEXITCODE
+    }
+}
+
+# ============================================================================
+# Main
+# ============================================================================
+
+function Main {
+    # Initialize
+    Initialize-WinMole
+    
+    if ($Help) {
+        Show-ServerHelp
+        return
+    }
+    
+    # Run the fleet server
+    Invoke-ServerTool -Arguments $ToolArgs
+}
+
+# Run
+try {
+    Main
+}
+catch {
+    Write-Host ""
+    Write-Error "An error occurred: $_"
+    Write-Host ""
+    exit 1
+}
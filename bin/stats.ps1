@@ -0,0 +1,208 @@
+# WinMole - Stats Command
+# Local usage statistics: space reclaimed over time and most-used features
+
+#Requires -Version 5.1
+param(
+    [int]$Months = 6,
+    [switch]$Reset,
+    [switch]$Help
+)
+
+$ErrorActionPreference = "Stop"
+Set-StrictMode -Version Latest
+
+# Get script location
+$scriptDir = Split-Path -Parent $MyInvocation.MyCommand.Path
+$libDir = Join-Path (Split-Path -Parent $scriptDir) "lib"
+
+# Import modules
+. "$libDir\core\common.ps1"
+Assert-CommandEnabled -Name "stats"
+
+# ============================================================================
+# Help
+# ============================================================================
+
+function Show-StatsHelp {
+    $cyan = $script:Colors.Cyan
+    $gray = $script:Colors.Gray
+    $nc = $script:Colors.NC
+    
+    Write-Host ""
+    Write-Host "  ${cyan}WinMole Stats${nc} - Local usage statistics"
+    Write-Host ""
+    Write-Host "  ${gray}USAGE:${nc}"
+    Write-Host "    winmole stats [options]"
+    Write-Host ""
+    Write-Host "  ${gray}OPTIONS:${nc}"
+    Write-Host "    -Months <n>     Months of history to chart (default: 6)"
+    Write-Host "    -Reset          Delete the recorded statistics"
+    Write-Host "    -Help           Show this help"
+    Write-Host ""
+    Write-Host "  ${gray}Statistics are stored only on this computer and never sent anywhere.${nc}"
+    Write-Host ""
+}
+
+# ============================================================================
+# Display
+# ============================================================================
+
+# Friendly names for recorded features
+$script:FeatureNames = @{
+    "clean"            = "Clean"
+    "uninstall"        = "Uninstall"
+    "analyze"          = "Analyze"
+    "analyze.scan"     = "Analyze: folder scans"
+    "analyze.export"   = "Analyze: exports"
+    "analyze.headless" = "Analyze: headless reports"
+    "analyze.drift"    = "Analyze: baseline comparisons"
+    "analyze.import"   = "Analyze: imported reports"
+    "analyze.types"    = "Analyze: file-type breakdowns"
+    "analyze.owners"   = "Analyze: by-owner breakdowns"
+    "analyze.largest"  = "Analyze: largest-files lists"
+    "analyze.denied"   = "Analyze: inaccessible-item lists"
+    "analyze.filter"   = "Analyze: filters and searches"
+    "analyze.exclude"  = "Analyze: excluded entries"
+    "analyze.recycle"  = "Analyze: moved to Recycle Bin"
+    "analyze.delete"   = "Analyze: permanent deletes"
+    "analyze.move"     = "Analyze: moved entries"
+    "analyze.relocate" = "Analyze: relocated to other drives"
+    "status"           = "Status"
+    "optimize"         = "Optimize"
+    "doctor"           = "Doctor"
+    "purge"            = "Purge"
+    "stats"            = "Stats"
+    "server"           = "Server"
+}
+
+function Get-FeatureName {
+    param([string]$Feature)
+    
+    if ($script:FeatureNames.ContainsKey($Feature)) {
+        return $script:FeatureNames[$Feature]
+    }
+    return $Feature
+}
+
+function Show-ReclaimedByMonth {
+    param(
+        [object[]]$Freed,
+        [int]$Months
+    )
+    
+    $cyan = $script:Colors.Cyan
+    $gray = $script:Colors.Gray
+    $nc = $script:Colors.NC
+    
+    Write-Host "  ${gray}Reclaimed per month${nc}"
+    Write-Host ""
+    
+    $start = (Get-Date -Day 1).Date.AddMonths(-($Months - 1))
+    $rows = for ($i = 0; $i -lt $Months; $i++) {
+        $month = $start.AddMonths($i)
+        $bytes = ($Freed | Where-Object { $_.At -ge $month -and $_.At -lt $month.AddMonths(1) } |
+            Measure-Object Bytes -Sum).Sum
+        [PSCustomObject]@{ Month = $month; Bytes = [long]$bytes }
+    }
+    
+    $max = ($rows | Measure-Object Bytes -Maximum).Maximum
+    foreach ($row in $rows) {
+        $width = if ($max -gt 0) { [int][math]::Round($row.Bytes / $max * 30) } else { 0 }
+        $bar = ([string][char]0x2588) * $width + ([string][char]0x2591) * (30 - $width)
+        Write-Host ("    {0}  {1}{2}{3}  {4}" -f $row.Month.ToString("MMM yyyy"), $cyan, $bar, $nc, (Format-ByteSize $row.Bytes))
+    }
+    Write-Host ""
+}
+
+function Show-TopFeatures {
+    param([object[]]$Runs)
+    
+    $gray = $script:Colors.Gray
+    $nc = $script:Colors.NC
+    
+    Write-Host "  ${gray}Most used${nc}"
+    Write-Host ""
+    
+    $groups = $Runs | Group-Object Feature | Sort-Object Count -Descending | Select-Object -First 8
+    foreach ($group in $groups) {
+        Write-Host ("    {0,-34} {1,5}x" -f (Get-FeatureName $group.Name), $group.Count)
+    }
+    Write-Host ""
+}
+
+function Show-UsageStats {
+    param([int]$Months)
+    
+    $green = $script:Colors.Green
+    $cyan = $script:Colors.Cyan
+    $gray = $script:Colors.Gray
+    $nc = $script:Colors.NC
+    
+    $records = @(Get-UsageRecords)
+    
+    Show-Header -Title "Your WinMole Stats" -Subtitle "Stored only on this computer - nothing is sent anywhere"
+    
+    if ($records.Count -eq 0) {
+        Write-Host "  No activity recorded yet. Run a cleanup or scan and come back."
+        Write-Host ""
+        return
+    }
+    
+    $freed = @($records | Where-Object { $_.Kind -eq "freed" })
+    $runs = @($records | Where-Object { $_.Kind -eq "run" })
+    $total = [long](($freed | Measure-Object Bytes -Sum).Sum)
+    $since = ($records | Measure-Object At -Minimum).Minimum
+    $scans = @($runs | Where-Object { $_.Feature -eq "analyze.scan" }).Count
+    $cleanups = @($runs | Where-Object { $_.Feature -in @("clean", "purge") }).Count
+    
+    Write-Host "  $($green)━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━$($nc)"
+    Write-Host "  $($green)$($script:Icons.Success)$($nc) You've reclaimed $($cyan)$(Format-ByteSize $total)$($nc) with WinMole"
+    Write-Host "  $($green)━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━$($nc)"
+    Write-Host ""
+    Write-Host "  ${gray}Since:${nc}        $($since.ToString("d MMM yyyy"))"
+    Write-Host "  ${gray}Cleanups run:${nc} $cleanups"
+    Write-Host "  ${gray}Folder scans:${nc} $scans"
+    Write-Host ""
+    
+    Show-ReclaimedByMonth -Freed $freed -Months $Months
+    
+    if ($runs.Count -gt 0) {
+        Show-TopFeatures -Runs $runs
+    }
+    
+    Write-Host "  ${gray}Data: $(Get-UsageFile)${nc}"
+    Write-Host ""
+}
+
+# ============================================================================
+# Main
+# ============================================================================
+
+function Main {
+    if ($Help) {
+        Show-StatsHelp
+        return
+    }
+    
+    if ($Reset) {
+        $path = Get-UsageFile
+        if ((Test-Path $path) -and (Read-Confirmation -Prompt "Delete all recorded statistics?" -Default $false)) {
+            Remove-Item $path -Force
+            Write-Success "Statistics cleared"
+        }
+        return
+    }
+    
+    if ($Months -lt 1) {
+        $Months = 1
+    }
+    Show-UsageStats -Months $Months
+}
+
+# Run
+try {
+    Main
+}
+finally {
+    Clear-TempFiles
+}
@@ -4,7 +4,11 @@
 
 #Requires -Version 5.1
 param(
-    [switch]$Help
+    [switch]$Help,
+    
+    # Flags passed through to status.exe (e.g. --oneline)
+    [Parameter(ValueFromRemainingArguments)]
+    [string[]]$ToolArgs
 )
 
 $ErrorActionPreference = "Stop"
@@ -17,6 +21,7 @@ $script:WINMOLE_CMD = Join-Path $script:WINMOLE_ROOT "cmd"
 
 # Import core
 . "$script:WINMOLE_LIB\core\common.ps1"
+Assert-CommandEnabled -Name "status"
 
 # ============================================================================
 # Help
@@ -35,26 +40,65 @@ function Show-StatusHelp {
     Write-Host ""
     Write-Host "  ${green}USAGE:${nc}"
     Write-Host ""
-    Write-Host "    winmole status"
+    Write-Host "    winmole status [--oneline] [--interval <duration>] [--timeout <duration>] [--redact] [--profile <name>]"
+    Write-Host "    winmole status --push [--samples <n>] [--interval <duration>] [--timeout <duration>]"
+    Write-Host "    winmole status --otlp [--samples <n>] [--interval <duration>] [--timeout <duration>]"
+    Write-Host "    winmole status --port <number>"
+    Write-Host "    winmole status --web <address>"
+    Write-Host ""
+    Write-Host "  ${green}OPTIONS:${nc}"
     Write-Host ""
-    Write-Host "  ${green}DISPLAYS:${nc}"
+    Write-Host "    ${cyan}--oneline${nc}     Print one summary line and exit (for prompts/status bars)"
+    Write-Host "    ${cyan}--interval${nc}    Sampling window for --oneline, --push and --otlp rates (default: 1s)"
+    Write-Host "    ${cyan}--timeout${nc}     Give up --oneline, --push or --otlp sampling after this long (exit 124)"
+    Write-Host "    ${cyan}--push${nc}        Send a batch of samples to the server in config.json and exit"
+    Write-Host "    ${cyan}--otlp${nc}        Export a batch of samples to the OTLP endpoint in config.json and exit"
+    Write-Host "    ${cyan}--samples${nc}     Samples --push and --otlp take, one per --interval (default: 10)"
+    Write-Host "    ${cyan}--redact${nc}      Mask computer name, user names and IP addresses (for screenshots)"
+    Write-Host "    ${cyan}--profile${nc}     Use a named settings profile from config.json"
+    Write-Host "    ${cyan}--port${nc}        Print the processes using a port and exit (exit 1 when none)"
+    Write-Host "    ${cyan}--web${nc}         Serve the cards and the latest scans to a browser (e.g. 127.0.0.1:8741) until Ctrl+C"
     Write-Host ""
-    Write-Host "    ${cyan}CPU${nc}        Usage per core with graph"
-    Write-Host "    ${cyan}Memory${nc}     RAM usage and availability"
-    Write-Host "    ${cyan}Disk${nc}       Drive usage and free space"
-    Write-Host "    ${cyan}Network${nc}    Bytes sent/received per interface"
-    Write-Host "    ${cyan}Processes${nc}  Top processes by CPU/memory"
+    Write-Host "  ${green}TABS:${nc}"
+    Write-Host ""
+    Write-Host "    ${cyan}Overview${nc}   CPU, memory, system drive and network cards"
+    Write-Host "    ${cyan}Processes${nc}  All processes by CPU or memory"
+    Write-Host "    ${cyan}Disks${nc}      Usage and read/write rates per volume"
+    Write-Host "    ${cyan}Network${nc}    Traffic per interface, connection counts, listening ports and quick fixes"
+    Write-Host "    ${cyan}History${nc}    Trend graphs for the last ten minutes"
+    Write-Host "    ${cyan}Energy${nc}     Apps that used the most energy/battery (prompts for elevation)"
+    Write-Host "    ${cyan}Apps${nc}       Per-app CPU, disk and network for the last 30+ days from SRUM (prompts for elevation)"
     Write-Host ""
     Write-Host "  ${green}CONTROLS:${nc}"
     Write-Host ""
-    Write-Host "    ${cyan}Tab${nc}        Cycle through panels"
-    Write-Host "    ${cyan}1-4${nc}        Jump to panel (CPU/Mem/Disk/Net)"
-    Write-Host "    ${cyan}r${nc}          Refresh now"
-    Write-Host "    ${cyan}q/Esc${nc}      Quit"
+    Write-Host "    ${cyan}Tab/Left/Right${nc}  Cycle through tabs"
+    Write-Host "    ${cyan}1-7${nc}             Jump to tab"
+    Write-Host "    ${cyan}e${nc}               Edit the Overview card layout"
+    Write-Host "    ${cyan}Up/Down${nc}         Scroll the process list"
+    Write-Host "    ${cyan}s${nc}               Sort processes by CPU/memory"
+    Write-Host "    ${cyan}a${nc}               Show cumulative CPU time and I/O per process"
+    Write-Host "    ${cyan}w/z${nc}             Change the totals window / reset the totals"
+    Write-Host "    ${cyan}v${nc}               Show automatic process snapshots"
+    Write-Host "    ${cyan}v${nc}               Disks: partition map of each physical disk"
+    Write-Host "    ${cyan}r${nc}               Reload energy data or app history"
+    Write-Host "    ${cyan}f/d/a/w${nc}         Network: flush DNS, renew DHCP, restart adapter, reset Winsock"
+    Write-Host "    ${cyan}o${nc}               Network: listening ports by process, conflicts flagged"
+    Write-Host "    ${cyan}/${nc}               Network: who is using a port"
+    Write-Host "    ${cyan}n${nc}               Network: devices on the local network (s sweeps the subnets)"
+    Write-Host "    ${cyan}S${nc}               Network: shares, client sessions and open files (c closes a file)"
+    Write-Host "    ${cyan}M${nc}               Network: mapped drives and whether their servers answer (c reconnect, e remap, u remove)"
+    Write-Host "    ${cyan}m${nc}               Drop a labelled marker into the history"
+    Write-Host "    ${cyan}p${nc}               Toggle redaction of names and IP addresses"
+    Write-Host "    ${cyan}P${nc}               Switch settings profile"
+    Write-Host "    ${cyan}x${nc}               Export history (with markers) to CSV"
+    Write-Host "    ${cyan}q/Esc${nc}           Quit"
     Write-Host ""
     Write-Host "  ${green}EXAMPLES:${nc}"
     Write-Host ""
-    Write-Host "    ${gray}winmole status${nc}    ${gray}# Launch system monitor${nc}"
+    Write-Host "    ${gray}winmole status${nc}              ${gray}# Launch system monitor${nc}"
+    Write-Host "    ${gray}winmole status --oneline${nc}    ${gray}# cpu 12% mem 48% C: 71% ...${nc}"
+    Write-Host "    ${gray}winmole status --port 3000${nc}  ${gray}# Who is holding port 3000${nc}"
+    Write-Host "    ${gray}winmole status --web 127.0.0.1:8741${nc}  ${gray}# Dashboard in a browser tab${nc}"
     Write-Host ""
 }
 
@@ -103,6 +147,15 @@ function Build-StatusTool {
             return $false
         }
         
+        # The elevated helper lives next to status.exe
+        $helperPath = Join-Path (Split-Path -Parent $binaryPath) "helper.exe"
+        $buildOutput = & go build -ldflags="-s -w" -o $helperPath ..\helper 2>&1
+        
+        if ($LASTEXITCODE -ne 0) {
+            Write-Error "Build failed: $buildOutput"
+            return $false
+        }
+        
         Write-Success "Build complete"
         return $true
     }
@@ -116,17 +169,31 @@ function Build-StatusTool {
 }
 
 function Invoke-StatusTool {
+    param([string[]]$Arguments)
+    
     $binaryPath = Get-GoBinaryPath
     
-    # Build if binary doesn't exist or source is newer
-    $srcPath = Join-Path $script:WINMOLE_CMD "status\main.go"
+    # Build if binary doesn't exist or any source file is newer
+    $srcDirs = @(
+        (Join-Path $script:WINMOLE_CMD "status"),
+        (Join-Path $script:WINMOLE_CMD "helper"),
+        (Join-Path $script:WINMOLE_ROOT "internal"),
+        (Join-Path $script:WINMOLE_ROOT "pkg")
+    )
+    $helperPath = Join-Path (Split-Path -Parent $binaryPath) "helper.exe"
     $needsBuild = $false
     
-    if (-not (Test-Path $binaryPath)) {
+    if (-not (Test-Path $binaryPath) -or -not (Test-Path $helperPath)) {
         $needsBuild = $true
     }
-    elseif ((Get-Item $srcPath).LastWriteTime -gt (Get-Item $binaryPath).LastWriteTime) {
-        $needsBuild = $true
+    else {
+        $binaryTime = (Get-Item $binaryPath).LastWriteTime
+        # The web page's files are built into status.exe too
+        $newer = Get-ChildItem -Path $srcDirs -Include *.go, *.html, *.js, *.css -Recurse -ErrorAction SilentlyContinue |
+            Where-Object { $_.LastWriteTime -gt $binaryTime }
+        if ($newer) {
+            $needsBuild = $true
+        }
     }
     
     if ($needsBuild) {
@@ -136,7 +203,16 @@ function Invoke-StatusTool {
     }
     
     # Run the monitor
-    & $binaryPath
+    $statusArgs = @()
+    if ($Arguments) {
+        $statusArgs += $Arguments
+    }
+    
+    & $binaryPath @statusArgs
+    # 124 = timed out, 130 = interrupted; scripts rely on these
+    if ($LASTEXITCODE -ne 0) {
+        exit $LASTEXITCODE
+    }
 }
 
 # ============================================================================
@@ -153,7 +229,7 @@ function Main {
     }
     
     # Run the status monitor
-    Invoke-StatusTool
+    Invoke-StatusTool -Arguments $ToolArgs
 }
 
 # Run
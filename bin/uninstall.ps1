@@ -19,6 +19,7 @@ $libDir = Join-Path (Split-Path -Parent $scriptDir) "lib"
 
 # Import modules
 . "$libDir\core\common.ps1"
+Assert-CommandEnabled -Name "uninstall"
 
 # ============================================================================
 # Help
@@ -0,0 +1,62 @@
+//go:build windows
+
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// z colors the size bars by when each entry was last modified, from green
+// for this month to red for five years and more, so old dead weight stands
+// out while browsing; the legend shows the ages instead of the file
+// categories. A folder's age is that of the folder itself, which changes
+// when files are added to or removed from it but not when a file deeper
+// down is edited.
+
+// ageBand is the color of entries modified within an age.
+type ageBand struct {
+	days  int // 0 for anything older than the bands before
+	label string
+	color string
+}
+
+var ageBands = []ageBand{
+	{30, "< 1 month", "46"},
+	{182, "< 6 months", "148"},
+	{365, "< 1 year", "184"},
+	{730, "< 2 years", "214"},
+	{1825, "< 5 years", "202"},
+	{0, "older", "196"},
+}
+
+// ageStyle colors a bar for an entry last modified at t; entries whose
+// time is unknown keep the plain bar color.
+func ageStyle(t time.Time) lipgloss.Style {
+	if t.IsZero() {
+		return barStyle
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(ageColor(t)))
+}
+
+// ageColor is the color of the band t falls in.
+func ageColor(t time.Time) string {
+	age := time.Since(t)
+	for _, band := range ageBands {
+		if band.days == 0 || age < time.Duration(band.days)*24*time.Hour {
+			return band.color
+		}
+	}
+	return ageBands[len(ageBands)-1].color
+}
+
+// ageLegend renders every band in its own color.
+func ageLegend() string {
+	parts := make([]string, 0, len(ageBands))
+	for _, band := range ageBands {
+		parts = append(parts, lipgloss.NewStyle().Foreground(lipgloss.Color(band.color)).Render("■ "+band.label))
+	}
+	return strings.Join(parts, "  ")
+}
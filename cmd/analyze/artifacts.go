@@ -0,0 +1,625 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/policy"
+	"github.com/winmole/winmole/internal/usage"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/scan"
+)
+
+// B finds the folders below the current one that builds and package
+// managers recreate: node_modules, virtual environments, target, bin and
+// obj, .gradle, pip and npm caches. A folder only counts when its project
+// says what it is, so a bin beside a .csproj is build output but a bin
+// of scripts is not. The folders are totalled by kind and listed largest
+// first. Space selects folders and a selects every folder of one kind; D
+// lists what would be deleted, and D again deletes it for good, since
+// the Recycle Bin would only keep the space in use. The Docker build
+// cache is reported alongside when Docker is running, and p prunes it.
+
+// artifactRule recognizes one kind of folder.
+type artifactRule struct {
+	names  []string // folder names, any case
+	kind   string
+	beside []string // one of these must be in the parent folder, if any
+	inside []string // one of these must be in the folder itself, if any
+	parent string   // the parent folder's name, if it matters
+}
+
+var gradleFiles = []string{"build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"}
+
+var artifactRules = []artifactRule{
+	{names: []string{"node_modules"}, kind: "Node.js dependencies", beside: []string{"package.json"}},
+	{names: []string{".next", ".nuxt", ".svelte-kit", ".angular", ".parcel-cache", ".turbo"}, kind: "Web framework caches", beside: []string{"package.json"}},
+	{names: []string{".venv", "venv", "env"}, kind: "Python virtual environments", inside: []string{"pyvenv.cfg"}},
+	{names: []string{"__pycache__", ".pytest_cache", ".mypy_cache", ".ruff_cache", ".tox", ".nox"}, kind: "Python caches"},
+	{names: []string{"target"}, kind: "Rust and Maven build output", beside: []string{"Cargo.toml", "pom.xml"}},
+	{names: []string{"bin", "obj"}, kind: ".NET build output", beside: []string{"*.csproj", "*.fsproj", "*.vbproj"}},
+	{names: []string{".gradle", "build"}, kind: "Gradle caches and build output", beside: gradleFiles},
+	{names: []string{"npm-cache", "go-build"}, kind: "Package manager caches"},
+	{names: []string{"cache"}, kind: "Package manager caches", parent: "pip"},
+	{names: []string{"cache"}, kind: "Package manager caches", parent: "yarn"},
+	{names: []string{"v3-cache"}, kind: "Package manager caches", parent: "NuGet"},
+}
+
+// matchArtifact returns the kind of the folder name in parent, or "".
+// hasBeside and hasInside report whether the parent and the folder itself
+// hold a file matching a pattern.
+func matchArtifact(name, parent string, hasBeside, hasInside func(pattern string) bool) string {
+	for _, r := range artifactRules {
+		if !containsFold(r.names, name) {
+			continue
+		}
+		if r.parent != "" && !strings.EqualFold(filepath.Base(parent), r.parent) {
+			continue
+		}
+		if len(r.beside) > 0 && !anyPattern(r.beside, hasBeside) {
+			continue
+		}
+		if len(r.inside) > 0 && !anyPattern(r.inside, hasInside) {
+			continue
+		}
+		return r.kind
+	}
+	return ""
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyPattern(patterns []string, has func(pattern string) bool) bool {
+	for _, p := range patterns {
+		if has(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// artifact is a folder found, with its kind.
+type artifact struct {
+	Entry
+	kind string
+}
+
+// artifactKind totals the folders of one kind.
+type artifactKind struct {
+	name  string
+	size  int64
+	count int
+}
+
+// artifactView lists the folders found below one folder.
+type artifactView struct {
+	found    []artifact // largest first
+	kinds    []artifactKind
+	docker   int64 // reclaimable Docker build cache; -1 when Docker is not running
+	chosen   map[string]bool
+	preview  bool // listing only what D would delete
+	pruning  bool
+	selected int
+	offset   int
+}
+
+type artifactResultMsg struct {
+	path   string
+	found  []artifact
+	docker int64
+	err    error
+}
+
+type dockerPrunedMsg struct {
+	docker int64 // measured again
+	err    error
+}
+
+func (m model) artifactCmd() tea.Cmd {
+	return func() tea.Msg {
+		found, err := findArtifacts(context.Background(), m.path, m.progress)
+		return artifactResultMsg{path: m.path, found: found, docker: dockerBuildCache(), err: err}
+	}
+}
+
+// findArtifacts collects the folders below path that artifactRules
+// recognize, from the MFT when it can be read and by walking otherwise.
+// A folder found is not looked into, so node_modules inside node_modules
+// count once.
+func findArtifacts(ctx context.Context, path string, progress *scan.Counters) ([]artifact, error) {
+	var found []artifact
+	fromMFT := false
+	if vol := mftVolume(path); vol != "" && progress.Exclude == nil {
+		if idx, err := loadMFTIndex(ctx, vol, progress); err == nil {
+			found, err = idx.artifacts(path)
+			fromMFT = err == nil
+		}
+	}
+	if !fromMFT {
+		found = nil
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+		filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return nil // Skip errors
+			}
+			if p == path {
+				return nil
+			}
+			if d.Type()&(fs.ModeSymlink|fs.ModeIrregular) != 0 && scan.LinkTarget(p) != "" {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if progress.Exclude != nil && progress.Exclude(p, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.IsDir() {
+				progress.Files.Add(1)
+				return nil
+			}
+			progress.Dirs.Add(1)
+			parent := filepath.Dir(p)
+			kind := matchArtifact(d.Name(), parent, dirHas(parent), dirHas(p))
+			if kind == "" {
+				return nil
+			}
+			t := scan.Tree(ctx, p, progress)
+			info, _ := d.Info()
+			e := Entry{Name: d.Name(), Path: p, Size: t.Size, Alloc: t.Alloc, Cloud: t.Cloud, Files: t.Files, Dirs: t.Dirs, IsDir: true}
+			if info != nil {
+				e.ModTime = info.ModTime()
+			}
+			found = append(found, artifact{Entry: e, kind: kind})
+			return filepath.SkipDir
+		})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Size > found[j].Size })
+	return found, nil
+}
+
+// dirHas reports whether dir holds a file matching a pattern.
+func dirHas(dir string) func(pattern string) bool {
+	return func(pattern string) bool {
+		if strings.ContainsAny(pattern, "*?[") {
+			matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+			return len(matches) > 0
+		}
+		_, err := os.Lstat(filepath.Join(dir, pattern))
+		return err == nil
+	}
+}
+
+// artifacts is findArtifacts over the MFT: the totals of a folder found
+// are already in its record.
+func (idx *mftIndex) artifacts(path string) ([]artifact, error) {
+	root, err := idx.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	has := func(rec uint32) func(pattern string) bool {
+		return func(pattern string) bool {
+			pattern = strings.ToLower(pattern)
+			for _, c := range idx.nodes[rec].children {
+				if ok, _ := filepath.Match(pattern, strings.ToLower(idx.nodes[c].name)); ok {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	type folder struct {
+		rec  uint32
+		path string
+	}
+	var found []artifact
+	stack := []folder{{root, filepath.Clean(path)}}
+	for len(stack) > 0 {
+		d := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, c := range idx.nodes[d.rec].children {
+			n := &idx.nodes[c]
+			if !n.isDir || n.reparse {
+				continue
+			}
+			p := filepath.Join(d.path, n.name)
+			kind := matchArtifact(n.name, d.path, has(d.rec), has(c))
+			if kind == "" {
+				stack = append(stack, folder{c, p})
+				continue
+			}
+			e := Entry{Name: n.name, Path: p, Size: n.size, Alloc: n.alloc, Cloud: n.cloud, Files: n.files, Dirs: n.dirs, IsDir: true}
+			e.ModTime, e.AccessTime = n.times()
+			found = append(found, artifact{Entry: e, kind: kind})
+		}
+	}
+	return found, nil
+}
+
+// dockerSize is one size in docker's output, such as 1.2GB or 512kB.
+var dockerSize = regexp.MustCompile(`^([0-9.]+)\s*([kKMGT]?B)`)
+
+// dockerBuildCache returns how much of Docker's build cache can be pruned,
+// or -1 when Docker is not installed or not running.
+func dockerBuildCache() int64 {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return -1
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "docker", "system", "df", "--format", "{{.Type}}\t{{.Reclaimable}}").Output()
+	if err != nil {
+		return -1
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		typ, size, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if !ok || typ != "Build Cache" {
+			continue
+		}
+		m := dockerSize.FindStringSubmatch(size)
+		if m == nil {
+			return 0
+		}
+		n, _ := strconv.ParseFloat(m[1], 64)
+		// Docker counts in powers of 1000.
+		scale := map[string]float64{"B": 1, "kB": 1e3, "KB": 1e3, "MB": 1e6, "GB": 1e9, "TB": 1e12}[m[2]]
+		return int64(n * scale)
+	}
+	return 0
+}
+
+func dockerPruneCmd() tea.Cmd {
+	return func() tea.Msg {
+		err := exec.Command("docker", "builder", "prune", "--force").Run()
+		return dockerPrunedMsg{docker: dockerBuildCache(), err: err}
+	}
+}
+
+// showArtifacts starts looking for the folders.
+func (m model) showArtifacts() (tea.Model, tea.Cmd) {
+	if m.imported != "" {
+		m.status = "Imported reports cannot be searched for build folders"
+		return m, nil
+	}
+	m.scanning = true
+	m.status = "Finding build output and dependency folders..."
+	m.progress.Files.Store(0)
+	m.progress.Dirs.Store(0)
+	m.progress.Bytes.Store(0)
+	return m, tea.Batch(m.artifactCmd(), tickCmd())
+}
+
+func (m model) applyArtifacts(msg artifactResultMsg) model {
+	usage.Run("analyze.artifacts")
+	m.artifacts = &artifactView{found: msg.found, docker: msg.docker, chosen: make(map[string]bool)}
+	m.artifacts.total()
+	m.status = m.artifacts.summary()
+	return m
+}
+
+func (m model) applyDockerPruned(msg dockerPrunedMsg) model {
+	v := m.artifacts
+	if v == nil {
+		return m
+	}
+	v.pruning = false
+	if msg.err != nil {
+		m.status = fmt.Sprintf("Error: docker builder prune: %v", msg.err)
+		return m
+	}
+	freed := max(v.docker, 0) - max(msg.docker, 0)
+	v.docker = msg.docker
+	usage.Freed("analyze.docker", max(freed, 0))
+	m.status = fmt.Sprintf("Pruned the Docker build cache • %s freed", humanize.Bytes(max(freed, 0)))
+	return m
+}
+
+// total adds up the folders by kind, largest kind first.
+func (v *artifactView) total() {
+	byKind := make(map[string]int)
+	v.kinds = v.kinds[:0]
+	for _, a := range v.found {
+		i, ok := byKind[a.kind]
+		if !ok {
+			i = len(v.kinds)
+			byKind[a.kind] = i
+			v.kinds = append(v.kinds, artifactKind{name: a.kind})
+		}
+		v.kinds[i].size += a.Size
+		v.kinds[i].count++
+	}
+	sort.SliceStable(v.kinds, func(i, j int) bool { return v.kinds[i].size > v.kinds[j].size })
+	v.selected = min(v.selected, max(len(v.rows())-1, 0))
+	v.offset = min(v.offset, v.selected)
+}
+
+// rows are the folders listed: all of them, or in the preview the ones
+// selected.
+func (v *artifactView) rows() []artifact {
+	if !v.preview {
+		return v.found
+	}
+	var rows []artifact
+	for _, a := range v.found {
+		if v.chosen[cacheKey(a.Path)] {
+			rows = append(rows, a)
+		}
+	}
+	return rows
+}
+
+// chosenEntries are the folders selected, largest first.
+func (v *artifactView) chosenEntries() []Entry {
+	var entries []Entry
+	for _, a := range v.found {
+		if v.chosen[cacheKey(a.Path)] {
+			entries = append(entries, a.Entry)
+		}
+	}
+	return entries
+}
+
+// summary is the status line of the view.
+func (v *artifactView) summary() string {
+	var total int64
+	for _, k := range v.kinds {
+		total += k.size
+	}
+	s := fmt.Sprintf("%d build and dependency folders • %s", len(v.found), humanize.Bytes(total))
+	if v.docker > 0 {
+		s += fmt.Sprintf(" • Docker build cache %s", humanize.Bytes(v.docker))
+	}
+	if chosen := v.chosenEntries(); len(chosen) > 0 {
+		var size int64
+		for _, e := range chosen {
+			size += e.Size
+		}
+		s += fmt.Sprintf(" • %d selected, %s", len(chosen), humanize.Bytes(size))
+	}
+	return s
+}
+
+// without drops the folders a batch removed.
+func (v *artifactView) without(done []Entry) {
+	gone := make(map[string]bool, len(done))
+	for _, e := range done {
+		gone[cacheKey(e.Path)] = true
+		delete(v.chosen, cacheKey(e.Path))
+	}
+	found := v.found[:0]
+	for _, a := range v.found {
+		if !gone[cacheKey(a.Path)] {
+			found = append(found, a)
+		}
+	}
+	v.found = found
+	v.preview = false
+	v.total()
+}
+
+// artifactListHeight is the room left for folders below the kinds.
+func (m model) artifactListHeight() int {
+	return max(m.viewportHeight()-len(m.artifacts.kinds)-2, 3)
+}
+
+// handleArtifactsKey handles keys while the build folders are listed.
+func (m model) handleArtifactsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.artifacts
+	rows := v.rows()
+	if v.pruning {
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		return m, nil
+	}
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc", "q", "B":
+		if v.preview {
+			v.preview = false
+			v.total()
+			break
+		}
+		m.artifacts = nil
+		m.status = m.totalStatus()
+		return m, nil
+	case "up", "k":
+		if v.selected > 0 {
+			v.selected--
+			v.offset = min(v.offset, v.selected)
+		}
+	case "down", "j":
+		if v.selected < len(rows)-1 {
+			v.selected++
+			if h := m.artifactListHeight(); v.selected >= v.offset+h {
+				v.offset = v.selected - h + 1
+			}
+		}
+	case " ":
+		if len(rows) == 0 || v.preview {
+			break
+		}
+		key := cacheKey(rows[v.selected].Path)
+		if v.chosen[key] {
+			delete(v.chosen, key)
+		} else {
+			v.chosen[key] = true
+		}
+		if v.selected < len(rows)-1 {
+			v.selected++
+			if h := m.artifactListHeight(); v.selected >= v.offset+h {
+				v.offset = v.selected - h + 1
+			}
+		}
+	case "a":
+		// Every folder of the selected one's kind, or none if all are.
+		if len(rows) == 0 || v.preview {
+			break
+		}
+		kind := rows[v.selected].kind
+		all := true
+		for _, a := range v.found {
+			if a.kind == kind && !v.chosen[cacheKey(a.Path)] {
+				all = false
+				break
+			}
+		}
+		for _, a := range v.found {
+			if a.kind == kind {
+				if all {
+					delete(v.chosen, cacheKey(a.Path))
+				} else {
+					v.chosen[cacheKey(a.Path)] = true
+				}
+			}
+		}
+	case "D":
+		entries := v.chosenEntries()
+		if len(entries) == 0 {
+			m.status = "Space or a selects the folders to delete"
+			return m, nil
+		}
+		if !v.preview {
+			// The dry run: nothing is deleted until D is pressed again
+			// and the prompt answered.
+			v.preview = true
+			v.selected, v.offset = 0, 0
+			var size int64
+			for _, e := range entries {
+				size += e.Size
+			}
+			m.status = fmt.Sprintf("Would delete %s, %s • D to delete them • Esc back", itemCount(len(entries)), humanize.Bytes(size))
+			return m, nil
+		}
+		return m.confirmBatch(batchDelete, entries), nil
+	case "p":
+		// Pruning deletes data like D does, so the same rules apply.
+		if m.imported != "" {
+			m.status = "Read-only: " + m.imported + " was recorded on another machine"
+			return m, nil
+		}
+		if policy.Get().DisableFileDeletion {
+			m.status = "Pruning the Docker build cache is " + policy.DisabledMessage
+			return m, nil
+		}
+		if v.docker <= 0 {
+			m.status = "No Docker build cache to prune"
+			return m, nil
+		}
+		v.pruning = true
+		m.status = "Pruning the Docker build cache..."
+		return m, dockerPruneCmd()
+	case "y":
+		if len(rows) > 0 {
+			m = m.copyPath(rows[v.selected].Entry)
+		}
+		return m, nil
+	case "o", "O":
+		if len(rows) > 0 {
+			m = m.openAction(rows[v.selected].Entry, msg.String() == "O")
+		}
+		return m, nil
+	case "enter", "right", "l":
+		if len(rows) == 0 {
+			break
+		}
+		dir := rows[v.selected].Path
+		traceAction("jump", dir, m.redactor)
+		m.artifacts = nil
+		m.history = append(m.history, historyEntry{
+			Path:     m.path,
+			Selected: m.selected,
+			Offset:   m.offset,
+		})
+		m.path = filepath.Dir(dir)
+		m.selected, m.offset = 0, 0
+		m.focus = dir
+		return m.load()
+	}
+	if !v.preview {
+		m.status = v.summary()
+	}
+	return m, nil
+}
+
+// renderArtifacts shows the totals by kind, then the folders with their
+// size, kind and path; ✓ marks the folders selected.
+func (m model) renderArtifacts() string {
+	v := m.artifacts
+	var b strings.Builder
+	for _, k := range v.kinds {
+		chosen := 0
+		for _, a := range v.found {
+			if a.kind == k.name && v.chosen[cacheKey(a.Path)] {
+				chosen++
+			}
+		}
+		line := fmt.Sprintf("%10s  %-32s %d folders", humanize.Bytes(k.size), k.name, k.count)
+		if chosen > 0 {
+			line += fmt.Sprintf(", %d selected", chosen)
+		}
+		b.WriteString(dimStyle.Render(line) + "\n")
+	}
+	if v.docker > 0 {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("%10s  %-32s p prunes it", humanize.Bytes(v.docker), "Docker build cache")) + "\n")
+	}
+	if len(v.found) == 0 {
+		b.WriteString(dimStyle.Render("  (no build output or dependency folders found)") + "\n")
+		return b.String()
+	}
+	b.WriteString("\n")
+	if v.preview {
+		b.WriteString(warnStyle.Render("  Dry run: D deletes these folders for good") + "\n")
+	}
+
+	rows := v.rows()
+	end := min(v.offset+m.artifactListHeight(), len(rows))
+	for i := v.offset; i < end; i++ {
+		a := rows[i]
+		rel, err := filepath.Rel(m.path, a.Path)
+		if err != nil {
+			rel = a.Path
+		}
+		check := " "
+		if v.chosen[cacheKey(a.Path)] {
+			check = "✓"
+		}
+		line := fmt.Sprintf("%s %-32s %s", check, a.kind, rel)
+		size := sizeStyle.Render(fmt.Sprintf("%10s", humanize.Bytes(a.Size)))
+		switch {
+		case i == v.selected:
+			b.WriteString(size + " " + selectedStyle.Render(line))
+		case check != " " && !v.preview:
+			b.WriteString(size + " " + warnStyle.Render(line))
+		default:
+			b.WriteString(size + " " + normalStyle.Render(line))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,138 @@
+//go:build windows
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/winmole/winmole/internal/redact"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// A baseline is an export (--export) of a freshly imaged machine, or any
+// other report importReport understands.
+// Comparing against it shows what has appeared or grown since: a quick
+// drift detector for lab and kiosk fleets.
+type baseline struct {
+	root  string
+	name  string           // "baseline", or which snapshot
+	sizes map[string]int64 // cacheKey(path) -> size
+	tree  dirCache         // the report's listings, for what has gone since
+}
+
+func loadBaseline(path string) (*baseline, error) {
+	c, root, err := importReport(path)
+	if err != nil {
+		return nil, fmt.Errorf("baseline: %w", err)
+	}
+	b := &baseline{root: root, name: "baseline", sizes: make(map[string]int64), tree: c}
+	for _, l := range c {
+		for _, e := range l.entries {
+			b.sizes[cacheKey(e.Path)] = e.Size
+		}
+	}
+	return b, nil
+}
+
+// compare returns how much path grew since the baseline and whether it is
+// new. A nil baseline reports no drift.
+func (b *baseline) compare(path string, size int64) (growth int64, isNew bool) {
+	if b == nil {
+		return 0, false
+	}
+	base, ok := b.sizes[cacheKey(path)]
+	if !ok {
+		return size, true
+	}
+	return size - base, false
+}
+
+// driftLabel is the TUI column for an entry: "new", "+1.2 GB", "-300 MB"
+// or "".
+func (b *baseline) driftLabel(e Entry) string {
+	growth, isNew := b.compare(e.Path, e.Size)
+	switch {
+	case isNew:
+		return "new"
+	case growth != 0:
+		return signedBytes(growth)
+	}
+	return ""
+}
+
+// driftRow is one line of a drift report.
+type driftRow struct {
+	exportRow
+	BaselineSize int64  `json:"baselineSize"`
+	Growth       int64  `json:"growth"`
+	Status       string `json:"status"` // "new" or "larger"
+}
+
+// runDrift prints the entries that are new or
+// larger than in the baseline, biggest growth first.
+func runDrift(w io.Writer, c dirCache, root string, top int, format string, b *baseline, r *redact.Redactor) error {
+	switch format {
+	case "text", "json", "csv":
+	default:
+		return fmt.Errorf("unknown format %q (use text, json or csv)", format)
+	}
+
+	var rows []driftRow
+	for _, row := range c.exportRows(root, nil) {
+		growth, isNew := b.compare(row.Path, row.Size)
+		if growth <= 0 && !isNew {
+			continue
+		}
+		d := driftRow{exportRow: row, Growth: growth, Status: "larger"}
+		if isNew {
+			d.Status = "new"
+		} else {
+			d.BaselineSize = row.Size - growth
+		}
+		d.Path = r.String(d.Path)
+		rows = append(rows, d)
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Growth > rows[j].Growth })
+	if top > 0 && len(rows) > top {
+		rows = rows[:top]
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"path", "size", "baseline_size", "growth", "status", "depth", "is_dir"})
+		for _, d := range rows {
+			cw.Write([]string{
+				d.Path,
+				strconv.FormatInt(d.Size, 10),
+				strconv.FormatInt(d.BaselineSize, 10),
+				strconv.FormatInt(d.Growth, 10),
+				d.Status,
+				strconv.Itoa(d.Depth),
+				strconv.FormatBool(d.IsDir),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	if len(rows) == 0 {
+		fmt.Fprintf(w, "%s matches the baseline\n", r.String(root))
+		return nil
+	}
+	fmt.Fprintf(w, "%s  %d entries new or larger than the baseline\n", r.String(root), len(rows))
+	for _, d := range rows {
+		fmt.Fprintf(w, "%10s  %-6s  %s\n", "+"+humanize.Bytes(d.Growth), d.Status, d.Path)
+	}
+	return nil
+}
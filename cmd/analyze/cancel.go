@@ -0,0 +1,78 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/scan"
+)
+
+// Esc during a folder scan stops it and lists what was measured so far,
+// instead of leaving you on the spinner. Folders still being measured show
+// the part seen; ones not reached yet are missing. The listing is marked
+// incomplete, kept for this session only, and r scans it again.
+
+// scanRun is a folder scan in progress. Results are only taken from the
+// run the model is waiting for, so a stopped scan that answers late cannot
+// overwrite a newer one.
+type scanRun struct {
+	cancel   context.CancelFunc
+	stopping bool
+	started  time.Time
+	expected int64 // bytes the folder held last time, 0 when unknown
+
+	// When several roots are scanned at once, their counters, how many
+	// are still running and the errors of those that failed.
+	roots   []*scan.Counters
+	pending int
+	failed  []string
+}
+
+// startScan scans m.path afresh, stopping a scan still running.
+func (m model) startScan() (model, tea.Cmd) {
+	if m.run != nil {
+		m.run.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.run = &scanRun{cancel: cancel, started: time.Now(), expected: m.expectedSize()}
+	m.scanning = true
+	m.status = "Scanning..."
+	m.progress = newProgress(m.exclude)
+	return m, tea.Batch(m.scanCmd(ctx, m.run), tickCmd())
+}
+
+// stopScan cancels the folder scan; its partial result arrives as a
+// scanResultMsg like a finished one.
+func (m model) stopScan() model {
+	m.run.cancel()
+	m.run.stopping = true
+	m.status = "Stopping the scan..."
+	return m
+}
+
+// scanStatus is the status line while the folder scan runs.
+func (m model) scanStatus() string {
+	if m.run != nil && m.run.stopping {
+		return spinnerFrames[m.spinner] + " Stopping the scan..."
+	}
+	progress := []*scan.Counters{m.progress}
+	if m.run != nil && m.run.roots != nil {
+		progress = m.run.roots
+	}
+	var bytes, files, dirs int64
+	for _, p := range progress {
+		bytes += p.Bytes.Load()
+		files += p.Files.Load()
+		dirs += p.Dirs.Load()
+	}
+	status := fmt.Sprintf("%s Scanning... %s in %d files, %d dirs", spinnerFrames[m.spinner], humanize.Bytes(bytes), files, dirs)
+	if m.run != nil {
+		status += m.run.estimate(bytes)
+	}
+	return status
+}
@@ -0,0 +1,134 @@
+//go:build windows
+
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Built-in file categories, in legend order.
+var categoryOrder = []string{"media", "archives", "executables", "documents", "code", "system"}
+
+var defaultCategoryColors = map[string]string{
+	"media":       "213",
+	"archives":    "214",
+	"executables": "203",
+	"documents":   "117",
+	"code":        "120",
+	"system":      "245",
+}
+
+var defaultCategoryExtensions = map[string][]string{
+	"media": {
+		".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp", ".heic", ".tif", ".tiff", ".raw", ".svg",
+		".mp4", ".mkv", ".avi", ".mov", ".wmv", ".webm", ".m4v",
+		".mp3", ".wav", ".flac", ".aac", ".ogg", ".m4a", ".wma",
+	},
+	"archives": {
+		".zip", ".7z", ".rar", ".tar", ".gz", ".bz2", ".xz", ".zst", ".cab", ".iso", ".img", ".vhd", ".vhdx", ".wim",
+	},
+	"executables": {
+		".exe", ".msi", ".msp", ".msix", ".appx", ".bat", ".cmd", ".ps1", ".com", ".scr",
+	},
+	"documents": {
+		".pdf", ".doc", ".docx", ".xls", ".xlsx", ".ppt", ".pptx", ".odt", ".ods", ".odp", ".rtf", ".txt", ".md", ".csv", ".epub",
+	},
+	"code": {
+		".go", ".rs", ".c", ".h", ".cpp", ".hpp", ".cs", ".java", ".kt", ".py", ".js", ".ts", ".tsx", ".jsx",
+		".html", ".css", ".json", ".yaml", ".yml", ".toml", ".xml", ".sql", ".sh", ".rb", ".php", ".swift",
+	},
+	"system": {
+		".dll", ".sys", ".drv", ".ocx", ".cpl", ".mui", ".cat", ".inf", ".log", ".tmp", ".dmp", ".etl", ".evtx", ".pf", ".db", ".dat",
+	},
+}
+
+// fallbackCategoryColor is used for user-defined categories without a color.
+const fallbackCategoryColor = "252"
+
+// categorizer maps file extensions to categories and their styles.
+type categorizer struct {
+	byExt  map[string]string
+	styles map[string]lipgloss.Style
+	order  []string
+}
+
+// newCategorizer merges the user's extension map and colors from config
+// over the built-in defaults. User categories not in categoryOrder are
+// appended to the legend alphabetically.
+func newCategorizer(cfg analyzeConfig) *categorizer {
+	c := &categorizer{
+		byExt:  make(map[string]string),
+		styles: make(map[string]lipgloss.Style),
+		order:  append([]string(nil), categoryOrder...),
+	}
+	for category, exts := range defaultCategoryExtensions {
+		for _, ext := range exts {
+			c.byExt[ext] = category
+		}
+	}
+
+	known := make(map[string]bool, len(categoryOrder))
+	for _, category := range categoryOrder {
+		known[category] = true
+	}
+	var extra []string
+	for ext, category := range cfg.Categories {
+		category = strings.ToLower(strings.TrimSpace(category))
+		if category == "" {
+			continue
+		}
+		c.byExt[normalizeExt(ext)] = category
+		if !known[category] {
+			known[category] = true
+			extra = append(extra, category)
+		}
+	}
+	sort.Strings(extra)
+	c.order = append(c.order, extra...)
+
+	for _, category := range c.order {
+		color := defaultCategoryColors[category]
+		if custom, ok := cfg.CategoryColors[category]; ok && custom != "" {
+			color = custom
+		}
+		if color == "" {
+			color = fallbackCategoryColor
+		}
+		c.styles[category] = lipgloss.NewStyle().Foreground(lipgloss.Color(color))
+	}
+	return c
+}
+
+// category returns the category of a file name, or "" if it has none.
+func (c *categorizer) category(name string) string {
+	return c.byExt[normalizeExt(filepath.Ext(name))]
+}
+
+// style returns the style for a file name, falling back to normalStyle.
+func (c *categorizer) style(name string) lipgloss.Style {
+	if style, ok := c.styles[c.category(name)]; ok {
+		return style
+	}
+	return normalStyle
+}
+
+// legend renders every category name in its own color.
+func (c *categorizer) legend() string {
+	parts := make([]string, 0, len(c.order))
+	for _, category := range c.order {
+		parts = append(parts, c.styles[category].Render("■ "+category))
+	}
+	return strings.Join(parts, "  ")
+}
+
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
@@ -0,0 +1,83 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// y copies the full path of the selected entry to the clipboard, for
+// pasting into PowerShell or a file dialog.
+
+var (
+	user32               = windows.NewLazySystemDLL("user32.dll")
+	procOpenClipboard    = user32.NewProc("OpenClipboard")
+	procCloseClipboard   = user32.NewProc("CloseClipboard")
+	procEmptyClipboard   = user32.NewProc("EmptyClipboard")
+	procSetClipboardData = user32.NewProc("SetClipboardData")
+
+	procGlobalAlloc  = kernel32.NewProc("GlobalAlloc")
+	procGlobalFree   = kernel32.NewProc("GlobalFree")
+	procGlobalLock   = kernel32.NewProc("GlobalLock")
+	procGlobalUnlock = kernel32.NewProc("GlobalUnlock")
+	procMoveMemory   = kernel32.NewProc("RtlMoveMemory")
+)
+
+const (
+	cfUnicodeText = 13
+	gmemMoveable  = 0x0002
+)
+
+// copyToClipboard puts text on the clipboard as Unicode text.
+func copyToClipboard(text string) error {
+	data, err := windows.UTF16FromString(text)
+	if err != nil {
+		return err
+	}
+
+	// The clipboard is opened by a thread, which must also close it.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if r, _, err := procOpenClipboard.Call(0); r == 0 {
+		return fmt.Errorf("open clipboard: %w", err)
+	}
+	defer procCloseClipboard.Call()
+	if r, _, err := procEmptyClipboard.Call(); r == 0 {
+		return fmt.Errorf("empty clipboard: %w", err)
+	}
+
+	size := uintptr(len(data)) * unsafe.Sizeof(data[0])
+	mem, _, err := procGlobalAlloc.Call(gmemMoveable, size)
+	if mem == 0 {
+		return fmt.Errorf("allocate: %w", err)
+	}
+	ptr, _, err := procGlobalLock.Call(mem)
+	if ptr == 0 {
+		procGlobalFree.Call(mem)
+		return fmt.Errorf("lock: %w", err)
+	}
+	procMoveMemory.Call(ptr, uintptr(unsafe.Pointer(&data[0])), size)
+	procGlobalUnlock.Call(mem)
+
+	// Once set, the memory belongs to the clipboard.
+	if r, _, err := procSetClipboardData.Call(cfUnicodeText, mem); r == 0 {
+		procGlobalFree.Call(mem)
+		return fmt.Errorf("set clipboard: %w", err)
+	}
+	return nil
+}
+
+// copyPath copies e's full path and says so in the status line.
+func (m model) copyPath(e Entry) model {
+	if err := copyToClipboard(e.Path); err != nil {
+		m.status = fmt.Sprintf("Could not copy the path: %v", err)
+		return m
+	}
+	m.status = "Copied " + e.Path
+	return m
+}
@@ -0,0 +1,35 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// Files that OneDrive and other sync clients keep only in the cloud count
+// at their full size, as Explorer shows them, though they take next to
+// nothing on disk. Rows and the status line say how much of a size is
+// online-only, and a (size on disk) leaves it out. Scanning never
+// downloads them.
+
+// cloudSuffix notes how much of e is online-only, after its name.
+func (m model) cloudSuffix(e Entry) string {
+	switch {
+	case e.Cloud == 0:
+		return ""
+	case !e.IsDir:
+		return " " + m.icons.cloud + " online-only"
+	}
+	return fmt.Sprintf(" %s %s online-only", m.icons.cloud, humanize.Bytes(e.Cloud))
+}
+
+// cloudTotal is how much of the current folder is online-only.
+func (m model) cloudTotal() int64 {
+	var total int64
+	for _, e := range m.cache[cacheKey(m.path)].entries {
+		total += e.Cloud
+	}
+	return total
+}
@@ -0,0 +1,298 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/elevate"
+	"github.com/winmole/winmole/internal/policy"
+	"github.com/winmole/winmole/internal/usage"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// W asks DISM how big the component store (WinSxS) really is. Most of the
+// folder is hard links to the files Windows runs from, so walking it, as
+// Explorer does, counts them as if they took space of their own. DISM
+// /AnalyzeComponentStore separates what is shared with Windows from the
+// backups, disabled features and cache that cleanup can remove, and c then
+// runs the cleanup Windows schedules itself (/StartComponentCleanup,
+// without /ResetBase, so installed updates can still be uninstalled). Both
+// need administrator rights and go through the elevated helper.
+
+// componentReport is what DISM says about the component store; sizes are
+// -1 when DISM left them out.
+type componentReport struct {
+	explorer    int64 // what walking the folder reports
+	actual      int64
+	shared      int64 // hard links to Windows' own files
+	backups     int64 // backups and disabled features
+	cache       int64 // cache and temporary data
+	lastCleanup string
+	packages    int // reclaimable packages
+	recommended bool
+}
+
+// reclaimable is the most cleanup can free: everything not shared.
+func (r *componentReport) reclaimable() int64 {
+	return max(r.backups, 0) + max(r.cache, 0)
+}
+
+// componentView shows the report.
+type componentView struct {
+	loading bool
+	running bool // cleanup in progress
+	confirm bool // waiting for y to clean up
+}
+
+// status is the status line while DISM runs.
+func (v *componentView) status(frame string) string {
+	if v.running {
+		return frame + " Running component cleanup; this can take a while..."
+	}
+	return frame + " Analyzing the component store with DISM (administrator)..."
+}
+
+type componentMsg struct {
+	report  *componentReport
+	cleaned bool // after a cleanup
+	before  int64
+	err     error
+}
+
+// analyzeComponentStore runs the analysis through the helper.
+func analyzeComponentStore() (*componentReport, error) {
+	out, err := elevate.RunAction(elevate.OpAnalyzeComponentStore, nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseComponentReport(out)
+}
+
+func componentCmd() tea.Cmd {
+	return func() tea.Msg {
+		report, err := analyzeComponentStore()
+		return componentMsg{report: report, err: err}
+	}
+}
+
+func componentCleanupCmd(before int64) tea.Cmd {
+	return func() tea.Msg {
+		if _, err := elevate.RunAction(elevate.OpComponentCleanup, nil); err != nil {
+			return componentMsg{cleaned: true, err: err}
+		}
+		report, err := analyzeComponentStore()
+		return componentMsg{report: report, cleaned: true, before: before, err: err}
+	}
+}
+
+// parseComponentReport reads DISM's "Name : value" lines.
+func parseComponentReport(out string) (*componentReport, error) {
+	r := &componentReport{explorer: -1, actual: -1, shared: -1, backups: -1, cache: -1}
+	found := false
+	for _, line := range strings.Split(out, "\n") {
+		name, value, ok := strings.Cut(line, " : ")
+		if !ok {
+			continue
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		switch name {
+		case "Windows Explorer Reported Size of Component Store":
+			r.explorer = parseDismSize(value)
+		case "Actual Size of Component Store":
+			r.actual = parseDismSize(value)
+			found = true
+		case "Shared with Windows":
+			r.shared = parseDismSize(value)
+		case "Backups and Disabled Features":
+			r.backups = parseDismSize(value)
+		case "Cache and Temporary Data":
+			r.cache = parseDismSize(value)
+		case "Date of Last Cleanup":
+			r.lastCleanup = value
+		case "Number of Reclaimable Packages":
+			r.packages, _ = strconv.Atoi(value)
+		case "Component Store Cleanup Recommended":
+			r.recommended = strings.EqualFold(value, "Yes")
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("DISM gave no component store size: %s", humanize.Truncate(strings.TrimSpace(out), 200))
+	}
+	return r, nil
+}
+
+// parseDismSize reads a size such as "7.80 GB" or "512 bytes". DISM counts
+// in powers of 1024.
+func parseDismSize(s string) int64 {
+	number, unit, _ := strings.Cut(s, " ")
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return -1
+	}
+	scale := map[string]float64{"bytes": 1, "KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30, "TB": 1 << 40}[unit]
+	if scale == 0 {
+		return -1
+	}
+	return int64(n * scale)
+}
+
+// winSxS is the component store's folder.
+func winSxS() string {
+	return filepath.Join(os.Getenv("SystemRoot"), "WinSxS")
+}
+
+// componentSuffix notes on the WinSxS folder's row that its size is
+// mostly shared with Windows.
+func (m model) componentSuffix(e Entry) string {
+	if !e.IsDir || cacheKey(e.Path) != cacheKey(winSxS()) {
+		return ""
+	}
+	if r := m.winsxs; r != nil {
+		return fmt.Sprintf(" (%s actual, %s reclaimable • W)", humanize.Bytes(max(r.actual, 0)), humanize.Bytes(r.reclaimable()))
+	}
+	return " (mostly shared with Windows • W for what is reclaimable)"
+}
+
+// showComponents opens the view, analyzing the store on first use.
+func (m model) showComponents() (tea.Model, tea.Cmd) {
+	usage.Run("analyze.winsxs")
+	if m.winsxs != nil {
+		m.components = &componentView{}
+		m.status = m.componentStatus()
+		return m, nil
+	}
+	m.components = &componentView{loading: true}
+	m.status = "Analyzing the component store with DISM (administrator)..."
+	return m, tea.Batch(componentCmd(), tickCmd())
+}
+
+func (m model) applyComponents(msg componentMsg) model {
+	v := m.components
+	if v != nil {
+		v.loading, v.running = false, false
+	}
+	if msg.err != nil {
+		m.status = fmt.Sprintf("Error: %v", msg.err)
+		return m
+	}
+	m.winsxs = msg.report
+	if !msg.cleaned {
+		m.status = m.componentStatus()
+		return m
+	}
+
+	// The folder and the folders above it shrank by an amount only a
+	// rescan can tell.
+	dir := cacheKey(winSxS())
+	dropMFTIndex(winSxS())
+	for key := range m.cache {
+		if key == dir || isUnder(key, dir) || isUnder(dir, key) {
+			delete(m.cache, key)
+		}
+	}
+	traceAction("component-cleanup", winSxS(), m.redactor)
+	freed := max(msg.before-msg.report.actual, 0)
+	usage.Freed("analyze.winsxs", freed)
+	m.status = fmt.Sprintf("Component cleanup freed %s • %s", humanize.Bytes(freed), m.componentStatus())
+	return m
+}
+
+// componentStatus sums up the last report.
+func (m model) componentStatus() string {
+	r := m.winsxs
+	if r == nil {
+		return ""
+	}
+	s := fmt.Sprintf("WinSxS: %s actual • up to %s reclaimable", humanize.Bytes(max(r.actual, 0)), humanize.Bytes(r.reclaimable()))
+	if r.recommended {
+		s += " • cleanup recommended"
+	}
+	return s
+}
+
+// handleComponentsKey handles keys while the report is shown.
+func (m model) handleComponentsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.components
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+	if v.confirm {
+		v.confirm = false
+		if msg.String() != "y" && msg.String() != "Y" {
+			m.status = "Cleanup cancelled"
+			return m, nil
+		}
+		v.running = true
+		m.status = "Running component cleanup; this can take a while..."
+		return m, tea.Batch(componentCleanupCmd(max(m.winsxs.actual, 0)), tickCmd())
+	}
+	switch msg.String() {
+	case "W", "esc", "q":
+		// A cleanup under way still finishes and updates the report.
+		m.components = nil
+		m.status = m.totalStatus()
+	case "r":
+		if v.loading || v.running {
+			break
+		}
+		v.loading = true
+		m.status = "Analyzing the component store with DISM (administrator)..."
+		return m, tea.Batch(componentCmd(), tickCmd())
+	case "c", "enter":
+		if v.loading || v.running || m.winsxs == nil {
+			break
+		}
+		if policy.Get().DisableFileDeletion {
+			m.status = "Cleaning up the component store is " + policy.DisabledMessage
+			return m, nil
+		}
+		v.confirm = true
+		m.status = "Remove superseded components now? y to go ahead"
+	}
+	return m, nil
+}
+
+// renderComponents lays out the report.
+func (m model) renderComponents() string {
+	r := m.winsxs
+	if r == nil {
+		return dimStyle.Render("  Waiting for DISM; the analysis takes a minute or two") + "\n"
+	}
+	var b strings.Builder
+	row := func(name string, size int64, note string) {
+		value := "?"
+		if size >= 0 {
+			value = humanize.Bytes(size)
+		}
+		b.WriteString(fmt.Sprintf("%s %s", sizeStyle.Render(fmt.Sprintf("%10s", value)), normalStyle.Render(fmt.Sprintf("%-32s", name))))
+		if note != "" {
+			b.WriteString(dimStyle.Render(note))
+		}
+		b.WriteString("\n")
+	}
+	row("Size Explorer reports", r.explorer, "counts hard links to Windows' files again")
+	row("Actual size", r.actual, "")
+	row("  Shared with Windows", r.shared, "the files Windows runs from; not reclaimable")
+	row("  Backups and disabled features", r.backups, "older component versions kept for rollback")
+	row("  Cache and temporary data", r.cache, "")
+	b.WriteString("\n")
+	b.WriteString(normalStyle.Render(fmt.Sprintf("  Last cleanup: %s • reclaimable packages: %d", r.lastCleanup, r.packages)))
+	b.WriteString("\n")
+	if r.recommended {
+		b.WriteString(warnStyle.Render("  DISM recommends a cleanup; c runs it"))
+	} else {
+		b.WriteString(dimStyle.Render("  DISM does not recommend a cleanup now"))
+	}
+	b.WriteString("\n\n")
+	b.WriteString(dimStyle.Render("  Cleanup removes superseded components as Windows' scheduled task does, without /ResetBase,"))
+	b.WriteString("\n")
+	b.WriteString(dimStyle.Render("  so installed updates can still be uninstalled."))
+	b.WriteString("\n")
+	return b.String()
+}
@@ -0,0 +1,583 @@
+//go:build windows
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/usage"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/scan"
+	"golang.org/x/sys/windows"
+)
+
+// Z estimates what compressing the selected folder would save before
+// anything is compressed. The folder's files are grouped by extension, a
+// few files of each are read and compressed in memory the way Windows
+// would, in independent chunks, and the ratio is applied to the whole
+// extension. DEFLATE stands in for Windows' own algorithms, so the figures
+// are a guide rather than a promise. Files already compressed are left
+// out. Space picks the extensions to compress, m switches the method, and
+// Enter then y runs compact.exe on the picked extensions; c then y runs it
+// on the whole folder, for a game or an SDK that is rarely used. K in the
+// list compresses the selected folder whole: c there compares with a
+// snapshot, so K estimates the folder as Z does and opens the view at the
+// whole-folder prompt, with the method and the estimate in front of you
+// before y. A bar follows compact.exe through
+// the files, and the result is set against the estimate. Folders with
+// online-only files are refused, since compact.exe would download them.
+
+const (
+	// compressSamples is how many files of each extension are read.
+	compressSamples = 6
+	// compressSampleSize is how much of each sample is read.
+	compressSampleSize = 1 << 20
+	// compressWorthwhile is the share an extension must save to be
+	// picked at first.
+	compressWorthwhile = 0.10
+	// clusterSize is the usual NTFS allocation unit.
+	clusterSize = 4096
+)
+
+// compressMethod is one way Windows can compress files.
+type compressMethod struct {
+	name  string
+	flag  string // compact.exe's algorithm switch; "" for NTFS compression
+	chunk int    // bytes compressed independently
+	level int    // the DEFLATE level standing in for the algorithm
+	about string
+}
+
+// compressMethods are the methods m steps through. The WOF methods
+// (XPRESS, LZX) suit files that are read but rarely written: a write
+// decompresses the file. NTFS compression stays in place as files change,
+// but saves less and costs more to read.
+var compressMethods = []compressMethod{
+	{"XPRESS8K", "/exe:xpress8k", 8 << 10, flate.BestSpeed, "fast to read; for files rarely written"},
+	{"LZX", "/exe:lzx", 32 << 10, flate.BestCompression, "smallest; for files rarely written, such as apps and games"},
+	{"NTFS", "", 64 << 10, flate.BestSpeed, "kept compressed as files change"},
+}
+
+// compressStat is one extension of the folder and what compressing it
+// would save.
+type compressStat struct {
+	ext     string
+	files   int64
+	size    int64
+	sampled int64    // bytes read
+	packed  [3]int64 // of them, what each method would leave, by compressMethods
+	samples []string
+}
+
+// estimate is the size the extension would take with method i.
+func (s compressStat) estimate(i int) int64 {
+	if s.sampled == 0 {
+		return s.size
+	}
+	return int64(float64(s.size) * float64(s.packed[i]) / float64(s.sampled))
+}
+
+// saving is what compressing the extension with method i would save.
+func (s compressStat) saving(i int) int64 {
+	return s.size - s.estimate(i)
+}
+
+// compressView is the estimate for one folder.
+type compressView struct {
+	dir      Entry
+	stats    []compressStat // most saved first
+	method   int
+	chosen   map[string]bool
+	selected int
+	offset   int
+	confirm  bool // waiting for y to compress
+	whole    bool // compressing every file rather than the picked extensions
+	running  bool
+	total    int64         // files in the run
+	done     *atomic.Int64 // files compact.exe has reported
+}
+
+type compressEstimateMsg struct {
+	path  string // the folder shown when the estimate started
+	whole bool   // started with K, to ask straight away about the whole folder
+	dir   Entry
+	stats []compressStat
+	err   error
+}
+
+type compactMsg struct {
+	dir           Entry
+	method        string
+	estimate      int64 // the saving expected
+	before, after int64 // space allocated
+	err           error
+}
+
+func (m model) compressEstimateCmd(dir Entry, whole bool) tea.Cmd {
+	progress, path := m.progress, m.path
+	return func() tea.Msg {
+		stats, err := estimateCompression(dir.Path, progress)
+		return compressEstimateMsg{path: path, whole: whole, dir: dir, stats: stats, err: err}
+	}
+}
+
+// estimateCompression groups the files below path by extension and
+// compresses samples of each.
+func estimateCompression(path string, progress *scan.Counters) ([]compressStat, error) {
+	type file struct {
+		path string
+		size int64
+	}
+	byExt := make(map[string][]file)
+	add := func(p string, size int64) {
+		ext := normalizeExt(filepath.Ext(p))
+		if ext == "" {
+			ext = noExtension
+		}
+		byExt[ext] = append(byExt[ext], file{p, size})
+	}
+
+	fromMFT := false
+	if vol := mftVolume(path); vol != "" && progress.Exclude == nil && !progress.FollowLinks {
+		if idx, err := loadMFTIndex(context.Background(), vol, progress); err == nil {
+			fromMFT = idx.eachNode(path, func(dir string, n *mftNode) {
+				// Compressed, sparse and WOF files allocate less than
+				// their size, or are reparse points.
+				if !n.reparse && n.alloc >= n.size && n.size > 0 {
+					add(filepath.Join(dir, n.name), n.size)
+				}
+			}) == nil
+		}
+	}
+	if !fromMFT {
+		byExt = make(map[string][]file)
+		err := scan.Walk(context.Background(), path, progress, func(p string, info fs.FileInfo) {
+			// Compressed, sparse and WOF files allocate less than their
+			// size, or are reparse points.
+			if a, ok := info.Sys().(*syscall.Win32FileAttributeData); ok &&
+				a.FileAttributes&(windows.FILE_ATTRIBUTE_COMPRESSED|windows.FILE_ATTRIBUTE_SPARSE_FILE|windows.FILE_ATTRIBUTE_REPARSE_POINT|placeholderAttributes) != 0 {
+				return
+			}
+			if info.Size() > 0 {
+				add(p, info.Size())
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	stats := make([]compressStat, 0, len(byExt))
+	for ext, files := range byExt {
+		s := compressStat{ext: ext, files: int64(len(files))}
+		for _, f := range files {
+			s.size += f.size
+		}
+		// Samples spread from the smallest file to the largest.
+		sort.Slice(files, func(i, j int) bool { return files[i].size < files[j].size })
+		n := min(len(files), compressSamples)
+		for i := range n {
+			f := files[i*(len(files)-1)/max(n-1, 1)]
+			read, packed, err := compressSample(f.path)
+			if err != nil {
+				continue
+			}
+			progress.Bytes.Add(read)
+			s.sampled += read
+			for k := range packed {
+				s.packed[k] += packed[k]
+			}
+			s.samples = append(s.samples, f.path)
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// compressSample reads up to compressSampleSize of the file at path and
+// returns how much it read and what each method would leave of it.
+func compressSample(path string) (read int64, packed [3]int64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, packed, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, compressSampleSize))
+	if err != nil {
+		return 0, packed, err
+	}
+	for i, method := range compressMethods {
+		packed[i] = packedSize(data, method)
+	}
+	return int64(len(data)), packed, nil
+}
+
+// packedSize compresses data in the method's chunks, each on its own. A
+// chunk that does not shrink is stored as is. NTFS compression allocates
+// whole clusters to each chunk, so a chunk only shrinks by a cluster or
+// more.
+func packedSize(data []byte, method compressMethod) int64 {
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, method.level)
+	var total int64
+	for start := 0; start < len(data); start += method.chunk {
+		chunk := data[start:min(start+method.chunk, len(data))]
+		buf.Reset()
+		w.Reset(&buf)
+		w.Write(chunk)
+		w.Close()
+		n := int64(buf.Len())
+		if method.flag == "" {
+			n = (n + clusterSize - 1) / clusterSize * clusterSize
+		}
+		total += min(n, int64(len(chunk)))
+	}
+	return total
+}
+
+// showCompress estimates compression for the selected folder, then asks
+// about compressing all of it when whole.
+func (m model) showCompress(whole bool) (tea.Model, tea.Cmd) {
+	if m.imported != "" {
+		m.status = "Read-only: " + m.imported + " was recorded on another machine"
+		return m, nil
+	}
+	if len(m.entries) == 0 {
+		return m, nil
+	}
+	dir := m.entries[m.selected]
+	if !dir.IsDir || dir.Target != "" {
+		if whole {
+			m.status = "K compresses a folder"
+		} else {
+			m.status = "Z estimates compression for a folder"
+		}
+		return m, nil
+	}
+	m.scanning = true
+	m.status = "Estimating compression..."
+	m.progress.Files.Store(0)
+	m.progress.Dirs.Store(0)
+	m.progress.Bytes.Store(0)
+	return m, tea.Batch(m.compressEstimateCmd(dir, whole), tickCmd())
+}
+
+func (m model) applyCompressEstimate(msg compressEstimateMsg) model {
+	usage.Run("analyze.compress")
+	v := &compressView{dir: msg.dir, stats: msg.stats, method: 1, chosen: make(map[string]bool)}
+	v.sortStats()
+	for _, s := range v.stats {
+		if s.ext != noExtension && float64(s.saving(v.method)) >= compressWorthwhile*float64(s.size) {
+			v.chosen[s.ext] = true
+		}
+	}
+	m.compress = v
+	m.status = v.summary()
+	return m
+}
+
+func (v *compressView) sortStats() {
+	sort.Slice(v.stats, func(i, j int) bool {
+		return v.stats[i].saving(v.method) > v.stats[j].saving(v.method)
+	})
+}
+
+// picked returns the totals of the extensions picked.
+func (v *compressView) picked() (files, size, saving int64) {
+	for _, s := range v.stats {
+		if v.chosen[s.ext] {
+			files, size, saving = files+s.files, size+s.size, saving+s.saving(v.method)
+		}
+	}
+	return files, size, saving
+}
+
+// all returns the totals of every extension.
+func (v *compressView) all() (files, size, saving int64) {
+	for _, s := range v.stats {
+		files, size, saving = files+s.files, size+s.size, saving+max(s.saving(v.method), 0)
+	}
+	return files, size, saving
+}
+
+// summary is the status line of the view.
+func (v *compressView) summary() string {
+	var size, saving int64
+	for _, s := range v.stats {
+		size += s.size
+		saving += max(s.saving(v.method), 0)
+	}
+	method := compressMethods[v.method]
+	line := fmt.Sprintf("%s • %s uncompressed • %s could save about %s (%s)", v.dir.Name, humanize.Bytes(size), method.name, humanize.Bytes(saving), method.about)
+	if files, size, saving := v.picked(); files > 0 {
+		line += fmt.Sprintf(" • picked %s, %s, saving about %s", itemCount(int(files)), humanize.Bytes(size), humanize.Bytes(saving))
+	}
+	return line
+}
+
+// compactArgs are compact.exe's arguments for the picked extensions, or
+// every file when whole, run in the folder.
+func (v *compressView) compactArgs() []string {
+	args := []string{"/c", "/s", "/i"}
+	if flag := compressMethods[v.method].flag; flag != "" {
+		args = append(args, flag)
+	}
+	if v.whole {
+		return append(args, "*")
+	}
+	for _, s := range v.stats {
+		if v.chosen[s.ext] {
+			args = append(args, "*"+s.ext)
+		}
+	}
+	return args
+}
+
+// compactFileLine reports whether a line of compact.exe's output is one
+// file, such as "game.pak  524288 :  131072 = 4.0 to 1 [OK]". The words
+// are translated but the figures keep their place in every language.
+func compactFileLine(line string) bool {
+	return strings.Contains(line, " : ") && strings.Contains(line, " = ")
+}
+
+// compactCmd compresses the files and measures the space the folder takes
+// before and after, counting the files compact.exe reports into done.
+func compactCmd(dir Entry, method string, estimate int64, args []string, done *atomic.Int64) tea.Cmd {
+	return func() tea.Msg {
+		msg := compactMsg{dir: dir, method: method, estimate: estimate}
+		msg.before = scan.Tree(context.Background(), dir.Path, nil).Alloc
+		cmd := exec.Command("compact.exe", args...)
+		cmd.Dir = dir.Path
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			msg.err = err
+			return msg
+		}
+		cmd.Stderr = cmd.Stdout
+		if err := cmd.Start(); err != nil {
+			msg.err = fmt.Errorf("compact.exe: %w", err)
+			return msg
+		}
+		var last string
+		lines := bufio.NewScanner(out)
+		for lines.Scan() {
+			line := strings.TrimSpace(lines.Text())
+			if compactFileLine(line) {
+				done.Add(1)
+			}
+			if line != "" {
+				last = line
+			}
+		}
+		err = cmd.Wait()
+		msg.after = scan.Tree(context.Background(), dir.Path, nil).Alloc
+		if err != nil && msg.after >= msg.before {
+			// With /i compact.exe carries on past files in use and
+			// fails at the end; only a run that saved nothing failed.
+			msg.err = fmt.Errorf("compact.exe: %w: %s", err, last)
+		}
+		return msg
+	}
+}
+
+// status is the status line while compact.exe runs: a bar of the files
+// it has been through.
+func (v *compressView) status(frame string) string {
+	done := min(v.done.Load(), v.total)
+	percent := 0.0
+	if v.total > 0 {
+		percent = float64(done) / float64(v.total) * 100
+	}
+	filled := int(percent / 5)
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", 20-filled)
+	return fmt.Sprintf("%s Compressing %s with %s %s %3.0f%% • %d of %d files", frame, v.dir.Name,
+		compressMethods[v.method].name, bar, percent, done, v.total)
+}
+
+// compressResult sets the space saved against the estimate.
+func compressResult(msg compactMsg) string {
+	saved := msg.before - msg.after
+	line := fmt.Sprintf("Compressed %s with %s: %s on disk, was %s", msg.dir.Name, msg.method, humanize.Bytes(msg.after), humanize.Bytes(msg.before))
+	if saved > 0 {
+		line += fmt.Sprintf(" • saved %s", humanize.Bytes(saved))
+	} else {
+		line += " • saved nothing"
+	}
+	return line + fmt.Sprintf(", estimated %s", humanize.Bytes(msg.estimate))
+}
+
+// confirmCompact asks for y before compressing the picked extensions, or
+// the whole folder.
+func (m model) confirmCompact(whole bool) (tea.Model, tea.Cmd) {
+	v := m.compress
+	files, size, saving := v.picked()
+	what := fmt.Sprintf("%s (%s) in %s", itemCount(int(files)), humanize.Bytes(size), v.dir.Name)
+	if whole {
+		files, size, saving = v.all()
+		what = fmt.Sprintf("all of %s (%s, %s)", v.dir.Name, itemCount(int(files)), humanize.Bytes(size))
+	}
+	if v.dir.Cloud > 0 {
+		// compact.exe opens every file it matches, which downloads
+		// online-only files, and analyze never hydrates them.
+		m.status = fmt.Sprintf("%s holds %s of online-only files that compressing would download; free them up in OneDrive first",
+			v.dir.Name, humanize.Bytes(v.dir.Cloud))
+		return m, nil
+	}
+	if files == 0 {
+		if whole {
+			m.status = "Nothing left to compress in " + v.dir.Name
+		} else {
+			m.status = "Space picks the extensions to compress, or c compresses the whole folder"
+		}
+		return m, nil
+	}
+	v.confirm, v.whole = true, whole
+	m.status = fmt.Sprintf("Compress %s with %s, saving about %s? y to go ahead", what, compressMethods[v.method].name, humanize.Bytes(saving))
+	return m, nil
+}
+
+func (m model) applyCompact(msg compactMsg) (tea.Model, tea.Cmd) {
+	m.compress = nil
+	if msg.err != nil {
+		m.status = fmt.Sprintf("Error: %v", msg.err)
+		return m, nil
+	}
+	traceAction("compress", msg.dir.Path, m.redactor)
+	status := compressResult(msg)
+	// The folder's listings are stale for its space on disk.
+	dropMFTIndex(m.path)
+	for key := range m.cache {
+		if key == cacheKey(msg.dir.Path) || isUnder(key, cacheKey(msg.dir.Path)) {
+			delete(m.cache, key)
+		}
+	}
+	m, cmd := m.startScan()
+	delete(m.cache, cacheKey(m.path))
+	m.status = status
+	return m, cmd
+}
+
+// handleCompressKey handles keys while the estimate is shown.
+func (m model) handleCompressKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.compress
+	if v.running {
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		return m, nil
+	}
+	if v.confirm {
+		v.confirm = false
+		if msg.String() != "y" && msg.String() != "Y" {
+			m.status = "Compression cancelled"
+			return m, nil
+		}
+		files, _, saving := v.picked()
+		if v.whole {
+			files, _, saving = v.all()
+		}
+		v.running, v.total, v.done = true, files, new(atomic.Int64)
+		m.status = v.status(spinnerFrames[m.spinner])
+		return m, tea.Batch(compactCmd(v.dir, compressMethods[v.method].name, saving, v.compactArgs(), v.done), tickCmd())
+	}
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "Z", "esc", "q":
+		m.compress = nil
+		m.status = m.totalStatus()
+		return m, nil
+	case "up", "k":
+		if v.selected > 0 {
+			v.selected--
+			v.offset = min(v.offset, v.selected)
+		}
+	case "down", "j":
+		if v.selected < len(v.stats)-1 {
+			v.selected++
+			if h := m.viewportHeight(); v.selected >= v.offset+h {
+				v.offset = v.selected - h + 1
+			}
+		}
+	case " ":
+		if v.selected >= len(v.stats) {
+			break
+		}
+		ext := v.stats[v.selected].ext
+		switch {
+		case ext == noExtension:
+			m.status = "Files without an extension cannot be picked; compact.exe selects by name pattern"
+			return m, nil
+		case v.chosen[ext]:
+			delete(v.chosen, ext)
+		default:
+			v.chosen[ext] = true
+		}
+	case "m":
+		selected := ""
+		if v.selected < len(v.stats) {
+			selected = v.stats[v.selected].ext
+		}
+		v.method = (v.method + 1) % len(compressMethods)
+		v.sortStats()
+		for i, s := range v.stats {
+			if s.ext == selected {
+				v.selected = i
+			}
+		}
+	case "enter":
+		return m.confirmCompact(false)
+	case "c":
+		return m.confirmCompact(true)
+	}
+	m.status = v.summary()
+	return m, nil
+}
+
+// renderCompress lists the extensions with their size and the estimate for
+// the method shown; ✓ marks the ones picked.
+func (m model) renderCompress() string {
+	v := m.compress
+	if len(v.stats) == 0 {
+		return dimStyle.Render("  (no uncompressed files)") + "\n"
+	}
+	var b strings.Builder
+	end := min(v.offset+m.viewportHeight(), len(v.stats))
+	for i := v.offset; i < end; i++ {
+		s := v.stats[i]
+		check := " "
+		if v.chosen[s.ext] {
+			check = "✓"
+		}
+		share := 0.0
+		if s.size > 0 {
+			share = float64(s.saving(v.method)) / float64(s.size) * 100
+		}
+		line := fmt.Sprintf("%s %-12s %8d files %10s → %10s  %5.1f%% saved  (%d sampled)",
+			check, s.ext, s.files, humanize.Bytes(s.size), humanize.Bytes(s.estimate(v.method)), share, len(s.samples))
+		switch {
+		case i == v.selected:
+			b.WriteString(selectedStyle.Render(line))
+		case check != " ":
+			b.WriteString(normalStyle.Render(line))
+		default:
+			b.WriteString(dimStyle.Render(line))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,90 @@
+//go:build windows
+
+package main
+
+import (
+	"github.com/winmole/winmole/internal/config"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// configSection is the key of the analyzer's settings in config.json.
+const configSection = "analyze"
+
+// analyzeConfig holds the user-tunable analyzer settings.
+type analyzeConfig struct {
+	// BarScale is "linear" (share of the total) or "log".
+	BarScale string `json:"barScale"`
+
+	// BarColor is "plain" or "age", coloring bars by last modified.
+	BarColor string `json:"barColor"`
+
+	// Categories maps file extensions (".blend") to a category name,
+	// overriding or extending the built-in map.
+	Categories map[string]string `json:"categories,omitempty"`
+
+	// CategoryColors maps category names to lipgloss colors ("213", "#ff00aa").
+	CategoryColors map[string]string `json:"categoryColors,omitempty"`
+
+	// Icons is "auto", "emoji", "nerd" or "ascii".
+	Icons string `json:"icons"`
+
+	// Exclude lists paths scans leave out, in .gitignore style; see
+	// exclusions.
+	Exclude []string `json:"exclude,omitempty"`
+
+	// StaleDays is the age the old-files view starts at.
+	StaleDays int `json:"staleDays"`
+
+	// Streams starts with alternate data streams counted in sizes, as F
+	// turns on.
+	Streams bool `json:"streams"`
+
+	// Snapshots is what --prune keeps of the scheduled snapshots.
+	Snapshots snapshotRetention `json:"snapshots"`
+}
+
+func defaultConfig() analyzeConfig {
+	return analyzeConfig{
+		BarScale:  "linear",
+		BarColor:  "plain",
+		Icons:     "auto",
+		StaleDays: 365,
+		Snapshots: defaultRetention(),
+	}
+}
+
+// loadConfig returns the analyzer settings, falling back to defaults for
+// anything the user has not set, and applies the shared units and times
+// settings.
+func loadConfig() (analyzeConfig, error) {
+	cfg := defaultConfig()
+	if err := config.Load(configSection, &cfg); err != nil {
+		return defaultConfig(), err
+	}
+	if err := config.ApplyUnits(); err != nil {
+		return defaultConfig(), err
+	}
+	if err := config.ApplyTimes(); err != nil {
+		return defaultConfig(), err
+	}
+	return cfg, nil
+}
+
+// toggleTimes switches between absolute and relative times for this run;
+// the times setting in config.json chooses the one to start with.
+func toggleTimes() string {
+	if humanize.CurrentTimes() == humanize.Absolute {
+		humanize.SetTimes(humanize.Relative)
+		return "Times shown relative to now"
+	}
+	humanize.SetTimes(humanize.Absolute)
+	return "Times shown as local date and time"
+}
+
+// profileLabel names a profile for display.
+func profileLabel(name string) string {
+	if name == "" {
+		return "(base settings)"
+	}
+	return name
+}
@@ -0,0 +1,240 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unsafe"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/policy"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/scan"
+	"golang.org/x/sys/windows"
+)
+
+var (
+	shell32              = windows.NewLazySystemDLL("shell32.dll")
+	procSHFileOperationW = shell32.NewProc("SHFileOperationW")
+)
+
+// SHFileOperation constants (shellapi.h).
+const (
+	foDelete          = 0x0003
+	fofSilent         = 0x0004
+	fofNoConfirmation = 0x0010
+	fofAllowUndo      = 0x0040
+	fofNoErrorUI      = 0x0400
+
+	// Errors for paths the shell cannot handle, from the SHFileOperation
+	// documentation.
+	dePathTooDeep     = 0x79
+	deFileNameTooLong = 0x81
+)
+
+// shFileOpStruct mirrors SHFILEOPSTRUCTW on 64-bit Windows, where the
+// structure uses natural alignment.
+type shFileOpStruct struct {
+	hwnd                  uintptr
+	wFunc                 uint32
+	pFrom                 *uint16
+	pTo                   *uint16
+	fFlags                uint16
+	fAnyOperationsAborted int32
+	hNameMappings         uintptr
+	lpszProgressTitle     *uint16
+}
+
+// recycle moves path to the Recycle Bin without any shell dialogs; the TUI
+// has already asked for confirmation. The shell cannot take paths longer
+// than MAX_PATH, which only a permanent delete can remove.
+func recycle(path string) error {
+	// MAX_PATH counts UTF-16 units, not the bytes of the UTF-8 string.
+	if n := len(utf16.Encode([]rune(path))); n >= windows.MAX_PATH {
+		return fmt.Errorf("the path is too long for the Recycle Bin (%d characters); D deletes it permanently", n)
+	}
+	// pFrom is a list of NUL-terminated names ending with an extra NUL.
+	from, err := windows.UTF16FromString(path)
+	if err != nil {
+		return err
+	}
+	from = append(from, 0)
+
+	op := shFileOpStruct{
+		wFunc:  foDelete,
+		pFrom:  &from[0],
+		fFlags: fofAllowUndo | fofNoConfirmation | fofSilent | fofNoErrorUI,
+	}
+	switch r, _, _ := procSHFileOperationW.Call(uintptr(unsafe.Pointer(&op))); r {
+	case 0:
+	case dePathTooDeep, deFileNameTooLong:
+		return errors.New("it holds paths too long for the Recycle Bin; D deletes it permanently")
+	default:
+		return fmt.Errorf("SHFileOperation failed with code 0x%x", r)
+	}
+	if op.fAnyOperationsAborted != 0 {
+		return errors.New("the operation was cancelled")
+	}
+	return nil
+}
+
+// deleteBlocked explains why machine policy forbids deleting e, or returns "".
+func deleteBlocked(e Entry) string {
+	p := policy.Get()
+	switch {
+	case p.DisableFileDeletion:
+		return "Deleting is " + policy.DisabledMessage
+	case p.Excluded(e.Path):
+		return fmt.Sprintf("%s is protected by a policy exclusion", e.Name)
+	}
+	return ""
+}
+
+type deleteMsg struct {
+	entry     Entry
+	permanent bool
+	err       error
+}
+
+func recycleCmd(e Entry) tea.Cmd {
+	return func() tea.Msg {
+		return deleteMsg{entry: e, err: recycle(e.Path)}
+	}
+}
+
+// handleConfirmKey answers the pending delete prompt: 'y' goes ahead, any
+// other key cancels.
+func (m model) handleConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	e := *m.confirm
+	m.confirm = nil
+	if msg.String() != "y" && msg.String() != "Y" {
+		m.status = "Delete cancelled"
+		return m, nil
+	}
+	m.status = fmt.Sprintf("Moving %s to the Recycle Bin...", e.Name)
+	return m, recycleCmd(e)
+}
+
+// renderConfirm is the prompt shown in place of the status line.
+func (m model) renderConfirm() string {
+	e := m.confirm
+	kind := "file"
+	if e.IsDir {
+		kind = "folder"
+	}
+	return warnStyle.Render(fmt.Sprintf("Move %s %q (%s) to the Recycle Bin? y/N", kind, e.Name, humanize.Bytes(e.Size)))
+}
+
+// dirListing is a scanned directory kept for navigating back without a rescan.
+type dirListing struct {
+	path       string
+	entries    []Entry
+	totalSize  int64
+	largest    []Entry          // largest files below, when the scan collected them
+	links      bool             // hard links were detected, see scan.Links
+	follow     bool             // links and junctions were followed
+	streams    bool             // alternate data streams were counted
+	unreadable *scan.Unreadable // what the scan skipped; nil when not known
+	savedAt    time.Time        // set while the listing is from an earlier session and unverified
+	partial    bool             // the scan was stopped; sizes are incomplete
+}
+
+// dirCache maps a directory path (lower-cased) to its last scan.
+type dirCache map[string]dirListing
+
+func cacheKey(path string) string {
+	return strings.ToLower(filepath.Clean(path))
+}
+
+// isUnder reports whether path lies inside dir. Both must be cache keys.
+func isUnder(path, dir string) bool {
+	// Drive roots ("c:\") already end in a separator.
+	if !strings.HasSuffix(dir, `\`) {
+		dir += `\`
+	}
+	return strings.HasPrefix(path, dir)
+}
+
+// remove drops a deleted file or folder from every cached directory: the
+// entry itself disappears from its parent's listing and the entries leading
+// to it in ancestor listings shrink by its sizes, file and folder count.
+func (c dirCache) remove(deleted Entry) {
+	target := cacheKey(deleted.Path)
+	dirs := deleted.Dirs
+	if deleted.IsDir {
+		dirs++
+	}
+	for dir, listing := range c {
+		if !isUnder(target, dir) {
+			continue
+		}
+
+		entries := make([]Entry, 0, len(listing.entries))
+		for _, e := range listing.entries {
+			key := cacheKey(e.Path)
+			switch {
+			case key == target:
+				continue
+			case isUnder(target, key):
+				e.Size = max(e.Size-deleted.Size, 0)
+				e.Alloc = max(e.Alloc-deleted.Alloc, 0)
+				e.Cloud = max(e.Cloud-deleted.Cloud, 0)
+				e.Linked = max(e.Linked-deleted.Linked, 0)
+				e.LinkedAlloc = max(e.LinkedAlloc-deleted.LinkedAlloc, 0)
+				e.Streams = max(e.Streams-deleted.Streams, 0)
+				e.Files = max(e.Files-deleted.Files, 0)
+				e.Dirs = max(e.Dirs-dirs, 0)
+			}
+			entries = append(entries, e)
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Size > entries[j].Size
+		})
+
+		listing.entries = entries
+		listing.totalSize = max(listing.totalSize-deleted.Size, 0)
+		if listing.largest != nil {
+			largest := make([]Entry, 0, len(listing.largest))
+			for _, f := range listing.largest {
+				if key := cacheKey(f.Path); key != target && !isUnder(key, target) {
+					largest = append(largest, f)
+				}
+			}
+			listing.largest = largest
+		}
+		c[dir] = listing
+	}
+	// The folder itself (and anything below it) is gone.
+	for dir := range c {
+		if dir == target || isUnder(dir, target) {
+			delete(c, dir)
+		}
+	}
+}
+
+// applyDelete updates the listing after e was removed from disk, keeping
+// the selection on the same row where possible.
+func (m model) applyDelete(e Entry) model {
+	m.cache.remove(e)
+	m.marks.drop(e.Path)
+	if listing, ok := m.cache[cacheKey(m.path)]; ok {
+		m.entries = m.shown(listing.entries)
+		m.totalSize = listing.totalSize
+	}
+	if m.selected >= len(m.entries) {
+		m.selected = len(m.entries) - 1
+	}
+	if m.selected < 0 {
+		m.selected = 0
+	}
+	if m.offset > m.selected {
+		m.offset = m.selected
+	}
+	return m
+}
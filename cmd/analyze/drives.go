@@ -0,0 +1,201 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// Started without a path, analyze lists the mounted drives with how full
+// each one is and what their Recycle Bins hold, and Enter scans the
+// selected one. ← at the root of a drive comes back to the list.
+
+// driveInfo is one mounted volume.
+type driveInfo struct {
+	root   string // "C:\"
+	fsType string
+	total  uint64
+	used   uint64
+	free   uint64
+	err    error // the usage could not be read, e.g. an empty card reader
+
+	recycled      int64 // in the Recycle Bin
+	recycledItems int64
+}
+
+// drivePicker is the start screen.
+type drivePicker struct {
+	drives   []driveInfo
+	selected int
+	loading  bool
+	err      error
+	focus    string // root to select once the list arrives
+	confirm  bool   // waiting for y to empty the selected Recycle Bin
+}
+
+type drivesMsg struct {
+	drives []driveInfo
+	err    error
+}
+
+// drivesCmd reads the mounted volumes. Usage is read here rather than in
+// the view, since a disconnected network drive can take seconds to answer.
+func drivesCmd() tea.Cmd {
+	return func() tea.Msg {
+		partitions, err := disk.Partitions(false)
+		if err != nil {
+			return drivesMsg{err: fmt.Errorf("partitions: %w", err)}
+		}
+		drives := make([]driveInfo, 0, len(partitions))
+		for _, p := range partitions {
+			d := driveInfo{root: p.Mountpoint + `\`, fsType: p.Fstype}
+			if usage, err := disk.Usage(d.root); err == nil {
+				d.total, d.used, d.free = usage.Total, usage.Used, usage.Free
+			} else {
+				d.err = err
+			}
+			if d.err == nil {
+				d.recycled, d.recycledItems, _ = recycleBin(d.root)
+			}
+			drives = append(drives, d)
+		}
+		return drivesMsg{drives: drives}
+	}
+}
+
+// openDrives shows the drive list, with the drive of the current path
+// selected.
+func (m model) openDrives() (model, tea.Cmd) {
+	if m.run != nil {
+		m.run.cancel()
+		m.run = nil
+	}
+	m.scanning = false
+	m.drives = &drivePicker{loading: true, focus: filepath.VolumeName(m.path) + `\`}
+	return m, drivesCmd()
+}
+
+func (m model) applyDrives(msg drivesMsg) model {
+	if m.drives == nil {
+		return m
+	}
+	p := m.drives
+	p.loading = false
+	p.drives, p.err = msg.drives, msg.err
+	for i, d := range p.drives {
+		if strings.EqualFold(d.root, p.focus) {
+			p.selected = i
+		}
+	}
+	return m
+}
+
+// handleDrivesKey handles keys while the drive list is shown.
+func (m model) handleDrivesKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.drives
+	if p.confirm {
+		p.confirm = false
+		if msg.String() != "y" && msg.String() != "Y" {
+			m.status = "Recycle Bin left as it is"
+			return m, nil
+		}
+		d := p.drives[p.selected]
+		m.status = "Emptying the Recycle Bin on " + d.root + "..."
+		return m, emptyRecycleCmd(d)
+	}
+	switch msg.String() {
+	case "q", "ctrl+c", "esc":
+		return m, tea.Quit
+	case "up", "k":
+		p.selected = max(p.selected-1, 0)
+	case "down", "j":
+		p.selected = min(p.selected+1, max(len(p.drives)-1, 0))
+	case "r":
+		if !p.loading {
+			p.loading = true
+			return m, drivesCmd()
+		}
+	case "e":
+		if !p.loading {
+			m = m.confirmEmpty()
+		}
+	case "enter", "right", "l":
+		if p.selected >= len(p.drives) {
+			return m, nil
+		}
+		d := p.drives[p.selected]
+		if d.err != nil {
+			m.status = fmt.Sprintf("Cannot read %s: %v", d.root, d.err)
+			return m, nil
+		}
+		m.drives = nil
+		m.path = d.root
+		m.history = nil
+		m.selected, m.offset = 0, 0
+		m.status = ""
+		if len(m.cache) == 0 {
+			// The first drive picked; read the saved scans first.
+			m.scanning = true
+			m.status = "Scanning..."
+			return m, tea.Batch(m.restoreCmd(), tickCmd())
+		}
+		return m.load()
+	}
+	return m, nil
+}
+
+func (m model) renderDrives() string {
+	p := m.drives
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("💽 Pick a drive to analyze"))
+	b.WriteString("\n\n")
+	switch {
+	case p.err != nil:
+		b.WriteString(warnStyle.Render(fmt.Sprintf("  Cannot list the drives: %v", p.err)))
+		b.WriteString("\n")
+	case p.drives == nil:
+		b.WriteString(dimStyle.Render("  Reading the drives..."))
+		b.WriteString("\n")
+	}
+
+	for i, d := range p.drives {
+		var line string
+		if d.err != nil {
+			line = fmt.Sprintf("%-5s %-6s %s", d.root, d.fsType, "not readable")
+		} else {
+			filled := barWidth(int64(d.used), int64(d.total), false, 20)
+			bar := strings.Repeat("█", filled) + strings.Repeat("░", 20-filled)
+			if i != p.selected {
+				bar = barStyle.Render(bar)
+			}
+			line = fmt.Sprintf("%-5s %-6s %s %9s used of %9s • %9s free", d.root, d.fsType, bar,
+				humanize.Bytes(d.used), humanize.Bytes(d.total), humanize.Bytes(d.free))
+			if d.recycled > 0 {
+				line += fmt.Sprintf(" • %9s in Recycle Bin", humanize.Bytes(d.recycled))
+			}
+		}
+		switch {
+		case i == p.selected:
+			b.WriteString(selectedStyle.Render(line))
+		case d.err != nil:
+			b.WriteString(dimStyle.Render(line))
+		default:
+			b.WriteString(normalStyle.Render(line))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	if m.status != "" {
+		b.WriteString(statusStyle.Render(m.status))
+		b.WriteString("\n")
+	}
+	b.WriteString(dimStyle.Render("↑/↓ select • Enter scan • e empty Recycle Bin • r reload • q quit"))
+	return b.String()
+}
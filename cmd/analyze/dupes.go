@@ -0,0 +1,635 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/usage"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/scan"
+	"golang.org/x/sys/windows"
+)
+
+// u finds duplicate files below the current folder. Files are grouped by
+// size; those sharing a size are hashed by a pool of workers, first their
+// opening bytes and then, for those still alike, all of them. The sets of
+// identical files are listed by the space their extra copies take. Space
+// selects copies in a set, and d recycles the selected copies or L
+// replaces them with hard links to the copy kept, which frees the space
+// and leaves every path in place. A set always keeps one copy unselected.
+// Files already hard-linked together count as one copy, and cloud
+// placeholders are left out rather than downloaded.
+
+const (
+	// dupMinSize is the smallest file worth hashing.
+	dupMinSize = 64 << 10
+	// dupHeadSize is how much of each file the first pass hashes.
+	dupHeadSize = 64 << 10
+	// dupWorkers is how many files are hashed at once.
+	dupWorkers = 4
+)
+
+// dupSet is a set of files with the same contents.
+type dupSet struct {
+	size  int64
+	files []Entry // shortest path first
+}
+
+// wasted is the space the extra copies take.
+func (s dupSet) wasted() int64 {
+	return s.size * int64(len(s.files)-1)
+}
+
+// dupSearch is shared with the goroutines looking for duplicates.
+type dupSearch struct {
+	path   string
+	cancel context.CancelFunc
+	files  atomic.Int64 // files listed
+	total  atomic.Int64 // bytes to hash
+	hashed atomic.Int64 // bytes hashed
+}
+
+type dupMsg struct {
+	path string
+	sets []dupSet
+	err  error
+}
+
+// dupView lists the duplicate sets of one folder.
+type dupView struct {
+	sets     []dupSet // most wasted first
+	chosen   map[string]bool
+	selected int // row, see rows
+	offset   int
+}
+
+// dupRow is a line of the view: a set's header when file is -1.
+type dupRow struct {
+	set, file int
+}
+
+// hashJob is one file to hash, and what hashing it found.
+type hashJob struct {
+	entry Entry
+	id    fileID
+	sum   [sha256.Size]byte
+	ok    bool
+}
+
+// fileID identifies a file across its hard links.
+type fileID struct {
+	volume, high, low uint32
+}
+
+func (m model) dupCmd(ctx context.Context, s *dupSearch) tea.Cmd {
+	walk := &scan.Counters{Exclude: m.exclude.hook(), FollowLinks: m.follow}
+	return func() tea.Msg {
+		sets, err := findDuplicates(ctx, s, walk)
+		return dupMsg{path: s.path, sets: sets, err: err}
+	}
+}
+
+// findDuplicates groups the files below s.path by size, then by the hash
+// of their first bytes and then of their contents. walk decides which files
+// are looked at, as in the folder scan.
+func findDuplicates(ctx context.Context, s *dupSearch, walk *scan.Counters) ([]dupSet, error) {
+	bySize := make(map[int64][]string)
+	add := func(dir, name string, size int64) {
+		s.files.Add(1)
+		if size >= dupMinSize {
+			bySize[size] = append(bySize[size], filepath.Join(dir, name))
+		}
+	}
+
+	fromMFT := false
+	if vol := mftVolume(s.path); vol != "" && walk.Exclude == nil && !walk.FollowLinks {
+		if idx, err := loadMFTIndex(ctx, vol, new(scan.Counters)); err == nil {
+			fromMFT = idx.eachFile(s.path, add) == nil
+		}
+	}
+	if !fromMFT {
+		err := scan.Walk(ctx, s.path, walk, func(p string, info fs.FileInfo) {
+			add(filepath.Dir(p), info.Name(), info.Size())
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Files of a size no other file has cannot have a duplicate.
+	var jobs []hashJob
+	for size, paths := range bySize {
+		if len(paths) < 2 {
+			continue
+		}
+		for _, p := range paths {
+			jobs = append(jobs, hashJob{entry: Entry{Name: filepath.Base(p), Path: p, Size: size, Alloc: size, Files: 1}})
+		}
+		// The opening bytes, then at most all of each file again.
+		s.total.Add((min(size, dupHeadSize) + size) * int64(len(paths)))
+	}
+
+	hashAll(ctx, jobs, dupHeadSize, s)
+	groups := groupHashed(jobs)
+	var full []hashJob
+	var sets []dupSet
+	var rest int64
+	for _, g := range groups {
+		if g[0].entry.Size <= dupHeadSize {
+			sets = append(sets, newDupSet(g))
+			continue
+		}
+		full = append(full, g...)
+		rest += g[0].entry.Size * int64(len(g))
+	}
+	s.total.Store(s.hashed.Load() + rest)
+	hashAll(ctx, full, -1, s)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, g := range groupHashed(full) {
+		sets = append(sets, newDupSet(g))
+	}
+
+	sort.Slice(sets, func(i, j int) bool { return sets[i].wasted() > sets[j].wasted() })
+	return sets, nil
+}
+
+// hashAll hashes the first limit bytes of each job's file (all of it when
+// limit is negative) with dupWorkers at a time. Jobs that cannot be read
+// are left with ok false.
+func hashAll(ctx context.Context, jobs []hashJob, limit int64, s *dupSearch) {
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range dupWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				jobs[i].ok = hashFile(ctx, &jobs[i], limit, s) == nil
+			}
+		}()
+	}
+	for i := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// hashFile fills in the file's ID, modification time and hash.
+func hashFile(ctx context.Context, job *hashJob, limit int64, s *dupSearch) error {
+	info, err := os.Lstat(job.entry.Path)
+	if err != nil {
+		return err
+	}
+	if onlineOnly(info) {
+		return errors.New("cloud placeholder")
+	}
+	if info.Size() != job.entry.Size {
+		return errors.New("changed since listed")
+	}
+	job.entry.ModTime = info.ModTime()
+
+	f, err := os.Open(job.entry.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var bhi windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(windows.Handle(f.Fd()), &bhi); err != nil {
+		return err
+	}
+	job.id = fileID{bhi.VolumeSerialNumber, bhi.FileIndexHigh, bhi.FileIndexLow}
+
+	h := sha256.New()
+	var r io.Reader = f
+	if limit >= 0 {
+		r = io.LimitReader(f, limit)
+	}
+	buf := make([]byte, 256<<10)
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		n, err := r.Read(buf)
+		h.Write(buf[:n])
+		s.hashed.Add(int64(n))
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	copy(job.sum[:], h.Sum(nil))
+	return nil
+}
+
+// groupHashed groups the hashed jobs by size and hash, keeping one job
+// per file so hard links to each other count once, and returns the groups
+// of two or more.
+func groupHashed(jobs []hashJob) [][]hashJob {
+	type key struct {
+		size int64
+		sum  [sha256.Size]byte
+	}
+	groups := make(map[key][]hashJob)
+	seen := make(map[fileID]bool)
+	for _, j := range jobs {
+		if !j.ok || seen[j.id] {
+			continue
+		}
+		seen[j.id] = true
+		k := key{j.entry.Size, j.sum}
+		groups[k] = append(groups[k], j)
+	}
+	var list [][]hashJob
+	for _, g := range groups {
+		if len(g) > 1 {
+			list = append(list, g)
+		}
+	}
+	return list
+}
+
+func newDupSet(g []hashJob) dupSet {
+	s := dupSet{size: g[0].entry.Size}
+	for _, j := range g {
+		s.files = append(s.files, j.entry)
+	}
+	sort.Slice(s.files, func(i, j int) bool {
+		if len(s.files[i].Path) != len(s.files[j].Path) {
+			return len(s.files[i].Path) < len(s.files[j].Path)
+		}
+		return s.files[i].Path < s.files[j].Path
+	})
+	return s
+}
+
+// status describes the search while it runs.
+func (s *dupSearch) status(frame string) string {
+	total, hashed := s.total.Load(), s.hashed.Load()
+	if total == 0 {
+		return fmt.Sprintf("%s Looking for duplicates... %d files listed", frame, s.files.Load())
+	}
+	return fmt.Sprintf("%s Comparing files of the same size... %s of at most %s read", frame, humanize.Bytes(hashed), humanize.Bytes(total))
+}
+
+// startDupSearch looks for duplicates below the current folder.
+func (m model) startDupSearch() (tea.Model, tea.Cmd) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &dupSearch{path: m.path, cancel: cancel}
+	m.dupSearch = s
+	m.status = s.status(spinnerFrames[m.spinner])
+	return m, tea.Batch(m.dupCmd(ctx, s), tickCmd())
+}
+
+// handleDupSearchKey stops the search on Esc.
+func (m model) handleDupSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		m.dupSearch.cancel()
+		return m, tea.Quit
+	case "esc":
+		m.dupSearch.cancel()
+	}
+	return m, nil
+}
+
+func (m model) applyDups(msg dupMsg) model {
+	if m.dupSearch == nil || m.dupSearch.path != msg.path {
+		return m
+	}
+	m.dupSearch.cancel()
+	m.dupSearch = nil
+	switch {
+	case errors.Is(msg.err, context.Canceled):
+		m.status = "Duplicate search stopped"
+		return m
+	case msg.err != nil:
+		m.status = fmt.Sprintf("Error: %v", msg.err)
+		return m
+	case len(msg.sets) == 0:
+		m.status = "No duplicate files of " + humanize.Bytes(dupMinSize) + " or more"
+		return m
+	}
+	usage.Run("analyze.duplicates")
+	m.dups = &dupView{sets: msg.sets, chosen: make(map[string]bool)}
+	m.dups.selected = 1
+	m.status = m.dups.summary()
+	return m
+}
+
+// summary totals the sets and what is selected.
+func (v *dupView) summary() string {
+	var wasted int64
+	for _, s := range v.sets {
+		wasted += s.wasted()
+	}
+	line := fmt.Sprintf("%d sets of duplicates • %s in extra copies", len(v.sets), humanize.Bytes(wasted))
+	if files := v.chosenFiles(); len(files) > 0 {
+		var size int64
+		for _, e := range files {
+			size += e.Size
+		}
+		line += fmt.Sprintf(" • %s selected (%s)", itemCount(len(files)), humanize.Bytes(size))
+	}
+	return line
+}
+
+// rows flattens the sets into lines: each set's header, then its files.
+func (v *dupView) rows() []dupRow {
+	var rows []dupRow
+	for i, s := range v.sets {
+		rows = append(rows, dupRow{i, -1})
+		for j := range s.files {
+			rows = append(rows, dupRow{i, j})
+		}
+	}
+	return rows
+}
+
+// chosenFiles are the selected copies, in set order.
+func (v *dupView) chosenFiles() []Entry {
+	var files []Entry
+	for _, s := range v.sets {
+		for _, e := range s.files {
+			if v.chosen[cacheKey(e.Path)] {
+				files = append(files, e)
+			}
+		}
+	}
+	return files
+}
+
+// keeps is the copy of the set that stays: the first not selected.
+func (v *dupView) keeps(s dupSet) Entry {
+	for _, e := range s.files {
+		if !v.chosen[cacheKey(e.Path)] {
+			return e
+		}
+	}
+	return Entry{}
+}
+
+// toggle selects or unselects a copy, keeping one copy of the set
+// unselected.
+func (v *dupView) toggle(s dupSet, e Entry) bool {
+	key := cacheKey(e.Path)
+	if v.chosen[key] {
+		delete(v.chosen, key)
+		return true
+	}
+	n := 0
+	for _, f := range s.files {
+		if v.chosen[cacheKey(f.Path)] {
+			n++
+		}
+	}
+	if n == len(s.files)-1 {
+		return false
+	}
+	v.chosen[key] = true
+	return true
+}
+
+// without drops the files done by a batch, and sets left with one file.
+func (v *dupView) without(done []Entry) {
+	gone := make(map[string]bool, len(done))
+	for _, e := range done {
+		gone[cacheKey(e.Path)] = true
+		delete(v.chosen, cacheKey(e.Path))
+	}
+	sets := v.sets[:0]
+	for _, s := range v.sets {
+		files := s.files[:0]
+		for _, e := range s.files {
+			if !gone[cacheKey(e.Path)] {
+				files = append(files, e)
+			}
+		}
+		s.files = files
+		if len(files) > 1 {
+			sets = append(sets, s)
+		}
+	}
+	v.sets = sets
+	rows := v.rows()
+	v.selected = min(v.selected, max(len(rows)-1, 0))
+	if v.selected < len(rows) && rows[v.selected].file < 0 && v.selected+1 < len(rows) {
+		v.selected++
+	}
+	v.offset = min(v.offset, v.selected)
+}
+
+// linkTargets pairs each selected copy with the copy kept in its set, for
+// L. Copies on another volume than the kept one cannot be linked.
+func (v *dupView) linkTargets() (map[string]string, error) {
+	targets := make(map[string]string)
+	for _, s := range v.sets {
+		keep := v.keeps(s)
+		for _, e := range s.files {
+			if !v.chosen[cacheKey(e.Path)] {
+				continue
+			}
+			if !strings.EqualFold(filepath.VolumeName(e.Path), filepath.VolumeName(keep.Path)) {
+				return nil, fmt.Errorf("%s is on another drive than the copy kept, so it cannot be a hard link", e.Name)
+			}
+			targets[cacheKey(e.Path)] = keep.Path
+		}
+	}
+	return targets, nil
+}
+
+// linkDuplicate replaces path with a hard link to keep: the link is made
+// next to it under a temporary name, then renamed over it, so path is
+// never missing.
+func linkDuplicate(path, keep string) error {
+	a, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	b, err := os.Stat(keep)
+	if err != nil {
+		return err
+	}
+	if a.Size() != b.Size() {
+		return errors.New("it changed since it was compared")
+	}
+	tmp := path + ".winmole-link"
+	if err := os.Link(keep, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// handleDupsKey handles keys while the duplicate sets are listed.
+func (m model) handleDupsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.dups
+	rows := v.rows()
+	var row dupRow
+	if v.selected < len(rows) {
+		row = rows[v.selected]
+	}
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "u", "esc", "q":
+		m.dups = nil
+		m.status = m.totalStatus()
+		return m, nil
+	case "up", "k":
+		for i := v.selected - 1; i >= 0; i-- {
+			if rows[i].file >= 0 {
+				v.selected = i
+				break
+			}
+		}
+		// Show the set's header above its first file.
+		v.offset = min(v.offset, max(v.selected-1, 0))
+	case "down", "j":
+		for i := v.selected + 1; i < len(rows); i++ {
+			if rows[i].file >= 0 {
+				v.selected = i
+				break
+			}
+		}
+		if h := m.viewportHeight(); v.selected >= v.offset+h {
+			v.offset = v.selected - h + 1
+		}
+	case " ":
+		if row.file < 0 {
+			break
+		}
+		s := v.sets[row.set]
+		if !v.toggle(s, s.files[row.file]) {
+			m.status = "One copy of each set stays; unselect another copy first"
+			return m, nil
+		}
+	case "A":
+		// Select every copy but the first of each set.
+		for _, s := range v.sets {
+			for i, e := range s.files {
+				if i > 0 {
+					v.chosen[cacheKey(e.Path)] = true
+				} else {
+					delete(v.chosen, cacheKey(e.Path))
+				}
+			}
+		}
+	case "d":
+		files := v.chosenFiles()
+		if len(files) == 0 {
+			m.status = "Space selects the copies to recycle"
+			return m, nil
+		}
+		return m.confirmBatch(batchRecycle, files), nil
+	case "L":
+		files := v.chosenFiles()
+		if len(files) == 0 {
+			m.status = "Space selects the copies to replace with hard links"
+			return m, nil
+		}
+		targets, err := v.linkTargets()
+		if err != nil {
+			m.status = err.Error()
+			return m, nil
+		}
+		m = m.confirmBatch(batchLink, files)
+		if m.batch != nil {
+			m.batch.links = targets
+		}
+		return m, nil
+	case "y":
+		if row.file >= 0 {
+			m = m.copyPath(v.sets[row.set].files[row.file])
+		}
+		return m, nil
+	case "o", "O":
+		if row.file >= 0 {
+			m = m.openAction(v.sets[row.set].files[row.file], msg.String() == "O")
+		}
+		return m, nil
+	case "enter", "right", "l":
+		if row.file < 0 {
+			break
+		}
+		file := v.sets[row.set].files[row.file]
+		traceAction("jump", file.Path, m.redactor)
+		m.dups = nil
+		m.history = append(m.history, historyEntry{
+			Path:     m.path,
+			Selected: m.selected,
+			Offset:   m.offset,
+		})
+		m.path = filepath.Dir(file.Path)
+		m.selected, m.offset = 0, 0
+		m.focus = file.Path
+		return m.load()
+	}
+	m.status = v.summary()
+	return m, nil
+}
+
+// renderDups lists each set with its extra space, then its copies; ✓
+// marks the copies selected.
+func (m model) renderDups() string {
+	v := m.dups
+	if len(v.sets) == 0 {
+		return dimStyle.Render("  (no duplicates left)") + "\n"
+	}
+	var b strings.Builder
+	rows := v.rows()
+	end := min(v.offset+m.viewportHeight(), len(rows))
+	for i := v.offset; i < end; i++ {
+		r := rows[i]
+		s := v.sets[r.set]
+		if r.file < 0 {
+			b.WriteString(sizeStyle.Render(fmt.Sprintf("%10s", humanize.Bytes(s.wasted()))))
+			b.WriteString(dimStyle.Render(fmt.Sprintf("  %d copies of %s", len(s.files), humanize.Bytes(s.size))))
+			b.WriteString("\n")
+			continue
+		}
+		e := s.files[r.file]
+		rel, err := filepath.Rel(m.path, e.Path)
+		if err != nil {
+			rel = e.Path
+		}
+		check := " "
+		if v.chosen[cacheKey(e.Path)] {
+			check = "✓"
+		}
+		line := fmt.Sprintf("            %s %s %s", check, m.icons.icon(e, m.categories), rel)
+		switch {
+		case i == v.selected:
+			b.WriteString(selectedStyle.Render(line))
+		case check != " ":
+			b.WriteString(warnStyle.Render(line))
+		default:
+			b.WriteString(normalStyle.Render(line))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,49 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// While a folder scan runs the status line shows the bytes measured so
+// far. When the folder was measured before, in this session or a saved one,
+// or its size is known from the listing of the folder above, that size is
+// taken as the goal for a percentage and a time left. Folders that grew
+// since make the estimate run out early; it then stops at 99%.
+
+// expectedSize is what m.path measured last time, 0 when unknown. MFT
+// scans read the whole volume whatever the folder, so they get no
+// estimate.
+func (m model) expectedSize() int64 {
+	if vol := mftVolume(m.path); vol != "" && !m.follow && !m.streams {
+		return 0
+	}
+	key := cacheKey(m.path)
+	if l, ok := m.cache[key]; ok && !l.partial {
+		return l.totalSize
+	}
+	if l, ok := m.cache[cacheKey(filepath.Dir(m.path))]; ok && !l.partial {
+		if e, ok := l.entryLeadingTo(key); ok && cacheKey(e.Path) == key {
+			return e.Size
+		}
+	}
+	return 0
+}
+
+// estimate is the percentage and time left after measuring scanned bytes,
+// or "" while there is too little to go on.
+func (r *scanRun) estimate(scanned int64) string {
+	elapsed := time.Since(r.started)
+	if r.expected <= 0 || scanned <= 0 || elapsed < 2*time.Second {
+		return ""
+	}
+	done := float64(scanned) / float64(r.expected)
+	if done >= 0.99 {
+		return " • 99%"
+	}
+	left := time.Duration(float64(elapsed) * (1 - done) / done)
+	return fmt.Sprintf(" • %.0f%%, about %s left", done*100, left.Round(time.Second))
+}
@@ -0,0 +1,177 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/winmole/winmole/internal/usage"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// analyze.exclude in config.json lists paths that scans leave out, as if
+// they were not there, in the style of .gitignore:
+//
+//	node_modules        a file or folder of that name at any depth
+//	*.vhdx              names matching a glob at any depth
+//	**/obj/Debug        a path ending in obj\Debug
+//	C:\Windows\**       everything below C:\Windows, and the folder itself
+//	D:\Steam\           a trailing separator matches folders only
+//
+// Matching ignores case, and / and \ are the same. X in the list excludes
+// the selected entry for the rest of the session; the totals above it
+// shrink at once, as after a delete. Exclusions are not applied to the MFT,
+// which holds totals for every folder, so scans walk the folders instead
+// while any are set.
+
+// exclusion is one compiled pattern.
+type exclusion struct {
+	segments []string // lower-cased; "**" matches any number of segments
+	dirOnly  bool
+}
+
+// exclusions are the patterns from the config plus the entries excluded
+// with X. They are read by scans running in the background, hence the
+// lock.
+type exclusions struct {
+	mu       sync.RWMutex
+	patterns []string
+	compiled []exclusion
+	paths    map[string]bool // excluded with X, by cacheKey
+}
+
+func newExclusions(patterns []string) *exclusions {
+	x := &exclusions{paths: make(map[string]bool)}
+	for _, p := range patterns {
+		if c, ok := compileExclusion(p); ok {
+			x.patterns = append(x.patterns, p)
+			x.compiled = append(x.compiled, c)
+		}
+	}
+	return x
+}
+
+func compileExclusion(pattern string) (exclusion, bool) {
+	p := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(pattern), "/", `\`))
+	var c exclusion
+	if strings.HasSuffix(p, `\`) && len(p) > 1 {
+		c.dirOnly = true
+		p = strings.TrimRight(p, `\`)
+	}
+	if p == "" {
+		return c, false
+	}
+	c.segments = strings.Split(p, `\`)
+	// Patterns that do not start at a drive or share match at any depth.
+	if !filepath.IsAbs(p) && !strings.HasPrefix(p, `\\`) && c.segments[0] != "**" {
+		c.segments = append([]string{"**"}, c.segments...)
+	}
+	return c, true
+}
+
+// matchSegments matches path against pat one segment at a time.
+func matchSegments(pat, path []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			pat = pat[1:]
+			if len(pat) == 0 {
+				return true
+			}
+			for i := range path {
+				if matchSegments(pat, path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 {
+			return false
+		}
+		if ok, err := filepath.Match(pat[0], path[0]); !ok || err != nil {
+			return false
+		}
+		pat, path = pat[1:], path[1:]
+	}
+	return len(path) == 0
+}
+
+// match reports whether path is excluded. It is the scans' Exclude hook.
+func (x *exclusions) match(path string, isDir bool) bool {
+	key := cacheKey(path)
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if x.paths[key] {
+		return true
+	}
+	segments := strings.Split(strings.TrimRight(key, `\`), `\`)
+	for _, c := range x.compiled {
+		if (!c.dirOnly || isDir) && matchSegments(c.segments, segments) {
+			return true
+		}
+	}
+	return false
+}
+
+// add excludes path for the rest of the session.
+func (x *exclusions) add(path string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.paths[cacheKey(path)] = true
+}
+
+// withPatterns returns exclusions with patterns in place of x's, keeping
+// the entries excluded with X.
+func (x *exclusions) withPatterns(patterns []string) *exclusions {
+	n := newExclusions(patterns)
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	for p := range x.paths {
+		n.paths[p] = true
+	}
+	return n
+}
+
+// count is how many patterns and paths are excluded.
+func (x *exclusions) count() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.patterns) + len(x.paths)
+}
+
+// list is every pattern and path excluded, for telling whether a saved
+// scan was made with the same exclusions.
+func (x *exclusions) list() []string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	list := slices.Clone(x.patterns)
+	for p := range x.paths {
+		list = append(list, p)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// hook is x.match for scan.Counters.Exclude, or nil when nothing can be
+// excluded, which lets scans use the MFT.
+func (x *exclusions) hook() func(string, bool) bool {
+	if x == nil || x.count() == 0 {
+		return nil
+	}
+	return x.match
+}
+
+// excludeSelected leaves the selected entry out for the rest of the
+// session, taking its size off the totals above it.
+func (m model) excludeSelected() model {
+	e := m.entries[m.selected]
+	m.exclude.add(e.Path)
+	m = m.applyDelete(e)
+	usage.Run("analyze.exclude")
+	m.status = fmt.Sprintf("Excluded %s (%s) for this session • Total: %s", e.Name, humanize.Bytes(e.Size), humanize.Bytes(m.totalSize))
+	return m
+}
@@ -0,0 +1,203 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/winmole/winmole/internal/config"
+	"github.com/winmole/winmole/internal/otlp"
+	"github.com/winmole/winmole/internal/redact"
+	"github.com/winmole/winmole/internal/scanprogress"
+	"github.com/winmole/winmole/pkg/scan"
+)
+
+// exportRow is one file or folder in an export. Depth is 1 for the entries
+// of the exported folder, 2 for their children and so on.
+type exportRow struct {
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	Alloc int64  `json:"allocated"`
+	Cloud int64  `json:"cloud"`
+	Files int64  `json:"files"`
+	Dirs  int64  `json:"dirs"`
+	Depth int    `json:"depth"`
+	IsDir bool   `json:"isDir"`
+}
+
+// exportDoc is the JSON export.
+type exportDoc struct {
+	Root      string      `json:"root"`
+	TotalSize int64       `json:"totalSize"`
+	ScannedAt time.Time   `json:"scannedAt"`
+	Entries   []exportRow `json:"entries"`
+}
+
+// exportRows flattens the scanned tree below root, parents before their
+// children. Folders the user has opened are expanded from the cache; others
+// appear with their totals only.
+func (c dirCache) exportRows(root string, r *redact.Redactor) []exportRow {
+	var rows []exportRow
+	var walk func(dir string, depth int)
+	walk = func(dir string, depth int) {
+		for _, e := range c[cacheKey(dir)].entries {
+			rows = append(rows, exportRow{
+				Path:  r.String(e.Path),
+				Size:  e.Size,
+				Alloc: e.Alloc,
+				Cloud: e.Cloud,
+				Files: e.Files,
+				Dirs:  e.Dirs,
+				Depth: depth,
+				IsDir: e.IsDir,
+			})
+			if _, ok := c[cacheKey(e.Path)]; ok && e.IsDir {
+				walk(e.Path, depth+1)
+			}
+		}
+	}
+	walk(root, 1)
+	return rows
+}
+
+// writeExport writes the tree below root to path as CSV when the name ends
+// in .csv and as JSON otherwise.
+func writeExport(path string, c dirCache, root string, r *redact.Redactor) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create export: %w", err)
+	}
+	defer f.Close()
+
+	rows := c.exportRows(root, r)
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		err = writeCSV(f, rows)
+	} else {
+		err = writeJSON(f, c, root, rows, r)
+	}
+	if err != nil {
+		return fmt.Errorf("write export: %w", err)
+	}
+	return f.Close()
+}
+
+func writeCSV(w io.Writer, rows []exportRow) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"path", "size", "files", "depth", "is_dir", "dirs", "allocated", "cloud"}); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := []string{
+			row.Path,
+			strconv.FormatInt(row.Size, 10),
+			strconv.FormatInt(row.Files, 10),
+			strconv.Itoa(row.Depth),
+			strconv.FormatBool(row.IsDir),
+			strconv.FormatInt(row.Dirs, 10),
+			strconv.FormatInt(row.Alloc, 10),
+			strconv.FormatInt(row.Cloud, 10),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeJSON(w io.Writer, c dirCache, root string, rows []exportRow, r *redact.Redactor) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(exportDoc{
+		Root:      r.String(root),
+		TotalSize: c[cacheKey(root)].totalSize,
+		ScannedAt: time.Now(),
+		Entries:   rows,
+	})
+}
+
+// exportToCache writes an export named after the current time into the
+// cache directory and returns its path.
+func (m model) exportToCache(ext string) (string, error) {
+	dir := config.CacheDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create cache directory: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("analyze-%s%s", time.Now().Format("20060102-150405"), ext))
+	if err := writeExport(path, m.cache, m.path, m.redactor); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// partialTree is a tree being scanned for a headless report. Finished
+// listings can be taken from it while the scan is still running, so a scan
+// left behind on a stuck share still yields what it found.
+type partialTree struct {
+	mu sync.Mutex
+	c  dirCache
+}
+
+func newPartialTree() *partialTree {
+	return &partialTree{c: make(dirCache)}
+}
+
+func (t *partialTree) set(l dirListing) {
+	t.mu.Lock()
+	t.c[cacheKey(l.path)] = l
+	t.mu.Unlock()
+}
+
+// snapshot returns a copy of the listings so far.
+func (t *partialTree) snapshot() dirCache {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := make(dirCache, len(t.c))
+	for key, l := range t.c {
+		c[key] = l
+	}
+	return c
+}
+
+// scanTree scans root and the folders below it down to depth levels into
+// t, leaving out what x excludes and counting alternate data streams when
+// streams is set, and records a span per folder in tr. When ctx is done it
+// stops with ctx.Err(), keeping what it measured. Its progress is
+// published for status --web.
+func scanTree(ctx context.Context, root string, depth int, x *exclusions, streams bool, t *partialTree, tr *otlpTrace) error {
+	progress := &scan.Counters{Exclude: x.hook(), Streams: streams}
+	defer scanprogress.Publish(root, progress)()
+	var visit func(dir string, level int, parent otlp.SpanID) error
+	visit = func(dir string, level int, parent otlp.SpanID) (err error) {
+		span := tr.start(dir, level, parent)
+		entries, totalSize, err := scanDirectory(ctx, dir, progress)
+		defer func() { tr.end(span, entries, totalSize, err) }()
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		t.set(dirListing{path: dir, entries: entries, totalSize: totalSize})
+		if ctx.Err() != nil || level >= depth {
+			return ctx.Err()
+		}
+		for _, e := range entries {
+			if e.IsDir {
+				// Unreadable subfolders keep their totals only.
+				if visit(e.Path, level+1, span.ID) != nil && ctx.Err() != nil {
+					return ctx.Err()
+				}
+			}
+		}
+		return nil
+	}
+	return visit(root, 1, otlp.SpanID{})
+}
@@ -0,0 +1,169 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/usage"
+)
+
+// / narrows the list to names containing the typed text, or matching it as
+// a glob when it has wildcards (*.iso, backup-202?-*). The list follows as
+// you type; Esc clears the filter. The last pattern is also a search: n and
+// N jump to the next and previous match anywhere in the folders scanned so
+// far, opening the folder that holds it.
+
+// filterPrompt is the filter being typed; previous is restored on Esc.
+type filterPrompt struct {
+	input    string
+	previous string
+}
+
+// matchName reports whether name matches pattern, case-insensitively.
+func matchName(pattern, name string) bool {
+	pattern, name = strings.ToLower(pattern), strings.ToLower(name)
+	if strings.ContainsAny(pattern, "*?[") {
+		ok, err := filepath.Match(pattern, name)
+		return ok && err == nil
+	}
+	return strings.Contains(name, pattern)
+}
+
+// filterEntries returns the entries whose name matches pattern, or all of
+// them when pattern is empty.
+func filterEntries(entries []Entry, pattern string) []Entry {
+	if pattern == "" {
+		return entries
+	}
+	var matched []Entry
+	for _, e := range entries {
+		if matchName(pattern, e.Name) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+// refilter shows the current folder's listing with the current filter and
+// order, keeping the selection in range.
+func (m model) refilter() model {
+	listing, ok := m.cache[cacheKey(m.path)]
+	if !ok {
+		return m
+	}
+	m.entries = m.shown(listing.entries)
+	if m.selected >= len(m.entries) {
+		m.selected = max(len(m.entries)-1, 0)
+	}
+	m.offset = min(m.offset, m.selected)
+	return m
+}
+
+// handleFilterKey edits the filter: the list narrows as the pattern is
+// typed, Enter keeps it and Esc restores the one before.
+func (m model) handleFilterKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.filterPrompt
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.filterPrompt = nil
+		m.filter = p.previous
+		return m.refilter(), nil
+	case tea.KeyEnter:
+		m.filterPrompt = nil
+		if p.input != "" {
+			usage.Run("analyze.filter")
+			m.search = p.input
+			m.status = fmt.Sprintf("%d of %d entries match %q • n/N next/previous in all scanned folders",
+				len(m.entries), len(m.cache[cacheKey(m.path)].entries), p.input)
+		}
+		return m, nil
+	case tea.KeyBackspace:
+		if r := []rune(p.input); len(r) > 0 {
+			p.input = string(r[:len(r)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		p.input += string(msg.Runes)
+	}
+	m.filter = p.input
+	m.selected, m.offset = 0, 0
+	return m.refilter(), nil
+}
+
+// nextMatch jumps to the next (or previous) entry matching the last filter
+// in any scanned folder, in path order from the selected entry, wrapping
+// around at the ends.
+func (m model) nextMatch(forward bool) (tea.Model, tea.Cmd) {
+	if m.search == "" {
+		m.status = "Press / to search"
+		return m, nil
+	}
+	var matches []Entry
+	for _, listing := range m.cache {
+		for _, e := range listing.entries {
+			if matchName(m.search, e.Name) {
+				matches = append(matches, e)
+			}
+		}
+	}
+	if len(matches) == 0 {
+		m.status = fmt.Sprintf("No match for %q in the scanned folders", m.search)
+		return m, nil
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return cacheKey(matches[i].Path) < cacheKey(matches[j].Path)
+	})
+
+	from := cacheKey(m.path)
+	if len(m.entries) > 0 {
+		from = cacheKey(m.entries[m.selected].Path)
+	}
+	i := sort.Search(len(matches), func(i int) bool {
+		return cacheKey(matches[i].Path) > from
+	})
+	if !forward {
+		i = sort.Search(len(matches), func(i int) bool {
+			return cacheKey(matches[i].Path) >= from
+		}) - 1
+	}
+	i = (i + len(matches)) % len(matches)
+	target := matches[i]
+	status := fmt.Sprintf("Match %d of %d for %q", i+1, len(matches), m.search)
+
+	parent := filepath.Dir(target.Path)
+	if cacheKey(parent) == cacheKey(m.path) {
+		m.filter = ""
+		m = m.refilter()
+		m.focus = target.Path
+		m = m.selectFocus()
+		m.status = status
+		return m, nil
+	}
+	m.history = append(m.history, historyEntry{
+		Path:     m.path,
+		Selected: m.selected,
+		Offset:   m.offset,
+	})
+	m.path = parent
+	m.selected, m.offset = 0, 0
+	m.focus = target.Path
+	next, cmd := m.load()
+	if nm, ok := next.(model); ok && !nm.scanning {
+		nm.status = status
+		return nm, cmd
+	}
+	return next, cmd
+}
+
+// renderFilterPrompt draws the filter being typed in place of the status
+// line.
+func (m model) renderFilterPrompt() string {
+	return normalStyle.Render("/"+m.filterPrompt.input+"█") +
+		dimStyle.Render(fmt.Sprintf("  %d matches • Enter keep • Esc cancel", len(m.entries)))
+}
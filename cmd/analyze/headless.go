@@ -0,0 +1,46 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/winmole/winmole/internal/redact"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// runHeadless prints the top largest files and folders below root in c to w
+// as "text", "json" or "csv", for scheduled scripts and CI where the TUI is
+// no use.
+func runHeadless(w io.Writer, c dirCache, root string, top int, format string, r *redact.Redactor) error {
+	switch format {
+	case "text", "json", "csv":
+	default:
+		return fmt.Errorf("unknown format %q (use text, json or csv)", format)
+	}
+
+	rows := c.exportRows(root, r)
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Size > rows[j].Size })
+	if top > 0 && len(rows) > top {
+		rows = rows[:top]
+	}
+
+	switch format {
+	case "json":
+		return writeJSON(w, c, root, rows, r)
+	case "csv":
+		return writeCSV(w, rows)
+	}
+
+	fmt.Fprintf(w, "%s  %s\n", r.String(root), humanize.Bytes(c[cacheKey(root)].totalSize))
+	for _, row := range rows {
+		kind := "file"
+		if row.IsDir {
+			kind = "dir "
+		}
+		fmt.Fprintf(w, "%10s  %s  %s\n", humanize.Bytes(row.Size), kind, row.Path)
+	}
+	return nil
+}
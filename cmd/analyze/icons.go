@@ -0,0 +1,230 @@
+//go:build windows
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/winmole/winmole/internal/jsonc"
+	"golang.org/x/sys/windows/registry"
+)
+
+// iconSet renders the glyph shown before each entry name.
+type iconSet struct {
+	dir        string
+	file       string
+	link       string // symbolic links, junctions and mount points
+	cloud      string // marks online-only sizes
+	byCategory map[string]string
+	byExt      map[string]string
+}
+
+var emojiIcons = iconSet{
+	dir:   "📁",
+	file:  "📄",
+	link:  "🔗",
+	cloud: "☁",
+}
+
+// Nerd Font glyphs from the Font Awesome range, which every patched font ships.
+var nerdIcons = iconSet{
+	dir:   "\uf07b",
+	file:  "\uf15b",
+	link:  "\uf0c1",
+	cloud: "\uf0c2",
+	byCategory: map[string]string{
+		"media":       "\uf1c5",
+		"archives":    "\uf1c6",
+		"executables": "\uf17a",
+		"documents":   "\uf15c",
+		"code":        "\uf1c9",
+		"system":      "\uf013",
+	},
+	byExt: map[string]string{
+		".mp4": "\uf1c8", ".mkv": "\uf1c8", ".avi": "\uf1c8", ".mov": "\uf1c8", ".wmv": "\uf1c8", ".webm": "\uf1c8", ".m4v": "\uf1c8",
+		".mp3": "\uf1c7", ".wav": "\uf1c7", ".flac": "\uf1c7", ".aac": "\uf1c7", ".ogg": "\uf1c7", ".m4a": "\uf1c7", ".wma": "\uf1c7",
+		".pdf": "\uf1c1",
+		".doc": "\uf1c2", ".docx": "\uf1c2", ".odt": "\uf1c2", ".rtf": "\uf1c2",
+		".xls": "\uf1c3", ".xlsx": "\uf1c3", ".ods": "\uf1c3", ".csv": "\uf1c3",
+		".ppt": "\uf1c4", ".pptx": "\uf1c4", ".odp": "\uf1c4",
+		".iso": "\uf0a0", ".img": "\uf0a0", ".vhd": "\uf0a0", ".vhdx": "\uf0a0",
+	},
+}
+
+var asciiIcons = iconSet{
+	dir:   "d",
+	file:  "-",
+	link:  "l",
+	cloud: "~",
+}
+
+// icon returns the glyph for an entry, preferring an extension-specific
+// glyph, then the entry's category glyph, then the generic file glyph.
+func (s iconSet) icon(entry Entry, categories *categorizer) string {
+	if entry.Target != "" {
+		return s.link
+	}
+	if entry.IsDir {
+		return s.dir
+	}
+	if glyph, ok := s.byExt[normalizeExt(filepath.Ext(entry.Name))]; ok {
+		return glyph
+	}
+	if glyph, ok := s.byCategory[categories.category(entry.Name)]; ok {
+		return glyph
+	}
+	return s.file
+}
+
+// resolveIconSet maps the "icons" setting to an icon set. "auto" (or an
+// unknown value) picks Nerd Font glyphs only when the terminal is known to
+// use a Nerd Font, and keeps the emoji set otherwise.
+func resolveIconSet(setting string) iconSet {
+	switch strings.ToLower(setting) {
+	case "emoji":
+		return emojiIcons
+	case "nerd":
+		return nerdIcons
+	case "ascii":
+		return asciiIcons
+	}
+	if nerdFontDetected() {
+		return nerdIcons
+	}
+	return emojiIcons
+}
+
+// nerdFontDetected reports whether Windows Terminal is configured with a
+// Nerd Font face. Without Windows Terminal there is no reliable way to learn
+// the console font, so a merely installed Nerd Font is not enough.
+func nerdFontDetected() bool {
+	if os.Getenv("WT_SESSION") == "" {
+		return false
+	}
+	face := windowsTerminalFontFace()
+	if face == "" {
+		return false
+	}
+	return isNerdFontName(face) && nerdFontInstalled(face)
+}
+
+// windowsTerminalFontFace returns the font face of the Windows Terminal
+// profile this session runs in, or "" if it cannot be determined.
+func windowsTerminalFontFace() string {
+	localAppData := os.Getenv("LOCALAPPDATA")
+	if localAppData == "" {
+		return ""
+	}
+	candidates := []string{
+		filepath.Join(localAppData, "Packages", "Microsoft.WindowsTerminal_8wekyb3d8bbwe", "LocalState", "settings.json"),
+		filepath.Join(localAppData, "Packages", "Microsoft.WindowsTerminalPreview_8wekyb3d8bbwe", "LocalState", "settings.json"),
+		filepath.Join(localAppData, "Microsoft", "Windows Terminal", "settings.json"),
+	}
+
+	for _, path := range candidates {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if face := terminalFontFace(data, os.Getenv("WT_PROFILE_ID")); face != "" {
+			return face
+		}
+	}
+	return ""
+}
+
+// terminalProfile is the part of a Windows Terminal profile that sets the
+// font; fontFace is the setting's name before font.face.
+type terminalProfile struct {
+	GUID     string `json:"guid"`
+	Name     string `json:"name"`
+	FontFace string `json:"fontFace"`
+	Font     struct {
+		Face string `json:"face"`
+	} `json:"font"`
+}
+
+func (p terminalProfile) face() string {
+	if p.Font.Face != "" {
+		return p.Font.Face
+	}
+	return p.FontFace
+}
+
+// terminalFontFace reads the font face of profile profileID from Windows
+// Terminal settings, falling back to the default profile and then to the
+// defaults of every profile. The file is JSON with comments, as Windows
+// Terminal writes it. A list of faces such as "CaskaydiaCove NF, Consolas"
+// yields the first.
+func terminalFontFace(data []byte, profileID string) string {
+	var settings struct {
+		DefaultProfile string          `json:"defaultProfile"`
+		Profiles       json.RawMessage `json:"profiles"`
+	}
+	// A file we cannot parse is treated as "unknown font" rather than an
+	// error.
+	if err := json.Unmarshal(jsonc.Standardize(data), &settings); err != nil {
+		return ""
+	}
+	// profiles is an object with defaults and list, or in old files just
+	// the list.
+	var profiles struct {
+		Defaults terminalProfile   `json:"defaults"`
+		List     []terminalProfile `json:"list"`
+	}
+	if err := json.Unmarshal(settings.Profiles, &profiles); err != nil {
+		json.Unmarshal(settings.Profiles, &profiles.List)
+	}
+	if profileID == "" {
+		profileID = settings.DefaultProfile
+	}
+
+	face := profiles.Defaults.face()
+	for _, p := range profiles.List {
+		if profileID != "" && (strings.EqualFold(p.GUID, profileID) || p.Name == profileID) {
+			if f := p.face(); f != "" {
+				face = f
+			}
+			break
+		}
+	}
+	first, _, _ := strings.Cut(face, ",")
+	return strings.TrimSpace(first)
+}
+
+func isNerdFontName(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.Contains(lower, "nerd font") ||
+		strings.HasSuffix(lower, " nf") ||
+		strings.Contains(lower, " nf ") ||
+		strings.HasSuffix(lower, " nfm") ||
+		strings.HasSuffix(lower, " nfp")
+}
+
+// nerdFontInstalled checks the per-machine and per-user font registrations
+// for a font whose name starts with face.
+func nerdFontInstalled(face string) bool {
+	const fontsKey = `SOFTWARE\Microsoft\Windows NT\CurrentVersion\Fonts`
+	prefix := strings.ToLower(face)
+
+	for _, root := range []registry.Key{registry.LOCAL_MACHINE, registry.CURRENT_USER} {
+		key, err := registry.OpenKey(root, fontsKey, registry.QUERY_VALUE)
+		if err != nil {
+			continue
+		}
+		names, err := key.ReadValueNames(-1)
+		key.Close()
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			if strings.HasPrefix(strings.ToLower(name), prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
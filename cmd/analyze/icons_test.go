@@ -0,0 +1,110 @@
+//go:build windows
+
+package main
+
+import "testing"
+
+// settingsJSON is shaped like the settings.json Windows Terminal writes on
+// first run, comments and all, with a Nerd Font set on one profile.
+const settingsJSON = `{
+    "$help": "https://aka.ms/terminal-documentation",
+    "$schema": "https://aka.ms/terminal-profiles-schema",
+    "actions": 
+    [
+        {
+            "command": 
+            {
+                "action": "copy",
+                "singleLine": false
+            },
+            "id": "User.copy.644BA8F2"
+        },
+    ],
+    "copyFormatting": "none",
+    "copyOnSelect": false,
+    "defaultProfile": "{61c54bbd-c2c6-5271-96e7-009a87ff44bf}",
+    // Add custom actions and keybindings to this array.
+    // To unbind a key combination from your defaults.json, set the command to "unbound".
+    "profiles": 
+    {
+        "defaults": 
+        {
+            "font": 
+            {
+                "face": "Cascadia Mono"
+            }
+        },
+        "list": 
+        [
+            {
+                "commandline": "%SystemRoot%\\System32\\WindowsPowerShell\\v1.0\\powershell.exe",
+                "guid": "{61c54bbd-c2c6-5271-96e7-009a87ff44bf}",
+                "hidden": false,
+                "name": "Windows PowerShell"
+            },
+            {
+                /* pwsh with the prompt theme */
+                "font": 
+                {
+                    "face": "CaskaydiaCove Nerd Font Mono, Consolas",
+                    "size": 11
+                },
+                "guid": "{574e775e-4f2a-5b96-ac1e-a2962a402336}",
+                "hidden": false,
+                "name": "PowerShell",
+                "source": "Windows.Terminal.PowershellCore"
+            },
+        ]
+    },
+    "schemes": [],
+    "themes": []
+}
+`
+
+// legacySettings is the older shape: profiles as a plain list and the
+// font in fontFace.
+const legacySettings = `{
+    "defaultProfile": "Ubuntu",
+    "profiles": [
+        { "guid": "{2c4de342-38b7-51cf-b940-2309a097f518}", "name": "Ubuntu", "fontFace": "FiraCode NF" },
+    ],
+}`
+
+func TestTerminalFontFace(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		profile  string
+		want     string
+	}{
+		{"default profile falls back to defaults", settingsJSON, "", "Cascadia Mono"},
+		{"profile in use", settingsJSON, "{574e775e-4f2a-5b96-ac1e-a2962a402336}", "CaskaydiaCove Nerd Font Mono"},
+		{"guid case", settingsJSON, "{574E775E-4F2A-5B96-AC1E-A2962A402336}", "CaskaydiaCove Nerd Font Mono"},
+		{"unknown profile", settingsJSON, "{00000000-0000-0000-0000-000000000000}", "Cascadia Mono"},
+		{"legacy list and fontFace", legacySettings, "", "FiraCode NF"},
+		{"not json", "{ oops", "", ""},
+		{"no font", `{"profiles": {"list": []}}`, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := terminalFontFace([]byte(tt.settings), tt.profile); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsNerdFontName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"CaskaydiaCove Nerd Font Mono": true,
+		"FiraCode NF":                  true,
+		"JetBrainsMono NFM":            true,
+		"Hack NF Bold":                 true,
+		"Cascadia Mono":                false,
+		"Consolas":                     false,
+	} {
+		if got := isNerdFontName(name); got != want {
+			t.Errorf("isNerdFontName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+//go:build windows
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Reports collected on other machines can be opened in the analyzer
+// (--import) and used as a baseline, so it works as a viewer and differ for
+// artifacts teams already gather. Recognised formats:
+//
+//   - WinMole exports (--export, e/E), JSON or CSV
+//   - Sysinternals du -c (CSV) or -ct (tab separated)
+//   - WinDirStat results saved as CSV
+//
+// Imported trees are read-only: their paths belong to another machine.
+
+// importRow is one file or folder of a report.
+type importRow struct {
+	path  string
+	size  int64
+	files int64
+	dirs  int64
+	isDir bool
+}
+
+var errUnknownReport = errors.New("not a WinMole export, du -c/-ct report or WinDirStat CSV")
+
+// importReport reads the report at path into listings and returns them
+// with the report's top folder.
+func importReport(path string) (dirCache, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("read report: %w", err)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var rows []importRow
+	if t := bytes.TrimSpace(data); len(t) > 0 && t[0] == '{' {
+		rows, err = parseExportJSON(t)
+	} else {
+		rows, err = parseReportCSV(data)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	c, root := buildImportCache(rows)
+	if root == "" {
+		return nil, "", fmt.Errorf("%s: no entries with full paths", filepath.Base(path))
+	}
+	return c, root, nil
+}
+
+func parseExportJSON(data []byte) ([]importRow, error) {
+	var doc exportDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	rows := []importRow{{path: doc.Root, size: doc.TotalSize, isDir: true}}
+	for _, e := range doc.Entries {
+		rows = append(rows, importRow{path: e.Path, size: e.Size, files: e.Files, dirs: e.Dirs, isDir: e.IsDir})
+	}
+	return rows, nil
+}
+
+// parseReportCSV reads a comma or tab separated report, skipping anything
+// before the header (du prints a banner unless run with -nobanner).
+func parseReportCSV(data []byte) ([]importRow, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	if bytes.Count(data, []byte("\t")) > bytes.Count(data, []byte(",")) {
+		r.Comma = '\t' // du -ct
+	}
+
+	var col map[string]int
+	var rows []importRow
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if col == nil {
+			col = reportHeader(rec)
+			continue
+		}
+		field := func(name string) string {
+			if i, ok := col[name]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+
+		switch {
+		case hasColumns(col, "path", "is_dir"): // WinMole
+			rows = append(rows, importRow{
+				path:  field("path"),
+				size:  parseCount(field("size")),
+				files: parseCount(field("files")),
+				dirs:  parseCount(field("dirs")),
+				isDir: field("is_dir") == "true",
+			})
+
+		case hasColumns(col, "path", "directorysize"): // du
+			dir := field("path")
+			rows = append(rows, importRow{
+				path:  dir,
+				size:  parseCount(field("directorysize")),
+				files: parseCount(field("filecount")),
+				dirs:  parseCount(field("directorycount")),
+				isDir: true,
+			})
+			// du lists folders only; the files directly inside one show up
+			// as a single entry so the sizes add up.
+			if size := parseCount(field("currentfilesize")); size > 0 {
+				rows = append(rows, importRow{
+					path:  filepath.Join(dir, "(files)"),
+					size:  size,
+					files: parseCount(field("currentfilecount")),
+				})
+			}
+
+		default: // WinDirStat
+			files := parseCount(field("files"))
+			rows = append(rows, importRow{
+				path:  field("name"),
+				size:  parseCount(field("size")),
+				files: max(files, 1),
+				dirs:  parseCount(field("folders")),
+				isDir: files > 0 || parseCount(field("folders")) > 0,
+			})
+		}
+	}
+	if col == nil {
+		return nil, errUnknownReport
+	}
+	return rows, nil
+}
+
+// reportHeader maps lower-cased column names to their index, or returns nil
+// when rec is not the header of a known report.
+func reportHeader(rec []string) map[string]int {
+	col := make(map[string]int, len(rec))
+	for i, name := range rec {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if hasColumns(col, "path", "is_dir") || hasColumns(col, "path", "directorysize") || hasColumns(col, "name", "size") {
+		return col
+	}
+	return nil
+}
+
+func hasColumns(col map[string]int, names ...string) bool {
+	for _, name := range names {
+		if _, ok := col[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCount reads a number, ignoring thousands separators.
+func parseCount(s string) int64 {
+	s = strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
+
+// buildImportCache turns rows into listings keyed like scanned ones. The
+// root is the shallowest folder; folders without a row of their own are
+// sized from their entries.
+func buildImportCache(rows []importRow) (dirCache, string) {
+	byKey := make(map[string]importRow, len(rows))
+	root := ""
+	for _, row := range rows {
+		if row.path == "" || !filepath.IsAbs(row.path) {
+			continue
+		}
+		row.path = filepath.Clean(row.path)
+		key := cacheKey(row.path)
+		byKey[key] = row
+		if root == "" || strings.Count(row.path, `\`) < strings.Count(root, `\`) {
+			root = row.path
+		}
+	}
+	if root == "" {
+		return nil, ""
+	}
+
+	c := make(dirCache)
+	rootKey := cacheKey(root)
+	for key, row := range byKey {
+		if key == rootKey {
+			continue
+		}
+		parent := filepath.Dir(row.path)
+		l := c[cacheKey(parent)]
+		l.path = parent
+		l.entries = append(l.entries, Entry{
+			Name:  filepath.Base(row.path),
+			Path:  row.path,
+			Size:  row.size,
+			Files: row.files,
+			Dirs:  row.dirs,
+			IsDir: row.isDir,
+		})
+		c[cacheKey(parent)] = l
+	}
+
+	for key, l := range c {
+		sort.Slice(l.entries, func(i, j int) bool {
+			return l.entries[i].Size > l.entries[j].Size
+		})
+		if row, ok := byKey[key]; ok && row.size > 0 {
+			l.totalSize = row.size
+		} else {
+			for _, e := range l.entries {
+				l.totalSize += e.Size
+			}
+		}
+		// A row listed with entries below it is a folder whatever the
+		// report said.
+		for i, e := range l.entries {
+			if _, ok := c[cacheKey(e.Path)]; ok {
+				l.entries[i].IsDir = true
+			}
+		}
+		c[key] = l
+	}
+	return c, root
+}
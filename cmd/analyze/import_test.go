@@ -0,0 +1,168 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// duCSV is what du -c prints, banner included.
+const duCSV = "\r\nDU v1.62 - Directory disk usage reporter\r\nCopyright (C) 2005-2018 Mark Russinovich\r\nSysinternals - www.sysinternals.com\r\n\r\n" +
+	"Path,CurrentFileCount,CurrentFileSize,FileCount,DirectoryCount,DirectorySize,DirectorySizeOnDisk\r\n" +
+	"\"C:\\Data\\src\",2,1500,2,0,1500,8192\r\n" +
+	"\"C:\\Data\",1,100,3,1,1600,12288\r\n"
+
+// duTSV is what du -ct -nobanner prints.
+const duTSV = "Path\tCurrentFileCount\tCurrentFileSize\tFileCount\tDirectoryCount\tDirectorySize\tDirectorySizeOnDisk\r\n" +
+	"C:\\Data\\src\t2\t1500\t2\t0\t1500\t8192\r\n" +
+	"C:\\Data\t0\t0\t2\t1\t1500\t8192\r\n"
+
+// winDirStatCSV is a WinDirStat list saved as CSV, with thousands
+// separators in the sizes.
+const winDirStatCSV = "Name,Size,Items,Files,Folders,Last Change,Attributes\r\n" +
+	"C:\\Data,\"1,600\",4,3,1,2026-10-01 10:00,D\r\n" +
+	"C:\\Data\\src,\"1,500\",2,2,0,2026-10-01 10:00,D\r\n" +
+	"C:\\Data\\notes.txt,100,1,0,0,2026-10-01 09:00,A\r\n"
+
+// winMoleCSV is a WinMole --export in CSV.
+const winMoleCSV = "path,size,files,dirs,is_dir\n" +
+	"C:\\Data\\src,1500,2,0,true\n" +
+	"C:\\Data\\notes.txt,100,1,0,false\n"
+
+func TestParseReportCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []importRow
+	}{
+		{"du -c", duCSV, []importRow{
+			{path: `C:\Data\src`, size: 1500, files: 2, isDir: true},
+			{path: `C:\Data\src\(files)`, size: 1500, files: 2},
+			{path: `C:\Data`, size: 1600, files: 3, dirs: 1, isDir: true},
+			{path: `C:\Data\(files)`, size: 100, files: 1},
+		}},
+		{"du -ct", duTSV, []importRow{
+			{path: `C:\Data\src`, size: 1500, files: 2, isDir: true},
+			{path: `C:\Data\src\(files)`, size: 1500, files: 2},
+			{path: `C:\Data`, size: 1500, files: 2, dirs: 1, isDir: true},
+		}},
+		{"WinDirStat", winDirStatCSV, []importRow{
+			{path: `C:\Data`, size: 1600, files: 3, dirs: 1, isDir: true},
+			{path: `C:\Data\src`, size: 1500, files: 2, isDir: true},
+			{path: `C:\Data\notes.txt`, size: 100, files: 1},
+		}},
+		{"WinMole", winMoleCSV, []importRow{
+			{path: `C:\Data\src`, size: 1500, files: 2, isDir: true},
+			{path: `C:\Data\notes.txt`, size: 100, files: 1},
+		}},
+		{"short rows", "Path,DirectorySize,FileCount\r\nC:\\Data\r\nC:\\Other,x,7\r\n", []importRow{
+			{path: `C:\Data`, isDir: true},
+			{path: `C:\Other`, files: 7, isDir: true},
+		}},
+		{"header only", "Name,Size\r\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseReportCSV([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseReportCSVMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"empty", ""},
+		{"no known header", "Folder,Bytes\r\nC:\\Data,100\r\n"},
+		{"banner only", "DU v1.62 - Directory disk usage reporter\r\n"},
+		{"binary", "\x00\x01\x02PK\x03\x04"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseReportCSV([]byte(tt.in)); !errors.Is(err, errUnknownReport) {
+				t.Errorf("got %v, want %v", err, errUnknownReport)
+			}
+		})
+	}
+}
+
+func TestParseCount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"0", 0},
+		{"1234", 1234},
+		{"1,234,567", 1234567},
+		{"1.234.567", 1234567},
+		{"1 234", 1234},
+		{"", 0},
+		{"n/a", 0},
+	}
+	for _, tt := range tests {
+		if got := parseCount(tt.in); got != tt.want {
+			t.Errorf("parseCount(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBuildImportCache(t *testing.T) {
+	rows, err := parseReportCSV([]byte(duCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, root := buildImportCache(rows)
+	if root != `C:\Data` {
+		t.Fatalf("root = %q, want C:\\Data", root)
+	}
+	top := c[cacheKey(root)]
+	if top.totalSize != 1600 {
+		t.Errorf("total = %d, want 1600", top.totalSize)
+	}
+	var names []string
+	for _, e := range top.entries {
+		names = append(names, e.Name)
+	}
+	if got := strings.Join(names, ","); got != "src,(files)" {
+		t.Errorf("entries = %s, want src,(files) by size", got)
+	}
+	if !top.entries[0].IsDir || top.entries[1].IsDir {
+		t.Errorf("IsDir: got %v, %v", top.entries[0].IsDir, top.entries[1].IsDir)
+	}
+
+	// Reports from elsewhere with only relative paths have no root.
+	if c, root := buildImportCache([]importRow{{path: `Data\src`}, {path: ""}}); c != nil || root != "" {
+		t.Errorf("relative paths: got %v, %q", c, root)
+	}
+}
+
+func TestParseExportJSON(t *testing.T) {
+	got, err := parseExportJSON([]byte(`{"root": "C:\\Data", "totalSize": 1600, "entries": [
+		{"path": "C:\\Data\\src", "size": 1500, "files": 2, "depth": 1, "isDir": true}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []importRow{
+		{path: `C:\Data`, size: 1600, isDir: true},
+		{path: `C:\Data\src`, size: 1500, files: 2, isDir: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+
+	for _, in := range []string{`{"root": "C:\\Data"`, `{"entries": {}}`, `{"totalSize": "big"}`} {
+		if _, err := parseExportJSON([]byte(in)); err == nil {
+			t.Errorf("%s: want an error", in)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+//go:build windows
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/elevate"
+	"github.com/winmole/winmole/internal/msi"
+	"github.com/winmole/winmole/internal/policy"
+	"github.com/winmole/winmole/internal/usage"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// I accounts for the Windows Installer cache (%SystemRoot%\Installer),
+// which keeps a copy of every installed product and patch and often runs
+// to tens of gigabytes. The elevated helper asks the Installer API which
+// packages belong to a product or patch, for every user, and lists the
+// rest as orphaned, largest first, with what each says it installs.
+// Packages from the last day are left out, since an installation copies
+// its package before registering it. Space selects orphans and A selects
+// them all; D deletes them for good after you type "delete". The helper
+// checks each one again before deleting it and refuses while an
+// installation is running.
+
+// installerView lists the orphaned packages.
+type installerView struct {
+	cache    *msi.Cache
+	loading  bool
+	removing bool
+	selected int
+	offset   int
+	chosen   map[string]bool
+	confirm  bool   // typing "delete"
+	input    string // typed so far
+}
+
+type installerMsg struct {
+	cache *msi.Cache
+	err   error
+}
+
+type installerRemovedMsg struct {
+	removal msi.Removal
+	err     error
+}
+
+func installerCmd() tea.Cmd {
+	return func() tea.Msg {
+		out, err := elevate.RunAction(elevate.OpInstallerCache, nil)
+		if err != nil {
+			return installerMsg{err: err}
+		}
+		var cache msi.Cache
+		if err := json.Unmarshal([]byte(out), &cache); err != nil {
+			return installerMsg{err: err}
+		}
+		return installerMsg{cache: &cache}
+	}
+}
+
+func installerRemoveCmd(paths []string) tea.Cmd {
+	return func() tea.Msg {
+		out, err := elevate.RunAction(elevate.OpRemoveInstallerOrphans, elevate.PackageArgs{Paths: paths})
+		if err != nil {
+			return installerRemovedMsg{err: err}
+		}
+		var removal msi.Removal
+		err = json.Unmarshal([]byte(out), &removal)
+		return installerRemovedMsg{removal: removal, err: err}
+	}
+}
+
+// showInstaller opens the view and reads the cache.
+func (m model) showInstaller() (tea.Model, tea.Cmd) {
+	usage.Run("analyze.installer")
+	m.installer = &installerView{loading: true, chosen: map[string]bool{}}
+	m.status = "Reading the Windows Installer cache (administrator)..."
+	return m, installerCmd()
+}
+
+func (m model) applyInstaller(msg installerMsg) model {
+	v := m.installer
+	if v == nil {
+		return m
+	}
+	v.loading = false
+	if msg.err != nil {
+		m.installer = nil
+		m.status = fmt.Sprintf("Error: %v", msg.err)
+		return m
+	}
+	v.cache = msg.cache
+	v.chosen = map[string]bool{}
+	v.selected, v.offset = 0, 0
+	m.status = v.summary()
+	return m
+}
+
+func (m model) applyInstallerRemoved(msg installerRemovedMsg) (model, tea.Cmd) {
+	v := m.installer
+	if v != nil {
+		v.removing = false
+	}
+	if msg.err != nil {
+		m.status = fmt.Sprintf("Error: %v", msg.err)
+		return m, nil
+	}
+	dir := cacheKey(msi.Dir())
+	traceAction("remove-installer-orphans", msi.Dir(), m.redactor)
+	dropMFTIndex(msi.Dir())
+	for key := range m.cache {
+		if key == dir || isUnder(dir, key) {
+			delete(m.cache, key)
+		}
+	}
+	usage.Freed("analyze.installer", msg.removal.Freed)
+	m.status = fmt.Sprintf("Removed %s (%s)", itemCount(msg.removal.Removed), humanize.Bytes(msg.removal.Freed))
+	if len(msg.removal.Failed) > 0 {
+		m.status += fmt.Sprintf(" • %d failed: %s", len(msg.removal.Failed), msg.removal.Failed[0])
+	}
+	if v == nil {
+		return m, nil
+	}
+	// List again so the view shows what is left.
+	v.loading = true
+	return m, installerCmd()
+}
+
+// chosenPaths are the selected orphans.
+func (v *installerView) chosenPaths() ([]string, int64) {
+	var paths []string
+	var size int64
+	for _, p := range v.cache.Orphans {
+		if v.chosen[cacheKey(p.Path)] {
+			paths = append(paths, p.Path)
+			size += p.Size
+		}
+	}
+	return paths, size
+}
+
+// summary totals the cache and the selection.
+func (v *installerView) summary() string {
+	c := v.cache
+	s := fmt.Sprintf("%d registered (%s) • %d orphaned (%s)",
+		len(c.Registered), humanize.Bytes(msi.Total(c.Registered)), len(c.Orphans), humanize.Bytes(msi.Total(c.Orphans)))
+	if c.Recent > 0 {
+		s += fmt.Sprintf(" • %d from the last day left out", c.Recent)
+	}
+	if paths, size := v.chosenPaths(); len(paths) > 0 {
+		s += fmt.Sprintf(" • %d selected, %s", len(paths), humanize.Bytes(size))
+	}
+	return s
+}
+
+func (m model) installerListHeight() int {
+	return max(m.viewportHeight()-2, 3)
+}
+
+// handleInstallerKey handles keys while the orphans are listed.
+func (m model) handleInstallerKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.installer
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+	if v.loading || v.removing {
+		if v.loading && (msg.String() == "esc" || msg.String() == "I" || msg.String() == "q") {
+			m.installer = nil
+			m.status = m.totalStatus()
+		}
+		return m, nil
+	}
+	if v.confirm {
+		switch msg.Type {
+		case tea.KeyEsc:
+			v.confirm, v.input = false, ""
+			m.status = "Deletion cancelled"
+		case tea.KeyEnter:
+			if v.input != "delete" {
+				break
+			}
+			v.confirm, v.input = false, ""
+			paths, size := v.chosenPaths()
+			v.removing = true
+			m.status = fmt.Sprintf("Deleting %s (%s)...", itemCount(len(paths)), humanize.Bytes(size))
+			return m, installerRemoveCmd(paths)
+		case tea.KeyBackspace:
+			if r := []rune(v.input); len(r) > 0 {
+				v.input = string(r[:len(r)-1])
+			}
+		case tea.KeyRunes:
+			v.input += string(msg.Runes)
+		}
+		return m, nil
+	}
+
+	orphans := v.cache.Orphans
+	switch msg.String() {
+	case "I", "esc", "q":
+		m.installer = nil
+		m.status = m.totalStatus()
+		return m, nil
+	case "up", "k":
+		if v.selected > 0 {
+			v.selected--
+			v.offset = min(v.offset, v.selected)
+		}
+	case "down", "j":
+		if v.selected < len(orphans)-1 {
+			v.selected++
+			if h := m.installerListHeight(); v.selected >= v.offset+h {
+				v.offset = v.selected - h + 1
+			}
+		}
+	case " ":
+		if len(orphans) == 0 {
+			break
+		}
+		key := cacheKey(orphans[v.selected].Path)
+		v.chosen[key] = !v.chosen[key]
+		if !v.chosen[key] {
+			delete(v.chosen, key)
+		}
+		if v.selected < len(orphans)-1 {
+			v.selected++
+			if h := m.installerListHeight(); v.selected >= v.offset+h {
+				v.offset = v.selected - h + 1
+			}
+		}
+	case "A":
+		// All of them, or none if all are.
+		all := len(v.chosen) == len(orphans)
+		v.chosen = map[string]bool{}
+		if !all {
+			for _, p := range orphans {
+				v.chosen[cacheKey(p.Path)] = true
+			}
+		}
+	case "D":
+		if policy.Get().DisableFileDeletion {
+			m.status = "Deleting is " + policy.DisabledMessage
+			return m, nil
+		}
+		if paths, _ := v.chosenPaths(); len(paths) == 0 {
+			m.status = "Select orphaned packages with Space or A first"
+			return m, nil
+		}
+		v.confirm = true
+		return m, nil
+	case "r":
+		v.loading = true
+		m.status = "Reading the Windows Installer cache (administrator)..."
+		return m, installerCmd()
+	case "y":
+		if len(orphans) > 0 {
+			p := orphans[v.selected]
+			m = m.copyPath(Entry{Name: filepath.Base(p.Path), Path: p.Path})
+		}
+		return m, nil
+	case "O":
+		if len(orphans) > 0 {
+			p := orphans[v.selected]
+			m = m.openAction(Entry{Name: filepath.Base(p.Path), Path: p.Path, Size: p.Size}, true)
+		}
+		return m, nil
+	}
+	m.status = v.summary()
+	return m, nil
+}
+
+// renderInstaller lists the orphans, or the typed confirmation.
+func (m model) renderInstaller() string {
+	v := m.installer
+	if v.cache == nil {
+		return dimStyle.Render("  Asking Windows Installer which packages are in use...") + "\n"
+	}
+	var b strings.Builder
+	if v.confirm {
+		paths, size := v.chosenPaths()
+		b.WriteString(warnStyle.Render(fmt.Sprintf("  Delete %s of orphaned installer packages (%s)?", itemCount(len(paths)), humanize.Bytes(size))))
+		b.WriteString("\n\n")
+		b.WriteString(dimStyle.Render("  Bypasses the Recycle Bin and cannot be undone. A product whose package was wrongly"))
+		b.WriteString("\n")
+		b.WriteString(dimStyle.Render("  taken for an orphan asks for its setup media to repair or uninstall."))
+		b.WriteString("\n\n")
+		b.WriteString(normalStyle.Render(`  Type "delete" to confirm:`))
+		b.WriteString("\n")
+		input := "  > " + v.input + "█"
+		if v.input == "delete" {
+			b.WriteString(selectedStyle.Render(input) + "\n\n" + dimStyle.Render("  Enter delete • Esc cancel"))
+		} else {
+			b.WriteString(normalStyle.Render(input) + "\n\n" + dimStyle.Render("  Esc cancel"))
+		}
+		b.WriteString("\n")
+		return b.String()
+	}
+
+	orphans := v.cache.Orphans
+	if len(orphans) == 0 {
+		b.WriteString(dimStyle.Render("  (no orphaned packages: every package belongs to an installed product or patch)") + "\n")
+		return b.String()
+	}
+	end := min(v.offset+m.installerListHeight(), len(orphans))
+	for i := v.offset; i < end; i++ {
+		p := orphans[i]
+		check := " "
+		if v.chosen[cacheKey(p.Path)] {
+			check = "✓"
+		}
+		kind := "product"
+		if p.Patch {
+			kind = "patch"
+		}
+		name := p.Name
+		if name == "" {
+			name = "(no name)"
+		}
+		line := fmt.Sprintf("%s %-14s %-7s %s  %s", check, filepath.Base(p.Path), kind, fmt.Sprintf("%-12s", humanize.Date(p.Modified)), name)
+		size := sizeStyle.Render(fmt.Sprintf("%10s", humanize.Bytes(p.Size)))
+		switch {
+		case i == v.selected:
+			b.WriteString(size + " " + selectedStyle.Render(line))
+		case check != " ":
+			b.WriteString(size + " " + warnStyle.Render(line))
+		default:
+			b.WriteString(size + " " + normalStyle.Render(line))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,223 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/usage"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/scan"
+)
+
+// The largest-files view (f) lists the biggest files anywhere below the
+// current folder, however deep, so a stray 40 GB disk image three levels
+// down does not hide inside its folder's total. Scans keep the largest
+// files they measure as they go; folders loaded another way (restored from
+// the saved scan, re-measured after a change) are walked again on demand.
+// Enter jumps to the folder holding the selected file.
+
+// largestKept is how many files each scan keeps for the view.
+const largestKept = 100
+
+// largestView is the largest-files view of one folder.
+type largestView struct {
+	files    []Entry // largest first
+	selected int
+	offset   int
+}
+
+type largestResultMsg struct {
+	path  string
+	files []Entry
+	err   error
+}
+
+// newProgress returns fresh counters for a scan, keeping its largest files
+// and leaving out what x excludes.
+func newProgress(x *exclusions) *scan.Counters {
+	return &scan.Counters{Largest: scan.NewLargest(largestKept), Exclude: x.hook()}
+}
+
+func (m model) largestCmd() tea.Cmd {
+	return func() tea.Msg {
+		files, err := largestFiles(m.path, m.progress)
+		return largestResultMsg{path: m.path, files: files, err: err}
+	}
+}
+
+// largestFiles collects the largest files below path into progress, from
+// the MFT when it can be read and by walking otherwise.
+func largestFiles(path string, progress *scan.Counters) ([]Entry, error) {
+	if vol := mftVolume(path); vol != "" && progress.Exclude == nil {
+		if idx, err := loadMFTIndex(context.Background(), vol, progress); err == nil {
+			if idx.addLargest(path, progress.Largest) == nil {
+				return progress.Largest.Files(), nil
+			}
+		}
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	scan.Size(path, progress)
+	return progress.Largest.Files(), nil
+}
+
+// addLargest offers every file below path to top.
+func (idx *mftIndex) addLargest(path string, top *scan.Largest) error {
+	if top == nil {
+		return nil
+	}
+	return idx.eachFile(path, func(dir, name string, size int64) {
+		top.Add(filepath.Join(dir, name), size)
+	})
+}
+
+// largestCached collects the largest files below path from the listings in
+// c, for imported reports whose files cannot be walked.
+func (c dirCache) largestCached(path string) []Entry {
+	top := scan.NewLargest(largestKept)
+	key := cacheKey(path)
+	for dir, listing := range c {
+		if dir != key && !isUnder(dir, key) {
+			continue
+		}
+		for _, e := range listing.entries {
+			if !e.IsDir {
+				top.Add(e.Path, e.Size)
+			}
+		}
+	}
+	return top.Files()
+}
+
+// showLargest opens the view from the current listing, or starts collecting
+// the files when the listing does not have them.
+func (m model) showLargest() (tea.Model, tea.Cmd) {
+	listing, ok := m.cache[cacheKey(m.path)]
+	switch {
+	case ok && listing.largest != nil:
+		return m.openLargest(listing.largest), nil
+	case m.imported != "":
+		return m.openLargest(m.cache.largestCached(m.path)), nil
+	}
+	m.scanning = true
+	m.status = "Finding the largest files..."
+	m.progress = newProgress(m.exclude)
+	return m, tea.Batch(m.largestCmd(), tickCmd())
+}
+
+func (m model) openLargest(files []Entry) model {
+	usage.Run("analyze.largest")
+	m.largest = &largestView{files: files}
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	m.status = fmt.Sprintf("%d largest files • %s of %s", len(files), humanize.Bytes(total), humanize.Bytes(m.totalSize))
+	return m
+}
+
+// handleLargestKey moves through the largest-files view; Enter opens the
+// folder holding the selected file, f or Esc returns to the list.
+func (m model) handleLargestKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.largest
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "f", "esc", "q":
+		m.largest = nil
+		m.status = m.totalStatus()
+	case "up", "k":
+		if v.selected > 0 {
+			v.selected--
+			v.offset = min(v.offset, v.selected)
+		}
+	case "down", "j":
+		if v.selected < len(v.files)-1 {
+			v.selected++
+			if h := m.viewportHeight(); v.selected >= v.offset+h {
+				v.offset = v.selected - h + 1
+			}
+		}
+	case "y":
+		if len(v.files) > 0 {
+			m = m.copyPath(v.files[v.selected])
+		}
+	case "o", "O":
+		if len(v.files) > 0 {
+			m = m.openAction(v.files[v.selected], msg.String() == "O")
+		}
+	case "enter", "right", "l":
+		if len(v.files) == 0 {
+			break
+		}
+		file := v.files[v.selected]
+		traceAction("jump", file.Path, m.redactor)
+		m.largest = nil
+		m.history = append(m.history, historyEntry{
+			Path:     m.path,
+			Selected: m.selected,
+			Offset:   m.offset,
+		})
+		m.path = filepath.Dir(file.Path)
+		m.selected, m.offset = 0, 0
+		m.focus = file.Path
+		return m.load()
+	}
+	return m, nil
+}
+
+// selectFocus selects the entry m.focus names, if the listing has it, and
+// scrolls it into view.
+func (m model) selectFocus() model {
+	if m.focus == "" {
+		return m
+	}
+	key := cacheKey(m.focus)
+	m.focus = ""
+	for i, e := range m.entries {
+		if cacheKey(e.Path) == key {
+			m.selected = i
+			if h := m.viewportHeight(); i >= m.offset+h {
+				m.offset = i - h + 1
+			}
+			m.offset = min(m.offset, i)
+			break
+		}
+	}
+	return m
+}
+
+// renderLargest lists the files with their size and path below the current
+// folder.
+func (m model) renderLargest() string {
+	v := m.largest
+	if len(v.files) == 0 {
+		return dimStyle.Render("  (no files)") + "\n"
+	}
+	var b strings.Builder
+	end := min(v.offset+m.viewportHeight(), len(v.files))
+	for i := v.offset; i < end; i++ {
+		f := v.files[i]
+		filled := barWidth(f.Size, m.totalSize, m.logScale, 20)
+		bar := strings.Repeat("█", filled) + strings.Repeat("░", 20-filled)
+		rel, err := filepath.Rel(m.path, f.Path)
+		if err != nil {
+			rel = f.Path
+		}
+		name := fmt.Sprintf("%s %s", m.icons.icon(f, m.categories), rel)
+		if i == v.selected {
+			b.WriteString(selectedStyle.Render(fmt.Sprintf("%s %s %s", sizeStyle.Render(humanize.Bytes(f.Size)), barStyle.Render(bar), name)))
+		} else {
+			b.WriteString(fmt.Sprintf("%s %s %s", sizeStyle.Render(humanize.Bytes(f.Size)), barStyle.Render(bar), m.categories.style(f.Name).Render(name)))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
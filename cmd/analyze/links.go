@@ -0,0 +1,59 @@
+//go:build windows
+
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// L switches between apparent totals, where a file counts once for every
+// hard link to it, and deduplicated ones, where it counts once. Windows
+// links most of C:\Windows\WinSxS into System32 and elsewhere, so the
+// apparent size of C:\Windows is far more than it takes. Telling links apart
+// means opening every file, so folders are scanned again the first time they
+// are shown deduplicated. The MFT engine lists each file in one folder only,
+// so its scans are always deduplicated.
+
+// J follows symbolic links, junctions and mount points, which are otherwise
+// listed with their target but count as empty. Each target is measured
+// once per scan and never one that contains the link, so a junction that
+// leads back up the tree cannot loop. Following links uses the directory
+// walker even where the MFT could be read.
+
+// scannedAsShown reports whether a cached listing was scanned with the
+// options now in effect.
+func (m model) scannedAsShown(l dirListing) bool {
+	return (l.links || !m.dedupe) && l.follow == m.follow && l.streams == m.streams
+}
+
+// toggleFollow switches following links and rescans the current folder.
+func (m model) toggleFollow() (tea.Model, tea.Cmd) {
+	if m.imported != "" {
+		m.status = "Links are only followed in folders scanned on this machine"
+		return m, nil
+	}
+	m.follow = !m.follow
+	if len(m.entries) > 0 {
+		m.focus = m.entries[m.selected].Path
+	}
+	return m.load()
+}
+
+// toggleDedupe switches hard-link deduplication, rescanning the current
+// folder if its links have not been checked yet.
+func (m model) toggleDedupe() (tea.Model, tea.Cmd) {
+	if m.imported != "" {
+		m.status = "Hard links are only detected in folders scanned on this machine"
+		return m, nil
+	}
+	m.dedupe = !m.dedupe
+	if len(m.entries) > 0 {
+		m.focus = m.entries[m.selected].Path
+	}
+	if listing, ok := m.cache[cacheKey(m.path)]; ok && !m.scannedAsShown(listing) {
+		return m.load()
+	}
+	m = m.refilter().selectFocus()
+	m.status = m.totalStatus()
+	return m, nil
+}
@@ -3,17 +3,26 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
-	"sync"
-	"sync/atomic"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/winmole/winmole/internal/config"
+	"github.com/winmole/winmole/internal/elevate"
+	"github.com/winmole/winmole/internal/etw"
+	"github.com/winmole/winmole/internal/headless"
+	"github.com/winmole/winmole/internal/redact"
+	"github.com/winmole/winmole/internal/usage"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/scan"
 )
 
 // Styles
@@ -43,31 +52,84 @@ var (
 
 	statusStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("241"))
+
+	warnStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")).
+			Bold(true)
 )
 
 // Entry represents a file or directory
-type Entry struct {
-	Name  string
-	Path  string
-	Size  int64
-	IsDir bool
-}
+type Entry = scan.Entry
 
 // Model is the Bubble Tea model
 type model struct {
-	path         string
-	entries      []Entry
-	selected     int
-	offset       int
-	width        int
-	height       int
-	scanning     bool
-	status       string
-	totalSize    int64
-	history      []historyEntry
-	spinner      int
-	filesScanned int64
-	dirsScanned  int64
+	path        string
+	entries     []Entry
+	selected    int
+	offset      int
+	width       int
+	height      int
+	scanning    bool
+	status      string
+	totalSize   int64
+	history     []historyEntry
+	spinner     int
+	progress    *scan.Counters
+	logScale    bool
+	ageColors   bool // bars colored by last modified; see ageBands
+	onDisk      bool // sizes are space allocated on disk
+	dedupe      bool // hard-linked files count once
+	follow      bool // measure what links and junctions lead to
+	streams     bool // count alternate data streams in sizes
+	streamList  *streamView
+	owners      map[string]string // owner column by cacheKey; nil when hidden
+	ownerTotals *ownerBreakdown
+	categories  *categorizer
+	icons       iconSet
+	redactor    *redact.Redactor
+	profile     string
+	cache       dirCache
+	confirm     *Entry // entry awaiting delete confirmation
+	purge       *purgePrompt
+	purging     *purgeProgress
+	marks       marks
+	batch       *batchPrompt
+	batching    *batchProgress
+	baseline    *baseline
+	treemap     bool   // show the treemap instead of the list
+	imported    string // name of the imported report; the tree is read-only
+	types       *extBreakdown
+	largest     *largestView
+	unreadable  *unreadableView
+	snapshots   *snapshotPicker
+	diff        *diffView
+	trend       *trendView
+	dupSearch   *dupSearch // the duplicate search in progress, if any
+	dups        *dupView
+	stale       *staleView
+	mediaSearch *mediaSearch // the media search in progress, if any
+	media       *mediaView
+	compress    *compressView
+	suggest     *suggestView
+	artifacts   *artifactView
+	components  *componentView
+	winsxs      *componentReport
+	installer   *installerView
+	shadows     *shadowView
+	sysFiles    *sysFilesView
+	staleDays   int      // the age the old-files view starts at
+	run         *scanRun // the folder scan in progress, if any
+	exclude     *exclusions
+	focus       string // path to select once its folder is listed
+	drives      *drivePicker
+	roots       []string // several paths given; rootsPath lists them
+	pickDrive   bool     // started without a path; ← at a drive root lists the drives
+	watch       *folderWatch
+
+	filterPrompt *filterPrompt
+	filter       string // narrows the list to matching names
+	search       string // last filter, for n/N
+	sort         sortMode
 }
 
 type historyEntry struct {
@@ -78,8 +140,15 @@ type historyEntry struct {
 
 // Messages
 type scanResultMsg struct {
+	path      string
 	entries   []Entry
 	totalSize int64
+	largest   []Entry
+	links     bool // hard links were detected
+	follow    bool // links and junctions were followed
+	streams   bool // alternate data streams were counted
+	skipped   *scan.Unreadable
+	run       *scanRun
 	err       error
 }
 
@@ -88,12 +157,37 @@ type tickMsg time.Time
 var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 
 func main() {
+	redacted := flag.Bool("redact", false, "mask the computer name, user names and IP addresses")
+	profile := flag.String("profile", "", "use the named settings profile from config.json")
+	export := flag.String("export", "", "scan without the TUI and write the results to this .json or .csv file")
+	depth := flag.Int("depth", 1, "folder levels to include with --export, --snapshot and --no-tui")
+	noTUI := flag.Bool("no-tui", false, "print the largest files and folders to stdout instead of starting the TUI")
+	top := flag.Int("top", 20, "entries to print with --no-tui (0 for all)")
+	format := flag.String("format", "text", "--no-tui output format: text, json or csv")
+	baselinePath := flag.String("baseline", "", "compare against an export or report of a reference machine")
+	importPath := flag.String("import", "", "open a WinMole export, du -c/-ct report or WinDirStat CSV instead of scanning")
+	snapshot := flag.Bool("snapshot", false, "scan without the TUI and save the results as a snapshot, for c and T to compare with later")
+	keep := flag.Int("keep", 0, "with --snapshot, delete all but this many newest snapshots of the folder (0 keeps them all)")
+	prune := flag.Bool("prune", false, "with --snapshot, thin out older snapshots by the analyze.snapshots retention and budget in config.json")
+	push := flag.Bool("push", false, "with --snapshot, send what changed since the previous snapshot to the server in config.json")
+	timeout := flag.Duration("timeout", 0, "with --no-tui, --export and --snapshot, stop scanning after this long (e.g. 5m) and write what was found")
+	user := flag.String("user", "", `sign in to the share (\\server\share) as this user; the password is asked for`)
+	flag.Parse()
+
+	if *profile != "" {
+		config.SetProfile(*profile)
+	}
+	enableBackupMode()
+
 	startPath := os.Getenv("WINMOLE_ANALYZE_PATH")
-	if startPath == "" && len(os.Args) > 1 {
-		startPath = os.Args[1]
+	if startPath == "" && flag.NArg() > 0 {
+		startPath = flag.Arg(0)
 	}
+	// Without a path the TUI starts on the drive list; the reports cover
+	// the current directory.
+	pickDrive := startPath == "" && !*noTUI && *export == "" && *importPath == "" && !*snapshot
 	if startPath == "" {
-		startPath = os.Getenv("USERPROFILE")
+		startPath = "."
 	}
 
 	absPath, err := filepath.Abs(startPath)
@@ -102,29 +196,235 @@ func main() {
 		os.Exit(1)
 	}
 
-	p := tea.NewProgram(newModel(absPath), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+	// Several paths are scanned side by side in the TUI.
+	var roots []string
+	if flag.NArg() > 1 && os.Getenv("WINMOLE_ANALYZE_PATH") == "" {
+		if *noTUI || *export != "" || *importPath != "" || *snapshot {
+			fmt.Fprintln(os.Stderr, "Error: several paths can only be analyzed in the TUI")
+			os.Exit(1)
+		}
+		seen := make(map[string]bool)
+		for _, arg := range flag.Args() {
+			root, err := filepath.Abs(arg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error resolving path: %v\n", err)
+				os.Exit(1)
+			}
+			if !seen[cacheKey(root)] {
+				seen[cacheKey(root)] = true
+				roots = append(roots, root)
+			}
+		}
+	}
+
+	// Sign in to shares that need it now, while the console is free.
+	if *importPath == "" && !pickDrive {
+		signedIn := make(map[string]bool)
+		for _, path := range append([]string{absPath}, roots...) {
+			share := cacheKey(shareRoot(path))
+			if signedIn[share] {
+				continue
+			}
+			signedIn[share] = true
+			if err := connectShare(path, *user); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: using default settings: %v\n", err)
+	}
+	exclude := newExclusions(cfg.Exclude)
+
+	var base *baseline
+	if *baselinePath != "" {
+		if base, err = loadBaseline(*baselinePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var imported dirCache
+	if *importPath != "" {
+		if imported, absPath, err = importReport(*importPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		usage.Run("analyze.import")
+	}
+
+	// tree is what the reports below cover: the imported report, or a
+	// scan depth levels deep. A scan stopped by --timeout or Ctrl+C yields
+	// what it found; stopped records why, for finish.
+	var stopped error
+	tree := func() dirCache {
+		if imported != nil {
+			return imported
+		}
+		ctx, stop := headless.Context(*timeout)
+		defer stop()
+		t := newPartialTree()
+		tr := newOTLPTrace(redact.New(*redacted))
+		err := headless.Wait(ctx, func() error { return scanTree(ctx, absPath, *depth, exclude, cfg.Streams, t, tr) })
+		tr.export()
+		if err != nil && !headless.Stopped(err) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		stopped = err
+		return t.snapshot()
+	}
+	// finish ends a headless run, with a distinct exit code if the scan
+	// was cut short.
+	finish := func() {
+		if stopped != nil {
+			fmt.Fprintf(os.Stderr, "Warning: scan %s, results are partial\n", headless.Reason(stopped))
+			os.Exit(headless.ExitCode(stopped))
+		}
+	}
+
+	if *noTUI && base != nil {
+		if err := runDrift(os.Stdout, tree(), absPath, *top, *format, base, redact.New(*redacted)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		usage.Run("analyze.drift")
+		finish()
+		return
+	}
+
+	if *noTUI {
+		if err := runHeadless(os.Stdout, tree(), absPath, *top, *format, redact.New(*redacted)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		usage.Run("analyze.headless")
+		finish()
+		return
+	}
+
+	if *export != "" {
+		if err := writeExport(*export, tree(), absPath, redact.New(*redacted)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		usage.Run("analyze.export")
+		fmt.Println(*export)
+		finish()
+		return
+	}
+
+	if *snapshot {
+		var prev *previousScan
+		if *push {
+			prev = loadPreviousScan(absPath)
+		}
+		c := tree()
+		path, err := writeSnapshot(c, absPath, *keep)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *push {
+			cur, err := readSnapshotInfo(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: not pushed: %v\n", err)
+			} else {
+				pushScanDiff(c, cur, prev)
+			}
+		}
+		if *prune {
+			if removed, freed := pruneByRetention(absPath, cfg.Snapshots); removed > 0 {
+				fmt.Fprintf(os.Stderr, "Pruned %d older snapshots (%s)\n", removed, humanize.Bytes(freed))
+			}
+		}
+		usage.Run("analyze.snapshot")
+		fmt.Println(path)
+		finish()
+		return
+	}
+
+	m := newModel(absPath, cfg)
+	m.profile = config.Profile()
+	m.baseline = base
+	if len(roots) > 1 {
+		m.roots = roots
+		m.path = rootsPath
+	}
+	if pickDrive {
+		m.pickDrive = true
+		m.drives = &drivePicker{loading: true, focus: filepath.VolumeName(absPath) + `\`}
+	}
+	if imported != nil {
+		m.cache = imported
+		m.imported = filepath.Base(*importPath)
+		loaded, _ := m.load()
+		m = loaded.(model)
+	}
+	if *redacted {
+		m.redactor.Toggle()
+	}
+
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	final, err := p.Run()
+	etw.Close()
+	elevate.Shutdown()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	if fm, ok := final.(model); ok && fm.imported == "" {
+		if err := saveScanCache(fm.cache, fm.exclude.list()); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
 }
 
-func newModel(path string) model {
+func newModel(path string, cfg analyzeConfig) model {
+	exclude := newExclusions(cfg.Exclude)
 	return model{
-		path:     path,
-		status:   "Scanning...",
-		scanning: true,
+		path:       path,
+		status:     "Scanning...",
+		scanning:   true,
+		logScale:   cfg.BarScale == "log",
+		ageColors:  cfg.BarColor == "age",
+		categories: newCategorizer(cfg),
+		icons:      resolveIconSet(cfg.Icons),
+		redactor:   redact.New(false),
+		cache:      make(dirCache),
+		marks:      make(marks),
+		progress:   newProgress(exclude),
+		exclude:    exclude,
+		staleDays:  cfg.StaleDays,
+		streams:    cfg.Streams,
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Batch(m.scanCmd(), tickCmd())
+	if m.imported != "" {
+		return nil
+	}
+	if m.drives != nil {
+		return drivesCmd()
+	}
+	return tea.Batch(m.restoreCmd(), tickCmd())
 }
 
-func (m model) scanCmd() tea.Cmd {
+func (m model) scanCmd(ctx context.Context, run *scanRun) tea.Cmd {
 	return func() tea.Msg {
-		entries, totalSize, err := scanDirectory(m.path, &m.filesScanned, &m.dirsScanned)
-		return scanResultMsg{entries: entries, totalSize: totalSize, err: err}
+		markJournal(m.path)
+		etw.Writef(etw.LevelInfo, etw.KeywordScan, "scan start: %s", m.redactor.String(m.path))
+		if m.dedupe {
+			m.progress.Links = scan.NewLinks()
+		}
+		m.progress.FollowLinks = m.follow
+		m.progress.Streams = m.streams
+		m.progress.Unreadable = scan.NewUnreadable(unreadableKept)
+		entries, totalSize, err := scanDirectory(ctx, m.path, m.progress)
+		return scanResultMsg{path: m.path, entries: entries, totalSize: totalSize, largest: m.progress.Largest.Files(), links: m.dedupe, follow: m.follow, streams: m.streams, skipped: m.progress.Unreadable, run: run, err: err}
 	}
 }
 
@@ -134,7 +434,19 @@ func tickCmd() tea.Cmd {
 	})
 }
 
+// Update handles msg, then has the owners of rows that came into view read
+// when the owner column is on.
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	if nm, ok := next.(model); ok && nm.owners != nil {
+		if lookup := nm.lookupOwners(); lookup != nil {
+			return nm, tea.Batch(cmd, lookup)
+		}
+	}
+	return next, cmd
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		return m.handleKey(msg)
@@ -144,26 +456,252 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		return m, nil
 
+	case restoredMsg:
+		for key, listing := range msg.cache {
+			if _, ok := m.cache[key]; !ok {
+				m.cache[key] = listing
+			}
+		}
+		return m.load()
+
 	case scanResultMsg:
+		if m.path == rootsPath && msg.run == m.run && m.run != nil {
+			return m.applyRootScan(msg)
+		}
+		if msg.path != m.path || msg.run != m.run {
+			// A scan for a directory we have since left, or one replaced
+			// by a newer scan.
+			return m, nil
+		}
 		m.scanning = false
-		if msg.err != nil {
+		m.run = nil
+		partial := errors.Is(msg.err, context.Canceled)
+		if msg.err != nil && !partial {
 			m.status = fmt.Sprintf("Error: %v", msg.err)
 			return m, nil
 		}
-		m.entries = msg.entries
+		m = m.cacheScan(msg)
+		m.entries = m.shown(msg.entries)
 		m.totalSize = msg.totalSize
 		m.selected = 0
 		m.offset = 0
-		m.status = fmt.Sprintf("Total: %s", humanizeBytes(m.totalSize))
+		m = m.selectFocus()
+		m.status = m.totalStatus()
+		return m, nil
+
+	case largestResultMsg:
+		if msg.path != m.path {
+			return m, nil
+		}
+		m.scanning = false
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Error: %v", msg.err)
+			return m, nil
+		}
+		if listing, ok := m.cache[cacheKey(msg.path)]; ok {
+			listing.largest = msg.files
+			m.cache[cacheKey(msg.path)] = listing
+		}
+		return m.openLargest(msg.files), nil
+
+	case staleResultMsg:
+		if msg.path != m.path {
+			return m, nil
+		}
+		m.scanning = false
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Error: %v", msg.err)
+			return m, nil
+		}
+		return m.applyStale(msg), nil
+
+	case compressEstimateMsg:
+		if msg.path != m.path {
+			return m, nil
+		}
+		m.scanning = false
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Error: %v", msg.err)
+			return m, nil
+		}
+		m = m.applyCompressEstimate(msg)
+		if msg.whole {
+			return m.confirmCompact(true)
+		}
+		return m, nil
+
+	case compactMsg:
+		return m.applyCompact(msg)
+
+	case artifactResultMsg:
+		if msg.path != m.path {
+			return m, nil
+		}
+		m.scanning = false
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Error: %v", msg.err)
+			return m, nil
+		}
+		return m.applyArtifacts(msg), nil
+
+	case dockerPrunedMsg:
+		return m.applyDockerPruned(msg), nil
+
+	case componentMsg:
+		return m.applyComponents(msg), nil
+
+	case installerMsg:
+		return m.applyInstaller(msg), nil
+
+	case installerRemovedMsg:
+		return m.applyInstallerRemoved(msg)
+
+	case shadowMsg:
+		return m.applyShadows(msg), nil
+
+	case shadowChangedMsg:
+		return m.applyShadowChanged(msg)
+
+	case sysFilesMsg:
+		return m.applySysFiles(msg), nil
+
+	case hibernationMsg:
+		return m.applyHibernation(msg)
+
+	case suggestMsg:
+		return m.applySuggestions(msg), nil
+
+	case cleanedMsg:
+		return m.applyCleaned(msg), nil
+
+	case extResultMsg:
+		if msg.path != m.path {
+			return m, nil
+		}
+		m.scanning = false
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Error: %v", msg.err)
+			return m, nil
+		}
+		usage.Run("analyze.types")
+		m.types = &extBreakdown{stats: msg.stats, total: msg.total}
+		m.status = fmt.Sprintf("%d file types • Total: %s", len(msg.stats), humanize.Bytes(msg.total))
+		return m, nil
+
+	case ownerLookupMsg:
+		if m.owners != nil {
+			for key, owner := range msg.owners {
+				m.owners[key] = owner
+			}
+		}
+		return m, nil
+
+	case ownerResultMsg:
+		if msg.path != m.path {
+			return m, nil
+		}
+		m.scanning = false
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Error: %v", msg.err)
+			return m, nil
+		}
+		usage.Run("analyze.owners")
+		m.ownerTotals = &ownerBreakdown{stats: msg.stats, total: msg.total}
+		m.status = fmt.Sprintf("%d owners • Total: %s", len(msg.stats), humanize.Bytes(msg.total))
+		return m, nil
+
+	case drivesMsg:
+		return m.applyDrives(msg), nil
+
+	case recycleEmptiedMsg:
+		return m.applyRecycleEmptied(msg)
+
+	case snapshotsMsg:
+		return m.applySnapshots(msg), nil
+
+	case trendMsg:
+		return m.applyTrend(msg), nil
+
+	case dupMsg:
+		return m.applyDups(msg), nil
+
+	case mediaMsg:
+		return m.applyMedia(msg), nil
+
+	case previewMsg:
+		if m.media != nil {
+			m.media.previews[msg.path] = msg.preview
+		}
+		return m, nil
+
+	case watchMsg:
+		return m.applyWatch(msg)
+
+	case remeasureMsg:
+		return m.applyRemeasure(msg)
+
+	case fadeMsg:
+		return m.applyFade()
+
+	case batchMsg:
+		return m.applyBatch(msg)
+
+	case deleteMsg:
+		m.purging = nil
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Could not delete %s: %v", msg.entry.Name, msg.err)
+			if msg.permanent {
+				m.status += " • press r to rescan"
+			}
+			return m, nil
+		}
+		m = m.applyDelete(msg.entry)
+		dropMFTIndex(msg.entry.Path)
+		if msg.permanent {
+			traceAction("delete", msg.entry.Path, m.redactor)
+			usage.Freed("analyze.delete", msg.entry.Size)
+			m.status = fmt.Sprintf("Deleted %s (%s) • Total: %s", msg.entry.Name, humanize.Bytes(msg.entry.Size), humanize.Bytes(m.totalSize))
+		} else {
+			traceAction("recycle", msg.entry.Path, m.redactor)
+			usage.Freed("analyze.recycle", msg.entry.Size)
+			m.status = fmt.Sprintf("Moved %s to the Recycle Bin • Total: %s", msg.entry.Name, humanize.Bytes(m.totalSize))
+		}
 		return m, nil
 
 	case tickMsg:
 		if m.scanning {
 			m.spinner = (m.spinner + 1) % len(spinnerFrames)
-			files := atomic.LoadInt64(&m.filesScanned)
-			dirs := atomic.LoadInt64(&m.dirsScanned)
-			m.status = fmt.Sprintf("%s Scanning... %d files, %d dirs",
-				spinnerFrames[m.spinner], files, dirs)
+			m.status = m.scanStatus()
+			return m, tickCmd()
+		}
+		if m.purging != nil {
+			m.spinner = (m.spinner + 1) % len(spinnerFrames)
+			m.status = m.purging.status(spinnerFrames[m.spinner])
+			return m, tickCmd()
+		}
+		if m.batching != nil {
+			m.spinner = (m.spinner + 1) % len(spinnerFrames)
+			m.status = m.batching.status(spinnerFrames[m.spinner])
+			return m, tickCmd()
+		}
+		if m.dupSearch != nil {
+			m.spinner = (m.spinner + 1) % len(spinnerFrames)
+			m.status = m.dupSearch.status(spinnerFrames[m.spinner])
+			return m, tickCmd()
+		}
+		if m.mediaSearch != nil {
+			m.spinner = (m.spinner + 1) % len(spinnerFrames)
+			m.status = m.mediaSearch.status(spinnerFrames[m.spinner])
+			return m, tickCmd()
+		}
+		if m.compress != nil && m.compress.running {
+			m.spinner = (m.spinner + 1) % len(spinnerFrames)
+			m.status = m.compress.status(spinnerFrames[m.spinner])
+			return m, tickCmd()
+		}
+		if m.components != nil && (m.components.loading || m.components.running) {
+			m.spinner = (m.spinner + 1) % len(spinnerFrames)
+			m.status = m.components.status(spinnerFrames[m.spinner])
 			return m, tickCmd()
 		}
 		return m, nil
@@ -173,6 +711,101 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case m.drives != nil:
+		return m.handleDrivesKey(msg)
+	case m.purge != nil:
+		return m.handlePurgeKey(msg)
+	case m.confirm != nil:
+		return m.handleConfirmKey(msg)
+	case m.batch != nil:
+		return m.handleBatchKey(msg)
+	case m.filterPrompt != nil:
+		return m.handleFilterKey(msg)
+	case m.types != nil:
+		return m.handleTypesKey(msg)
+	case m.ownerTotals != nil:
+		return m.handleOwnersKey(msg)
+	case m.largest != nil:
+		return m.handleLargestKey(msg)
+	case m.unreadable != nil:
+		return m.handleUnreadableKey(msg)
+	case m.snapshots != nil:
+		return m.handleSnapshotsKey(msg)
+	case m.diff != nil:
+		return m.handleDiffKey(msg)
+	case m.trend != nil:
+		return m.handleTrendKey(msg)
+	case m.purging != nil, m.batching != nil:
+		// Keep the listing stable until the delete finishes.
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		return m, nil
+	case m.dupSearch != nil:
+		return m.handleDupSearchKey(msg)
+	case m.dups != nil:
+		return m.handleDupsKey(msg)
+	case m.stale != nil:
+		return m.handleStaleKey(msg)
+	case m.mediaSearch != nil:
+		return m.handleMediaSearchKey(msg)
+	case m.media != nil:
+		return m.handleMediaKey(msg)
+	case m.compress != nil:
+		return m.handleCompressKey(msg)
+	case m.suggest != nil:
+		return m.handleSuggestKey(msg)
+	case m.artifacts != nil:
+		return m.handleArtifactsKey(msg)
+	case m.components != nil:
+		return m.handleComponentsKey(msg)
+	case m.installer != nil:
+		return m.handleInstallerKey(msg)
+	case m.shadows != nil:
+		return m.handleShadowsKey(msg)
+	case m.sysFiles != nil:
+		return m.handleSysFilesKey(msg)
+	case m.streamList != nil:
+		return m.handleStreamsKey(msg)
+	}
+
+	if m.imported != "" {
+		switch msg.String() {
+		case "d", "D", "M", "G", "r", "w":
+			m.status = "Read-only: " + m.imported + " was recorded on another machine"
+			return m, nil
+		}
+	}
+
+	if msg.String() == "esc" && m.run != nil && !m.run.stopping {
+		return m.stopScan(), nil
+	}
+
+	if msg.String() == "esc" && m.filter != "" {
+		m.filter = ""
+		m.status = m.totalStatus()
+		return m.refilter(), nil
+	}
+
+	if msg.String() == "esc" && len(m.marks) > 0 {
+		clear(m.marks)
+		m.status = "Marks cleared"
+		return m, nil
+	}
+
+	if m.path == rootsPath {
+		if next, cmd, ok := m.rootsKey(msg.String()); ok {
+			return next, cmd
+		}
+	}
+
+	if m.treemap {
+		if dx, dy, ok := treemapDirection(msg.String()); ok {
+			return m.moveTreemap(dx, dy), nil
+		}
+	}
+
 	switch msg.String() {
 	case "q", "ctrl+c", "esc":
 		if len(m.history) > 0 {
@@ -182,10 +815,7 @@ func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.path = last.Path
 			m.selected = last.Selected
 			m.offset = last.Offset
-			m.scanning = true
-			atomic.StoreInt64(&m.filesScanned, 0)
-			atomic.StoreInt64(&m.dirsScanned, 0)
-			return m, tea.Batch(m.scanCmd(), tickCmd())
+			return m.load()
 		}
 		return m, tea.Quit
 
@@ -200,7 +830,7 @@ func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "down", "j":
 		if m.selected < len(m.entries)-1 {
 			m.selected++
-			viewportHeight := m.height - 6
+			viewportHeight := m.viewportHeight()
 			if m.selected >= m.offset+viewportHeight {
 				m.offset = m.selected - viewportHeight + 1
 			}
@@ -215,11 +845,9 @@ func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				Offset:   m.offset,
 			})
 			m.path = m.entries[m.selected].Path
-			m.scanning = true
-			m.status = "Scanning..."
-			atomic.StoreInt64(&m.filesScanned, 0)
-			atomic.StoreInt64(&m.dirsScanned, 0)
-			return m, tea.Batch(m.scanCmd(), tickCmd())
+			m.selected = 0
+			m.offset = 0
+			return m.load()
 		}
 
 	case "left", "h", "backspace":
@@ -229,13 +857,17 @@ func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.path = last.Path
 			m.selected = last.Selected
 			m.offset = last.Offset
-			m.scanning = true
-			atomic.StoreInt64(&m.filesScanned, 0)
-			atomic.StoreInt64(&m.dirsScanned, 0)
-			return m, tea.Batch(m.scanCmd(), tickCmd())
+			return m.load()
 		} else {
-			// Go to parent
+			// Go to parent, or back to the drives at a root
 			parent := filepath.Dir(m.path)
+			if parent == m.path && m.pickDrive {
+				return m.openDrives()
+			}
+			if parent == m.path && m.roots != nil {
+				m.path = rootsPath
+				return m.load()
+			}
 			if parent != m.path {
 				m.history = append(m.history, historyEntry{
 					Path:     m.path,
@@ -243,47 +875,461 @@ func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					Offset:   m.offset,
 				})
 				m.path = parent
-				m.scanning = true
-				atomic.StoreInt64(&m.filesScanned, 0)
-				atomic.StoreInt64(&m.dirsScanned, 0)
-				return m, tea.Batch(m.scanCmd(), tickCmd())
+				m.selected = 0
+				m.offset = 0
+				return m.load()
 			}
 		}
 
-	case "r":
+	case "b":
+		m.logScale = !m.logScale
+
+	case "z":
+		m.ageColors = !m.ageColors
+
+	case "A":
+		m.status = toggleTimes()
+
+	case "a":
+		m = m.toggleOnDisk()
+
+	case "L":
+		return m.toggleDedupe()
+
+	case "J":
+		return m.toggleFollow()
+
+	case "F":
+		return m.toggleStreams()
+
+	case ":":
+		if !m.scanning && len(m.entries) > 0 {
+			return m.showStreams(m.entries[m.selected])
+		}
+
+	case "s":
+		m.sort = m.sort.next()
+		if len(m.entries) > 0 {
+			m.focus = m.entries[m.selected].Path
+		}
+		m = m.refilter().selectFocus()
+
+	case "x":
+		if m.scanning {
+			break
+		}
+		if m.imported != "" {
+			m.status = "File types are not part of imported reports"
+			break
+		}
 		m.scanning = true
-		m.status = "Scanning..."
-		atomic.StoreInt64(&m.filesScanned, 0)
-		atomic.StoreInt64(&m.dirsScanned, 0)
-		return m, tea.Batch(m.scanCmd(), tickCmd())
+		m.status = "Counting file types..."
+		m.progress.Files.Store(0)
+		m.progress.Dirs.Store(0)
+		m.progress.Bytes.Store(0)
+		return m, tea.Batch(m.extCmd(), tickCmd())
+
+	case "U":
+		if !m.scanning {
+			return m.showOwners()
+		}
+
+	case "Y":
+		m = m.toggleOwners()
+
+	case "f":
+		if !m.scanning {
+			return m.showLargest()
+		}
+
+	case "g":
+		if !m.scanning {
+			return m.showStale()
+		}
+
+	case "v":
+		if !m.scanning {
+			return m.startMediaSearch()
+		}
+
+	case "Z":
+		if !m.scanning {
+			return m.showCompress(false)
+		}
+
+	case "K":
+		if !m.scanning {
+			return m.showCompress(true)
+		}
+
+	case "R":
+		if m.imported != "" {
+			m.status = "Read-only: " + m.imported + " was recorded on another machine"
+			break
+		}
+		if !m.scanning {
+			return m.openSuggestions()
+		}
+
+	case "B":
+		if !m.scanning {
+			return m.showArtifacts()
+		}
+
+	case "W":
+		if m.imported != "" {
+			m.status = "Read-only: " + m.imported + " was recorded on another machine"
+		} else if !m.scanning {
+			return m.showComponents()
+		}
+
+	case "I":
+		if m.imported != "" {
+			m.status = "Read-only: " + m.imported + " was recorded on another machine"
+		} else if !m.scanning {
+			return m.showInstaller()
+		}
+
+	case "V":
+		if m.imported != "" {
+			m.status = "Read-only: " + m.imported + " was recorded on another machine"
+		} else if !m.scanning {
+			return m.showShadows()
+		}
+
+	case "H":
+		if m.imported != "" {
+			m.status = "Read-only: " + m.imported + " was recorded on another machine"
+		} else if !m.scanning {
+			return m.showSysFiles()
+		}
+
+	case "i":
+		if !m.scanning {
+			m = m.showUnreadable()
+		}
+
+	case "/":
+		if !m.scanning {
+			m.filterPrompt = &filterPrompt{input: m.filter, previous: m.filter}
+		}
+
+	case "n", "N":
+		if !m.scanning {
+			return m.nextMatch(msg.String() == "n")
+		}
+
+	case "t":
+		m.treemap = !m.treemap
+		// Keep the block selected in the treemap on screen in the list.
+		if h := m.viewportHeight(); m.selected < m.offset || m.selected >= m.offset+h {
+			m.offset = max(m.selected-h+1, 0)
+		}
+
+	case "p":
+		m.redactor.Toggle()
+
+	case "P":
+		name, err := config.NextProfile(m.profile)
+		if err != nil {
+			m.status = fmt.Sprintf("Profiles unavailable: %v", err)
+			break
+		}
+		config.SetProfile(name)
+		cfg, err := loadConfig()
+		if err != nil {
+			m.status = fmt.Sprintf("Profile %s: using default settings: %v", profileLabel(name), err)
+		} else if !m.scanning {
+			m.status = m.totalStatus()
+		}
+		m.profile = name
+		m.logScale = cfg.BarScale == "log"
+		m.ageColors = cfg.BarColor == "age"
+		m.categories = newCategorizer(cfg)
+		m.icons = resolveIconSet(cfg.Icons)
+		m.exclude = m.exclude.withPatterns(cfg.Exclude)
+		m.staleDays = cfg.StaleDays
+
+	case " ":
+		if !m.scanning && len(m.entries) > 0 {
+			m = m.markSelected()
+		}
+
+	case "M":
+		if m.scanning || len(m.entries) == 0 {
+			break
+		}
+		entries := m.marks.entries()
+		if len(entries) == 0 {
+			entries = []Entry{m.entries[m.selected]}
+		}
+		m = m.confirmBatch(batchMove, entries)
+
+	case "G":
+		if m.scanning || len(m.entries) == 0 {
+			break
+		}
+		entries := m.marks.entries()
+		if len(entries) == 0 {
+			entries = []Entry{m.entries[m.selected]}
+		}
+		if reason := relocatable(entries); reason != "" {
+			m.status = reason
+			break
+		}
+		m = m.confirmBatch(batchRelocate, entries)
+
+	case "o", "O":
+		if len(m.entries) > 0 {
+			m = m.openAction(m.entries[m.selected], msg.String() == "O")
+		}
+
+	case "y":
+		if len(m.entries) > 0 {
+			m = m.copyPath(m.entries[m.selected])
+		}
+
+	case "X":
+		// Capital, since x opens the file types view.
+		if !m.scanning && len(m.entries) > 0 {
+			return m.excludeSelected(), nil
+		}
+
+	case "d":
+		if !m.scanning && len(m.marks) > 0 {
+			m = m.confirmBatch(batchRecycle, m.marks.entries())
+		} else if !m.scanning && len(m.entries) > 0 {
+			e := m.entries[m.selected]
+			if reason := deleteBlocked(e); reason != "" {
+				m.status = reason
+				break
+			}
+			m.confirm = &e
+		}
+
+	case "D":
+		if !m.scanning && len(m.marks) > 0 {
+			m = m.confirmBatch(batchDelete, m.marks.entries())
+		} else if !m.scanning && len(m.entries) > 0 {
+			e := m.entries[m.selected]
+			if reason := deleteBlocked(e); reason != "" {
+				m.status = reason
+				break
+			}
+			m.purge = &purgePrompt{entry: e}
+		}
+
+	case "e", "E":
+		if m.scanning {
+			break
+		}
+		ext := ".json"
+		if msg.String() == "E" {
+			ext = ".csv"
+		}
+		export := m.exportToCache
+		if len(m.marks) > 0 {
+			export = m.exportMarked
+		}
+		path, err := export(ext)
+		if err != nil {
+			m.status = fmt.Sprintf("Export failed: %v", err)
+		} else {
+			usage.Run("analyze.export")
+			traceAction("export", path, m.redactor)
+			m.status = "Exported to " + path
+		}
+
+	case "S":
+		if m.scanning {
+			break
+		}
+		path, err := m.saveSnapshot()
+		if err != nil {
+			m.status = fmt.Sprintf("Snapshot failed: %v", err)
+		} else {
+			usage.Run("analyze.snapshot")
+			traceAction("snapshot", path, m.redactor)
+			m.status = "Saved a snapshot to " + path + " • c compares with it later"
+		}
+
+	case "c":
+		if m.scanning {
+			break
+		}
+		if m.baseline == nil {
+			return m.openSnapshots()
+		}
+		m = m.showDiff()
+
+	case "C":
+		if !m.scanning {
+			return m.openSnapshots()
+		}
+
+	case "T":
+		if !m.scanning {
+			return m.openTrend()
+		}
+
+	case "u":
+		if m.scanning {
+			break
+		}
+		if m.imported != "" {
+			m.status = "Duplicates cannot be found in imported reports"
+			break
+		}
+		return m.startDupSearch()
+
+	case "w":
+		if !m.scanning || m.watch != nil {
+			return m.toggleWatch()
+		}
+
+	case "r":
+		traceAction("rescan", m.path, m.redactor)
+		dropMFTIndex(m.path)
+		m, cmd := m.startScan() // estimates from the listing being replaced
+		delete(m.cache, cacheKey(m.path))
+		if m.owners != nil {
+			m.owners = map[string]string{} // read again as rows are shown
+		}
+		return m, cmd
 	}
 
 	return m, nil
 }
 
+// cacheScan keeps a finished or stopped scan's listing.
+func (m model) cacheScan(msg scanResultMsg) model {
+	partial := errors.Is(msg.err, context.Canceled)
+	m.cache[cacheKey(msg.path)] = dirListing{path: msg.path, entries: msg.entries, totalSize: msg.totalSize, largest: msg.largest, links: msg.links, follow: msg.follow, streams: msg.streams, unreadable: msg.skipped, partial: partial}
+	if !partial {
+		usage.Run("analyze.scan")
+	}
+	traceScan(msg.path, msg.entries, msg.totalSize, m.redactor)
+	return m
+}
+
+// load shows m.path from the cache when it has been scanned before and
+// starts a scan otherwise.
+func (m model) load() (tea.Model, tea.Cmd) {
+	if m.path == rootsPath {
+		return m.loadRoots()
+	}
+	traceAction("open", m.path, m.redactor)
+	m.filter = ""
+	if listing, ok := m.cache[cacheKey(m.path)]; ok && (m.scannedAsShown(listing) || m.imported != "") {
+		m.scanning = false
+		m.entries = m.shown(listing.entries)
+		m.totalSize = listing.totalSize
+		if m.selected >= len(m.entries) {
+			m.selected, m.offset = 0, 0
+		}
+		m = m.selectFocus()
+		m.status = m.totalStatus()
+		if !listing.savedAt.IsZero() {
+			m.status += fmt.Sprintf(" • saved %s, r to rescan", humanize.Time(listing.savedAt))
+		}
+		return m, nil
+	}
+
+	if m.imported != "" {
+		m.scanning = false
+		m.entries, m.totalSize = nil, 0
+		m.selected, m.offset = 0, 0
+		m.focus = ""
+		m.status = "Not covered by " + m.imported
+		return m, nil
+	}
+
+	return m.startScan()
+}
+
 func (m model) View() string {
+	if m.drives != nil {
+		return m.redactor.String(m.renderDrives())
+	}
+
 	var b strings.Builder
 
 	// Header
-	header := titleStyle.Render(fmt.Sprintf("📁 %s", m.path))
+	title := m.path
+	if title == rootsPath {
+		title = strings.Join(m.roots, " + ")
+	}
+	header := titleStyle.Render(fmt.Sprintf("📁 %s", title))
 	b.WriteString(header)
+	b.WriteString(dimStyle.Render("  sorted by " + m.sort.String() + " • " + humanize.CurrentUnits().Label()))
 	b.WriteString("\n\n")
 
 	if m.scanning {
 		b.WriteString(statusStyle.Render(m.status))
 		b.WriteString("\n")
-		return b.String()
+		if m.run != nil && !m.run.stopping {
+			b.WriteString("\n" + dimStyle.Render("Esc stop and show what was measured so far") + "\n")
+		}
+		return m.redactor.String(b.String())
+	}
+	if m.dupSearch != nil || m.mediaSearch != nil {
+		b.WriteString(statusStyle.Render(m.status))
+		b.WriteString("\n\n" + dimStyle.Render("Esc stop") + "\n")
+		return m.redactor.String(b.String())
 	}
 
-	if len(m.entries) == 0 {
+	if m.purge != nil {
+		b.WriteString(m.renderPurge())
+		b.WriteString("\n")
+	} else if m.batch != nil {
+		b.WriteString(m.renderBatch())
+		b.WriteString("\n")
+	} else if m.types != nil {
+		b.WriteString(m.renderTypes())
+	} else if m.ownerTotals != nil {
+		b.WriteString(m.renderOwners())
+	} else if m.largest != nil {
+		b.WriteString(m.renderLargest())
+	} else if m.unreadable != nil {
+		b.WriteString(m.renderUnreadable())
+	} else if m.snapshots != nil {
+		b.WriteString(m.renderSnapshots())
+	} else if m.diff != nil {
+		b.WriteString(m.renderDiff())
+	} else if m.trend != nil {
+		b.WriteString(m.renderTrend())
+	} else if m.dups != nil {
+		b.WriteString(m.renderDups())
+	} else if m.stale != nil {
+		b.WriteString(m.renderStale())
+	} else if m.media != nil {
+		b.WriteString(m.renderMedia())
+	} else if m.compress != nil {
+		b.WriteString(m.renderCompress())
+	} else if m.suggest != nil {
+		b.WriteString(m.renderSuggest())
+	} else if m.artifacts != nil {
+		b.WriteString(m.renderArtifacts())
+	} else if m.components != nil {
+		b.WriteString(m.renderComponents())
+	} else if m.installer != nil {
+		b.WriteString(m.renderInstaller())
+	} else if m.shadows != nil {
+		b.WriteString(m.renderShadows())
+	} else if m.sysFiles != nil {
+		b.WriteString(m.renderSysFiles())
+	} else if m.streamList != nil {
+		b.WriteString(m.renderStreams())
+	} else if len(m.entries) == 0 && m.filter != "" {
+		b.WriteString(dimStyle.Render("  (no entries match)"))
+		b.WriteString("\n")
+	} else if len(m.entries) == 0 {
 		b.WriteString(dimStyle.Render("  (empty directory)"))
 		b.WriteString("\n")
+	} else if m.treemap {
+		b.WriteString(m.renderTreemap())
 	} else {
-		viewportHeight := m.height - 6
-		if viewportHeight < 5 {
-			viewportHeight = 5
-		}
-
+		viewportHeight := m.viewportHeight()
 		endIdx := m.offset + viewportHeight
 		if endIdx > len(m.entries) {
 			endIdx = len(m.entries)
@@ -293,137 +1339,227 @@ func (m model) View() string {
 			entry := m.entries[i]
 
 			// Size bar
-			var barWidth int
-			if m.totalSize > 0 {
-				barWidth = int(float64(entry.Size) / float64(m.totalSize) * 20)
-				if barWidth > 20 {
-					barWidth = 20
-				}
-			}
-			bar := strings.Repeat("█", barWidth) + strings.Repeat("░", 20-barWidth)
+			filled := barWidth(m.size(entry), m.total(), m.logScale, 20)
+			bar := strings.Repeat("█", filled) + strings.Repeat("░", 20-filled)
 
-			// Icon
-			icon := "📄"
-			if entry.IsDir {
-				icon = "📁"
-			}
+			icon := m.icons.icon(entry, m.categories)
 
 			// Format line
-			size := sizeStyle.Render(humanizeBytes(entry.Size))
+			size := sizeStyle.Render(humanize.Bytes(m.size(entry)))
 			barStr := barStyle.Render(bar)
+			if m.ageColors {
+				barStr = ageStyle(entry.ModTime).Render(bar)
+			}
 			name := fmt.Sprintf("%s %s", icon, entry.Name)
+			if entry.Target != "" {
+				name += " → " + entry.Target
+			}
+			name += m.cloudSuffix(entry)
+			name += streamSuffix(entry)
+			name += m.componentSuffix(entry)
+			name += sysFileSuffix(entry)
+			changed, delta, isChanged := m.changeStyle(entry)
+			if isChanged {
+				name += delta
+			}
 
-			line := fmt.Sprintf("%s %s %s", size, barStr, name)
+			// Drift against the baseline, if one was given
+			drift := ""
+			if m.baseline != nil {
+				drift = fmt.Sprintf("%-10s ", m.baseline.driftLabel(entry))
+			}
+
+			// File and folder counts, and the date when sorted by it
+			column := countColumns(entry) + m.sortColumn(entry) + m.ownerColumn(entry)
+
+			// Marks, when there are any
+			if len(m.marks) > 0 {
+				if m.marks.has(entry) {
+					name = "● " + name
+				} else {
+					name = "  " + name
+				}
+			}
 
 			if i == m.selected {
+				line := fmt.Sprintf("%s %s %s%s%s", size, barStr, drift, column, name)
 				b.WriteString(selectedStyle.Render(line))
 			} else {
-				b.WriteString(normalStyle.Render(line))
+				nameStyle := normalStyle
+				if !entry.IsDir {
+					nameStyle = m.categories.style(entry.Name)
+				}
+				if isChanged {
+					nameStyle = changed
+				}
+				b.WriteString(fmt.Sprintf("%s %s %s%s%s", size, barStr, warnStyle.Render(drift), dimStyle.Render(column), nameStyle.Render(name)))
 			}
 			b.WriteString("\n")
 		}
 	}
 
-	// Status bar
+	// Legend
 	b.WriteString("\n")
-	b.WriteString(statusStyle.Render(m.status))
+	if m.ageColors {
+		b.WriteString(ageLegend())
+	} else {
+		b.WriteString(m.categories.legend())
+	}
 	b.WriteString("\n")
-	b.WriteString(dimStyle.Render("↑/↓ navigate • Enter/→ open • ←/Backspace back • r refresh • q quit"))
 
-	return b.String()
-}
-
-// scanDirectory scans a directory and returns entries sorted by size
-func scanDirectory(path string, filesScanned, dirsScanned *int64) ([]Entry, int64, error) {
-	var entries []Entry
-	var totalSize int64
-	var mu sync.Mutex
-
-	dirEntries, err := os.ReadDir(path)
-	if err != nil {
-		return nil, 0, err
+	// Status bar
+	b.WriteString("\n")
+	status := m.status
+	if m.logScale {
+		status += " • log scale"
 	}
-
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, 10) // Limit concurrent goroutines
-
-	for _, de := range dirEntries {
-		de := de
-		wg.Add(1)
-		sem <- struct{}{}
-
-		go func() {
-			defer wg.Done()
-			defer func() { <-sem }()
-
-			fullPath := filepath.Join(path, de.Name())
-			var size int64
-
-			if de.IsDir() {
-				atomic.AddInt64(dirsScanned, 1)
-				size = getDirSize(fullPath, filesScanned, dirsScanned)
-			} else {
-				atomic.AddInt64(filesScanned, 1)
-				if info, err := de.Info(); err == nil {
-					size = info.Size()
-				}
-			}
-
-			mu.Lock()
-			entries = append(entries, Entry{
-				Name:  de.Name(),
-				Path:  fullPath,
-				Size:  size,
-				IsDir: de.IsDir(),
-			})
-			totalSize += size
-			mu.Unlock()
-		}()
+	if m.ageColors {
+		status += " • colored by age"
 	}
+	if m.onDisk {
+		status += " • size on disk"
+	}
+	if m.dedupe {
+		status += " • hard links once"
+	}
+	if m.follow {
+		status += " • following links"
+	}
+	if m.streams {
+		status += " • streams counted"
+	}
+	if n := m.unreadableCount(); n > 0 {
+		status += fmt.Sprintf(" • %d inaccessible (i)", n)
+	}
+	if m.profile != "" {
+		status += " • profile " + m.profile
+	}
+	if m.imported != "" {
+		status += " • imported " + m.imported
+	}
+	if m.baseline != nil {
+		status += " • vs " + m.baseline.name
+	}
+	if m.filter != "" {
+		status += fmt.Sprintf(" • filter %q, Esc clears", m.filter)
+	}
+	if len(m.marks) > 0 {
+		status += fmt.Sprintf(" • %d marked (%s)", len(m.marks.entries()), humanize.Bytes(m.markedSize()))
+	}
+	status += m.watchStatus()
+	if m.redactor.Enabled() {
+		status += " • redacted"
+	}
+	if m.confirm != nil {
+		b.WriteString(m.renderConfirm())
+	} else if m.filterPrompt != nil {
+		b.WriteString(m.renderFilterPrompt())
+	} else {
+		b.WriteString(statusStyle.Render(status))
+	}
+	b.WriteString("\n")
+	help := "↑/↓ navigate • Enter/→ open • ←/Backspace back • Space mark • d recycle • D delete • M move • G relocate to another drive • o open • O show in Explorer • y copy path • e/E export • S snapshot • c/C compare • T trend • t treemap • x file types • U by owner • f largest files • g old files • u duplicates • v photos and videos • Z compress • K compress whole folder • R suggestions • B build folders • W component store • I installer cache • V shadow copies • H paging and hibernation files • i inaccessible • w watch • X exclude (x is file types) • / filter • n/N next match • s sort • a size on disk • L hard links • J follow links • F count streams • : streams of the entry • Y owner column • b bar scale • z color by age • A absolute/relative times • p redact • P profile • r refresh • q quit"
+	if m.types != nil {
+		help = "↑/↓ scroll • x/Esc back to the list"
+	}
+	if m.ownerTotals != nil {
+		help = "↑/↓ scroll • U/Esc back to the list"
+	}
+	if m.largest != nil {
+		help = "↑/↓ navigate • Enter/→ open containing folder • o open • O show in Explorer • y copy path • f/Esc back to the list"
+	}
+	if m.unreadable != nil {
+		help = "↑/↓ scroll • i/Esc back to the list"
+	}
+	if m.snapshots != nil {
+		help = "↑/↓ select • Enter compare • Esc back to the list"
+	}
+	if m.diff != nil {
+		help = "↑/↓ navigate • Enter/→ open containing folder • y copy path • C other snapshot • c/Esc back to the list"
+	}
+	if m.trend != nil {
+		help = "↑/↓ select • Enter compare with this snapshot • T/Esc back to the list"
+	}
+	if m.dups != nil {
+		help = "↑/↓ navigate • Space select copy • A select all but one per set • d recycle selected • L hard-link selected • Enter/→ open containing folder • o open • y copy path • u/Esc back to the list"
+	}
+	if m.stale != nil {
+		help = "↑/↓ navigate • +/- older/newer • m not changed/not used • Space select • d recycle selected • Enter/→ open containing folder • o open • y copy path • g/Esc back to the list"
+	}
+	if m.suggest != nil {
+		help = "↑/↓ select • Enter clean • o open • y copy path • r measure again • R/Esc back to the list"
+	}
+	if m.artifacts != nil {
+		help = "↑/↓ navigate • Space select • a select kind • D list then delete selected • p prune Docker build cache • Enter/→ open containing folder • o open • y copy path • B/Esc back to the list"
+	}
+	if m.installer != nil {
+		help = "↑/↓ navigate • Space select • A select all • D delete selected • O show in Explorer • y copy path • r read again • I/Esc back to the list"
+	}
+	if m.sysFiles != nil {
+		help = "h hibernation off • s reduced hibernation file • f full hibernation • p paging file settings • r read again • H/Esc back to the list"
+	}
+	if m.streamList != nil {
+		help = "↑/↓ navigate • y copy file:stream • :/Esc back to the list"
+	}
+	if m.shadows != nil {
+		help = "↑/↓ navigate • m set maximum • o delete oldest copy • r read again • V/Esc back to the list"
+	}
+	if m.components != nil {
+		help = "c clean up the component store • r analyze again • W/Esc back to the list"
+	}
+	if m.compress != nil {
+		help = "↑/↓ navigate • Space pick extension • m method • Enter compress picked • c compress whole folder • Z/Esc back to the list"
+	}
+	if m.media != nil {
+		help = "Tab next page • ↑/↓ navigate • Space select spare • A select all spares • d recycle selected • Enter/→ open containing folder • o open • y copy path • v/Esc back to the list"
+	}
+	b.WriteString(dimStyle.Render(help))
 
-	wg.Wait()
-
-	// Sort by size descending
-	sort.Slice(entries, func(i, j int) bool {
-		return entries[i].Size > entries[j].Size
-	})
-
-	return entries, totalSize, nil
+	return m.redactor.String(b.String())
 }
 
-// getDirSize calculates the total size of a directory
-func getDirSize(path string, filesScanned, dirsScanned *int64) int64 {
-	var size int64
-
-	filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
-		if err != nil {
-			return nil // Skip errors
-		}
+// viewportHeight is the number of list rows that fit between the header
+// and the legend/status/help footer.
+func (m model) viewportHeight() int {
+	h := m.height - 8
+	if h < 5 {
+		h = 5
+	}
+	return h
+}
 
-		if d.IsDir() {
-			atomic.AddInt64(dirsScanned, 1)
-		} else {
-			atomic.AddInt64(filesScanned, 1)
-			if info, err := d.Info(); err == nil {
-				size += info.Size()
-			}
+// scanDirectory scans a directory and returns entries sorted by size. When
+// ctx is done it returns what was measured so far with ctx.Err().
+func scanDirectory(ctx context.Context, path string, progress *scan.Counters) ([]Entry, int64, error) {
+	if progress == nil {
+		progress = new(scan.Counters)
+	}
+	if isNetworkPath(path) {
+		progress.Workers = networkWorkers
+	}
+	// Elevated on NTFS, the MFT has everything; fall back to walking on
+	// any problem reading it.
+	if vol := mftVolume(path); vol != "" && !progress.FollowLinks && !progress.Streams && progress.Exclude == nil {
+		if entries, total, err := scanMFT(ctx, vol, path, progress); err == nil {
+			return entries, total, ctx.Err()
 		}
-		return nil
-	})
-
-	return size
+	}
+	return scan.DirContext(ctx, path, progress)
 }
 
-// humanizeBytes converts bytes to human-readable format
-func humanizeBytes(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
+// barWidth returns how many of width cells an entry of size should fill.
+// The log scale keeps small entries visible when one entry dominates the total.
+func barWidth(size, total int64, logScale bool, width int) int {
+	if size <= 0 || total <= 0 {
+		return 0
+	}
+	ratio := float64(size) / float64(total)
+	if logScale {
+		ratio = math.Log1p(float64(size)) / math.Log1p(float64(total))
 	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+	filled := int(ratio * float64(width))
+	if filled > width {
+		filled = width
 	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+	return filled
 }
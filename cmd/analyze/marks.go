@@ -0,0 +1,521 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/usage"
+	"github.com/winmole/winmole/pkg/humanize"
+	"golang.org/x/sys/windows"
+)
+
+// Space marks the selected entry, in any folder, and the status line sums
+// what is marked. While anything is marked, d, D, M and G recycle, delete,
+// move or relocate all of it after one confirmation, and e/E export just
+// the marked entries. Esc clears the marks.
+
+// Batch operations.
+const (
+	batchRecycle  = "recycle"
+	batchDelete   = "delete"
+	batchMove     = "move"
+	batchLink     = "link"     // replace duplicates with hard links, see dupes.go
+	batchRelocate = "relocate" // move to another drive behind a junction, see relocate.go
+)
+
+// marks are the marked entries by cacheKey. Like the cache, the map is
+// shared by copies of the model.
+type marks map[string]Entry
+
+// toggle marks e, or unmarks it when it is marked.
+func (k marks) toggle(e Entry) {
+	key := cacheKey(e.Path)
+	if _, ok := k[key]; ok {
+		delete(k, key)
+	} else {
+		k[key] = e
+	}
+}
+
+func (k marks) has(e Entry) bool {
+	_, ok := k[cacheKey(e.Path)]
+	return ok
+}
+
+// drop unmarks path and everything below it, once it is gone from disk.
+func (k marks) drop(path string) {
+	target := cacheKey(path)
+	for key := range k {
+		if key == target || isUnder(key, target) {
+			delete(k, key)
+		}
+	}
+}
+
+// entries are the marked entries largest first, leaving out those inside
+// a marked folder, which go with it.
+func (k marks) entries() []Entry {
+	var list []Entry
+	for key, e := range k {
+		inside := false
+		for other := range k {
+			if other != key && isUnder(key, other) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			list = append(list, e)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Size != list[j].Size {
+			return list[i].Size > list[j].Size
+		}
+		return list[i].Path < list[j].Path
+	})
+	return list
+}
+
+// markedSize sums the marked entries as the list shows their sizes.
+func (m model) markedSize() int64 {
+	var total int64
+	for _, e := range m.marks.entries() {
+		total += m.size(e)
+	}
+	return total
+}
+
+// markSelected toggles the mark on the selected entry and moves down.
+func (m model) markSelected() model {
+	m.marks.toggle(m.entries[m.selected])
+	if m.selected < len(m.entries)-1 {
+		m.selected++
+		if h := m.viewportHeight(); m.selected >= m.offset+h {
+			m.offset = m.selected - h + 1
+		}
+	}
+	return m
+}
+
+// batchPrompt is the confirmation for a batch operation. Deletes are
+// confirmed by typing "delete", moves and relocations by typing the
+// destination folder.
+type batchPrompt struct {
+	op      string
+	entries []Entry
+	size    int64
+	input   string
+	links   map[string]string // batchLink: each entry's copy to link to, by cacheKey
+}
+
+// batchProgress is shared with the goroutine working through a batch.
+type batchProgress struct {
+	op      string
+	entries []Entry
+	size    int64
+	dest    string            // folder moved or relocated into
+	links   map[string]string // see batchPrompt
+	done    atomic.Int64      // entries finished
+	tree    purgeProgress     // files and bytes handled so far
+}
+
+type batchMsg struct {
+	op     string
+	dest   string
+	done   []Entry
+	failed []string // "name: reason"
+}
+
+// confirmBatch asks before running op on entries.
+func (m model) confirmBatch(op string, entries []Entry) model {
+	for _, e := range entries {
+		if reason := deleteBlocked(e); reason != "" {
+			m.status = reason
+			return m
+		}
+	}
+	var size int64
+	for _, e := range entries {
+		size += e.Size
+	}
+	m.batch = &batchPrompt{op: op, entries: entries, size: size}
+	return m
+}
+
+// handleBatchKey answers the batch prompt. A recycle goes ahead on 'y';
+// deletes, moves and relocations take typed input and Enter.
+func (m model) handleBatchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.batch
+	if p.op == batchRecycle || p.op == batchLink {
+		m.batch = nil
+		if msg.String() != "y" && msg.String() != "Y" {
+			m.status = "Batch cancelled"
+			return m, nil
+		}
+		return m.runBatch(p, "")
+	}
+
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.batch = nil
+		m.status = "Batch cancelled"
+	case tea.KeyEnter:
+		if p.op == batchDelete && p.input == "delete" {
+			m.batch = nil
+			return m.runBatch(p, "")
+		}
+		if p.op == batchMove || p.op == batchRelocate {
+			check := moveDestination
+			if p.op == batchRelocate {
+				check = relocateDestination
+			}
+			dest, err := check(p.input, p.entries)
+			if err != nil {
+				m.status = err.Error()
+				return m, nil
+			}
+			m.batch = nil
+			return m.runBatch(p, dest)
+		}
+	case tea.KeyBackspace:
+		if r := []rune(p.input); len(r) > 0 {
+			p.input = string(r[:len(r)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		p.input += string(msg.Runes)
+	}
+	return m, nil
+}
+
+// moveDestination checks the folder typed for a move.
+func moveDestination(input string, entries []Entry) (string, error) {
+	dest := strings.Trim(strings.TrimSpace(input), `"`)
+	if dest == "" {
+		return "", errors.New("Type the folder to move into")
+	}
+	dest, err := filepath.Abs(dest)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(dest); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%s is not a folder", dest)
+	}
+	key := cacheKey(dest)
+	for _, e := range entries {
+		if src := cacheKey(e.Path); key == src || isUnder(key, src) {
+			return "", fmt.Errorf("Cannot move %s into itself", e.Name)
+		}
+	}
+	return dest, nil
+}
+
+func (m model) runBatch(p *batchPrompt, dest string) (tea.Model, tea.Cmd) {
+	progress := &batchProgress{op: p.op, entries: p.entries, size: p.size, dest: dest, links: p.links}
+	m.batching = progress
+	return m, tea.Batch(batchCmd(progress), tickCmd())
+}
+
+func batchCmd(p *batchProgress) tea.Cmd {
+	return func() tea.Msg {
+		msg := batchMsg{op: p.op, dest: p.dest}
+		for _, e := range p.entries {
+			var err error
+			switch p.op {
+			case batchRecycle:
+				err = recycle(e.Path)
+				p.tree.bytes.Add(e.Size)
+			case batchDelete:
+				err = removeTree(e.Path, &p.tree)
+			case batchMove:
+				err = moveEntry(e, p.dest, &p.tree)
+			case batchRelocate:
+				err = relocateEntry(e, p.dest, &p.tree)
+			case batchLink:
+				err = linkDuplicate(e.Path, p.links[cacheKey(e.Path)])
+				p.tree.bytes.Add(e.Size)
+			}
+			p.done.Add(1)
+			if err != nil {
+				msg.failed = append(msg.failed, fmt.Sprintf("%s: %v", e.Name, err))
+				continue
+			}
+			msg.done = append(msg.done, e)
+		}
+		return msg
+	}
+}
+
+// moveEntry moves e into the folder dest. Within a drive it is a rename;
+// across drives the files are copied and the originals deleted.
+func moveEntry(e Entry, dest string, p *purgeProgress) error {
+	path := e.Path
+	target := filepath.Join(dest, filepath.Base(path))
+	if _, err := os.Lstat(target); err == nil {
+		return fmt.Errorf("%s already exists", target)
+	}
+	err := os.Rename(path, target)
+	if err == nil {
+		p.bytes.Add(e.Size)
+	}
+	if err == nil || !errors.Is(err, windows.ERROR_NOT_SAME_DEVICE) {
+		return err
+	}
+	if err := copyTree(path, target, p); err != nil {
+		removeTree(target, new(purgeProgress))
+		return fmt.Errorf("copy: %w", err)
+	}
+	return removeTree(path, new(purgeProgress))
+}
+
+// copyTree copies the files and folders below src to dst, which must not
+// exist. Links and junctions are not followed and stop the copy, since
+// what they point at would not come along.
+func copyTree(src, dst string, p *purgeProgress) error {
+	return filepath.WalkDir(src, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, name)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case d.IsDir():
+			return os.Mkdir(target, 0o755)
+		case !d.Type().IsRegular():
+			return fmt.Errorf("%s is a link; move it within its drive instead", name)
+		}
+		n, err := copyFile(name, target)
+		p.files.Add(1)
+		p.bytes.Add(n)
+		return err
+	})
+}
+
+func copyFile(src, dst string) (int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return 0, err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode())
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chtimes(dst, info.ModTime(), info.ModTime())
+	}
+	return n, err
+}
+
+// applyBatch updates the listings after a batch: entries done are gone
+// from where they were, and folders a move added to are scanned again. A
+// relocated folder's parent is scanned again too, to show its junction.
+func (m model) applyBatch(msg batchMsg) (tea.Model, tea.Cmd) {
+	m.batching = nil
+	var size int64
+	for _, e := range msg.done {
+		if msg.op != batchLink {
+			// Linked copies keep their paths and sizes.
+			m = m.applyDelete(e)
+		}
+		dropMFTIndex(e.Path)
+		traceAction(msg.op, e.Path, m.redactor)
+		size += e.Size
+	}
+	if m.dups != nil {
+		m.dups.without(msg.done)
+	}
+	if m.stale != nil {
+		m.stale.without(msg.done)
+	}
+	if m.media != nil {
+		m.media.lib.without(msg.done)
+	}
+	if m.artifacts != nil {
+		m.artifacts.without(msg.done)
+	}
+
+	verb := map[string]string{
+		batchRecycle:  "Moved %s (%s) to the Recycle Bin",
+		batchDelete:   "Deleted %s (%s)",
+		batchMove:     "Moved %s (%s) to " + msg.dest,
+		batchLink:     "Replaced %s (%s) with hard links",
+		batchRelocate: "Relocated %s (%s) to " + msg.dest + ", leaving junctions",
+	}[msg.op]
+	m.status = fmt.Sprintf(verb, itemCount(len(msg.done)), humanize.Bytes(size))
+	if len(msg.failed) > 0 {
+		m.status += fmt.Sprintf(" • %d failed: %s", len(msg.failed), strings.Join(msg.failed, "; "))
+	}
+
+	switch msg.op {
+	case batchRecycle:
+		usage.Freed("analyze.recycle", size)
+	case batchDelete:
+		usage.Freed("analyze.delete", size)
+	case batchLink:
+		usage.Freed("analyze.link", size)
+	case batchMove, batchRelocate:
+		if msg.op == batchMove {
+			usage.Run("analyze.move")
+		} else {
+			usage.Freed("analyze.relocate", size)
+			for _, e := range msg.done {
+				delete(m.cache, cacheKey(filepath.Dir(e.Path)))
+			}
+		}
+		if len(msg.done) > 0 {
+			// The destination and the folders above it grew by an amount
+			// only a rescan can tell.
+			dest := cacheKey(msg.dest)
+			dropMFTIndex(msg.dest)
+			for key := range m.cache {
+				if key == dest || isUnder(dest, key) {
+					delete(m.cache, key)
+				}
+			}
+			if _, ok := m.cache[cacheKey(m.path)]; !ok {
+				status := m.status
+				next, cmd := m.load()
+				m = next.(model)
+				m.status = status
+				return m, cmd
+			}
+		}
+	}
+	m.status += " • Total: " + humanize.Bytes(m.totalSize)
+	return m, nil
+}
+
+// status describes a running batch.
+func (p *batchProgress) status(frame string) string {
+	verb := map[string]string{batchRecycle: "Recycling", batchDelete: "Deleting", batchMove: "Moving", batchLink: "Linking", batchRelocate: "Relocating"}[p.op]
+	done := min(p.done.Load()+1, int64(len(p.entries)))
+	line := fmt.Sprintf("%s %s %d of %d items... %s", frame, verb, done, len(p.entries), humanize.Bytes(p.tree.bytes.Load()))
+	if p.size > 0 {
+		pct := min(float64(p.tree.bytes.Load())/float64(p.size)*100, 100)
+		line += fmt.Sprintf(" of %s (%.0f%%)", humanize.Bytes(p.size), pct)
+	}
+	return line
+}
+
+func itemCount(n int) string {
+	if n == 1 {
+		return "1 item"
+	}
+	return fmt.Sprintf("%d items", n)
+}
+
+// batchPreview is how many of the entries a batch prompt lists.
+const batchPreview = 5
+
+// renderBatch draws the batch confirmation.
+func (m model) renderBatch() string {
+	p := m.batch
+	title := map[string]string{
+		batchRecycle:  "Move %s (%s) to the Recycle Bin?",
+		batchDelete:   "Permanently delete %s (%s)?",
+		batchMove:     "Move %s (%s) to another folder?",
+		batchLink:     "Replace %s (%s) with hard links to the copies kept?",
+		batchRelocate: "Relocate %s (%s) to another drive, leaving junctions?",
+	}[p.op]
+
+	var b strings.Builder
+	b.WriteString(warnStyle.Render(fmt.Sprintf(title, itemCount(len(p.entries)), humanize.Bytes(p.size))))
+	b.WriteString("\n\n")
+	for i, e := range p.entries {
+		if i == batchPreview {
+			b.WriteString(dimStyle.Render(fmt.Sprintf("  and %d more", len(p.entries)-batchPreview)))
+			b.WriteString("\n")
+			break
+		}
+		b.WriteString(normalStyle.Render(fmt.Sprintf("  %10s  %s", humanize.Bytes(e.Size), e.Path)))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	switch p.op {
+	case batchRecycle:
+		b.WriteString(dimStyle.Render("y recycle • any other key cancels"))
+	case batchLink:
+		b.WriteString(dimStyle.Render("Linked copies share one file: a change to one shows in all"))
+		b.WriteString("\n\n")
+		b.WriteString(dimStyle.Render("y link • any other key cancels"))
+	case batchDelete:
+		b.WriteString(dimStyle.Render("Bypasses the Recycle Bin and cannot be undone"))
+		b.WriteString("\n\n")
+		b.WriteString(normalStyle.Render(`Type "delete" to confirm:`))
+		b.WriteString("\n")
+		input := "> " + p.input + "█"
+		if p.input == "delete" {
+			b.WriteString(selectedStyle.Render(input))
+			b.WriteString("\n\n")
+			b.WriteString(dimStyle.Render("Enter delete • Esc cancel"))
+		} else {
+			b.WriteString(normalStyle.Render(input))
+			b.WriteString("\n\n")
+			b.WriteString(dimStyle.Render("Esc cancel"))
+		}
+	case batchMove:
+		b.WriteString(normalStyle.Render("Folder to move them into:"))
+		b.WriteString("\n")
+		b.WriteString(normalStyle.Render("> " + p.input + "█"))
+		b.WriteString("\n\n")
+		b.WriteString(dimStyle.Render("Enter move • Esc cancel"))
+	case batchRelocate:
+		b.WriteString(dimStyle.Render("Programs keep finding them at their old paths; permissions are not copied"))
+		b.WriteString("\n\n")
+		b.WriteString(normalStyle.Render("Folder on another drive to move them into:"))
+		b.WriteString("\n")
+		b.WriteString(normalStyle.Render("> " + p.input + "█"))
+		b.WriteString("\n\n")
+		b.WriteString(dimStyle.Render("Enter relocate • Esc cancel"))
+	}
+	return modalStyle.Render(b.String())
+}
+
+// exportMarked writes the marked entries, with what was scanned below
+// them, into the cache directory and returns its path. The export's root
+// is the folder holding them all.
+func (m model) exportMarked(ext string) (string, error) {
+	entries := m.marks.entries()
+	root := filepath.Dir(entries[0].Path)
+	for _, e := range entries[1:] {
+		for root != filepath.Dir(root) && !isUnder(cacheKey(e.Path), cacheKey(root)) {
+			root = filepath.Dir(root)
+		}
+	}
+
+	c := make(dirCache, len(m.cache)+1)
+	for key, l := range m.cache {
+		c[key] = l
+	}
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+	c[cacheKey(root)] = dirListing{path: root, entries: entries, totalSize: total}
+
+	m.cache, m.path = c, root
+	return m.exportToCache(ext)
+}
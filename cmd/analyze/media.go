@@ -0,0 +1,932 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/winmole/winmole/internal/usage"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/scan"
+)
+
+// v looks at the photos and videos below the current folder as a library
+// rather than as files. Tab moves between four pages:
+//
+//   - Overview totals the library by camera and by month taken.
+//   - Bursts are shots from one camera, in one folder, taken within
+//     burstGap of each other and looking alike; all but the largest of each
+//     is usually a spare.
+//   - Exports are one picture saved at several resolutions, found by their
+//     shared camera and capture time and confirmed by how they look; the
+//     largest resolution is kept.
+//   - Videos lists videos in codecs older than HEVC with what re-encoding
+//     them at a typical HEVC bit rate for their frame size would save.
+//
+// Space selects spare copies on the Bursts and Exports pages and d recycles
+// them. When the window is wide enough, a pane on the right shows what the
+// selected file's headers say and a preview of photos.
+
+const (
+	// burstGap is how far apart shots of one burst are taken at most.
+	burstGap = 2 * time.Second
+	// nearHashBits is how many bits of two pictures' difference hashes
+	// may differ for them to count as the same picture.
+	nearHashBits = 10
+	// mediaWorkers is how many files are read at once.
+	mediaWorkers = 4
+	// mediaPaneWidth is the width of the detail pane.
+	mediaPaneWidth = 36
+)
+
+// mediaTabs are the pages of the view.
+var mediaTabs = []string{"Overview", "Bursts", "Exports", "Videos"}
+
+const (
+	tabOverview = iota
+	tabBursts
+	tabExports
+	tabVideos
+)
+
+// mediaSearch is shared with the goroutines reading the library.
+type mediaSearch struct {
+	path   string
+	cancel context.CancelFunc
+	files  atomic.Int64 // media files found
+	read   atomic.Int64 // of them, read
+}
+
+type mediaMsg struct {
+	path string
+	lib  *mediaLibrary
+	err  error
+}
+
+// mediaLibrary is what the search found.
+type mediaLibrary struct {
+	items   []mediaItem
+	cameras []mediaGroup // largest first
+	months  []mediaGroup // newest first
+	bursts  []mediaSet
+	exports []mediaSet
+	videos  []videoSaving // most saved first
+}
+
+// mediaGroup totals the files of one camera or month.
+type mediaGroup struct {
+	name   string
+	photos int
+	videos int
+	size   int64
+}
+
+// mediaSet is a set of near-identical pictures, the one to keep first.
+type mediaSet struct {
+	items []int // into mediaLibrary.items
+}
+
+// videoSaving is what re-encoding a video would save.
+type videoSaving struct {
+	item   int
+	target int64 // estimated size once re-encoded
+}
+
+// mediaView shows the library of one folder.
+type mediaView struct {
+	lib      *mediaLibrary
+	tab      int
+	chosen   map[string]bool
+	selected int // row, see rows
+	offset   int
+	previews map[string]string // rendered previews by path; "" when none
+}
+
+// mediaRow is a line of a page: a set's header when item is -1.
+type mediaRow struct {
+	set, item int
+}
+
+func (m model) mediaCmd(ctx context.Context, s *mediaSearch) tea.Cmd {
+	walk := &scan.Counters{Exclude: m.exclude.hook(), FollowLinks: m.follow}
+	return func() tea.Msg {
+		lib, err := findMedia(ctx, s, walk)
+		return mediaMsg{path: s.path, lib: lib, err: err}
+	}
+}
+
+// findMedia lists the photos and videos below s.path, reads their headers
+// and works out the library's groups, bursts, exports and video savings.
+func findMedia(ctx context.Context, s *mediaSearch, walk *scan.Counters) (*mediaLibrary, error) {
+	var files []Entry
+	add := func(dir string, name string, size int64, modified time.Time) {
+		ext := strings.ToLower(filepath.Ext(name))
+		if !photoExtensions[ext] && !videoExtensions[ext] {
+			return
+		}
+		s.files.Add(1)
+		files = append(files, Entry{Name: name, Path: filepath.Join(dir, name), Size: size, Alloc: size, Files: 1, ModTime: modified})
+	}
+
+	fromMFT := false
+	if vol := mftVolume(s.path); vol != "" && walk.Exclude == nil && !walk.FollowLinks {
+		if idx, err := loadMFTIndex(ctx, vol, new(scan.Counters)); err == nil {
+			fromMFT = idx.eachNode(s.path, func(dir string, n *mftNode) {
+				if !n.remote && !n.reparse {
+					modified, _ := n.times()
+					add(dir, n.name, n.size, modified)
+				}
+			}) == nil
+		}
+	}
+	if !fromMFT {
+		files = nil
+		err := scan.Walk(ctx, s.path, walk, func(p string, info fs.FileInfo) {
+			if !onlineOnly(info) {
+				add(filepath.Dir(p), info.Name(), info.Size(), info.ModTime())
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lib := &mediaLibrary{items: make([]mediaItem, len(files))}
+	eachMedia(ctx, len(files), func(i int) {
+		lib.items[i], _ = readMediaItem(files[i])
+		s.read.Add(1)
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	lib.findSets(ctx)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	lib.findVideos()
+	lib.group()
+	return lib, nil
+}
+
+// eachMedia calls fn for 0..n-1 with mediaWorkers at a time.
+func eachMedia(ctx context.Context, n int, fn func(i int)) {
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range mediaWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := range n {
+		if ctx.Err() != nil {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// findSets finds the bursts and exports. Only the photos that could be
+// one are decoded for their hash.
+func (lib *mediaLibrary) findSets(ctx context.Context) {
+	var photos []int
+	for i := range lib.items {
+		if it := &lib.items[i]; !it.video && it.exifTime {
+			photos = append(photos, i)
+		}
+	}
+
+	// Bursts: runs of shots close in time, in one folder and from one
+	// camera, at one resolution.
+	sort.Slice(photos, func(a, b int) bool {
+		x, y := &lib.items[photos[a]], &lib.items[photos[b]]
+		if dx, dy := filepath.Dir(x.entry.Path), filepath.Dir(y.entry.Path); dx != dy {
+			return dx < dy
+		}
+		if x.camera != y.camera {
+			return x.camera < y.camera
+		}
+		return x.taken.Before(y.taken)
+	})
+	var runs [][]int
+	for i := 0; i < len(photos); {
+		j := i + 1
+		for j < len(photos) && burstNext(&lib.items[photos[j-1]], &lib.items[photos[j]]) {
+			j++
+		}
+		if j-i > 1 {
+			runs = append(runs, photos[i:j])
+		}
+		i = j
+	}
+
+	// Exports: one camera and capture time at several resolutions.
+	byShot := make(map[string][]int)
+	for _, i := range photos {
+		it := &lib.items[i]
+		key := it.camera + "|" + it.taken.Format(time.DateTime)
+		byShot[key] = append(byShot[key], i)
+	}
+	var shots [][]int
+	for _, g := range byShot {
+		if len(g) > 1 && !sameSize(lib.items, g) {
+			shots = append(shots, g)
+		}
+	}
+
+	var hash []int
+	for _, g := range append(runs, shots...) {
+		hash = append(hash, g...)
+	}
+	eachMedia(ctx, len(hash), func(i int) {
+		it := &lib.items[hash[i]]
+		if img, err := mediaImage(it); err == nil {
+			it.hash, it.hashed = dHash(img), true
+		}
+	})
+
+	for _, g := range runs {
+		lib.bursts = append(lib.bursts, lib.cluster(g, func(a, b *mediaItem) bool {
+			return a.entry.Size > b.entry.Size
+		})...)
+	}
+	for _, g := range shots {
+		for _, set := range lib.cluster(g, func(a, b *mediaItem) bool {
+			if a.pixels() != b.pixels() {
+				return a.pixels() > b.pixels()
+			}
+			return a.entry.Size > b.entry.Size
+		}) {
+			// A set at one resolution is a copy, which u finds.
+			if !sameSize(lib.items, set.items) {
+				lib.exports = append(lib.exports, set)
+			}
+		}
+	}
+	lib.sortSets()
+}
+
+// burstNext reports whether b was shot in the same burst as a, the shot
+// before it.
+func burstNext(a, b *mediaItem) bool {
+	return filepath.Dir(a.entry.Path) == filepath.Dir(b.entry.Path) &&
+		a.camera == b.camera && a.width == b.width && a.height == b.height &&
+		b.taken.Sub(a.taken) <= burstGap
+}
+
+// sameSize reports whether the items all have one resolution.
+func sameSize(items []mediaItem, g []int) bool {
+	for _, i := range g[1:] {
+		if items[i].width != items[g[0]].width || items[i].height != items[g[0]].height {
+			return false
+		}
+	}
+	return true
+}
+
+// cluster splits g into sets of pictures that look alike, each ordered
+// with the one better says to keep first.
+func (lib *mediaLibrary) cluster(g []int, better func(a, b *mediaItem) bool) []mediaSet {
+	var sets []mediaSet
+	used := make(map[int]bool)
+	for _, i := range g {
+		if used[i] || !lib.items[i].hashed {
+			continue
+		}
+		set := mediaSet{items: []int{i}}
+		for _, j := range g {
+			if j != i && !used[j] && nearlySame(&lib.items[i], &lib.items[j]) {
+				set.items = append(set.items, j)
+				used[j] = true
+			}
+		}
+		used[i] = true
+		if len(set.items) > 1 {
+			sort.SliceStable(set.items, func(a, b int) bool {
+				return better(&lib.items[set.items[a]], &lib.items[set.items[b]])
+			})
+			sets = append(sets, set)
+		}
+	}
+	return sets
+}
+
+// extra is the space the set's spares take.
+func (lib *mediaLibrary) extra(s mediaSet) int64 {
+	var size int64
+	for _, i := range s.items[1:] {
+		size += lib.items[i].entry.Size
+	}
+	return size
+}
+
+func (lib *mediaLibrary) sortSets() {
+	for _, sets := range [][]mediaSet{lib.bursts, lib.exports} {
+		sort.Slice(sets, func(i, j int) bool { return lib.extra(sets[i]) > lib.extra(sets[j]) })
+	}
+}
+
+// modernCodecs are the video codecs not worth re-encoding.
+var modernCodecs = map[string]bool{"hvc1": true, "hev1": true, "dvh1": true, "dvhe": true, "av01": true, "vp09": true}
+
+// hevcBitRate is a typical HEVC bit rate, in bits per second, for a frame
+// of the given height.
+func hevcBitRate(height int) int64 {
+	switch {
+	case height >= 2160:
+		return 20_000_000
+	case height >= 1440:
+		return 10_000_000
+	case height >= 1080:
+		return 6_000_000
+	case height >= 720:
+		return 3_500_000
+	default:
+		return 1_500_000
+	}
+}
+
+// findVideos estimates what re-encoding each older video would save; the
+// ones that would shrink by less than a fifth are left out.
+func (lib *mediaLibrary) findVideos() {
+	for i := range lib.items {
+		it := &lib.items[i]
+		if !it.video || it.codec == "" || modernCodecs[it.codec] || it.duration <= 0 || it.height == 0 {
+			continue
+		}
+		target := int64(it.duration.Seconds() * float64(hevcBitRate(it.height)) / 8)
+		if target*5 < it.entry.Size*4 {
+			lib.videos = append(lib.videos, videoSaving{item: i, target: target})
+		}
+	}
+	sort.Slice(lib.videos, func(a, b int) bool {
+		return lib.saving(lib.videos[a]) > lib.saving(lib.videos[b])
+	})
+}
+
+func (lib *mediaLibrary) saving(v videoSaving) int64 {
+	return lib.items[v.item].entry.Size - v.target
+}
+
+// group totals the library by camera and by month taken.
+func (lib *mediaLibrary) group() {
+	cameras := make(map[string]*mediaGroup)
+	months := make(map[string]*mediaGroup)
+	for i := range lib.items {
+		it := &lib.items[i]
+		if it.removed {
+			continue
+		}
+		camera := it.camera
+		if camera == "" {
+			camera = "(unknown)"
+		}
+		for _, g := range []*mediaGroup{groupOf(cameras, camera), groupOf(months, it.taken.Format("2006-01"))} {
+			if it.video {
+				g.videos++
+			} else {
+				g.photos++
+			}
+			g.size += it.entry.Size
+		}
+	}
+	lib.cameras, lib.months = lib.cameras[:0], lib.months[:0]
+	for _, g := range cameras {
+		lib.cameras = append(lib.cameras, *g)
+	}
+	for _, g := range months {
+		lib.months = append(lib.months, *g)
+	}
+	sort.Slice(lib.cameras, func(i, j int) bool { return lib.cameras[i].size > lib.cameras[j].size })
+	sort.Slice(lib.months, func(i, j int) bool { return lib.months[i].name > lib.months[j].name })
+}
+
+func groupOf(groups map[string]*mediaGroup, name string) *mediaGroup {
+	g, ok := groups[name]
+	if !ok {
+		g = &mediaGroup{name: name}
+		groups[name] = g
+	}
+	return g
+}
+
+// without drops the files a batch removed from the library.
+func (lib *mediaLibrary) without(done []Entry) {
+	gone := make(map[string]bool, len(done))
+	for _, e := range done {
+		gone[cacheKey(e.Path)] = true
+	}
+	// Sets and videos point into items by index, so removed items keep
+	// their places and are only marked.
+	for i := range lib.items {
+		if gone[cacheKey(lib.items[i].entry.Path)] {
+			lib.items[i].removed = true
+		}
+	}
+	prune := func(sets []mediaSet) []mediaSet {
+		kept := sets[:0]
+		for _, s := range sets {
+			items := s.items[:0]
+			for _, i := range s.items {
+				if !lib.items[i].removed {
+					items = append(items, i)
+				}
+			}
+			if s.items = items; len(items) > 1 {
+				kept = append(kept, s)
+			}
+		}
+		return kept
+	}
+	lib.bursts, lib.exports = prune(lib.bursts), prune(lib.exports)
+	videos := lib.videos[:0]
+	for _, v := range lib.videos {
+		if !lib.items[v.item].removed {
+			videos = append(videos, v)
+		}
+	}
+	lib.videos = videos
+	lib.group()
+}
+
+func (s *mediaSearch) status(frame string) string {
+	files, read := s.files.Load(), s.read.Load()
+	if read == 0 {
+		return fmt.Sprintf("%s Looking for photos and videos... %d found", frame, files)
+	}
+	return fmt.Sprintf("%s Reading photos and videos... %d of %d", frame, read, files)
+}
+
+// startMediaSearch reads the library below the current folder.
+func (m model) startMediaSearch() (tea.Model, tea.Cmd) {
+	if m.imported != "" {
+		m.status = "Photos and videos are not part of imported reports"
+		return m, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &mediaSearch{path: m.path, cancel: cancel}
+	m.mediaSearch = s
+	m.status = s.status(spinnerFrames[m.spinner])
+	return m, tea.Batch(m.mediaCmd(ctx, s), tickCmd())
+}
+
+// handleMediaSearchKey stops the search on Esc.
+func (m model) handleMediaSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		m.mediaSearch.cancel()
+		return m, tea.Quit
+	case "esc":
+		m.mediaSearch.cancel()
+	}
+	return m, nil
+}
+
+func (m model) applyMedia(msg mediaMsg) model {
+	if m.mediaSearch == nil || m.mediaSearch.path != msg.path {
+		return m
+	}
+	m.mediaSearch.cancel()
+	m.mediaSearch = nil
+	switch {
+	case errors.Is(msg.err, context.Canceled):
+		m.status = "Media search stopped"
+		return m
+	case msg.err != nil:
+		m.status = fmt.Sprintf("Error: %v", msg.err)
+		return m
+	case len(msg.lib.items) == 0:
+		m.status = "No photos or videos below this folder"
+		return m
+	}
+	usage.Run("analyze.media")
+	m.media = &mediaView{lib: msg.lib, chosen: make(map[string]bool), previews: make(map[string]string)}
+	m.status = m.media.summary()
+	return m
+}
+
+// summary totals the page shown and what is selected.
+func (v *mediaView) summary() string {
+	lib := v.lib
+	var line string
+	switch v.tab {
+	case tabOverview:
+		var photos, videos int
+		var size int64
+		for _, g := range lib.cameras {
+			photos, videos, size = photos+g.photos, videos+g.videos, size+g.size
+		}
+		line = fmt.Sprintf("%d photos and %d videos • %s • %d cameras", photos, videos, humanize.Bytes(size), len(lib.cameras))
+	case tabBursts, tabExports:
+		sets, what := lib.bursts, "bursts"
+		if v.tab == tabExports {
+			sets, what = lib.exports, "pictures exported more than once"
+		}
+		var extra int64
+		for _, s := range sets {
+			extra += lib.extra(s)
+		}
+		line = fmt.Sprintf("%d %s • %s in spares", len(sets), what, humanize.Bytes(extra))
+	case tabVideos:
+		var saved int64
+		for _, s := range lib.videos {
+			saved += lib.saving(s)
+		}
+		line = fmt.Sprintf("%d videos in older codecs • about %s smaller as HEVC", len(lib.videos), humanize.Bytes(saved))
+	}
+	if files := v.chosenFiles(); len(files) > 0 {
+		var size int64
+		for _, e := range files {
+			size += e.Size
+		}
+		line += fmt.Sprintf(" • %s selected (%s)", itemCount(len(files)), humanize.Bytes(size))
+	}
+	return line
+}
+
+// sets returns the sets of the page shown, if it lists sets.
+func (v *mediaView) sets() []mediaSet {
+	switch v.tab {
+	case tabBursts:
+		return v.lib.bursts
+	case tabExports:
+		return v.lib.exports
+	}
+	return nil
+}
+
+// rows lists the lines of the page that can be selected or scrolled.
+func (v *mediaView) rows() []mediaRow {
+	var rows []mediaRow
+	switch v.tab {
+	case tabOverview:
+		for i := range len(v.lib.cameras) + len(v.lib.months) + 2 {
+			rows = append(rows, mediaRow{-1, i})
+		}
+	case tabBursts, tabExports:
+		for i, s := range v.sets() {
+			rows = append(rows, mediaRow{i, -1})
+			for _, item := range s.items {
+				rows = append(rows, mediaRow{i, item})
+			}
+		}
+	case tabVideos:
+		for _, s := range v.lib.videos {
+			rows = append(rows, mediaRow{-1, s.item})
+		}
+	}
+	return rows
+}
+
+// current returns the item of the selected row, if it has one.
+func (v *mediaView) current() *mediaItem {
+	rows := v.rows()
+	if v.tab == tabOverview || v.selected >= len(rows) || rows[v.selected].item < 0 {
+		return nil
+	}
+	return &v.lib.items[rows[v.selected].item]
+}
+
+// chosenFiles are the selected spares, in set order.
+func (v *mediaView) chosenFiles() []Entry {
+	var files []Entry
+	for _, sets := range [][]mediaSet{v.lib.bursts, v.lib.exports} {
+		for _, s := range sets {
+			for _, i := range s.items {
+				if e := v.lib.items[i].entry; v.chosen[cacheKey(e.Path)] {
+					files = append(files, e)
+				}
+			}
+		}
+	}
+	return files
+}
+
+// toggle selects or unselects the item in s, keeping one of s unselected.
+func (v *mediaView) toggle(s mediaSet, item int) bool {
+	key := cacheKey(v.lib.items[item].entry.Path)
+	if v.chosen[key] {
+		delete(v.chosen, key)
+		return true
+	}
+	left := 0
+	for _, i := range s.items {
+		if !v.chosen[cacheKey(v.lib.items[i].entry.Path)] {
+			left++
+		}
+	}
+	if left <= 1 {
+		return false
+	}
+	v.chosen[key] = true
+	return true
+}
+
+// firstRow selects the first selectable row of the page.
+func (v *mediaView) firstRow() {
+	v.selected, v.offset = 0, 0
+	if rows := v.rows(); len(rows) > 1 && rows[0].item < 0 && v.tab != tabOverview {
+		v.selected = 1
+	}
+}
+
+type previewMsg struct {
+	path    string
+	preview string
+}
+
+// previewCmd renders the preview of the selected photo once.
+func (m model) previewCmd() tea.Cmd {
+	v := m.media
+	it := v.current()
+	if it == nil || m.width < 100 {
+		return nil
+	}
+	if _, ok := v.previews[it.entry.Path]; ok {
+		return nil
+	}
+	item := *it
+	height := max(m.viewportHeight()-8, 4)
+	return func() tea.Msg {
+		img, err := mediaImage(&item)
+		if err != nil {
+			return previewMsg{path: item.entry.Path}
+		}
+		return previewMsg{path: item.entry.Path, preview: renderPreview(img, mediaPaneWidth-2, height)}
+	}
+}
+
+// handleMediaKey handles keys while the library is shown.
+func (m model) handleMediaKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.media
+	rows := v.rows()
+	var row mediaRow
+	if v.selected < len(rows) {
+		row = rows[v.selected]
+	}
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "v", "esc", "q":
+		m.media = nil
+		m.status = m.totalStatus()
+		return m, nil
+	case "tab", "shift+tab":
+		step := 1
+		if msg.String() == "shift+tab" {
+			step = len(mediaTabs) - 1
+		}
+		v.tab = (v.tab + step) % len(mediaTabs)
+		v.firstRow()
+	case "up", "k":
+		for i := v.selected - 1; i >= 0; i-- {
+			if rows[i].item >= 0 {
+				v.selected = i
+				break
+			}
+		}
+		// Show a set's header above its first file.
+		v.offset = min(v.offset, max(v.selected-1, 0))
+	case "down", "j":
+		for i := v.selected + 1; i < len(rows); i++ {
+			if rows[i].item >= 0 {
+				v.selected = i
+				break
+			}
+		}
+		if h := m.viewportHeight(); v.selected >= v.offset+h {
+			v.offset = v.selected - h + 1
+		}
+	case " ":
+		if sets := v.sets(); sets != nil && row.item >= 0 && !v.toggle(sets[row.set], row.item) {
+			m.status = "One picture of each set stays; unselect another first"
+			return m, nil
+		}
+	case "A":
+		// Select the spares of every set on the page.
+		for _, s := range v.sets() {
+			for n, i := range s.items {
+				if key := cacheKey(v.lib.items[i].entry.Path); n > 0 {
+					v.chosen[key] = true
+				} else {
+					delete(v.chosen, key)
+				}
+			}
+		}
+	case "d":
+		files := v.chosenFiles()
+		if len(files) == 0 {
+			m.status = "Space selects the spares to recycle on the Bursts and Exports pages"
+			return m, nil
+		}
+		return m.confirmBatch(batchRecycle, files), nil
+	case "y":
+		if it := v.current(); it != nil {
+			m = m.copyPath(it.entry)
+		}
+		return m, nil
+	case "o", "O":
+		if it := v.current(); it != nil {
+			m = m.openAction(it.entry, msg.String() == "O")
+		}
+		return m, nil
+	case "enter", "right", "l":
+		it := v.current()
+		if it == nil {
+			break
+		}
+		traceAction("jump", it.entry.Path, m.redactor)
+		m.media = nil
+		m.history = append(m.history, historyEntry{
+			Path:     m.path,
+			Selected: m.selected,
+			Offset:   m.offset,
+		})
+		m.path = filepath.Dir(it.entry.Path)
+		m.selected, m.offset = 0, 0
+		m.focus = it.entry.Path
+		return m.load()
+	}
+	m.status = v.summary()
+	return m, m.previewCmd()
+}
+
+// renderMedia draws the page shown, with the detail pane beside it when
+// the window is wide enough.
+func (m model) renderMedia() string {
+	v := m.media
+	var tabs []string
+	for i, name := range mediaTabs {
+		if i == v.tab {
+			tabs = append(tabs, selectedStyle.Render(" "+name+" "))
+		} else {
+			tabs = append(tabs, dimStyle.Render(" "+name+" "))
+		}
+	}
+	list := m.renderMediaPage()
+	if m.width >= 100 && v.tab != tabOverview {
+		list = lipgloss.JoinHorizontal(lipgloss.Top,
+			lipgloss.NewStyle().Width(m.width-mediaPaneWidth-2).MaxWidth(m.width-mediaPaneWidth-2).Render(list),
+			m.renderMediaPane())
+	}
+	return strings.Join(tabs, " ") + "\n" + list
+}
+
+func (m model) renderMediaPage() string {
+	v := m.media
+	lib := v.lib
+	rows := v.rows()
+	if len(rows) == 0 {
+		return dimStyle.Render("  (nothing on this page)") + "\n"
+	}
+	var b strings.Builder
+	end := min(v.offset+m.viewportHeight()-1, len(rows))
+	for i := v.offset; i < end; i++ {
+		r := rows[i]
+		switch v.tab {
+		case tabOverview:
+			b.WriteString(m.overviewLine(r.item))
+		case tabBursts, tabExports:
+			s := v.sets()[r.set]
+			if r.item < 0 {
+				first := &lib.items[s.items[0]]
+				b.WriteString(sizeStyle.Render(fmt.Sprintf("%10s", humanize.Bytes(lib.extra(s)))))
+				b.WriteString(dimStyle.Render(fmt.Sprintf("  %d pictures • %s • %s", len(s.items), first.camera, first.taken.Format("2006-01-02 15:04:05"))))
+				break
+			}
+			it := &lib.items[r.item]
+			check := " "
+			if v.chosen[cacheKey(it.entry.Path)] {
+				check = "✓"
+			}
+			line := fmt.Sprintf("  %8s %s %9s %s", humanize.Bytes(it.entry.Size), check, mediaResolution(it), m.relPath(it.entry.Path))
+			switch {
+			case i == v.selected:
+				b.WriteString(selectedStyle.Render(line))
+			case check != " ":
+				b.WriteString(warnStyle.Render(line))
+			default:
+				b.WriteString(normalStyle.Render(line))
+			}
+		case tabVideos:
+			it := &lib.items[r.item]
+			line := fmt.Sprintf("%9s %s %9s %s %8s %s", "-"+humanize.Bytes(it.entry.Size-v.videoTarget(r.item)),
+				it.codec, mediaResolution(it), formatDuration(it.duration), humanize.Bytes(it.entry.Size), m.relPath(it.entry.Path))
+			if i == v.selected {
+				b.WriteString(selectedStyle.Render(line))
+			} else {
+				b.WriteString(normalStyle.Render(line))
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// overviewLine is line n of the overview: the cameras, then the months.
+func (m model) overviewLine(n int) string {
+	lib := m.media.lib
+	group := func(g mediaGroup) string {
+		return fmt.Sprintf("  %10s  %-28s %6d photos %5d videos", humanize.Bytes(g.size), g.name, g.photos, g.videos)
+	}
+	switch {
+	case n == 0:
+		return titleStyle.Render("By camera")
+	case n <= len(lib.cameras):
+		return normalStyle.Render(group(lib.cameras[n-1]))
+	case n == len(lib.cameras)+1:
+		return titleStyle.Render("By month taken")
+	default:
+		return normalStyle.Render(group(lib.months[n-len(lib.cameras)-2]))
+	}
+}
+
+// videoTarget is the estimated re-encoded size of item.
+func (v *mediaView) videoTarget(item int) int64 {
+	for _, s := range v.lib.videos {
+		if s.item == item {
+			return s.target
+		}
+	}
+	return v.lib.items[item].entry.Size
+}
+
+// relPath shows path relative to the current folder.
+func (m model) relPath(path string) string {
+	if rel, err := filepath.Rel(m.path, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+func mediaResolution(it *mediaItem) string {
+	if it.width == 0 {
+		return "?"
+	}
+	return fmt.Sprintf("%dx%d", it.width, it.height)
+}
+
+// formatDuration shows a video's length as 1:02:03 or 2:03.
+func formatDuration(d time.Duration) string {
+	s := int(d.Round(time.Second).Seconds())
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// renderMediaPane shows what the selected file's headers say, and its
+// preview once rendered.
+func (m model) renderMediaPane() string {
+	v := m.media
+	it := v.current()
+	var b strings.Builder
+	if it == nil {
+		return ""
+	}
+	field := func(name, value string) {
+		if value != "" {
+			b.WriteString(dimStyle.Render(fmt.Sprintf("%-9s", name)) + value + "\n")
+		}
+	}
+	field("Name", it.entry.Name)
+	field("Size", humanize.Bytes(it.entry.Size))
+	field("Camera", it.camera)
+	if it.exifTime {
+		field("Taken", it.taken.Format("2006-01-02 15:04:05"))
+	} else {
+		field("Modified", it.taken.Format("2006-01-02 15:04:05"))
+	}
+	if it.width > 0 {
+		field("Pixels", mediaResolution(it))
+	}
+	if it.video {
+		field("Codec", it.codec)
+		if it.duration > 0 {
+			field("Length", formatDuration(it.duration))
+			field("Bit rate", fmt.Sprintf("%.1f Mbit/s", float64(it.entry.Size)*8/it.duration.Seconds()/1e6))
+		}
+	}
+	if preview, ok := v.previews[it.entry.Path]; ok && preview != "" {
+		b.WriteString("\n" + preview)
+	} else if !ok && !it.video {
+		b.WriteString("\n" + dimStyle.Render("Loading the preview..."))
+	}
+	return lipgloss.NewStyle().Width(mediaPaneWidth).PaddingLeft(2).Render(b.String())
+}
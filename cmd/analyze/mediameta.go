@@ -0,0 +1,462 @@
+//go:build windows
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png" // previews of PNG files
+	"io"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The media view reads just enough of each photo and video to place it:
+// the camera, capture time and pixel size from a photo's EXIF block, and
+// the duration, frame size and codec from an MP4 or QuickTime file's moov
+// box. Nothing else is decoded, except the small EXIF thumbnail (or the
+// whole image when there is none) of the photos that need comparing or a
+// preview.
+
+var photoExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".heic": true, ".heif": true, ".tif": true, ".tiff": true,
+	".dng": true, ".cr2": true, ".nef": true, ".arw": true, ".orf": true, ".rw2": true,
+}
+
+var videoExtensions = map[string]bool{
+	".mp4": true, ".mov": true, ".m4v": true, ".3gp": true, ".avi": true, ".mts": true, ".m2ts": true, ".mkv": true, ".wmv": true,
+}
+
+// mediaHeadSize is how much of a photo is read for its EXIF block.
+const mediaHeadSize = 256 << 10
+
+// mediaItem is one photo or video and what its headers say.
+type mediaItem struct {
+	entry    Entry
+	video    bool
+	camera   string    // "Make Model"; "" when unknown
+	taken    time.Time // capture time; the file's ModTime when unknown
+	exifTime bool      // taken came from the file's own metadata
+	width    int
+	height   int
+	duration time.Duration
+	codec    string // four-character code of the video track, e.g. "avc1"
+	thumbOff int64  // the EXIF thumbnail, if any
+	thumbLen int64
+	hash     uint64 // difference hash, once hashed is set
+	hashed   bool
+	removed  bool // recycled since the search
+}
+
+// pixels is the photo's or video frame's area.
+func (it *mediaItem) pixels() int {
+	return it.width * it.height
+}
+
+// readMediaItem reads what the headers of the file e say.
+func readMediaItem(e Entry) (mediaItem, error) {
+	it := mediaItem{entry: e, taken: e.ModTime}
+	ext := strings.ToLower(filepath.Ext(e.Name))
+	it.video = videoExtensions[ext]
+	f, err := os.Open(e.Path)
+	if err != nil {
+		return it, err
+	}
+	defer f.Close()
+	if it.video {
+		return it, readMovieInfo(f, e.Size, &it)
+	}
+	head := make([]byte, min(e.Size, mediaHeadSize))
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return it, err
+	}
+	head = head[:n]
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8}):
+		readJPEGInfo(head, &it)
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")) && len(head) >= 24:
+		it.width = int(binary.BigEndian.Uint32(head[16:]))
+		it.height = int(binary.BigEndian.Uint32(head[20:]))
+	case bytes.HasPrefix(head, []byte("II*\x00")), bytes.HasPrefix(head, []byte("MM\x00*")):
+		// TIFF and most camera raw formats are a TIFF file.
+		readEXIF(head, 0, &it)
+	}
+	return it, nil
+}
+
+// readJPEGInfo reads the frame size and EXIF block of a JPEG.
+func readJPEGInfo(b []byte, it *mediaItem) {
+	for i := 2; i+4 <= len(b); {
+		if b[i] != 0xFF {
+			return
+		}
+		marker := b[i+1]
+		if marker == 0xD8 || marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			i += 2
+			continue
+		}
+		size := int(binary.BigEndian.Uint16(b[i+2:]))
+		seg := b[i+4 : min(i+2+size, len(b))]
+		switch {
+		case marker == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")):
+			readEXIF(seg[6:], int64(i+4+6), it)
+		case marker >= 0xC0 && marker <= 0xCF && marker != 0xC4 && marker != 0xC8 && marker != 0xCC && len(seg) >= 5:
+			// A start-of-frame marker: the real size, whatever EXIF says.
+			it.height = int(binary.BigEndian.Uint16(seg[1:]))
+			it.width = int(binary.BigEndian.Uint16(seg[3:]))
+			return
+		case marker == 0xDA:
+			return // the image data
+		}
+		i += 2 + size
+	}
+}
+
+// EXIF tags the view uses.
+const (
+	tagMake          = 0x010F
+	tagModel         = 0x0110
+	tagExifIFD       = 0x8769
+	tagDateTimeOrig  = 0x9003
+	tagPixelX        = 0xA002
+	tagPixelY        = 0xA003
+	tagThumbOffset   = 0x0201
+	tagThumbLength   = 0x0202
+	exifTimeLayout   = "2006:01:02 15:04:05"
+	maxEXIFEntries   = 512
+	tiffHeaderLength = 8
+)
+
+// readEXIF reads the camera, capture time, pixel size and thumbnail from a
+// TIFF structure t, which starts at base in the file.
+func readEXIF(t []byte, base int64, it *mediaItem) {
+	if len(t) < tiffHeaderLength {
+		return
+	}
+	var order binary.ByteOrder
+	switch string(t[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return
+	}
+	var maker, model string
+	// ifd calls fn with each entry's tag, type, count and value field.
+	ifd := func(off uint32, fn func(tag, typ uint16, count uint32, value []byte)) uint32 {
+		if off == 0 || int(off)+2 > len(t) {
+			return 0
+		}
+		n := int(order.Uint16(t[off:]))
+		p := int(off) + 2
+		for range min(n, maxEXIFEntries) {
+			if p+12 > len(t) {
+				return 0
+			}
+			fn(order.Uint16(t[p:]), order.Uint16(t[p+2:]), order.Uint32(t[p+4:]), t[p+8:p+12])
+			p += 12
+		}
+		if p+4 > len(t) {
+			return 0
+		}
+		return order.Uint32(t[p:])
+	}
+	ascii := func(count uint32, value []byte) string {
+		s := value
+		if count > 4 {
+			off := order.Uint32(value)
+			if int64(off)+int64(count) > int64(len(t)) {
+				return ""
+			}
+			s = t[off : off+count]
+		} else {
+			s = s[:count]
+		}
+		return strings.TrimSpace(strings.TrimRight(string(s), "\x00"))
+	}
+	number := func(typ uint16, value []byte) int {
+		if typ == 3 {
+			return int(order.Uint16(value))
+		}
+		return int(order.Uint32(value))
+	}
+
+	var exif uint32
+	next := ifd(order.Uint32(t[4:]), func(tag, typ uint16, count uint32, value []byte) {
+		switch tag {
+		case tagMake:
+			maker = ascii(count, value)
+		case tagModel:
+			model = ascii(count, value)
+		case tagExifIFD:
+			exif = order.Uint32(value)
+		}
+	})
+	ifd(exif, func(tag, typ uint16, count uint32, value []byte) {
+		switch tag {
+		case tagDateTimeOrig:
+			if taken, err := time.ParseInLocation(exifTimeLayout, ascii(count, value), time.Local); err == nil {
+				it.taken, it.exifTime = taken, true
+			}
+		case tagPixelX:
+			it.width = number(typ, value)
+		case tagPixelY:
+			it.height = number(typ, value)
+		}
+	})
+	var thumbOff, thumbLen uint32
+	ifd(next, func(tag, typ uint16, count uint32, value []byte) {
+		switch tag {
+		case tagThumbOffset:
+			thumbOff = order.Uint32(value)
+		case tagThumbLength:
+			thumbLen = order.Uint32(value)
+		}
+	})
+	if thumbOff > 0 && thumbLen > 0 {
+		it.thumbOff, it.thumbLen = base+int64(thumbOff), int64(thumbLen)
+	}
+
+	// Many makers repeat their name in the model: "Canon" "Canon EOS R6".
+	switch {
+	case model == "":
+		it.camera = maker
+	case maker == "" || strings.HasPrefix(strings.ToLower(model), strings.ToLower(maker)):
+		it.camera = model
+	default:
+		it.camera = maker + " " + model
+	}
+}
+
+// movieEpoch is where QuickTime times count from.
+var movieEpoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// maxMoovSize bounds the moov box read into memory.
+const maxMoovSize = 64 << 20
+
+// readMovieInfo reads the duration, capture time, frame size and codec of
+// an MP4 or QuickTime file. Other containers are listed by size only.
+func readMovieInfo(f *os.File, size int64, it *mediaItem) error {
+	for off := int64(0); off+8 <= size; {
+		var hdr [16]byte
+		if _, err := f.ReadAt(hdr[:8], off); err != nil {
+			return err
+		}
+		boxSize, head := int64(binary.BigEndian.Uint32(hdr[:])), int64(8)
+		switch boxSize {
+		case 0:
+			boxSize = size - off
+		case 1:
+			if _, err := f.ReadAt(hdr[8:], off+8); err != nil {
+				return err
+			}
+			boxSize, head = int64(binary.BigEndian.Uint64(hdr[8:])), 16
+		}
+		if boxSize < head {
+			return nil // not an MP4 file
+		}
+		if string(hdr[4:8]) == "moov" {
+			if boxSize > maxMoovSize {
+				return fmt.Errorf("moov box of %d bytes", boxSize)
+			}
+			moov := make([]byte, boxSize-head)
+			if _, err := f.ReadAt(moov, off+head); err != nil {
+				return err
+			}
+			readMoov(moov, it)
+			return nil
+		}
+		off += boxSize
+	}
+	return nil
+}
+
+// eachBox calls fn with the type and contents of each box in b.
+func eachBox(b []byte, fn func(typ string, body []byte)) {
+	for len(b) >= 8 {
+		size, head := uint64(binary.BigEndian.Uint32(b)), uint64(8)
+		if size == 1 && len(b) >= 16 {
+			size, head = binary.BigEndian.Uint64(b[8:]), 16
+		} else if size == 0 {
+			size = uint64(len(b))
+		}
+		if size < head || size > uint64(len(b)) {
+			return
+		}
+		fn(string(b[4:8]), b[head:size])
+		b = b[size:]
+	}
+}
+
+// readMoov reads the movie header and the video track.
+func readMoov(moov []byte, it *mediaItem) {
+	eachBox(moov, func(typ string, body []byte) {
+		switch typ {
+		case "mvhd":
+			var created, scale, duration uint64
+			switch {
+			case len(body) >= 20 && body[0] == 0:
+				created = uint64(binary.BigEndian.Uint32(body[4:]))
+				scale = uint64(binary.BigEndian.Uint32(body[12:]))
+				duration = uint64(binary.BigEndian.Uint32(body[16:]))
+			case len(body) >= 32 && body[0] == 1:
+				created = binary.BigEndian.Uint64(body[4:])
+				scale = uint64(binary.BigEndian.Uint32(body[20:]))
+				duration = binary.BigEndian.Uint64(body[24:])
+			}
+			if scale > 0 {
+				it.duration = time.Duration(float64(duration) / float64(scale) * float64(time.Second))
+			}
+			if created > 0 {
+				it.taken, it.exifTime = movieEpoch.Add(time.Duration(created)*time.Second).Local(), true
+			}
+		case "trak":
+			readTrack(body, it)
+		}
+	})
+}
+
+// readTrack fills in the frame size and codec from a video track.
+func readTrack(trak []byte, it *mediaItem) {
+	var width, height int
+	var codec string
+	video := false
+	eachBox(trak, func(typ string, body []byte) {
+		switch typ {
+		case "tkhd":
+			// Width and height end the box, as 16.16 fixed point.
+			if len(body) >= 80 {
+				width = int(binary.BigEndian.Uint32(body[len(body)-8:]) >> 16)
+				height = int(binary.BigEndian.Uint32(body[len(body)-4:]) >> 16)
+			}
+		case "mdia":
+			eachBox(body, func(typ string, body []byte) {
+				switch typ {
+				case "hdlr":
+					video = len(body) >= 12 && string(body[8:12]) == "vide"
+				case "minf":
+					eachBox(body, func(typ string, body []byte) {
+						if typ != "stbl" {
+							return
+						}
+						eachBox(body, func(typ string, body []byte) {
+							if typ == "stsd" && len(body) >= 16 {
+								codec = string(body[12:16])
+							}
+						})
+					})
+				}
+			})
+		}
+	})
+	if video && it.codec == "" {
+		it.width, it.height, it.codec = width, height, codec
+	}
+}
+
+// mediaImage decodes the item's EXIF thumbnail or, failing that, the image
+// itself when it is a JPEG or PNG small enough to decode.
+func mediaImage(it *mediaItem) (image.Image, error) {
+	f, err := os.Open(it.entry.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if it.thumbLen > 0 {
+		if img, err := jpeg.Decode(io.NewSectionReader(f, it.thumbOff, it.thumbLen)); err == nil {
+			return img, nil
+		}
+	}
+	ext := strings.ToLower(filepath.Ext(it.entry.Name))
+	if it.video || (ext != ".jpg" && ext != ".jpeg" && ext != ".png") {
+		return nil, errors.New("no preview for this format")
+	}
+	if it.entry.Size > 64<<20 {
+		return nil, errors.New("too large to preview")
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+// dHash is a difference hash of img: each bit says whether a cell of a
+// 9x8 grid is brighter than the next one along its row. Near-identical
+// pictures, at whatever resolution, differ in only a few bits.
+func dHash(img image.Image) uint64 {
+	var gray [8][9]uint32
+	r := img.Bounds()
+	for y := range 8 {
+		for x := range 9 {
+			// The mean of a few samples inside the cell.
+			var sum, n uint32
+			for sy := range 3 {
+				for sx := range 3 {
+					px := r.Min.X + (x*3+sx)*r.Dx()/27
+					py := r.Min.Y + (y*3+sy)*r.Dy()/24
+					cr, cg, cb, _ := img.At(px, py).RGBA()
+					sum += (cr*299 + cg*587 + cb*114) / 1000
+					n++
+				}
+			}
+			gray[y][x] = sum / n
+		}
+	}
+	var h uint64
+	for y := range 8 {
+		for x := range 8 {
+			h <<= 1
+			if gray[y][x] > gray[y][x+1] {
+				h |= 1
+			}
+		}
+	}
+	return h
+}
+
+// nearlySame reports whether two hashed pictures look alike.
+func nearlySame(a, b *mediaItem) bool {
+	return a.hashed && b.hashed && bits.OnesCount64(a.hash^b.hash) <= nearHashBits
+}
+
+// renderPreview draws img in width columns with half blocks, two pixels to
+// a cell, keeping its shape within height rows.
+func renderPreview(img image.Image, width, height int) string {
+	r := img.Bounds()
+	if r.Dx() == 0 || r.Dy() == 0 {
+		return ""
+	}
+	// A cell is about twice as tall as it is wide, and holds two pixels.
+	w, h := width, width*r.Dy()/r.Dx()
+	if h > height*2 {
+		w, h = height*2*r.Dx()/r.Dy(), height*2
+	}
+	w, h = max(w, 1), max(h&^1, 2)
+	at := func(x, y int) string {
+		cr, cg, cb, _ := img.At(r.Min.X+x*r.Dx()/w, r.Min.Y+y*r.Dy()/h).RGBA()
+		return fmt.Sprintf("#%02x%02x%02x", cr>>8, cg>>8, cb>>8)
+	}
+	var b strings.Builder
+	for y := 0; y < h; y += 2 {
+		for x := range w {
+			b.WriteString(lipgloss.NewStyle().
+				Foreground(lipgloss.Color(at(x, y))).
+				Background(lipgloss.Color(at(x, y+1))).
+				Render("▀"))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
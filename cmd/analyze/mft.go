@@ -0,0 +1,599 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/winmole/winmole/pkg/scan"
+	"golang.org/x/sys/windows"
+)
+
+// The MFT engine reads an NTFS volume's Master File Table directly, the way
+// WizTree does, instead of walking directories. One sequential read of the
+// table yields every file's name, parent and size, which takes seconds where
+// a walk of C:\ takes minutes. It needs admin rights to open the raw volume;
+// anything else falls back to the directory walker.
+
+// NTFS on-disk constants.
+const (
+	mftRootRecord   = 5         // the volume's root directory
+	mftFirstUser    = 24        // records below this are NTFS metadata
+	mftRefMask      = 1<<48 - 1 // file reference: low 48 bits are the record number
+	mftReadChunk    = 4 << 20   // bytes read from the volume at a time
+	attrStandard    = 0x10
+	attrFileName    = 0x30
+	attrData        = 0x80
+	attrEnd         = 0xFFFFFFFF
+	recordInUse     = 0x01
+	recordDirectory = 0x02
+	attrCompressed  = 0x0001 // attribute flags
+	attrSparse      = 0x8000
+	nameSpaceDOS    = 2
+)
+
+// Attributes of a cloud placeholder whose contents are not all on disk, as
+// scan.Entry.Cloud counts them.
+const placeholderAttributes = windows.FILE_ATTRIBUTE_RECALL_ON_DATA_ACCESS |
+	windows.FILE_ATTRIBUTE_RECALL_ON_OPEN |
+	windows.FILE_ATTRIBUTE_OFFLINE
+
+// mftNode is one file or directory of the volume.
+type mftNode struct {
+	name     string
+	parent   uint32
+	size     int64  // file size, or total size below a directory
+	alloc    int64  // clusters allocated, likewise
+	cloud    int64  // size of cloud placeholders, likewise
+	files    int64  // files below a directory
+	dirs     int64  // directories below a directory
+	modified uint64 // FILETIME of the last change
+	accessed uint64 // FILETIME of the last read, as lazily as NTFS keeps it
+	isDir    bool
+	inUse    bool
+	reparse  bool // a reparse point: link, junction, placeholder...
+	remote   bool // a cloud placeholder not fully downloaded
+	children []uint32
+}
+
+// mftIndex is the parsed table of one volume.
+type mftIndex struct {
+	volume string // "C:"
+	nodes  []mftNode
+}
+
+// mftIndexes caches the parsed table per volume; a nil index records that
+// the volume's MFT could not be read, so the walker is used without retrying.
+var mftIndexes = struct {
+	sync.Mutex
+	byVolume map[string]*mftIndex
+}{byVolume: make(map[string]*mftIndex)}
+
+var errMFTUnavailable = errors.New("MFT unavailable")
+
+// mftVolume returns the volume ("C:") whose MFT can be used to scan path,
+// or "" when the walker has to be used.
+func mftVolume(path string) string {
+	if !windows.GetCurrentProcessToken().IsElevated() {
+		return ""
+	}
+	vol := filepath.VolumeName(path)
+	if len(vol) != 2 || vol[1] != ':' {
+		return "" // UNC paths and the like
+	}
+	root, err := windows.UTF16PtrFromString(vol + `\`)
+	if err != nil {
+		return ""
+	}
+	fs := make([]uint16, windows.MAX_PATH+1)
+	if err := windows.GetVolumeInformation(root, nil, 0, nil, nil, nil, &fs[0], uint32(len(fs))); err != nil {
+		return ""
+	}
+	if windows.UTF16ToString(fs) != "NTFS" {
+		return ""
+	}
+	return strings.ToUpper(vol)
+}
+
+// dropMFTIndex forgets the parsed table of path's volume so the next scan
+// reads it again (after a refresh or a delete).
+func dropMFTIndex(path string) {
+	mftIndexes.Lock()
+	defer mftIndexes.Unlock()
+	delete(mftIndexes.byVolume, strings.ToUpper(filepath.VolumeName(path)))
+}
+
+// scanMFT lists path from the volume's MFT, reading the table on first use.
+func scanMFT(ctx context.Context, vol, path string, progress *scan.Counters) ([]Entry, int64, error) {
+	idx, err := loadMFTIndex(ctx, vol, progress)
+	if err != nil {
+		return nil, 0, err
+	}
+	entries, total, err := idx.list(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	idx.addLargest(path, progress.Largest)
+	return entries, total, nil
+}
+
+// loadMFTIndex returns the parsed table of vol, reading it on first use. A
+// read cut short by ctx is not remembered, so the next scan reads it again.
+func loadMFTIndex(ctx context.Context, vol string, progress *scan.Counters) (*mftIndex, error) {
+	mftIndexes.Lock()
+	idx, ok := mftIndexes.byVolume[vol]
+	mftIndexes.Unlock()
+	if ok && idx == nil {
+		return nil, errMFTUnavailable
+	}
+	if !ok {
+		var err error
+		idx, err = readMFT(ctx, vol, progress)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		mftIndexes.Lock()
+		mftIndexes.byVolume[vol] = idx
+		mftIndexes.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return idx, nil
+}
+
+// lookup returns the record of the directory at path.
+func (idx *mftIndex) lookup(path string) (uint32, error) {
+	rest := strings.Trim(strings.TrimPrefix(filepath.Clean(path), filepath.VolumeName(path)), `\`)
+	dir := uint32(mftRootRecord)
+	if rest != "" {
+		for _, part := range strings.Split(rest, `\`) {
+			next, ok := idx.child(dir, part)
+			if !ok {
+				return 0, fmt.Errorf("%s not found in the MFT", path)
+			}
+			dir = next
+		}
+	}
+	if !idx.nodes[dir].isDir {
+		return 0, fmt.Errorf("%s is not a directory", path)
+	}
+	return dir, nil
+}
+
+// list returns the entries of the directory at path, sorted by size.
+func (idx *mftIndex) list(path string) ([]Entry, int64, error) {
+	dir, err := idx.lookup(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var entries []Entry
+	var total int64
+	for _, c := range idx.nodes[dir].children {
+		n := &idx.nodes[c]
+		e := Entry{
+			Name:  n.name,
+			Path:  filepath.Join(path, n.name),
+			Size:  n.size,
+			Alloc: n.alloc,
+			Cloud: n.cloud,
+			Files: n.files,
+			Dirs:  n.dirs,
+			IsDir: n.isDir,
+		}
+		e.ModTime, e.AccessTime = n.times()
+		if !n.isDir {
+			e.Files = 1
+			if n.remote {
+				e.Cloud = n.size
+			}
+		}
+		if n.reparse {
+			// A junction's record has no children, so its target is not
+			// counted twice; only the target needs reading.
+			e.Target = scan.LinkTarget(e.Path)
+		}
+		entries = append(entries, e)
+		total += n.size
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Size > entries[j].Size
+	})
+	return entries, total, nil
+}
+
+func (idx *mftIndex) child(dir uint32, name string) (uint32, bool) {
+	for _, c := range idx.nodes[dir].children {
+		if strings.EqualFold(idx.nodes[c].name, name) {
+			return c, true
+		}
+	}
+	return 0, false
+}
+
+// times returns when the node was last modified and read; zero when the
+// record does not say.
+func (n *mftNode) times() (modified, accessed time.Time) {
+	return filetimeOf(n.modified), filetimeOf(n.accessed)
+}
+
+// filetimeOf converts a raw FILETIME, or returns zero for 0.
+func filetimeOf(v uint64) time.Time {
+	if v == 0 {
+		return time.Time{}
+	}
+	ft := windows.Filetime{LowDateTime: uint32(v), HighDateTime: uint32(v >> 32)}
+	return time.Unix(0, ft.Nanoseconds())
+}
+
+// volumeGeometry is what the NTFS boot sector says about the layout.
+type volumeGeometry struct {
+	bytesPerSector uint32
+	clusterSize    int64
+	mftOffset      int64
+	recordSize     uint32
+}
+
+// readMFT parses the whole MFT of vol into an index with directory totals.
+// It gives up with ctx.Err() once ctx is done; a partial table is no use.
+func readMFT(ctx context.Context, vol string, progress *scan.Counters) (*mftIndex, error) {
+	name, err := windows.UTF16PtrFromString(`\\.\` + vol)
+	if err != nil {
+		return nil, err
+	}
+	h, err := windows.CreateFile(name, windows.GENERIC_READ,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil, windows.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open volume %s: %w", vol, err)
+	}
+	f := os.NewFile(uintptr(h), vol)
+	defer f.Close()
+
+	// Volume reads must be sector aligned; 4096 covers 4K-sector drives.
+	boot := make([]byte, 4096)
+	if _, err := f.ReadAt(boot, 0); err != nil {
+		return nil, fmt.Errorf("read boot sector: %w", err)
+	}
+	geo, err := parseBootSector(boot)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record 0 is $MFT itself; its $DATA runs say where the table lives.
+	rec := make([]byte, geo.recordSize)
+	if _, err := f.ReadAt(rec, geo.mftOffset); err != nil {
+		return nil, fmt.Errorf("read $MFT record: %w", err)
+	}
+	if err := applyFixup(rec, geo.bytesPerSector); err != nil {
+		return nil, err
+	}
+	runs, mftSize, err := mftDataRuns(rec, geo.clusterSize)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &mftIndex{volume: vol, nodes: make([]mftNode, mftSize/int64(geo.recordSize))}
+	var recNo uint32
+	buf := make([]byte, mftReadChunk)
+	for _, run := range runs {
+		for off := int64(0); off < run.length; {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			n := min(int64(len(buf)), run.length-off)
+			chunk := buf[:n]
+			if _, err := f.ReadAt(chunk, run.offset+off); err != nil {
+				return nil, fmt.Errorf("read MFT: %w", err)
+			}
+			for i := int64(0); i+int64(geo.recordSize) <= n; i += int64(geo.recordSize) {
+				if int(recNo) >= len(idx.nodes) {
+					break
+				}
+				idx.parseRecord(recNo, chunk[i:i+int64(geo.recordSize)], geo.bytesPerSector)
+				if node := &idx.nodes[recNo]; node.inUse && node.isDir {
+					progress.Dirs.Add(1)
+				} else if node.inUse {
+					progress.Files.Add(1)
+					progress.Bytes.Add(node.size)
+				}
+				recNo++
+			}
+			off += n
+		}
+	}
+
+	idx.link()
+	return idx, nil
+}
+
+func parseBootSector(b []byte) (volumeGeometry, error) {
+	if string(b[3:11]) != "NTFS    " {
+		return volumeGeometry{}, errors.New("not an NTFS boot sector")
+	}
+	g := volumeGeometry{bytesPerSector: uint32(binary.LittleEndian.Uint16(b[0x0B:]))}
+	sectorsPerCluster := int64(b[0x0D])
+	if sectorsPerCluster > 0x80 {
+		// Clusters above 64 KB store the count as a negative power of two.
+		sectorsPerCluster = 1 << (256 - sectorsPerCluster)
+	}
+	g.clusterSize = int64(g.bytesPerSector) * sectorsPerCluster
+	g.mftOffset = int64(binary.LittleEndian.Uint64(b[0x30:])) * g.clusterSize
+	if c := int8(b[0x40]); c > 0 {
+		g.recordSize = uint32(int64(c) * g.clusterSize)
+	} else {
+		g.recordSize = 1 << uint(-c)
+	}
+	if g.bytesPerSector == 0 || g.clusterSize == 0 || g.recordSize < g.bytesPerSector {
+		return volumeGeometry{}, errors.New("unexpected NTFS geometry")
+	}
+	return g, nil
+}
+
+// applyFixup restores the last two bytes of every sector of a record, which
+// NTFS swaps for an update sequence number to detect torn writes.
+func applyFixup(rec []byte, bytesPerSector uint32) error {
+	if string(rec[:4]) != "FILE" {
+		return errors.New("bad MFT record signature")
+	}
+	usaOff := int(binary.LittleEndian.Uint16(rec[4:]))
+	usaCount := int(binary.LittleEndian.Uint16(rec[6:]))
+	if usaOff+2*usaCount > len(rec) || usaCount == 0 {
+		return errors.New("bad MFT update sequence")
+	}
+	usn := rec[usaOff : usaOff+2]
+	for i := 1; i < usaCount; i++ {
+		end := i*int(bytesPerSector) - 2
+		if end+2 > len(rec) {
+			break
+		}
+		if rec[end] != usn[0] || rec[end+1] != usn[1] {
+			return errors.New("torn MFT record")
+		}
+		copy(rec[end:end+2], rec[usaOff+2*i:usaOff+2*i+2])
+	}
+	return nil
+}
+
+// mftRun is a contiguous stretch of the MFT on disk, in bytes.
+type mftRun struct {
+	offset int64
+	length int64
+}
+
+// mftDataRuns decodes the data runs of $MFT's unnamed $DATA attribute.
+func mftDataRuns(rec []byte, clusterSize int64) ([]mftRun, int64, error) {
+	var result []mftRun
+	var found bool
+	var size int64
+	eachAttribute(rec, func(typ uint32, a []byte) {
+		if typ != attrData || found || a[8] == 0 || a[9] != 0 {
+			return
+		}
+		found = true
+		size = int64(binary.LittleEndian.Uint64(a[0x30:]))
+		p := int(binary.LittleEndian.Uint16(a[0x20:]))
+		var lcn int64
+		for p < len(a) && a[p] != 0 {
+			lenBytes, offBytes := int(a[p]&0x0F), int(a[p]>>4)
+			p++
+			if p+lenBytes+offBytes > len(a) {
+				break
+			}
+			length := readUint(a[p : p+lenBytes])
+			p += lenBytes
+			if offBytes == 0 {
+				continue // sparse; does not happen for $MFT
+			}
+			lcn += readInt(a[p : p+offBytes])
+			p += offBytes
+			result = append(result, mftRun{offset: lcn * clusterSize, length: int64(length) * clusterSize})
+		}
+	})
+	if !found {
+		return nil, 0, errors.New("$MFT has no data attribute")
+	}
+	var covered int64
+	for _, r := range result {
+		covered += r.length
+	}
+	if covered < size {
+		// The rest is described by an attribute list, which only happens
+		// on extremely fragmented tables.
+		return nil, 0, errors.New("$MFT is too fragmented to read directly")
+	}
+	return result, size, nil
+}
+
+// parseRecord fills node recNo from a raw MFT record. Extension records
+// (for files with many attributes) add their data size to the base record.
+func (idx *mftIndex) parseRecord(recNo uint32, rec []byte, bytesPerSector uint32) {
+	if string(rec[:4]) != "FILE" {
+		return
+	}
+	flags := binary.LittleEndian.Uint16(rec[0x16:])
+	if flags&recordInUse == 0 {
+		return
+	}
+	if applyFixup(rec, bytesPerSector) != nil {
+		return
+	}
+
+	target := recNo
+	if base := binary.LittleEndian.Uint64(rec[0x20:]) & mftRefMask; base != 0 {
+		if base >= uint64(len(idx.nodes)) {
+			return
+		}
+		target = uint32(base)
+	} else {
+		idx.nodes[recNo].inUse = true
+		idx.nodes[recNo].isDir = flags&recordDirectory != 0
+	}
+	n := &idx.nodes[target]
+
+	eachAttribute(rec, func(typ uint32, a []byte) {
+		switch typ {
+		case attrStandard:
+			if v := residentValue(a); a[8] == 0 && len(v) >= 0x10 {
+				n.modified = binary.LittleEndian.Uint64(v[0x08:])
+				if len(v) >= 0x20 {
+					n.accessed = binary.LittleEndian.Uint64(v[0x18:])
+				}
+				if len(v) >= 0x24 {
+					attrs := binary.LittleEndian.Uint32(v[0x20:])
+					n.reparse = attrs&windows.FILE_ATTRIBUTE_REPARSE_POINT != 0
+					n.remote = attrs&placeholderAttributes != 0
+				}
+			}
+
+		case attrFileName:
+			if a[8] != 0 {
+				return
+			}
+			v := residentValue(a)
+			if len(v) < 0x42 {
+				return
+			}
+			nameLen := int(v[0x40])
+			if v[0x41] == nameSpaceDOS && n.name != "" || 0x42+2*nameLen > len(v) {
+				return
+			}
+			u := make([]uint16, nameLen)
+			for i := range u {
+				u[i] = binary.LittleEndian.Uint16(v[0x42+2*i:])
+			}
+			n.name = string(utf16.Decode(u))
+			n.parent = uint32(binary.LittleEndian.Uint64(v) & mftRefMask)
+
+		case attrData:
+			if a[9] != 0 {
+				return // alternate data stream
+			}
+			if a[8] == 0 {
+				n.size = int64(binary.LittleEndian.Uint32(a[0x10:]))
+			} else if binary.LittleEndian.Uint64(a[0x10:]) == 0 {
+				// Only the first extent (starting VCN 0) carries the size.
+				n.size = int64(binary.LittleEndian.Uint64(a[0x30:]))
+				n.alloc = int64(binary.LittleEndian.Uint64(a[0x28:]))
+				// Compressed and sparse streams also record the clusters
+				// actually in use.
+				if flags := binary.LittleEndian.Uint16(a[0x0C:]); flags&(attrCompressed|attrSparse) != 0 && len(a) >= 0x48 {
+					n.alloc = int64(binary.LittleEndian.Uint64(a[0x40:]))
+				}
+			}
+		}
+	})
+}
+
+// eachAttribute calls fn with the type and bytes of every attribute.
+func eachAttribute(rec []byte, fn func(typ uint32, a []byte)) {
+	p := int(binary.LittleEndian.Uint16(rec[0x14:]))
+	for p+16 <= len(rec) {
+		typ := binary.LittleEndian.Uint32(rec[p:])
+		if typ == attrEnd {
+			return
+		}
+		length := int(binary.LittleEndian.Uint32(rec[p+4:]))
+		if length < 16 || p+length > len(rec) {
+			return
+		}
+		a := rec[p : p+length]
+		// Resident headers are 0x18 bytes, non-resident ones 0x40.
+		if a[8] == 0 && length < 0x18 || a[8] != 0 && length < 0x40 {
+			return
+		}
+		fn(typ, a)
+		p += length
+	}
+}
+
+// residentValue returns the content of a resident attribute.
+func residentValue(a []byte) []byte {
+	if len(a) < 0x18 {
+		return nil
+	}
+	size := int(binary.LittleEndian.Uint32(a[0x10:]))
+	off := int(binary.LittleEndian.Uint16(a[0x14:]))
+	if off+size > len(a) {
+		return nil
+	}
+	return a[off : off+size]
+}
+
+// link builds the directory tree and sums sizes and file and directory
+// counts upwards.
+// NTFS metadata files (records below 24) are left out, as a directory walk
+// would never see them.
+func (idx *mftIndex) link() {
+	nodes := idx.nodes
+	for i := range nodes {
+		n := &nodes[i]
+		if !n.inUse || n.name == "" || i == mftRootRecord {
+			continue
+		}
+		if i < mftFirstUser || int(n.parent) >= len(nodes) || !nodes[n.parent].isDir {
+			n.inUse = false
+			continue
+		}
+		nodes[n.parent].children = append(nodes[n.parent].children, uint32(i))
+	}
+
+	// Add every file and directory to each directory above it. Depth is
+	// bounded to survive a corrupt parent chain.
+	for i := range nodes {
+		n := &nodes[i]
+		if !n.inUse || i < mftFirstUser {
+			continue
+		}
+		p := n.parent
+		for depth := 0; depth < 1024; depth++ {
+			d := &nodes[p]
+			if !d.inUse && p != mftRootRecord {
+				break
+			}
+			if n.isDir {
+				d.dirs++
+			} else {
+				d.size += n.size
+				d.alloc += n.alloc
+				d.files++
+				if n.remote {
+					d.cloud += n.size
+				}
+			}
+			if p == mftRootRecord {
+				break
+			}
+			p = d.parent
+		}
+	}
+}
+
+// readUint and readInt decode the variable-length little-endian numbers
+// of a data run.
+func readUint(b []byte) uint64 {
+	var v uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	return v
+}
+
+func readInt(b []byte) int64 {
+	v := int64(readUint(b))
+	if n := len(b); n > 0 && n < 8 && b[n-1]&0x80 != 0 {
+		v -= 1 << (8 * uint(n))
+	}
+	return v
+}
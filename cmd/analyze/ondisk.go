@@ -0,0 +1,90 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// a switches every size in the list and treemap between the logical size
+// and the space allocated on disk, as Explorer's "Size on disk" shows it.
+// The two disagree for NTFS-compressed and sparse files, cloud placeholders
+// and folders of many small files, and only the allocated size adds up to
+// the volume's free space. The largest-files and file-type views always
+// show logical sizes.
+
+// size is e's size in the current mode: logical or on disk, and with or
+// without further hard links.
+func (m model) size(e Entry) int64 {
+	size, linked := e.Size, e.Linked
+	if m.onDisk {
+		size, linked = e.Alloc, e.LinkedAlloc
+	}
+	if m.dedupe {
+		size -= linked
+	}
+	return max(size, 0)
+}
+
+// total is the current folder's total in the current mode.
+func (m model) total() int64 {
+	if !m.onDisk && !m.dedupe {
+		return m.totalSize
+	}
+	var total int64
+	for _, e := range m.cache[cacheKey(m.path)].entries {
+		total += m.size(e)
+	}
+	return total
+}
+
+// totalStatus is the status line naming the folder's total.
+func (m model) totalStatus() string {
+	status := "Total: " + humanize.Bytes(m.total())
+	if m.onDisk {
+		status += " on disk"
+	}
+	if m.dedupe {
+		status += ", hard links once"
+	}
+	if m.onDisk || m.dedupe {
+		status += fmt.Sprintf(" (%s apparent)", humanize.Bytes(m.totalSize))
+	}
+	if cloud := m.cloudTotal(); cloud > 0 && !m.onDisk {
+		status += fmt.Sprintf(", %s of it online-only", humanize.Bytes(cloud))
+	}
+	if n := m.exclude.count(); n > 0 {
+		status += fmt.Sprintf(" • %d excluded", n)
+	}
+	if m.cache[cacheKey(m.path)].partial {
+		status += " • scan stopped, sizes incomplete (r to rescan)"
+	}
+	return status
+}
+
+// bySize returns entries largest first by the size shown, copied rather
+// than reordered in place.
+func (m model) bySize(entries []Entry) []Entry {
+	sorted := append([]Entry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool { return m.size(sorted[i]) > m.size(sorted[j]) })
+	return sorted
+}
+
+// toggleOnDisk switches between logical sizes and size on disk, keeping the
+// selected entry.
+func (m model) toggleOnDisk() model {
+	if m.imported != "" {
+		m.status = "Size on disk is only known for folders scanned on this machine"
+		return m
+	}
+	m.onDisk = !m.onDisk
+	if len(m.entries) > 0 {
+		m.focus = m.entries[m.selected].Path
+	}
+	m = m.refilter().selectFocus()
+	m.status = m.totalStatus()
+	return m
+}
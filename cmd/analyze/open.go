@@ -0,0 +1,69 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// o opens the selected entry as a double-click in Explorer would: a file
+// with the app associated with it, a folder in a new Explorer window. O
+// opens the folder holding the entry in Explorer with the entry selected.
+// Both work in the list of largest files too.
+
+// openEntry opens path with its associated application.
+func openEntry(path string) error {
+	verb, err := windows.UTF16PtrFromString("open")
+	if err != nil {
+		return err
+	}
+	file, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	return windows.ShellExecute(0, verb, file, nil, nil, windows.SW_SHOWNORMAL)
+}
+
+// revealEntry opens Explorer on the folder holding path, with path
+// selected. Explorer wants the path quoted after /select, which the usual
+// argument quoting would not do, so the command line is written out.
+func revealEntry(path string) error {
+	explorer := filepath.Join(os.Getenv("WINDIR"), "explorer.exe")
+	cmd := exec.Command(explorer)
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: fmt.Sprintf(`"%s" /select,"%s"`, explorer, path)}
+	// Explorer exits with 1 even when it worked.
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// openAction runs o or O on e and reports it in the status line.
+func (m model) openAction(e Entry, reveal bool) model {
+	if m.imported != "" {
+		m.status = "Read-only: " + m.imported + " was recorded on another machine"
+		return m
+	}
+	action, open := "open", openEntry
+	if reveal {
+		action, open = "reveal", revealEntry
+	}
+	if err := open(e.Path); err != nil {
+		m.status = fmt.Sprintf("Could not open %s: %v", e.Name, err)
+		return m
+	}
+	traceAction(action, e.Path, m.redactor)
+	if reveal {
+		m.status = "Showing " + e.Name + " in Explorer"
+	} else {
+		m.status = "Opened " + e.Name
+	}
+	return m
+}
@@ -0,0 +1,103 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/winmole/winmole/internal/otlp"
+	"github.com/winmole/winmole/internal/redact"
+)
+
+// When an OTLP endpoint is set (see internal/otlp), a scan without the TUI
+// is exported as a trace: an analyze.scan span for the whole scan, and an
+// analyze.dir span for every folder below it down to --depth, under the
+// span of its parent folder, so a trace viewer shows where the time went.
+// Each span says which folder, how big it is and how many entries it has;
+// with --redact the paths are masked as in the reports.
+
+// traceScope is the instrumentation scope of the scan spans.
+const traceScope = "winmole/analyze"
+
+// otlpTrace collects the spans of one scan. A nil *otlpTrace records
+// nothing.
+type otlpTrace struct {
+	settings otlp.Settings
+	redactor *redact.Redactor
+	id       otlp.TraceID
+
+	mu    sync.Mutex
+	spans []otlp.Span
+}
+
+// newOTLPTrace returns a trace to record a scan in, or nil when there is
+// no endpoint to export it to.
+func newOTLPTrace(r *redact.Redactor) *otlpTrace {
+	settings, err := otlp.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if !settings.Configured() {
+		return nil
+	}
+	return &otlpTrace{settings: settings, redactor: r, id: otlp.NewTraceID()}
+}
+
+// start begins the span of the folder dir, level levels below the root.
+func (tr *otlpTrace) start(dir string, level int, parent otlp.SpanID) otlp.Span {
+	if tr == nil {
+		return otlp.Span{}
+	}
+	name := "analyze.dir"
+	if level == 1 {
+		name = "analyze.scan"
+	}
+	return otlp.Span{
+		TraceID: tr.id,
+		ID:      otlp.NewSpanID(),
+		Parent:  parent,
+		Name:    name,
+		Start:   time.Now(),
+		Attrs: map[string]any{
+			"winmole.path":  tr.redactor.String(dir),
+			"winmole.depth": level,
+		},
+	}
+}
+
+// end records sp with what the scan of its folder found.
+func (tr *otlpTrace) end(sp otlp.Span, entries []Entry, totalSize int64, err error) {
+	if tr == nil {
+		return
+	}
+	sp.End = time.Now()
+	sp.Attrs["winmole.size"] = totalSize
+	sp.Attrs["winmole.entries"] = len(entries)
+	if err != nil {
+		sp.ErrorMsg = tr.redactor.String(err.Error())
+	}
+	tr.mu.Lock()
+	tr.spans = append(tr.spans, sp)
+	tr.mu.Unlock()
+}
+
+// export sends the spans recorded so far. A scan still winding down after
+// a timeout adds no more once they are sent.
+func (tr *otlpTrace) export() {
+	if tr == nil {
+		return
+	}
+	tr.mu.Lock()
+	spans := tr.spans
+	tr.spans = nil
+	tr.mu.Unlock()
+	if len(spans) == 0 {
+		return
+	}
+	if err := tr.settings.ExportSpans(traceScope, spans); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: exporting the scan trace: %v\n", err)
+	}
+}
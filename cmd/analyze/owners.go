@@ -0,0 +1,264 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/scan"
+	"golang.org/x/sys/windows"
+)
+
+// On a shared machine or a file server the question is often not which
+// folder but whose files fill the disk. Y adds a column with the owner of
+// each entry, as its security descriptor names it, read in the background
+// as rows come into view; U totals everything below the current folder by
+// owner, like x does by extension. Reading an owner opens the file's
+// security descriptor, which the MFT index does not keep, so U walks the
+// folder. Owners whose account is gone show as their SID.
+
+// unknownOwner is the owner of files whose security descriptor cannot be
+// read.
+const unknownOwner = "(unknown)"
+
+// ownerWidth is the width of the owner column.
+const ownerWidth = 24
+
+// ownerStat is the total size and number of files of one owner.
+type ownerStat struct {
+	owner string
+	size  int64
+	files int64
+}
+
+// ownerBreakdown is the by-owner view of one folder.
+type ownerBreakdown struct {
+	stats  []ownerStat // largest first
+	total  int64
+	offset int
+}
+
+type ownerResultMsg struct {
+	path  string
+	stats []ownerStat
+	total int64
+	err   error
+}
+
+// accounts maps SIDs to account names, since looking one up can mean
+// asking a domain controller.
+var accounts sync.Map
+
+// ownerSID returns the SID of the owner of path.
+func ownerSID(path string) (string, error) {
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION)
+	if err != nil {
+		return "", err
+	}
+	sid, _, err := sd.Owner()
+	if err != nil {
+		return "", err
+	}
+	if sid == nil {
+		return "", fmt.Errorf("%s has no owner", path)
+	}
+	return sid.String(), nil
+}
+
+// accountName returns DOMAIN\user for the SID s, or s itself when no
+// account has it anymore.
+func accountName(s string) string {
+	if name, ok := accounts.Load(s); ok {
+		return name.(string)
+	}
+	name := s
+	if sid, err := windows.StringToSid(s); err == nil {
+		if account, domain, _, err := sid.LookupAccount(""); err == nil {
+			name = account
+			if domain != "" {
+				name = domain + `\` + account
+			}
+		}
+	}
+	accounts.Store(s, name)
+	return name
+}
+
+// ownerOf returns the account that owns path.
+func ownerOf(path string) string {
+	s, err := ownerSID(path)
+	if err != nil {
+		return unknownOwner
+	}
+	return accountName(s)
+}
+
+// ownerColumn shows who owns e when the column is on, or a placeholder
+// until lookupOwners has read it.
+func (m model) ownerColumn(e Entry) string {
+	if m.owners == nil {
+		return ""
+	}
+	owner := m.owners[cacheKey(e.Path)]
+	if owner == "" {
+		owner = "…"
+	}
+	owner = m.redactor.String(owner)
+	if w := len([]rune(owner)); w > ownerWidth {
+		owner = "…" + string([]rune(owner)[w-ownerWidth+1:])
+	}
+	return fmt.Sprintf("%-*s ", ownerWidth, owner)
+}
+
+// ownerLookupMsg carries owners read by lookupOwners, by cacheKey.
+type ownerLookupMsg struct {
+	owners map[string]string
+}
+
+// lookupOwners reads the owners of the rows in view that have none yet,
+// away from the UI, since each can mean a round trip to a file server or
+// a domain controller. Rows being read are kept in m.owners as "".
+func (m model) lookupOwners() tea.Cmd {
+	end := min(m.offset+m.viewportHeight(), len(m.entries))
+	var paths []string
+	for _, e := range m.entries[min(m.offset, end):end] {
+		key := cacheKey(e.Path)
+		if _, ok := m.owners[key]; !ok {
+			m.owners[key] = ""
+			paths = append(paths, e.Path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		owners := make(map[string]string, len(paths))
+		for _, p := range paths {
+			owners[cacheKey(p)] = ownerOf(p)
+		}
+		return ownerLookupMsg{owners: owners}
+	}
+}
+
+// toggleOwners shows or hides the owner column.
+func (m model) toggleOwners() model {
+	switch {
+	case m.imported != "":
+		m.status = "Owners are not part of imported reports"
+	case m.owners != nil:
+		m.owners = nil
+		m.status = m.totalStatus()
+	default:
+		m.owners = map[string]string{}
+		m.status = m.totalStatus()
+	}
+	return m
+}
+
+func (m model) ownersCmd() tea.Cmd {
+	return func() tea.Msg {
+		stats, total, err := ownersBelow(m.path, m.progress)
+		return ownerResultMsg{path: m.path, stats: stats, total: total, err: err}
+	}
+}
+
+// ownersBelow totals the files below path by owner.
+func ownersBelow(path string, progress *scan.Counters) ([]ownerStat, int64, error) {
+	bySID := make(map[string]*ownerStat)
+	err := scan.Walk(context.Background(), path, progress, func(p string, info fs.FileInfo) {
+		sid, err := ownerSID(p)
+		if err != nil {
+			sid = unknownOwner
+		}
+		s, ok := bySID[sid]
+		if !ok {
+			s = &ownerStat{owner: sid}
+			bySID[sid] = s
+		}
+		s.size += info.Size()
+		s.files++
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Names are looked up once per owner rather than per file.
+	stats := make([]ownerStat, 0, len(bySID))
+	var total int64
+	for sid, s := range bySID {
+		if sid != unknownOwner {
+			s.owner = accountName(sid)
+		}
+		stats = append(stats, *s)
+		total += s.size
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].size > stats[j].size
+	})
+	return stats, total, nil
+}
+
+// showOwners starts totalling the current folder by owner.
+func (m model) showOwners() (tea.Model, tea.Cmd) {
+	if m.imported != "" {
+		m.status = "Owners are not part of imported reports"
+		return m, nil
+	}
+	m.scanning = true
+	m.status = "Reading owners..."
+	m.progress.Files.Store(0)
+	m.progress.Dirs.Store(0)
+	m.progress.Bytes.Store(0)
+	return m, tea.Batch(m.ownersCmd(), tickCmd())
+}
+
+// handleOwnersKey scrolls the by-owner view; U or Esc returns to the list.
+func (m model) handleOwnersKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	o := m.ownerTotals
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "U", "esc", "q":
+		m.ownerTotals = nil
+		m.status = m.totalStatus()
+	case "up", "k":
+		o.offset = max(o.offset-1, 0)
+	case "down", "j":
+		o.offset = max(min(o.offset+1, len(o.stats)-m.viewportHeight()), 0)
+	}
+	return m, nil
+}
+
+// renderOwners lists the owners with their size, file count and share of
+// the total.
+func (m model) renderOwners() string {
+	o := m.ownerTotals
+	if len(o.stats) == 0 {
+		return dimStyle.Render("  (no files)") + "\n"
+	}
+	var b strings.Builder
+	end := min(o.offset+m.viewportHeight(), len(o.stats))
+	for _, s := range o.stats[o.offset:end] {
+		filled := barWidth(s.size, o.total, m.logScale, 20)
+		bar := strings.Repeat("█", filled) + strings.Repeat("░", 20-filled)
+		pct := 0.0
+		if o.total > 0 {
+			pct = float64(s.size) * 100 / float64(o.total)
+		}
+		b.WriteString(fmt.Sprintf("%s %s %5.1f%%  %s %s\n",
+			sizeStyle.Render(humanize.Bytes(s.size)),
+			barStyle.Render(bar),
+			pct,
+			normalStyle.Render(fmt.Sprintf("%-32s", m.redactor.String(s.owner))),
+			dimStyle.Render(fmt.Sprintf("%d files", s.files)),
+		))
+	}
+	return b.String()
+}
@@ -0,0 +1,169 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+var modalStyle = lipgloss.NewStyle().
+	Border(lipgloss.RoundedBorder()).
+	BorderForeground(lipgloss.Color("203")).
+	Padding(1, 2)
+
+// purgePrompt is the permanent-delete modal. The user has to type the
+// entry's name before it is deleted.
+type purgePrompt struct {
+	entry Entry
+	input string
+}
+
+// purgeProgress is shared with the deleting goroutine.
+type purgeProgress struct {
+	entry Entry
+	files atomic.Int64
+	bytes atomic.Int64
+}
+
+// handlePurgeKey edits the typed confirmation: Enter deletes once the name
+// matches, Esc cancels.
+func (m model) handlePurgeKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.purge = nil
+		m.status = "Delete cancelled"
+	case tea.KeyEnter:
+		if !strings.EqualFold(m.purge.input, m.purge.entry.Name) {
+			return m, nil
+		}
+		progress := &purgeProgress{entry: m.purge.entry}
+		m.purge = nil
+		m.purging = progress
+		return m, tea.Batch(purgeCmd(progress), tickCmd())
+	case tea.KeyBackspace:
+		if r := []rune(m.purge.input); len(r) > 0 {
+			m.purge.input = string(r[:len(r)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.purge.input += string(msg.Runes)
+	}
+	return m, nil
+}
+
+func purgeCmd(p *purgeProgress) tea.Cmd {
+	return func() tea.Msg {
+		err := removeTree(p.entry.Path, p)
+		return deleteMsg{entry: p.entry, permanent: true, err: err}
+	}
+}
+
+// removeTree deletes path and everything below it, bypassing the Recycle
+// Bin, counting progress as it goes. Junctions and symlinks are removed
+// without following them. Read-only files are made writable first.
+func removeTree(path string, p *purgeProgress) error {
+	var dirs []string
+	var failed int
+	walkErr := filepath.WalkDir(path, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			failed++
+			return nil
+		}
+		if d.IsDir() {
+			dirs = append(dirs, name)
+			return nil
+		}
+		var size int64
+		if info, err := d.Info(); err == nil {
+			size = info.Size()
+		}
+		if err := removeFile(name); err != nil {
+			failed++
+			return nil
+		}
+		p.files.Add(1)
+		p.bytes.Add(size)
+		return nil
+	})
+	if walkErr != nil {
+		return walkErr
+	}
+
+	// Children were appended after their parents.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := removeFile(dirs[i]); err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d items could not be deleted", failed)
+	}
+	return nil
+}
+
+func removeFile(name string) error {
+	err := os.Remove(name)
+	if errors.Is(err, fs.ErrPermission) {
+		if os.Chmod(name, 0o666) == nil {
+			err = os.Remove(name)
+		}
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// purgeStatus describes a running permanent delete.
+func (p *purgeProgress) status(frame string) string {
+	done := p.bytes.Load()
+	line := fmt.Sprintf("%s Deleting %s... %d files, %s", frame, p.entry.Name, p.files.Load(), humanize.Bytes(done))
+	if p.entry.Size > 0 {
+		pct := float64(done) / float64(p.entry.Size) * 100
+		if pct > 100 {
+			pct = 100
+		}
+		line += fmt.Sprintf(" of %s (%.0f%%)", humanize.Bytes(p.entry.Size), pct)
+	}
+	return line
+}
+
+// renderPurge draws the permanent-delete modal.
+func (m model) renderPurge() string {
+	e := m.purge.entry
+	kind := "file"
+	if e.IsDir {
+		kind = "folder"
+	}
+
+	var b strings.Builder
+	b.WriteString(warnStyle.Render(fmt.Sprintf("Permanently delete this %s?", kind)))
+	b.WriteString("\n\n")
+	b.WriteString(normalStyle.Render(e.Path))
+	b.WriteString("\n")
+	b.WriteString(dimStyle.Render(humanize.Bytes(e.Size) + " • bypasses the Recycle Bin and cannot be undone"))
+	b.WriteString("\n\n")
+	b.WriteString(normalStyle.Render(fmt.Sprintf("Type %q to confirm:", e.Name)))
+	b.WriteString("\n")
+
+	input := "> " + m.purge.input + "█"
+	if strings.EqualFold(m.purge.input, e.Name) {
+		b.WriteString(selectedStyle.Render(input))
+		b.WriteString("\n\n")
+		b.WriteString(dimStyle.Render("Enter delete • Esc cancel"))
+	} else {
+		b.WriteString(normalStyle.Render(input))
+		b.WriteString("\n\n")
+		b.WriteString(dimStyle.Render("Esc cancel"))
+	}
+	return modalStyle.Render(b.String())
+}
@@ -0,0 +1,95 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/winmole/winmole/internal/upload"
+)
+
+// --snapshot --push sends what changed below the folder since its previous
+// snapshot to the WinMole server set in config.json, as a compressed diff
+// rather than the whole tree, so a small fleet's nightly scans can be
+// followed in one place. The first scan of a folder, with nothing to
+// compare with, sends its entries as new. A diff the server cannot take
+// now waits in the outbox and goes with the next run.
+
+// maxPushedChanges caps a diff at its biggest changes; the totals still
+// cover everything.
+const maxPushedChanges = 5000
+
+// previousScan is the snapshot a diff is taken against, read before the
+// new snapshot is saved, since --keep may delete it then.
+type previousScan struct {
+	info snapshotInfo
+	tree *baseline
+}
+
+// loadPreviousScan reads the newest snapshot of root; nil when there is
+// none to compare with.
+func loadPreviousScan(root string) *previousScan {
+	info, ok := latestSnapshot(root)
+	if !ok {
+		return nil
+	}
+	b, err := loadBaseline(info.path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: sending the whole tree: %v\n", err)
+		return nil
+	}
+	return &previousScan{info: info, tree: b}
+}
+
+// buildScanDiff compares the tree of the snapshot just saved, cur, with the
+// previous scan, or lists it whole when prev is nil.
+func buildScanDiff(c dirCache, cur snapshotInfo, prev *previousScan) upload.ScanDiff {
+	d := upload.ScanDiff{Root: cur.root, ScannedAt: cur.scannedAt, TotalSize: cur.totalSize}
+	var rows []diffRow
+	if prev != nil {
+		d.Since, d.SinceSize = &prev.info.scannedAt, prev.info.totalSize
+		rows = treeDiff(prev.tree, c, cur.root)
+	} else {
+		for _, r := range c.exportRows(cur.root, nil) {
+			rows = append(rows, diffRow{entry: Entry{Path: r.Path, IsDir: r.IsDir}, after: r.Size, status: "new"})
+		}
+	}
+	if len(rows) > maxPushedChanges {
+		rows, d.Truncated = rows[:maxPushedChanges], true
+	}
+	d.Changes = make([]upload.ScanChange, 0, len(rows))
+	for _, r := range rows {
+		d.Changes = append(d.Changes, upload.ScanChange{
+			Path:   r.entry.Path,
+			Before: r.before,
+			After:  r.after,
+			IsDir:  r.entry.IsDir,
+			Status: r.status,
+		})
+	}
+	return d
+}
+
+// pushScanDiff queues the diff and sends everything queued. Failures are
+// warnings: the snapshot is saved either way.
+func pushScanDiff(c dirCache, cur snapshotInfo, prev *previousScan) {
+	settings, err := upload.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if !settings.Configured() {
+		fmt.Fprintf(os.Stderr, "Warning: not pushed: %v\n", upload.ErrNotConfigured)
+		return
+	}
+	if err := upload.Queue(upload.KindScanDiff, buildScanDiff(c, cur, prev)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: not pushed: %v\n", err)
+		return
+	}
+	sent, err := upload.Flush(settings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v; %d batches wait to be sent\n", err, upload.Pending())
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Pushed %d batches to %s\n", sent, settings.URL)
+}
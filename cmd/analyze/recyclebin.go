@@ -0,0 +1,130 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/policy"
+	"github.com/winmole/winmole/internal/usage"
+	"github.com/winmole/winmole/pkg/humanize"
+	"golang.org/x/sys/windows"
+)
+
+// The drive list shows how much each drive's Recycle Bin holds, which a
+// scan of the drive counts under $Recycle.Bin where few look, and e empties
+// the selected drive's bin after asking.
+
+var (
+	procSHQueryRecycleBinW = shell32.NewProc("SHQueryRecycleBinW")
+	procSHEmptyRecycleBinW = shell32.NewProc("SHEmptyRecycleBinW")
+)
+
+// SHEmptyRecycleBin flags (shellapi.h).
+const (
+	sherbNoConfirmation = 0x1
+	sherbNoProgressUI   = 0x2
+	sherbNoSound        = 0x4
+)
+
+// shQueryRBInfo mirrors SHQUERYRBINFO, which is packed to 1 byte on 32-bit
+// Windows, as Go lays it out there too.
+type shQueryRBInfo struct {
+	cbSize   uint32
+	size     int64
+	numItems int64
+}
+
+// recycleBin returns the size and item count of the Recycle Bin on the
+// drive with root, such as `C:\`, for the current user.
+func recycleBin(root string) (size, items int64, err error) {
+	p, err := windows.UTF16PtrFromString(root)
+	if err != nil {
+		return 0, 0, err
+	}
+	info := shQueryRBInfo{cbSize: uint32(unsafe.Sizeof(shQueryRBInfo{}))}
+	if r, _, _ := procSHQueryRecycleBinW.Call(uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&info))); r != 0 {
+		return 0, 0, windows.Errno(r & 0xFFFF)
+	}
+	return info.size, info.numItems, nil
+}
+
+// emptyRecycleBin deletes everything in the Recycle Bin on the drive with
+// root, without Explorer's own prompt: the drive list has asked already.
+func emptyRecycleBin(root string) error {
+	p, err := windows.UTF16PtrFromString(root)
+	if err != nil {
+		return err
+	}
+	r, _, _ := procSHEmptyRecycleBinW.Call(0, uintptr(unsafe.Pointer(p)), sherbNoConfirmation|sherbNoProgressUI|sherbNoSound)
+	// An empty bin reports E_UNEXPECTED on some versions.
+	if r != 0 && r != 0x8000FFFF {
+		return windows.Errno(r & 0xFFFF)
+	}
+	return nil
+}
+
+type recycleEmptiedMsg struct {
+	root  string
+	freed int64
+	err   error
+}
+
+func emptyRecycleCmd(d driveInfo) tea.Cmd {
+	return func() tea.Msg {
+		if err := emptyRecycleBin(d.root); err != nil {
+			return recycleEmptiedMsg{root: d.root, err: err}
+		}
+		left, _, _ := recycleBin(d.root)
+		return recycleEmptiedMsg{root: d.root, freed: max(d.recycled-left, 0)}
+	}
+}
+
+// confirmEmpty asks before emptying the selected drive's Recycle Bin.
+// Emptying it deletes for good, so the deletion policy applies.
+func (m model) confirmEmpty() model {
+	p := m.drives
+	if p.selected >= len(p.drives) {
+		return m
+	}
+	if policy.Get().DisableFileDeletion {
+		m.status = "Emptying the Recycle Bin is " + policy.DisabledMessage
+		return m
+	}
+	d := p.drives[p.selected]
+	if d.recycled == 0 {
+		m.status = "The Recycle Bin on " + d.root + " is empty"
+		return m
+	}
+	p.confirm = true
+	m.status = fmt.Sprintf("Empty the Recycle Bin on %s: %s in %s, for good? y to go ahead",
+		d.root, humanize.Bytes(d.recycled), itemCount(int(d.recycledItems)))
+	return m
+}
+
+func (m model) applyRecycleEmptied(msg recycleEmptiedMsg) (model, tea.Cmd) {
+	if msg.err != nil {
+		m.status = fmt.Sprintf("Cannot empty the Recycle Bin on %s: %v", msg.root, msg.err)
+	} else {
+		usage.Run("analyze.recyclebin")
+		usage.Freed("analyze.recyclebin", msg.freed)
+		traceAction("empty-recycle-bin", msg.root, m.redactor)
+		m.status = fmt.Sprintf("Emptied the Recycle Bin on %s • %s freed", msg.root, humanize.Bytes(msg.freed))
+	}
+	// Listings of the drive's root and its bin are out of date.
+	root := cacheKey(msg.root)
+	dropMFTIndex(msg.root)
+	for key := range m.cache {
+		if key == root || (isUnder(key, root) && strings.Contains(key, `\$recycle.bin`)) {
+			delete(m.cache, key)
+		}
+	}
+	if m.drives == nil {
+		return m, nil
+	}
+	m.drives.loading = true
+	return m, drivesCmd()
+}
@@ -0,0 +1,214 @@
+//go:build windows
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/winmole/winmole/pkg/humanize"
+	"golang.org/x/sys/windows"
+)
+
+// G relocates the selected folder, or the marked ones, to another drive
+// and leaves a junction where each one was, so programs that expect it
+// there keep working while the space is freed on its own drive. Every
+// step can be undone until the last: the folder is copied, the copy
+// compared with it, the original renamed aside (which fails while a
+// program holds a file in it open), the junction made and checked, and
+// only then is the original deleted. A failure before that puts
+// everything back the way it was. Permissions are not copied: the copy
+// takes those of the folder it lands in.
+
+// relocatingSuffix is added to a folder's name while its junction is made.
+const relocatingSuffix = ".winmole-relocating"
+
+// relocatable returns why entries cannot be relocated, or "".
+func relocatable(entries []Entry) string {
+	for _, e := range entries {
+		switch {
+		case e.Target != "":
+			return fmt.Sprintf("%s is already a link to %s", e.Name, e.Target)
+		case !e.IsDir:
+			return fmt.Sprintf("%s is a file; only folders can be relocated", e.Name)
+		}
+	}
+	return ""
+}
+
+// relocateDestination checks the folder typed for a relocation: it has
+// to be on another local drive with room for everything.
+func relocateDestination(input string, entries []Entry) (string, error) {
+	dest, err := moveDestination(input, entries)
+	if err != nil {
+		return "", err
+	}
+	if isNetworkPath(dest) {
+		return "", errors.New("Junctions cannot point at network folders; pick a local drive")
+	}
+	vol := filepath.VolumeName(dest)
+	var size int64
+	for _, e := range entries {
+		if strings.EqualFold(filepath.VolumeName(e.Path), vol) {
+			return "", fmt.Errorf("%s is on the same drive as %s; use M to move within a drive", dest, e.Name)
+		}
+		size += e.Size
+	}
+	if u, err := disk.Usage(vol + `\`); err == nil && u.Free < uint64(size) {
+		return "", fmt.Errorf("%s has %s free, %s is needed", vol, humanize.Bytes(u.Free), humanize.Bytes(size))
+	}
+	return dest, nil
+}
+
+// relocateEntry moves the folder e into dest on another drive and leaves
+// a junction to it at its old path.
+func relocateEntry(e Entry, dest string, p *purgeProgress) error {
+	path := e.Path
+	target := filepath.Join(dest, filepath.Base(path))
+	if _, err := os.Lstat(target); err == nil {
+		return fmt.Errorf("%s already exists", target)
+	}
+	aside := path + relocatingSuffix
+	if _, err := os.Lstat(aside); err == nil {
+		return fmt.Errorf("%s is left from an earlier relocation", aside)
+	}
+
+	if err := copyTree(path, target, p); err != nil {
+		removeTree(target, new(purgeProgress))
+		return fmt.Errorf("copy: %w", err)
+	}
+	if err := sameTree(path, target); err != nil {
+		removeTree(target, new(purgeProgress))
+		return fmt.Errorf("the copy does not match: %w", err)
+	}
+	if err := os.Rename(path, aside); err != nil {
+		removeTree(target, new(purgeProgress))
+		if errors.Is(err, windows.ERROR_SHARING_VIOLATION) || errors.Is(err, windows.ERROR_ACCESS_DENIED) {
+			return errors.New("a program is using it; close it and try again")
+		}
+		return err
+	}
+	rollback := func() {
+		os.Remove(path)
+		os.Rename(aside, path)
+		removeTree(target, new(purgeProgress))
+	}
+	if err := createJunction(path, target); err != nil {
+		rollback()
+		return fmt.Errorf("junction: %w", err)
+	}
+	if err := checkJunction(path, target); err != nil {
+		rollback()
+		return fmt.Errorf("junction: %w", err)
+	}
+	if err := removeTree(aside, new(purgeProgress)); err != nil {
+		return fmt.Errorf("relocated, but %s could not be deleted: %w", aside, err)
+	}
+	return nil
+}
+
+// sameTree compares the copy dst with src: every folder and file in src
+// has to be there, files with the same size.
+func sameTree(src, dst string) error {
+	return filepath.WalkDir(src, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, name)
+		if err != nil {
+			return err
+		}
+		a, err := d.Info()
+		if err != nil {
+			return err
+		}
+		b, err := os.Lstat(filepath.Join(dst, rel))
+		if err != nil {
+			return fmt.Errorf("%s is missing", rel)
+		}
+		if a.IsDir() != b.IsDir() || (!a.IsDir() && a.Size() != b.Size()) {
+			return fmt.Errorf("%s differs", rel)
+		}
+		return nil
+	})
+}
+
+// createJunction makes link, which must not exist, a junction to the
+// folder target. Unlike symbolic links, junctions need no privilege.
+func createJunction(link, target string) error {
+	if err := os.Mkdir(link, 0o755); err != nil {
+		return err
+	}
+	if err := setMountPoint(link, target); err != nil {
+		os.Remove(link)
+		return err
+	}
+	return nil
+}
+
+// setMountPoint writes the junction reparse point (REPARSE_DATA_BUFFER
+// with a MountPointReparseBuffer) on the empty folder link.
+func setMountPoint(link, target string) error {
+	sub := utf16.Encode([]rune(`\??\` + target))
+	shown := utf16.Encode([]rune(target))
+	// Tag, length and reserved, then the four offsets and lengths, then
+	// both names, each ending in a NUL.
+	buf := make([]byte, 16+2*(len(sub)+1+len(shown)+1))
+	le := binary.LittleEndian
+	le.PutUint32(buf[0:], windows.IO_REPARSE_TAG_MOUNT_POINT)
+	le.PutUint16(buf[4:], uint16(len(buf)-8))
+	le.PutUint16(buf[8:], 0)
+	le.PutUint16(buf[10:], uint16(2*len(sub)))
+	le.PutUint16(buf[12:], uint16(2*(len(sub)+1)))
+	le.PutUint16(buf[14:], uint16(2*len(shown)))
+	for i, c := range sub {
+		le.PutUint16(buf[16+2*i:], c)
+	}
+	at := 16 + 2*(len(sub)+1)
+	for i, c := range shown {
+		le.PutUint16(buf[at+2*i:], c)
+	}
+
+	name, err := windows.UTF16PtrFromString(link)
+	if err != nil {
+		return err
+	}
+	h, err := windows.CreateFile(name, windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING,
+		windows.FILE_FLAG_BACKUP_SEMANTICS|windows.FILE_FLAG_OPEN_REPARSE_POINT, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+	var n uint32
+	return windows.DeviceIoControl(h, windows.FSCTL_SET_REPARSE_POINT, &buf[0], uint32(len(buf)), nil, 0, &n, nil)
+}
+
+// checkJunction makes sure link leads to the folder target.
+func checkJunction(link, target string) error {
+	to, err := os.Readlink(link)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(filepath.Clean(to), filepath.Clean(target)) {
+		return fmt.Errorf("it points at %s", to)
+	}
+	a, err := os.Stat(link)
+	if err != nil {
+		return err
+	}
+	b, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(a, b) {
+		return errors.New("it does not lead to the copy")
+	}
+	return nil
+}
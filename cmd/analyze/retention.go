@@ -0,0 +1,132 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// The nightly job of `winmole analyze schedule` runs with --prune, so the
+// snapshot history thins out as it ages instead of growing without end:
+// the newest snapshot of each of the last days, weeks and months is kept,
+// like a backup rotation, and the whole snapshot folder is held to a
+// budget by deleting the oldest snapshots first. Days, weeks and months
+// follow the local calendar, so a day when the clocks change is one day.
+
+// snapshotRetention is the analyze.snapshots setting.
+type snapshotRetention struct {
+	Daily    int   `json:"daily"`    // days with a snapshot to keep one of
+	Weekly   int   `json:"weekly"`   // weeks
+	Monthly  int   `json:"monthly"`  // months
+	BudgetMB int64 `json:"budgetMB"` // for the whole snapshot folder; 0 for none
+}
+
+func defaultRetention() snapshotRetention {
+	return snapshotRetention{Daily: 7, Weekly: 4, Monthly: 12, BudgetMB: 2048}
+}
+
+// kept picks the snapshots of one folder, newest first, that r keeps: the
+// newest in each of the r.Daily most recent days that have one, and the
+// same for weeks and months. The newest snapshot is always kept.
+func (r snapshotRetention) kept(list []snapshotInfo) map[string]bool {
+	keep := make(map[string]bool)
+	if len(list) > 0 {
+		keep[list[0].path] = true
+	}
+	periods := []struct {
+		n   int
+		key func(y, m, d, week int) string
+	}{
+		{r.Daily, func(y, m, d, _ int) string { return fmt.Sprintf("%d-%d-%d", y, m, d) }},
+		{r.Weekly, func(_, _, _, week int) string { return fmt.Sprint(week) }},
+		{r.Monthly, func(y, m, _, _ int) string { return fmt.Sprintf("%d-%d", y, m) }},
+	}
+	for _, p := range periods {
+		seen := make(map[string]bool)
+		for _, s := range list {
+			if len(seen) >= p.n {
+				break
+			}
+			t := s.scannedAt.Local()
+			y, m, d := t.Date()
+			wy, w := t.ISOWeek()
+			key := p.key(y, int(m), d, wy*100+w)
+			if !seen[key] {
+				seen[key] = true
+				keep[s.path] = true
+			}
+		}
+	}
+	return keep
+}
+
+// pruneByRetention deletes the snapshots of root that r does not keep,
+// then the oldest snapshots of any folder while the snapshot folder is
+// over r's budget, sparing each folder's newest. It returns how many
+// snapshots went and their size.
+func pruneByRetention(root string, r snapshotRetention) (removed int, freed int64) {
+	files, err := filepath.Glob(filepath.Join(snapshotDir(), "*.json"))
+	if err != nil {
+		return 0, 0
+	}
+	type file struct {
+		snapshotInfo
+		size int64
+	}
+	var all []file
+	for _, path := range files {
+		s, err := readSnapshotInfo(path)
+		if err != nil {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		all = append(all, file{s, info.Size()})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].scannedAt.After(all[j].scannedAt) })
+
+	remove := func(f file) {
+		if os.Remove(f.path) == nil {
+			removed++
+			freed += f.size
+		}
+	}
+
+	var mine []snapshotInfo
+	for _, f := range all {
+		if cacheKey(f.root) == cacheKey(root) {
+			mine = append(mine, f.snapshotInfo)
+		}
+	}
+	keep := r.kept(mine)
+	var left []file
+	spare := make(map[string]bool) // each folder's newest snapshot
+	seen := make(map[string]bool)
+	var total int64
+	for _, f := range all {
+		if cacheKey(f.root) == cacheKey(root) && !keep[f.path] {
+			remove(f)
+			continue
+		}
+		if !seen[cacheKey(f.root)] {
+			seen[cacheKey(f.root)] = true
+			spare[f.path] = true
+		}
+		left = append(left, f)
+		total += f.size
+	}
+
+	budget := r.BudgetMB << 20
+	for i := len(left) - 1; i >= 0 && budget > 0 && total > budget; i-- {
+		if f := left[i]; !spare[f.path] {
+			total -= f.size
+			remove(f)
+		}
+	}
+	return removed, freed
+}
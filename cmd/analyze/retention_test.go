@@ -0,0 +1,65 @@
+//go:build windows
+
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestRetentionKept(t *testing.T) {
+	tests := []struct {
+		name  string
+		r     snapshotRetention
+		times []string // newest first, local time
+		want  []string
+	}{
+		{"empty", defaultRetention(), nil, nil},
+		{"nothing but the newest", snapshotRetention{},
+			[]string{"2026-10-15 22:00", "2026-10-14 22:00"},
+			[]string{"2026-10-15 22:00"}},
+		{"newest of each day", snapshotRetention{Daily: 3},
+			[]string{"2026-10-15 22:00", "2026-10-15 08:00", "2026-10-14 23:59", "2026-10-13 00:00", "2026-10-12 12:00"},
+			[]string{"2026-10-13 00:00", "2026-10-14 23:59", "2026-10-15 22:00"}},
+		{"days without snapshots do not count", snapshotRetention{Daily: 2},
+			[]string{"2026-10-15 22:00", "2026-10-01 22:00", "2026-09-01 22:00"},
+			[]string{"2026-10-01 22:00", "2026-10-15 22:00"}},
+		{"weeks start on Monday", snapshotRetention{Weekly: 2},
+			[]string{"2026-10-15 22:00", "2026-10-12 00:30", "2026-10-11 23:30", "2026-10-05 22:00", "2026-10-04 22:00"},
+			[]string{"2026-10-11 23:30", "2026-10-15 22:00"}},
+		{"ISO week across the new year", snapshotRetention{Weekly: 2},
+			[]string{"2027-01-01 22:00", "2026-12-28 22:00", "2026-12-27 22:00", "2026-12-20 22:00"},
+			[]string{"2026-12-27 22:00", "2027-01-01 22:00"}},
+		{"newest of each month", snapshotRetention{Monthly: 2},
+			[]string{"2026-10-15 22:00", "2026-10-01 00:00", "2026-09-30 23:59", "2026-09-01 22:00", "2026-08-31 22:00"},
+			[]string{"2026-09-30 23:59", "2026-10-15 22:00"}},
+		{"same month in another year", snapshotRetention{Monthly: 2},
+			[]string{"2026-10-15 22:00", "2025-10-15 22:00", "2024-10-15 22:00"},
+			[]string{"2025-10-15 22:00", "2026-10-15 22:00"}},
+		{"periods add up", snapshotRetention{Daily: 2, Weekly: 1, Monthly: 2},
+			[]string{"2026-10-15 22:00", "2026-10-14 22:00", "2026-10-13 22:00", "2026-09-20 22:00", "2026-09-10 22:00"},
+			[]string{"2026-09-20 22:00", "2026-10-14 22:00", "2026-10-15 22:00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var list []snapshotInfo
+			for _, s := range tt.times {
+				at, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+				if err != nil {
+					t.Fatal(err)
+				}
+				list = append(list, snapshotInfo{path: s, scannedAt: at.UTC()})
+			}
+			var got []string
+			for path := range tt.r.kept(list) {
+				got = append(got, path)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
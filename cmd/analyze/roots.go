@@ -0,0 +1,183 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/etw"
+	"github.com/winmole/winmole/pkg/scan"
+)
+
+// Given several paths (analyze C:\ D:\ E:\), the analyzer scans them all
+// at once and starts on a list with one row per path. Enter opens one as
+// usual and ← at its top comes back to the list. Since the rows are whole
+// roots, the list is for comparing and opening only: deleting, moving,
+// excluding and the per-folder views start inside a root.
+
+// rootsPath stands for the list of roots in m.path, the history and the
+// cache. It cannot be a real path.
+const rootsPath = "::roots"
+
+// rootsOnly is what the keys that need a real folder say on the list of
+// roots.
+const rootsOnly = "Open one of the roots first; this list only compares them"
+
+// loadRoots shows the list of roots, scanning those not scanned yet as
+// shown.
+func (m model) loadRoots() (tea.Model, tea.Cmd) {
+	var missing []string
+	for _, root := range m.roots {
+		if l, ok := m.cache[cacheKey(root)]; !ok || !m.scannedAsShown(l) {
+			missing = append(missing, root)
+		}
+	}
+	if len(missing) == 0 {
+		return m.showRoots(), nil
+	}
+	return m.scanRoots(missing)
+}
+
+// scanRoots scans roots in parallel, each with its own counters so that
+// each listing gets its own largest files and unreadable folders.
+func (m model) scanRoots(roots []string) (model, tea.Cmd) {
+	if m.run != nil {
+		m.run.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.run = &scanRun{cancel: cancel, started: time.Now(), pending: len(roots)}
+	cmds := []tea.Cmd{tickCmd()}
+	for _, root := range roots {
+		progress := newProgress(m.exclude)
+		if l, ok := m.cache[cacheKey(root)]; ok && !l.partial {
+			m.run.expected += l.totalSize
+		}
+		m.run.roots = append(m.run.roots, progress)
+		cmds = append(cmds, m.rootScanCmd(ctx, m.run, root, progress))
+	}
+	m.scanning = true
+	m.status = "Scanning..."
+	return m, tea.Batch(cmds...)
+}
+
+func (m model) rootScanCmd(ctx context.Context, run *scanRun, root string, progress *scan.Counters) tea.Cmd {
+	return func() tea.Msg {
+		markJournal(root)
+		etw.Writef(etw.LevelInfo, etw.KeywordScan, "scan start: %s", m.redactor.String(root))
+		if m.dedupe {
+			progress.Links = scan.NewLinks()
+		}
+		progress.FollowLinks = m.follow
+		progress.Streams = m.streams
+		progress.Unreadable = scan.NewUnreadable(unreadableKept)
+		entries, totalSize, err := scanDirectory(ctx, root, progress)
+		return scanResultMsg{path: root, entries: entries, totalSize: totalSize, largest: progress.Largest.Files(), links: m.dedupe, follow: m.follow, streams: m.streams, skipped: progress.Unreadable, run: run, err: err}
+	}
+}
+
+// applyRootScan takes the scan of one root, and shows the list once the
+// last one is in.
+func (m model) applyRootScan(msg scanResultMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil && !errors.Is(msg.err, context.Canceled) {
+		m.run.failed = append(m.run.failed, fmt.Sprintf("%s: %v", msg.path, msg.err))
+	} else {
+		m = m.cacheScan(msg)
+	}
+	m.run.pending--
+	if m.run.pending > 0 {
+		return m, nil
+	}
+	failed := m.run.failed
+	m.scanning = false
+	m.run = nil
+	m = m.showRoots()
+	if len(failed) > 0 {
+		m.status = "Error: " + strings.Join(failed, "; ")
+	}
+	return m, nil
+}
+
+// showRoots lists the roots from their scans, one folder-like entry each.
+func (m model) showRoots() model {
+	listing := dirListing{path: rootsPath, links: m.dedupe, follow: m.follow, streams: m.streams}
+	for _, root := range m.roots {
+		l, ok := m.cache[cacheKey(root)]
+		if !ok {
+			continue
+		}
+		e := folderEntry(root, root, l)
+		listing.entries = append(listing.entries, e)
+		listing.totalSize += e.Size
+		listing.partial = listing.partial || l.partial
+	}
+	m.cache[cacheKey(rootsPath)] = listing
+
+	m.filter = ""
+	m.scanning = false
+	m.entries = m.shown(listing.entries)
+	m.totalSize = listing.totalSize
+	if m.selected >= len(m.entries) {
+		m.selected, m.offset = 0, 0
+	}
+	m = m.selectFocus()
+	m.status = m.totalStatus()
+	return m
+}
+
+// folderEntry sums a folder's listing into the entry its parent lists it
+// as.
+func folderEntry(name, path string, l dirListing) Entry {
+	e := Entry{Name: name, Path: path, Size: l.totalSize, IsDir: true}
+	for _, c := range l.entries {
+		e.Alloc += c.Alloc
+		e.Cloud += c.Cloud
+		e.Linked += c.Linked
+		e.LinkedAlloc += c.LinkedAlloc
+		e.Streams += c.Streams
+		e.Files += c.Files
+		e.Dirs += c.Dirs
+		if c.IsDir {
+			e.Dirs++
+		}
+		if c.ModTime.After(e.ModTime) {
+			e.ModTime = c.ModTime
+		}
+	}
+	return e
+}
+
+// rescanRoots scans every root afresh.
+func (m model) rescanRoots() (tea.Model, tea.Cmd) {
+	for _, root := range m.roots {
+		traceAction("rescan", root, m.redactor)
+		dropMFTIndex(root)
+	}
+	m, cmd := m.scanRoots(m.roots) // estimates from the listings being replaced
+	for _, root := range m.roots {
+		delete(m.cache, cacheKey(root))
+	}
+	return m, cmd
+}
+
+// rootsKey handles the keys that act differently on the list of roots,
+// reporting whether it did.
+func (m model) rootsKey(key string) (tea.Model, tea.Cmd, bool) {
+	switch key {
+	case "d", "D", "M", "X", " ", "e", "E", "f", "x", "i", "/", "n", "N", "w", "S", "c", "C", "T", "u", "g", "v", "Z", "K", "B", "W", "I", "V", "H", "G", "U":
+		m.status = rootsOnly
+		return m, nil, true
+	case "r":
+		next, cmd := m.rescanRoots()
+		return next, cmd, true
+	case "left", "h", "backspace":
+		if len(m.history) == 0 {
+			return m, nil, true // nothing above
+		}
+	}
+	return m, nil, false
+}
@@ -0,0 +1,299 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/config"
+	"github.com/winmole/winmole/pkg/scan"
+)
+
+// The scanned folders are saved to the cache directory on exit. On the next
+// launch the change journal of each volume is replayed from where the saved
+// scan left off, so only folders that changed are scanned again. Volumes
+// whose journal cannot be read (older Windows without admin rights, a reset
+// journal) are shown as saved, with their age, until refreshed with r.
+
+const (
+	scanCacheFile    = "analyze-scan.json"
+	scanCacheVersion = 4 // 2: folder counts and size on disk; 3: links marked; 4: cloud placeholders
+)
+
+type savedScan struct {
+	Version  int                     `json:"version"`
+	Journals map[string]journalMark  `json:"journals"`          // by volume ("C:")
+	Dirs     map[string]savedListing `json:"dirs"`              // by cacheKey
+	Exclude  []string                `json:"exclude,omitempty"` // see exclusions.list
+}
+
+type savedListing struct {
+	Path      string    `json:"path"`
+	SavedAt   time.Time `json:"savedAt"`
+	TotalSize int64     `json:"totalSize"`
+	Entries   []Entry   `json:"entries"`
+}
+
+func scanCachePath() string {
+	return filepath.Join(config.CacheDir(), scanCacheFile)
+}
+
+// saveScanCache writes c for the next launch. Listings that were loaded
+// without replaying their volume's journal are only kept while the volume
+// still has no journal position; otherwise the saved position would vouch
+// for changes they never saw.
+func saveScanCache(c dirCache, exclude []string) error {
+	doc := savedScan{
+		Version:  scanCacheVersion,
+		Exclude:  exclude,
+		Journals: make(map[string]journalMark),
+		Dirs:     make(map[string]savedListing, len(c)),
+	}
+	now := time.Now()
+	for key, l := range c {
+		if l.partial || l.path == rootsPath {
+			continue
+		}
+		vol := driveVolume(l.path)
+		mark, marked := journalMarkOf(vol)
+		if !l.savedAt.IsZero() && marked {
+			continue
+		}
+		if marked {
+			doc.Journals[vol] = mark
+		}
+		savedAt := l.savedAt
+		if savedAt.IsZero() {
+			savedAt = now
+		}
+		doc.Dirs[key] = savedListing{Path: l.path, SavedAt: savedAt, TotalSize: l.totalSize, Entries: l.entries}
+	}
+
+	if err := os.MkdirAll(config.CacheDir(), 0o755); err != nil {
+		return fmt.Errorf("create cache directory: %w", err)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	tmp := scanCachePath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("save scan cache: %w", err)
+	}
+	return os.Rename(tmp, scanCachePath())
+}
+
+// loadScanCache reads the saved scan and brings it up to date from the
+// change journals. A missing or unreadable file yields an empty cache, and
+// so does one saved with other exclusions than exclude.
+func loadScanCache(progress *scan.Counters, exclude []string) dirCache {
+	c := make(dirCache)
+	data, err := os.ReadFile(scanCachePath())
+	if err != nil {
+		return c
+	}
+	var doc savedScan
+	if json.Unmarshal(data, &doc) != nil || doc.Version != scanCacheVersion || !slices.Equal(doc.Exclude, exclude) {
+		return c
+	}
+
+	volumes := make(map[string]bool)
+	for key, l := range doc.Dirs {
+		if _, err := os.Stat(l.Path); err != nil {
+			continue // deleted since
+		}
+		c[key] = dirListing{path: l.Path, entries: l.Entries, totalSize: l.TotalSize, savedAt: l.SavedAt}
+		volumes[driveVolume(l.Path)] = true
+	}
+
+	for vol := range volumes {
+		mark, ok := doc.Journals[vol]
+		if !ok || vol == "" {
+			continue
+		}
+		dirs, now, err := changedDirs(vol, mark)
+		if err != nil {
+			continue
+		}
+		// The replay starts from the position read before rescanning, so
+		// anything changing meanwhile is replayed again next time.
+		setJournalMark(vol, now)
+		for key, l := range c {
+			if driveVolume(l.path) == vol {
+				l.savedAt = time.Time{}
+				c[key] = l
+			}
+		}
+		c.refresh(dirs, progress)
+	}
+	return c
+}
+
+// refresh rescans what changed below the cached folders; dirs are the
+// folders the journal saw changes in. A changed folder that is cached is
+// listed again, and a change further down re-measures only the entry of the
+// nearest cached folder that leads to it. Work goes deepest first so each
+// size change is carried up to the ancestors exactly once.
+func (c dirCache) refresh(dirs []string, progress *scan.Counters) {
+	targets := make(map[string]string) // cacheKey -> path
+	for _, dir := range dirs {
+		key := cacheKey(dir)
+		for k := key; ; {
+			if listing, ok := c[k]; ok {
+				if k == key {
+					targets[key] = dir
+				} else if e, ok := listing.entryLeadingTo(key); ok {
+					targets[cacheKey(e.Path)] = e.Path
+				}
+				break
+			}
+			parent := filepath.Dir(k)
+			if parent == k {
+				break
+			}
+			k = parent
+		}
+	}
+
+	keys := make([]string, 0, len(targets))
+	for key := range targets {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+
+	for _, key := range keys {
+		path := targets[key]
+		if old, ok := c[key]; ok {
+			entries, total, err := scan.Dir(path, progress)
+			if err != nil {
+				continue
+			}
+			now, before := countBelow(entries), countBelow(old.entries)
+			c[key] = dirListing{path: old.path, entries: entries, totalSize: total}
+			c.adjust(key, scan.Totals{
+				Size:        total - old.totalSize,
+				Alloc:       now.Alloc - before.Alloc,
+				Cloud:       now.Cloud - before.Cloud,
+				Linked:      now.Linked - before.Linked,
+				LinkedAlloc: now.LinkedAlloc - before.LinkedAlloc,
+				Streams:     now.Streams - before.Streams,
+				Files:       now.Files - before.Files,
+				Dirs:        now.Dirs - before.Dirs,
+			})
+			continue
+		}
+
+		parent, ok := c[cacheKey(filepath.Dir(path))]
+		if !ok {
+			continue
+		}
+		e, ok := parent.entryLeadingTo(key)
+		if !ok {
+			continue
+		}
+		t := scan.Tree(context.Background(), path, progress)
+		c.adjust(key, scan.Totals{
+			Size:        t.Size - e.Size,
+			Alloc:       t.Alloc - e.Alloc,
+			Cloud:       t.Cloud - e.Cloud,
+			Linked:      t.Linked - e.Linked,
+			LinkedAlloc: t.LinkedAlloc - e.LinkedAlloc,
+			Streams:     t.Streams - e.Streams,
+			Files:       t.Files - e.Files,
+			Dirs:        t.Dirs - e.Dirs,
+		})
+	}
+}
+
+// countBelow totals a listing's entries, except for the logical size which
+// the listing keeps: size on disk, hard links and the files and folders in
+// and below them.
+func countBelow(entries []Entry) (t scan.Totals) {
+	for _, e := range entries {
+		t.Alloc += e.Alloc
+		t.Cloud += e.Cloud
+		t.Linked += e.Linked
+		t.LinkedAlloc += e.LinkedAlloc
+		t.Streams += e.Streams
+		t.Files += e.Files
+		t.Dirs += e.Dirs
+		if e.IsDir {
+			t.Dirs++
+		}
+	}
+	return t
+}
+
+// entryLeadingTo returns the entry of l that is key or contains it.
+func (l dirListing) entryLeadingTo(key string) (Entry, bool) {
+	for _, e := range l.entries {
+		if k := cacheKey(e.Path); k == key || isUnder(key, k) {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// adjust carries a change in the totals of the folder at key into the
+// listings of the cached folders above it.
+func (c dirCache) adjust(key string, d scan.Totals) {
+	if d == (scan.Totals{}) {
+		return
+	}
+	for dir, listing := range c {
+		if !isUnder(key, dir) {
+			continue
+		}
+		entries := make([]Entry, len(listing.entries))
+		copy(entries, listing.entries)
+		for i, e := range entries {
+			if k := cacheKey(e.Path); k == key || isUnder(key, k) {
+				entries[i].Size = max(e.Size+d.Size, 0)
+				entries[i].Alloc = max(e.Alloc+d.Alloc, 0)
+				entries[i].Cloud = max(e.Cloud+d.Cloud, 0)
+				entries[i].Linked = max(e.Linked+d.Linked, 0)
+				entries[i].LinkedAlloc = max(e.LinkedAlloc+d.LinkedAlloc, 0)
+				entries[i].Streams = max(e.Streams+d.Streams, 0)
+				entries[i].Files = max(e.Files+d.Files, 0)
+				entries[i].Dirs = max(e.Dirs+d.Dirs, 0)
+			}
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Size > entries[j].Size
+		})
+		listing.entries = entries
+		listing.totalSize = max(listing.totalSize+d.Size, 0)
+		listing.largest = nil // collected again when next shown
+		c[dir] = listing
+	}
+}
+
+// formatAge renders how old a saved listing is: "5 min", "3 h", "2 days".
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%d min", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%d h", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d days", int(d.Hours()/24))
+}
+
+// restoredMsg carries the saved scan once it has been brought up to date.
+type restoredMsg struct {
+	cache dirCache
+}
+
+func (m model) restoreCmd() tea.Cmd {
+	return func() tea.Msg {
+		return restoredMsg{cache: loadScanCache(m.progress, m.exclude.list())}
+	}
+}
@@ -0,0 +1,307 @@
+//go:build windows
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/elevate"
+	"github.com/winmole/winmole/internal/policy"
+	"github.com/winmole/winmole/internal/usage"
+	"github.com/winmole/winmole/internal/vss"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// V lists the space Volume Shadow Copies take on each volume: restore
+// points, previous versions of files and the copies some backup tools
+// make. They live outside any folder, so a scan of the drive never counts
+// them, and Windows lets them grow to a maximum that is often a tenth of
+// the volume. m sets a new maximum, after which Windows deletes the oldest
+// copies to fit, and o deletes the oldest copy, which may be the oldest
+// restore point. Everything goes through the elevated helper.
+
+// shadowView lists the volumes with shadow storage.
+type shadowView struct {
+	storage  []vss.Storage
+	loading  bool
+	running  bool // resizing or deleting
+	selected int
+	resize   bool   // typing the new maximum
+	input    string // typed so far
+	confirm  bool   // waiting for y to delete the oldest copy
+	changed  string // the volume just resized or trimmed
+	before   uint64 // what its copies used before
+}
+
+type shadowMsg struct {
+	storage []vss.Storage
+	err     error
+}
+
+type shadowChangedMsg struct {
+	action string // what was done, for the status line
+	volume string
+	err    error
+}
+
+func shadowCmd() tea.Cmd {
+	return func() tea.Msg {
+		out, err := elevate.RunAction(elevate.OpShadowStorage, nil)
+		if err != nil {
+			return shadowMsg{err: err}
+		}
+		var storage []vss.Storage
+		err = json.Unmarshal([]byte(out), &storage)
+		return shadowMsg{storage: storage, err: err}
+	}
+}
+
+func shadowResizeCmd(s vss.Storage, size string) tea.Cmd {
+	return func() tea.Msg {
+		_, err := elevate.RunAction(elevate.OpResizeShadowStorage, elevate.ShadowArgs{Volume: s.Volume, On: s.On, MaxSize: size})
+		return shadowChangedMsg{action: "Set the maximum to " + strings.ToUpper(size), volume: s.Volume, err: err}
+	}
+}
+
+func shadowDeleteOldestCmd(s vss.Storage) tea.Cmd {
+	return func() tea.Msg {
+		_, err := elevate.RunAction(elevate.OpDeleteOldestShadow, elevate.ShadowArgs{Volume: s.Volume})
+		return shadowChangedMsg{action: "Deleted the oldest shadow copy", volume: s.Volume, err: err}
+	}
+}
+
+// showShadows opens the view and reads the shadow storage.
+func (m model) showShadows() (tea.Model, tea.Cmd) {
+	usage.Run("analyze.shadows")
+	m.shadows = &shadowView{loading: true}
+	m.status = "Reading shadow copy storage (administrator)..."
+	return m, shadowCmd()
+}
+
+func (m model) applyShadows(msg shadowMsg) model {
+	v := m.shadows
+	if v == nil {
+		return m
+	}
+	v.loading = false
+	if msg.err != nil {
+		m.shadows = nil
+		m.status = fmt.Sprintf("Error: %v", msg.err)
+		return m
+	}
+	v.storage = msg.storage
+	v.selected = min(v.selected, max(len(v.storage)-1, 0))
+	if v.changed != "" {
+		usage.Freed("analyze.shadows", int64(v.before)-int64(v.used(v.changed)))
+		v.changed = ""
+	}
+	m.status = v.summary()
+	return m
+}
+
+func (m model) applyShadowChanged(msg shadowChangedMsg) (model, tea.Cmd) {
+	v := m.shadows
+	if v != nil {
+		v.running = false
+	}
+	if msg.err != nil {
+		m.status = fmt.Sprintf("Error: %v", msg.err)
+		return m, nil
+	}
+	traceAction("shadow-storage", msg.volume, m.redactor)
+	m.status = msg.action + " on " + msg.volume
+	if v == nil {
+		return m, nil
+	}
+	// Read again to show what is left and count what went.
+	v.changed, v.before = msg.volume, v.used(msg.volume)
+	v.loading = true
+	return m, shadowCmd()
+}
+
+// used is what the copies of volume took when last read.
+func (v *shadowView) used(volume string) uint64 {
+	for _, s := range v.storage {
+		if s.Volume == volume {
+			return s.Used
+		}
+	}
+	return 0
+}
+
+// summary totals the storage of every volume.
+func (v *shadowView) summary() string {
+	var used, allocated uint64
+	copies := 0
+	for _, s := range v.storage {
+		used += s.Used
+		allocated += s.Allocated
+		copies += s.Copies
+	}
+	return fmt.Sprintf("%s using %s (%s allocated) on %d volumes",
+		shadowCount(copies), humanize.Bytes(used), humanize.Bytes(allocated), len(v.storage))
+}
+
+// handleShadowsKey handles keys while the volumes are listed.
+func (m model) handleShadowsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.shadows
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+	if v.loading || v.running {
+		if v.loading && (msg.String() == "esc" || msg.String() == "V" || msg.String() == "q") {
+			m.shadows = nil
+			m.status = m.totalStatus()
+		}
+		return m, nil
+	}
+	if v.resize {
+		switch msg.Type {
+		case tea.KeyEsc:
+			v.resize, v.input = false, ""
+			m.status = v.summary()
+		case tea.KeyEnter:
+			if !vss.ValidMaxSize(v.input) {
+				m.status = "Type a share of the volume, a size or UNBOUNDED: 10%, 20GB, UNBOUNDED"
+				break
+			}
+			s := v.storage[v.selected]
+			v.resize, v.running = false, true
+			m.status = fmt.Sprintf("Setting the shadow storage maximum of %s to %s...", s.Volume, strings.ToUpper(v.input))
+			size := v.input
+			v.input = ""
+			return m, shadowResizeCmd(s, size)
+		case tea.KeyBackspace:
+			if r := []rune(v.input); len(r) > 0 {
+				v.input = string(r[:len(r)-1])
+			}
+		case tea.KeyRunes:
+			v.input += string(msg.Runes)
+		}
+		return m, nil
+	}
+	if v.confirm {
+		v.confirm = false
+		if msg.String() != "y" {
+			m.status = "Deletion cancelled"
+			return m, nil
+		}
+		s := v.storage[v.selected]
+		v.running = true
+		m.status = "Deleting the oldest shadow copy of " + s.Volume + "..."
+		return m, shadowDeleteOldestCmd(s)
+	}
+
+	switch msg.String() {
+	case "V", "esc", "q":
+		m.shadows = nil
+		m.status = m.totalStatus()
+		return m, nil
+	case "up", "k":
+		if v.selected > 0 {
+			v.selected--
+		}
+	case "down", "j":
+		if v.selected < len(v.storage)-1 {
+			v.selected++
+		}
+	case "m":
+		if len(v.storage) == 0 {
+			break
+		}
+		if s := v.storage[v.selected]; !isDriveLetter(s.Volume) || !isDriveLetter(s.On) {
+			m.status = "Only volumes with a drive letter can be resized here"
+			return m, nil
+		}
+		v.resize = true
+		return m, nil
+	case "o":
+		if len(v.storage) == 0 {
+			break
+		}
+		if policy.Get().DisableFileDeletion {
+			m.status = "Deleting shadow copies is " + policy.DisabledMessage
+			return m, nil
+		}
+		s := v.storage[v.selected]
+		if s.Copies == 0 {
+			m.status = s.Volume + " has no shadow copies"
+			return m, nil
+		}
+		if !isDriveLetter(s.Volume) {
+			m.status = "Only volumes with a drive letter can be changed here"
+			return m, nil
+		}
+		v.confirm = true
+		m.status = fmt.Sprintf("Delete the oldest shadow copy of %s, from %s? It may be a restore point. y to go ahead",
+			s.Volume, humanize.Time(s.Oldest))
+		return m, nil
+	case "r":
+		v.loading = true
+		m.status = "Reading shadow copy storage (administrator)..."
+		return m, shadowCmd()
+	}
+	m.status = v.summary()
+	return m, nil
+}
+
+func shadowCount(n int) string {
+	if n == 1 {
+		return "1 shadow copy"
+	}
+	return fmt.Sprintf("%d shadow copies", n)
+}
+
+// isDriveLetter reports whether volume is a drive such as "C:".
+func isDriveLetter(volume string) bool {
+	return len(volume) == 2 && volume[1] == ':'
+}
+
+// renderShadows lists the volumes, or the maximum being typed.
+func (m model) renderShadows() string {
+	v := m.shadows
+	if v.storage == nil && v.loading {
+		return dimStyle.Render("  Asking the Volume Shadow Copy service...") + "\n"
+	}
+	if len(v.storage) == 0 {
+		return dimStyle.Render("  (no volume has shadow copy storage)") + "\n"
+	}
+	var b strings.Builder
+	for i, s := range v.storage {
+		limit := "no maximum"
+		if s.Max > 0 {
+			limit = "max " + humanize.Bytes(s.Max)
+			if s.Capacity > 0 {
+				limit += fmt.Sprintf(" (%.0f%%)", float64(s.Max)*100/float64(s.Capacity))
+			}
+		}
+		on := ""
+		if !strings.EqualFold(s.On, s.Volume) {
+			on = " on " + s.On
+		}
+		line := fmt.Sprintf("%-4s%s  %s allocated, %s • %s", s.Volume, on, humanize.Bytes(s.Allocated), limit, shadowCount(s.Copies))
+		if s.Copies > 0 {
+			line += " • oldest " + humanize.Date(s.Oldest)
+		}
+		size := sizeStyle.Render(fmt.Sprintf("%10s", humanize.Bytes(s.Used)))
+		if i == v.selected {
+			b.WriteString(size + " " + selectedStyle.Render(line))
+		} else {
+			b.WriteString(size + " " + normalStyle.Render(line))
+		}
+		b.WriteString("\n")
+	}
+	if v.resize {
+		b.WriteString("\n")
+		b.WriteString(normalStyle.Render(fmt.Sprintf("  New maximum for %s (10%%, 20GB or UNBOUNDED):", v.storage[v.selected].Volume)))
+		b.WriteString("\n")
+		b.WriteString(normalStyle.Render("  > " + v.input + "█"))
+		b.WriteString("\n")
+		b.WriteString(dimStyle.Render("  Below what the copies use, Windows deletes the oldest to fit • Enter set • Esc cancel"))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,442 @@
+//go:build windows
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/config"
+	"github.com/winmole/winmole/internal/usage"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// S saves what has been scanned below the current folder as a snapshot,
+// and c compares the current folder against one: every file and folder
+// that grew, shrank, appeared or went since, biggest change first, which
+// answers "what grew 30 GB since last month?". C picks the snapshot to
+// compare with. Snapshots are plain JSON exports, so --baseline takes them
+// too, and only folders listed on both sides are compared: one the
+// snapshot never opened has no sizes inside it to compare with.
+
+// snapshotInfo describes a saved snapshot.
+type snapshotInfo struct {
+	path      string
+	root      string
+	scannedAt time.Time
+	totalSize int64
+}
+
+// snapshotPicker is the list of snapshots to compare with.
+type snapshotPicker struct {
+	list     []snapshotInfo // newest first
+	selected int
+	loading  bool
+	err      error
+}
+
+type snapshotsMsg struct {
+	list []snapshotInfo
+	err  error
+}
+
+// diffRow is one file or folder that changed since the snapshot.
+type diffRow struct {
+	entry  Entry // as scanned now, or as in the snapshot when gone
+	before int64
+	after  int64
+	status string // "new", "gone" or ""
+}
+
+// diffView lists what changed below the current folder.
+type diffView struct {
+	rows     []diffRow // biggest change first
+	selected int
+	offset   int
+}
+
+// snapshotDir holds the snapshots saved with S.
+func snapshotDir() string {
+	return filepath.Join(config.CacheDir(), "snapshots")
+}
+
+// saveSnapshot writes the scanned tree below the current folder to a new
+// snapshot and returns its path.
+func (m model) saveSnapshot() (string, error) {
+	return writeSnapshot(m.cache, m.path, 0)
+}
+
+// writeSnapshot saves the tree below root as a new snapshot, then deletes
+// all but the keep newest snapshots of root (0 keeps them all). Paths are
+// never redacted, or they would not match the next scan.
+func writeSnapshot(c dirCache, root string, keep int) (string, error) {
+	dir := snapshotDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create snapshot directory: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.json", snapshotLabel(root), time.Now().Format("20060102-150405")))
+	if err := writeExport(path, c, root, nil); err != nil {
+		return "", err
+	}
+	if keep > 0 {
+		pruneSnapshots(root, keep)
+	}
+	return path, nil
+}
+
+// pruneSnapshots deletes all but the keep newest snapshots of root, so a
+// scheduled scan does not fill the disk it watches.
+func pruneSnapshots(root string, keep int) {
+	files, err := filepath.Glob(filepath.Join(snapshotDir(), snapshotLabel(root)+"-*.json"))
+	if err != nil {
+		return
+	}
+	var mine []snapshotInfo
+	for _, file := range files {
+		s, err := readSnapshotInfo(file)
+		if err == nil && cacheKey(s.root) == cacheKey(root) {
+			mine = append(mine, s)
+		}
+	}
+	sort.Slice(mine, func(i, j int) bool { return mine[i].scannedAt.After(mine[j].scannedAt) })
+	for _, s := range mine[min(keep, len(mine)):] {
+		os.Remove(s.path)
+	}
+}
+
+// latestSnapshot returns the newest saved snapshot of root.
+func latestSnapshot(root string) (snapshotInfo, bool) {
+	files, err := filepath.Glob(filepath.Join(snapshotDir(), snapshotLabel(root)+"-*.json"))
+	if err != nil {
+		return snapshotInfo{}, false
+	}
+	var latest snapshotInfo
+	for _, file := range files {
+		s, err := readSnapshotInfo(file)
+		if err == nil && cacheKey(s.root) == cacheKey(root) && s.scannedAt.After(latest.scannedAt) {
+			latest = s
+		}
+	}
+	return latest, latest.path != ""
+}
+
+// readSnapshotInfo reads the fields before a snapshot's entries, without
+// parsing the entries themselves.
+func readSnapshotInfo(file string) (snapshotInfo, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return snapshotInfo{}, err
+	}
+	defer f.Close()
+	s := snapshotInfo{path: file}
+	dec := json.NewDecoder(f)
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return s, fmt.Errorf("%s: not a snapshot", filepath.Base(file))
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return s, err
+		}
+		switch t {
+		case "root":
+			err = dec.Decode(&s.root)
+		case "totalSize":
+			err = dec.Decode(&s.totalSize)
+		case "scannedAt":
+			err = dec.Decode(&s.scannedAt)
+		default:
+			// The entries come last; everything needed is read by then.
+			if s.root == "" {
+				return s, fmt.Errorf("%s: not a snapshot", filepath.Base(file))
+			}
+			return s, nil
+		}
+		if err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+// snapshotLabel names a snapshot file after the folder: "C" for C:\,
+// otherwise the folder's name.
+func snapshotLabel(path string) string {
+	name := filepath.Base(path)
+	if vol := filepath.VolumeName(path); len(path) <= len(vol)+1 {
+		name = strings.TrimSuffix(vol, ":")
+	}
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`\/:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" {
+		return "snapshot"
+	}
+	return name
+}
+
+// snapshotsCmd lists the saved snapshots whose root is the current folder
+// or a folder above or below it.
+func (m model) snapshotsCmd() tea.Cmd {
+	current := cacheKey(m.path)
+	return func() tea.Msg {
+		files, err := filepath.Glob(filepath.Join(snapshotDir(), "*.json"))
+		if err != nil {
+			return snapshotsMsg{err: err}
+		}
+		var list []snapshotInfo
+		for _, file := range files {
+			s, err := readSnapshotInfo(file)
+			if err != nil {
+				continue
+			}
+			root := cacheKey(s.root)
+			if root != current && !isUnder(current, root) && !isUnder(root, current) {
+				continue
+			}
+			list = append(list, s)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].scannedAt.After(list[j].scannedAt) })
+		return snapshotsMsg{list: list}
+	}
+}
+
+// openSnapshots shows the snapshot list.
+func (m model) openSnapshots() (tea.Model, tea.Cmd) {
+	m.snapshots = &snapshotPicker{loading: true}
+	return m, m.snapshotsCmd()
+}
+
+func (m model) applySnapshots(msg snapshotsMsg) model {
+	if m.snapshots == nil {
+		return m
+	}
+	m.snapshots.loading = false
+	m.snapshots.list, m.snapshots.err = msg.list, msg.err
+	m.snapshots.selected = 0
+	return m
+}
+
+// handleSnapshotsKey handles keys while the snapshot list is shown.
+func (m model) handleSnapshotsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.snapshots
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc", "q", "C":
+		m.snapshots = nil
+	case "up", "k":
+		p.selected = max(p.selected-1, 0)
+	case "down", "j":
+		p.selected = min(p.selected+1, max(len(p.list)-1, 0))
+	case "enter":
+		if p.selected >= len(p.list) {
+			break
+		}
+		m.snapshots = nil
+		return m.compareWith(p.list[p.selected])
+	}
+	return m, nil
+}
+
+// compareWith makes the snapshot the baseline and lists what changed since.
+func (m model) compareWith(s snapshotInfo) (tea.Model, tea.Cmd) {
+	b, err := loadBaseline(s.path)
+	if err != nil {
+		m.status = fmt.Sprintf("Cannot read the snapshot: %v", err)
+		return m, nil
+	}
+	b.name = "snapshot of " + humanize.Time(s.scannedAt)
+	m.baseline = b
+	usage.Run("analyze.diff")
+	return m.showDiff(), nil
+}
+
+func (m model) renderSnapshots() string {
+	p := m.snapshots
+	var b strings.Builder
+	switch {
+	case p.err != nil:
+		b.WriteString(warnStyle.Render(fmt.Sprintf("  Cannot list the snapshots: %v", p.err)))
+		b.WriteString("\n")
+		return b.String()
+	case p.loading:
+		b.WriteString(dimStyle.Render("  Reading the snapshots..."))
+		b.WriteString("\n")
+		return b.String()
+	case len(p.list) == 0:
+		b.WriteString(dimStyle.Render("  No snapshots of this folder yet; S saves one"))
+		b.WriteString("\n")
+		return b.String()
+	}
+	for i, s := range p.list {
+		// Relative times already say the age.
+		age := ""
+		if humanize.CurrentTimes() == humanize.Absolute {
+			age = formatAge(time.Since(s.scannedAt)) + " ago"
+		}
+		line := fmt.Sprintf("  %-17s %-10s %s %s", humanize.Time(s.scannedAt), age, sizeStyle.Render(humanize.Bytes(s.totalSize)), s.root)
+		if i == p.selected {
+			b.WriteString(selectedStyle.Render(line))
+		} else {
+			b.WriteString(normalStyle.Render(line))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// showDiff compares the current folder with the baseline.
+func (m model) showDiff() model {
+	m.diff = &diffView{rows: m.diffRows()}
+	if len(m.diff.rows) == 0 {
+		m.status = "No changes since the " + m.baseline.name
+		return m
+	}
+	var net int64
+	for _, r := range m.diff.rows {
+		if cacheKey(filepath.Dir(r.entry.Path)) == cacheKey(m.path) {
+			net += r.after - r.before
+		}
+	}
+	m.status = fmt.Sprintf("%d changes since the %s • %s overall", len(m.diff.rows), m.baseline.name, signedBytes(net))
+	return m
+}
+
+// diffRows lists what changed below the current folder, in the folders
+// listed both now and in the baseline.
+func (m model) diffRows() []diffRow {
+	return treeDiff(m.baseline, m.cache, m.path)
+}
+
+// treeDiff lists what changed below root between b and c, biggest change
+// first, in the folders listed in both.
+func treeDiff(b *baseline, c dirCache, root string) []diffRow {
+	var rows []diffRow
+	var walk func(dir string)
+	walk = func(dir string) {
+		before, ok := b.tree[cacheKey(dir)]
+		now, scanned := c[cacheKey(dir)]
+		if !ok || !scanned {
+			return
+		}
+		seen := make(map[string]bool, len(now.entries))
+		for _, e := range now.entries {
+			key := cacheKey(e.Path)
+			seen[key] = true
+			size, had := b.sizes[key]
+			if e.Size != size {
+				r := diffRow{entry: e, before: size, after: e.Size}
+				if !had {
+					r.status = "new"
+				}
+				rows = append(rows, r)
+			}
+			if e.IsDir {
+				walk(e.Path)
+			}
+		}
+		for _, e := range before.entries {
+			if !seen[cacheKey(e.Path)] && e.Size > 0 {
+				rows = append(rows, diffRow{entry: e, before: e.Size, status: "gone"})
+			}
+		}
+	}
+	walk(root)
+	sort.SliceStable(rows, func(i, j int) bool {
+		return abs(rows[i].after-rows[i].before) > abs(rows[j].after-rows[j].before)
+	})
+	return rows
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// handleDiffKey handles keys while the changes are listed.
+func (m model) handleDiffKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.diff
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "c", "esc", "q":
+		m.diff = nil
+		m.status = m.totalStatus()
+	case "C":
+		m.diff = nil
+		return m.openSnapshots()
+	case "up", "k":
+		if v.selected > 0 {
+			v.selected--
+			v.offset = min(v.offset, v.selected)
+		}
+	case "down", "j":
+		if v.selected < len(v.rows)-1 {
+			v.selected++
+			if h := m.viewportHeight(); v.selected >= v.offset+h {
+				v.offset = v.selected - h + 1
+			}
+		}
+	case "y":
+		if len(v.rows) > 0 {
+			m = m.copyPath(v.rows[v.selected].entry)
+		}
+	case "enter", "right", "l":
+		if len(v.rows) == 0 {
+			break
+		}
+		e := v.rows[v.selected].entry
+		m.diff = nil
+		m.history = append(m.history, historyEntry{
+			Path:     m.path,
+			Selected: m.selected,
+			Offset:   m.offset,
+		})
+		m.path = filepath.Dir(e.Path)
+		m.selected, m.offset = 0, 0
+		m.focus = e.Path
+		return m.load()
+	}
+	return m, nil
+}
+
+func (m model) renderDiff() string {
+	v := m.diff
+	if len(v.rows) == 0 {
+		return dimStyle.Render("  (no changes)") + "\n"
+	}
+	var b strings.Builder
+	end := min(v.offset+m.viewportHeight(), len(v.rows))
+	for i := v.offset; i < end; i++ {
+		r := v.rows[i]
+		rel, err := filepath.Rel(m.path, r.entry.Path)
+		if err != nil {
+			rel = r.entry.Path
+		}
+		delta := r.after - r.before
+		line := fmt.Sprintf("%10s  %10s → %-10s %-5s %s %s", signedBytes(delta), humanize.Bytes(r.before), humanize.Bytes(r.after),
+			r.status, m.icons.icon(r.entry, m.categories), rel)
+		switch {
+		case i == v.selected:
+			b.WriteString(selectedStyle.Render(line))
+		case delta < 0:
+			b.WriteString(shrunkStyle.Render(line))
+		default:
+			b.WriteString(grownStyle.Render(line))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,409 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/usage"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/scan"
+	"golang.org/x/sys/windows/registry"
+)
+
+// g lists the files below the current folder that have not changed in a
+// given number of days, largest first: forgotten downloads, old disk
+// images, installers kept "just in case". +/- step through the ages, and
+// m switches to files neither changed nor read in that time, which needs
+// Windows to record when files are read; NTFS does so lazily, and not at
+// all on volumes where last-access updates are turned off. Space selects
+// files and d recycles them.
+
+// staleMinSize is the smallest file listed; below it there is little to win.
+const staleMinSize = 1 << 20
+
+// staleAges are the ages +/- step through, in days.
+var staleAges = []int{30, 90, 180, 365, 730, 1825}
+
+// staleView lists the files of one folder not touched in days.
+type staleView struct {
+	files    []Entry // every file of staleMinSize or more, largest first
+	shown    []Entry // those older than days
+	days     int
+	unused   bool // by the last read as well as the last change
+	readsOff bool // Windows does not record reads; see accessUpdatesOff
+	chosen   map[string]bool
+	selected int
+	offset   int
+}
+
+type staleResultMsg struct {
+	path  string
+	files []Entry
+	err   error
+}
+
+func (m model) staleCmd() tea.Cmd {
+	return func() tea.Msg {
+		files, err := staleFiles(context.Background(), m.path, m.progress)
+		return staleResultMsg{path: m.path, files: files, err: err}
+	}
+}
+
+// staleFiles collects the files of staleMinSize or more below path with
+// their times, from the MFT when it can be read and by walking otherwise.
+// Cloud placeholders and links are left out; recycling them frees nothing.
+func staleFiles(ctx context.Context, path string, progress *scan.Counters) ([]Entry, error) {
+	var files []Entry
+	fromMFT := false
+	if vol := mftVolume(path); vol != "" && progress.Exclude == nil {
+		if idx, err := loadMFTIndex(ctx, vol, progress); err == nil {
+			fromMFT = idx.eachNode(path, func(dir string, n *mftNode) {
+				if n.size < staleMinSize || n.reparse || n.remote {
+					return
+				}
+				e := Entry{Name: n.name, Path: filepath.Join(dir, n.name), Size: n.size, Alloc: n.alloc, Files: 1}
+				e.ModTime, e.AccessTime = n.times()
+				files = append(files, e)
+			}) == nil
+		}
+	}
+	if !fromMFT {
+		files = nil
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+		filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return nil // Skip errors
+			}
+			if p != path && d.Type()&(fs.ModeSymlink|fs.ModeIrregular) != 0 && scan.LinkTarget(p) != "" {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if p != path && progress.Exclude != nil && progress.Exclude(p, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				progress.Dirs.Add(1)
+				return nil
+			}
+			progress.Files.Add(1)
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			progress.Bytes.Add(info.Size())
+			if info.Size() < staleMinSize || onlineOnly(info) {
+				return nil
+			}
+			files = append(files, Entry{
+				Name:       d.Name(),
+				Path:       p,
+				Size:       info.Size(),
+				Alloc:      info.Size(),
+				Files:      1,
+				ModTime:    info.ModTime(),
+				AccessTime: scan.AccessTime(info),
+			})
+			return nil
+		})
+	}
+	scan.SortBySize(files)
+	return files, nil
+}
+
+// onlineOnly reports whether info is a cloud placeholder not fully on disk.
+func onlineOnly(info fs.FileInfo) bool {
+	if d, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		return d.FileAttributes&placeholderAttributes != 0
+	}
+	return false
+}
+
+// lastUsed is when e was last changed or, if later, read.
+func lastUsed(e Entry) time.Time {
+	if e.AccessTime.After(e.ModTime) {
+		return e.AccessTime
+	}
+	return e.ModTime
+}
+
+// accessUpdatesOff reports whether Windows has been told not to record when
+// files are read, so last-read times say nothing.
+func accessUpdatesOff() bool {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control\FileSystem`, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	defer key.Close()
+	v, _, err := key.GetIntegerValue("NtfsDisableLastAccessUpdate")
+	return err == nil && v&1 != 0
+}
+
+// showStale starts collecting the files for the view.
+func (m model) showStale() (tea.Model, tea.Cmd) {
+	if m.imported != "" {
+		m.status = "File ages are not part of imported reports"
+		return m, nil
+	}
+	m.scanning = true
+	m.status = "Finding old files..."
+	m.progress.Files.Store(0)
+	m.progress.Dirs.Store(0)
+	m.progress.Bytes.Store(0)
+	return m, tea.Batch(m.staleCmd(), tickCmd())
+}
+
+func (m model) applyStale(msg staleResultMsg) model {
+	usage.Run("analyze.stale")
+	days := m.staleDays
+	if days <= 0 {
+		days = defaultConfig().StaleDays
+	}
+	m.stale = &staleView{files: msg.files, days: days, readsOff: accessUpdatesOff(), chosen: make(map[string]bool)}
+	m.stale.refilter()
+	m.status = m.stale.summary()
+	return m
+}
+
+// refilter lists the files older than v.days again, keeping the selection
+// on screen.
+func (v *staleView) refilter() {
+	cutoff := time.Now().AddDate(0, 0, -v.days)
+	v.shown = v.shown[:0]
+	for _, e := range v.files {
+		t := e.ModTime
+		if v.unused {
+			t = lastUsed(e)
+		}
+		if !t.IsZero() && t.Before(cutoff) {
+			v.shown = append(v.shown, e)
+		}
+	}
+	v.selected = min(v.selected, max(len(v.shown)-1, 0))
+	v.offset = min(v.offset, v.selected)
+}
+
+// summary is the status line of the view.
+func (v *staleView) summary() string {
+	var total int64
+	for _, e := range v.shown {
+		total += e.Size
+	}
+	what := "changed"
+	if v.unused {
+		what = "changed or read"
+	}
+	s := fmt.Sprintf("%s not %s in %s • %s", itemCount(len(v.shown)), what, staleAge(v.days), humanize.Bytes(total))
+	if chosen := v.chosenFiles(); len(chosen) > 0 {
+		var size int64
+		for _, e := range chosen {
+			size += e.Size
+		}
+		s += fmt.Sprintf(" • %d selected, %s", len(chosen), humanize.Bytes(size))
+	}
+	if v.unused && v.readsOff {
+		s += " • last-read times are not being recorded on this PC"
+	}
+	return s
+}
+
+// staleAge names a number of days.
+func staleAge(days int) string {
+	switch {
+	case days >= 365 && days%365 == 0:
+		if days == 365 {
+			return "a year"
+		}
+		return fmt.Sprintf("%d years", days/365)
+	case days >= 30 && days%30 == 0:
+		if days == 30 {
+			return "a month"
+		}
+		return fmt.Sprintf("%d months", days/30)
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+// chosenFiles returns the selected files still shown, largest first.
+func (v *staleView) chosenFiles() []Entry {
+	var files []Entry
+	for _, e := range v.shown {
+		if v.chosen[cacheKey(e.Path)] {
+			files = append(files, e)
+		}
+	}
+	return files
+}
+
+// without drops the files a batch removed.
+func (v *staleView) without(done []Entry) {
+	gone := make(map[string]bool, len(done))
+	for _, e := range done {
+		gone[cacheKey(e.Path)] = true
+		delete(v.chosen, cacheKey(e.Path))
+	}
+	files := v.files[:0]
+	for _, e := range v.files {
+		if !gone[cacheKey(e.Path)] {
+			files = append(files, e)
+		}
+	}
+	v.files = files
+	v.refilter()
+}
+
+// nextAge returns the preset after days, or before it when older is false.
+func nextAge(days int, older bool) int {
+	if older {
+		for _, d := range staleAges {
+			if d > days {
+				return d
+			}
+		}
+		return days
+	}
+	for i := len(staleAges) - 1; i >= 0; i-- {
+		if staleAges[i] < days {
+			return staleAges[i]
+		}
+	}
+	return days
+}
+
+// handleStaleKey handles keys while the old files are listed.
+func (m model) handleStaleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.stale
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "g", "esc", "q":
+		m.stale = nil
+		m.status = m.totalStatus()
+		return m, nil
+	case "up", "k":
+		if v.selected > 0 {
+			v.selected--
+			v.offset = min(v.offset, v.selected)
+		}
+	case "down", "j":
+		if v.selected < len(v.shown)-1 {
+			v.selected++
+			if h := m.viewportHeight(); v.selected >= v.offset+h {
+				v.offset = v.selected - h + 1
+			}
+		}
+	case "+", "=":
+		v.days = nextAge(v.days, true)
+		v.refilter()
+	case "-":
+		v.days = nextAge(v.days, false)
+		v.refilter()
+	case "m":
+		v.unused = !v.unused
+		v.refilter()
+	case " ":
+		if len(v.shown) == 0 {
+			break
+		}
+		key := cacheKey(v.shown[v.selected].Path)
+		if v.chosen[key] {
+			delete(v.chosen, key)
+		} else {
+			v.chosen[key] = true
+		}
+		if v.selected < len(v.shown)-1 {
+			v.selected++
+			if h := m.viewportHeight(); v.selected >= v.offset+h {
+				v.offset = v.selected - h + 1
+			}
+		}
+	case "d":
+		files := v.chosenFiles()
+		if len(files) == 0 {
+			m.status = "Space selects the files to recycle"
+			return m, nil
+		}
+		return m.confirmBatch(batchRecycle, files), nil
+	case "y":
+		if len(v.shown) > 0 {
+			m = m.copyPath(v.shown[v.selected])
+		}
+		return m, nil
+	case "o", "O":
+		if len(v.shown) > 0 {
+			m = m.openAction(v.shown[v.selected], msg.String() == "O")
+		}
+		return m, nil
+	case "enter", "right", "l":
+		if len(v.shown) == 0 {
+			break
+		}
+		file := v.shown[v.selected]
+		traceAction("jump", file.Path, m.redactor)
+		m.stale = nil
+		m.history = append(m.history, historyEntry{
+			Path:     m.path,
+			Selected: m.selected,
+			Offset:   m.offset,
+		})
+		m.path = filepath.Dir(file.Path)
+		m.selected, m.offset = 0, 0
+		m.focus = file.Path
+		return m.load()
+	}
+	m.status = v.summary()
+	return m, nil
+}
+
+// renderStale lists the files with their size, age and path; ✓ marks the
+// files selected.
+func (m model) renderStale() string {
+	v := m.stale
+	if len(v.shown) == 0 {
+		return dimStyle.Render(fmt.Sprintf("  (no files of %s or more left untouched that long; - shortens the age)", humanize.Bytes(staleMinSize))) + "\n"
+	}
+	var b strings.Builder
+	end := min(v.offset+m.viewportHeight(), len(v.shown))
+	for i := v.offset; i < end; i++ {
+		e := v.shown[i]
+		t := e.ModTime
+		if v.unused {
+			t = lastUsed(e)
+		}
+		rel, err := filepath.Rel(m.path, e.Path)
+		if err != nil {
+			rel = e.Path
+		}
+		check := " "
+		if v.chosen[cacheKey(e.Path)] {
+			check = "✓"
+		}
+		line := fmt.Sprintf("%s %-12s %s %s", check, humanize.Date(t), m.icons.icon(e, m.categories), rel)
+		size := sizeStyle.Render(fmt.Sprintf("%10s", humanize.Bytes(e.Size)))
+		switch {
+		case i == v.selected:
+			b.WriteString(size + " " + selectedStyle.Render(line))
+		case check != " ":
+			b.WriteString(size + " " + warnStyle.Render(line))
+		default:
+			b.WriteString(size + " " + normalStyle.Render(line))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
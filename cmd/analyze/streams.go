@@ -0,0 +1,144 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/scan"
+)
+
+// NTFS files can carry alternate data streams beside their contents:
+// Zone.Identifier on downloads, thumbnails and metadata some programs
+// keep, and now and then gigabytes hidden on purpose. Explorer neither
+// shows nor counts them, and neither does a scan by default, since
+// listing them means opening every file. F counts them in the sizes and
+// scans the current folder again, and rows then say how much of their
+// size is in streams; : lists the streams of the selected file or folder.
+// The MFT engine does not read them, so counting them uses the directory
+// walker.
+
+// streamView lists the streams of one entry.
+type streamView struct {
+	entry    Entry
+	streams  []scan.Stream
+	total    int64
+	selected int
+}
+
+// streamSuffix notes how much of e is in streams, after its name, when
+// they were counted.
+func streamSuffix(e Entry) string {
+	if e.Streams == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%s in streams)", humanize.Bytes(e.Streams))
+}
+
+// toggleStreams switches counting alternate data streams and rescans the
+// current folder.
+func (m model) toggleStreams() (tea.Model, tea.Cmd) {
+	if m.imported != "" {
+		m.status = "Streams are only counted in folders scanned on this machine"
+		return m, nil
+	}
+	m.streams = !m.streams
+	if len(m.entries) > 0 {
+		m.focus = m.entries[m.selected].Path
+	}
+	return m.load()
+}
+
+// showStreams opens the stream list of e.
+func (m model) showStreams(e Entry) (tea.Model, tea.Cmd) {
+	if m.imported != "" {
+		m.status = "Streams are only read on this machine"
+		return m, nil
+	}
+	streams, err := scan.Streams(e.Path)
+	if err != nil {
+		m.status = fmt.Sprintf("Cannot list the streams of %s: %v", e.Name, err)
+		return m, nil
+	}
+	sort.Slice(streams, func(i, j int) bool { return streams[i].Size > streams[j].Size })
+	v := &streamView{entry: e, streams: streams}
+	for _, s := range streams {
+		v.total += s.Size
+	}
+	m.streamList = v
+	m.status = v.summary()
+	return m, nil
+}
+
+func (v *streamView) summary() string {
+	if len(v.streams) == 0 {
+		return v.entry.Name + " has no alternate data streams"
+	}
+	n := "1 stream"
+	if len(v.streams) != 1 {
+		n = fmt.Sprintf("%d streams", len(v.streams))
+	}
+	return fmt.Sprintf("%s has %s, %s", v.entry.Name, n, humanize.Bytes(v.total))
+}
+
+// handleStreamsKey scrolls the list; : or Esc closes it.
+func (m model) handleStreamsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.streamList
+	switch msg.String() {
+	case ":", "esc", "q":
+		m.streamList = nil
+		m.status = m.totalStatus()
+	case "up", "k":
+		if v.selected > 0 {
+			v.selected--
+		}
+	case "down", "j":
+		if v.selected < len(v.streams)-1 {
+			v.selected++
+		}
+	case "y":
+		// As file:stream, the way more < and Notepad open a stream.
+		if len(v.streams) > 0 {
+			m = m.copyPath(Entry{Path: v.entry.Path + ":" + v.streams[v.selected].Name})
+		}
+	}
+	return m, nil
+}
+
+// renderStreams lists the entry's streams, largest first.
+func (m model) renderStreams() string {
+	v := m.streamList
+	var b strings.Builder
+	b.WriteString(normalStyle.Render("  " + v.entry.Path))
+	b.WriteString("\n\n")
+	if len(v.streams) == 0 {
+		b.WriteString(dimStyle.Render("  (no alternate data streams)"))
+		b.WriteString("\n")
+	}
+	h := max(m.viewportHeight()-4, 1)
+	start := max(v.selected-h+1, 0)
+	for i, s := range v.streams {
+		if i < start || i >= start+h {
+			continue
+		}
+		line := sizeStyle.Render(fmt.Sprintf("%10s", humanize.Bytes(s.Size))) + " " + normalStyle.Render(":"+s.Name)
+		if i == v.selected {
+			line = selectedStyle.Render(fmt.Sprintf("%10s :%s", humanize.Bytes(s.Size), s.Name))
+		}
+		b.WriteString(line + "\n")
+	}
+	if v.entry.IsDir && v.entry.Streams > 0 {
+		b.WriteString("\n")
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  Files below hold %s in streams", humanize.Bytes(v.entry.Streams))))
+		b.WriteString("\n")
+	} else if v.entry.IsDir && !m.streams {
+		b.WriteString("\n")
+		b.WriteString(dimStyle.Render("  F counts the streams of the files below in the sizes"))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
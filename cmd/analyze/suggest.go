@@ -0,0 +1,360 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/usage"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/scan"
+	"golang.org/x/sys/windows"
+)
+
+// R lists the places Windows and common apps leave space that can be taken
+// back, whichever folder is shown: temp folders, browser caches, crash
+// dumps, the thumbnail cache and a previous Windows installation. Each one
+// is measured, and Enter cleans the selected one after asking. Cleaning
+// deletes for good rather than recycling, since the point is the space;
+// files in use are skipped, and temp files younger than a day are left for
+// the installers that may still need them. Windows.old belongs to
+// TrustedInstaller, so for it Enter opens Disk Cleanup instead.
+
+// suggestion is one reclaimable location.
+type suggestion struct {
+	name   string
+	about  string
+	paths  []string      // what cleaning removes: folders emptied, files deleted
+	minAge time.Duration // files changed more recently are kept
+	admin  bool          // only an elevated analyzer can clean it
+	tool   []string      // a command that cleans it instead, if any
+	size   int64
+	files  int64
+}
+
+// suggestView is the list of suggestions.
+type suggestView struct {
+	items    []suggestion
+	loading  bool
+	selected int
+	confirm  bool // waiting for y to clean the selected one
+	cleaning bool
+}
+
+type suggestMsg struct {
+	items []suggestion
+}
+
+type cleanedMsg struct {
+	index int
+	item  suggestion // measured again
+	freed int64
+	err   error
+}
+
+// suggestions finds the reclaimable locations that exist on this machine.
+func suggestions() []suggestion {
+	local := os.Getenv("LOCALAPPDATA")
+	systemRoot := os.Getenv("SystemRoot")
+	var items []suggestion
+	add := func(s suggestion) {
+		var found []string
+		for _, p := range s.paths {
+			if _, err := os.Stat(p); err == nil {
+				found = append(found, p)
+			}
+		}
+		if len(found) > 0 {
+			s.paths = found
+			items = append(items, s)
+		}
+	}
+
+	add(suggestion{name: "Your temp folder", about: "files apps left behind", paths: []string{filepath.Clean(os.TempDir())}, minAge: 24 * time.Hour})
+	add(suggestion{name: "Windows temp folder", about: "files services and installers left behind", paths: []string{filepath.Join(systemRoot, "Temp")}, minAge: 24 * time.Hour, admin: true})
+
+	browsers := []struct{ name, dir string }{
+		{"Chrome", `Google\Chrome\User Data`},
+		{"Edge", `Microsoft\Edge\User Data`},
+		{"Brave", `BraveSoftware\Brave-Browser\User Data`},
+	}
+	for _, b := range browsers {
+		var paths []string
+		profiles, _ := filepath.Glob(filepath.Join(local, b.dir, "*"))
+		for _, p := range profiles {
+			if name := filepath.Base(p); name == "Default" || strings.HasPrefix(name, "Profile ") {
+				for _, cache := range []string{"Cache", "Code Cache", "GPUCache"} {
+					paths = append(paths, filepath.Join(p, cache))
+				}
+			}
+		}
+		add(suggestion{name: b.name + " cache", about: "downloaded again as pages are visited", paths: paths})
+	}
+	firefox, _ := filepath.Glob(filepath.Join(local, `Mozilla\Firefox\Profiles\*\cache2`))
+	add(suggestion{name: "Firefox cache", about: "downloaded again as pages are visited", paths: firefox})
+
+	add(suggestion{name: "App crash dumps", about: "memory dumps of apps that crashed", paths: []string{filepath.Join(local, "CrashDumps")}})
+	add(suggestion{name: "System crash dumps", about: "memory dumps from blue screens", paths: []string{
+		filepath.Join(systemRoot, "MEMORY.DMP"),
+		filepath.Join(systemRoot, "Minidump"),
+		filepath.Join(systemRoot, "LiveKernelReports"),
+	}, admin: true})
+
+	thumbs, _ := filepath.Glob(filepath.Join(local, `Microsoft\Windows\Explorer\thumbcache_*.db`))
+	add(suggestion{name: "Thumbnail cache", about: "rebuilt as Explorer shows pictures; files Explorer holds are skipped", paths: thumbs})
+
+	drive := os.Getenv("SystemDrive")
+	add(suggestion{name: "Previous Windows installation", about: "Windows.old, kept to roll back an upgrade", paths: []string{drive + `\Windows.old`},
+		tool: []string{"cleanmgr.exe", "/d", drive}})
+	return items
+}
+
+// suggestCmd measures the suggestions, several at once.
+func suggestCmd() tea.Cmd {
+	return func() tea.Msg {
+		items := suggestions()
+		var wg sync.WaitGroup
+		for i := range items {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				items[i].measure()
+			}()
+		}
+		wg.Wait()
+		sort.SliceStable(items, func(i, j int) bool { return items[i].size > items[j].size })
+		return suggestMsg{items: items}
+	}
+}
+
+// measure totals what cleaning s would remove.
+func (s *suggestion) measure() {
+	s.size, s.files = 0, 0
+	for _, p := range s.paths {
+		t := scan.Tree(context.Background(), p, nil)
+		s.size += t.Size
+		s.files += t.Files
+	}
+}
+
+// clean removes what s covers: the contents of its folders and its files,
+// keeping files newer than s.minAge and anything that cannot be removed.
+func (s *suggestion) clean() error {
+	cutoff := time.Now().Add(-s.minAge)
+	for _, p := range s.paths {
+		if reason := deleteBlocked(Entry{Name: filepath.Base(p), Path: p}); reason != "" {
+			return fmt.Errorf("%s", reason)
+		}
+	}
+	for _, p := range s.paths {
+		info, err := os.Lstat(p)
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			os.Remove(p)
+			continue
+		}
+		children, err := os.ReadDir(p)
+		if err != nil {
+			continue
+		}
+		for _, c := range children {
+			child := filepath.Join(p, c.Name())
+			if s.minAge > 0 {
+				if info, err := c.Info(); err != nil || info.ModTime().After(cutoff) {
+					continue
+				}
+			}
+			os.RemoveAll(child) // in-use files stay behind
+		}
+	}
+	return nil
+}
+
+func cleanCmd(index int, s suggestion) tea.Cmd {
+	return func() tea.Msg {
+		before := s.size
+		if err := s.clean(); err != nil {
+			return cleanedMsg{index: index, item: s, err: err}
+		}
+		s.measure()
+		return cleanedMsg{index: index, item: s, freed: before - s.size}
+	}
+}
+
+// openSuggestions measures the suggestions and shows them.
+func (m model) openSuggestions() (tea.Model, tea.Cmd) {
+	usage.Run("analyze.suggestions")
+	m.suggest = &suggestView{loading: true}
+	m.status = "Measuring the usual places..."
+	return m, suggestCmd()
+}
+
+func (m model) applySuggestions(msg suggestMsg) model {
+	if m.suggest == nil {
+		return m
+	}
+	m.suggest.loading = false
+	m.suggest.items = msg.items
+	m.status = m.suggest.summary()
+	return m
+}
+
+func (m model) applyCleaned(msg cleanedMsg) model {
+	v := m.suggest
+	if v == nil {
+		return m
+	}
+	v.cleaning = false
+	if msg.err != nil {
+		m.status = fmt.Sprintf("Error: %v", msg.err)
+		return m
+	}
+	v.items[msg.index] = msg.item
+	for _, p := range msg.item.paths {
+		traceAction("clean", p, m.redactor)
+		dropMFTIndex(p)
+		// Listings of the cleaned folders and every folder above them
+		// are out of date.
+		for key := range m.cache {
+			if key == cacheKey(p) || isUnder(key, cacheKey(p)) || isUnder(cacheKey(p), key) {
+				delete(m.cache, key)
+			}
+		}
+	}
+	m.status = fmt.Sprintf("Freed %s from %s", humanize.Bytes(max(msg.freed, 0)), msg.item.name)
+	if msg.item.size > 0 {
+		m.status += fmt.Sprintf(" • %s left in use or too recent", humanize.Bytes(msg.item.size))
+	}
+	return m
+}
+
+// summary totals the suggestions.
+func (v *suggestView) summary() string {
+	var size int64
+	for _, s := range v.items {
+		size += s.size
+	}
+	return fmt.Sprintf("%d places • %s could be freed", len(v.items), humanize.Bytes(size))
+}
+
+// handleSuggestKey handles keys while the suggestions are shown.
+func (m model) handleSuggestKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.suggest
+	if v.cleaning || v.loading {
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc", "R", "q":
+			if v.loading {
+				m.suggest = nil
+				return m.load()
+			}
+		}
+		return m, nil
+	}
+	if v.confirm {
+		v.confirm = false
+		if msg.String() != "y" && msg.String() != "Y" {
+			m.status = "Cleanup cancelled"
+			return m, nil
+		}
+		v.cleaning = true
+		s := v.items[v.selected]
+		m.status = fmt.Sprintf("Cleaning %s...", s.name)
+		return m, cleanCmd(v.selected, s)
+	}
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "R", "esc", "q":
+		// Cleaning may have changed the listing shown.
+		m.suggest = nil
+		return m.load()
+	case "up", "k":
+		v.selected = max(v.selected-1, 0)
+	case "down", "j":
+		v.selected = min(v.selected+1, max(len(v.items)-1, 0))
+	case "r":
+		v.loading = true
+		m.status = "Measuring the usual places..."
+		return m, suggestCmd()
+	case "enter", "d":
+		if v.selected >= len(v.items) {
+			break
+		}
+		s := v.items[v.selected]
+		switch {
+		case s.tool != nil:
+			if err := exec.Command(s.tool[0], s.tool[1:]...).Start(); err != nil {
+				m.status = fmt.Sprintf("Error: %v", err)
+			} else {
+				m.status = "Disk Cleanup opened • Clean up system files, then Previous Windows installation(s)"
+			}
+		case s.admin && !windows.GetCurrentProcessToken().IsElevated():
+			m.status = s.name + " can only be cleaned from an elevated terminal"
+		case s.size == 0:
+			m.status = "Nothing to clean in " + s.name
+		default:
+			v.confirm = true
+			m.status = fmt.Sprintf("Delete %s (%s) from %s for good? y to go ahead", itemCount(int(s.files)), humanize.Bytes(s.size), s.name)
+		}
+		return m, nil
+	case "y":
+		if v.selected < len(v.items) {
+			m = m.copyPath(Entry{Name: v.items[v.selected].name, Path: v.items[v.selected].paths[0]})
+		}
+		return m, nil
+	case "o", "O":
+		if v.selected < len(v.items) {
+			p := v.items[v.selected].paths[0]
+			m = m.openAction(Entry{Name: filepath.Base(p), Path: p, IsDir: true}, msg.String() == "O")
+		}
+		return m, nil
+	}
+	m.status = v.summary()
+	return m, nil
+}
+
+// renderSuggest lists the suggestions, largest first as measured.
+func (m model) renderSuggest() string {
+	v := m.suggest
+	if v.loading && v.items == nil {
+		return dimStyle.Render("  Measuring temp folders, caches and crash dumps...") + "\n"
+	}
+	if len(v.items) == 0 {
+		return dimStyle.Render("  (nothing found to clean)") + "\n"
+	}
+	elevated := windows.GetCurrentProcessToken().IsElevated()
+	var b strings.Builder
+	for i, s := range v.items {
+		note := s.about
+		switch {
+		case s.tool != nil:
+			note += " • Enter opens Disk Cleanup"
+		case s.admin && !elevated:
+			note += " • needs an elevated terminal"
+		}
+		line := fmt.Sprintf("%10s  %-30s %s", humanize.Bytes(s.size), s.name, note)
+		switch {
+		case i == v.selected:
+			b.WriteString(selectedStyle.Render(line))
+		case s.size == 0:
+			b.WriteString(dimStyle.Render(line))
+		default:
+			b.WriteString(normalStyle.Render(line))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
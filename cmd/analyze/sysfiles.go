@@ -0,0 +1,304 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/elevate"
+	"github.com/winmole/winmole/internal/sysfiles"
+	"github.com/winmole/winmole/internal/usage"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// H explains the paging, hibernation and swap files at the root of the
+// drives, which are often the biggest files on C: and which Windows holds
+// open, so they can be neither read nor deleted. It shows how each is set
+// up and what changing that would free: turning hibernation off, or
+// switching to the reduced hibernation file that only serves Fast Startup,
+// through the elevated helper after y, and a paging file much larger than
+// Windows has needed since boot, through System Properties, which p opens.
+// Their rows in the listing say what they are.
+
+// sysFilesView shows the report.
+type sysFilesView struct {
+	report  *sysfiles.Report
+	loading bool
+	running bool   // powercfg in progress
+	confirm string // the hibernation mode waiting for y
+}
+
+type sysFilesMsg struct {
+	report *sysfiles.Report
+	err    error
+}
+
+type hibernationMsg struct {
+	mode   string
+	before int64            // the hibernation file's size before
+	after  *sysfiles.Report // read again once powercfg is done
+	err    error
+}
+
+func sysFilesCmd() tea.Cmd {
+	return func() tea.Msg {
+		r, err := sysfiles.Read()
+		return sysFilesMsg{report: r, err: err}
+	}
+}
+
+func hibernationCmd(mode string, before int64) tea.Cmd {
+	return func() tea.Msg {
+		if _, err := elevate.RunAction(elevate.OpSetHibernation, elevate.HibernationArgs{Mode: mode}); err != nil {
+			return hibernationMsg{mode: mode, err: err}
+		}
+		after, _ := sysfiles.Read()
+		return hibernationMsg{mode: mode, before: before, after: after}
+	}
+}
+
+// sysFileSuffix notes on the rows of the files what they are.
+func sysFileSuffix(e Entry) string {
+	switch sysfiles.Kind(e.Path) {
+	case sysfiles.KindPaging:
+		return " (paging file • H)"
+	case sysfiles.KindHibernation:
+		return " (hibernation file • H)"
+	case sysfiles.KindSwap:
+		return " (swap file for Store apps • H)"
+	}
+	return ""
+}
+
+// showSysFiles opens the view and reads the settings.
+func (m model) showSysFiles() (tea.Model, tea.Cmd) {
+	usage.Run("analyze.sysfiles")
+	m.sysFiles = &sysFilesView{loading: true}
+	m.status = "Reading the paging and hibernation settings..."
+	return m, sysFilesCmd()
+}
+
+func (m model) applySysFiles(msg sysFilesMsg) model {
+	v := m.sysFiles
+	if v == nil {
+		return m
+	}
+	v.loading = false
+	if msg.err != nil {
+		m.sysFiles = nil
+		m.status = fmt.Sprintf("Error: %v", msg.err)
+		return m
+	}
+	v.report = msg.report
+	m.status = v.summary()
+	return m
+}
+
+func (m model) applyHibernation(msg hibernationMsg) (model, tea.Cmd) {
+	v := m.sysFiles
+	if v != nil {
+		v.running = false
+	}
+	if msg.err != nil {
+		m.status = fmt.Sprintf("Error: %v", msg.err)
+		return m, nil
+	}
+	traceAction("hibernation-"+msg.mode, "powercfg", m.redactor)
+	var size int64
+	if msg.after != nil {
+		size = msg.after.Size(sysfiles.KindHibernation)
+	}
+	if freed := msg.before - size; freed > 0 {
+		usage.Freed("analyze.sysfiles", freed)
+		m.status = fmt.Sprintf("Hibernation set to %s • %s freed", msg.mode, humanize.Bytes(freed))
+	} else {
+		m.status = "Hibernation set to " + msg.mode
+	}
+	// The drive roots list the file at its old size.
+	for key, l := range m.cache {
+		for _, e := range l.entries {
+			if sysfiles.Kind(e.Path) == sysfiles.KindHibernation {
+				dropMFTIndex(l.path)
+				delete(m.cache, key)
+				break
+			}
+		}
+	}
+	if v != nil && msg.after != nil {
+		v.report = msg.after
+	}
+	return m, nil
+}
+
+// summary totals the files.
+func (v *sysFilesView) summary() string {
+	r := v.report
+	var total int64
+	for _, f := range r.Files {
+		total += f.Size
+	}
+	return fmt.Sprintf("Paging, hibernation and swap files take %s in %d files", humanize.Bytes(total), len(r.Files))
+}
+
+// pagingSavings estimates what a fixed paging file of twice the peak use
+// since boot, and at least 4 GB, would free; 0 when it would not be worth
+// it.
+func (v *sysFilesView) pagingSavings() (fixed, freed int64) {
+	var allocated, peak int64
+	for _, p := range v.report.Paging {
+		allocated += p.Allocated
+		peak += p.Peak
+	}
+	fixed = max(2*peak, 4<<30)
+	if allocated-fixed < 2<<30 {
+		return 0, 0
+	}
+	return fixed, allocated - fixed
+}
+
+// handleSysFilesKey handles keys while the files are shown.
+func (m model) handleSysFilesKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.sysFiles
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+	if v.loading || v.running {
+		if v.loading && (msg.String() == "esc" || msg.String() == "H" || msg.String() == "q") {
+			m.sysFiles = nil
+			m.status = m.totalStatus()
+		}
+		return m, nil
+	}
+	if v.confirm != "" {
+		mode := v.confirm
+		v.confirm = ""
+		if msg.String() != "y" {
+			m.status = "Cancelled"
+			return m, nil
+		}
+		v.running = true
+		m.status = "Setting hibernation to " + mode + " (administrator)..."
+		return m, hibernationCmd(mode, v.report.Size(sysfiles.KindHibernation))
+	}
+
+	r := v.report
+	switch msg.String() {
+	case "H", "esc", "q":
+		m.sysFiles = nil
+		m.status = m.totalStatus()
+		return m, nil
+	case "h":
+		if !r.Hibernation {
+			m.status = "Hibernation is already off"
+			return m, nil
+		}
+		v.confirm = "off"
+		m.status = fmt.Sprintf("Turn hibernation off, freeing %s? Hibernate and Fast Startup stop working. y to go ahead",
+			humanize.Bytes(r.Size(sysfiles.KindHibernation)))
+		return m, nil
+	case "s":
+		if r.Hibernation && r.ReducedHibernation {
+			m.status = "The hibernation file is already reduced"
+			return m, nil
+		}
+		v.confirm = "reduced"
+		m.status = "Switch to the reduced hibernation file? Fast Startup keeps working, Hibernate does not. y to go ahead"
+		return m, nil
+	case "f":
+		if r.Hibernation && !r.ReducedHibernation {
+			m.status = "Hibernation is already on with the full file"
+			return m, nil
+		}
+		v.confirm = "full"
+		m.status = fmt.Sprintf("Turn full hibernation on? The file takes about %s. y to go ahead", humanize.Bytes(2*r.ReducedSize()))
+		return m, nil
+	case "p":
+		if err := exec.Command("SystemPropertiesPerformance.exe").Start(); err != nil {
+			m.status = fmt.Sprintf("Cannot open System Properties: %v", err)
+		} else {
+			m.status = "System Properties opened • Advanced, then Change under Virtual memory"
+		}
+		return m, nil
+	case "r":
+		v.loading = true
+		m.status = "Reading the paging and hibernation settings..."
+		return m, sysFilesCmd()
+	}
+	m.status = v.summary()
+	return m, nil
+}
+
+// renderSysFiles lists the files and what could be done about them.
+func (m model) renderSysFiles() string {
+	v := m.sysFiles
+	r := v.report
+	if r == nil {
+		return dimStyle.Render("  Reading the paging and hibernation settings...") + "\n"
+	}
+	var b strings.Builder
+	for _, f := range r.Files {
+		var note string
+		switch f.Kind {
+		case sysfiles.KindPaging:
+			note = "paging file • size set by hand"
+			if r.AutomaticPaging {
+				note = "paging file • managed by Windows"
+			}
+			for _, p := range r.Paging {
+				if strings.EqualFold(p.Path, f.Path) {
+					note += fmt.Sprintf(" • %s in use, at most %s since boot", humanize.Bytes(p.Current), humanize.Bytes(p.Peak))
+				}
+			}
+		case sysfiles.KindHibernation:
+			switch {
+			case !r.Hibernation:
+				note = "hibernation file • hibernation is off"
+			case r.ReducedHibernation:
+				note = "hibernation file • reduced, for Fast Startup only"
+			default:
+				note = "hibernation file • full"
+			}
+			if r.FastStartup {
+				note += " • Fast Startup on"
+			}
+		case sysfiles.KindSwap:
+			note = "swap file • suspends Store apps; small and managed by Windows"
+		}
+		size := sizeStyle.Render(fmt.Sprintf("%10s", humanize.Bytes(f.Size)))
+		b.WriteString(size + " " + normalStyle.Render(fmt.Sprintf("%-18s", f.Path)) + dimStyle.Render(note) + "\n")
+	}
+	if len(r.Files) == 0 {
+		b.WriteString(dimStyle.Render("  (no paging, hibernation or swap file on the fixed drives)") + "\n")
+	}
+
+	b.WriteString("\n")
+	hint := func(key, text string) {
+		b.WriteString(normalStyle.Render(fmt.Sprintf("  %-2s", key)) + " " + text + "\n")
+	}
+	if hiber := r.Size(sysfiles.KindHibernation); r.Hibernation && hiber > 0 {
+		hint("h", fmt.Sprintf("Turn hibernation off: frees %s. Hibernate and Fast Startup stop working.", humanize.Bytes(hiber)))
+		if !r.ReducedHibernation {
+			if saved := hiber - r.ReducedSize(); saved > 0 {
+				hint("s", fmt.Sprintf("Reduced hibernation file: frees about %s and keeps Fast Startup; Hibernate stops working.", humanize.Bytes(saved)))
+			}
+		} else {
+			hint("f", "Full hibernation file: brings back Hibernate.")
+		}
+	} else if !r.Hibernation {
+		hint("f", fmt.Sprintf("Turn hibernation back on; the file takes about %s.", humanize.Bytes(2*r.ReducedSize())))
+	}
+	if fixed, freed := v.pagingSavings(); freed > 0 {
+		hint("p", fmt.Sprintf("The paging file is far larger than Windows has needed since boot: a fixed %s would free about %s.",
+			humanize.Bytes(fixed), humanize.Bytes(freed)))
+		b.WriteString(dimStyle.Render("     Let the PC run its usual workload for a few days first, and do not turn the paging file off:"))
+		b.WriteString("\n")
+		b.WriteString(dimStyle.Render("     programs fail when memory runs out, and Windows needs it to save crash dumps."))
+		b.WriteString("\n")
+	} else {
+		hint("p", "Paging file settings in System Properties; its size matches what Windows has needed.")
+	}
+	return b.String()
+}
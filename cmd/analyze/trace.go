@@ -0,0 +1,31 @@
+//go:build windows
+
+package main
+
+import (
+	"github.com/winmole/winmole/internal/etw"
+	"github.com/winmole/winmole/internal/redact"
+)
+
+// traceTop is how many of a scan's largest entries are written to ETW.
+const traceTop = 10
+
+// traceScan marks a finished scan on the ETW timeline, followed by its
+// largest entries, so a trace shows what the analyzer found and when.
+func traceScan(path string, entries []Entry, total int64, r *redact.Redactor) {
+	if !etw.Enabled(etw.LevelInfo, etw.KeywordScan) {
+		return
+	}
+	etw.Writef(etw.LevelInfo, etw.KeywordScan, "scan done: %s, %d bytes in %d entries", r.String(path), total, len(entries))
+	for i, e := range entries {
+		if i == traceTop {
+			break
+		}
+		etw.Writef(etw.LevelInfo, etw.KeywordScan, "top %d: %s, %d bytes, %d files", i+1, r.String(e.Path), e.Size, e.Files)
+	}
+}
+
+// traceAction marks something the user did.
+func traceAction(action, path string, r *redact.Redactor) {
+	etw.Writef(etw.LevelInfo, etw.KeywordAction, "%s: %s", action, r.String(path))
+}
@@ -0,0 +1,247 @@
+//go:build windows
+
+package main
+
+import (
+	"math"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// The treemap view (t) draws the current folder as nested rectangles whose
+// areas are proportional to size, WinDirStat style, so the biggest space
+// users stand out at a glance. Layout is squarified (Bruls, Huizing and van
+// Wijk) to keep blocks close to square; the arrow keys move to the
+// neighbouring block and Enter opens it like in the list.
+
+// treemapPalette holds background colors for blocks, used in turn.
+var treemapPalette = []string{"24", "30", "65", "96", "131", "60", "101", "67", "137", "23", "94", "97"}
+
+// cellAspect is how many times taller than wide a terminal cell is.
+const cellAspect = 2.0
+
+type tmRect struct {
+	x, y, w, h float64
+}
+
+// squarify lays out sizes (sorted largest first) in r.
+func squarify(sizes []int64, r tmRect) []tmRect {
+	rects := make([]tmRect, len(sizes))
+	var total float64
+	for _, s := range sizes {
+		total += float64(s)
+	}
+	if total <= 0 || r.w <= 0 || r.h <= 0 {
+		return rects
+	}
+	scale := r.w * r.h / total
+	area := func(i int) float64 { return float64(sizes[i]) * scale }
+
+	// worst is the highest aspect ratio in a row of areas sum, with the
+	// smallest and largest areas lo and hi, laid along a side of length side.
+	worst := func(sum, lo, hi, side float64) float64 {
+		s2, w2 := sum*sum, side*side
+		return math.Max(w2*hi/s2, s2/(w2*lo))
+	}
+
+	for i := 0; i < len(sizes) && area(i) > 0; {
+		side := math.Min(r.w, r.h)
+		sum, lo, hi := area(i), area(i), area(i)
+		j := i + 1
+		for ; j < len(sizes) && area(j) > 0; j++ {
+			a := area(j)
+			if worst(sum+a, math.Min(lo, a), math.Max(hi, a), side) > worst(sum, lo, hi, side) {
+				break
+			}
+			sum, lo, hi = sum+a, math.Min(lo, a), math.Max(hi, a)
+		}
+
+		// Lay the row along the short side and shrink what is left.
+		if r.w >= r.h {
+			colW := sum / r.h
+			y := r.y
+			for k := i; k < j; k++ {
+				h := area(k) / colW
+				rects[k] = tmRect{r.x, y, colW, h}
+				y += h
+			}
+			r.x, r.w = r.x+colW, r.w-colW
+		} else {
+			rowH := sum / r.w
+			x := r.x
+			for k := i; k < j; k++ {
+				w := area(k) / rowH
+				rects[k] = tmRect{x, r.y, w, rowH}
+				x += w
+			}
+			r.y, r.h = r.y+rowH, r.h-rowH
+		}
+		i = j
+	}
+	return rects
+}
+
+// treemapLayout is the layout on a width x height grid of cells: each
+// cell holds the index of the entry whose block covers its center, or -1,
+// and boxes holds each block's cells (empty when too small to show).
+type treemapLayout struct {
+	width, height int
+	cells         [][]int
+	rects         []tmRect
+	boxes         []tmBox
+}
+
+type tmBox struct {
+	x, y, w, h int
+}
+
+func (m model) treemapLayout() treemapLayout {
+	width, height := max(m.width-2, 20), m.viewportHeight()
+	sizes := make([]int64, len(m.entries))
+	for i, e := range m.entries {
+		sizes[i] = m.size(e)
+	}
+	// Lay out in square units so blocks look square on screen.
+	rects := squarify(sizes, tmRect{0, 0, float64(width), float64(height) * cellAspect})
+
+	l := treemapLayout{
+		width:  width,
+		height: height,
+		cells:  make([][]int, height),
+		rects:  rects,
+		boxes:  make([]tmBox, len(rects)),
+	}
+	for y := range l.cells {
+		l.cells[y] = make([]int, width)
+		for x := range l.cells[y] {
+			l.cells[y][x] = -1
+		}
+	}
+	// A cell belongs to a block when its center lies inside the block.
+	first := func(v float64) int { return int(math.Ceil(v - 0.5)) }
+	for i, r := range rects {
+		x1, x2 := max(first(r.x), 0), min(first(r.x+r.w), width)
+		y1, y2 := max(first(r.y/cellAspect), 0), min(first((r.y+r.h)/cellAspect), height)
+		if x1 >= x2 || y1 >= y2 {
+			continue
+		}
+		l.boxes[i] = tmBox{x1, y1, x2 - x1, y2 - y1}
+		for y := y1; y < y2; y++ {
+			for x := x1; x < x2; x++ {
+				l.cells[y][x] = i
+			}
+		}
+	}
+	return l
+}
+
+// renderTreemap draws the blocks with each entry's name and size in its top
+// left corner where they fit.
+func (m model) renderTreemap() string {
+	l := m.treemapLayout()
+	text := make([][]rune, l.height)
+	for y := range text {
+		text[y] = []rune(strings.Repeat(" ", l.width))
+	}
+	for i, e := range m.entries {
+		box := l.boxes[i]
+		x, y, w, h := box.x, box.y, box.w, box.h
+		if w < 3 {
+			continue
+		}
+		label := []string{m.redactor.String(e.Name), humanize.Bytes(m.size(e))}
+		for n, s := range label {
+			if n >= h {
+				break
+			}
+			r := []rune(s)
+			if len(r) > w-1 {
+				r = r[:w-1]
+			}
+			copy(text[y+n][x+1:], r)
+		}
+	}
+
+	var b strings.Builder
+	for y, row := range l.cells {
+		// Render runs of cells belonging to the same block together.
+		for x := 0; x < len(row); {
+			end := x + 1
+			for end < len(row) && row[end] == row[x] {
+				end++
+			}
+			b.WriteString(m.treemapStyle(row[x]).Render(string(text[y][x:end])))
+			x = end
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func (m model) treemapStyle(i int) lipgloss.Style {
+	switch {
+	case i < 0:
+		return dimStyle
+	case i == m.selected:
+		return selectedStyle
+	case m.ageColors && i < len(m.entries) && !m.entries[i].ModTime.IsZero():
+		return lipgloss.NewStyle().
+			Foreground(lipgloss.Color("232")).
+			Background(lipgloss.Color(ageColor(m.entries[i].ModTime)))
+	}
+	return lipgloss.NewStyle().
+		Foreground(lipgloss.Color("255")).
+		Background(lipgloss.Color(treemapPalette[i%len(treemapPalette)]))
+}
+
+// treemapDirection maps the movement keys to a direction.
+func treemapDirection(key string) (dx, dy float64, ok bool) {
+	switch key {
+	case "up", "k":
+		return 0, -1, true
+	case "down", "j":
+		return 0, 1, true
+	case "left", "h":
+		return -1, 0, true
+	case "right", "l":
+		return 1, 0, true
+	}
+	return 0, 0, false
+}
+
+// moveTreemap selects the nearest visible block in direction (dx, dy) from
+// the selected one.
+func (m model) moveTreemap(dx, dy float64) model {
+	l := m.treemapLayout()
+	if m.selected >= len(l.rects) {
+		return m
+	}
+	center := func(r tmRect) (float64, float64) { return r.x + r.w/2, r.y + r.h/2 }
+	sx, sy := center(l.rects[m.selected])
+
+	best, bestDist := -1, math.Inf(1)
+	for i, r := range l.rects {
+		if i == m.selected {
+			continue
+		}
+		if l.boxes[i].w == 0 {
+			continue
+		}
+		cx, cy := center(r)
+		along := (cx-sx)*dx + (cy-sy)*dy
+		if along <= 0 {
+			continue
+		}
+		// Prefer blocks straight ahead over ones off to the side.
+		across := math.Abs((cx-sx)*dy) + math.Abs((cy-sy)*dx)
+		if d := along + 2*across; d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	if best >= 0 {
+		m.selected = best
+	}
+	return m
+}
@@ -0,0 +1,196 @@
+//go:build windows
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// T charts the current folder's size across its snapshots, oldest first
+// and ending with the scan on screen, so the nightly snapshots of
+// `winmole analyze schedule` show when a folder started growing. Enter
+// compares the current scan with the selected snapshot, as c does.
+
+// trendPoint is the folder's size in one snapshot, or now.
+type trendPoint struct {
+	snapshot  snapshotInfo // zero for the current scan
+	scannedAt time.Time
+	size      int64
+}
+
+// trendView is the folder's size over time.
+type trendView struct {
+	path     string
+	points   []trendPoint // oldest first
+	selected int
+	offset   int
+	loading  bool
+	err      error
+}
+
+type trendMsg struct {
+	path   string
+	points []trendPoint
+	err    error
+}
+
+// trendCmd reads the folder's size from every snapshot that covers it. A
+// snapshot of a folder below it does not, so it is left out.
+func (m model) trendCmd() tea.Cmd {
+	current := cacheKey(m.path)
+	path := m.path
+	return func() tea.Msg {
+		files, err := filepath.Glob(filepath.Join(snapshotDir(), "*.json"))
+		if err != nil {
+			return trendMsg{path: path, err: err}
+		}
+		var points []trendPoint
+		for _, file := range files {
+			s, err := readSnapshotInfo(file)
+			if err != nil {
+				continue
+			}
+			root := cacheKey(s.root)
+			if root != current && !isUnder(current, root) {
+				continue
+			}
+			if root == current {
+				points = append(points, trendPoint{snapshot: s, scannedAt: s.scannedAt, size: s.totalSize})
+				continue
+			}
+			if size, ok := snapshotSize(file, current); ok {
+				points = append(points, trendPoint{snapshot: s, scannedAt: s.scannedAt, size: size})
+			}
+		}
+		sort.Slice(points, func(i, j int) bool { return points[i].scannedAt.Before(points[j].scannedAt) })
+		return trendMsg{path: path, points: points}
+	}
+}
+
+// snapshotSize looks up the size of the folder with key in a snapshot.
+func snapshotSize(file, key string) (int64, bool) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, false
+	}
+	var doc exportDoc
+	if json.Unmarshal(data, &doc) != nil {
+		return 0, false
+	}
+	for _, row := range doc.Entries {
+		if row.IsDir && cacheKey(row.Path) == key {
+			return row.Size, true
+		}
+	}
+	return 0, false
+}
+
+// openTrend shows the folder's size over time.
+func (m model) openTrend() (tea.Model, tea.Cmd) {
+	m.trend = &trendView{path: m.path, loading: true}
+	return m, m.trendCmd()
+}
+
+func (m model) applyTrend(msg trendMsg) model {
+	if m.trend == nil || m.trend.path != msg.path {
+		return m
+	}
+	v := m.trend
+	v.loading = false
+	v.points, v.err = msg.points, msg.err
+	if l, ok := m.cache[cacheKey(m.path)]; ok {
+		v.points = append(v.points, trendPoint{scannedAt: time.Now(), size: l.totalSize})
+	}
+	v.selected = max(len(v.points)-1, 0)
+	if h := m.viewportHeight(); v.selected >= h {
+		v.offset = v.selected - h + 1
+	}
+	return m
+}
+
+// handleTrendKey handles keys while the trend is shown.
+func (m model) handleTrendKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.trend
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "T", "esc", "q":
+		m.trend = nil
+	case "up", "k":
+		if v.selected > 0 {
+			v.selected--
+			v.offset = min(v.offset, v.selected)
+		}
+	case "down", "j":
+		if v.selected < len(v.points)-1 {
+			v.selected++
+			if h := m.viewportHeight(); v.selected >= v.offset+h {
+				v.offset = v.selected - h + 1
+			}
+		}
+	case "enter":
+		if v.selected >= len(v.points) || v.points[v.selected].snapshot.path == "" {
+			break
+		}
+		m.trend = nil
+		return m.compareWith(v.points[v.selected].snapshot)
+	}
+	return m, nil
+}
+
+func (m model) renderTrend() string {
+	v := m.trend
+	var b strings.Builder
+	switch {
+	case v.err != nil:
+		b.WriteString(warnStyle.Render(fmt.Sprintf("  Cannot read the snapshots: %v", v.err)))
+		b.WriteString("\n")
+		return b.String()
+	case v.loading:
+		b.WriteString(dimStyle.Render("  Reading the snapshots..."))
+		b.WriteString("\n")
+		return b.String()
+	case len(v.points) < 2:
+		b.WriteString(dimStyle.Render("  Not enough snapshots of this folder yet; S saves one, `winmole analyze schedule` saves one every night"))
+		b.WriteString("\n")
+		return b.String()
+	}
+	var largest int64
+	for _, p := range v.points {
+		largest = max(largest, p.size)
+	}
+	end := min(v.offset+m.viewportHeight(), len(v.points))
+	for i := v.offset; i < end; i++ {
+		p := v.points[i]
+		when := humanize.Time(p.scannedAt)
+		if p.snapshot.path == "" {
+			when = "now"
+		}
+		delta := ""
+		if i > 0 {
+			delta = signedBytes(p.size - v.points[i-1].size)
+		}
+		filled := barWidth(p.size, largest, false, 30)
+		bar := strings.Repeat("█", filled) + strings.Repeat("░", 30-filled)
+		if i != v.selected {
+			bar = barStyle.Render(bar)
+		}
+		line := fmt.Sprintf("  %-17s %10s %s %10s", when, humanize.Bytes(p.size), bar, delta)
+		if i == v.selected {
+			b.WriteString(selectedStyle.Render(line))
+		} else {
+			b.WriteString(normalStyle.Render(line))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
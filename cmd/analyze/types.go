@@ -0,0 +1,172 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/scan"
+)
+
+// The file-type view (x) totals everything below the current folder by
+// extension: how much space .mp4 or .log files take across the whole tree,
+// which is often a better guide to what to clean than folder by folder.
+
+const noExtension = "(no extension)"
+
+// extStat is the total size and number of files with one extension.
+type extStat struct {
+	ext   string
+	size  int64
+	files int64
+}
+
+// extBreakdown is the file-type view of one folder.
+type extBreakdown struct {
+	stats  []extStat // largest first
+	total  int64
+	offset int
+}
+
+type extResultMsg struct {
+	path  string
+	stats []extStat
+	total int64
+	err   error
+}
+
+func (m model) extCmd() tea.Cmd {
+	return func() tea.Msg {
+		stats, total, err := extensionsOf(m.path, m.progress)
+		return extResultMsg{path: m.path, stats: stats, total: total, err: err}
+	}
+}
+
+// extensionsOf totals the files below path by extension, from the MFT when
+// it can be read and by walking otherwise.
+func extensionsOf(path string, progress *scan.Counters) ([]extStat, int64, error) {
+	byExt := make(map[string]*extStat)
+	add := func(_, name string, size int64) {
+		ext := normalizeExt(filepath.Ext(name))
+		if ext == "" {
+			ext = noExtension
+		}
+		s, ok := byExt[ext]
+		if !ok {
+			s = &extStat{ext: ext}
+			byExt[ext] = s
+		}
+		s.size += size
+		s.files++
+	}
+
+	fromMFT := false
+	if vol := mftVolume(path); vol != "" && progress.Exclude == nil && !progress.FollowLinks {
+		if idx, err := loadMFTIndex(context.Background(), vol, progress); err == nil {
+			fromMFT = idx.eachFile(path, add) == nil
+		}
+	}
+	if !fromMFT {
+		err := scan.Walk(context.Background(), path, progress, func(_ string, info fs.FileInfo) {
+			add("", info.Name(), info.Size())
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	stats := make([]extStat, 0, len(byExt))
+	var total int64
+	for _, s := range byExt {
+		stats = append(stats, *s)
+		total += s.size
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].size > stats[j].size
+	})
+	return stats, total, nil
+}
+
+// eachFile calls fn for every file below the directory at path, with the
+// path of the folder holding it.
+func (idx *mftIndex) eachFile(path string, fn func(dir, name string, size int64)) error {
+	return idx.eachNode(path, func(dir string, n *mftNode) {
+		fn(dir, n.name, n.size)
+	})
+}
+
+// eachNode is eachFile with the file's whole record.
+func (idx *mftIndex) eachNode(path string, fn func(dir string, n *mftNode)) error {
+	root, err := idx.lookup(path)
+	if err != nil {
+		return err
+	}
+	type folder struct {
+		rec  uint32
+		path string
+	}
+	stack := []folder{{root, filepath.Clean(path)}}
+	for len(stack) > 0 {
+		d := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, c := range idx.nodes[d.rec].children {
+			if n := &idx.nodes[c]; n.isDir {
+				stack = append(stack, folder{c, filepath.Join(d.path, n.name)})
+			} else {
+				fn(d.path, n)
+			}
+		}
+	}
+	return nil
+}
+
+// handleTypesKey scrolls the file-type view; x or Esc returns to the list.
+func (m model) handleTypesKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	t := m.types
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "x", "esc", "q":
+		m.types = nil
+		m.status = m.totalStatus()
+	case "up", "k":
+		t.offset = max(t.offset-1, 0)
+	case "down", "j":
+		t.offset = max(min(t.offset+1, len(t.stats)-m.viewportHeight()), 0)
+	}
+	return m, nil
+}
+
+// renderTypes lists the extensions with their size, file count and share of
+// the total.
+func (m model) renderTypes() string {
+	t := m.types
+	if len(t.stats) == 0 {
+		return dimStyle.Render("  (no files)") + "\n"
+	}
+	var b strings.Builder
+	end := min(t.offset+m.viewportHeight(), len(t.stats))
+	for _, s := range t.stats[t.offset:end] {
+		filled := barWidth(s.size, t.total, m.logScale, 20)
+		bar := strings.Repeat("█", filled) + strings.Repeat("░", 20-filled)
+		pct := 0.0
+		if t.total > 0 {
+			pct = float64(s.size) * 100 / float64(t.total)
+		}
+		b.WriteString(fmt.Sprintf("%s %s %5.1f%%  %s %s\n",
+			sizeStyle.Render(humanize.Bytes(s.size)),
+			barStyle.Render(bar),
+			pct,
+			m.categories.style("x"+s.ext).Render(fmt.Sprintf("%-16s", s.ext)),
+			dimStyle.Render(fmt.Sprintf("%d files", s.files)),
+		))
+	}
+	return b.String()
+}
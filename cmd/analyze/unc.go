@@ -0,0 +1,114 @@
+//go:build windows
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/winmole/winmole/internal/netdrive"
+	"golang.org/x/sys/windows"
+)
+
+// Shares (\\server\share) and mapped drives can be analyzed like local
+// folders. When the server wants other credentials than the signed-in
+// user's, analyze asks for them on the console before starting, or up
+// front with --user, and connects with them; the connection lasts until
+// sign-out, as with net use. Every file costs a round trip to the server,
+// so network folders are walked a few subfolders at a time rather than
+// the usual ten, which keeps the server from queueing requests.
+
+// networkWorkers is how many subfolders of a network folder are walked at
+// once.
+const networkWorkers = 4
+
+// shareRoot returns the share path lies on (`\\server\share`), or "" when
+// it is not a UNC path.
+func shareRoot(path string) string {
+	if !strings.HasPrefix(path, `\\`) || strings.HasPrefix(path, `\\?\`) || strings.HasPrefix(path, `\\.\`) {
+		return ""
+	}
+	return filepath.VolumeName(path)
+}
+
+// isNetworkPath reports whether path is on a share or a mapped drive.
+func isNetworkPath(path string) bool {
+	if shareRoot(path) != "" {
+		return true
+	}
+	vol := filepath.VolumeName(path)
+	if len(vol) != 2 || vol[1] != ':' {
+		return false
+	}
+	root, err := windows.UTF16PtrFromString(vol + `\`)
+	return err == nil && windows.GetDriveType(root) == windows.DRIVE_REMOTE
+}
+
+// needsCredentials reports whether opening a share failed because the
+// server did not accept the signed-in user.
+func needsCredentials(err error) bool {
+	return errors.Is(err, windows.ERROR_ACCESS_DENIED) || errors.Is(err, windows.ERROR_LOGON_FAILURE) ||
+		errors.Is(err, windows.ERROR_BAD_USERNAME)
+}
+
+// connectShare makes sure the share holding path can be read. With user
+// set it signs in as that user; otherwise it only asks for credentials
+// when the share turns the signed-in user away. Paths that are not on a
+// share are left alone.
+func connectShare(path, user string) error {
+	share := shareRoot(path)
+	if share == "" {
+		return nil
+	}
+	if user == "" {
+		_, err := os.Stat(path)
+		if err == nil || !needsCredentials(err) {
+			return nil
+		}
+		fmt.Fprintf(os.Stderr, "%s needs other credentials.\n", share)
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	for attempt := 0; attempt < 3; attempt++ {
+		name := user
+		if name == "" {
+			fmt.Fprint(os.Stderr, `User (DOMAIN\user or user@domain): `)
+			line, err := in.ReadString('\n')
+			if err != nil {
+				return fmt.Errorf("%s needs credentials; pass --user or connect with net use first", share)
+			}
+			name = strings.TrimSpace(line)
+		}
+		fmt.Fprintf(os.Stderr, "Password for %s: ", name)
+		password, err := readPassword(in)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return fmt.Errorf("read password: %w", err)
+		}
+		err = netdrive.Connect("", share, name, password, false)
+		if !errors.Is(err, netdrive.ErrCredentials) {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "The server did not accept that user name and password.")
+	}
+	return fmt.Errorf("could not sign in to %s", share)
+}
+
+// readPassword reads a line from the console without echoing it.
+func readPassword(in *bufio.Reader) (string, error) {
+	stdin := windows.Handle(os.Stdin.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(stdin, &mode); err == nil {
+		windows.SetConsoleMode(stdin, mode&^windows.ENABLE_ECHO_INPUT)
+		defer windows.SetConsoleMode(stdin, mode)
+	}
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
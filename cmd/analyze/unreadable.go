@@ -0,0 +1,108 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/usage"
+	"github.com/winmole/winmole/pkg/scan"
+	"golang.org/x/sys/windows"
+)
+
+// A walk skips what it cannot read, which would leave totals quietly short.
+// The status line counts what the scan of the current folder skipped and i
+// lists it. Run elevated, the analyzer takes the backup privilege, so only
+// files in use or damaged stay unreadable; on NTFS the MFT is read instead
+// and nothing is skipped at all.
+
+// unreadableKept is how many unreadable paths each scan keeps for the list.
+const unreadableKept = 500
+
+// unreadableView lists what the scan of the current folder could not read.
+type unreadableView struct {
+	failures []scan.Failure
+	count    int
+	offset   int
+}
+
+// enableBackupMode takes the backup privilege when running elevated.
+func enableBackupMode() {
+	if windows.GetCurrentProcessToken().IsElevated() {
+		scan.EnableBackupPrivilege()
+	}
+}
+
+// unreadableCount is how many items the scan of the current folder skipped.
+func (m model) unreadableCount() int {
+	return m.cache[cacheKey(m.path)].unreadable.Count()
+}
+
+func (m model) showUnreadable() model {
+	u := m.cache[cacheKey(m.path)].unreadable
+	if u.Count() == 0 {
+		m.status = "Everything below this folder could be read"
+		return m
+	}
+	usage.Run("analyze.denied")
+	m.unreadable = &unreadableView{failures: u.Paths(), count: u.Count()}
+	m.status = fmt.Sprintf("%d items could not be read and are not in the totals", u.Count())
+	if !windows.GetCurrentProcessToken().IsElevated() {
+		m.status += " • run elevated to read them"
+	}
+	return m
+}
+
+// handleUnreadableKey scrolls the list; i or Esc returns to the folder.
+func (m model) handleUnreadableKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.unreadable
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "i", "esc", "q":
+		m.unreadable = nil
+		m.status = m.totalStatus()
+	case "up", "k":
+		v.offset = max(v.offset-1, 0)
+	case "down", "j":
+		v.offset = min(v.offset+1, max(len(v.failures)-m.viewportHeight(), 0))
+	}
+	return m, nil
+}
+
+// failureReason says briefly why a path could not be read.
+func failureReason(err error) string {
+	var pathErr *fs.PathError
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return "access denied"
+	case errors.As(err, &pathErr):
+		return pathErr.Err.Error()
+	}
+	return err.Error()
+}
+
+// renderUnreadable lists the paths below the current folder with the
+// reason each could not be read.
+func (m model) renderUnreadable() string {
+	v := m.unreadable
+	var b strings.Builder
+	end := min(v.offset+m.viewportHeight(), len(v.failures))
+	for _, f := range v.failures[v.offset:end] {
+		rel, err := filepath.Rel(m.path, f.Path)
+		if err != nil {
+			rel = f.Path
+		}
+		reason := warnStyle.Render(fmt.Sprintf("%-16s", failureReason(f.Err)))
+		b.WriteString("  " + reason + " " + normalStyle.Render(rel) + "\n")
+	}
+	if end == len(v.failures) && v.count > len(v.failures) {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  ... and %d more\n", v.count-len(v.failures))))
+	}
+	return b.String()
+}
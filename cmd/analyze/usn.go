@@ -0,0 +1,263 @@
+//go:build windows
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// The USN change journal is NTFS's log of every create, write, rename and
+// delete on a volume. Remembering how far into it the saved scan reaches
+// lets the next launch replay only what happened since and rescan just the
+// folders involved instead of the whole drive.
+
+var (
+	kernel32         = windows.NewLazySystemDLL("kernel32.dll")
+	procOpenFileById = kernel32.NewProc("OpenFileById")
+)
+
+// Change journal constants (winioctl.h).
+const (
+	fsctlQueryUSNJournal            = 0x000900F4
+	fsctlReadUSNJournal             = 0x000900BB
+	fsctlReadUnprivilegedUSNJournal = 0x000903AB // Windows 10 1709+, no admin needed
+	usnReadBuffer                   = 64 << 10
+	maxJournalDirs                  = 20000 // past this many changed folders a rescan is cheaper
+)
+
+var errJournalReset = errors.New("change journal was reset or has wrapped")
+
+// journalMark is a position in a volume's change journal.
+type journalMark struct {
+	JournalID uint64 `json:"journalId"`
+	NextUSN   int64  `json:"nextUsn"`
+}
+
+// usnJournalData mirrors USN_JOURNAL_DATA_V0.
+type usnJournalData struct {
+	UsnJournalID    uint64
+	FirstUsn        int64
+	NextUsn         int64
+	LowestValidUsn  int64
+	MaxUsn          int64
+	MaximumSize     uint64
+	AllocationDelta uint64
+}
+
+// readUSNJournalData mirrors READ_USN_JOURNAL_DATA_V0.
+type readUSNJournalData struct {
+	StartUsn          int64
+	ReasonMask        uint32
+	ReturnOnlyOnClose uint32
+	Timeout           uint64
+	BytesToWaitFor    uint64
+	UsnJournalID      uint64
+}
+
+// fileIDDescriptor mirrors FILE_ID_DESCRIPTOR with a 64-bit file ID; the
+// union is 16 bytes wide.
+type fileIDDescriptor struct {
+	size   uint32
+	typ    uint32
+	fileID uint64
+	_      uint64
+}
+
+// journalMarks holds, per volume, the journal position taken before this
+// session first scanned it. Everything cached for the volume is at least
+// that recent.
+var journalMarks = struct {
+	sync.Mutex
+	byVolume map[string]journalMark
+}{byVolume: make(map[string]journalMark)}
+
+// driveVolume returns path's drive ("C:"), or "" for UNC paths and the like.
+func driveVolume(path string) string {
+	vol := filepath.VolumeName(path)
+	if len(vol) != 2 || vol[1] != ':' {
+		return ""
+	}
+	return strings.ToUpper(vol)
+}
+
+// markJournal records the journal position of path's volume the first time
+// the volume is scanned. Volumes without a readable journal stay unmarked.
+func markJournal(path string) {
+	vol := driveVolume(path)
+	if vol == "" {
+		return
+	}
+	journalMarks.Lock()
+	_, ok := journalMarks.byVolume[vol]
+	journalMarks.Unlock()
+	if ok {
+		return
+	}
+
+	j, err := openJournal(vol)
+	if err != nil {
+		return
+	}
+	defer j.close()
+	data, err := j.query()
+	if err != nil {
+		return
+	}
+	setJournalMark(vol, journalMark{JournalID: data.UsnJournalID, NextUSN: data.NextUsn})
+}
+
+func setJournalMark(vol string, mark journalMark) {
+	journalMarks.Lock()
+	defer journalMarks.Unlock()
+	journalMarks.byVolume[vol] = mark
+}
+
+func journalMarkOf(vol string) (journalMark, bool) {
+	journalMarks.Lock()
+	defer journalMarks.Unlock()
+	mark, ok := journalMarks.byVolume[vol]
+	return mark, ok
+}
+
+// journal is an open handle for reading a volume's change journal.
+type journal struct {
+	h        windows.Handle
+	readCode uint32
+}
+
+// openJournal opens the raw volume when elevated and otherwise its root
+// folder, which the unprivileged read accepts.
+func openJournal(vol string) (*journal, error) {
+	name, access, flags, code := `\\.\`+vol, uint32(windows.GENERIC_READ), uint32(0), uint32(fsctlReadUSNJournal)
+	if !windows.GetCurrentProcessToken().IsElevated() {
+		name, access, flags, code = vol+`\`, windows.FILE_READ_ATTRIBUTES, windows.FILE_FLAG_BACKUP_SEMANTICS, fsctlReadUnprivilegedUSNJournal
+	}
+	p, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	h, err := windows.CreateFile(p, access,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil, windows.OPEN_EXISTING, flags, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", name, err)
+	}
+	return &journal{h: h, readCode: code}, nil
+}
+
+func (j *journal) close() {
+	windows.CloseHandle(j.h)
+}
+
+func (j *journal) query() (usnJournalData, error) {
+	var data usnJournalData
+	var n uint32
+	err := windows.DeviceIoControl(j.h, fsctlQueryUSNJournal, nil, 0,
+		(*byte)(unsafe.Pointer(&data)), uint32(unsafe.Sizeof(data)), &n, nil)
+	if err != nil {
+		return data, fmt.Errorf("query change journal: %w", err)
+	}
+	return data, nil
+}
+
+// changedDirs returns the folders of vol in which anything was created,
+// written, renamed or deleted since mark, and the journal's position now.
+func changedDirs(vol string, since journalMark) ([]string, journalMark, error) {
+	j, err := openJournal(vol)
+	if err != nil {
+		return nil, since, err
+	}
+	defer j.close()
+
+	data, err := j.query()
+	if err != nil {
+		return nil, since, err
+	}
+	if data.UsnJournalID != since.JournalID || since.NextUSN < data.FirstUsn {
+		return nil, since, errJournalReset
+	}
+	now := journalMark{JournalID: data.UsnJournalID, NextUSN: data.NextUsn}
+
+	parents := make(map[uint64]struct{})
+	buf := make([]byte, usnReadBuffer)
+	in := readUSNJournalData{
+		StartUsn:     since.NextUSN,
+		ReasonMask:   0xFFFFFFFF,
+		UsnJournalID: data.UsnJournalID,
+	}
+	for in.StartUsn < data.NextUsn {
+		var n uint32
+		err := windows.DeviceIoControl(j.h, j.readCode,
+			(*byte)(unsafe.Pointer(&in)), uint32(unsafe.Sizeof(in)),
+			&buf[0], uint32(len(buf)), &n, nil)
+		if err != nil {
+			return nil, since, fmt.Errorf("read change journal: %w", err)
+		}
+		if n < 8 {
+			break
+		}
+		next := int64(binary.LittleEndian.Uint64(buf))
+		for off := uint32(8); off+60 <= n; {
+			rec := buf[off:n]
+			length := binary.LittleEndian.Uint32(rec)
+			if length < 60 || length > uint32(len(rec)) {
+				break
+			}
+			// USN_RECORD_V2; other versions only come from ReFS.
+			if binary.LittleEndian.Uint16(rec[4:]) == 2 {
+				parents[binary.LittleEndian.Uint64(rec[16:])] = struct{}{}
+			}
+			off += length
+		}
+		if len(parents) > maxJournalDirs {
+			return nil, since, fmt.Errorf("more than %d folders changed", maxJournalDirs)
+		}
+		if next <= in.StartUsn {
+			break
+		}
+		in.StartUsn = next
+	}
+
+	dirs := make([]string, 0, len(parents))
+	for frn := range parents {
+		// Folders deleted since show up as a change in their parent.
+		if dir, err := j.resolve(frn); err == nil {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, now, nil
+}
+
+// resolve turns a file reference number into the folder's current path.
+func (j *journal) resolve(frn uint64) (string, error) {
+	desc := fileIDDescriptor{fileID: frn}
+	desc.size = uint32(unsafe.Sizeof(desc))
+	r, _, callErr := procOpenFileById.Call(
+		uintptr(j.h),
+		uintptr(unsafe.Pointer(&desc)),
+		windows.FILE_READ_ATTRIBUTES,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		0,
+		windows.FILE_FLAG_BACKUP_SEMANTICS,
+	)
+	h := windows.Handle(r)
+	if h == windows.InvalidHandle {
+		return "", callErr
+	}
+	defer windows.CloseHandle(h)
+
+	buf := make([]uint16, windows.MAX_LONG_PATH)
+	n, err := windows.GetFinalPathNameByHandle(h, &buf[0], uint32(len(buf)), 0)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(windows.UTF16ToString(buf[:n]), `\\?\`), nil
+}
@@ -0,0 +1,586 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+	"unsafe"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/scan"
+	"golang.org/x/sys/windows"
+)
+
+// w watches the folder on screen and everything below it, keeping the
+// sizes in every listing current as files are created, written, renamed
+// and deleted, so a build filling bin\ or a download growing can be
+// followed live. Rows that changed light up for a few seconds, green when
+// they grew and orange when they shrank. What a notification alone does
+// not tell, such as the size of a folder moved in from elsewhere or of a
+// file deleted before it was ever listed, is measured again in the
+// background. w again stops watching.
+
+const (
+	watchBuffer  = 64 << 10 // the most ReadDirectoryChangesW returns for a share
+	highlightFor = 3 * time.Second
+	fadeInterval = 250 * time.Millisecond
+)
+
+var (
+	grownStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("42")).
+			Bold(true)
+
+	shrunkStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("209")).
+			Bold(true)
+)
+
+// folderWatch is a running watch. Its maps are only used from Update.
+type folderWatch struct {
+	root    string
+	handle  windows.Handle
+	events  chan watchMsg
+	done    chan struct{}
+	known   map[string]int64     // sizes of files seen since the watch started, by cache key
+	pending map[string]bool      // paths being measured again
+	changed map[string]rowChange // recent changes, by cache key
+	grown   int64                // net change since the watch started
+	fading  bool                 // a fadeCmd is on its way
+}
+
+// rowChange is the recent change of one file or folder.
+type rowChange struct {
+	at    time.Time
+	delta int64
+}
+
+// watchEvent is one notification, with what the path is now.
+type watchEvent struct {
+	action uint32      // windows.FILE_ACTION_*
+	path   string      // absolute
+	info   os.FileInfo // nil when the path is gone
+}
+
+type watchMsg struct {
+	watch    *folderWatch
+	events   []watchEvent
+	overflow bool  // more changed at once than fit the buffer; some were lost
+	err      error // the watch ended
+}
+
+// remeasureMsg is a file or folder measured again.
+type remeasureMsg struct {
+	watch   *folderWatch
+	path    string
+	entry   Entry
+	listing *dirListing // the folder's own listing; nil for a file
+	err     error
+}
+
+type fadeMsg struct{}
+
+// startWatch starts reading the changes below root.
+func startWatch(root string) (*folderWatch, error) {
+	p, err := windows.UTF16PtrFromString(root)
+	if err != nil {
+		return nil, err
+	}
+	h, err := windows.CreateFile(p, windows.FILE_LIST_DIRECTORY,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE, nil,
+		windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return nil, err
+	}
+	w := &folderWatch{
+		root:    root,
+		handle:  h,
+		events:  make(chan watchMsg, 16),
+		done:    make(chan struct{}),
+		known:   make(map[string]int64),
+		pending: make(map[string]bool),
+		changed: make(map[string]rowChange),
+	}
+	go w.read()
+	return w, nil
+}
+
+// read passes on the notifications until the watch is stopped or fails.
+func (w *folderWatch) read() {
+	defer windows.CloseHandle(w.handle)
+	buf := make([]byte, watchBuffer)
+	const filter = windows.FILE_NOTIFY_CHANGE_FILE_NAME | windows.FILE_NOTIFY_CHANGE_DIR_NAME | windows.FILE_NOTIFY_CHANGE_SIZE
+	for {
+		var n uint32
+		err := windows.ReadDirectoryChanges(w.handle, &buf[0], uint32(len(buf)), true, filter, &n, nil, 0)
+		msg := watchMsg{watch: w}
+		switch {
+		case err == windows.ERROR_NOTIFY_ENUM_DIR, err == nil && n == 0:
+			msg.overflow = true
+		case err != nil:
+			msg.err = err
+		default:
+			msg.events = w.parse(buf[:n])
+		}
+		select {
+		case w.events <- msg:
+		case <-w.done:
+			return
+		}
+		if msg.err != nil {
+			return
+		}
+	}
+}
+
+// parse decodes a buffer of FILE_NOTIFY_INFORMATION records.
+func (w *folderWatch) parse(buf []byte) []watchEvent {
+	var events []watchEvent
+	for offset := uint32(0); ; {
+		info := (*windows.FileNotifyInformation)(unsafe.Pointer(&buf[offset]))
+		name := windows.UTF16ToString(unsafe.Slice(&info.FileName, info.FileNameLength/2))
+		e := watchEvent{action: info.Action, path: filepath.Join(w.root, name)}
+		if e.action != windows.FILE_ACTION_REMOVED && e.action != windows.FILE_ACTION_RENAMED_OLD_NAME {
+			if fi, err := os.Lstat(e.path); err == nil {
+				e.info = fi
+			}
+		}
+		events = append(events, e)
+		if info.NextEntryOffset == 0 {
+			return events
+		}
+		offset += info.NextEntryOffset
+	}
+}
+
+// wait delivers the next batch of notifications.
+func (w *folderWatch) wait() tea.Cmd {
+	return func() tea.Msg {
+		if msg, ok := <-w.events; ok {
+			return msg
+		}
+		return nil
+	}
+}
+
+// stop ends the watch; the reader closes the handle on its way out.
+func (w *folderWatch) stop() {
+	close(w.done)
+	windows.CancelIoEx(w.handle, nil)
+}
+
+// note records a change of path for highlighting.
+func (w *folderWatch) note(path string, delta int64) {
+	key := cacheKey(path)
+	c := w.changed[key]
+	w.changed[key] = rowChange{at: time.Now(), delta: c.delta + delta}
+	w.grown += delta
+}
+
+// change is the recent change at or below path, if any.
+func (w *folderWatch) change(path string) (rowChange, bool) {
+	key := cacheKey(path)
+	var sum rowChange
+	found := false
+	for k, c := range w.changed {
+		if k == key || isUnder(k, key) {
+			sum.delta += c.delta
+			if c.at.After(sum.at) {
+				sum.at = c.at
+			}
+			found = true
+		}
+	}
+	return sum, found
+}
+
+// fade drops the changes that are no longer highlighted and reports
+// whether any still are.
+func (w *folderWatch) fade() bool {
+	for k, c := range w.changed {
+		if time.Since(c.at) >= highlightFor {
+			delete(w.changed, k)
+		}
+	}
+	return len(w.changed) > 0
+}
+
+func fadeCmd() tea.Cmd {
+	return tea.Tick(fadeInterval, func(time.Time) tea.Msg { return fadeMsg{} })
+}
+
+// toggleWatch starts watching the current folder, or stops watching.
+func (m model) toggleWatch() (tea.Model, tea.Cmd) {
+	if m.watch != nil {
+		m.watch.stop()
+		m.watch = nil
+		m.status = "Stopped watching"
+		return m, nil
+	}
+	w, err := startWatch(m.path)
+	if err != nil {
+		m.status = fmt.Sprintf("Cannot watch %s: %v", m.path, err)
+		return m, nil
+	}
+	m.watch = w
+	m.status = m.totalStatus()
+	return m, w.wait()
+}
+
+// applyWatch updates the listings for a batch of notifications.
+func (m model) applyWatch(msg watchMsg) (tea.Model, tea.Cmd) {
+	w := m.watch
+	if w == nil || msg.watch != w {
+		return m, nil
+	}
+	if msg.err != nil {
+		m.watch = nil // the reader has ended
+		m.status = fmt.Sprintf("Stopped watching: %v", msg.err)
+		return m, nil
+	}
+	if len(msg.events) > 0 {
+		dropMFTIndex(w.root) // the parsed table no longer matches the disk
+	}
+	cmds := []tea.Cmd{w.wait()}
+	for _, e := range msg.events {
+		if cmd := m.applyEvent(e); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	}
+	next, cmd := m.showChanges()
+	if msg.overflow {
+		next.status = "Too many changes at once to follow; sizes may be off until r"
+	}
+	return next, tea.Batch(append(cmds, cmd)...)
+}
+
+// applyEvent applies one notification to the cached listings, or returns
+// the command measuring what it concerns again when the notification does
+// not say by how much things changed.
+func (m model) applyEvent(e watchEvent) tea.Cmd {
+	w := m.watch
+	key := cacheKey(e.path)
+	if m.watchExcluded(e) {
+		return nil
+	}
+	switch e.action {
+	case windows.FILE_ACTION_ADDED, windows.FILE_ACTION_RENAMED_NEW_NAME:
+		if e.info == nil {
+			return nil // gone again already
+		}
+		if e.info.IsDir() {
+			return m.remeasure(e.path)
+		}
+		f := fileEntry(e.path, e.info)
+		w.known[key] = f.Size
+		if old, ok := m.cache.entry(e.path); ok {
+			delta := entryDelta(f, old) // it replaced a file
+			m.cache.grow(e.path, delta)
+			w.note(e.path, delta.Size)
+		} else {
+			m.cache.add(f)
+			w.note(e.path, f.Size)
+		}
+
+	case windows.FILE_ACTION_MODIFIED:
+		if e.info == nil || e.info.IsDir() {
+			return nil
+		}
+		size := e.info.Size()
+		old, ok := m.cache.entry(e.path)
+		if !ok {
+			known, seen := w.known[key]
+			if !seen {
+				w.known[key] = size
+				return m.remeasure(e.path)
+			}
+			old.Size = known
+		}
+		w.known[key] = size
+		if grown := size - old.Size; grown != 0 {
+			m.cache.grow(e.path, Entry{Size: grown, Alloc: grown, ModTime: e.info.ModTime()})
+			w.note(e.path, grown)
+		}
+
+	case windows.FILE_ACTION_REMOVED, windows.FILE_ACTION_RENAMED_OLD_NAME:
+		size, known := w.known[key]
+		delete(w.known, key)
+		if old, ok := m.cache.entry(e.path); ok {
+			m.cache.remove(old)
+			w.note(e.path, -old.Size)
+			return nil
+		}
+		if !known {
+			return m.remeasure(e.path)
+		}
+		m.cache.grow(e.path, Entry{Size: -size, Alloc: -size, Files: -1})
+		w.note(e.path, -size)
+	}
+	return nil
+}
+
+// watchExcluded reports whether e concerns an excluded path or one inside
+// an excluded folder.
+func (m model) watchExcluded(e watchEvent) bool {
+	isDir := e.info != nil && e.info.IsDir()
+	root := cacheKey(m.watch.root)
+	for p := e.path; cacheKey(p) != root && isUnder(cacheKey(p), root); p = filepath.Dir(p) {
+		if m.exclude.match(p, isDir || p != e.path) {
+			return true
+		}
+	}
+	return false
+}
+
+// remeasure measures path again in the background, or the nearest folder
+// above it that is listed, when its own folder never was.
+func (m model) remeasure(path string) tea.Cmd {
+	for {
+		parent := filepath.Dir(path)
+		if parent == path {
+			return nil
+		}
+		if _, ok := m.cache[cacheKey(parent)]; ok {
+			break
+		}
+		path = parent
+	}
+	w := m.watch
+	if w.pending[cacheKey(path)] {
+		return nil
+	}
+	w.pending[cacheKey(path)] = true
+
+	progress := newProgress(m.exclude)
+	progress.FollowLinks = m.follow
+	progress.Streams = m.streams
+	if m.dedupe {
+		progress.Links = scan.NewLinks()
+	}
+	if isNetworkPath(path) {
+		progress.Workers = networkWorkers
+	}
+	links, follow, streams := m.dedupe, m.follow, m.streams
+	return func() tea.Msg {
+		msg := remeasureMsg{watch: w, path: path}
+		info, err := os.Lstat(path)
+		switch {
+		case err != nil:
+			msg.err = err
+		case !info.IsDir():
+			msg.entry = fileEntry(path, info)
+		default:
+			// Walked rather than read from the MFT, whose parsed table
+			// predates the change.
+			entries, total, err := scan.DirContext(context.Background(), path, progress)
+			msg.listing = &dirListing{path: path, entries: entries, totalSize: total, links: links, follow: follow, streams: streams}
+			msg.entry = folderEntry(filepath.Base(path), path, *msg.listing)
+			msg.entry.ModTime = info.ModTime()
+			msg.err = err
+		}
+		return msg
+	}
+}
+
+// applyRemeasure puts a file or folder measured again in place of what
+// the listings had.
+func (m model) applyRemeasure(msg remeasureMsg) (tea.Model, tea.Cmd) {
+	w := m.watch
+	if w == nil || msg.watch != w {
+		return m, nil
+	}
+	delete(w.pending, cacheKey(msg.path))
+	old, listed := m.cache.entry(msg.path)
+	switch {
+	case os.IsNotExist(msg.err):
+		if listed {
+			m.cache.remove(old)
+			w.note(msg.path, -old.Size)
+		}
+	case msg.err != nil:
+		m.status = fmt.Sprintf("Could not measure %s again: %v", msg.path, msg.err)
+		return m, nil
+	default:
+		for dir := range m.cache {
+			if key := cacheKey(msg.path); dir == key || isUnder(dir, key) {
+				delete(m.cache, dir) // listings inside are out of date
+			}
+		}
+		if msg.listing != nil {
+			m.cache[cacheKey(msg.path)] = *msg.listing
+		}
+		if listed {
+			delta := entryDelta(msg.entry, old)
+			m.cache.grow(msg.path, delta)
+			w.note(msg.path, delta.Size)
+		} else {
+			m.cache.add(msg.entry)
+			w.note(msg.path, msg.entry.Size)
+		}
+	}
+	return m.showChanges()
+}
+
+// showChanges shows the updated listing of the current folder, keeping
+// the same entry selected, and keeps the highlight fading.
+func (m model) showChanges() (model, tea.Cmd) {
+	busy := m.scanning || m.purging != nil || m.batching != nil
+	if listing, ok := m.cache[cacheKey(m.path)]; ok && !busy {
+		if len(m.entries) > 0 {
+			m.focus = m.entries[m.selected].Path
+		}
+		m.totalSize = listing.totalSize
+		m = m.refilter().selectFocus()
+		m.status = m.totalStatus()
+	}
+	if m.watch.fading || len(m.watch.changed) == 0 {
+		return m, nil
+	}
+	m.watch.fading = true
+	return m, fadeCmd()
+}
+
+// applyFade ends highlights as they time out.
+func (m model) applyFade() (tea.Model, tea.Cmd) {
+	if m.watch == nil {
+		return m, nil
+	}
+	if m.watch.fade() {
+		return m, fadeCmd()
+	}
+	m.watch.fading = false
+	return m, nil
+}
+
+// watchStatus is the status line's note on the watch.
+func (m model) watchStatus() string {
+	if m.watch == nil {
+		return ""
+	}
+	return " • watching, " + signedBytes(m.watch.grown) + " since start (w stops)"
+}
+
+// changeStyle highlights a row that changed recently, with the change to
+// append to its name.
+func (m model) changeStyle(e Entry) (lipgloss.Style, string, bool) {
+	if m.watch == nil {
+		return lipgloss.Style{}, "", false
+	}
+	c, ok := m.watch.change(e.Path)
+	if !ok || time.Since(c.at) >= highlightFor {
+		return lipgloss.Style{}, "", false
+	}
+	if c.delta < 0 {
+		return shrunkStyle, " " + signedBytes(c.delta), true
+	}
+	return grownStyle, " " + signedBytes(c.delta), true
+}
+
+func signedBytes(n int64) string {
+	if n < 0 {
+		return "-" + humanize.Bytes(-n)
+	}
+	return "+" + humanize.Bytes(n)
+}
+
+// fileEntry is the entry of a file from its attributes.
+func fileEntry(path string, info os.FileInfo) Entry {
+	return Entry{Name: info.Name(), Path: path, Size: info.Size(), Alloc: info.Size(), Files: 1, ModTime: info.ModTime()}
+}
+
+// entryDelta is what changed from old to e, in sizes and counts.
+func entryDelta(e, old Entry) Entry {
+	return Entry{
+		Size:        e.Size - old.Size,
+		Alloc:       e.Alloc - old.Alloc,
+		Cloud:       e.Cloud - old.Cloud,
+		Linked:      e.Linked - old.Linked,
+		LinkedAlloc: e.LinkedAlloc - old.LinkedAlloc,
+		Streams:     e.Streams - old.Streams,
+		Files:       e.Files - old.Files,
+		Dirs:        e.Dirs - old.Dirs,
+		ModTime:     e.ModTime,
+	}
+}
+
+// entry finds path in its folder's cached listing.
+func (c dirCache) entry(path string) (Entry, bool) {
+	key := cacheKey(path)
+	for _, e := range c[cacheKey(filepath.Dir(path))].entries {
+		if cacheKey(e.Path) == key {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// grow adds delta's sizes and counts to path's entry in its folder's
+// listing and to the entries leading to it in the listings above: the
+// counterpart of remove for files that change size.
+func (c dirCache) grow(path string, delta Entry) {
+	target := cacheKey(path)
+	for dir, listing := range c {
+		if !isUnder(target, dir) {
+			continue
+		}
+		found := false
+		entries := make([]Entry, 0, len(listing.entries))
+		for _, e := range listing.entries {
+			key := cacheKey(e.Path)
+			if key == target || isUnder(target, key) {
+				e.Size = max(e.Size+delta.Size, 0)
+				e.Alloc = max(e.Alloc+delta.Alloc, 0)
+				e.Cloud = max(e.Cloud+delta.Cloud, 0)
+				e.Linked = max(e.Linked+delta.Linked, 0)
+				e.LinkedAlloc = max(e.LinkedAlloc+delta.LinkedAlloc, 0)
+				e.Streams = max(e.Streams+delta.Streams, 0)
+				e.Files = max(e.Files+delta.Files, 0)
+				e.Dirs = max(e.Dirs+delta.Dirs, 0)
+				if key == target && delta.ModTime.After(e.ModTime) {
+					e.ModTime = delta.ModTime
+				}
+				found = true
+			}
+			entries = append(entries, e)
+		}
+		if !found {
+			continue // not listed this far down; the next scan picks it up
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Size > entries[j].Size
+		})
+		listing.entries = entries
+		listing.totalSize = max(listing.totalSize+delta.Size, 0)
+		c[dir] = listing
+	}
+}
+
+// add lists a new file or folder in its folder's listing and adds it to
+// the entries leading to it in the listings above.
+func (c dirCache) add(e Entry) {
+	delta := e
+	if e.IsDir {
+		delta.Dirs++
+	}
+	c.grow(e.Path, delta)
+	parent := cacheKey(filepath.Dir(e.Path))
+	listing, ok := c[parent]
+	if !ok {
+		return
+	}
+	entries := append(slices.Clip(listing.entries), e)
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Size > entries[j].Size
+	})
+	listing.entries = entries
+	listing.totalSize += e.Size
+	c[parent] = listing
+}
@@ -0,0 +1,71 @@
+//go:build windows
+
+// Command helper is WinMole's elevated helper. The TUIs start it on demand
+// through a UAC prompt (see internal/elevate) when they need admin rights,
+// instead of running elevated themselves. It only performs the operations
+// in elevate.Ops and logs every request to helper-audit.log in
+// %ProgramData%\WinMole, where only administrators can write.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/winmole/winmole/internal/elevate"
+)
+
+// auditFile records every privileged request.
+const auditFile = "helper-audit.log"
+
+func main() {
+	pipe := flag.String("pipe", "", "named pipe to serve")
+	parent := flag.Uint("parent", 0, "process ID of the client allowed to connect")
+	flag.Parse()
+
+	if *pipe == "" || *parent == 0 {
+		fmt.Fprintln(os.Stderr, "helper is started by winmole analyze/status; it is not meant to be run directly")
+		os.Exit(2)
+	}
+
+	// Output files go to a directory the client can read but not change.
+	workDir, err := elevate.NewWorkDir(uint32(*parent))
+	if err != nil {
+		fatal(err)
+	}
+	err = elevate.Serve(*pipe, uint32(*parent), elevate.Ops(workDir), audit)
+	os.RemoveAll(workDir)
+	if err != nil {
+		fatal(err)
+	}
+}
+
+// audit appends one line per request; failures to log are not fatal.
+func audit(op string, args json.RawMessage, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error: " + err.Error()
+	}
+	line := fmt.Sprintf("%s pid=%d op=%s args=%s %s\n",
+		time.Now().Format(time.RFC3339), os.Getpid(), op, string(args), outcome)
+
+	dir, err := elevate.DataDir()
+	if err != nil {
+		return
+	}
+	f, err := elevate.OpenAppend(filepath.Join(dir, auditFile))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.WriteString(line)
+}
+
+func fatal(err error) {
+	audit("start", nil, err)
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(1)
+}
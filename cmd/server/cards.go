@@ -0,0 +1,94 @@
+//go:build windows
+
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/winmole/winmole/internal/upload"
+	"github.com/winmole/winmole/pkg/metrics"
+)
+
+// staleAfter is how long without a batch before a host counts as offline:
+// agents usually push every few minutes.
+const staleAfter = 15 * time.Minute
+
+// sampleMetrics fills in the fields the dashboard cards read from a pushed
+// sample, so the server draws each host with the status cards.
+func sampleMetrics(s upload.Sample) metrics.Metrics {
+	m := metrics.Metrics{
+		CPUUsage:       s.CPU,
+		CPUCores:       s.CPUCores,
+		CPUModel:       s.CPUModel,
+		MemTotal:       s.MemTotal,
+		MemPercent:     s.Mem,
+		MemInUse:       s.MemInUse,
+		MemCached:      s.MemCached,
+		MemCommitted:   s.MemCommitted,
+		MemCommitLimit: s.MemCommitLimit,
+		MemInstalled:   s.MemInstalled,
+		NetSentRate:    s.NetSent,
+		NetRecvRate:    s.NetRecv,
+		DiskPath:       s.SystemDrive,
+		CollectedAt:    s.At,
+	}
+	if m.MemTotal > 0 {
+		m.MemInUsePercent = float64(m.MemInUse) / float64(m.MemTotal) * 100
+	} else {
+		// Agents from before the cards' fields were pushed.
+		m.MemInUsePercent = s.Mem
+	}
+	for _, d := range s.Disks {
+		m.Disks = append(m.Disks, metrics.DiskInfo{Mount: d.Mount, Total: d.Total, Used: d.Used, Percent: percent(d.Used, d.Total)})
+	}
+	if d, ok := systemDisk(s); ok {
+		m.DiskPath, m.DiskTotal, m.DiskUsed, m.DiskPercent = d.Mount, d.Total, d.Used, percent(d.Used, d.Total)
+	}
+	return m
+}
+
+// systemDisk is the system drive of a sample, or its first drive.
+func systemDisk(s upload.Sample) (upload.Disk, bool) {
+	for _, d := range s.Disks {
+		if s.SystemDrive != "" && strings.EqualFold(strings.TrimRight(d.Mount, `\`), strings.TrimRight(s.SystemDrive, `\`)) {
+			return d, true
+		}
+	}
+	if len(s.Disks) > 0 {
+		return s.Disks[0], true
+	}
+	return upload.Disk{}, false
+}
+
+func percent(used, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(used) / float64(total) * 100
+}
+
+// cpuHistory is the CPU use of the recent samples, oldest first.
+func cpuHistory(h host, n int) []float64 {
+	samples := h.Samples
+	if len(samples) > n {
+		samples = samples[len(samples)-n:]
+	}
+	values := make([]float64, len(samples))
+	for i, s := range samples {
+		values[i] = s.CPU
+	}
+	return values
+}
+
+// scanTotals sums the latest scan of every folder, and what they grew by
+// since the scan before.
+func scanTotals(h host) (total, change int64) {
+	for _, d := range h.Scans {
+		total += d.TotalSize
+		if d.Since != nil {
+			change += d.TotalSize - d.SinceSize
+		}
+	}
+	return total, change
+}
@@ -0,0 +1,53 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/winmole/winmole/internal/config"
+	"github.com/winmole/winmole/internal/upload"
+)
+
+// serverConfig is the server's part of the "server" section of
+// config.json, which agents read their url and token from: on the server
+// the token is the one every agent must send.
+type serverConfig struct {
+	Token    string `json:"token"`
+	Listen   string `json:"listen"`   // address to listen on, such as ":8740"
+	KeepDays int    `json:"keepDays"` // days of history kept; 0 keeps everything
+	DataDir  string `json:"dataDir"`  // where the history is kept
+	CertFile string `json:"certFile"` // with KeyFile, serve https
+	KeyFile  string `json:"keyFile"`
+}
+
+func defaultConfig() serverConfig {
+	return serverConfig{
+		Listen:   ":8740",
+		KeepDays: 90,
+		DataDir:  filepath.Join(config.CacheDir(), "server"),
+	}
+}
+
+// loadConfig returns the server settings. WINMOLE_SERVER_TOKEN overrides
+// the token, as it does for agents.
+func loadConfig() (serverConfig, error) {
+	cfg := defaultConfig()
+	err := config.Load(upload.Key, &cfg)
+	if token := os.Getenv(upload.TokenEnv); token != "" {
+		cfg.Token = token
+	}
+	return cfg, err
+}
+
+// keep is how long history is kept.
+func (c serverConfig) keep() time.Duration {
+	return time.Duration(c.KeepDays) * 24 * time.Hour
+}
+
+// tls reports whether the server serves https.
+func (c serverConfig) tls() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
@@ -0,0 +1,152 @@
+//go:build windows
+
+package main
+
+import (
+	"compress/gzip"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/winmole/winmole/internal/upload"
+)
+
+// maxBatch caps a batch, compressed and uncompressed; a scan diff of the
+// 5,000 biggest changes is well under it.
+const maxBatch = 32 << 20
+
+// api serves the agents and the read-only views:
+//
+//	POST /api/v1/scan-diff   a batch from upload.Flush (bearer token)
+//	POST /api/v1/metrics
+//	GET  /api/v1/hosts       every host with its latest sample and scans
+//	GET  /                   the web page
+//
+// The GETs take the token either as a bearer token or, for browsers, as
+// the password of basic authentication with any user name.
+type api struct {
+	store *store
+	token string
+	// added is called after each batch is stored.
+	added func(machine, kind string)
+}
+
+func (a *api) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/{kind}", a.ingest)
+	mux.HandleFunc("GET /api/v1/hosts", a.hosts)
+	mux.HandleFunc("GET /{$}", a.page)
+	return mux
+}
+
+// authorized checks the token, in constant time.
+func (a *api) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		_, token, ok = r.BasicAuth()
+	}
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) == 1
+}
+
+func (a *api) ingest(w http.ResponseWriter, r *http.Request) {
+	if !a.authorized(r) {
+		http.Error(w, "wrong or missing token", http.StatusUnauthorized)
+		return
+	}
+	kind := r.PathValue("kind")
+	if kind != upload.KindScanDiff && kind != upload.KindMetrics {
+		http.Error(w, "unknown kind "+kind, http.StatusNotFound)
+		return
+	}
+
+	var body io.Reader = http.MaxBytesReader(w, r.Body, maxBatch)
+	if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(w, "not gzip: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer zr.Close()
+		body = zr
+	}
+	raw, err := io.ReadAll(io.LimitReader(body, maxBatch+1))
+	var tooBig *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooBig) || len(raw) > maxBatch:
+		http.Error(w, fmt.Sprintf("batch over %d MB", maxBatch>>20), http.StatusRequestEntityTooLarge)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var env upload.Envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		http.Error(w, "not an envelope: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if env.Kind != kind {
+		http.Error(w, fmt.Sprintf("%s batch sent to /api/v1/%s", env.Kind, kind), http.StatusBadRequest)
+		return
+	}
+	addr, _, _ := net.SplitHostPort(r.RemoteAddr)
+	if err := a.store.add(env, addr); err != nil {
+		if errors.Is(err, errBadBatch) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		// The agent keeps the batch and sends it again.
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if a.added != nil {
+		a.added(env.Machine, env.Kind)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// hostSummary is one host in /api/v1/hosts.
+type hostSummary struct {
+	Machine  string                     `json:"machine"`
+	Addr     string                     `json:"addr,omitempty"`
+	LastSeen time.Time                  `json:"lastSeen"`
+	Latest   *upload.Sample             `json:"latest,omitempty"`
+	Scans    map[string]upload.ScanDiff `json:"scans"`
+}
+
+func (a *api) hosts(w http.ResponseWriter, r *http.Request) {
+	if !a.authorized(r) {
+		http.Error(w, "wrong or missing token", http.StatusUnauthorized)
+		return
+	}
+	out := []hostSummary{}
+	for _, h := range a.store.snapshot() {
+		s := hostSummary{Machine: h.Machine, Addr: h.Addr, LastSeen: h.LastSeen, Scans: h.Scans}
+		if latest, ok := h.latest(); ok {
+			s.Latest = &latest
+		}
+		out = append(out, s)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+func (a *api) page(w http.ResponseWriter, r *http.Request) {
+	if !a.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="WinMole server", charset="UTF-8"`)
+		http.Error(w, "sign in with the server token as the password", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := renderPage(w, a.store.snapshot()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: render page: %v\n", err)
+	}
+}
@@ -0,0 +1,130 @@
+//go:build windows
+
+// Command server collects the scan diffs and metric batches WinMole agents
+// push (see internal/upload), keeps each host's history on disk and shows
+// the fleet in a terminal view and a read-only web page with the status
+// cards: a self-hosted mini-monitoring system for a handful of PCs.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/config"
+	"github.com/winmole/winmole/internal/headless"
+	"github.com/winmole/winmole/internal/upload"
+)
+
+// pruneEvery is how often history past keepDays is dropped while the
+// server runs.
+const pruneEvery = 24 * time.Hour
+
+func main() {
+	listen := flag.String("listen", "", "address to listen on (default from config.json, else :8740)")
+	dataDir := flag.String("data", "", "directory the history is kept in (default: server in the cache directory)")
+	noTUI := flag.Bool("no-tui", false, "log received batches instead of showing the fleet view, for running as a service")
+	service := flag.Bool("service", false, "run under the service control manager, as the MSI installs it")
+	profile := flag.String("profile", "", "use the named settings profile from config.json")
+	flag.Parse()
+
+	if *profile != "" {
+		config.SetProfile(*profile)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if *service {
+		cfg = cfg.withServiceSettings()
+	}
+	if *listen != "" {
+		cfg.Listen = *listen
+	}
+	if *dataDir != "" {
+		cfg.DataDir = *dataDir
+	}
+	if cfg.Token == "" {
+		fmt.Fprintf(os.Stderr, "Error: no token: set \"%s\": {\"token\": ...} in config.json or %s, and the same token on every agent\n",
+			upload.Key, upload.TokenEnv)
+		os.Exit(1)
+	}
+
+	s, err := openStore(cfg.DataDir, cfg.keep())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	a := &api{store: s, token: cfg.Token}
+	srv := &http.Server{Addr: cfg.Listen, Handler: a.handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		for range time.Tick(pruneEvery) {
+			s.prune()
+		}
+	}()
+
+	if *service {
+		if err := runService(srv, cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *noTUI {
+		a.added = func(machine, kind string) {
+			fmt.Printf("%s %s from %s\n", time.Now().Format(time.RFC3339), kind, machine)
+		}
+		ctx, stop := headless.Context(0)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			srv.Shutdown(shutdown)
+		}()
+		fmt.Fprintf(os.Stderr, "Listening on %s, history in %s\n", cfg.Listen, cfg.DataDir)
+		if err := serve(srv, cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	p := tea.NewProgram(newModel(s, cfg.Listen), tea.WithAltScreen())
+	a.added = func(machine, kind string) {
+		p.Send(addedMsg{machine: machine, kind: kind})
+	}
+	go func() {
+		if err := serve(srv, cfg); err != nil {
+			p.Send(serveErrMsg{err: err})
+		}
+	}()
+	_, err = p.Run()
+	srv.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// serve listens until the server is shut down, over https when a
+// certificate is set.
+func serve(srv *http.Server, cfg serverConfig) error {
+	var err error
+	if cfg.tls() {
+		err = srv.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
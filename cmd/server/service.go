@@ -0,0 +1,87 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc"
+)
+
+// The MSI's Server feature installs the server as a Windows service that
+// runs server.exe --service as LocalService. A service has no user whose
+// config.json it could read, so the installer keeps its settings under
+// HKLM\Software\WinMole\Server, readable only by administrators and the
+// service:
+//
+//	Token    REG_SZ  the token every agent must send
+//	Listen   REG_SZ  address to listen on, such as ":8740"
+//	DataDir  REG_SZ  where the history is kept
+//
+// Values that are set override config.json; the history is otherwise kept
+// in LocalService's own cache folder.
+
+// ServiceName is the name the service is installed under.
+const ServiceName = "WinMoleServer"
+
+// serviceKey is the service's settings key under HKEY_LOCAL_MACHINE.
+const serviceKey = `Software\WinMole\Server`
+
+// withServiceSettings returns c with the installer's settings applied.
+func (c serverConfig) withServiceSettings() serverConfig {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, serviceKey, registry.QUERY_VALUE)
+	if err != nil {
+		return c
+	}
+	defer k.Close()
+	for name, field := range map[string]*string{"Token": &c.Token, "Listen": &c.Listen, "DataDir": &c.DataDir} {
+		if v, _, err := k.GetStringValue(name); err == nil && v != "" {
+			*field = v
+		}
+	}
+	return c
+}
+
+// serverService runs srv under the service control manager.
+type serverService struct {
+	srv *http.Server
+	cfg serverConfig
+}
+
+// runService serves until the service is stopped.
+func runService(srv *http.Server, cfg serverConfig) error {
+	return svc.Run(ServiceName, &serverService{srv: srv, cfg: cfg})
+}
+
+// Execute is called by the service control manager.
+func (s *serverService) Execute(_ []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	served := make(chan error, 1)
+	go func() { served <- serve(s.srv, s.cfg) }()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case err := <-served:
+			// The address is taken or the certificate unreadable.
+			if err != nil {
+				return true, 1
+			}
+			return false, 0
+		case r := <-requests:
+			switch r.Cmd {
+			case svc.Interrogate:
+				status <- r.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				s.srv.Shutdown(ctx)
+				cancel()
+				return false, 0
+			}
+		}
+	}
+}
@@ -0,0 +1,399 @@
+//go:build windows
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/winmole/winmole/internal/upload"
+)
+
+// Each host has a folder under the data directory with one JSON line per
+// batch it sent, by kind, so the history survives restarts and can be read
+// with any JSON tool:
+//
+//	hosts\pc-042\metrics.jsonl
+//	hosts\pc-042\scan-diff.jsonl
+//
+// The store keeps what the views need in memory: the recent samples and
+// the latest diff of every scanned folder.
+
+// maxRecent is how many samples of each host stay in memory: with the
+// default ten one-second samples every five minutes, about six hours.
+const maxRecent = 720
+
+// maxGrowth is how many scans of each folder stay in memory, whatever the
+// retention: a nightly scan for almost three years.
+const maxGrowth = 1000
+
+// record is one line of a host's history.
+type record struct {
+	ReceivedAt time.Time `json:"receivedAt"`
+	Addr       string    `json:"addr,omitempty"` // the agent's address
+	upload.Envelope
+}
+
+// scanPoint is the size of a scanned folder at one scan.
+type scanPoint struct {
+	At   time.Time `json:"at"`
+	Size int64     `json:"size"`
+}
+
+// host is what the server knows about one agent.
+type host struct {
+	Machine  string                     `json:"machine"`
+	Addr     string                     `json:"addr,omitempty"`
+	LastSeen time.Time                  `json:"lastSeen"`
+	Interval float64                    `json:"intervalSeconds,omitempty"`
+	Samples  []upload.Sample            `json:"samples"`
+	Scans    map[string]upload.ScanDiff `json:"scans"`   // the latest diff by root
+	Growth   map[string][]scanPoint     `json:"growth"`  // every scan's total by root
+	Batches  int                        `json:"batches"` // batches received
+}
+
+// latest returns the newest sample.
+func (h *host) latest() (upload.Sample, bool) {
+	if len(h.Samples) == 0 {
+		return upload.Sample{}, false
+	}
+	return h.Samples[len(h.Samples)-1], true
+}
+
+// roots lists the scanned folders, in order.
+func (h *host) roots() []string {
+	roots := make([]string, 0, len(h.Scans))
+	for root := range h.Scans {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// store holds every host, keyed by the lower-case machine name.
+type store struct {
+	mu    sync.RWMutex
+	dir   string
+	keep  time.Duration
+	hosts map[string]*host
+}
+
+// errBadBatch is a batch the agent should not send again.
+var errBadBatch = errors.New("bad batch")
+
+// openStore reads the history in dir, dropping what is older than keep.
+func openStore(dir string, keep time.Duration) (*store, error) {
+	s := &store{dir: dir, keep: keep, hosts: map[string]*host{}}
+	if err := os.MkdirAll(filepath.Join(dir, "hosts"), 0o755); err != nil {
+		return nil, fmt.Errorf("create data directory: %w", err)
+	}
+	s.prune()
+	folders, _ := filepath.Glob(filepath.Join(dir, "hosts", "*"))
+	for _, folder := range folders {
+		for _, kind := range []string{upload.KindMetrics, upload.KindScanDiff} {
+			f, err := os.Open(filepath.Join(folder, kind+".jsonl"))
+			if err != nil {
+				continue
+			}
+			// A torn or damaged line costs only its own batch.
+			damaged := 0
+			sc := historyLines(f)
+			for sc.Scan() {
+				var r record
+				if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+					damaged++
+					continue
+				}
+				s.apply(r)
+			}
+			if err := sc.Err(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: read %s: %v\n", f.Name(), err)
+			}
+			if damaged > 0 {
+				fmt.Fprintf(os.Stderr, "Warning: skipped %d damaged lines in %s\n", damaged, f.Name())
+			}
+			f.Close()
+		}
+	}
+	return s, nil
+}
+
+// historyLines reads a history file line by line; a line holds one batch,
+// which can be as big as maxBatch.
+func historyLines(f *os.File) *bufio.Scanner {
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64<<10), 2*maxBatch)
+	return sc
+}
+
+// hostDir is the folder of a machine: its name in lower case, with
+// anything but letters, digits, dots, dashes and underscores replaced.
+// Names Windows reserves for devices, such as con or com1.local, get a
+// leading underscore, since a folder by that name cannot be created.
+func (s *store) hostDir(machine string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, strings.ToLower(machine))
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	if name = strings.Trim(name, "."); name == "" {
+		name = "_"
+	}
+	if reservedName(name) {
+		name = "_" + name
+	}
+	return filepath.Join(s.dir, "hosts", name)
+}
+
+// reservedName reports whether Windows takes name for a device, whatever
+// extension follows it.
+func reservedName(name string) bool {
+	base, _, _ := strings.Cut(name, ".")
+	switch base {
+	case "con", "prn", "aux", "nul":
+		return true
+	}
+	return len(base) == 4 && (strings.HasPrefix(base, "com") || strings.HasPrefix(base, "lpt")) &&
+		base[3] >= '0' && base[3] <= '9'
+}
+
+// add checks a batch, writes it to the host's history and applies it.
+func (s *store) add(env upload.Envelope, addr string) error {
+	if env.Version != upload.Version {
+		return fmt.Errorf("%w: version %d, want %d", errBadBatch, env.Version, upload.Version)
+	}
+	if strings.TrimSpace(env.Machine) == "" {
+		return fmt.Errorf("%w: no machine name", errBadBatch)
+	}
+	if err := decodeData(env); err != nil {
+		return err
+	}
+
+	r := record{ReceivedAt: time.Now().UTC(), Addr: addr, Envelope: env}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dir := s.hostDir(env.Machine)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, env.Kind+".jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	s.applyLocked(r)
+	return nil
+}
+
+// decodeData checks that the data of env is a batch of its kind.
+func decodeData(env upload.Envelope) error {
+	var err error
+	switch env.Kind {
+	case upload.KindMetrics:
+		var b upload.MetricBatch
+		err = json.Unmarshal(env.Data, &b)
+	case upload.KindScanDiff:
+		var d upload.ScanDiff
+		if err = json.Unmarshal(env.Data, &d); err == nil && d.Root == "" {
+			err = errors.New("no root")
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q", errBadBatch, env.Kind)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", errBadBatch, env.Kind, err)
+	}
+	return nil
+}
+
+func (s *store) apply(r record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.applyLocked(r)
+}
+
+func (s *store) applyLocked(r record) {
+	key := strings.ToLower(r.Machine)
+	h := s.hosts[key]
+	if h == nil {
+		h = &host{Machine: r.Machine, Scans: map[string]upload.ScanDiff{}, Growth: map[string][]scanPoint{}}
+		s.hosts[key] = h
+	}
+	if r.ReceivedAt.After(h.LastSeen) {
+		h.LastSeen, h.Addr = r.ReceivedAt, r.Addr
+	}
+	h.Batches++
+
+	switch r.Kind {
+	case upload.KindMetrics:
+		var b upload.MetricBatch
+		if json.Unmarshal(r.Data, &b) != nil {
+			return
+		}
+		h.Interval = b.IntervalSeconds
+		h.Samples = append(h.Samples, b.Samples...)
+		sort.SliceStable(h.Samples, func(i, j int) bool { return h.Samples[i].At.Before(h.Samples[j].At) })
+		if len(h.Samples) > maxRecent {
+			h.Samples = h.Samples[len(h.Samples)-maxRecent:]
+		}
+	case upload.KindScanDiff:
+		var d upload.ScanDiff
+		if json.Unmarshal(r.Data, &d) != nil {
+			return
+		}
+		if prev, ok := h.Scans[d.Root]; !ok || !d.ScannedAt.Before(prev.ScannedAt) {
+			h.Scans[d.Root] = d
+		}
+		h.Growth[d.Root] = trimGrowth(append(h.Growth[d.Root], scanPoint{At: d.ScannedAt, Size: d.TotalSize}), s.cutoff())
+	}
+}
+
+// cutoff is when the history kept starts, or zero when it is kept for good.
+func (s *store) cutoff() time.Time {
+	if s.keep <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(-s.keep)
+}
+
+// trimGrowth drops the points from before cutoff and all but the newest
+// maxGrowth, like the scan files lose their old lines.
+func trimGrowth(points []scanPoint, cutoff time.Time) []scanPoint {
+	kept := points[:0]
+	for _, p := range points {
+		if !p.At.Before(cutoff) {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) > maxGrowth {
+		kept = append([]scanPoint(nil), kept[len(kept)-maxGrowth:]...)
+	}
+	return kept
+}
+
+// snapshot returns a copy of every host, by machine name.
+func (s *store) snapshot() []host {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	hosts := make([]host, 0, len(s.hosts))
+	for _, h := range s.hosts {
+		c := *h
+		c.Samples = append([]upload.Sample(nil), h.Samples...)
+		c.Scans = make(map[string]upload.ScanDiff, len(h.Scans))
+		for root, d := range h.Scans {
+			c.Scans[root] = d
+		}
+		c.Growth = make(map[string][]scanPoint, len(h.Growth))
+		for root, g := range h.Growth {
+			c.Growth[root] = append([]scanPoint(nil), g...)
+		}
+		hosts = append(hosts, c)
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		return strings.ToLower(hosts[i].Machine) < strings.ToLower(hosts[j].Machine)
+	})
+	return hosts
+}
+
+// prune drops the lines older than keep from every history file, and the
+// folders of hosts left with none. In memory, it drops the same scans from
+// the growth of each folder, and folders not scanned since.
+func (s *store) prune() {
+	if s.keep <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cutoff := s.cutoff()
+	for _, h := range s.hosts {
+		for root, points := range h.Growth {
+			if h.Growth[root] = trimGrowth(points, cutoff); len(h.Growth[root]) == 0 {
+				delete(h.Growth, root)
+			}
+		}
+		for root, d := range h.Scans {
+			if d.ScannedAt.Before(cutoff) {
+				delete(h.Scans, root)
+			}
+		}
+	}
+	folders, _ := filepath.Glob(filepath.Join(s.dir, "hosts", "*"))
+	for _, folder := range folders {
+		files, _ := filepath.Glob(filepath.Join(folder, "*.jsonl"))
+		for _, file := range files {
+			if err := pruneFile(file, cutoff); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: prune %s: %v\n", file, err)
+			}
+		}
+		os.Remove(folder) // only when empty
+	}
+}
+
+// pruneFile rewrites file without the lines received before cutoff, and
+// removes it when none are left.
+func pruneFile(file string, cutoff time.Time) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	var keep [][]byte
+	dropped := 0
+	sc := historyLines(f)
+	for sc.Scan() {
+		var r struct {
+			ReceivedAt time.Time `json:"receivedAt"`
+		}
+		// Damaged lines go too, and the ones after them stay.
+		if json.Unmarshal(sc.Bytes(), &r) != nil || r.ReceivedAt.Before(cutoff) {
+			dropped++
+			continue
+		}
+		keep = append(keep, append([]byte(nil), sc.Bytes()...))
+	}
+	f.Close()
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	if dropped == 0 {
+		return nil
+	}
+	if len(keep) == 0 {
+		return os.Remove(file)
+	}
+	tmp := file + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	for _, line := range keep {
+		out.Write(append(line, '\n'))
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, file)
+}
@@ -0,0 +1,142 @@
+//go:build windows
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHostDir(t *testing.T) {
+	s := &store{dir: `C:\ProgramData\WinMole`}
+	tests := []struct {
+		machine string
+		want    string
+	}{
+		{"PC-042", "pc-042"},
+		{"pc_042.corp.lan", "pc_042.corp.lan"},
+		{"..", "_"},
+		{".", "_"},
+		{"...hidden.", "hidden"},
+		{`..\..\Windows`, "_.._windows"},
+		{"../../etc", "_.._etc"},
+		{`C:\Temp`, "c__temp"},
+		{"a/b", "a_b"},
+		{"pc 042", "pc_042"},
+		{"pc:stream", "pc_stream"},
+		{"Büro", "b_ro"},
+		{"CON", "_con"},
+		{"nul", "_nul"},
+		{"Aux.local", "_aux.local"},
+		{"com1", "_com1"},
+		{"LPT9.corp", "_lpt9.corp"},
+		{"com10", "com10"},
+		{"console", "console"},
+		{"", "_"},
+		{strings.Repeat("x", 100), strings.Repeat("x", 64)},
+	}
+	for _, tt := range tests {
+		got := s.hostDir(tt.machine)
+		if want := filepath.Join(s.dir, "hosts", tt.want); got != want {
+			t.Errorf("hostDir(%q) = %q, want %q", tt.machine, got, want)
+		}
+		if filepath.Dir(got) != filepath.Join(s.dir, "hosts") {
+			t.Errorf("hostDir(%q) = %q, outside the hosts folder", tt.machine, got)
+		}
+	}
+}
+
+func TestReservedName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"con", true},
+		{"prn", true},
+		{"aux", true},
+		{"nul", true},
+		{"nul.txt", true},
+		{"com1", true},
+		{"com9.local", true},
+		{"lpt1", true},
+		{"com", false},
+		{"comx", false},
+		{"com10", false},
+		{"lpt", false},
+		{"null", false},
+		{"icon", false},
+		{"_con", false},
+	}
+	for _, tt := range tests {
+		if got := reservedName(tt.name); got != tt.want {
+			t.Errorf("reservedName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTrimGrowth(t *testing.T) {
+	now := time.Date(2026, 10, 15, 3, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	points := func(ages ...int) []scanPoint {
+		var p []scanPoint
+		for _, age := range ages {
+			p = append(p, scanPoint{At: now.Add(-time.Duration(age) * day), Size: int64(age)})
+		}
+		return p
+	}
+	sizes := func(p []scanPoint) []int64 {
+		var s []int64
+		for _, x := range p {
+			s = append(s, x.Size)
+		}
+		return s
+	}
+	tests := []struct {
+		name   string
+		in     []scanPoint
+		cutoff time.Time
+		want   []int64
+	}{
+		{"no retention", points(400, 100, 1, 0), time.Time{}, []int64{400, 100, 1, 0}},
+		{"older points go", points(100, 91, 90, 89, 0), now.Add(-90 * day), []int64{90, 89, 0}},
+		{"all too old", points(100, 95), now.Add(-90 * day), nil},
+		{"empty", nil, now, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sizes(trimGrowth(tt.in, tt.cutoff))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	// The newest maxGrowth points stay when retention is off.
+	var many []scanPoint
+	for i := maxGrowth + 50; i > 0; i-- {
+		many = append(many, scanPoint{At: now.Add(-time.Duration(i) * time.Hour), Size: int64(i)})
+	}
+	got := trimGrowth(many, time.Time{})
+	if len(got) != maxGrowth || got[0].Size != maxGrowth || got[len(got)-1].Size != 1 {
+		t.Errorf("got %d points from %d to %d, want %d from %d to 1",
+			len(got), got[0].Size, got[len(got)-1].Size, maxGrowth, maxGrowth)
+	}
+}
+
+func TestCutoff(t *testing.T) {
+	if c := (&store{}).cutoff(); !c.IsZero() {
+		t.Errorf("no retention: got %v, want zero", c)
+	}
+	s := &store{keep: 90 * 24 * time.Hour}
+	want := time.Now().Add(-s.keep)
+	if c := s.cutoff(); c.Sub(want).Abs() > time.Minute {
+		t.Errorf("90 days: got %v, want about %v", c, want)
+	}
+}
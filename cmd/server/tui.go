@@ -0,0 +1,288 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/winmole/winmole/internal/upload"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/tui"
+)
+
+// Styles
+var (
+	titleStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("205")).
+			MarginBottom(1)
+
+	labelStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241"))
+
+	valueStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("229")).
+			Bold(true)
+
+	selectedStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("229")).
+			Background(lipgloss.Color("57")).
+			Bold(true)
+
+	sizeStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("39"))
+
+	warnStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214"))
+)
+
+// detailChanges is how many of a folder's biggest changes the detail view
+// lists.
+const detailChanges = 8
+
+// model is the fleet view: every host in a list, and one host's cards,
+// CPU history and scans after Enter.
+type model struct {
+	store    *store
+	hosts    []host
+	selected int
+	detail   bool // showing hosts[selected]
+	listen   string
+	received int
+	status   string
+	width    int
+	height   int
+}
+
+// addedMsg reports a batch the API stored.
+type addedMsg struct {
+	machine string
+	kind    string
+}
+
+// serveErrMsg reports that the listener stopped.
+type serveErrMsg struct{ err error }
+
+type tickMsg time.Time
+
+func tick() tea.Cmd {
+	return tea.Tick(5*time.Second, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+func newModel(s *store, listen string) model {
+	return model{store: s, hosts: s.snapshot(), listen: listen, status: "Waiting for agents on " + listen}
+}
+
+func (m model) Init() tea.Cmd {
+	return tick()
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case tickMsg:
+		// Refresh the "last seen" times and offline marks.
+		m = m.refresh()
+		return m, tick()
+	case addedMsg:
+		m.received++
+		m.status = fmt.Sprintf("%s %s from %s", humanize.Time(time.Now()), msg.kind, msg.machine)
+		m = m.refresh()
+	case serveErrMsg:
+		m.status = fmt.Sprintf("Error: %v", msg.err)
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+// refresh reads the hosts again, keeping the selection on the same host.
+func (m model) refresh() model {
+	var current string
+	if m.selected < len(m.hosts) {
+		current = m.hosts[m.selected].Machine
+	}
+	m.hosts = m.store.snapshot()
+	m.selected = min(m.selected, max(len(m.hosts)-1, 0))
+	for i, h := range m.hosts {
+		if h.Machine == current {
+			m.selected = i
+		}
+	}
+	return m
+}
+
+func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "q":
+		if !m.detail {
+			return m, tea.Quit
+		}
+		m.detail = false
+	case "esc", "backspace", "left", "h":
+		m.detail = false
+	case "up", "k":
+		if m.selected > 0 {
+			m.selected--
+		}
+	case "down", "j":
+		if m.selected < len(m.hosts)-1 {
+			m.selected++
+		}
+	case "enter", "right", "l":
+		if len(m.hosts) > 0 {
+			m.detail = true
+		}
+	case "r":
+		m = m.refresh()
+	}
+	return m, nil
+}
+
+func (m model) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("WinMole server"))
+	b.WriteString("\n")
+	if m.detail && m.selected < len(m.hosts) {
+		b.WriteString(m.renderHost(m.hosts[m.selected]))
+	} else {
+		b.WriteString(m.renderList())
+	}
+	b.WriteString("\n")
+	b.WriteString(labelStyle.Render(fmt.Sprintf("%s • %d batches received since start", m.status, m.received)))
+	b.WriteString("\n")
+	if m.detail {
+		b.WriteString(labelStyle.Render("Esc back • q back • Ctrl+C quit"))
+	} else {
+		b.WriteString(labelStyle.Render("↑↓ select • Enter details • r refresh • q quit"))
+	}
+	return b.String()
+}
+
+// renderList shows one line per host.
+func (m model) renderList() string {
+	if len(m.hosts) == 0 {
+		return labelStyle.Render("  No agent has pushed yet. Set \"server\" in config.json on each PC and run\n"+
+			"  winmole analyze schedule --push and winmole status --push.") + "\n"
+	}
+	var b strings.Builder
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  %-20s %-14s %6s %6s %12s %10s %10s", "Host", "Last seen", "CPU", "Mem", "System drive", "Scanned", "Change")))
+	b.WriteString("\n")
+	for i, h := range m.hosts {
+		seen := humanize.Time(h.LastSeen)
+		cpu, mem, disk := "-", "-", "-"
+		if s, ok := h.latest(); ok {
+			mm := sampleMetrics(s)
+			cpu, mem = fmt.Sprintf("%.0f%%", mm.CPUUsage), fmt.Sprintf("%.0f%%", mm.MemInUsePercent)
+			if mm.DiskTotal > 0 {
+				disk = fmt.Sprintf("%s %.0f%%", mm.DiskPath, mm.DiskPercent)
+			}
+		}
+		scanned, change := "-", ""
+		if len(h.Scans) > 0 {
+			total, diff := scanTotals(h)
+			scanned, change = humanize.Bytes(total), signedBytes(diff)
+		}
+		line := fmt.Sprintf("  %-20s %-14s %6s %6s %12s %10s %10s",
+			humanize.Truncate(h.Machine, 20), humanize.Truncate(seen, 14), cpu, mem, disk, scanned, change)
+		switch {
+		case i == m.selected:
+			line = selectedStyle.Render(line)
+		case time.Since(h.LastSeen) > staleAfter:
+			line = warnStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// renderHost shows the status cards from the host's latest sample, its CPU
+// history and the biggest changes of each scanned folder.
+func (m model) renderHost(h host) string {
+	var b strings.Builder
+	head := valueStyle.Render(h.Machine) + labelStyle.Render(" • last seen "+humanize.Time(h.LastSeen))
+	if h.Addr != "" {
+		head += labelStyle.Render(" from " + h.Addr)
+	}
+	if time.Since(h.LastSeen) > staleAfter {
+		head += warnStyle.Render(" • offline")
+	}
+	b.WriteString(head + "\n\n")
+
+	if s, ok := h.latest(); ok {
+		mm := sampleMetrics(s)
+		cards := make([]string, 0, 4)
+		for _, name := range []string{tui.CardCPU, tui.CardMemory, tui.CardDisk, tui.CardNetwork} {
+			cards = append(cards, tui.Card(name, mm))
+		}
+		per := 2
+		if m.width >= 4*43 {
+			per = 4
+		}
+		for start := 0; start < len(cards); start += per {
+			b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, cards[start:min(start+per, len(cards))]...))
+			b.WriteString("\n")
+		}
+		width := max(min(m.width-12, 120), 20)
+		b.WriteString(labelStyle.Render("CPU  ") + tui.Spark(cpuHistory(h, width), 100))
+		b.WriteString(labelStyle.Render(fmt.Sprintf("  last %d samples", len(cpuHistory(h, width)))))
+		b.WriteString("\n")
+	} else {
+		b.WriteString(labelStyle.Render("  No metrics yet: run winmole status --push on it.") + "\n")
+	}
+
+	for _, root := range h.roots() {
+		d := h.Scans[root]
+		b.WriteString("\n")
+		line := valueStyle.Render(root) + " " + sizeStyle.Render(humanize.Bytes(d.TotalSize))
+		if d.Since != nil {
+			line += labelStyle.Render(fmt.Sprintf(" • %s since %s", signedBytes(d.TotalSize-d.SinceSize), humanize.Time(*d.Since)))
+		} else {
+			line += labelStyle.Render(" • first scan")
+		}
+		line += labelStyle.Render(" • scanned " + humanize.Time(d.ScannedAt))
+		b.WriteString(line + "\n")
+		if d.Since == nil {
+			continue
+		}
+		for _, c := range topChanges(d.Changes, detailChanges) {
+			note := ""
+			if c.Status != "" {
+				note = " (" + c.Status + ")"
+			}
+			b.WriteString(fmt.Sprintf("  %10s  %s\n", signedBytes(c.After-c.Before), humanize.Truncate(c.Path, max(m.width-16, 30))+labelStyle.Render(note)))
+		}
+	}
+	return b.String()
+}
+
+// topChanges returns the n changes that moved the most bytes.
+func topChanges(changes []upload.ScanChange, n int) []upload.ScanChange {
+	sorted := append([]upload.ScanChange(nil), changes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return abs(sorted[i].After-sorted[i].Before) > abs(sorted[j].After-sorted[j].Before)
+	})
+	return sorted[:min(n, len(sorted))]
+}
+
+func signedBytes(n int64) string {
+	if n < 0 {
+		return "-" + humanize.Bytes(-n)
+	}
+	return "+" + humanize.Bytes(n)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,139 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/metrics"
+)
+
+// The web page shows every host with the dashboard's four cards and its
+// scanned folders. It is read-only, has no scripts and reloads itself, so
+// it can stay open on a wall screen.
+
+// webHost is one host on the page.
+type webHost struct {
+	Machine  string
+	Addr     string
+	LastSeen time.Time
+	Offline  bool
+	HasData  bool
+	M        metrics.Metrics
+	Scans    []webScan
+}
+
+type webScan struct {
+	Root      string
+	Total     int64
+	Change    int64
+	HasChange bool
+	ScannedAt time.Time
+}
+
+var pageFuncs = template.FuncMap{
+	"bytes": func(n any) string {
+		switch v := n.(type) {
+		case int64:
+			return humanize.Bytes(v)
+		case uint64:
+			return humanize.Bytes(v)
+		case float64:
+			return humanize.Bytes(uint64(v))
+		}
+		return fmt.Sprint(n)
+	},
+	"change": signedBytes,
+	"pct":    func(p float64) string { return fmt.Sprintf("%.1f%%", p) },
+	"width":  func(p float64) string { return fmt.Sprintf("%.0f", min(max(p, 0), 100)) },
+	"level": func(p float64) string {
+		switch {
+		case p >= 90:
+			return "high"
+		case p >= 70:
+			return "med"
+		}
+		return "low"
+	},
+	"when": humanize.Time,
+}
+
+var pageTemplate = template.Must(template.New("page").Funcs(pageFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="30">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>WinMole server</title>
+<style>
+body { background: #1c1c1c; color: #d0d0d0; font: 14px/1.4 "Cascadia Mono", Consolas, monospace; margin: 1.5em; }
+h1 { color: #ff5faf; font-size: 1.2em; }
+h2 { color: #ffffaf; font-size: 1.05em; margin: 1.5em 0 .5em; }
+.dim, .label { color: #626262; }
+.offline { color: #ffaf00; }
+.cards { display: flex; flex-wrap: wrap; gap: .6em; }
+.card { border: 1px solid #5f5fd7; border-radius: 6px; padding: .4em .8em; width: 20em; }
+.card b { color: #ffffaf; }
+.bar { display: inline-block; width: 10em; height: .7em; background: #585858; vertical-align: middle; }
+.bar span { display: block; height: 100%; }
+.low { background: #00d787; } .med { background: #ffff00; } .high { background: #ff0000; }
+table { border-collapse: collapse; margin-top: .6em; }
+td { padding: 0 1em 0 0; }
+td.size { color: #00afff; text-align: right; }
+</style>
+</head>
+<body>
+<h1>WinMole server</h1>
+<p class="dim">{{len .}} hosts • reloads every 30 seconds</p>
+{{range .}}
+<h2>{{.Machine}} <span class="{{if .Offline}}offline{{else}}dim{{end}}">• last seen {{when .LastSeen}}{{if .Offline}} (offline){{end}}{{if .Addr}} from {{.Addr}}{{end}}</span></h2>
+{{if .HasData}}{{with .M}}
+<div class="cards">
+<div class="card"><b>CPU</b><br><span class="label">{{.CPUModel}}</span><br><br>
+<span class="label">Usage:</span> <span class="bar"><span class="{{level .CPUUsage}}" style="width:{{width .CPUUsage}}%"></span></span> {{pct .CPUUsage}}<br>
+<span class="label">Cores: {{.CPUCores}}</span></div>
+<div class="card"><b>Memory</b><br><span class="label">{{bytes .MemInUse}} / {{bytes .MemTotal}} in use</span><br><br>
+<span class="label">Usage:</span> <span class="bar"><span class="{{level .MemInUsePercent}}" style="width:{{width .MemInUsePercent}}%"></span></span> {{pct .MemInUsePercent}}<br>
+<span class="label">Committed: {{bytes .MemCommitted}} / {{bytes .MemCommitLimit}}</span><br>
+<span class="label">Cached: {{bytes .MemCached}}</span></div>
+<div class="card"><b>Disk ({{.DiskPath}})</b><br><span class="label">{{bytes .DiskUsed}} / {{bytes .DiskTotal}}</span><br><br>
+<span class="label">Usage:</span> <span class="bar"><span class="{{level .DiskPercent}}" style="width:{{width .DiskPercent}}%"></span></span> {{pct .DiskPercent}}</div>
+<div class="card"><b>Network</b><br><span class="label">Traffic rates</span><br><br>
+<span class="label">↑ Upload:</span> <b>{{bytes .NetSentRate}}/s</b><br>
+<span class="label">↓ Download:</span> <b>{{bytes .NetRecvRate}}/s</b></div>
+</div>
+{{end}}{{else}}<p class="dim">No metrics yet: run winmole status --push on it.</p>{{end}}
+{{if .Scans}}<table>
+{{range .Scans}}<tr><td class="size">{{bytes .Total}}</td><td>{{.Root}}</td><td class="dim">{{if .HasChange}}{{change .Change}} since the scan before{{else}}first scan{{end}} • scanned {{when .ScannedAt}}</td></tr>
+{{end}}</table>{{end}}
+{{else}}
+<p class="dim">No agent has pushed yet. Set "server": {"url": ..., "token": ...} in config.json on each PC and run winmole analyze schedule --push and winmole status --push.</p>
+{{end}}
+</body>
+</html>
+`))
+
+// renderPage writes the page for hosts.
+func renderPage(w io.Writer, hosts []host) error {
+	var page []webHost
+	for _, h := range hosts {
+		wh := webHost{Machine: h.Machine, Addr: h.Addr, LastSeen: h.LastSeen, Offline: time.Since(h.LastSeen) > staleAfter}
+		if s, ok := h.latest(); ok {
+			wh.HasData, wh.M = true, sampleMetrics(s)
+		}
+		for _, root := range h.roots() {
+			d := h.Scans[root]
+			scan := webScan{Root: root, Total: d.TotalSize, ScannedAt: d.ScannedAt}
+			if d.Since != nil {
+				scan.HasChange, scan.Change = true, d.TotalSize-d.SinceSize
+			}
+			wh.Scans = append(wh.Scans, scan)
+		}
+		page = append(page, wh)
+	}
+	return pageTemplate.Execute(w, page)
+}
@@ -0,0 +1,216 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// attributionBucketSpan is the resolution of the attribution history.
+const attributionBucketSpan = time.Minute
+
+// attributionMaxBuckets keeps one day of per-minute buckets.
+const attributionMaxBuckets = 24 * 60
+
+// attributionWindows are the report windows cycled with 'w'. Zero means
+// everything since monitoring started (or was reset).
+var attributionWindows = []time.Duration{5 * time.Minute, 15 * time.Minute, time.Hour, 0}
+
+// processKey identifies a process instance; the name guards against PID reuse.
+type processKey struct {
+	PID  int32
+	Name string
+}
+
+// processUsage is the resource consumption attributed to one process.
+type processUsage struct {
+	CPUSeconds float64
+	ReadBytes  uint64
+	WriteBytes uint64
+}
+
+func (u *processUsage) add(o processUsage) {
+	u.CPUSeconds += o.CPUSeconds
+	u.ReadBytes += o.ReadBytes
+	u.WriteBytes += o.WriteBytes
+}
+
+type attributionBucket struct {
+	start time.Time
+	usage map[processKey]processUsage
+}
+
+// attribution accumulates per-process CPU time and disk I/O deltas between
+// samples, so processes that exited inside the window are still counted.
+type attribution struct {
+	since   time.Time
+	last    map[processKey]ProcessInfo
+	buckets []attributionBucket
+}
+
+func newAttribution() *attribution {
+	return &attribution{}
+}
+
+// reset starts a new measurement window at now.
+func (a *attribution) reset(now time.Time) {
+	// The last sample is kept so the next delta is measured from it.
+	a.since = now
+	a.buckets = nil
+}
+
+// observe records the deltas between the previous and the current process list.
+func (a *attribution) observe(at time.Time, procs []ProcessInfo) {
+	current := make(map[processKey]ProcessInfo, len(procs))
+	for _, p := range procs {
+		current[processKey{PID: p.PID, Name: p.Name}] = p
+	}
+
+	if a.last == nil {
+		// First sample: only establish the baseline.
+		a.last = current
+		a.since = at
+		return
+	}
+
+	bucket := a.bucketFor(at)
+	for key, p := range current {
+		var delta processUsage
+		if before, ok := a.last[key]; ok {
+			if p.CPUTime > before.CPUTime {
+				delta.CPUSeconds = p.CPUTime - before.CPUTime
+			}
+			if p.ReadBytes > before.ReadBytes {
+				delta.ReadBytes = p.ReadBytes - before.ReadBytes
+			}
+			if p.WriteBytes > before.WriteBytes {
+				delta.WriteBytes = p.WriteBytes - before.WriteBytes
+			}
+		} else {
+			// Started since the last sample, so all of its usage is new.
+			delta = processUsage{CPUSeconds: p.CPUTime, ReadBytes: p.ReadBytes, WriteBytes: p.WriteBytes}
+		}
+		if delta == (processUsage{}) {
+			continue
+		}
+		u := bucket.usage[key]
+		u.add(delta)
+		bucket.usage[key] = u
+	}
+	a.last = current
+}
+
+func (a *attribution) bucketFor(at time.Time) *attributionBucket {
+	start := at.Truncate(attributionBucketSpan)
+	if n := len(a.buckets); n > 0 && a.buckets[n-1].start.Equal(start) {
+		return &a.buckets[n-1]
+	}
+	a.buckets = append(a.buckets, attributionBucket{start: start, usage: make(map[processKey]processUsage)})
+	if len(a.buckets) > attributionMaxBuckets {
+		a.buckets = a.buckets[len(a.buckets)-attributionMaxBuckets:]
+	}
+	return &a.buckets[len(a.buckets)-1]
+}
+
+// attributionRow is one line of the report.
+type attributionRow struct {
+	Key processKey
+	processUsage
+}
+
+// report sums usage over the trailing window (0 = since start), heaviest first.
+func (a *attribution) report(now time.Time, window time.Duration) []attributionRow {
+	totals := make(map[processKey]processUsage)
+	cutoff := time.Time{}
+	if window > 0 {
+		cutoff = now.Add(-window).Truncate(attributionBucketSpan)
+	}
+	for _, b := range a.buckets {
+		if b.start.Before(cutoff) {
+			continue
+		}
+		for key, u := range b.usage {
+			t := totals[key]
+			t.add(u)
+			totals[key] = t
+		}
+	}
+
+	rows := make([]attributionRow, 0, len(totals))
+	for key, u := range totals {
+		rows = append(rows, attributionRow{Key: key, processUsage: u})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].CPUSeconds != rows[j].CPUSeconds {
+			return rows[i].CPUSeconds > rows[j].CPUSeconds
+		}
+		return rows[i].ReadBytes+rows[i].WriteBytes > rows[j].ReadBytes+rows[j].WriteBytes
+	})
+	return rows
+}
+
+func (m model) renderAttribution() string {
+	now := m.metrics.CollectedAt
+	window := attributionWindows[m.attrWindow]
+	rows := m.attribution.report(now, window)
+
+	// Average CPU is taken over the time actually covered.
+	span := now.Sub(m.attribution.since)
+	label := "since " + m.attribution.since.Format("15:04:05")
+	if window > 0 {
+		label = "over the last " + humanize.Duration(window)
+		if span < window {
+			label += fmt.Sprintf(" (%s recorded)", humanize.Duration(span))
+		}
+	}
+	if window == 0 || span < window {
+		window = span
+	}
+
+	var b strings.Builder
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  Cumulative usage %s • %d processes", label, len(rows))))
+	b.WriteString("\n\n")
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  %7s  %-32s %10s %8s %12s %12s",
+		"PID", "Name", "CPU time", "Avg CPU", "Read", "Written")))
+	b.WriteString("\n")
+
+	cores := float64(m.metrics.CPUCores)
+	if cores < 1 {
+		cores = 1
+	}
+	limit := m.contentHeight() - 3
+	for i, r := range rows {
+		if i >= limit {
+			break
+		}
+		avg := 0.0
+		if window > 0 {
+			avg = r.CPUSeconds / window.Seconds() / cores * 100
+		}
+		b.WriteString(fmt.Sprintf("  %7d  %-32s %10s %7.1f%% %12s %12s\n",
+			r.Key.PID,
+			humanize.Truncate(r.Key.Name, 32),
+			formatCPUTime(r.CPUSeconds),
+			avg,
+			humanize.Bytes(r.ReadBytes),
+			humanize.Bytes(r.WriteBytes)))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// formatCPUTime renders CPU seconds as "1h02m", "3m15s" or "12.4s".
+func formatCPUTime(seconds float64) string {
+	d := time.Duration(seconds * float64(time.Second))
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	return fmt.Sprintf("%.1fs", seconds)
+}
@@ -0,0 +1,139 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unsafe"
+
+	"github.com/winmole/winmole/pkg/humanize"
+	"golang.org/x/sys/windows"
+)
+
+// Much of the load on an otherwise idle PC is Windows housekeeping: the
+// search indexer catching up, a Defender scan, .NET precompiling assemblies
+// after an update, Delivery Optimization fetching or sharing updates. When
+// one of them is busy the health line says so in words, instead of leaving
+// users to guess from process names like mscorsvw.exe or svchost.exe.
+
+// backgroundTask is a piece of Windows housekeeping, recognised by its
+// processes or by the service it runs as.
+type backgroundTask struct {
+	doing     string
+	processes []string // lower-case image names
+	service   string   // its host process counts too (a shared svchost)
+}
+
+var backgroundTasks = []backgroundTask{
+	{doing: "Windows Search is indexing", processes: []string{"searchindexer.exe", "searchprotocolhost.exe", "searchfilterhost.exe"}},
+	{doing: "Microsoft Defender is scanning", processes: []string{"msmpeng.exe", "mpcmdrun.exe", "nissrv.exe", "mpdefendercoreservice.exe"}},
+	{doing: ".NET is precompiling assemblies (NGen)", processes: []string{"ngen.exe", "ngentask.exe", "mscorsvw.exe"}},
+	{doing: "Delivery Optimization is transferring updates", service: "DoSvc"},
+}
+
+// A task counts as active from this much CPU or I/O.
+const (
+	backgroundMinCPU = 5.0     // percent of the machine
+	backgroundMinIO  = 1 << 20 // bytes per second
+)
+
+// backgroundActivity is a task found busy in the latest sample.
+type backgroundActivity struct {
+	doing string
+	cpu   float64 // percent of the machine
+	io    float64 // bytes per second read and written, disk and network
+}
+
+// explainBackground returns the tasks busy in cur, measuring I/O against
+// prev, the most CPU first.
+func explainBackground(cur, prev Metrics) []backgroundActivity {
+	seconds := cur.CollectedAt.Sub(prev.CollectedAt).Seconds()
+	before := make(map[processKey]ProcessInfo, len(prev.Processes))
+	for _, p := range prev.Processes {
+		before[processKey{PID: p.PID, Name: p.Name}] = p
+	}
+
+	var active []backgroundActivity
+	for _, task := range backgroundTasks {
+		var hostPID int32
+		if task.service != "" {
+			pid, err := servicePID(task.service)
+			if err != nil || pid == 0 {
+				continue
+			}
+			hostPID = int32(pid)
+		}
+
+		a := backgroundActivity{doing: task.doing}
+		for _, p := range cur.Processes {
+			if !(task.service != "" && p.PID == hostPID) && !containsName(task.processes, p.Name) {
+				continue
+			}
+			a.cpu += p.CPUPercent
+			b, ok := before[processKey{PID: p.PID, Name: p.Name}]
+			if now, was := p.ReadBytes+p.WriteBytes, b.ReadBytes+b.WriteBytes; ok && seconds > 0 && now > was {
+				a.io += float64(now-was) / seconds
+			}
+		}
+		if a.cpu >= backgroundMinCPU || a.io >= backgroundMinIO {
+			active = append(active, a)
+		}
+	}
+
+	sort.SliceStable(active, func(i, j int) bool { return active[i].cpu > active[j].cpu })
+	return active
+}
+
+func containsName(names []string, name string) bool {
+	name = strings.ToLower(name)
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// servicePID returns the process a running service lives in, or 0 when it
+// is stopped. Querying status needs no admin rights.
+func servicePID(name string) (uint32, error) {
+	scm, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_CONNECT)
+	if err != nil {
+		return 0, err
+	}
+	defer windows.CloseServiceHandle(scm)
+
+	svcName, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return 0, err
+	}
+	svc, err := windows.OpenService(scm, svcName, windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return 0, err
+	}
+	defer windows.CloseServiceHandle(svc)
+
+	var status windows.SERVICE_STATUS_PROCESS
+	var needed uint32
+	err = windows.QueryServiceStatusEx(svc, windows.SC_STATUS_PROCESS_INFO,
+		(*byte)(unsafe.Pointer(&status)), uint32(unsafe.Sizeof(status)), &needed)
+	if err != nil {
+		return 0, err
+	}
+	return status.ProcessId, nil
+}
+
+// renderBackground names the busy background tasks for the health line,
+// or returns "".
+func (m model) renderBackground() string {
+	if len(m.background) == 0 {
+		return ""
+	}
+	parts := make([]string, len(m.background))
+	for i, a := range m.background {
+		parts[i] = fmt.Sprintf("%s (%.0f%% CPU, %s/s I/O)", a.doing, a.cpu, humanize.Bytes(int64(a.io)))
+	}
+	return warnStyle.Render("  ⚙ " + strings.Join(parts, " • "))
+}
@@ -0,0 +1,40 @@
+//go:build windows
+
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/pkg/metrics"
+	"time"
+)
+
+// The sampled figures and their collectors live in pkg/metrics so other
+// programs can embed them; the dashboard refers to them by these names.
+type (
+	Metrics          = metrics.Metrics
+	DiskInfo         = metrics.DiskInfo
+	InterfaceInfo    = metrics.InterfaceInfo
+	ConnectionCounts = metrics.ConnectionCounts
+	ProcessInfo      = metrics.ProcessInfo
+)
+
+// collectMetrics samples the given collectors in the background. The guard
+// runs them side by side, so a hung WMI or performance counter provider
+// cannot hold up the others: it gets a deadline, after which its last values
+// are kept and shown as stale, and is paused if it keeps failing.
+func collectMetrics(guard *metrics.Guard, due []metrics.Collector) tea.Cmd {
+	return func() tea.Msg {
+		return metricsMsg(guard.Collect(due))
+	}
+}
+
+// configureGuard applies the per-collector timeouts from the settings.
+func configureGuard(guard *metrics.Guard, cfg statusConfig) {
+	for _, c := range metrics.All() {
+		p := metrics.DefaultPolicy
+		if secs := cfg.Collectors[c.Name()].TimeoutSeconds; secs > 0 {
+			p.Timeout = time.Duration(secs) * time.Second
+		}
+		guard.SetPolicy(c.Name(), p)
+	}
+}
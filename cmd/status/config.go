@@ -0,0 +1,94 @@
+//go:build windows
+
+package main
+
+import (
+	"github.com/winmole/winmole/internal/config"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/metrics"
+)
+
+// configSection is the key of the dashboard's settings in config.json.
+const configSection = "status"
+
+// statusConfig holds the user-tunable dashboard settings.
+type statusConfig struct {
+	// Layout lists the Overview cards to show, in order. Cards missing
+	// from the list are hidden; leaving it unset shows every card.
+	Layout []string `json:"layout"`
+
+	// Snapshots sets the thresholds for automatic process snapshots.
+	Snapshots snapshotConfig `json:"snapshots"`
+
+	// IdleAfterSeconds is how long without keyboard or mouse input before
+	// the user counts as idle. Zero disables idle detection.
+	IdleAfterSeconds int `json:"idleAfterSeconds"`
+
+	// Collectors maps collector names (cpu, memory, disk, network,
+	// processes, host, pressure) to their idle behaviour.
+	Collectors map[string]collectorConfig `json:"collectors,omitempty"`
+
+	// CPUMethod is how CPU usage is measured: "time" (busy time) or
+	// "utility" (% Processor Utility, matching Task Manager).
+	CPUMethod string `json:"cpuMethod,omitempty"`
+}
+
+func defaultConfig() statusConfig {
+	return statusConfig{
+		Snapshots:        defaultSnapshotConfig(),
+		IdleAfterSeconds: 300,
+	}
+}
+
+// loadConfig returns the dashboard settings, falling back to defaults for
+// anything the user has not set, and applies the shared units and times
+// settings.
+func loadConfig() (statusConfig, error) {
+	cfg := defaultConfig()
+	if err := config.Load(configSection, &cfg); err != nil {
+		return defaultConfig(), err
+	}
+	if err := config.ApplyUnits(); err != nil {
+		return defaultConfig(), err
+	}
+	if err := config.ApplyTimes(); err != nil {
+		return defaultConfig(), err
+	}
+	return cfg, nil
+}
+
+// saveConfig persists the dashboard settings.
+func saveConfig(cfg statusConfig) error {
+	return config.Save(configSection, cfg)
+}
+
+// toggleTimes switches between absolute and relative times for this run;
+// the times setting in config.json chooses the one to start with.
+func toggleTimes() string {
+	if humanize.CurrentTimes() == humanize.Absolute {
+		humanize.SetTimes(humanize.Relative)
+		return "Times shown relative to now"
+	}
+	humanize.SetTimes(humanize.Absolute)
+	return "Times shown as local date and time"
+}
+
+// profileLabel names a profile for display.
+func profileLabel(name string) string {
+	if name == "" {
+		return "(base settings)"
+	}
+	return name
+}
+
+// applyConfig swaps in settings loaded for another profile, keeping the
+// collected data and saved snapshots.
+func (m model) applyConfig(cfg statusConfig) model {
+	m.config = cfg
+	m.layout = newLayout(cfg.Layout)
+	m.snapshots.cfg = cfg.Snapshots
+	m.schedule = newSchedule(cfg)
+	configureGuard(m.guard, cfg)
+	metrics.SetCPUMethod(cfg.CPUMethod)
+	return m
+}
@@ -0,0 +1,34 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/tui"
+)
+
+func (m model) renderDisks() string {
+	if len(m.metrics.Disks) == 0 {
+		return labelStyle.Render("  No volumes found")
+	}
+
+	var b strings.Builder
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  %-6s %-6s %-28s %19s %12s %12s",
+		"Drive", "FS", "Usage", "Used / Total", "Read", "Write")))
+	b.WriteString("\n")
+
+	for _, d := range m.metrics.Disks {
+		b.WriteString(fmt.Sprintf("  %-6s %-6s %s %5.1f%% %19s %12s %12s\n",
+			d.Mount,
+			d.FSType,
+			tui.Bar(d.Percent, 20),
+			d.Percent,
+			humanize.Bytes(d.Used)+" / "+humanize.Bytes(d.Total),
+			humanize.Bytes(uint64(d.ReadRate))+"/s",
+			humanize.Bytes(uint64(d.WriteRate))+"/s"))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
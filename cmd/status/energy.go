@@ -0,0 +1,365 @@
+//go:build windows
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unsafe"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/elevate"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/tui"
+	"golang.org/x/sys/windows"
+)
+
+// energyWindows are the report windows cycled with 'w' on the Energy tab.
+var energyWindows = []struct {
+	label string
+	days  int
+}{
+	{"today", 0},
+	{"last 7 days", 7},
+}
+
+// energyUsage is the energy Windows attributed to one app.
+type energyUsage struct {
+	App     string
+	Path    string
+	Total   float64 // as reported by the energy estimator (mJ)
+	CPU     float64
+	Display float64
+	Network float64
+}
+
+// energyRecord is one row of the SRUM energy estimation table.
+type energyRecord struct {
+	energyUsage
+	At time.Time
+}
+
+// energyView is the state of the Energy tab. The data is loaded on demand
+// because powercfg takes a few seconds and needs admin rights.
+type energyView struct {
+	records  []energyRecord
+	loadedAt time.Time
+	err      error
+	loading  bool
+	window   int
+}
+
+type energyMsg struct {
+	records []energyRecord
+	err     error
+}
+
+// needsLoad reports whether opening the tab should start a load.
+func (v energyView) needsLoad() bool {
+	return !v.loading && v.loadedAt.IsZero() && v.err == nil
+}
+
+// loadEnergy exports the SRUM energy table in the background.
+func loadEnergy() tea.Cmd {
+	return func() tea.Msg {
+		records, err := readEnergyRecords()
+		return energyMsg{records: records, err: err}
+	}
+}
+
+// readEnergyRecords needs admin rights, so the export runs in the elevated
+// helper unless winmole is already elevated.
+func readEnergyRecords() ([]energyRecord, error) {
+	out, err := elevate.RunFileOp(elevate.OpEnergyReport)
+	if err != nil {
+		return nil, fmt.Errorf("energy report: %w", err)
+	}
+	defer os.Remove(out)
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		return nil, fmt.Errorf("read energy report: %w", err)
+	}
+	return parseEnergyCSV(decodeText(data))
+}
+
+// decodeText converts powercfg output, which may be UTF-16 with a BOM, to a string.
+func decodeText(data []byte) string {
+	if len(data) >= 2 && data[0] == 0xFF && data[1] == 0xFE {
+		data = data[2:]
+		u := make([]uint16, len(data)/2)
+		for i := range u {
+			u[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+		}
+		return string(utf16.Decode(u))
+	}
+	return string(bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF")))
+}
+
+// parseEnergyCSV reads the columns it knows by header name, so the report
+// survives columns being added between Windows releases.
+func parseEnergyCSV(text string) ([]energyRecord, error) {
+	r := csv.NewReader(strings.NewReader(text))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("energy report header: %w", err)
+	}
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[strings.TrimSpace(name)] = i
+	}
+	appCol, ok := col["AppId"]
+	if !ok {
+		return nil, errors.New("energy report has no AppId column")
+	}
+	timeCol, ok := col["TimeStamp"]
+	if !ok {
+		return nil, errors.New("energy report has no TimeStamp column")
+	}
+
+	value := func(row []string, name string) float64 {
+		i, ok := col[name]
+		if !ok || i >= len(row) {
+			return 0
+		}
+		v, _ := strconv.ParseFloat(strings.TrimSpace(row[i]), 64)
+		return v
+	}
+
+	var records []energyRecord
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("energy report: %w", err)
+		}
+		if appCol >= len(row) || timeCol >= len(row) {
+			continue
+		}
+		at, ok := parseSRUMTime(row[timeCol])
+		if !ok {
+			continue
+		}
+
+		rec := energyRecord{At: at}
+		rec.Path = strings.TrimSpace(row[appCol])
+		rec.App = appName(rec.Path)
+		rec.CPU = value(row, "CPUEnergyConsumption")
+		rec.Display = value(row, "DisplayEnergyConsumption")
+		rec.Network = value(row, "NetworkEnergyConsumption") + value(row, "MBBEnergyConsumption")
+		rec.Total = value(row, "TotalEnergyConsumption")
+		if rec.Total == 0 {
+			// Older builds have no total column; sum the components.
+			rec.Total = rec.CPU + rec.Display + rec.Network +
+				value(row, "SocEnergyConsumption") +
+				value(row, "DiskEnergyConsumption") +
+				value(row, "OtherEnergyConsumption")
+		}
+		if rec.Total > 0 {
+			records = append(records, rec)
+		}
+	}
+	return records, nil
+}
+
+// parseSRUMTime accepts the timestamp formats powercfg has used.
+func parseSRUMTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{
+		"2006-01-02 15:04:05",
+		"2006-01-02T15:04:05",
+		"1/2/2006 3:04:05 PM",
+		"1/2/2006 15:04:05",
+		time.RFC3339,
+	} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// appName shortens a SRUM app identifier to something readable: a full
+// image path becomes its file name and packaged apps lose their suffixes.
+func appName(id string) string {
+	if strings.HasPrefix(id, "!!") {
+		// "!!chrome.exe!2020/01/01:00:00:00!0!"
+		if parts := strings.Split(strings.TrimPrefix(id, "!!"), "!"); parts[0] != "" {
+			return parts[0]
+		}
+	}
+	if i := strings.LastIndexAny(id, `\/`); i >= 0 && i < len(id)-1 {
+		return id[i+1:]
+	}
+	if i := strings.Index(id, "_"); i > 0 {
+		return id[:i]
+	}
+	return id
+}
+
+// energyReport sums records per app since the start of the selected window.
+func energyReport(records []energyRecord, now time.Time, days int) ([]energyUsage, float64) {
+	y, mo, d := now.Date()
+	since := time.Date(y, mo, d, 0, 0, 0, 0, now.Location())
+	if days > 0 {
+		since = now.AddDate(0, 0, -days)
+	}
+
+	byApp := make(map[string]*energyUsage)
+	var total float64
+	for _, r := range records {
+		if r.At.Before(since) {
+			continue
+		}
+		u, ok := byApp[r.Path]
+		if !ok {
+			u = &energyUsage{App: r.App, Path: r.Path}
+			byApp[r.Path] = u
+		}
+		u.Total += r.Total
+		u.CPU += r.CPU
+		u.Display += r.Display
+		u.Network += r.Network
+		total += r.Total
+	}
+
+	list := make([]energyUsage, 0, len(byApp))
+	for _, u := range byApp {
+		list = append(list, *u)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Total > list[j].Total })
+	return list, total
+}
+
+var (
+	kernel32                 = windows.NewLazySystemDLL("kernel32.dll")
+	procGetSystemPowerStatus = kernel32.NewProc("GetSystemPowerStatus")
+)
+
+// systemPowerStatus mirrors the Win32 SYSTEM_POWER_STATUS structure.
+type systemPowerStatus struct {
+	ACLineStatus        byte
+	BatteryFlag         byte
+	BatteryLifePercent  byte
+	SystemStatusFlag    byte
+	BatteryLifeTime     uint32
+	BatteryFullLifeTime uint32
+}
+
+// batterySummary describes the battery state in one line, or "" on
+// machines without a battery.
+func batterySummary() string {
+	var s systemPowerStatus
+	if r, _, _ := procGetSystemPowerStatus.Call(uintptr(unsafe.Pointer(&s))); r == 0 {
+		return ""
+	}
+	// 128 = no system battery, 255 = unknown
+	if s.BatteryFlag&128 != 0 || s.BatteryFlag == 255 {
+		return ""
+	}
+
+	line := "Battery"
+	if s.BatteryLifePercent <= 100 {
+		line += fmt.Sprintf(" %d%%", s.BatteryLifePercent)
+	}
+	switch {
+	case s.BatteryFlag&8 != 0:
+		line += " • charging"
+	case s.ACLineStatus == 1:
+		line += " • plugged in"
+	default:
+		line += " • on battery"
+		if s.BatteryLifeTime != 0xFFFFFFFF {
+			line += " • " + humanize.Duration(time.Duration(s.BatteryLifeTime)*time.Second) + " left"
+		}
+	}
+	return line
+}
+
+func (m model) handleEnergyKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "w":
+		m.energy.window = (m.energy.window + 1) % len(energyWindows)
+	case "r":
+		if !m.energy.loading {
+			m.energy.loading = true
+			m.energy.err = nil
+			return m, loadEnergy()
+		}
+	}
+	return m, nil
+}
+
+func (m model) renderEnergy() string {
+	var b strings.Builder
+
+	if battery := batterySummary(); battery != "" {
+		b.WriteString(valueStyle.Render("  " + battery))
+	} else {
+		b.WriteString(labelStyle.Render("  No battery detected; figures show estimated energy use on mains power"))
+	}
+	b.WriteString("\n\n")
+
+	switch {
+	case m.energy.loading:
+		b.WriteString(labelStyle.Render("  Reading Windows energy estimates (powercfg /srumutil)..."))
+		return b.String()
+	case m.energy.err != nil:
+		b.WriteString(warnStyle.Render(fmt.Sprintf("  Energy data unavailable: %v", m.energy.err)))
+		return b.String()
+	}
+
+	window := energyWindows[m.energy.window]
+	usage, total := energyReport(m.energy.records, time.Now(), window.days)
+	if total == 0 {
+		b.WriteString(labelStyle.Render("  No energy data recorded " + window.label + "."))
+		return b.String()
+	}
+
+	running := make(map[string]bool, len(m.metrics.Processes))
+	for _, p := range m.metrics.Processes {
+		running[strings.ToLower(p.Name)] = true
+	}
+
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  Energy use %s • %d apps • data as of %s",
+		window.label, len(usage), m.energy.loadedAt.Format("15:04"))))
+	b.WriteString("\n\n")
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  %-32s %7s %-20s %7s %7s %7s  %s",
+		"App", "Share", "", "CPU", "Display", "Network", "Running")))
+	b.WriteString("\n")
+
+	limit := m.contentHeight() - 5
+	for i, u := range usage {
+		if i >= limit {
+			break
+		}
+		share := u.Total / total * 100
+		state := ""
+		if running[strings.ToLower(u.App)] {
+			state = "●"
+		}
+		b.WriteString(fmt.Sprintf("  %-32s %6.1f%% %s %6.0f%% %6.0f%% %6.0f%%  %s\n",
+			humanize.Truncate(u.App, 32),
+			share,
+			tui.Bar(share, 20),
+			u.CPU/u.Total*100,
+			u.Display/u.Total*100,
+			u.Network/u.Total*100,
+			state))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,292 @@
+//go:build windows
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// A minimal read-only binding to the ESE database engine (esent.dll), enough
+// to walk the tables of SRUDB.dat.
+
+var (
+	esent = windows.NewLazySystemDLL("esent.dll")
+
+	procJetGetDatabaseFileInfo = esent.NewProc("JetGetDatabaseFileInfoW")
+	procJetSetSystemParameter  = esent.NewProc("JetSetSystemParameterW")
+	procJetCreateInstance      = esent.NewProc("JetCreateInstanceW")
+	procJetInit                = esent.NewProc("JetInit")
+	procJetTerm                = esent.NewProc("JetTerm")
+	procJetBeginSession        = esent.NewProc("JetBeginSessionW")
+	procJetEndSession          = esent.NewProc("JetEndSession")
+	procJetAttachDatabase      = esent.NewProc("JetAttachDatabaseW")
+	procJetOpenDatabase        = esent.NewProc("JetOpenDatabaseW")
+	procJetOpenTable           = esent.NewProc("JetOpenTableW")
+	procJetCloseTable          = esent.NewProc("JetCloseTable")
+	procJetGetTableColumnInfo  = esent.NewProc("JetGetTableColumnInfoW")
+	procJetMove                = esent.NewProc("JetMove")
+	procJetRetrieveColumn      = esent.NewProc("JetRetrieveColumn")
+)
+
+const (
+	jetParamSystemPath       = 0
+	jetParamTempPath         = 1
+	jetParamLogFilePath      = 2
+	jetParamRecovery         = 34
+	jetParamDatabasePageSize = 64
+
+	jetDbInfoPageSize = 17
+	jetColInfo        = 0
+
+	jetBitDbReadOnly    = 0x1
+	jetBitTableReadOnly = 0x4
+
+	jetMoveFirst = -0x80000000
+	jetMoveNext  = 1
+
+	jetWrnColumnNull       = 1004
+	jetWrnBufferTruncated  = 1006
+	jetErrNoCurrentRecord  = -1603
+	jetErrDatabaseDirty    = -550
+	jetErrFileAccessDenied = -1032
+)
+
+// jetError is a negative JET_ERR returned by an esent call.
+type jetError struct {
+	call string
+	code int32
+}
+
+func (e jetError) Error() string {
+	switch e.code {
+	case jetErrDatabaseDirty:
+		return fmt.Sprintf("%s: database was not shut down cleanly (JET_err %d)", e.call, e.code)
+	case jetErrFileAccessDenied:
+		return fmt.Sprintf("%s: access denied (JET_err %d)", e.call, e.code)
+	}
+	return fmt.Sprintf("%s failed with JET_err %d", e.call, e.code)
+}
+
+// jetCall invokes proc and converts a negative JET_ERR into an error. Warnings
+// (positive codes) are returned for the caller to inspect.
+func jetCall(name string, proc *windows.LazyProc, args ...uintptr) (int32, error) {
+	if err := proc.Find(); err != nil {
+		return 0, err
+	}
+	r, _, _ := proc.Call(args...)
+	code := int32(r)
+	if code < 0 {
+		return code, jetError{call: name, code: code}
+	}
+	return code, nil
+}
+
+// jetColumnDef mirrors JET_COLUMNDEF.
+type jetColumnDef struct {
+	cbStruct uint32
+	columnid uint32
+	coltyp   uint32
+	wCountry uint16
+	langid   uint16
+	cp       uint16
+	wCollate uint16
+	cbMax    uint32
+	grbit    uint32
+}
+
+// eseDB is a database opened read-only in a private ESE instance.
+type eseDB struct {
+	instance uintptr
+	session  uintptr
+	dbid     uint32
+}
+
+// openESE attaches path read-only. workDir receives the engine's checkpoint
+// and temporary files; recovery is disabled so nothing is replayed into the
+// database.
+func openESE(path, workDir string) (*eseDB, error) {
+	p16, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	dir16, err := windows.UTF16PtrFromString(workDir + `\`)
+	if err != nil {
+		return nil, err
+	}
+
+	var pageSize uint32
+	if _, err := jetCall("JetGetDatabaseFileInfo", procJetGetDatabaseFileInfo,
+		uintptr(unsafe.Pointer(p16)), uintptr(unsafe.Pointer(&pageSize)), 4, jetDbInfoPageSize); err != nil {
+		return nil, err
+	}
+	// The page size is global to the process and must be set before any instance exists.
+	if _, err := jetCall("JetSetSystemParameter", procJetSetSystemParameter,
+		0, 0, jetParamDatabasePageSize, uintptr(pageSize), 0); err != nil {
+		return nil, err
+	}
+
+	db := &eseDB{}
+	name16, _ := windows.UTF16PtrFromString("winmole")
+	if _, err := jetCall("JetCreateInstance", procJetCreateInstance,
+		uintptr(unsafe.Pointer(&db.instance)), uintptr(unsafe.Pointer(name16))); err != nil {
+		return nil, err
+	}
+
+	off16, _ := windows.UTF16PtrFromString("off")
+	params := []struct {
+		id  uintptr
+		str *uint16
+	}{
+		{jetParamSystemPath, dir16},
+		{jetParamTempPath, dir16},
+		{jetParamLogFilePath, dir16},
+		{jetParamRecovery, off16},
+	}
+	for _, p := range params {
+		if _, err := jetCall("JetSetSystemParameter", procJetSetSystemParameter,
+			uintptr(unsafe.Pointer(&db.instance)), 0, p.id, 0, uintptr(unsafe.Pointer(p.str))); err != nil {
+			jetCall("JetTerm", procJetTerm, db.instance)
+			return nil, err
+		}
+	}
+
+	if _, err := jetCall("JetInit", procJetInit, uintptr(unsafe.Pointer(&db.instance))); err != nil {
+		jetCall("JetTerm", procJetTerm, db.instance)
+		return nil, err
+	}
+	if _, err := jetCall("JetBeginSession", procJetBeginSession,
+		db.instance, uintptr(unsafe.Pointer(&db.session)), 0, 0); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := jetCall("JetAttachDatabase", procJetAttachDatabase,
+		db.session, uintptr(unsafe.Pointer(p16)), jetBitDbReadOnly); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := jetCall("JetOpenDatabase", procJetOpenDatabase,
+		db.session, uintptr(unsafe.Pointer(p16)), 0, uintptr(unsafe.Pointer(&db.dbid)), jetBitDbReadOnly); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Close ends the session and shuts the instance down.
+func (db *eseDB) Close() {
+	if db.session != 0 {
+		jetCall("JetEndSession", procJetEndSession, db.session, 0)
+		db.session = 0
+	}
+	if db.instance != 0 {
+		jetCall("JetTerm", procJetTerm, db.instance)
+		db.instance = 0
+	}
+}
+
+// eseTable is a cursor over one table.
+type eseTable struct {
+	db      *eseDB
+	id      uintptr
+	columns map[string]uint32
+	buf     []byte
+}
+
+// openTable opens name read-only and resolves the requested columns. Columns
+// missing from this Windows build are left out of the map.
+func (db *eseDB) openTable(name string, columns ...string) (*eseTable, error) {
+	n16, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	t := &eseTable{db: db, columns: make(map[string]uint32), buf: make([]byte, 512)}
+	if _, err := jetCall("JetOpenTable", procJetOpenTable,
+		db.session, uintptr(db.dbid), uintptr(unsafe.Pointer(n16)), 0, 0,
+		jetBitTableReadOnly, uintptr(unsafe.Pointer(&t.id))); err != nil {
+		return nil, err
+	}
+
+	for _, col := range columns {
+		c16, err := windows.UTF16PtrFromString(col)
+		if err != nil {
+			t.Close()
+			return nil, err
+		}
+		def := jetColumnDef{cbStruct: uint32(unsafe.Sizeof(jetColumnDef{}))}
+		if _, err := jetCall("JetGetTableColumnInfo", procJetGetTableColumnInfo,
+			db.session, t.id, uintptr(unsafe.Pointer(c16)),
+			uintptr(unsafe.Pointer(&def)), uintptr(def.cbStruct), jetColInfo); err != nil {
+			continue
+		}
+		t.columns[col] = def.columnid
+	}
+	return t, nil
+}
+
+// each calls fn for every row until fn returns false.
+func (t *eseTable) each(fn func() bool) error {
+	move := int32(jetMoveFirst)
+	for {
+		_, err := jetCall("JetMove", procJetMove, t.db.session, t.id, uintptr(move), 0)
+		if je, ok := err.(jetError); ok && je.code == jetErrNoCurrentRecord {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !fn() {
+			return nil
+		}
+		move = jetMoveNext
+	}
+}
+
+// raw returns the value of column in the current row, or nil when the
+// column is null or unknown.
+func (t *eseTable) raw(column string) []byte {
+	id, ok := t.columns[column]
+	if !ok {
+		return nil
+	}
+	for {
+		var actual uint32
+		code, err := jetCall("JetRetrieveColumn", procJetRetrieveColumn,
+			t.db.session, t.id, uintptr(id),
+			uintptr(unsafe.Pointer(&t.buf[0])), uintptr(len(t.buf)),
+			uintptr(unsafe.Pointer(&actual)), 0, 0)
+		if err != nil || code == jetWrnColumnNull {
+			return nil
+		}
+		if code == jetWrnBufferTruncated && int(actual) > len(t.buf) {
+			t.buf = make([]byte, actual)
+			continue
+		}
+		return t.buf[:actual]
+	}
+}
+
+// uint64 reads an integer column of any width as unsigned.
+func (t *eseTable) uint64(column string) uint64 {
+	var v [8]byte
+	copy(v[:], t.raw(column))
+	return binary.LittleEndian.Uint64(v[:])
+}
+
+// float64 reads an IEEE double (JET_coltypIEEEDouble, JET_coltypDateTime).
+func (t *eseTable) float64(column string) float64 {
+	b := t.raw(column)
+	if len(b) != 8 {
+		return 0
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
+
+// Close releases the cursor.
+func (t *eseTable) Close() {
+	jetCall("JetCloseTable", procJetCloseTable, t.db.session, t.id)
+}
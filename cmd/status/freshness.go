@@ -0,0 +1,94 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// Every Overview card says how old its numbers are, and a card whose
+// collector has missed its expected interval is drawn dimmed, so frozen
+// numbers are never mistaken for live ones.
+
+// staleSlack is how far past its expected interval a collector may report
+// before its card is dimmed, covering the time a sample takes.
+const staleSlack = time.Second
+
+// expected returns how often the collector called name should report
+// right now, or false when it is not expected to (idle-only collectors
+// while the user is active).
+func (s *schedule) expected(name string) (time.Duration, bool) {
+	policy := s.policies[name]
+	switch policy.Mode {
+	case modeSlowWhenIdle:
+		if s.idle {
+			if interval := time.Duration(policy.IdleIntervalSeconds) * time.Second; interval > 0 {
+				return interval, true
+			}
+			return defaultIdleInterval, true
+		}
+	case modeIdleOnly:
+		if !s.idle {
+			return 0, false
+		}
+	}
+	return tickInterval, true
+}
+
+// freshness describes the age of the collector name's values for the foot
+// of its card, and reports whether they are older than they should be.
+func (m model) freshness(name string, now time.Time) (label string, stale bool) {
+	at, ok := m.metrics.Sampled[name]
+	if !ok {
+		return "waiting for data", true
+	}
+	age := now.Sub(at)
+	label = "updated " + formatAge(age)
+	if humanize.CurrentTimes() == humanize.Absolute {
+		label = "updated " + humanize.Format(at, "15:04:05")
+	}
+	if m.metrics.Stale(name) {
+		return label + " • not answering", true
+	}
+	interval, expected := m.schedule.expected(name)
+	if !expected {
+		return label + " • sampled while idle", false
+	}
+	return label, age > 2*interval+staleSlack
+}
+
+// formatAge renders how long ago something happened: "just now", "12s ago",
+// "4m ago" or "2h ago".
+func formatAge(d time.Duration) string {
+	switch {
+	case d < 2*time.Second:
+		return "just now"
+	case d < time.Minute:
+		return fmt.Sprintf("%ds ago", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh ago", int(d.Hours()))
+}
+
+// sgr matches the terminal color and style sequences lipgloss emits.
+var sgr = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// dimmedStyle draws a stale card in a single muted color.
+var dimmedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("238"))
+
+// dimCard redraws a rendered card in dimmedStyle, line by line so the
+// color survives the card's own line breaks.
+func dimCard(card string) string {
+	lines := strings.Split(sgr.ReplaceAllString(card, ""), "\n")
+	for i, line := range lines {
+		lines[i] = dimmedStyle.Render(line)
+	}
+	return strings.Join(lines, "\n")
+}
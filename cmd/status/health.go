@@ -0,0 +1,113 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// The health score sums up the pressure on CPU, memory, disks and cooling
+// in one number in the header, 100 when nothing is held up, with a line
+// naming the worst bottleneck ("Disk-bound: D: at 100% active time") so
+// anyone can see at a glance where to look. It is driven mostly by the
+// worst resource: one saturated disk makes a slow machine however idle the
+// rest is.
+
+// strain is how hard one resource is pushed, from 0 (fine) to 1
+// (saturated), and why.
+type strain struct {
+	level  float64
+	reason string
+}
+
+// ramp maps v from lo (0) to hi (1), clamped.
+func ramp(v, lo, hi float64) float64 {
+	return min(max((v-lo)/(hi-lo), 0), 1)
+}
+
+func cpuStrain(m Metrics) strain {
+	waiting := m.CPUQueue / float64(max(m.CPUCores, 1))
+	s := strain{
+		level:  max(ramp(m.CPUUsage, 70, 100), ramp(waiting, 1, 4)),
+		reason: fmt.Sprintf("CPU-bound: %.0f%% busy", m.CPUUsage),
+	}
+	if waiting >= 1 {
+		s.reason += fmt.Sprintf(", %.0f threads waiting for a processor", m.CPUQueue)
+	}
+	return s
+}
+
+func memoryStrain(m Metrics) strain {
+	var commit float64
+	if m.MemCommitLimit > 0 {
+		commit = float64(m.MemCommitted) / float64(m.MemCommitLimit) * 100
+	}
+	inUse := strain{ramp(m.MemInUsePercent, 80, 97), fmt.Sprintf("Memory-bound: %.0f%% in use", m.MemInUsePercent)}
+	committed := strain{ramp(commit, 85, 100), fmt.Sprintf("Memory-bound: %.0f%% of the commit limit used", commit)}
+	paging := strain{ramp(m.MemPagesIn, 100, 2000), fmt.Sprintf("Memory-bound: reading %s pages/s back from disk", humanize.Count(int64(m.MemPagesIn)))}
+	return worst(inUse, committed, paging)
+}
+
+func diskStrain(m Metrics) strain {
+	var s strain
+	for _, d := range m.DiskActivity {
+		reason := fmt.Sprintf("Disk-bound: %s at %.0f%% active time", d.Mount, d.Active)
+		if d.Queue >= 2 {
+			reason += fmt.Sprintf(", %.0f requests queued", d.Queue)
+		}
+		s = worst(s, strain{max(ramp(d.Active, 60, 100), ramp(d.Queue, 2, 8)), reason})
+	}
+	return s
+}
+
+func thermalStrain(m Metrics) strain {
+	throttled := strain{ramp(m.Throttle, 0, 40), fmt.Sprintf("Thermal: processors slowed %.0f%% to cool down", m.Throttle)}
+	hot := strain{ramp(m.Temperature, 85, 100), fmt.Sprintf("Thermal: running at %.0f °C", m.Temperature)}
+	return worst(throttled, hot)
+}
+
+// worst returns the most strained of list.
+func worst(list ...strain) strain {
+	var w strain
+	for _, s := range list {
+		if s.level > w.level {
+			w = s
+		}
+	}
+	return w
+}
+
+// health scores m from 0 to 100 and names its bottleneck, if any.
+func health(m Metrics) (score int, summary string) {
+	strains := []strain{cpuStrain(m), memoryStrain(m), diskStrain(m), thermalStrain(m)}
+	var total float64
+	for _, s := range strains {
+		total += s.level
+	}
+	w := worst(strains...)
+	score = int(math.Round(100 - 70*w.level - 30*total/float64(len(strains))))
+	if w.level < 0.3 {
+		return score, "No bottleneck"
+	}
+	return score, w.reason
+}
+
+var healthBadStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
+// renderHealth is the health line of the header.
+func (m model) renderHealth() string {
+	score, summary := health(m.metrics)
+	style := barLowStyle
+	switch {
+	case score < 50:
+		style = healthBadStyle
+	case score < 80:
+		style = warnStyle
+	}
+	return valueStyle.Render("Health ") + style.Render(fmt.Sprintf("● %d", score)) + "  " + labelStyle.Render(summary) +
+		m.renderBackground()
+}
@@ -0,0 +1,169 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/tui"
+)
+
+// historyCapacity keeps ten minutes of one-second samples.
+const historyCapacity = 600
+
+// sample is the subset of Metrics kept for trend graphs.
+type sample struct {
+	At        time.Time
+	CPU       float64
+	Mem       float64
+	DiskRead  float64
+	DiskWrite float64
+	NetRecv   float64
+	NetSent   float64
+	Temp      float64 // °C, 0 when unknown
+	Clock     float64 // MHz, 0 when unknown
+	Throttle  float64 // percent
+}
+
+// history is a fixed-size ring buffer of samples, oldest first, plus the
+// markers the user dropped while monitoring.
+type history struct {
+	samples []sample
+	next    int
+	full    bool
+	markers []marker
+}
+
+func newHistory(capacity int) *history {
+	return &history{samples: make([]sample, capacity)}
+}
+
+func (h *history) add(metrics Metrics) {
+	s := sample{
+		At:       metrics.CollectedAt,
+		CPU:      metrics.CPUUsage,
+		Mem:      metrics.MemPercent,
+		NetRecv:  metrics.NetRecvRate,
+		NetSent:  metrics.NetSentRate,
+		Temp:     metrics.Temperature,
+		Clock:    metrics.CPUClock,
+		Throttle: metrics.Throttle,
+	}
+	for _, d := range metrics.Disks {
+		s.DiskRead += d.ReadRate
+		s.DiskWrite += d.WriteRate
+	}
+
+	h.samples[h.next] = s
+	h.next = (h.next + 1) % len(h.samples)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// all returns the stored samples in chronological order.
+func (h *history) all() []sample {
+	if !h.full {
+		return append([]sample(nil), h.samples[:h.next]...)
+	}
+	out := make([]sample, 0, len(h.samples))
+	out = append(out, h.samples[h.next:]...)
+	return append(out, h.samples[:h.next]...)
+}
+
+// series extracts one field from the most recent n samples.
+func series(samples []sample, n int, field func(sample) float64) []float64 {
+	if len(samples) > n {
+		samples = samples[len(samples)-n:]
+	}
+	values := make([]float64, len(samples))
+	for i, s := range samples {
+		values[i] = field(s)
+	}
+	return values
+}
+
+func (m model) renderHistory() string {
+	samples := m.history.all()
+	if len(samples) < 2 {
+		return labelStyle.Render("  Collecting samples...")
+	}
+	if m.showThermal {
+		return m.renderThermal(samples)
+	}
+
+	width := m.width - 40
+	if width < 20 {
+		width = 20
+	}
+
+	rows := []struct {
+		label   string
+		max     float64
+		field   func(sample) float64
+		display func(float64) string
+	}{
+		{"CPU", 100, func(s sample) float64 { return s.CPU }, formatPercent},
+		{"Memory", 100, func(s sample) float64 { return s.Mem }, formatPercent},
+		{"Disk read", 0, func(s sample) float64 { return s.DiskRead }, formatRate},
+		{"Disk write", 0, func(s sample) float64 { return s.DiskWrite }, formatRate},
+		{"Net ↓", 0, func(s sample) float64 { return s.NetRecv }, formatRate},
+		{"Net ↑", 0, func(s sample) float64 { return s.NetSent }, formatRate},
+	}
+
+	var b strings.Builder
+	span := samples[len(samples)-1].At.Sub(samples[0].At).Round(time.Second)
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  Last %s", span)))
+	b.WriteString("\n\n")
+
+	window := samples
+	if len(window) > width {
+		window = window[len(window)-width:]
+	}
+
+	for _, row := range rows {
+		values := series(window, width, row.field)
+		peak := 0.0
+		for _, v := range values {
+			if v > peak {
+				peak = v
+			}
+		}
+		b.WriteString(labelStyle.Render(fmt.Sprintf("  %-10s ", row.label)))
+		b.WriteString(barLowStyle.Render(tui.Spark(values, row.max)))
+		b.WriteString(fmt.Sprintf(" %s", row.display(values[len(values)-1])))
+		b.WriteString(labelStyle.Render(fmt.Sprintf(" (peak %s)", row.display(peak))))
+		b.WriteString("\n")
+	}
+
+	if len(m.history.markers) > 0 {
+		b.WriteString(labelStyle.Render(fmt.Sprintf("  %-10s ", "Markers")))
+		b.WriteString(warnStyle.Render(markerRow(window, m.history.markers)))
+		b.WriteString("\n\n")
+
+		// Most recent markers that fit the screen
+		first := len(m.history.markers) - (m.contentHeight() - len(rows) - 5)
+		if first < 0 {
+			first = 0
+		}
+		for i := first; i < len(m.history.markers); i++ {
+			mk := m.history.markers[i]
+			b.WriteString(fmt.Sprintf("  %s %s %s\n",
+				warnStyle.Render(string(markerGlyph(i))),
+				labelStyle.Render(humanize.Format(mk.At, "15:04:05")),
+				mk.Label))
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func formatPercent(v float64) string {
+	return fmt.Sprintf("%.1f%%", v)
+}
+
+func formatRate(v float64) string {
+	return humanize.Bytes(uint64(v)) + "/s"
+}
@@ -0,0 +1,123 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/pkg/metrics"
+)
+
+// F12 opens a hidden inspector listing the raw values each collector
+// returned, with its error, how long it took and how old its values are.
+// It helps explain a difference from Task Manager and test new collectors,
+// so it is left out of the help line.
+
+// inspector is the state of the raw metrics view.
+type inspector struct {
+	active bool
+	offset int
+}
+
+func (m model) handleInspectKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	page := m.inspectHeight()
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "f12", "esc":
+		m.inspector = inspector{}
+	case "up", "k":
+		m.inspector.offset--
+	case "down", "j":
+		m.inspector.offset++
+	case "pgup":
+		m.inspector.offset -= page
+	case "pgdown", " ":
+		m.inspector.offset += page
+	case "home":
+		m.inspector.offset = 0
+	}
+	m.inspector.offset = max(min(m.inspector.offset, len(m.inspectLines(time.Now()))-page), 0)
+	return m, nil
+}
+
+// inspectHeight is the number of inspector lines that fit on screen.
+func (m model) inspectHeight() int {
+	return max(m.height-9, 5)
+}
+
+// inspectLines lists every collector with its timings and raw fields.
+func (m model) inspectLines(now time.Time) []string {
+	var lines []string
+	for _, c := range metrics.All() {
+		name := c.Name()
+		head := valueStyle.Render(name)
+		if took, ok := m.metrics.Took[name]; ok {
+			head += labelStyle.Render(fmt.Sprintf("  took %s", took.Round(time.Microsecond)))
+		}
+		if at, ok := m.metrics.Sampled[name]; ok {
+			head += labelStyle.Render(fmt.Sprintf("  sampled %s ago", now.Sub(at).Round(time.Millisecond)))
+		} else {
+			head += labelStyle.Render("  never sampled")
+		}
+		if wait := m.guard.Paused(name); wait > 0 {
+			head += warnStyle.Render(fmt.Sprintf("  paused %s", wait.Round(time.Second)))
+		}
+		lines = append(lines, head)
+		if err, failed := m.metrics.Errors[name]; failed {
+			lines = append(lines, warnStyle.Render("  error: "+err.Error()))
+		}
+		only := c.Only(&m.metrics)
+		for _, field := range rawFields(only) {
+			lines = append(lines, "  "+field)
+		}
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// rawFields prints the non-zero fields of a sample, one per line, and the
+// elements of slices one per line below their count.
+func rawFields(sample Metrics) []string {
+	v := reflect.ValueOf(sample)
+	var lines []string
+	for i := 0; i < v.NumField(); i++ {
+		name, f := v.Type().Field(i).Name, v.Field(i)
+		switch name {
+		case "Errors", "Sampled", "Took", "CollectedAt":
+			continue
+		}
+		if f.IsZero() {
+			continue
+		}
+		if f.Kind() == reflect.Slice {
+			lines = append(lines, fmt.Sprintf("%s: %d items", name, f.Len()))
+			for j := 0; j < f.Len(); j++ {
+				lines = append(lines, fmt.Sprintf("  [%d] %+v", j, f.Index(j).Interface()))
+			}
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %+v", name, f.Interface()))
+	}
+	return lines
+}
+
+func (m model) renderInspector() string {
+	var b strings.Builder
+	b.WriteString(valueStyle.Render("Raw metrics"))
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  sample taken %s • fields at their zero value are left out",
+		m.metrics.CollectedAt.Format("15:04:05.000"))))
+	b.WriteString("\n\n")
+
+	lines := m.inspectLines(time.Now())
+	end := min(m.inspector.offset+m.inspectHeight(), len(lines))
+	for _, line := range lines[min(m.inspector.offset, end):end] {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,334 @@
+//go:build windows
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/config"
+	"github.com/winmole/winmole/pkg/humanize"
+	"golang.org/x/sys/windows"
+)
+
+// n on the Network tab lists the devices on the local network that this
+// PC has talked to or heard from, read from the ARP (IPv4) and neighbor
+// discovery (IPv6) tables, with the maker of each network card looked up
+// by the first half of its MAC address. s sweeps the local subnets first:
+// it sends one UDP packet to every address, which makes Windows resolve
+// each one, so devices that ignore ping still show up.
+
+var (
+	iphlpapi           = windows.NewLazySystemDLL("iphlpapi.dll")
+	procGetIpNetTable2 = iphlpapi.NewProc("GetIpNetTable2")
+	procFreeMibTable   = iphlpapi.NewProc("FreeMibTable")
+)
+
+// mibIPNetRow2 mirrors MIB_IPNET_ROW2.
+type mibIPNetRow2 struct {
+	address         [28]byte // SOCKADDR_INET
+	interfaceIndex  uint32
+	interfaceLuid   uint64
+	physicalAddress [32]byte
+	physicalLength  uint32
+	state           uint32
+	flags           uint8
+	_               [3]byte
+	reachability    uint32
+}
+
+// NL_NEIGHBOR_STATE values worth listing; the others are addresses that
+// did not answer.
+const (
+	nlnsProbe     = 2
+	nlnsDelay     = 3
+	nlnsStale     = 4
+	nlnsReachable = 5
+	nlnsPermanent = 6
+)
+
+// lanDevice is one neighbor.
+type lanDevice struct {
+	ip        netip.Addr
+	mac       net.HardwareAddr
+	vendor    string
+	name      string // reverse DNS, when it answered in time
+	iface     string
+	reachable bool // answered recently rather than remembered
+}
+
+type lanMsg struct {
+	devices []lanDevice
+	swept   bool
+	err     error
+}
+
+// lanView is the local-network view of the Network tab.
+type lanView struct {
+	show     bool
+	loading  bool
+	sweeping bool
+	offset   int
+	devices  []lanDevice
+	err      error
+}
+
+// readNeighbors reads the ARP and neighbor tables, leaving out broadcast,
+// multicast and unresolved entries.
+func readNeighbors() ([]lanDevice, error) {
+	var table unsafe.Pointer
+	if r, _, _ := procGetIpNetTable2.Call(uintptr(windows.AF_UNSPEC), uintptr(unsafe.Pointer(&table))); r != 0 {
+		return nil, fmt.Errorf("GetIpNetTable2: %w", windows.Errno(r))
+	}
+	defer procFreeMibTable.Call(uintptr(table))
+
+	// MIB_IPNET_TABLE2 is a count followed by the rows, 8-byte aligned.
+	n := *(*uint32)(table)
+	rows := unsafe.Slice((*mibIPNetRow2)(unsafe.Add(table, 8)), n)
+
+	names := make(map[uint32]string)
+	var devices []lanDevice
+	for _, row := range rows {
+		if row.state < nlnsProbe || row.physicalLength != 6 {
+			continue
+		}
+		mac := net.HardwareAddr(append([]byte(nil), row.physicalAddress[:6]...))
+		if mac[0]&1 != 0 || bytes.Equal(mac, make([]byte, 6)) {
+			continue // multicast or broadcast, or not resolved
+		}
+		ip, ok := sockaddrIP(row.address)
+		if !ok || ip.IsMulticast() || ip.IsLoopback() || ip.IsUnspecified() {
+			continue
+		}
+		if _, ok := names[row.interfaceIndex]; !ok {
+			if iface, err := net.InterfaceByIndex(int(row.interfaceIndex)); err == nil {
+				names[row.interfaceIndex] = iface.Name
+			}
+		}
+		devices = append(devices, lanDevice{
+			ip:        ip,
+			mac:       mac,
+			vendor:    macVendor(mac),
+			iface:     names[row.interfaceIndex],
+			reachable: row.state == nlnsReachable || row.state == nlnsDelay || row.state == nlnsProbe,
+		})
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].ip.Less(devices[j].ip) })
+	return devices, nil
+}
+
+// sockaddrIP reads the address out of a SOCKADDR_INET.
+func sockaddrIP(sa [28]byte) (netip.Addr, bool) {
+	switch *(*uint16)(unsafe.Pointer(&sa[0])) {
+	case windows.AF_INET:
+		return netip.AddrFrom4([4]byte(sa[4:8])), true
+	case windows.AF_INET6:
+		return netip.AddrFrom16([16]byte(sa[8:24])), true
+	}
+	return netip.Addr{}, false
+}
+
+// resolveNames looks up the devices' names in DNS, giving up after a
+// second in total; home routers usually know their clients' names.
+func resolveNames(devices []lanDevice) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := range devices {
+		wg.Add(1)
+		go func(d *lanDevice) {
+			defer wg.Done()
+			if names, err := net.DefaultResolver.LookupAddr(ctx, d.ip.String()); err == nil && len(names) > 0 {
+				d.name = strings.TrimSuffix(names[0], ".")
+			}
+		}(&devices[i])
+	}
+	wg.Wait()
+}
+
+// sweepLimit is the most addresses swept per subnet; larger subnets are
+// swept in the /24 around this PC's address.
+const sweepLimit = 254
+
+// sweepSubnets sends a UDP packet to every address of the IPv4 subnets
+// this PC is on, so that Windows resolves each of them.
+func sweepSubnets() error {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return err
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			ipnet, ok := a.(*net.IPNet)
+			if !ok || ipnet.IP.To4() == nil || ipnet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			prefix, ok := netip.AddrFromSlice(ipnet.IP.To4())
+			if !ok {
+				continue
+			}
+			bits, _ := ipnet.Mask.Size()
+			sweepPrefix(netip.PrefixFrom(prefix, max(bits, 24)).Masked(), prefix)
+		}
+	}
+	return nil
+}
+
+func sweepPrefix(p netip.Prefix, self netip.Addr) {
+	addr := p.Addr().Next() // skip the network address
+	for i := 0; i < sweepLimit && p.Contains(addr); i++ {
+		if addr != self {
+			// The discard port; nothing needs to listen there, the
+			// address only has to be resolved before the packet leaves.
+			if conn, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: addr.AsSlice(), Port: 9}); err == nil {
+				conn.Write([]byte{0})
+				conn.Close()
+			}
+		}
+		addr = addr.Next()
+	}
+}
+
+// lanCmd reads the neighbor tables, sweeping the subnets first when asked.
+func lanCmd(sweep bool) tea.Cmd {
+	return func() tea.Msg {
+		if sweep {
+			if err := sweepSubnets(); err != nil {
+				return lanMsg{swept: true, err: err}
+			}
+			// Give the devices time to answer.
+			time.Sleep(2 * time.Second)
+		}
+		devices, err := readNeighbors()
+		if err == nil {
+			resolveNames(devices)
+		}
+		return lanMsg{devices: devices, swept: sweep, err: err}
+	}
+}
+
+//go:embed oui.txt
+var embeddedOUI string
+
+var (
+	ouiOnce  sync.Once
+	ouiTable map[string]string // "B827EB" -> vendor
+)
+
+// macVendor names the maker of a network card from its MAC address.
+// Phones and recent Windows randomise the address per network, which
+// leaves nothing to look up.
+func macVendor(mac net.HardwareAddr) string {
+	ouiOnce.Do(loadOUI)
+	if vendor, ok := ouiTable[fmt.Sprintf("%02X%02X%02X", mac[0], mac[1], mac[2])]; ok {
+		return vendor
+	}
+	if mac[0]&2 != 0 {
+		return "private address"
+	}
+	return ""
+}
+
+// loadOUI reads the IEEE registry saved as oui.csv next to config.json,
+// when there is one, and the short embedded list otherwise.
+func loadOUI() {
+	ouiTable = make(map[string]string)
+	if f, err := os.Open(filepath.Join(config.Dir(), "oui.csv")); err == nil {
+		defer f.Close()
+		// Registry,Assignment,Organization Name,Organization Address
+		records, err := csv.NewReader(f).ReadAll()
+		if err == nil && len(records) > 1 {
+			for _, r := range records[1:] {
+				if len(r) >= 3 && len(r[1]) == 6 {
+					ouiTable[strings.ToUpper(r[1])] = strings.TrimSpace(r[2])
+				}
+			}
+			return
+		}
+	}
+	sc := bufio.NewScanner(strings.NewReader(embeddedOUI))
+	for sc.Scan() {
+		prefix, vendor, ok := strings.Cut(sc.Text(), "\t")
+		if ok && !strings.HasPrefix(prefix, "#") {
+			ouiTable[prefix] = vendor
+		}
+	}
+}
+
+// handleLANKey handles the Network tab's keys while the device list is
+// shown.
+func (m model) handleLANKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "n":
+		m.lan = lanView{}
+	case "r":
+		if !m.lan.loading {
+			m.lan.loading = true
+			return m, lanCmd(false)
+		}
+	case "s":
+		if !m.lan.loading {
+			m.lan.loading, m.lan.sweeping = true, true
+			return m, lanCmd(true)
+		}
+	case "up", "k":
+		m.lan.offset = max(m.lan.offset-1, 0)
+	case "down", "j":
+		m.lan.offset = min(m.lan.offset+1, max(len(m.lan.devices)-m.portsHeight(), 0))
+	}
+	return m, nil
+}
+
+func (m model) renderLAN() string {
+	v := m.lan
+	var b strings.Builder
+	switch {
+	case v.sweeping:
+		b.WriteString(labelStyle.Render("  Sweeping the local subnets..."))
+		b.WriteString("\n\n")
+	case v.err != nil:
+		b.WriteString(warnStyle.Render(fmt.Sprintf("  Cannot read the neighbor table: %v", v.err)))
+		b.WriteString("\n\n")
+	case v.devices == nil:
+		b.WriteString(labelStyle.Render("  Reading the neighbor table..."))
+		return b.String()
+	}
+
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  %-26s %-18s %-22s %-24s %s", "Address", "MAC", "Vendor", "Name", "Interface")))
+	b.WriteString("\n")
+	height := m.portsHeight() - 2
+	offset := min(v.offset, max(len(v.devices)-height, 0))
+	for _, d := range v.devices[offset:min(offset+height, len(v.devices))] {
+		line := fmt.Sprintf("  %-26s %-18s %-22s %-24s %s", d.ip, d.mac, humanize.Truncate(d.vendor, 22), humanize.Truncate(d.name, 24), d.iface)
+		if d.reachable {
+			b.WriteString(valueStyle.Render(line))
+		} else {
+			b.WriteString(labelStyle.Render(line))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  %d devices • dimmed ones have not answered recently", len(v.devices))))
+	return b.String()
+}
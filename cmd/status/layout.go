@@ -0,0 +1,215 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/winmole/winmole/pkg/tui"
+)
+
+// cardWidth is the rendered width of one Overview card including margin.
+const cardWidth = 42
+
+// card is one Overview panel that users can show, hide and reorder.
+type card struct {
+	id     string
+	title  string
+	render func(Metrics) string
+}
+
+// cards lists every available card in default order.
+var cards = []card{
+	{id: tui.CardCPU, title: "CPU", render: tui.CPUCard},
+	{id: tui.CardMemory, title: "Memory", render: tui.MemoryCard},
+	{id: tui.CardDisk, title: "Disk", render: tui.DiskCard},
+	{id: tui.CardNetwork, title: "Network", render: tui.NetworkCard},
+}
+
+func findCard(id string) (card, bool) {
+	for _, c := range cards {
+		if c.id == id {
+			return c, true
+		}
+	}
+	return card{}, false
+}
+
+// layoutSlot is one row of the layout editor.
+type layoutSlot struct {
+	id      string
+	enabled bool
+}
+
+// layout is the ordered card list, including hidden cards so the editor
+// can re-enable them in place.
+type layout []layoutSlot
+
+// newLayout builds a layout from the configured card IDs. Unknown IDs are
+// dropped; cards not mentioned are appended hidden. A missing (nil)
+// configuration enables every card in default order.
+func newLayout(ids []string) layout {
+	if ids == nil {
+		l := make(layout, len(cards))
+		for i, c := range cards {
+			l[i] = layoutSlot{id: c.id, enabled: true}
+		}
+		return l
+	}
+
+	var l layout
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if _, ok := findCard(id); ok && !seen[id] {
+			seen[id] = true
+			l = append(l, layoutSlot{id: id, enabled: true})
+		}
+	}
+	for _, c := range cards {
+		if !seen[c.id] {
+			l = append(l, layoutSlot{id: c.id, enabled: false})
+		}
+	}
+	return l
+}
+
+// enabledIDs returns the card IDs to persist.
+func (l layout) enabledIDs() []string {
+	// Non-nil so that hiding every card persists as [] rather than
+	// falling back to the default layout.
+	ids := []string{}
+	for _, slot := range l {
+		if slot.enabled {
+			ids = append(ids, slot.id)
+		}
+	}
+	return ids
+}
+
+func (l layout) clone() layout {
+	return append(layout(nil), l...)
+}
+
+// layoutEditor holds the state of the edit-layout mode.
+type layoutEditor struct {
+	active   bool
+	draft    layout
+	selected int
+}
+
+func (m model) renderOverview() string {
+	if m.editor.active {
+		return m.renderLayoutEditor()
+	}
+
+	// Card IDs double as the names of the collectors behind them.
+	now := time.Now()
+	var rendered []string
+	for _, slot := range m.layout {
+		if !slot.enabled {
+			continue
+		}
+		c, ok := findCard(slot.id)
+		if !ok {
+			continue
+		}
+		render := c.render
+		if c.id == tui.CardMemory && m.rawMemory {
+			render = tui.RawMemoryCard
+		}
+		card := render(m.metrics)
+		label, stale := m.freshness(c.id, now)
+		if stale {
+			card = dimCard(card)
+		}
+		rendered = append(rendered, lipgloss.JoinVertical(lipgloss.Left, card, labelStyle.Render("  "+label)))
+	}
+	if len(rendered) == 0 {
+		return labelStyle.Render("  All cards are hidden. Press 'e' to edit the layout.")
+	}
+
+	perRow := m.width / cardWidth
+	if perRow < 1 {
+		perRow = 1
+	}
+	var rows []string
+	for start := 0; start < len(rendered); start += perRow {
+		end := start + perRow
+		if end > len(rendered) {
+			end = len(rendered)
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, rendered[start:end]...))
+	}
+	return strings.Join(rows, "\n")
+}
+
+func (m model) renderLayoutEditor() string {
+	var b strings.Builder
+	b.WriteString(valueStyle.Render("  Edit layout"))
+	b.WriteString("\n\n")
+
+	for i, slot := range m.editor.draft {
+		c, _ := findCard(slot.id)
+		check := "[ ]"
+		if slot.enabled {
+			check = "[x]"
+		}
+		line := fmt.Sprintf("  %s %d. %s", check, i+1, c.title)
+		if i == m.editor.selected {
+			b.WriteString(activeTabStyle.Render(line))
+		} else {
+			b.WriteString(line)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// handleLayoutKey handles keys while the layout editor is open.
+func (m model) handleLayoutKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	e := &m.editor
+	// Copy before mutating so the saved layout never aliases the draft.
+	e.draft = e.draft.clone()
+
+	switch msg.String() {
+	case "up", "k":
+		if e.selected > 0 {
+			e.selected--
+		}
+	case "down", "j":
+		if e.selected < len(e.draft)-1 {
+			e.selected++
+		}
+	case "left", "shift+up", "K":
+		if e.selected > 0 {
+			e.draft[e.selected], e.draft[e.selected-1] = e.draft[e.selected-1], e.draft[e.selected]
+			e.selected--
+		}
+	case "right", "shift+down", "J":
+		if e.selected < len(e.draft)-1 {
+			e.draft[e.selected], e.draft[e.selected+1] = e.draft[e.selected+1], e.draft[e.selected]
+			e.selected++
+		}
+	case " ", "x":
+		e.draft[e.selected].enabled = !e.draft[e.selected].enabled
+	case "enter", "e":
+		m.layout = e.draft
+		m.editor = layoutEditor{}
+		m.config.Layout = m.layout.enabledIDs()
+		if err := saveConfig(m.config); err != nil {
+			m.notice = fmt.Sprintf("Layout not saved: %v", err)
+		} else {
+			m.notice = "Layout saved"
+		}
+	case "esc", "q":
+		m.editor = layoutEditor{}
+		m.notice = "Layout unchanged"
+	case "ctrl+c":
+		return m, tea.Quit
+	}
+	return m, nil
+}
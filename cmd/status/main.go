@@ -3,19 +3,20 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
-	"runtime"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/shirou/gopsutil/v3/cpu"
-	"github.com/shirou/gopsutil/v3/disk"
-	"github.com/shirou/gopsutil/v3/host"
-	"github.com/shirou/gopsutil/v3/mem"
-	"github.com/shirou/gopsutil/v3/net"
+	"github.com/winmole/winmole/internal/config"
+	"github.com/winmole/winmole/internal/elevate"
+	"github.com/winmole/winmole/internal/headless"
+	"github.com/winmole/winmole/internal/redact"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/metrics"
 )
 
 // Styles
@@ -25,12 +26,6 @@ var (
 			Foreground(lipgloss.Color("205")).
 			MarginBottom(1)
 
-	cardStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("62")).
-			Padding(0, 1).
-			MarginRight(1)
-
 	labelStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("241"))
 
@@ -38,54 +33,41 @@ var (
 			Foreground(lipgloss.Color("229")).
 			Bold(true)
 
-	barEmptyStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240"))
-
 	barLowStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("42"))
 
-	barMedStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("226"))
-
-	barHighStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("196"))
-
 	statusStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("241"))
+
+	tabStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("245")).
+			Padding(0, 1)
+
+	activeTabStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("229")).
+			Background(lipgloss.Color("57")).
+			Bold(true).
+			Padding(0, 1)
+
+	warnStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214"))
 )
 
-// Metrics holds all system metrics
-type Metrics struct {
-	// CPU
-	CPUUsage float64
-	CPUCores int
-	CPUModel string
-
-	// Memory
-	MemTotal   uint64
-	MemUsed    uint64
-	MemPercent float64
-
-	// Disk
-	DiskTotal   uint64
-	DiskUsed    uint64
-	DiskPercent float64
-	DiskPath    string
-
-	// Network
-	NetSent     uint64
-	NetRecv     uint64
-	NetSentRate float64
-	NetRecvRate float64
-
-	// System
-	Hostname string
-	OS       string
-	Uptime   time.Duration
-
-	// Timestamp
-	CollectedAt time.Time
-}
+// tab identifies one screen of the dashboard.
+type tab int
+
+const (
+	tabOverview tab = iota
+	tabProcesses
+	tabDisks
+	tabNetwork
+	tabHistory
+	tabEnergy
+	tabApps
+	tabCount
+)
+
+var tabNames = [tabCount]string{"Overview", "Processes", "Disks", "Network", "History", "Energy", "Apps"}
 
 type model struct {
 	metrics     Metrics
@@ -94,6 +76,43 @@ type model struct {
 	height      int
 	ready       bool
 	animFrame   int
+	activeTab   tab
+	history     *history
+	procSort    processSort
+	procOffset  int
+	config      statusConfig
+	layout      layout
+	editor      layoutEditor
+	prompt      markerPrompt
+	notice      string
+	background  []backgroundActivity
+	showThermal bool    // the History tab shows load against temperature and clock
+	netSelected string  // adapter selected on the Network tab
+	netPending  *netFix // fix awaiting y/n
+	netRunning  bool
+	ports       portsView
+	lan         lanView
+	shares      sharesView
+	mapped      mappedView
+	partitions  partitionsView
+
+	schedule      *schedule
+	guard         *metrics.Guard
+	snapshots     *snapshotWatcher
+	showSnapshots bool
+	snapSelected  int
+
+	attribution     *attribution
+	showAttribution bool
+	attrWindow      int
+
+	energy energyView
+	apps   appHistoryView
+
+	redactor  *redact.Redactor
+	profile   string
+	inspector inspector
+	rawMemory bool // memory card shows gopsutil's figures, not Task Manager's
 }
 
 // Messages
@@ -101,73 +120,135 @@ type metricsMsg Metrics
 type tickMsg time.Time
 
 func main() {
-	p := tea.NewProgram(newModel(), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	oneline := flag.Bool("oneline", false, "print a single status line and exit")
+	interval := flag.Duration("interval", time.Second, "sampling window for rates in --oneline, --push and --otlp mode")
+	redacted := flag.Bool("redact", false, "mask the computer name, user names and IP addresses")
+	profile := flag.String("profile", "", "use the named settings profile from config.json")
+	timeout := flag.Duration("timeout", 0, "with --oneline, --push and --otlp, give up sampling after this long")
+	push := flag.Bool("push", false, "take --samples samples and send them to the server in config.json, then exit")
+	otlpExport := flag.Bool("otlp", false, "take --samples samples and export them to the OTLP endpoint in config.json, then exit")
+	samples := flag.Int("samples", 10, "samples --push and --otlp take, each over --interval")
+	port := flag.Uint("port", 0, "print the processes using this port and exit")
+	web := flag.String("web", "", "serve the cards and the latest scans to a browser on this address, such as 127.0.0.1:8741, until Ctrl+C")
+	flag.Parse()
+
+	if *port != 0 {
+		used, err := printPortUsers(uint32(*port))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !used {
+			os.Exit(1)
+		}
+		return
 	}
-}
-
-func newModel() model {
-	return model{}
-}
 
-func (m model) Init() tea.Cmd {
-	return tea.Batch(collectMetrics(), tick())
-}
-
-func collectMetrics() tea.Cmd {
-	return func() tea.Msg {
-		var metrics Metrics
-		metrics.CollectedAt = time.Now()
+	if *profile != "" {
+		config.SetProfile(*profile)
+	}
 
-		// CPU
-		if cpuPercent, err := cpu.Percent(0, false); err == nil && len(cpuPercent) > 0 {
-			metrics.CPUUsage = cpuPercent[0]
+	if *push || *otlpExport {
+		if cfg, err := loadConfig(); err == nil {
+			metrics.SetCPUMethod(cfg.CPUMethod)
 		}
-		metrics.CPUCores = runtime.NumCPU()
-		if cpuInfo, err := cpu.Info(); err == nil && len(cpuInfo) > 0 {
-			metrics.CPUModel = cpuInfo[0].ModelName
+		ctx, stop := headless.Context(*timeout)
+		send := pushMetrics
+		if *otlpExport {
+			send = exportMetrics
 		}
-
-		// Memory
-		if memInfo, err := mem.VirtualMemory(); err == nil {
-			metrics.MemTotal = memInfo.Total
-			metrics.MemUsed = memInfo.Used
-			metrics.MemPercent = memInfo.UsedPercent
+		err := send(ctx, *interval, max(*samples, 1))
+		stop()
+		if code := headless.ExitCode(err); code != 0 {
+			if code == 1 {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+			os.Exit(code)
 		}
+		return
+	}
 
-		// Disk (system drive)
-		systemDrive := os.Getenv("SystemDrive")
-		if systemDrive == "" {
-			systemDrive = "C:"
+	if *web != "" {
+		if cfg, err := loadConfig(); err == nil {
+			metrics.SetCPUMethod(cfg.CPUMethod)
 		}
-		metrics.DiskPath = systemDrive
-		if diskInfo, err := disk.Usage(systemDrive + "\\"); err == nil {
-			metrics.DiskTotal = diskInfo.Total
-			metrics.DiskUsed = diskInfo.Used
-			metrics.DiskPercent = diskInfo.UsedPercent
+		ctx, stop := headless.Context(0)
+		err := serveWeb(ctx, *web, redact.New(*redacted))
+		stop()
+		if err != nil && !headless.Stopped(err) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
+		return
+	}
 
-		// Network
-		if netInfo, err := net.IOCounters(false); err == nil && len(netInfo) > 0 {
-			metrics.NetSent = netInfo[0].BytesSent
-			metrics.NetRecv = netInfo[0].BytesRecv
+	if *oneline {
+		if cfg, err := loadConfig(); err == nil {
+			metrics.SetCPUMethod(cfg.CPUMethod)
 		}
-
-		// System info
-		if hostInfo, err := host.Info(); err == nil {
-			metrics.Hostname = hostInfo.Hostname
-			metrics.OS = fmt.Sprintf("%s %s", hostInfo.Platform, hostInfo.PlatformVersion)
-			metrics.Uptime = time.Duration(hostInfo.Uptime) * time.Second
+		ctx, stop := headless.Context(*timeout)
+		m, err := sampleOnce(ctx, *interval)
+		stop()
+		if !m.CollectedAt.IsZero() {
+			fmt.Println(redact.New(*redacted).String(formatOneline(m)))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: sampling %s\n", headless.Reason(err))
+			os.Exit(headless.ExitCode(err))
 		}
+		return
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: using default settings: %v\n", err)
+	}
+
+	m := newModel(cfg)
+	m.profile = config.Profile()
+	if *redacted {
+		m.redactor.Toggle()
+	}
+	if saved, err := loadSnapshots(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring saved snapshots: %v\n", err)
+	} else {
+		m.snapshots.saved = saved
+	}
+
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	_, err = p.Run()
+	elevate.Shutdown()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
 
-		return metricsMsg(metrics)
+func newModel(cfg statusConfig) model {
+	guard := metrics.NewGuard(metrics.DefaultPolicy)
+	configureGuard(guard, cfg)
+	metrics.SetCPUMethod(cfg.CPUMethod)
+	return model{
+		history:     newHistory(historyCapacity),
+		config:      cfg,
+		layout:      newLayout(cfg.Layout),
+		snapshots:   newSnapshotWatcher(cfg.Snapshots),
+		schedule:    newSchedule(cfg),
+		guard:       guard,
+		attribution: newAttribution(),
+		redactor:    redact.New(false),
 	}
 }
 
+func (m model) Init() tea.Cmd {
+	return tea.Batch(collectMetrics(m.guard, m.schedule.due(time.Now())), tick())
+}
+
+// tickInterval is how often the dashboard samples and redraws.
+const tickInterval = time.Second
+
 func tick() tea.Cmd {
-	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+	return tea.Tick(tickInterval, func(t time.Time) tea.Msg {
 		return tickMsg(t)
 	})
 }
@@ -175,10 +256,7 @@ func tick() tea.Cmd {
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "q", "ctrl+c", "esc":
-			return m, tea.Quit
-		}
+		return m.handleKey(msg)
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -189,23 +267,240 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.prevMetrics = m.metrics
 		m.metrics = Metrics(msg)
 
-		// Calculate network rates
-		if !m.prevMetrics.CollectedAt.IsZero() {
-			elapsed := m.metrics.CollectedAt.Sub(m.prevMetrics.CollectedAt).Seconds()
-			if elapsed > 0 {
-				m.metrics.NetSentRate = float64(m.metrics.NetSent-m.prevMetrics.NetSent) / elapsed
-				m.metrics.NetRecvRate = float64(m.metrics.NetRecv-m.prevMetrics.NetRecv) / elapsed
+		// Fill in skipped collectors and calculate rates
+		metrics.Merge(&m.metrics, &m.prevMetrics)
+		m.history.add(m.metrics)
+		m.attribution.observe(m.metrics.CollectedAt, m.metrics.Processes)
+		m.background = explainBackground(m.metrics, m.prevMetrics)
+
+		if snap, ok := m.snapshots.observe(m.metrics); ok {
+			if err := m.snapshots.record(*snap); err != nil {
+				m.notice = fmt.Sprintf("Snapshot not saved: %v", err)
+			} else {
+				m.notice = "Process snapshot captured: " + snap.Trigger
 			}
 		}
 
 		m.ready = true
 		return m, nil
 
+	case portsMsg:
+		if m.ports.show {
+			m.ports.sockets, m.ports.names, m.ports.err = msg.sockets, msg.names, msg.err
+		}
+		return m, nil
+
+	case lanMsg:
+		if !m.lan.show {
+			return m, nil
+		}
+		m.lan.loading, m.lan.sweeping = false, false
+		m.lan.err = msg.err
+		if msg.err == nil {
+			m.lan.devices = append([]lanDevice{}, msg.devices...)
+		}
+		if msg.swept && msg.err == nil {
+			m.notice = fmt.Sprintf("Swept the local subnets: %d devices", len(msg.devices))
+		}
+		return m, nil
+
+	case sharesMsg:
+		if m.shares.show {
+			m = m.applyShares(msg)
+		}
+		return m, nil
+
+	case closeFileMsg:
+		if msg.err != nil {
+			m.notice = fmt.Sprintf("Could not close %s: %v", msg.file.Path, msg.err)
+		} else {
+			m.notice = "Closed " + msg.file.Path
+		}
+		if m.shares.show {
+			m.shares.loading = true
+			return m, sharesCmd()
+		}
+		return m, nil
+
+	case mappedMsg:
+		if m.mapped.show {
+			m = m.applyMapped(msg)
+		}
+		return m, nil
+
+	case partitionsMsg:
+		if m.partitions.show {
+			m.partitions.loading = false
+			m.partitions.disks, m.partitions.err = msg.disks, msg.err
+		}
+		return m, nil
+
+	case mapActionMsg:
+		if msg.err != nil {
+			m.notice = fmt.Sprintf("Failed: %v", msg.err)
+		} else {
+			m.notice = msg.done
+		}
+		if m.mapped.show {
+			m.mapped.loading = true
+			return m, mappedCmd()
+		}
+		return m, nil
+
+	case netFixMsg:
+		m.netRunning = false
+		m.notice = netFixNotice(msg)
+		return m, nil
+
+	case energyMsg:
+		m.energy = energyView{
+			records:  msg.records,
+			err:      msg.err,
+			loadedAt: time.Now(),
+			window:   m.energy.window,
+		}
+		return m, nil
+
+	case appHistoryMsg:
+		m.apps = appHistoryView{
+			days:     msg.days,
+			oldest:   msg.oldest,
+			mhz:      msg.mhz,
+			err:      msg.err,
+			loadedAt: time.Now(),
+			window:   m.apps.window,
+			sortBy:   m.apps.sortBy,
+		}
+		return m, nil
+
 	case tickMsg:
 		m.animFrame++
-		return m, tea.Batch(collectMetrics(), tick())
+		cmds := []tea.Cmd{collectMetrics(m.guard, m.schedule.due(time.Time(msg))), tick()}
+		if m.activeTab == tabNetwork && m.ports.show {
+			cmds = append(cmds, portsCmd())
+		}
+		return m, tea.Batch(cmds...)
+	}
+
+	return m, nil
+}
+
+func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.editor.active {
+		return m.handleLayoutKey(msg)
+	}
+	if m.prompt.active {
+		return m.handleMarkerKey(msg)
+	}
+	if m.inspector.active {
+		return m.handleInspectKey(msg)
+	}
+	if m.netPending != nil {
+		return m.handleNetFixKey(msg)
+	}
+	if m.ports.typing {
+		return m.handlePortPromptKey(msg)
 	}
+	if m.mapped.typing {
+		return m.handleRemapPromptKey(msg)
+	}
+	m.notice = ""
+
+	switch msg.String() {
+	case "q", "ctrl+c", "esc":
+		return m, tea.Quit
+
+	case "tab", "right", "l":
+		m.activeTab = (m.activeTab + 1) % tabCount
+
+	case "shift+tab", "left", "h":
+		m.activeTab = (m.activeTab + tabCount - 1) % tabCount
+
+	case "1", "2", "3", "4", "5", "6", "7":
+		m.activeTab = tab(msg.String()[0] - '1')
+
+	case "m":
+		m.prompt = markerPrompt{active: true, at: time.Now()}
+
+	case "f12":
+		m.inspector = inspector{active: true}
+
+	case "A":
+		m.notice = toggleTimes()
+
+	case "x":
+		path, err := m.history.export(m.redactor)
+		if err != nil {
+			m.notice = fmt.Sprintf("Export failed: %v", err)
+		} else {
+			m.notice = "History exported to " + path
+		}
+
+	case "p":
+		if m.redactor.Toggle() {
+			m.notice = "Redaction on: computer name, user names and IP addresses are masked"
+		} else {
+			m.notice = "Redaction off"
+		}
+
+	case "P":
+		name, err := config.NextProfile(m.profile)
+		if err != nil {
+			m.notice = fmt.Sprintf("Profiles unavailable: %v", err)
+			break
+		}
+		config.SetProfile(name)
+		cfg, err := loadConfig()
+		if err != nil {
+			m.notice = fmt.Sprintf("Profile %s: using default settings: %v", profileLabel(name), err)
+		} else {
+			m.notice = "Switched to profile " + profileLabel(name)
+		}
+		m = m.applyConfig(cfg)
+		m.profile = name
 
+	case "e":
+		if m.activeTab == tabOverview {
+			m.editor = layoutEditor{active: true, draft: m.layout.clone()}
+		}
+
+	default:
+		switch m.activeTab {
+		case tabOverview:
+			if msg.String() == "r" {
+				m.rawMemory = !m.rawMemory
+				if m.rawMemory {
+					m.notice = "Memory card: raw figures (used = total - available)"
+				} else {
+					m.notice = "Memory card: Task Manager figures"
+				}
+			}
+		case tabProcesses:
+			return m.handleProcessKey(msg)
+		case tabDisks:
+			return m.handleDisksKey(msg)
+		case tabNetwork:
+			return m.handleNetworkKey(msg)
+		case tabHistory:
+			if msg.String() == "t" {
+				m.showThermal = !m.showThermal
+			}
+		case tabEnergy:
+			return m.handleEnergyKey(msg)
+		case tabApps:
+			return m.handleAppHistoryKey(msg)
+		}
+	}
+
+	// Energy and app history are only read once their tab is first opened.
+	switch {
+	case m.activeTab == tabEnergy && m.energy.needsLoad():
+		m.energy.loading = true
+		return m, loadEnergy()
+	case m.activeTab == tabApps && m.apps.needsLoad():
+		m.apps.loading = true
+		return m, loadAppHistory()
+	}
 	return m, nil
 }
 
@@ -225,157 +520,192 @@ func (m model) View() string {
 	sysInfo := fmt.Sprintf("%s • %s • Uptime: %s",
 		m.metrics.Hostname,
 		m.metrics.OS,
-		formatDuration(m.metrics.Uptime))
+		humanize.Duration(m.metrics.Uptime))
+	if m.schedule.idle {
+		sysInfo += " • Idle " + humanize.Duration(m.schedule.idleFor)
+	}
+	if m.profile != "" {
+		sysInfo += " • profile " + m.profile
+	}
+	sysInfo += " • " + humanize.CurrentUnits().Label()
+	if m.redactor.Enabled() {
+		sysInfo += " • redacted"
+	}
 	b.WriteString(statusStyle.Render(sysInfo))
-	b.WriteString("\n\n")
-
-	// Cards
-	cpuCard := m.renderCPUCard()
-	memCard := m.renderMemoryCard()
-	diskCard := m.renderDiskCard()
-	netCard := m.renderNetworkCard()
-
-	// Layout cards
-	row1 := lipgloss.JoinHorizontal(lipgloss.Top, cpuCard, memCard)
-	row2 := lipgloss.JoinHorizontal(lipgloss.Top, diskCard, netCard)
-
-	b.WriteString(row1)
 	b.WriteString("\n")
-	b.WriteString(row2)
-
-	// Footer
+	b.WriteString(m.renderHealth())
 	b.WriteString("\n\n")
-	b.WriteString(statusStyle.Render("Press 'q' to quit"))
-
-	return b.String()
-}
-
-func (m model) renderCPUCard() string {
-	var content strings.Builder
-
-	content.WriteString(valueStyle.Render("CPU"))
-	content.WriteString("\n")
-	content.WriteString(labelStyle.Render(truncateString(m.metrics.CPUModel, 30)))
-	content.WriteString("\n\n")
 
-	// Usage bar
-	content.WriteString(labelStyle.Render("Usage: "))
-	content.WriteString(renderBar(m.metrics.CPUUsage, 20))
-	content.WriteString(fmt.Sprintf(" %.1f%%", m.metrics.CPUUsage))
-	content.WriteString("\n")
-
-	// Cores
-	content.WriteString(labelStyle.Render(fmt.Sprintf("Cores: %d", m.metrics.CPUCores)))
-
-	return cardStyle.Width(40).Render(content.String())
-}
-
-func (m model) renderMemoryCard() string {
-	var content strings.Builder
-
-	content.WriteString(valueStyle.Render("Memory"))
-	content.WriteString("\n")
-	content.WriteString(labelStyle.Render(fmt.Sprintf("%s / %s",
-		humanizeBytes(m.metrics.MemUsed),
-		humanizeBytes(m.metrics.MemTotal))))
-	content.WriteString("\n\n")
+	b.WriteString(m.renderTabBar())
+	b.WriteString("\n\n")
 
-	// Usage bar
-	content.WriteString(labelStyle.Render("Usage: "))
-	content.WriteString(renderBar(m.metrics.MemPercent, 20))
-	content.WriteString(fmt.Sprintf(" %.1f%%", m.metrics.MemPercent))
+	if m.inspector.active {
+		b.WriteString(m.renderInspector())
+		b.WriteString("\n\n")
+		b.WriteString(statusStyle.Render(m.footerHelp()))
+		return m.redactor.String(b.String())
+	}
 
-	return cardStyle.Width(40).Render(content.String())
-}
+	switch m.activeTab {
+	case tabOverview:
+		b.WriteString(m.renderOverview())
+	case tabProcesses:
+		b.WriteString(m.renderProcesses())
+	case tabDisks:
+		if m.partitions.show {
+			b.WriteString(m.renderPartitions())
+		} else {
+			b.WriteString(m.renderDisks())
+		}
+	case tabNetwork:
+		b.WriteString(m.renderNetwork())
+	case tabHistory:
+		b.WriteString(m.renderHistory())
+	case tabEnergy:
+		b.WriteString(m.renderEnergy())
+	case tabApps:
+		b.WriteString(m.renderAppHistory())
+	}
 
-func (m model) renderDiskCard() string {
-	var content strings.Builder
+	// Collector failures; late or paused collectors still show their last
+	// values, which are called out as stale.
+	if len(m.metrics.Errors) > 0 {
+		var stale, failed []string
+		for _, c := range metrics.All() {
+			name := c.Name()
+			if _, ok := m.metrics.Errors[name]; !ok {
+				continue
+			}
+			switch {
+			case m.guard.Paused(name) > 0:
+				stale = append(stale, fmt.Sprintf("%s (paused %s)", name, m.guard.Paused(name).Round(time.Second)))
+			case m.metrics.Stale(name):
+				if at, ok := m.metrics.Sampled[name]; ok {
+					stale = append(stale, fmt.Sprintf("%s (late, from %s ago)", name, time.Since(at).Round(time.Second)))
+				} else {
+					stale = append(stale, name+" (late, no data yet)")
+				}
+			default:
+				failed = append(failed, name)
+			}
+		}
+		var warnings []string
+		if len(stale) > 0 {
+			warnings = append(warnings, "Stale: "+strings.Join(stale, ", "))
+		}
+		if len(failed) > 0 {
+			warnings = append(warnings, "Collection failed: "+strings.Join(failed, ", "))
+		}
+		b.WriteString("\n\n")
+		b.WriteString(warnStyle.Render("⚠ " + strings.Join(warnings, " • ")))
+	}
 
-	content.WriteString(valueStyle.Render("Disk (" + m.metrics.DiskPath + ")"))
-	content.WriteString("\n")
-	content.WriteString(labelStyle.Render(fmt.Sprintf("%s / %s",
-		humanizeBytes(m.metrics.DiskUsed),
-		humanizeBytes(m.metrics.DiskTotal))))
-	content.WriteString("\n\n")
+	if m.prompt.active {
+		b.WriteString("\n\n")
+		b.WriteString(m.renderMarkerPrompt())
+	} else if m.ports.typing {
+		b.WriteString("\n\n")
+		b.WriteString(m.renderPortPrompt())
+	} else if m.mapped.typing {
+		b.WriteString("\n\n")
+		b.WriteString(m.renderRemapPrompt())
+	} else if m.netPending != nil {
+		b.WriteString("\n\n")
+		b.WriteString(m.renderNetFixPrompt())
+	} else if m.notice != "" {
+		b.WriteString("\n\n")
+		b.WriteString(warnStyle.Render(m.notice))
+	}
 
-	// Usage bar
-	content.WriteString(labelStyle.Render("Usage: "))
-	content.WriteString(renderBar(m.metrics.DiskPercent, 20))
-	content.WriteString(fmt.Sprintf(" %.1f%%", m.metrics.DiskPercent))
+	// Footer
+	b.WriteString("\n\n")
+	b.WriteString(statusStyle.Render(m.footerHelp()))
 
-	return cardStyle.Width(40).Render(content.String())
+	return m.redactor.String(b.String())
 }
 
-func (m model) renderNetworkCard() string {
-	var content strings.Builder
-
-	content.WriteString(valueStyle.Render("Network"))
-	content.WriteString("\n")
-	content.WriteString(labelStyle.Render("Traffic rates"))
-	content.WriteString("\n\n")
-
-	// Upload/Download rates
-	content.WriteString(labelStyle.Render("↑ Upload:   "))
-	content.WriteString(valueStyle.Render(fmt.Sprintf("%s/s", humanizeBytes(uint64(m.metrics.NetSentRate)))))
-	content.WriteString("\n")
-	content.WriteString(labelStyle.Render("↓ Download: "))
-	content.WriteString(valueStyle.Render(fmt.Sprintf("%s/s", humanizeBytes(uint64(m.metrics.NetRecvRate)))))
-
-	return cardStyle.Width(40).Render(content.String())
+func (m model) renderTabBar() string {
+	tabs := make([]string, 0, tabCount)
+	for i, name := range tabNames {
+		label := fmt.Sprintf("%d %s", i+1, name)
+		if tab(i) == m.activeTab {
+			tabs = append(tabs, activeTabStyle.Render(label))
+		} else {
+			tabs = append(tabs, tabStyle.Render(label))
+		}
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, tabs...)
 }
 
-func renderBar(percent float64, width int) string {
-	filled := int(percent / 100.0 * float64(width))
-	if filled > width {
-		filled = width
+func (m model) footerHelp() string {
+	if m.editor.active {
+		return "↑/↓ select • ←/→ move • space show/hide • Enter save • Esc cancel"
 	}
-	empty := width - filled
-
-	var style lipgloss.Style
-	switch {
-	case percent >= 90:
-		style = barHighStyle
-	case percent >= 70:
-		style = barMedStyle
-	default:
-		style = barLowStyle
+	if m.prompt.active {
+		return "Enter add marker • Esc cancel"
 	}
-
-	bar := style.Render(strings.Repeat("█", filled))
-	bar += barEmptyStyle.Render(strings.Repeat("░", empty))
-	return bar
-}
-
-func humanizeBytes(bytes uint64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
+	if m.inspector.active {
+		return "↑/↓/PgUp/PgDn scroll • F12/Esc close • q quit"
 	}
-	div, exp := uint64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+	if m.netPending != nil {
+		return "y run • n/Esc cancel"
 	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
-}
-
-func formatDuration(d time.Duration) string {
-	days := int(d.Hours() / 24)
-	hours := int(d.Hours()) % 24
-	minutes := int(d.Minutes()) % 60
-
-	if days > 0 {
-		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	if m.ports.typing {
+		return "Enter look up • Esc cancel"
 	}
-	if hours > 0 {
-		return fmt.Sprintf("%dh %dm", hours, minutes)
+	if m.mapped.typing {
+		return "Enter remap • Esc cancel"
+	}
+	help := "Tab/1-7 switch tab • m marker • x export history • A absolute/relative times • p redact • P profile • q quit"
+	switch m.activeTab {
+	case tabOverview:
+		help = "e edit layout • r raw memory • " + help
+	case tabProcesses:
+		if m.showSnapshots {
+			help = "↑/↓ select • v live list • " + help
+		} else if m.showAttribution {
+			help = "w window • z reset • a live list • " + help
+		} else {
+			help = "↑/↓ scroll • s sort • a usage totals • v snapshots • " + help
+		}
+	case tabDisks:
+		if m.partitions.show {
+			help = "↑/↓ scroll • r reload • v volumes • " + help
+		} else {
+			help = "v partition map • " + help
+		}
+	case tabNetwork:
+		switch {
+		case m.ports.show:
+			help = "↑/↓ scroll • / who uses a port • a all listeners • o adapters • " + help
+		case m.lan.show:
+			help = "↑/↓ scroll • s sweep subnets • r reload • n adapters • " + help
+		case m.shares.show:
+			help = "↑/↓ select file • c close file • r reload • S adapters • " + help
+		case m.mapped.show:
+			help = "↑/↓ select drive • c reconnect • e remap • u remove • r reload • M adapters • " + help
+		default:
+			help = "↑/↓ adapter • f flush DNS • d renew DHCP • a restart adapter • w reset Winsock • o ports • / who uses a port • n LAN devices • S shares • M mapped drives • " + help
+		}
+	case tabHistory:
+		if m.showThermal {
+			help = "t all graphs • " + help
+		} else {
+			help = "t thermal • " + help
+		}
+	case tabEnergy:
+		help = "w window • r reload • " + help
+	case tabApps:
+		help = "↑/↓ scroll • w window • s sort • r reload • " + help
 	}
-	return fmt.Sprintf("%dm", minutes)
+	return help
 }
 
-func truncateString(s string, max int) string {
-	if len(s) <= max {
-		return s
+// contentHeight is the number of rows available below the tab bar.
+func (m model) contentHeight() int {
+	h := m.height - 11
+	if h < 5 {
+		h = 5
 	}
-	return s[:max-3] + "..."
+	return h
 }
@@ -0,0 +1,242 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/netdrive"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// M on the Network tab lists the mapped network drives and whether their
+// servers answer, since a dead mapping hangs Explorer and any scan that
+// touches it. ↑/↓ select a drive; c reconnects it, e points it at another
+// share and u removes it. Mappings belong to the signed-in user, so this
+// runs in the monitor itself rather than the elevated helper.
+
+// probeTimeout is how long a server has to accept a connection before
+// its drives count as unreachable.
+const probeTimeout = 3 * time.Second
+
+// mappedDrive is a mapping with the outcome of probing its server.
+type mappedDrive struct {
+	netdrive.Mapping
+	latency time.Duration
+	err     error // the server did not answer
+}
+
+// mappedView is the mapped-drives view of the Network tab.
+type mappedView struct {
+	show     bool
+	loading  bool
+	drives   []mappedDrive
+	err      error
+	selected int
+	confirm  bool   // removing the selected drive awaits y/n
+	typing   bool   // entering the share to remap to
+	input    string // share being typed
+}
+
+type mappedMsg struct {
+	drives []mappedDrive
+	err    error
+}
+
+type mapActionMsg struct {
+	done string // "Reconnected Z:"
+	err  error
+}
+
+// mappedCmd lists the mapped drives and probes their servers in parallel.
+func mappedCmd() tea.Cmd {
+	return func() tea.Msg {
+		mappings, err := netdrive.List()
+		if err != nil {
+			return mappedMsg{err: err}
+		}
+		drives := make([]mappedDrive, len(mappings))
+		var wg sync.WaitGroup
+		for i, mapping := range mappings {
+			drives[i].Mapping = mapping
+			wg.Add(1)
+			go func(d *mappedDrive) {
+				defer wg.Done()
+				d.latency, d.err = netdrive.Probe(d.Mapping, probeTimeout)
+			}(&drives[i])
+		}
+		wg.Wait()
+		return mappedMsg{drives: drives}
+	}
+}
+
+// mapActionCmd runs a reconnect, remap or removal.
+func mapActionCmd(done string, action func() error) tea.Cmd {
+	return func() tea.Msg {
+		return mapActionMsg{done: done, err: action()}
+	}
+}
+
+// selectedMapping is the drive selected in the view, if any.
+func (v mappedView) selectedMapping() (netdrive.Mapping, bool) {
+	if v.selected < len(v.drives) {
+		return v.drives[v.selected].Mapping, true
+	}
+	return netdrive.Mapping{}, false
+}
+
+// handleMappedKey handles the Network tab's keys while the mapped drives
+// are shown.
+func (m model) handleMappedKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := &m.mapped
+	d, ok := v.selectedMapping()
+	if v.confirm {
+		v.confirm = false
+		if msg.String() != "y" && msg.String() != "Y" || !ok {
+			m.notice = "Cancelled"
+			return m, nil
+		}
+		m.notice = "Removing " + d.Local + "..."
+		return m, mapActionCmd("Removed "+d.Local, func() error { return netdrive.Disconnect(d.Local, true) })
+	}
+
+	switch msg.String() {
+	case "M":
+		m.mapped = mappedView{}
+	case "r":
+		if !v.loading {
+			v.loading = true
+			return m, mappedCmd()
+		}
+	case "up", "k":
+		v.selected = max(v.selected-1, 0)
+	case "down", "j":
+		v.selected = min(v.selected+1, max(len(v.drives)-1, 0))
+	case "c":
+		if ok {
+			m.notice = "Reconnecting " + d.Local + "..."
+			return m, mapActionCmd("Reconnected "+d.Local+" to "+d.Remote, func() error { return netdrive.Reconnect(d) })
+		}
+	case "e":
+		if ok {
+			v.typing, v.input = true, d.Remote
+		}
+	case "u":
+		v.confirm = ok
+	}
+	return m, nil
+}
+
+// handleRemapPromptKey edits the share the selected drive is remapped to.
+func (m model) handleRemapPromptKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := &m.mapped
+	switch msg.Type {
+	case tea.KeyEnter:
+		v.typing = false
+		d, ok := v.selectedMapping()
+		remote := strings.TrimRight(strings.TrimSpace(v.input), `\`)
+		if !ok || strings.EqualFold(remote, d.Remote) {
+			break
+		}
+		if !strings.HasPrefix(remote, `\\`) || !strings.Contains(remote[2:], `\`) {
+			m.notice = fmt.Sprintf(`%q is not a share; use \\server\share`, remote)
+			break
+		}
+		m.notice = "Mapping " + d.Local + " to " + remote + "..."
+		return m, mapActionCmd("Mapped "+d.Local+" to "+remote, func() error { return netdrive.Remap(d, remote) })
+	case tea.KeyEsc:
+		v.typing = false
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyBackspace:
+		if n := len(v.input); n > 0 {
+			v.input = v.input[:n-1]
+		}
+	case tea.KeyRunes:
+		v.input += string(msg.Runes)
+	}
+	return m, nil
+}
+
+func (m model) renderRemapPrompt() string {
+	d, _ := m.mapped.selectedMapping()
+	return valueStyle.Render("Map "+d.Local+" to: ") + m.mapped.input + "█"
+}
+
+// applyMapped takes a new listing, keeping the same drive selected.
+func (m model) applyMapped(msg mappedMsg) model {
+	v := &m.mapped
+	v.loading = false
+	v.err = msg.err
+	if msg.err != nil {
+		return m
+	}
+	selected, _ := v.selectedMapping()
+	v.drives = msg.drives
+	v.selected = min(v.selected, max(len(v.drives)-1, 0))
+	for i, d := range v.drives {
+		if d.Local == selected.Local {
+			v.selected = i
+		}
+	}
+	return m
+}
+
+func (m model) renderMapped() string {
+	v := m.mapped
+	var b strings.Builder
+	switch {
+	case v.err != nil:
+		b.WriteString(warnStyle.Render(fmt.Sprintf("  Cannot list the mapped drives: %v", v.err)))
+		return b.String()
+	case v.drives == nil && v.loading:
+		b.WriteString(labelStyle.Render("  Checking the mapped drives..."))
+		return b.String()
+	case len(v.drives) == 0:
+		b.WriteString(labelStyle.Render("  No network drives are mapped"))
+		return b.String()
+	}
+
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  %-4s %-44s %-12s %-10s %s", "", "Share", "State", "Latency", "Restored")))
+	b.WriteString("\n")
+	for i, d := range v.drives {
+		state, latency := "connected", fmt.Sprintf("%d ms", d.latency.Milliseconds())
+		switch {
+		case d.err != nil:
+			state, latency = "unreachable", "-"
+		case !d.Connected:
+			state = "disconnected"
+		}
+		restored := "no"
+		if d.Persistent {
+			restored = "at sign-in"
+		}
+		line := fmt.Sprintf("  %-4s %-44s %-12s %-10s %s", d.Local, humanize.Truncate(d.Remote, 44), state, latency, restored)
+		switch {
+		case i == v.selected:
+			b.WriteString(activeTabStyle.Render(line))
+		case d.err != nil:
+			b.WriteString(warnStyle.Render(line))
+		default:
+			b.WriteString(line)
+		}
+		b.WriteString("\n")
+	}
+
+	if d := v.drives[v.selected]; d.err != nil {
+		b.WriteString("\n")
+		b.WriteString(labelStyle.Render(fmt.Sprintf("  %s: %v", d.Server(), d.err)))
+		b.WriteString("\n")
+	}
+	if v.confirm {
+		d := v.drives[v.selected]
+		b.WriteString("\n")
+		b.WriteString(warnStyle.Render(fmt.Sprintf("Remove %s (%s)? Files open on it are closed. ", d.Local, d.Remote)))
+		b.WriteString(valueStyle.Render("y") + labelStyle.Render(" remove • ") + valueStyle.Render("n") + labelStyle.Render(" cancel"))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,154 @@
+//go:build windows
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/config"
+	"github.com/winmole/winmole/internal/redact"
+)
+
+// marker is a user annotation dropped into the history timeline.
+type marker struct {
+	At    time.Time
+	Label string
+}
+
+// markerPrompt holds the text being typed for a new marker.
+type markerPrompt struct {
+	active bool
+	text   string
+	at     time.Time
+}
+
+func (h *history) addMarker(at time.Time, label string) {
+	h.markers = append(h.markers, marker{At: at, Label: label})
+}
+
+// handleMarkerKey edits the marker label until Enter or Esc.
+func (m model) handleMarkerKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		label := strings.TrimSpace(m.prompt.text)
+		if label == "" {
+			label = fmt.Sprintf("marker %d", len(m.history.markers)+1)
+		}
+		m.history.addMarker(m.prompt.at, label)
+		m.notice = fmt.Sprintf("Marked %s: %s", m.prompt.at.Format("15:04:05"), label)
+		m.prompt = markerPrompt{}
+	case tea.KeyEsc:
+		m.prompt = markerPrompt{}
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyBackspace:
+		if r := []rune(m.prompt.text); len(r) > 0 {
+			m.prompt.text = string(r[:len(r)-1])
+		}
+	case tea.KeySpace:
+		m.prompt.text += " "
+	case tea.KeyRunes:
+		m.prompt.text += string(msg.Runes)
+	}
+	return m, nil
+}
+
+func (m model) renderMarkerPrompt() string {
+	return valueStyle.Render("Marker label: ") + m.prompt.text + "█"
+}
+
+// markerRow places the number of each marker under the sample it falls on.
+// samples must be the same window the sparklines were drawn from.
+func markerRow(samples []sample, markers []marker) string {
+	if len(samples) == 0 {
+		return ""
+	}
+	row := []rune(strings.Repeat(" ", len(samples)))
+	for i, mk := range markers {
+		if mk.At.Before(samples[0].At) {
+			continue
+		}
+		pos := len(samples) - 1
+		for j, s := range samples {
+			if !s.At.Before(mk.At) {
+				pos = j
+				break
+			}
+		}
+		row[pos] = markerGlyph(i)
+	}
+	return string(row)
+}
+
+// markerGlyph labels markers 1-9, then a-z, then '*'.
+func markerGlyph(i int) rune {
+	switch {
+	case i < 9:
+		return rune('1' + i)
+	case i < 9+26:
+		return rune('a' + i - 9)
+	}
+	return '*'
+}
+
+// exportHistory writes every stored sample to a CSV file in the cache
+// directory, with the label of any marker dropped since the previous row.
+func (h *history) export(r *redact.Redactor) (string, error) {
+	dir := config.CacheDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create cache directory: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("status-history-%s.csv", time.Now().Format("20060102-150405")))
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("create export: %w", err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	header := []string{"time", "cpu_percent", "mem_percent", "disk_read_bps", "disk_write_bps", "net_recv_bps", "net_sent_bps", "temp_c", "clock_mhz", "throttle_percent", "marker"}
+	if err := w.Write(header); err != nil {
+		return "", fmt.Errorf("write export: %w", err)
+	}
+
+	samples := h.all()
+	next := 0
+	for i, s := range samples {
+		// Attach every marker up to this sample; markers after the last
+		// sample land on the final row.
+		var labels []string
+		for next < len(h.markers) && (!h.markers[next].At.After(s.At) || i == len(samples)-1) {
+			labels = append(labels, r.String(h.markers[next].Label))
+			next++
+		}
+		record := []string{
+			s.At.Format(time.RFC3339),
+			strconv.FormatFloat(s.CPU, 'f', 1, 64),
+			strconv.FormatFloat(s.Mem, 'f', 1, 64),
+			strconv.FormatFloat(s.DiskRead, 'f', 0, 64),
+			strconv.FormatFloat(s.DiskWrite, 'f', 0, 64),
+			strconv.FormatFloat(s.NetRecv, 'f', 0, 64),
+			strconv.FormatFloat(s.NetSent, 'f', 0, 64),
+			strconv.FormatFloat(s.Temp, 'f', 1, 64),
+			strconv.FormatFloat(s.Clock, 'f', 0, 64),
+			strconv.FormatFloat(s.Throttle, 'f', 1, 64),
+			strings.Join(labels, "; "),
+		}
+		if err := w.Write(record); err != nil {
+			return "", fmt.Errorf("write export: %w", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", fmt.Errorf("write export: %w", err)
+	}
+	return path, nil
+}
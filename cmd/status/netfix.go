@@ -0,0 +1,177 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/elevate"
+)
+
+// The Network tab runs the fixes techs reach for first. ↑/↓ select an
+// adapter; f flushes the DNS cache, d renews the selected adapter's DHCP
+// lease, a restarts it and w resets Winsock. Each asks for y/n first and
+// reports the outcome in the notice line. All but the DNS flush need admin
+// rights and run in the elevated helper.
+
+// netFix is a fix waiting for confirmation or running.
+type netFix struct {
+	label   string // "Restart Wi-Fi"
+	warning string // what it disrupts
+	op      string // helper operation; "" for the DNS flush
+	adapter string
+}
+
+type netFixMsg struct {
+	fix    netFix
+	output string
+	err    error
+}
+
+// networkInterfaces is the Network tab's list, busiest first.
+func (m model) networkInterfaces() []InterfaceInfo {
+	ifaces := append([]InterfaceInfo(nil), m.metrics.Interfaces...)
+	sort.Slice(ifaces, func(i, j int) bool {
+		return ifaces[i].RecvRate+ifaces[i].SentRate > ifaces[j].RecvRate+ifaces[j].SentRate
+	})
+	return ifaces
+}
+
+// selectedAdapter is the adapter selected on the Network tab, defaulting
+// to the busiest.
+func (m model) selectedAdapter() string {
+	ifaces := m.networkInterfaces()
+	for _, iface := range ifaces {
+		if iface.Name == m.netSelected {
+			return iface.Name
+		}
+	}
+	if len(ifaces) > 0 {
+		return ifaces[0].Name
+	}
+	return ""
+}
+
+func (m model) handleNetworkKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.ports.show {
+		return m.handlePortsKey(msg)
+	}
+	if m.lan.show {
+		return m.handleLANKey(msg)
+	}
+	if m.shares.show {
+		return m.handleSharesKey(msg)
+	}
+	if m.mapped.show {
+		return m.handleMappedKey(msg)
+	}
+	adapter := m.selectedAdapter()
+	switch msg.String() {
+	case "S":
+		m.shares = sharesView{show: true, loading: true}
+		return m, sharesCmd()
+	case "M":
+		m.mapped = mappedView{show: true, loading: true}
+		return m, mappedCmd()
+	case "n":
+		m.lan = lanView{show: true, loading: true}
+		return m, lanCmd(false)
+	case "o", "/":
+		m.ports = portsView{show: true}
+		m.ports.typing = msg.String() == "/"
+		return m, portsCmd()
+	case "up", "k", "down", "j":
+		ifaces := m.networkInterfaces()
+		for i, iface := range ifaces {
+			if iface.Name != adapter {
+				continue
+			}
+			if msg.String() == "up" || msg.String() == "k" {
+				i = max(i-1, 0)
+			} else {
+				i = min(i+1, len(ifaces)-1)
+			}
+			m.netSelected = ifaces[i].Name
+			break
+		}
+	case "f":
+		m.netPending = &netFix{label: "Flush the DNS cache", warning: "names are looked up afresh"}
+	case "w":
+		m.netPending = &netFix{label: "Reset Winsock", warning: "takes effect after a restart and removes third-party network filters", op: elevate.OpResetWinsock}
+	case "d", "a":
+		if adapter == "" {
+			m.notice = "No network adapter to act on"
+			break
+		}
+		if msg.String() == "d" {
+			m.netPending = &netFix{label: "Renew the DHCP lease of " + adapter, warning: "the address may change", op: elevate.OpRenewDHCP, adapter: adapter}
+		} else {
+			m.netPending = &netFix{label: "Restart " + adapter, warning: "drops its connections for a few seconds", op: elevate.OpRestartAdapter, adapter: adapter}
+		}
+	}
+	if m.netPending != nil && m.netRunning {
+		m.netPending = nil
+		m.notice = "Another network fix is still running"
+	}
+	return m, nil
+}
+
+// handleNetFixKey confirms or cancels the pending fix.
+func (m model) handleNetFixKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	fix := *m.netPending
+	switch msg.String() {
+	case "y", "Y":
+		m.netPending = nil
+		m.netRunning = true
+		m.notice = fix.label + "..."
+		return m, runNetFix(fix)
+	case "ctrl+c":
+		return m, tea.Quit
+	case "n", "N", "esc":
+		m.netPending = nil
+		m.notice = "Cancelled"
+	}
+	return m, nil
+}
+
+func runNetFix(fix netFix) tea.Cmd {
+	return func() tea.Msg {
+		if fix.op == "" {
+			out, err := exec.Command("ipconfig", "/flushdns").CombinedOutput()
+			if err != nil {
+				err = fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+			}
+			return netFixMsg{fix: fix, output: string(out), err: err}
+		}
+		var args any
+		if fix.adapter != "" {
+			args = elevate.AdapterArgs{Name: fix.adapter}
+		}
+		out, err := elevate.RunAction(fix.op, args)
+		return netFixMsg{fix: fix, output: out, err: err}
+	}
+}
+
+// netFixNotice reports how a fix went, with the last line the tool printed.
+func netFixNotice(msg netFixMsg) string {
+	if msg.err != nil {
+		return fmt.Sprintf("%s failed: %v", msg.fix.label, msg.err)
+	}
+	notice := msg.fix.label + ": done"
+	lines := strings.Split(strings.TrimSpace(msg.output), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		notice += " • " + last
+	}
+	return notice
+}
+
+// renderNetFixPrompt asks to confirm the pending fix.
+func (m model) renderNetFixPrompt() string {
+	fix := m.netPending
+	return warnStyle.Render(fmt.Sprintf("%s? This %s. ", fix.label, fix.warning)) +
+		valueStyle.Render("y") + labelStyle.Render(" run • ") + valueStyle.Render("n") + labelStyle.Render(" cancel")
+}
@@ -0,0 +1,55 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+func (m model) renderNetwork() string {
+	if m.ports.show {
+		return m.renderPorts()
+	}
+	if m.lan.show {
+		return m.renderLAN()
+	}
+	if m.shares.show {
+		return m.renderShares()
+	}
+	if m.mapped.show {
+		return m.renderMapped()
+	}
+
+	var b strings.Builder
+
+	ifaces := m.networkInterfaces()
+	selected := m.selectedAdapter()
+
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  %-32s %12s %12s %12s %12s",
+		"Interface", "↓ Rate", "↑ Rate", "Received", "Sent")))
+	b.WriteString("\n")
+	for _, iface := range ifaces {
+		marker := "  "
+		if iface.Name == selected {
+			marker = "▸ "
+		}
+		b.WriteString(fmt.Sprintf("%s%-32s %12s %12s %12s %12s\n", marker,
+			humanize.Truncate(iface.Name, 32),
+			humanize.Bytes(uint64(iface.RecvRate))+"/s",
+			humanize.Bytes(uint64(iface.SentRate))+"/s",
+			humanize.Bytes(iface.Recv),
+			humanize.Bytes(iface.Sent)))
+	}
+
+	c := m.metrics.Connections
+	b.WriteString("\n")
+	b.WriteString(labelStyle.Render("  Connections: "))
+	b.WriteString(valueStyle.Render(fmt.Sprintf("%d established", c.Established)))
+	b.WriteString(labelStyle.Render(fmt.Sprintf(" • %d listening • %d time-wait • %d UDP",
+		c.Listening, c.TimeWait, c.UDP)))
+
+	return b.String()
+}
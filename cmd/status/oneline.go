@@ -0,0 +1,67 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/metrics"
+)
+
+// onelineCollectors are the cheap collectors needed for the one-line summary.
+var onelineCollectors = []metrics.Collector{metrics.CPU, metrics.Memory, metrics.Disk, metrics.Network}
+
+// sampleOnce takes two samples interval apart so rates can be reported.
+// If ctx ends first it returns ctx.Err() with the first sample, without
+// rates, when there is one.
+func sampleOnce(ctx context.Context, interval time.Duration) (Metrics, error) {
+	collect := func() <-chan Metrics {
+		ch := make(chan Metrics, 1)
+		go func() { ch <- metrics.Collect(onelineCollectors) }()
+		return ch
+	}
+
+	var prev, cur Metrics
+	select {
+	case prev = <-collect():
+	case <-ctx.Done():
+		return Metrics{}, ctx.Err()
+	}
+	select {
+	case <-time.After(interval):
+	case <-ctx.Done():
+		return prev, ctx.Err()
+	}
+	select {
+	case cur = <-collect():
+	case <-ctx.Done():
+		return prev, ctx.Err()
+	}
+	metrics.Merge(&cur, &prev)
+	return cur, nil
+}
+
+// formatOneline renders a prompt-friendly summary such as
+// "cpu 12% mem 48% C: 71% ↓1.2MB/s ↑0.3MB/s".
+func formatOneline(m Metrics) string {
+	parts := []string{
+		fmt.Sprintf("cpu %.0f%%", m.CPUUsage),
+		fmt.Sprintf("mem %.0f%%", m.MemPercent),
+	}
+	if m.DiskTotal > 0 {
+		parts = append(parts, fmt.Sprintf("%s %.0f%%", m.DiskPath, m.DiskPercent))
+	}
+	parts = append(parts,
+		"↓"+compactRate(m.NetRecvRate),
+		"↑"+compactRate(m.NetSentRate))
+	return strings.Join(parts, " ")
+}
+
+// compactRate formats a byte rate without spaces, e.g. "1.2MB/s".
+func compactRate(rate float64) string {
+	return strings.ReplaceAll(humanize.Bytes(uint64(rate)), " ", "") + "/s"
+}
@@ -0,0 +1,97 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/winmole/winmole/internal/headless"
+	"github.com/winmole/winmole/internal/otlp"
+)
+
+// --otlp takes --samples samples, each over --interval, like --push, and
+// exports them as gauges to the OTLP endpoint in config.json, named after
+// the OpenTelemetry system metrics where there is one. Rates, which those
+// only have as counters, are exported as winmole.* gauges.
+
+// otlpScope is the instrumentation scope of the exported gauges.
+const otlpScope = "winmole/status"
+
+// otlpGauges turns samples into gauges.
+func otlpGauges(samples []Metrics) []otlp.Gauge {
+	cpu := otlp.Gauge{Name: "system.cpu.utilization", Unit: "1", Description: "CPU use across all cores"}
+	mem := otlp.Gauge{Name: "system.memory.usage", Unit: "By", Description: "Physical memory in use and free"}
+	memUtil := otlp.Gauge{Name: "system.memory.utilization", Unit: "1", Description: "Share of physical memory in use"}
+	fs := otlp.Gauge{Name: "system.filesystem.usage", Unit: "By", Description: "Space used and free per drive"}
+	fsUtil := otlp.Gauge{Name: "system.filesystem.utilization", Unit: "1", Description: "Share of each drive used"}
+	net := otlp.Gauge{Name: "winmole.network.rate", Unit: "By/s", Description: "Network traffic over the sampling interval"}
+	disk := otlp.Gauge{Name: "winmole.disk.rate", Unit: "By/s", Description: "Disk reads and writes over the sampling interval"}
+
+	for _, m := range samples {
+		at := m.CollectedAt
+		cpu.Points = append(cpu.Points, otlp.Point{At: at, Value: m.CPUUsage / 100})
+		mem.Points = append(mem.Points,
+			otlp.Point{At: at, Value: float64(m.MemInUse), Attrs: map[string]any{"system.memory.state": "used"}},
+			otlp.Point{At: at, Value: float64(m.MemTotal - min(m.MemInUse, m.MemTotal)), Attrs: map[string]any{"system.memory.state": "free"}})
+		memUtil.Points = append(memUtil.Points, otlp.Point{At: at, Value: m.MemInUsePercent / 100, Attrs: map[string]any{"system.memory.state": "used"}})
+		net.Points = append(net.Points,
+			otlp.Point{At: at, Value: m.NetSentRate, Attrs: map[string]any{"network.io.direction": "transmit"}},
+			otlp.Point{At: at, Value: m.NetRecvRate, Attrs: map[string]any{"network.io.direction": "receive"}})
+		for _, d := range m.Disks {
+			mount := map[string]any{"system.filesystem.mountpoint": d.Mount, "system.filesystem.type": d.FSType}
+			fs.Points = append(fs.Points,
+				otlp.Point{At: at, Value: float64(d.Used), Attrs: withAttr(mount, "system.filesystem.state", "used")},
+				otlp.Point{At: at, Value: float64(d.Total - min(d.Used, d.Total)), Attrs: withAttr(mount, "system.filesystem.state", "free")})
+			fsUtil.Points = append(fsUtil.Points, otlp.Point{At: at, Value: d.Percent / 100, Attrs: mount})
+			disk.Points = append(disk.Points,
+				otlp.Point{At: at, Value: d.ReadRate, Attrs: withAttr(mount, "disk.io.direction", "read")},
+				otlp.Point{At: at, Value: d.WriteRate, Attrs: withAttr(mount, "disk.io.direction", "write")})
+		}
+	}
+	return []otlp.Gauge{cpu, mem, memUtil, fs, fsUtil, net, disk}
+}
+
+// withAttr returns a copy of attrs with key set to value.
+func withAttr(attrs map[string]any, key string, value any) map[string]any {
+	c := make(map[string]any, len(attrs)+1)
+	for k, v := range attrs {
+		c[k] = v
+	}
+	c[key] = value
+	return c
+}
+
+// exportMetrics samples and exports the samples. A run cut short by ctx
+// exports the samples taken so far.
+func exportMetrics(ctx context.Context, interval time.Duration, count int) error {
+	settings, err := otlp.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if !settings.Configured() {
+		return otlp.ErrNotConfigured
+	}
+
+	var samples []Metrics
+	for i := 0; i < count; i++ {
+		m, err := sampleOnce(ctx, interval)
+		if err != nil {
+			break
+		}
+		samples = append(samples, m)
+	}
+	if len(samples) > 0 {
+		if err := settings.ExportGauges(otlpScope, otlpGauges(samples)); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d samples to %s\n", len(samples), settings.Endpoint)
+	if err := ctx.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: sampling %s after %d samples\n", headless.Reason(err), len(samples))
+		return err
+	}
+	return nil
+}
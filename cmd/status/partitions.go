@@ -0,0 +1,457 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unsafe"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/winmole/winmole/pkg/humanize"
+	"golang.org/x/sys/windows"
+)
+
+// v on the Disks tab shows each physical disk's partition layout as a bar
+// drawn to scale: the EFI and reserved partitions, Windows, the recovery
+// partition and any unallocated space, which is usually where the
+// gigabytes missing from C: went. Disks are opened without read or write
+// access, which needs no admin rights.
+
+// winioctl.h constants.
+const (
+	ioctlDiskGetDriveGeometryEx     = 0x000700A0
+	ioctlDiskGetDriveLayoutEx       = 0x00070050
+	ioctlStorageQueryProperty       = 0x002D1400
+	ioctlVolumeGetVolumeDiskExtents = 0x00560000
+	partitionStyleMBR               = 0
+	partitionStyleGPT               = 1
+)
+
+// maxPhysicalDrives is how many \\.\PhysicalDriveN names are tried.
+const maxPhysicalDrives = 32
+
+// minGap is the smallest unallocated stretch shown; less than that is
+// alignment slack.
+const minGap int64 = 16 << 20
+
+// partitionKind is what a partition is for, which picks its color.
+type partitionKind int
+
+const (
+	partData partitionKind = iota
+	partEFI
+	partReserved
+	partRecovery
+	partOther
+	partFree
+)
+
+var partStyles = map[partitionKind]lipgloss.Style{
+	partData:     lipgloss.NewStyle().Foreground(lipgloss.Color("39")),
+	partEFI:      lipgloss.NewStyle().Foreground(lipgloss.Color("213")),
+	partReserved: lipgloss.NewStyle().Foreground(lipgloss.Color("245")),
+	partRecovery: lipgloss.NewStyle().Foreground(lipgloss.Color("214")),
+	partOther:    lipgloss.NewStyle().Foreground(lipgloss.Color("141")),
+	partFree:     lipgloss.NewStyle().Foreground(lipgloss.Color("238")),
+}
+
+// GPT partition types worth naming.
+var gptTypes = map[windows.GUID]struct {
+	name string
+	kind partitionKind
+}{
+	mustGUID("{C12A7328-F81F-11D2-BA4B-00A0C93EC93B}"): {"EFI system", partEFI},
+	mustGUID("{E3C9E316-0B5C-4DB8-817D-F92DF00215AE}"): {"Microsoft reserved", partReserved},
+	mustGUID("{EBD0A0A2-B9E5-4433-87C0-68B6B72699C7}"): {"Basic data", partData},
+	mustGUID("{DE94BBA4-06D1-4D40-A16A-BFD50179D6AC}"): {"Recovery", partRecovery},
+	mustGUID("{5808C8AA-7E8F-42E0-85D2-E1E90434CFB3}"): {"Dynamic disk metadata", partReserved},
+	mustGUID("{AF9B60A0-1431-4F62-BC68-3311714A69AD}"): {"Dynamic disk data", partData},
+	mustGUID("{E75CAF8F-F680-4CEE-AFA3-B001E56EFC2D}"): {"Storage Spaces", partOther},
+	mustGUID("{0FC63DAF-8483-4772-8E79-3D69D8477DE4}"): {"Linux", partOther},
+	mustGUID("{0657FD6D-A4AB-43C4-84E5-0933C84B4F4F}"): {"Linux swap", partOther},
+}
+
+func mustGUID(s string) windows.GUID {
+	g, err := windows.GUIDFromString(s)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// partition is one partition, or a stretch of unallocated space.
+type partition struct {
+	number int // 0 for unallocated space
+	offset int64
+	length int64
+	name   string // "Recovery"
+	kind   partitionKind
+	volume string // "C:", when it has a drive letter
+}
+
+// physicalDisk is one disk and its layout, in order of offset.
+type physicalDisk struct {
+	number     int
+	model      string
+	size       int64
+	style      string // "GPT" or "MBR"
+	partitions []partition
+}
+
+type partitionsMsg struct {
+	disks []physicalDisk
+	err   error
+}
+
+// partitionsView is the partition map of the Disks tab.
+type partitionsView struct {
+	show    bool
+	loading bool
+	disks   []physicalDisk
+	err     error
+	offset  int
+}
+
+// partitionInfoEx mirrors PARTITION_INFORMATION_EX.
+type partitionInfoEx struct {
+	style            uint32
+	_                uint32
+	startingOffset   int64
+	partitionLength  int64
+	partitionNumber  uint32
+	rewritePartition bool
+	isService        bool
+	_                [2]byte
+	info             [112]byte // PARTITION_INFORMATION_MBR or _GPT
+}
+
+// diskExtent mirrors DISK_EXTENT.
+type diskExtent struct {
+	diskNumber     uint32
+	_              uint32
+	startingOffset int64
+	extentLength   int64
+}
+
+func partitionsCmd() tea.Cmd {
+	return func() tea.Msg {
+		disks, err := readPartitions()
+		return partitionsMsg{disks: disks, err: err}
+	}
+}
+
+// readPartitions reads the layout of every physical disk.
+func readPartitions() ([]physicalDisk, error) {
+	volumes := volumeExtents()
+	var disks []physicalDisk
+	for n := 0; n < maxPhysicalDrives; n++ {
+		h, err := openDevice(fmt.Sprintf(`\\.\PhysicalDrive%d`, n))
+		if err != nil {
+			continue
+		}
+		disk, err := readDisk(h, n)
+		windows.CloseHandle(h)
+		if err != nil {
+			return disks, fmt.Errorf("disk %d: %w", n, err)
+		}
+		for i, p := range disk.partitions {
+			disk.partitions[i].volume = volumes[extentKey{n, p.offset}]
+		}
+		disks = append(disks, disk)
+	}
+	if len(disks) == 0 {
+		return nil, fmt.Errorf("no physical disks could be opened")
+	}
+	return disks, nil
+}
+
+// openDevice opens a disk or volume for control requests only.
+func openDevice(path string) (windows.Handle, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	return windows.CreateFile(p, 0, windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE, nil, windows.OPEN_EXISTING, 0, 0)
+}
+
+func readDisk(h windows.Handle, number int) (physicalDisk, error) {
+	disk := physicalDisk{number: number, model: diskModel(h)}
+
+	// DISK_GEOMETRY_EX: a 24-byte DISK_GEOMETRY, then the size.
+	var geometry [256]byte
+	var n uint32
+	if err := windows.DeviceIoControl(h, ioctlDiskGetDriveGeometryEx, nil, 0, &geometry[0], uint32(len(geometry)), &n, nil); err != nil {
+		return disk, fmt.Errorf("geometry: %w", err)
+	}
+	disk.size = *(*int64)(unsafe.Pointer(&geometry[24]))
+
+	// DRIVE_LAYOUT_INFORMATION_EX grows with the partition count.
+	buf := make([]byte, 4096)
+	for {
+		err := windows.DeviceIoControl(h, ioctlDiskGetDriveLayoutEx, nil, 0, &buf[0], uint32(len(buf)), &n, nil)
+		if err == windows.ERROR_INSUFFICIENT_BUFFER && len(buf) < 1<<20 {
+			buf = make([]byte, len(buf)*4)
+			continue
+		}
+		if err != nil {
+			return disk, fmt.Errorf("layout: %w", err)
+		}
+		break
+	}
+	style := *(*uint32)(unsafe.Pointer(&buf[0]))
+	count := *(*uint32)(unsafe.Pointer(&buf[4]))
+	// The partitions follow a 40-byte union of the MBR and GPT headers.
+	entries := unsafe.Slice((*partitionInfoEx)(unsafe.Pointer(&buf[48])), count)
+
+	usableStart, usableEnd := int64(0), disk.size
+	switch style {
+	case partitionStyleGPT:
+		disk.style = "GPT"
+		usableStart = *(*int64)(unsafe.Pointer(&buf[8+16]))
+		usableEnd = usableStart + *(*int64)(unsafe.Pointer(&buf[8+24]))
+	case partitionStyleMBR:
+		disk.style = "MBR"
+	default:
+		disk.style = "not initialized"
+	}
+
+	for _, e := range entries {
+		if e.partitionLength == 0 {
+			continue // unused MBR slots
+		}
+		p := partition{number: int(e.partitionNumber), offset: e.startingOffset, length: e.partitionLength}
+		p.name, p.kind = partitionType(e)
+		if p.kind == partReserved && p.name == "" {
+			continue // the extended partition that holds logical ones
+		}
+		disk.partitions = append(disk.partitions, p)
+	}
+	sort.Slice(disk.partitions, func(i, j int) bool { return disk.partitions[i].offset < disk.partitions[j].offset })
+	disk.partitions = withFreeSpace(disk.partitions, usableStart, usableEnd)
+	return disk, nil
+}
+
+// partitionType names an entry's type.
+func partitionType(e partitionInfoEx) (string, partitionKind) {
+	if e.style == partitionStyleGPT {
+		var typ windows.GUID
+		copy(unsafe.Slice((*byte)(unsafe.Pointer(&typ)), unsafe.Sizeof(typ)), e.info[:16])
+		if t, ok := gptTypes[typ]; ok {
+			return t.name, t.kind
+		}
+		return "Other", partOther
+	}
+	switch e.info[0] {
+	case 0x07, 0x0B, 0x0C, 0x0E, 0x06:
+		return "Basic data", partData
+	case 0x27:
+		return "Recovery", partRecovery
+	case 0xEF:
+		return "EFI system", partEFI
+	case 0x05, 0x0F:
+		return "", partReserved
+	case 0x42:
+		return "Dynamic disk", partData
+	case 0x83:
+		return "Linux", partOther
+	}
+	return fmt.Sprintf("Type %02X", e.info[0]), partOther
+}
+
+// withFreeSpace adds the gaps between partitions in [start, end) as
+// unallocated space.
+func withFreeSpace(parts []partition, start, end int64) []partition {
+	var out []partition
+	at := start
+	for _, p := range parts {
+		if p.offset-at >= minGap {
+			out = append(out, partition{offset: at, length: p.offset - at, name: "Unallocated", kind: partFree})
+		}
+		out = append(out, p)
+		at = max(at, p.offset+p.length)
+	}
+	if end-at >= minGap {
+		out = append(out, partition{offset: at, length: end - at, name: "Unallocated", kind: partFree})
+	}
+	return out
+}
+
+// diskModel reads the disk's product name.
+func diskModel(h windows.Handle) string {
+	// STORAGE_PROPERTY_QUERY for StorageDeviceProperty, PropertyStandardQuery.
+	var query [12]byte
+	var buf [1024]byte
+	var n uint32
+	if err := windows.DeviceIoControl(h, ioctlStorageQueryProperty, &query[0], uint32(len(query)), &buf[0], uint32(len(buf)), &n, nil); err != nil {
+		return ""
+	}
+	// STORAGE_DEVICE_DESCRIPTOR: the vendor and product offsets are at 12 and 16.
+	field := func(at int) string {
+		offset := *(*uint32)(unsafe.Pointer(&buf[at]))
+		if offset == 0 || offset >= n {
+			return ""
+		}
+		s := buf[offset:n]
+		if i := strings.IndexByte(string(s), 0); i >= 0 {
+			s = s[:i]
+		}
+		return strings.TrimSpace(string(s))
+	}
+	return strings.TrimSpace(field(12) + " " + field(16))
+}
+
+// extentKey locates a volume by the disk and offset it starts at.
+type extentKey struct {
+	disk   int
+	offset int64
+}
+
+// volumeExtents maps the start of each lettered volume to its letter.
+// Volumes spanning disks are found by their first extent.
+func volumeExtents() map[extentKey]string {
+	volumes := make(map[extentKey]string)
+	drives, err := windows.GetLogicalDrives()
+	if err != nil {
+		return volumes
+	}
+	for i := 0; i < 26; i++ {
+		if drives&(1<<i) == 0 {
+			continue
+		}
+		letter := string(rune('A'+i)) + ":"
+		h, err := openDevice(`\\.\` + letter)
+		if err != nil {
+			continue
+		}
+		// VOLUME_DISK_EXTENTS: a count, then the extents, 8-byte aligned.
+		var buf [8 + 24*4]byte
+		var n uint32
+		if windows.DeviceIoControl(h, ioctlVolumeGetVolumeDiskExtents, nil, 0, &buf[0], uint32(len(buf)), &n, nil) == nil {
+			if *(*uint32)(unsafe.Pointer(&buf[0])) > 0 {
+				e := (*diskExtent)(unsafe.Pointer(&buf[8]))
+				volumes[extentKey{int(e.diskNumber), e.startingOffset}] = letter
+			}
+		}
+		windows.CloseHandle(h)
+	}
+	return volumes
+}
+
+// handleDisksKey handles the Disks tab's keys.
+func (m model) handleDisksKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.partitions.show {
+		return m.handlePartitionsKey(msg)
+	}
+	if msg.String() == "v" {
+		m.partitions = partitionsView{show: true, loading: true}
+		return m, partitionsCmd()
+	}
+	return m, nil
+}
+
+// handlePartitionsKey handles the Disks tab's keys while the partition
+// map is shown.
+func (m model) handlePartitionsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "v":
+		m.partitions = partitionsView{}
+	case "r":
+		if !m.partitions.loading {
+			m.partitions.loading = true
+			return m, partitionsCmd()
+		}
+	case "up", "k":
+		m.partitions.offset = max(m.partitions.offset-1, 0)
+	case "down", "j":
+		m.partitions.offset = min(m.partitions.offset+1, max(len(m.partitionLines())-m.portsHeight(), 0))
+	}
+	return m, nil
+}
+
+// fileSystem is the file system of a lettered volume, from the metrics.
+func (m model) fileSystem(volume string) string {
+	for _, d := range m.metrics.Disks {
+		if strings.EqualFold(d.Mount, volume) {
+			return d.FSType
+		}
+	}
+	return ""
+}
+
+// partitionLines are the lines of the partition map.
+func (m model) partitionLines() []string {
+	v := m.partitions
+	if v.err != nil && v.disks == nil {
+		return []string{warnStyle.Render(fmt.Sprintf("  Cannot read the partition tables: %v", v.err))}
+	}
+	if v.disks == nil {
+		return []string{labelStyle.Render("  Reading the partition tables...")}
+	}
+
+	width := max(m.width-4, 20)
+	var lines []string
+	for _, d := range v.disks {
+		title := fmt.Sprintf("Disk %d", d.number)
+		if d.model != "" {
+			title += " • " + d.model
+		}
+		title += fmt.Sprintf(" • %s • %s", humanize.Bytes(d.size), d.style)
+		lines = append(lines, "  "+valueStyle.Render(title), "  "+partitionBar(d, width))
+		for _, p := range d.partitions {
+			label := p.name
+			if p.volume != "" {
+				label = p.volume + " " + label
+			}
+			fs := m.fileSystem(p.volume)
+			number := ""
+			if p.number > 0 {
+				number = fmt.Sprintf("#%d", p.number)
+			}
+			lines = append(lines, fmt.Sprintf("  %s %-4s %-28s %10s  %s", partStyles[p.kind].Render("■"), number,
+				humanize.Truncate(label, 28), humanize.Bytes(p.length), fs))
+		}
+		lines = append(lines, "")
+	}
+	if v.err != nil {
+		lines = append(lines, warnStyle.Render(fmt.Sprintf("  %v", v.err)))
+	}
+	return lines
+}
+
+// partitionBar draws the disk's layout to scale in width cells. Every
+// partition gets at least one cell, taken from the largest.
+func partitionBar(d physicalDisk, width int) string {
+	if d.size <= 0 || len(d.partitions) == 0 {
+		return partStyles[partFree].Render(strings.Repeat("░", width))
+	}
+	cells := make([]int, len(d.partitions))
+	used, largest := 0, 0
+	for i, p := range d.partitions {
+		cells[i] = max(int(float64(p.length)/float64(d.size)*float64(width)+0.5), 1)
+		used += cells[i]
+		if cells[i] > cells[largest] {
+			largest = i
+		}
+	}
+	cells[largest] = max(cells[largest]+width-used, 1)
+
+	var b strings.Builder
+	for i, p := range d.partitions {
+		glyph := "█"
+		if p.kind == partFree {
+			glyph = "░"
+		}
+		b.WriteString(partStyles[p.kind].Render(strings.Repeat(glyph, cells[i])))
+	}
+	return b.String()
+}
+
+func (m model) renderPartitions() string {
+	lines := m.partitionLines()
+	height := m.portsHeight()
+	offset := min(m.partitions.offset, max(len(lines)-height, 0))
+	return strings.TrimRight(strings.Join(lines[offset:min(offset+height, len(lines))], "\n"), "\n")
+}
@@ -0,0 +1,320 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// o on the Network tab lists the listening ports grouped by the process
+// that owns them, and flags ports bound by more than one process: the
+// usual reason a dev server will not start. / asks for a port number and
+// shows every socket using it, listening or connected; a goes back to all
+// listeners. --port does the same lookup from the command line.
+
+// socket is one entry of the socket table.
+type socket struct {
+	proto  string // "TCP" or "UDP"
+	local  string
+	port   uint32
+	remote string // "" for listeners
+	status string
+	pid    int32
+}
+
+// listening reports whether s waits for connections: a TCP listener or a
+// bound UDP socket.
+func (s socket) listening() bool {
+	return s.status == "LISTEN" || (s.proto == "UDP" && s.remote == "")
+}
+
+type portsMsg struct {
+	sockets []socket
+	names   map[int32]string
+	err     error
+}
+
+// portsView is the listening-ports view of the Network tab.
+type portsView struct {
+	show    bool
+	query   uint32 // port looked up with /, 0 for none
+	typing  bool   // a port number is being typed
+	input   string
+	offset  int
+	sockets []socket
+	names   map[int32]string
+	err     error
+}
+
+// readSockets reads the socket table and names the processes owning the
+// sockets. Names of processes this user may not open are left out.
+func readSockets() ([]socket, map[int32]string, error) {
+	conns, err := net.Connections("inet")
+	if err != nil {
+		return nil, nil, fmt.Errorf("socket table: %w", err)
+	}
+	sockets := make([]socket, 0, len(conns))
+	names := make(map[int32]string)
+	for _, c := range conns {
+		s := socket{proto: "TCP", local: c.Laddr.IP, port: c.Laddr.Port, status: c.Status, pid: c.Pid}
+		if c.Type == 2 { // SOCK_DGRAM
+			s.proto = "UDP"
+		}
+		if c.Raddr.IP != "" && c.Raddr.Port != 0 {
+			s.remote = joinHostPort(c.Raddr.IP, c.Raddr.Port)
+		}
+		sockets = append(sockets, s)
+		if _, ok := names[s.pid]; !ok {
+			names[s.pid] = processName(s.pid)
+		}
+	}
+	sort.Slice(sockets, func(i, j int) bool {
+		if sockets[i].port != sockets[j].port {
+			return sockets[i].port < sockets[j].port
+		}
+		return sockets[i].proto < sockets[j].proto
+	})
+	return sockets, names, nil
+}
+
+func processName(pid int32) string {
+	switch pid {
+	case 0:
+		return "System Idle Process"
+	case 4:
+		// Listeners registered with http.sys (IIS, WinRM, some dev tools)
+		// belong to the kernel.
+		return "System (http.sys)"
+	}
+	if p, err := process.NewProcess(pid); err == nil {
+		if name, err := p.Name(); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+func joinHostPort(ip string, port uint32) string {
+	if strings.Contains(ip, ":") {
+		return fmt.Sprintf("[%s]:%d", ip, port)
+	}
+	return fmt.Sprintf("%s:%d", ip, port)
+}
+
+func portsCmd() tea.Cmd {
+	return func() tea.Msg {
+		sockets, names, err := readSockets()
+		return portsMsg{sockets: sockets, names: names, err: err}
+	}
+}
+
+// portConflicts maps "3000/TCP" to the processes listening on it, for the
+// ports more than one process listens on.
+func portConflicts(sockets []socket) map[string][]int32 {
+	owners := make(map[string][]int32)
+	for _, s := range sockets {
+		if !s.listening() {
+			continue
+		}
+		key := fmt.Sprintf("%d/%s", s.port, s.proto)
+		if !containsPID(owners[key], s.pid) {
+			owners[key] = append(owners[key], s.pid)
+		}
+	}
+	for key, pids := range owners {
+		if len(pids) < 2 {
+			delete(owners, key)
+		}
+	}
+	return owners
+}
+
+func containsPID(pids []int32, pid int32) bool {
+	for _, p := range pids {
+		if p == pid {
+			return true
+		}
+	}
+	return false
+}
+
+// usingPort returns the sockets bound to port, listening or connected.
+func usingPort(sockets []socket, port uint32) []socket {
+	var matched []socket
+	for _, s := range sockets {
+		if s.port == port {
+			matched = append(matched, s)
+		}
+	}
+	return matched
+}
+
+func ownerLabel(pid int32, names map[int32]string) string {
+	if name := names[pid]; name != "" {
+		return fmt.Sprintf("%s (%d)", name, pid)
+	}
+	return fmt.Sprintf("PID %d", pid)
+}
+
+// formatPortUsers describes who uses port, one socket per line.
+func formatPortUsers(port uint32, sockets []socket, names map[int32]string) []string {
+	matched := usingPort(sockets, port)
+	if len(matched) == 0 {
+		return []string{fmt.Sprintf("Nothing is using port %d.", port)}
+	}
+	lines := make([]string, 0, len(matched))
+	for _, s := range matched {
+		what := "listening"
+		switch {
+		case s.remote != "":
+			what = strings.ToLower(s.status) + " with " + s.remote
+		case !s.listening():
+			what = strings.ToLower(s.status)
+		}
+		lines = append(lines, fmt.Sprintf("%-4s %-24s %-32s %s", s.proto, joinHostPort(s.local, s.port), ownerLabel(s.pid, names), what))
+	}
+	return lines
+}
+
+// printPortUsers is --port: it prints who uses port and reports whether
+// anything does.
+func printPortUsers(port uint32) (bool, error) {
+	sockets, names, err := readSockets()
+	if err != nil {
+		return false, err
+	}
+	for _, line := range formatPortUsers(port, sockets, names) {
+		fmt.Println(line)
+	}
+	return len(usingPort(sockets, port)) > 0, nil
+}
+
+// handlePortsKey handles the Network tab's keys while the ports view is
+// shown.
+func (m model) handlePortsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "o":
+		m.ports = portsView{}
+	case "/":
+		m.ports.typing, m.ports.input = true, ""
+	case "a":
+		m.ports.query, m.ports.offset = 0, 0
+	case "up", "k":
+		m.ports.offset = max(m.ports.offset-1, 0)
+	case "down", "j":
+		m.ports.offset = min(m.ports.offset+1, max(len(m.portLines())-m.portsHeight(), 0))
+	}
+	return m, nil
+}
+
+// handlePortPromptKey edits the port number being looked up.
+func (m model) handlePortPromptKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		port, err := strconv.ParseUint(strings.TrimSpace(m.ports.input), 10, 16)
+		m.ports.typing = false
+		if err != nil || port == 0 {
+			m.notice = fmt.Sprintf("%q is not a port number", m.ports.input)
+			break
+		}
+		m.ports.query, m.ports.offset = uint32(port), 0
+	case tea.KeyEsc:
+		m.ports.typing = false
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyBackspace:
+		if n := len(m.ports.input); n > 0 {
+			m.ports.input = m.ports.input[:n-1]
+		}
+	case tea.KeyRunes:
+		m.ports.input += string(msg.Runes)
+	}
+	return m, nil
+}
+
+func (m model) renderPortPrompt() string {
+	return valueStyle.Render("Who is using port: ") + m.ports.input + "█"
+}
+
+// portLines are the lines of the ports view: the listening ports by
+// process, conflicts first, or the sockets on the port looked up.
+func (m model) portLines() []string {
+	v := m.ports
+	if v.err != nil {
+		return []string{warnStyle.Render(fmt.Sprintf("  Cannot read the socket table: %v", v.err))}
+	}
+	if v.sockets == nil {
+		return []string{labelStyle.Render("  Reading the socket table...")}
+	}
+
+	if v.query != 0 {
+		lines := []string{labelStyle.Render(fmt.Sprintf("  Port %d", v.query)), ""}
+		for _, line := range formatPortUsers(v.query, v.sockets, v.names) {
+			lines = append(lines, "  "+line)
+		}
+		return lines
+	}
+
+	var lines []string
+	conflicts := portConflicts(v.sockets)
+	keys := make([]string, 0, len(conflicts))
+	for key := range conflicts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		owners := make([]string, len(conflicts[key]))
+		for i, pid := range conflicts[key] {
+			owners[i] = ownerLabel(pid, v.names)
+		}
+		lines = append(lines, warnStyle.Render(fmt.Sprintf("  ⚠ %s is bound by %s", key, strings.Join(owners, " and "))))
+	}
+	if len(keys) > 0 {
+		lines = append(lines, "")
+	}
+
+	byPID := make(map[int32][]string)
+	for _, s := range v.sockets {
+		if s.listening() {
+			byPID[s.pid] = append(byPID[s.pid], fmt.Sprintf("%s %s", s.proto, joinHostPort(s.local, s.port)))
+		}
+	}
+	pids := make([]int32, 0, len(byPID))
+	for pid := range byPID {
+		pids = append(pids, pid)
+	}
+	sort.Slice(pids, func(i, j int) bool {
+		a, b := strings.ToLower(v.names[pids[i]]), strings.ToLower(v.names[pids[j]])
+		if a != b {
+			return a < b
+		}
+		return pids[i] < pids[j]
+	})
+	for _, pid := range pids {
+		lines = append(lines, valueStyle.Render("  "+ownerLabel(pid, v.names)))
+		lines = append(lines, labelStyle.Render("      "+strings.Join(byPID[pid], ", ")))
+	}
+	if len(pids) == 0 {
+		lines = append(lines, labelStyle.Render("  Nothing is listening."))
+	}
+	return lines
+}
+
+// portsHeight is how many lines of the ports view fit on screen.
+func (m model) portsHeight() int {
+	return max(m.height-10, 5)
+}
+
+func (m model) renderPorts() string {
+	lines := m.portLines()
+	offset := min(m.ports.offset, max(len(lines)-m.portsHeight(), 0))
+	end := min(offset+m.portsHeight(), len(lines))
+	return strings.Join(lines[offset:end], "\n")
+}
@@ -0,0 +1,132 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// processSort selects the Processes tab ordering.
+type processSort int
+
+const (
+	sortByCPU processSort = iota
+	sortByMemory
+)
+
+// sortedProcesses returns the processes in the tab's current order.
+func (m model) sortedProcesses() []ProcessInfo {
+	return sortProcesses(m.metrics.Processes, m.procSort)
+}
+
+// sortProcesses returns a sorted copy of procs.
+func sortProcesses(list []ProcessInfo, by processSort) []ProcessInfo {
+	procs := append([]ProcessInfo(nil), list...)
+	sort.Slice(procs, func(i, j int) bool {
+		if by == sortByMemory {
+			return procs[i].Memory > procs[j].Memory
+		}
+		if procs[i].CPUPercent != procs[j].CPUPercent {
+			return procs[i].CPUPercent > procs[j].CPUPercent
+		}
+		return procs[i].Memory > procs[j].Memory
+	})
+	return procs
+}
+
+func (m model) handleProcessKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "v":
+		m.showSnapshots = !m.showSnapshots
+		m.showAttribution = false
+		m.snapSelected = 0
+		return m, nil
+	case "a":
+		m.showAttribution = !m.showAttribution
+		m.showSnapshots = false
+		return m, nil
+	}
+	if m.showAttribution {
+		switch msg.String() {
+		case "w":
+			m.attrWindow = (m.attrWindow + 1) % len(attributionWindows)
+		case "z":
+			m.attribution.reset(m.metrics.CollectedAt)
+			m.notice = "Usage totals reset"
+		}
+		return m, nil
+	}
+	if m.showSnapshots {
+		switch msg.String() {
+		case "up", "k":
+			if m.snapSelected > 0 {
+				m.snapSelected--
+			}
+		case "down", "j":
+			if m.snapSelected < len(m.snapshots.saved)-1 {
+				m.snapSelected++
+			}
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if m.procOffset > 0 {
+			m.procOffset--
+		}
+	case "down", "j":
+		if m.procOffset < len(m.metrics.Processes)-1 {
+			m.procOffset++
+		}
+	case "s":
+		if m.procSort == sortByCPU {
+			m.procSort = sortByMemory
+		} else {
+			m.procSort = sortByCPU
+		}
+		m.procOffset = 0
+	}
+	return m, nil
+}
+
+func (m model) renderProcesses() string {
+	if m.showSnapshots {
+		return m.renderSnapshots()
+	}
+	if m.showAttribution {
+		return m.renderAttribution()
+	}
+	procs := m.sortedProcesses()
+
+	sortLabel := "CPU"
+	if m.procSort == sortByMemory {
+		sortLabel = "memory"
+	}
+
+	var b strings.Builder
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  %d processes • sorted by %s", len(procs), sortLabel)))
+	b.WriteString("\n\n")
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  %7s  %-32s %7s %12s", "PID", "Name", "CPU", "Memory")))
+	b.WriteString("\n")
+
+	rows := m.contentHeight() - 3
+	start := m.procOffset
+	if start > len(procs) {
+		start = len(procs)
+	}
+	end := start + rows
+	if end > len(procs) {
+		end = len(procs)
+	}
+	for _, p := range procs[start:end] {
+		b.WriteString(fmt.Sprintf("  %7d  %-32s %6.1f%% %12s\n",
+			p.PID, humanize.Truncate(p.Name, 32), p.CPUPercent, humanize.Bytes(p.Memory)))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,81 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/winmole/winmole/internal/headless"
+	"github.com/winmole/winmole/internal/upload"
+)
+
+// --push takes --samples samples, each over --interval, and sends them to
+// the WinMole server set in config.json as one metrics batch. Run from
+// Task Scheduler every few minutes, it gives the server the load and the
+// drive space of each machine over time. A batch the server cannot take
+// now waits in the outbox and goes with the next run.
+
+func newSample(m Metrics) upload.Sample {
+	s := upload.Sample{
+		At:      m.CollectedAt,
+		CPU:     m.CPUUsage,
+		Mem:     m.MemPercent,
+		NetRecv: m.NetRecvRate,
+		NetSent: m.NetSentRate,
+
+		CPUModel:       m.CPUModel,
+		CPUCores:       m.CPUCores,
+		MemTotal:       m.MemTotal,
+		MemInUse:       m.MemInUse,
+		MemCommitted:   m.MemCommitted,
+		MemCommitLimit: m.MemCommitLimit,
+		MemCached:      m.MemCached,
+		MemInstalled:   m.MemInstalled,
+		SystemDrive:    m.DiskPath,
+	}
+	for _, d := range m.Disks {
+		s.DiskRead += d.ReadRate
+		s.DiskWrite += d.WriteRate
+		s.Disks = append(s.Disks, upload.Disk{Mount: d.Mount, Total: d.Total, Used: d.Used})
+	}
+	return s
+}
+
+// pushMetrics samples and queues a batch, then sends everything queued. A
+// run cut short by ctx queues the samples taken so far.
+func pushMetrics(ctx context.Context, interval time.Duration, count int) error {
+	settings, err := upload.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if !settings.Configured() {
+		return upload.ErrNotConfigured
+	}
+
+	batch := upload.MetricBatch{IntervalSeconds: interval.Seconds()}
+	for i := 0; i < count; i++ {
+		m, err := sampleOnce(ctx, interval)
+		if err != nil {
+			break
+		}
+		batch.Samples = append(batch.Samples, newSample(m))
+	}
+	if len(batch.Samples) > 0 {
+		if err := upload.Queue(upload.KindMetrics, batch); err != nil {
+			return err
+		}
+	}
+	sent, err := upload.Flush(settings)
+	if err != nil {
+		return fmt.Errorf("%v; %d batches wait to be sent", err, upload.Pending())
+	}
+	fmt.Fprintf(os.Stderr, "Pushed %d batches to %s\n", sent, settings.URL)
+	if err := ctx.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: sampling %s after %d samples\n", headless.Reason(err), len(batch.Samples))
+		return err
+	}
+	return nil
+}
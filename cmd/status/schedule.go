@@ -0,0 +1,120 @@
+//go:build windows
+
+package main
+
+import (
+	"time"
+	"unsafe"
+
+	"github.com/winmole/winmole/pkg/metrics"
+	"golang.org/x/sys/windows"
+)
+
+// Collection modes for collectorConfig.Mode.
+const (
+	// modeAlways samples on every tick.
+	modeAlways = "always"
+	// modeSlowWhenIdle samples every IdleIntervalSeconds while the user is idle.
+	modeSlowWhenIdle = "slow-when-idle"
+	// modeIdleOnly samples only while the user is idle, to catch background
+	// activity that hides behind interactive use.
+	modeIdleOnly = "idle-only"
+)
+
+// collectorConfig tunes how one collector reacts to user presence, and how
+// long it may take.
+type collectorConfig struct {
+	Mode                string `json:"mode"`
+	IdleIntervalSeconds int    `json:"idleIntervalSeconds"`
+
+	// TimeoutSeconds overrides how long one call may take before its last
+	// values are shown as stale.
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+// defaultIdleInterval applies to slow-when-idle collectors without an interval.
+const defaultIdleInterval = 10 * time.Second
+
+// schedule decides which collectors are due on each tick.
+type schedule struct {
+	idleAfter time.Duration
+	policies  map[string]collectorConfig
+	lastRun   map[string]time.Time
+
+	// Presence as of the last call to due
+	idle    bool
+	idleFor time.Duration
+}
+
+func newSchedule(cfg statusConfig) *schedule {
+	return &schedule{
+		idleAfter: time.Duration(cfg.IdleAfterSeconds) * time.Second,
+		policies:  cfg.Collectors,
+		lastRun:   make(map[string]time.Time),
+	}
+}
+
+// due returns the collectors to run at now and records them as run.
+func (s *schedule) due(now time.Time) []metrics.Collector {
+	s.idleFor = 0
+	if d, err := userIdleTime(); err == nil {
+		s.idleFor = d
+	}
+	s.idle = s.idleAfter > 0 && s.idleFor >= s.idleAfter
+
+	var list []metrics.Collector
+	for _, c := range metrics.All() {
+		if !s.shouldRun(c.Name(), now) {
+			continue
+		}
+		s.lastRun[c.Name()] = now
+		list = append(list, c)
+	}
+	return list
+}
+
+func (s *schedule) shouldRun(name string, now time.Time) bool {
+	policy := s.policies[name]
+	last, ran := s.lastRun[name]
+
+	switch policy.Mode {
+	case modeSlowWhenIdle:
+		if !s.idle || !ran {
+			return true
+		}
+		interval := time.Duration(policy.IdleIntervalSeconds) * time.Second
+		if interval <= 0 {
+			interval = defaultIdleInterval
+		}
+		return now.Sub(last) >= interval
+	case modeIdleOnly:
+		// Take one sample up front so the dashboard has something to show.
+		return s.idle || !ran
+	default:
+		return true
+	}
+}
+
+var (
+	user32               = windows.NewLazySystemDLL("user32.dll")
+	procGetLastInputInfo = user32.NewProc("GetLastInputInfo")
+)
+
+// lastInputInfo mirrors the Win32 LASTINPUTINFO structure.
+type lastInputInfo struct {
+	cbSize uint32
+	dwTime uint32
+}
+
+// userIdleTime returns how long ago the user last pressed a key or moved
+// the mouse in this session.
+func userIdleTime() (time.Duration, error) {
+	info := lastInputInfo{cbSize: uint32(unsafe.Sizeof(lastInputInfo{}))}
+	r, _, err := procGetLastInputInfo.Call(uintptr(unsafe.Pointer(&info)))
+	if r == 0 {
+		return 0, err
+	}
+	// dwTime is a 32-bit tick count; unsigned subtraction handles wraparound.
+	elapsed := uint32(windows.DurationSinceBoot().Milliseconds()) - info.dwTime
+	return time.Duration(elapsed) * time.Millisecond, nil
+}
@@ -0,0 +1,187 @@
+//go:build windows
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/internal/elevate"
+	"github.com/winmole/winmole/internal/smb"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// S on the Network tab shows what this machine shares: its shared
+// folders, the clients connected to them and the files they hold open.
+// ↑/↓ select an open file and c closes it, after a y/n, which releases a
+// lock that is holding up maintenance. Reading sessions and open files
+// and closing files need admin rights, so it goes through the helper.
+
+// sharesView is the file-server view of the Network tab.
+type sharesView struct {
+	show     bool
+	loading  bool
+	status   smb.Status
+	err      error
+	selected int           // open file
+	confirm  *smb.OpenFile // file awaiting y/n before closing
+}
+
+type sharesMsg struct {
+	status smb.Status
+	err    error
+}
+
+type closeFileMsg struct {
+	file smb.OpenFile
+	err  error
+}
+
+func sharesCmd() tea.Cmd {
+	return func() tea.Msg {
+		out, err := elevate.RunAction(elevate.OpSMBStatus, nil)
+		if err != nil {
+			return sharesMsg{err: err}
+		}
+		var status smb.Status
+		if err := json.Unmarshal([]byte(out), &status); err != nil {
+			return sharesMsg{err: fmt.Errorf("decode file server status: %w", err)}
+		}
+		return sharesMsg{status: status}
+	}
+}
+
+func closeFileCmd(f smb.OpenFile) tea.Cmd {
+	return func() tea.Msg {
+		_, err := elevate.RunAction(elevate.OpCloseFile, elevate.FileArgs{ID: f.ID})
+		return closeFileMsg{file: f, err: err}
+	}
+}
+
+// handleSharesKey handles the Network tab's keys while the file-server
+// view is shown.
+func (m model) handleSharesKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := &m.shares
+	if v.confirm != nil {
+		f := *v.confirm
+		v.confirm = nil
+		if msg.String() != "y" && msg.String() != "Y" {
+			m.notice = "Cancelled"
+			return m, nil
+		}
+		m.notice = "Closing " + f.Path + "..."
+		return m, closeFileCmd(f)
+	}
+
+	switch msg.String() {
+	case "S":
+		m.shares = sharesView{}
+	case "r":
+		if !v.loading {
+			v.loading = true
+			return m, sharesCmd()
+		}
+	case "up", "k":
+		v.selected = max(v.selected-1, 0)
+	case "down", "j":
+		v.selected = min(v.selected+1, max(len(v.status.Files)-1, 0))
+	case "c":
+		if v.selected < len(v.status.Files) {
+			f := v.status.Files[v.selected]
+			v.confirm = &f
+		}
+	}
+	return m, nil
+}
+
+// applyShares takes a new reading, keeping the same file selected.
+func (m model) applyShares(msg sharesMsg) model {
+	v := &m.shares
+	v.loading = false
+	v.err = msg.err
+	if msg.err != nil {
+		return m
+	}
+	var selectedID uint32
+	if v.selected < len(v.status.Files) {
+		selectedID = v.status.Files[v.selected].ID
+	}
+	v.status = msg.status
+	v.selected = min(v.selected, max(len(v.status.Files)-1, 0))
+	for i, f := range v.status.Files {
+		if f.ID == selectedID {
+			v.selected = i
+		}
+	}
+	return m
+}
+
+func (m model) renderShares() string {
+	v := m.shares
+	var b strings.Builder
+	switch {
+	case v.err != nil:
+		b.WriteString(warnStyle.Render(fmt.Sprintf("  Cannot read the file server: %v", v.err)))
+		return b.String()
+	case v.loading && v.status.Shares == nil:
+		b.WriteString(labelStyle.Render("  Reading shares, sessions and open files (needs admin rights)..."))
+		return b.String()
+	}
+
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  %-20s %-8s %-40s %6s", "Share", "Type", "Path", "Users")))
+	b.WriteString("\n")
+	for _, s := range v.status.Shares {
+		line := fmt.Sprintf("  %-20s %-8s %-40s %6d", humanize.Truncate(s.Name, 20), s.Kind, humanize.Truncate(s.Path, 40), s.Users)
+		if s.Special {
+			b.WriteString(labelStyle.Render(line))
+		} else {
+			b.WriteString(line)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	if len(v.status.Sessions) == 0 {
+		b.WriteString(labelStyle.Render("  No clients connected"))
+		b.WriteString("\n")
+	} else {
+		b.WriteString(labelStyle.Render(fmt.Sprintf("  %-24s %-24s %6s %12s %12s", "Client", "User", "Opens", "Connected", "Idle")))
+		b.WriteString("\n")
+		for _, s := range v.status.Sessions {
+			b.WriteString(fmt.Sprintf("  %-24s %-24s %6d %12s %12s\n", humanize.Truncate(s.Client, 24), humanize.Truncate(s.User, 24),
+				s.Opens, humanize.Duration(s.Time), humanize.Duration(s.Idle)))
+		}
+	}
+
+	b.WriteString("\n")
+	if len(v.status.Files) == 0 {
+		b.WriteString(labelStyle.Render("  No files open"))
+		return b.String()
+	}
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  %-16s %-6s %5s  %s", "User", "Mode", "Locks", "File")))
+	b.WriteString("\n")
+	height := max(m.height-18-len(v.status.Shares)-len(v.status.Sessions), 3)
+	offset := max(v.selected-height+1, 0)
+	for i := offset; i < min(offset+height, len(v.status.Files)); i++ {
+		f := v.status.Files[i]
+		mode := "read"
+		if f.Write {
+			mode = "write"
+		}
+		line := fmt.Sprintf("  %-16s %-6s %5d  %s", humanize.Truncate(f.User, 16), mode, f.Locks, f.Path)
+		if i == v.selected {
+			b.WriteString(activeTabStyle.Render(line))
+		} else {
+			b.WriteString(line)
+		}
+		b.WriteString("\n")
+	}
+	if v.confirm != nil {
+		b.WriteString("\n")
+		b.WriteString(warnStyle.Render(fmt.Sprintf("Close %s for %s? Unsaved changes on their side are lost. ", v.confirm.Path, v.confirm.User)))
+		b.WriteString(valueStyle.Render("y") + labelStyle.Render(" close • ") + valueStyle.Render("n") + labelStyle.Render(" cancel"))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
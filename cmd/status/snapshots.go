@@ -0,0 +1,229 @@
+//go:build windows
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/winmole/winmole/internal/config"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// snapshotFile stores automatic process snapshots in the cache directory so
+// they survive restarts.
+const snapshotFile = "process-snapshots.json"
+
+// snapshotConfig controls when process snapshots are captured. A zero
+// threshold disables that trigger.
+type snapshotConfig struct {
+	CPUPercent float64 `json:"cpuPercent"`
+	MemPercent float64 `json:"memPercent"`
+	Seconds    int     `json:"seconds"`
+	Top        int     `json:"top"`
+	Keep       int     `json:"keep"`
+}
+
+func defaultSnapshotConfig() snapshotConfig {
+	return snapshotConfig{
+		CPUPercent: 90,
+		MemPercent: 90,
+		Seconds:    10,
+		Top:        10,
+		Keep:       50,
+	}
+}
+
+// processSnapshot records the top processes at the moment a threshold fired.
+type processSnapshot struct {
+	At        time.Time         `json:"at"`
+	Trigger   string            `json:"trigger"`
+	CPU       float64           `json:"cpuPercent"`
+	Mem       float64           `json:"memPercent"`
+	Processes []snapshotProcess `json:"processes"`
+}
+
+type snapshotProcess struct {
+	PID        int32   `json:"pid"`
+	Name       string  `json:"name"`
+	CPUPercent float64 `json:"cpuPercent"`
+	Memory     uint64  `json:"memory"`
+}
+
+// snapshotWatcher tracks how long each threshold has been exceeded and fires
+// once per episode.
+type snapshotWatcher struct {
+	cfg      snapshotConfig
+	cpuSince time.Time
+	memSince time.Time
+	cpuFired bool
+	memFired bool
+	saved    []processSnapshot
+}
+
+func newSnapshotWatcher(cfg snapshotConfig) *snapshotWatcher {
+	return &snapshotWatcher{cfg: cfg}
+}
+
+// observe checks the latest metrics and returns a snapshot when a threshold
+// has been exceeded for the configured duration.
+func (w *snapshotWatcher) observe(metrics Metrics) (*processSnapshot, bool) {
+	hold := time.Duration(w.cfg.Seconds) * time.Second
+	now := metrics.CollectedAt
+
+	trigger := ""
+	if fired := sustained(w.cfg.CPUPercent, metrics.CPUUsage, now, hold, &w.cpuSince, &w.cpuFired); fired {
+		trigger = fmt.Sprintf("CPU ≥ %.0f%% for %s", w.cfg.CPUPercent, hold)
+	}
+	if fired := sustained(w.cfg.MemPercent, metrics.MemPercent, now, hold, &w.memSince, &w.memFired); fired {
+		if trigger != "" {
+			trigger += ", "
+		}
+		trigger += fmt.Sprintf("memory ≥ %.0f%% for %s", w.cfg.MemPercent, hold)
+	}
+	if trigger == "" {
+		return nil, false
+	}
+
+	snap := &processSnapshot{
+		At:      now,
+		Trigger: trigger,
+		CPU:     metrics.CPUUsage,
+		Mem:     metrics.MemPercent,
+	}
+	snap.Processes = topProcesses(metrics.Processes, w.cfg.Top)
+	return snap, true
+}
+
+// sustained reports whether value has stayed at or above threshold for hold,
+// firing only once until the value drops back below the threshold.
+func sustained(threshold, value float64, now time.Time, hold time.Duration, since *time.Time, fired *bool) bool {
+	if threshold <= 0 || value < threshold {
+		*since = time.Time{}
+		*fired = false
+		return false
+	}
+	if since.IsZero() {
+		*since = now
+	}
+	if *fired || now.Sub(*since) < hold {
+		return false
+	}
+	*fired = true
+	return true
+}
+
+// topProcesses keeps the heaviest processes by CPU and by memory, so a
+// memory-triggered snapshot still shows the culprit when it is idle.
+func topProcesses(procs []ProcessInfo, n int) []snapshotProcess {
+	byCPU := sortProcesses(procs, sortByCPU)
+	byMem := sortProcesses(procs, sortByMemory)
+
+	seen := make(map[int32]bool)
+	var out []snapshotProcess
+	add := func(list []ProcessInfo) {
+		for i := 0; i < len(list) && i < n; i++ {
+			p := list[i]
+			if seen[p.PID] {
+				continue
+			}
+			seen[p.PID] = true
+			out = append(out, snapshotProcess{PID: p.PID, Name: p.Name, CPUPercent: p.CPUPercent, Memory: p.Memory})
+		}
+	}
+	add(byCPU)
+	add(byMem)
+	return out
+}
+
+func snapshotPath() string {
+	return filepath.Join(config.CacheDir(), snapshotFile)
+}
+
+// loadSnapshots reads previously captured snapshots, oldest first.
+func loadSnapshots() ([]processSnapshot, error) {
+	data, err := os.ReadFile(snapshotPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read snapshots: %w", err)
+	}
+	var snaps []processSnapshot
+	if err := json.Unmarshal(data, &snaps); err != nil {
+		return nil, fmt.Errorf("parse snapshots: %w", err)
+	}
+	return snaps, nil
+}
+
+// record appends a snapshot and persists the newest cfg.Keep entries.
+func (w *snapshotWatcher) record(snap processSnapshot) error {
+	w.saved = append(w.saved, snap)
+	if keep := w.cfg.Keep; keep > 0 && len(w.saved) > keep {
+		w.saved = w.saved[len(w.saved)-keep:]
+	}
+
+	data, err := json.MarshalIndent(w.saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode snapshots: %w", err)
+	}
+	if err := os.MkdirAll(config.CacheDir(), 0o755); err != nil {
+		return fmt.Errorf("create cache directory: %w", err)
+	}
+	return os.WriteFile(snapshotPath(), data, 0o644)
+}
+
+func (m model) renderSnapshots() string {
+	snaps := m.snapshots.saved
+	if len(snaps) == 0 {
+		return labelStyle.Render(fmt.Sprintf("  No snapshots yet. One is captured when CPU or memory stays above %.0f%%/%.0f%% for %ds.",
+			m.snapshots.cfg.CPUPercent, m.snapshots.cfg.MemPercent, m.snapshots.cfg.Seconds))
+	}
+
+	selected := m.snapSelected
+	if selected >= len(snaps) {
+		selected = len(snaps) - 1
+	}
+
+	var b strings.Builder
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  %d snapshots (newest first)", len(snaps))))
+	b.WriteString("\n\n")
+
+	// Newest first; the selected one is expanded below the list.
+	const listRows = 5
+	start := selected - listRows + 1
+	if start < 0 {
+		start = 0
+	}
+	end := start + listRows
+	if end > len(snaps) {
+		end = len(snaps)
+	}
+	for i := start; i < end; i++ {
+		snap := snaps[len(snaps)-1-i]
+		line := fmt.Sprintf("  %s  %s", humanize.Format(snap.At, "2006-01-02 15:04:05"), snap.Trigger)
+		if i == selected {
+			b.WriteString(activeTabStyle.Render(line))
+		} else {
+			b.WriteString(line)
+		}
+		b.WriteString("\n")
+	}
+
+	snap := snaps[len(snaps)-1-selected]
+	b.WriteString("\n")
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  CPU %.1f%% • memory %.1f%%", snap.CPU, snap.Mem)))
+	b.WriteString("\n")
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  %7s  %-32s %7s %12s", "PID", "Name", "CPU", "Memory")))
+	b.WriteString("\n")
+	for _, p := range snap.Processes {
+		b.WriteString(fmt.Sprintf("  %7d  %-32s %6.1f%% %12s\n",
+			p.PID, humanize.Truncate(p.Name, 32), p.CPUPercent, humanize.Bytes(p.Memory)))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
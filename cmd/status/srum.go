@@ -0,0 +1,361 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/winmole/winmole/internal/elevate"
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// SRUM (System Resource Usage Monitor) keeps about a month of hourly
+// per-app usage in SRUDB.dat, whether or not winmole was running.
+const (
+	srumAppResourceTable = "{D10CA2FE-6FCF-4F6D-848E-B2E99266FA89}"
+	srumNetworkTable     = "{973F5D5C-1D90-4944-BE8E-24B94231A174}"
+	srumIDMapTable       = "SruDbIdMapTable"
+)
+
+// appHistoryWindows are the report windows cycled with 'w' on the Apps tab.
+var appHistoryWindows = []struct {
+	label string
+	days  int
+}{
+	{"today", 1},
+	{"last 7 days", 7},
+	{"last 30 days", 30},
+	{"everything recorded", 0},
+}
+
+// appHistorySort selects the Apps tab ordering.
+type appHistorySort int
+
+const (
+	appSortCPU appHistorySort = iota
+	appSortNetwork
+	appSortDisk
+	appSortCount
+)
+
+var appHistorySortNames = [appSortCount]string{"CPU", "network", "disk"}
+
+// appUsage is what SRUM recorded for one app in one day.
+type appUsage struct {
+	App       string
+	Day       time.Time
+	CPUCycles uint64
+	DiskRead  uint64
+	DiskWrite uint64
+	NetSent   uint64
+	NetRecv   uint64
+}
+
+func (u *appUsage) add(o appUsage) {
+	u.CPUCycles += o.CPUCycles
+	u.DiskRead += o.DiskRead
+	u.DiskWrite += o.DiskWrite
+	u.NetSent += o.NetSent
+	u.NetRecv += o.NetRecv
+}
+
+// appHistoryView is the state of the Apps tab, loaded on demand.
+type appHistoryView struct {
+	days     []appUsage
+	oldest   time.Time
+	mhz      float64
+	loadedAt time.Time
+	err      error
+	loading  bool
+	window   int
+	sortBy   appHistorySort
+	offset   int
+}
+
+type appHistoryMsg struct {
+	days   []appUsage
+	oldest time.Time
+	mhz    float64
+	err    error
+}
+
+func (v appHistoryView) needsLoad() bool {
+	return !v.loading && v.loadedAt.IsZero() && v.err == nil
+}
+
+// loadAppHistory imports SRUDB.dat in the background.
+func loadAppHistory() tea.Cmd {
+	return func() tea.Msg {
+		days, oldest, err := readSRUM()
+		msg := appHistoryMsg{days: days, oldest: oldest, err: err}
+		if info, err := cpu.Info(); err == nil && len(info) > 0 {
+			msg.mhz = info[0].Mhz
+		}
+		return msg
+	}
+}
+
+// readSRUM reads a shadow copy of the live database (it is held open by the
+// Diagnostic Policy Service and needs admin rights, so the elevated helper
+// makes the copy) and aggregates it per app and day.
+func readSRUM() ([]appUsage, time.Time, error) {
+	dst, err := elevate.RunFileOp(elevate.OpCopySRUM)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("copy SRUDB.dat: %w", err)
+	}
+	defer os.Remove(dst)
+
+	// The ESE engine keeps its checkpoint and temp files here.
+	dir, err := os.MkdirTemp("", "winmole-srum")
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := openESE(dst, dir)
+	var je jetError
+	if errors.As(err, &je) && je.code == jetErrDatabaseDirty {
+		// The copy is taken from a running database; repair it rather
+		// than replaying logs we do not have.
+		if out, err := exec.Command("esentutl", "/p", dst, "/o").CombinedOutput(); err != nil {
+			return nil, time.Time{}, fmt.Errorf("repair SRUDB.dat copy: %v: %s", err, strings.TrimSpace(string(out)))
+		}
+		db, err = openESE(dst, dir)
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer db.Close()
+
+	names, err := readSRUMNames(db)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	byDay := make(map[string]*appUsage)
+	var oldest time.Time
+	record := func(t *eseTable, fill func(u *appUsage)) {
+		at := oleTime(t.float64("TimeStamp"))
+		if at.IsZero() {
+			return
+		}
+		if oldest.IsZero() || at.Before(oldest) {
+			oldest = at
+		}
+		id := int32(t.uint64("AppId"))
+		name, ok := names[id]
+		if !ok {
+			name = fmt.Sprintf("app #%d", id)
+		}
+		y, m, d := at.Local().Date()
+		day := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+
+		key := name + "|" + day.Format("2006-01-02")
+		u, ok := byDay[key]
+		if !ok {
+			u = &appUsage{App: name, Day: day}
+			byDay[key] = u
+		}
+		fill(u)
+	}
+
+	resources, err := db.openTable(srumAppResourceTable, "TimeStamp", "AppId",
+		"ForegroundCycleTime", "BackgroundCycleTime",
+		"ForegroundBytesRead", "ForegroundBytesWritten",
+		"BackgroundBytesRead", "BackgroundBytesWritten")
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("app resource table: %w", err)
+	}
+	err = resources.each(func() bool {
+		record(resources, func(u *appUsage) {
+			u.CPUCycles += resources.uint64("ForegroundCycleTime") + resources.uint64("BackgroundCycleTime")
+			u.DiskRead += resources.uint64("ForegroundBytesRead") + resources.uint64("BackgroundBytesRead")
+			u.DiskWrite += resources.uint64("ForegroundBytesWritten") + resources.uint64("BackgroundBytesWritten")
+		})
+		return true
+	})
+	resources.Close()
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("app resource table: %w", err)
+	}
+
+	network, err := db.openTable(srumNetworkTable, "TimeStamp", "AppId", "BytesSent", "BytesRecvd")
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("network usage table: %w", err)
+	}
+	err = network.each(func() bool {
+		record(network, func(u *appUsage) {
+			u.NetSent += network.uint64("BytesSent")
+			u.NetRecv += network.uint64("BytesRecvd")
+		})
+		return true
+	})
+	network.Close()
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("network usage table: %w", err)
+	}
+
+	days := make([]appUsage, 0, len(byDay))
+	for _, u := range byDay {
+		days = append(days, *u)
+	}
+	return days, oldest, nil
+}
+
+// readSRUMNames maps SRUM ids to app names. Apps are stored as UTF-16
+// image paths or package names; SIDs and other binary ids are skipped.
+func readSRUMNames(db *eseDB) (map[int32]string, error) {
+	t, err := db.openTable(srumIDMapTable, "IdType", "IdIndex", "IdBlob")
+	if err != nil {
+		return nil, fmt.Errorf("id map: %w", err)
+	}
+	defer t.Close()
+
+	names := make(map[int32]string)
+	err = t.each(func() bool {
+		// 0 = app, 1 = service, 2 = app by package; 3 = user SID
+		if t.uint64("IdType") == 3 {
+			return true
+		}
+		blob := t.raw("IdBlob")
+		if len(blob) < 2 {
+			return true
+		}
+		u := make([]uint16, len(blob)/2)
+		for i := range u {
+			u[i] = uint16(blob[2*i]) | uint16(blob[2*i+1])<<8
+		}
+		name := strings.TrimRight(string(utf16.Decode(u)), "\x00")
+		names[int32(t.uint64("IdIndex"))] = appName(name)
+		return true
+	})
+	return names, err
+}
+
+// oleTime converts an OLE automation date (days since 1899-12-30 UTC).
+func oleTime(days float64) time.Time {
+	if days <= 0 {
+		return time.Time{}
+	}
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	return epoch.Add(time.Duration(days * 24 * float64(time.Hour)))
+}
+
+// report sums the daily records per app since the window start.
+func (v appHistoryView) report(now time.Time) []appUsage {
+	window := appHistoryWindows[v.window]
+	var since time.Time
+	if window.days > 0 {
+		y, m, d := now.AddDate(0, 0, -window.days+1).Date()
+		since = time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	}
+
+	byApp := make(map[string]*appUsage)
+	for _, day := range v.days {
+		if day.Day.Before(since) {
+			continue
+		}
+		u, ok := byApp[day.App]
+		if !ok {
+			u = &appUsage{App: day.App}
+			byApp[day.App] = u
+		}
+		u.add(day)
+	}
+
+	list := make([]appUsage, 0, len(byApp))
+	for _, u := range byApp {
+		list = append(list, *u)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		switch v.sortBy {
+		case appSortNetwork:
+			return a.NetSent+a.NetRecv > b.NetSent+b.NetRecv
+		case appSortDisk:
+			return a.DiskRead+a.DiskWrite > b.DiskRead+b.DiskWrite
+		}
+		return a.CPUCycles > b.CPUCycles
+	})
+	return list
+}
+
+func (m model) handleAppHistoryKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if m.apps.offset > 0 {
+			m.apps.offset--
+		}
+	case "down", "j":
+		if m.apps.offset < len(m.apps.report(time.Now()))-1 {
+			m.apps.offset++
+		}
+	case "w":
+		m.apps.window = (m.apps.window + 1) % len(appHistoryWindows)
+		m.apps.offset = 0
+	case "s":
+		m.apps.sortBy = (m.apps.sortBy + 1) % appSortCount
+		m.apps.offset = 0
+	case "r":
+		if !m.apps.loading {
+			m.apps.loading = true
+			m.apps.err = nil
+			return m, loadAppHistory()
+		}
+	}
+	return m, nil
+}
+
+func (m model) renderAppHistory() string {
+	switch {
+	case m.apps.loading:
+		return labelStyle.Render("  Importing SRUM usage history (shadow copy of SRUDB.dat)...")
+	case m.apps.err != nil:
+		return warnStyle.Render(fmt.Sprintf("  App history unavailable: %v", m.apps.err))
+	}
+
+	list := m.apps.report(time.Now())
+	window := appHistoryWindows[m.apps.window]
+
+	var b strings.Builder
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  %d apps • %s • sorted by %s • recorded since %s",
+		len(list), window.label, appHistorySortNames[m.apps.sortBy], m.apps.oldest.Local().Format("2006-01-02"))))
+	b.WriteString("\n\n")
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  %-32s %10s %12s %12s %12s %12s",
+		"App", "CPU time", "Disk read", "Disk write", "Received", "Sent")))
+	b.WriteString("\n")
+
+	rows := m.contentHeight() - 3
+	start := m.apps.offset
+	if start > len(list) {
+		start = len(list)
+	}
+	end := start + rows
+	if end > len(list) {
+		end = len(list)
+	}
+	for _, u := range list[start:end] {
+		cpuTime := "-"
+		if m.apps.mhz > 0 {
+			// SRUM counts cycles; convert at the nominal clock speed.
+			cpuTime = formatCPUTime(float64(u.CPUCycles) / (m.apps.mhz * 1e6))
+		}
+		b.WriteString(fmt.Sprintf("  %-32s %10s %12s %12s %12s %12s\n",
+			humanize.Truncate(u.App, 32),
+			cpuTime,
+			humanize.Bytes(u.DiskRead),
+			humanize.Bytes(u.DiskWrite),
+			humanize.Bytes(u.NetRecv),
+			humanize.Bytes(u.NetSent)))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,141 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/winmole/winmole/internal/scanprogress"
+	"github.com/winmole/winmole/internal/websocket"
+)
+
+// /api/stream is a WebSocket that pushes what the page would otherwise
+// poll for: every sample of the cards as it is taken, and the progress of
+// analyze scans running on the machine (see internal/scanprogress), so a
+// page or a script can follow them live. Each message is a JSON object:
+//
+//	{"type": "metrics",  "data": <as /api/metrics>}
+//	{"type": "scan",     "data": {"root", "pid", "started", "updated", "files", "dirs", "bytes"}}
+//	{"type": "scanDone", "data": <the scan's last progress>}
+//
+// A client that reads too slowly misses messages rather than holding the
+// others up; the next sample has the figures anyway.
+
+// streamBuffer is how many messages may wait for a slow client.
+const streamBuffer = 16
+
+// webEvent is one message on /api/stream.
+type webEvent struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// broadcast offers ev to every client.
+func (s *webServer) broadcast(ev webEvent) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for ch := range s.clients {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// watchScans reports the scans analyze has running, every time their
+// progress files change, until ctx ends.
+func (s *webServer) watchScans(ctx context.Context) {
+	seen := map[string]scanprogress.Scan{}
+	for {
+		now := map[string]scanprogress.Scan{}
+		running := scanprogress.Running()
+		for i, sc := range running {
+			key := strconv.Itoa(sc.PID) + "|" + strings.ToLower(sc.Root)
+			sc.Root = s.redactor.String(sc.Root)
+			running[i] = sc
+			now[key] = sc
+			if prev, ok := seen[key]; !ok || !prev.Updated.Equal(sc.Updated) {
+				s.broadcast(webEvent{Type: "scan", Data: sc})
+			}
+		}
+		for key, sc := range seen {
+			if _, ok := now[key]; !ok {
+				s.broadcast(webEvent{Type: "scanDone", Data: sc})
+			}
+		}
+		seen = now
+		s.mu.Lock()
+		s.running = running
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(scanprogress.Every):
+		}
+	}
+}
+
+// stream serves /api/stream until the client leaves or ctx ends.
+func (s *webServer) stream(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	// Browsers let any page open a WebSocket, sending the credentials they
+	// saved for this server with it, so only this server's pages may. The
+	// token, when set, is checked by auth on top of that.
+	if !sameOrigin(r) {
+		http.Error(w, "the stream is only served to this page", http.StatusForbidden)
+		return
+	}
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	events := make(chan webEvent, streamBuffer)
+	s.mu.Lock()
+	s.clients[events] = struct{}{}
+	latest, running := s.latest, s.running
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, events)
+		s.mu.Unlock()
+	}()
+
+	// A new client starts with what the others already have.
+	if latest != nil {
+		conn.WriteJSON(webEvent{Type: "metrics", Data: newWebCards(*latest, s.redactor)})
+	}
+	for _, sc := range running {
+		conn.WriteJSON(webEvent{Type: "scan", Data: sc})
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-conn.Done():
+			return
+		case ev := <-events:
+			if conn.WriteJSON(ev) != nil {
+				return
+			}
+		}
+	}
+}
+
+// sameOrigin reports whether r comes from a page of this server, or from
+// something that is not a browser and sends no Origin. It relies on auth
+// having checked r.Host, which the client chooses, or the token.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
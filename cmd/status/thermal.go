@@ -0,0 +1,190 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/winmole/winmole/pkg/tui"
+)
+
+// t on the History tab sets CPU load against temperature, clock and
+// throttling over the same ten minutes, and groups the samples by load.
+// A healthy machine only slows down when it is worked hard; one that runs
+// hot or drops its clock at modest load usually has clogged vents or a
+// tired fan.
+
+// loadBands group samples by CPU load, in percent.
+var loadBands = []struct {
+	label  string
+	lo, hi float64
+}{
+	{"0-25%", 0, 25},
+	{"25-50%", 25, 50},
+	{"50-75%", 50, 75},
+	{"75-100%", 75, 101},
+}
+
+// bandMinSamples is how many samples a band needs before it is judged.
+const bandMinSamples = 10
+
+// thermalBand sums the samples taken at one load band.
+type thermalBand struct {
+	samples   int
+	temp      float64 // sum of the known temperatures
+	temps     int
+	clock     float64 // sum of the known clocks
+	clocks    int
+	throttled int // samples with the processors slowed
+}
+
+func (b thermalBand) avgTemp() float64 {
+	if b.temps == 0 {
+		return 0
+	}
+	return b.temp / float64(b.temps)
+}
+
+func (b thermalBand) avgClock() float64 {
+	if b.clocks == 0 {
+		return 0
+	}
+	return b.clock / float64(b.clocks)
+}
+
+func thermalBands(samples []sample) []thermalBand {
+	bands := make([]thermalBand, len(loadBands))
+	for _, s := range samples {
+		for i, lb := range loadBands {
+			if s.CPU < lb.lo || s.CPU >= lb.hi {
+				continue
+			}
+			b := &bands[i]
+			b.samples++
+			if s.Temp > 0 {
+				b.temp += s.Temp
+				b.temps++
+			}
+			if s.Clock > 0 {
+				b.clock += s.Clock
+				b.clocks++
+			}
+			if s.Throttle > 0 {
+				b.throttled++
+			}
+		}
+	}
+	return bands
+}
+
+// thermalVerdict reads the bands: throttling or heat below half load
+// points at the cooling.
+func thermalVerdict(bands []thermalBand) (string, bool) {
+	for i, b := range bands[:2] {
+		if b.samples < bandMinSamples {
+			continue
+		}
+		if share := float64(b.throttled) / float64(b.samples); share >= 0.1 {
+			return fmt.Sprintf("Throttling at %s load (%.0f%% of the time): the cooling cannot keep up with modest work. "+
+				"Dust in the vents or a failing fan is the usual cause.", loadBands[i].label, share*100), true
+		}
+		if t := b.avgTemp(); t >= 85 {
+			return fmt.Sprintf("Running at %.0f °C on average at %s load: the cooling is struggling with modest work. "+
+				"Check the vents and fan.", t, loadBands[i].label), true
+		}
+	}
+	for i, b := range bands[2:] {
+		if b.samples >= bandMinSamples && b.throttled > 0 {
+			return fmt.Sprintf("Throttling only at %s load, which is normal for a hard-working thin laptop.", loadBands[i+2].label), false
+		}
+	}
+	return "No throttling so far.", false
+}
+
+func (m model) renderThermal(samples []sample) string {
+	width := max(m.width-40, 20)
+	window := samples
+	if len(window) > width {
+		window = window[len(window)-width:]
+	}
+
+	var peakClock float64
+	for _, s := range window {
+		peakClock = max(peakClock, s.Clock)
+	}
+
+	var b strings.Builder
+	span := samples[len(samples)-1].At.Sub(samples[0].At).Round(time.Second)
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  Load, temperature and clock over the last %s", span)))
+	b.WriteString("\n\n")
+
+	rows := []struct {
+		label   string
+		max     float64
+		field   func(sample) float64
+		display func(float64) string
+	}{
+		{"CPU", 100, func(s sample) float64 { return s.CPU }, formatPercent},
+		{"Temp", 100, func(s sample) float64 { return s.Temp }, formatCelsius},
+		{"Clock", peakClock, func(s sample) float64 { return s.Clock }, formatClock},
+		{"Throttle", 100, func(s sample) float64 { return s.Throttle }, formatPercent},
+	}
+	for _, row := range rows {
+		values := series(window, width, row.field)
+		b.WriteString(labelStyle.Render(fmt.Sprintf("  %-10s ", row.label)))
+		b.WriteString(barLowStyle.Render(tui.Spark(values, row.max)))
+		b.WriteString(" " + row.display(values[len(values)-1]))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	bands := thermalBands(samples)
+	b.WriteString(labelStyle.Render(fmt.Sprintf("  %-10s %8s %8s %9s %10s", "CPU load", "samples", "temp", "clock", "throttled")))
+	b.WriteString("\n")
+	for i, band := range bands {
+		throttled := "-"
+		if band.samples > 0 {
+			throttled = fmt.Sprintf("%.0f%%", float64(band.throttled)/float64(band.samples)*100)
+		}
+		b.WriteString(fmt.Sprintf("  %-10s %8d %8s %9s %10s\n", loadBands[i].label, band.samples,
+			formatCelsius(band.avgTemp()), formatClock(band.avgClock()), throttled))
+	}
+	b.WriteString("\n")
+
+	if peakClock == 0 && !hasTemperature(samples) {
+		b.WriteString(labelStyle.Render("  This PC reports neither temperatures nor clock speeds to Windows."))
+		return b.String()
+	}
+	verdict, bad := thermalVerdict(bands)
+	style := labelStyle
+	if bad {
+		style = warnStyle
+	}
+	b.WriteString(style.Render("  " + verdict))
+	return b.String()
+}
+
+func hasTemperature(samples []sample) bool {
+	for _, s := range samples {
+		if s.Temp > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func formatCelsius(v float64) string {
+	if v <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f °C", v)
+}
+
+func formatClock(v float64) string {
+	if v <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f GHz", v/1000)
+}
@@ -0,0 +1,418 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/winmole/winmole/internal/config"
+	"github.com/winmole/winmole/internal/redact"
+	"github.com/winmole/winmole/internal/scanprogress"
+	"github.com/winmole/winmole/internal/upload"
+	"github.com/winmole/winmole/pkg/metrics"
+)
+
+// --web serves a small dashboard to a browser: the Overview cards, updated
+// every two seconds, and the newest snapshot of each folder analyze has
+// saved, to browse read-only. The cards and the progress of running scans
+// come over a WebSocket (see stream.go), or by polling where that fails.
+// The page, its script and its style are built into status.exe. It listens
+// on 127.0.0.1 unless told otherwise; on any other address it asks for the
+// server token from config.json as the password, and refuses to start
+// without one. Without a token it only answers requests addressed to the
+// loopback address, so a page on another site cannot rebind its own name
+// to 127.0.0.1 and read the figures. With --redact the computer name, user
+// names and IP addresses are masked, in the paths too.
+
+//go:embed web
+var webFiles embed.FS
+
+// webInterval is how often the page's figures are sampled.
+const webInterval = 2 * time.Second
+
+// webCollectors are the collectors behind the cards.
+var webCollectors = []metrics.Collector{metrics.CPU, metrics.Memory, metrics.Disk, metrics.Network, metrics.Host}
+
+// webCards is /api/metrics: what the Overview cards show.
+type webCards struct {
+	Hostname    string    `json:"hostname"`
+	CollectedAt time.Time `json:"collectedAt"`
+	CPU         struct {
+		Model  string  `json:"model"`
+		Usage  float64 `json:"usage"`
+		Cores  int     `json:"cores"`
+		Method string  `json:"method"`
+	} `json:"cpu"`
+	Memory struct {
+		InUse       uint64  `json:"inUse"`
+		Total       uint64  `json:"total"`
+		Percent     float64 `json:"percent"`
+		Committed   uint64  `json:"committed"`
+		CommitLimit uint64  `json:"commitLimit"`
+		Cached      uint64  `json:"cached"`
+		Reserved    uint64  `json:"reserved"`
+	} `json:"memory"`
+	Disk struct {
+		Path    string  `json:"path"`
+		Used    uint64  `json:"used"`
+		Total   uint64  `json:"total"`
+		Percent float64 `json:"percent"`
+	} `json:"disk"`
+	Network struct {
+		SentRate float64 `json:"sentRate"`
+		RecvRate float64 `json:"recvRate"`
+	} `json:"network"`
+}
+
+func newWebCards(m Metrics, r *redact.Redactor) webCards {
+	var c webCards
+	c.Hostname, c.CollectedAt = r.String(m.Hostname), m.CollectedAt
+	c.CPU.Model, c.CPU.Usage, c.CPU.Cores, c.CPU.Method = m.CPUModel, m.CPUUsage, m.CPUCores, m.CPUMethod
+	c.Memory.InUse, c.Memory.Total, c.Memory.Percent = m.MemInUse, m.MemTotal, m.MemInUsePercent
+	c.Memory.Committed, c.Memory.CommitLimit, c.Memory.Cached = m.MemCommitted, m.MemCommitLimit, m.MemCached
+	if m.MemInstalled > m.MemTotal {
+		c.Memory.Reserved = m.MemInstalled - m.MemTotal
+	}
+	c.Disk.Path, c.Disk.Used, c.Disk.Total, c.Disk.Percent = r.String(m.DiskPath), m.DiskUsed, m.DiskTotal, m.DiskPercent
+	c.Network.SentRate, c.Network.RecvRate = m.NetSentRate, m.NetRecvRate
+	return c
+}
+
+// webScan is one folder in /api/scans.
+type webScan struct {
+	Root      string    `json:"root"`
+	ScannedAt time.Time `json:"scannedAt"`
+	TotalSize int64     `json:"totalSize"`
+	file      string
+}
+
+// webEntry is one file or folder in /api/tree.
+type webEntry struct {
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	Files int64  `json:"files"`
+	IsDir bool   `json:"isDir"`
+	// Open is whether the snapshot has the folder's contents: analyze
+	// only saves the folders that were opened or scanned.
+	Open bool `json:"open"`
+}
+
+// snapshotDoc is the part of analyze's JSON export (and so of its
+// snapshots) the page reads.
+type snapshotDoc struct {
+	Root      string    `json:"root"`
+	TotalSize int64     `json:"totalSize"`
+	ScannedAt time.Time `json:"scannedAt"`
+	Entries   []struct {
+		Path  string `json:"path"`
+		Size  int64  `json:"size"`
+		Files int64  `json:"files"`
+		IsDir bool   `json:"isDir"`
+	} `json:"entries"`
+}
+
+// loadedTree is a snapshot indexed by folder, with the paths as the page
+// sees them.
+type loadedTree struct {
+	file     string
+	modTime  time.Time
+	children map[string][]webEntry // by lower-case parent path
+}
+
+type webServer struct {
+	token    string // "" on 127.0.0.1
+	port     string // the Host a request without a token must be for
+	redactor *redact.Redactor
+
+	mu      sync.RWMutex
+	latest  *Metrics
+	loaded  *loadedTree // the snapshot browsed last
+	running []scanprogress.Scan
+	clients map[chan webEvent]struct{} // on /api/stream
+}
+
+// serveWeb samples the cards and serves the page on addr until ctx ends,
+// masking what r masks.
+func serveWeb(ctx context.Context, addr string, r *redact.Redactor) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("--web %s: %w", addr, err)
+	}
+	s := &webServer{port: port, redactor: r, clients: map[chan webEvent]struct{}{}}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		settings, _ := upload.Load()
+		if settings.Token == "" {
+			return fmt.Errorf("--web on %s needs a token: set \"%s\": {\"token\": ...} in config.json or %s, or listen on 127.0.0.1",
+				addr, upload.Key, upload.TokenEnv)
+		}
+		s.token = settings.Token
+	}
+
+	pages, _ := fs.Sub(webFiles, "web")
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServerFS(pages))
+	mux.HandleFunc("GET /api/metrics", s.cards)
+	mux.HandleFunc("GET /api/scans", s.scans)
+	mux.HandleFunc("GET /api/tree", s.tree)
+	mux.HandleFunc("GET /api/stream", func(w http.ResponseWriter, r *http.Request) { s.stream(ctx, w, r) })
+	srv := &http.Server{Addr: addr, Handler: s.auth(mux), ReadHeaderTimeout: 10 * time.Second}
+
+	go s.sample(ctx)
+	go s.watchScans(ctx)
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+	fmt.Fprintf(os.Stderr, "Serving the dashboard on http://%s/ • Ctrl+C stops\n", addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return ctx.Err()
+}
+
+// auth asks for the token, with any user name, when one is set, and
+// otherwise refuses requests for any host but this one's loopback address.
+func (s *webServer) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token == "" && !s.loopbackHost(r.Host) {
+			http.Error(w, "the dashboard only answers to 127.0.0.1, [::1] and localhost", http.StatusMisdirectedRequest)
+			return
+		}
+		if s.token != "" {
+			_, pass, _ := r.BasicAuth()
+			if subtle.ConstantTimeCompare([]byte(pass), []byte(s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="WinMole", charset="UTF-8"`)
+				http.Error(w, "sign in with the server token as the password", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// loopbackHost reports whether a request's Host names the loopback
+// address and the port the dashboard listens on. Browsers leave out port 80.
+func (s *webServer) loopbackHost(hostport string) bool {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host, port = hostport, "80"
+	}
+	if port != s.port {
+		return false
+	}
+	switch strings.ToLower(host) {
+	case "127.0.0.1", "::1", "[::1]", "localhost":
+		return true
+	}
+	return false
+}
+
+// sample keeps the latest figures, with rates from the one before.
+func (s *webServer) sample(ctx context.Context) {
+	guard := metrics.NewGuard(metrics.DefaultPolicy)
+	var prev *Metrics
+	for {
+		cur := guard.Collect(webCollectors)
+		if prev != nil {
+			metrics.Merge(&cur, prev)
+		}
+		prev = &cur
+		s.mu.Lock()
+		s.latest = &cur
+		s.mu.Unlock()
+		s.broadcast(webEvent{Type: "metrics", Data: newWebCards(cur, s.redactor)})
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(webInterval):
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *webServer) cards(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	latest := s.latest
+	s.mu.RUnlock()
+	if latest == nil {
+		http.Error(w, "no sample yet", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, newWebCards(*latest, s.redactor))
+}
+
+func (s *webServer) scans(w http.ResponseWriter, r *http.Request) {
+	list := latestScans()
+	if list == nil {
+		list = []webScan{}
+	}
+	for i := range list {
+		list[i].Root = s.redactor.String(list[i].Root)
+	}
+	writeJSON(w, list)
+}
+
+// tree lists a folder of the newest snapshot of root. Both are paths as
+// the page has them, masked with --redact.
+func (s *webServer) tree(w http.ResponseWriter, r *http.Request) {
+	root, dir := r.URL.Query().Get("root"), r.URL.Query().Get("path")
+	var scan *webScan
+	for _, sc := range latestScans() {
+		if strings.EqualFold(s.redactor.String(sc.Root), root) {
+			sc.Root = s.redactor.String(sc.Root)
+			scan = &sc
+			break
+		}
+	}
+	if scan == nil {
+		http.Error(w, "no snapshot of "+root, http.StatusNotFound)
+		return
+	}
+	t, err := s.loadTree(scan.file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if dir == "" {
+		dir = scan.Root
+	}
+	entries, ok := t.children[strings.ToLower(filepath.Clean(dir))]
+	if !ok {
+		http.Error(w, "the snapshot has no contents for "+dir, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, struct {
+		Root      string     `json:"root"`
+		Path      string     `json:"path"`
+		ScannedAt time.Time  `json:"scannedAt"`
+		Entries   []webEntry `json:"entries"`
+	}{scan.Root, dir, scan.ScannedAt, entries})
+}
+
+// loadTree reads a snapshot, reusing the one read last when it is the
+// same file. Its paths are masked as the redactor masks them; two folders
+// that only differ in a masked name are listed together.
+func (s *webServer) loadTree(file string) (*loadedTree, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	t := s.loaded
+	s.mu.RUnlock()
+	if t != nil && t.file == file && t.modTime.Equal(info.ModTime()) {
+		return t, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var doc snapshotDoc
+	if err := json.NewDecoder(f).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(file), err)
+	}
+	t = &loadedTree{file: file, modTime: info.ModTime(), children: map[string][]webEntry{}}
+	t.children[strings.ToLower(filepath.Clean(s.redactor.String(doc.Root)))] = nil
+	for _, e := range doc.Entries {
+		path := s.redactor.String(e.Path)
+		parent := strings.ToLower(filepath.Dir(path))
+		t.children[parent] = append(t.children[parent], webEntry{Path: path, Size: e.Size, Files: e.Files, IsDir: e.IsDir})
+	}
+	for parent, entries := range t.children {
+		for i := range entries {
+			if entries[i].IsDir {
+				_, entries[i].Open = t.children[strings.ToLower(entries[i].Path)]
+			}
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Size > entries[j].Size })
+		t.children[parent] = entries
+	}
+	s.mu.Lock()
+	s.loaded = t
+	s.mu.Unlock()
+	return t, nil
+}
+
+// latestScans returns the newest snapshot of each folder, from the
+// snapshots folder analyze saves to.
+func latestScans() []webScan {
+	files, _ := filepath.Glob(filepath.Join(config.CacheDir(), "snapshots", "*.json"))
+	newest := map[string]webScan{}
+	for _, file := range files {
+		s, err := readScanHeader(file)
+		if err != nil {
+			continue
+		}
+		key := strings.ToLower(s.Root)
+		if cur, ok := newest[key]; !ok || s.ScannedAt.After(cur.ScannedAt) {
+			newest[key] = s
+		}
+	}
+	var list []webScan
+	for _, s := range newest {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return strings.ToLower(list[i].Root) < strings.ToLower(list[j].Root) })
+	return list
+}
+
+// readScanHeader reads the fields before a snapshot's entries.
+func readScanHeader(file string) (webScan, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return webScan{}, err
+	}
+	defer f.Close()
+	s := webScan{file: file}
+	dec := json.NewDecoder(f)
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return s, fmt.Errorf("%s: not a snapshot", filepath.Base(file))
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return s, err
+		}
+		switch t {
+		case "root":
+			err = dec.Decode(&s.Root)
+		case "totalSize":
+			err = dec.Decode(&s.TotalSize)
+		case "scannedAt":
+			err = dec.Decode(&s.ScannedAt)
+		default:
+			// The entries come last.
+			if s.Root == "" {
+				return s, fmt.Errorf("%s: not a snapshot", filepath.Base(file))
+			}
+			return s, nil
+		}
+		if err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
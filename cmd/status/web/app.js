@@ -0,0 +1,246 @@
+// The WinMole status page: the Overview cards and the progress of running
+// scans as /api/stream pushes them (or the cards from /api/metrics every
+// two seconds where the socket cannot be opened), and the newest snapshots
+// from /api/scans to browse with /api/tree. Everything is read-only, and
+// names are set as text, never as HTML.
+"use strict";
+
+const units = ["B", "KB", "MB", "GB", "TB", "PB"];
+
+function bytes(n) {
+  let i = 0;
+  n = Math.max(n || 0, 0);
+  while (n >= 1024 && i < units.length - 1) {
+    n /= 1024;
+    i++;
+  }
+  return (i === 0 ? n.toFixed(0) : n.toFixed(1)) + " " + units[i];
+}
+
+function el(tag, props, ...children) {
+  const e = document.createElement(tag);
+  Object.assign(e, props || {});
+  for (const c of children) {
+    e.append(c);
+  }
+  return e;
+}
+
+// bar mirrors the dashboard's: green below 70%, yellow below 90%, red above.
+function bar(percent) {
+  const level = percent >= 90 ? "high" : percent >= 70 ? "med" : "low";
+  const fill = el("span", { className: level });
+  fill.style.width = Math.min(Math.max(percent, 0), 100) + "%";
+  return el("span", { className: "bar" }, fill);
+}
+
+function card(title, subtitle, ...lines) {
+  const c = el("div", { className: "card" }, el("b", {}, title), el("br"), el("span", { className: "label" }, subtitle), el("br"), el("br"));
+  lines.forEach((line, i) => {
+    if (i > 0) {
+      c.append(el("br"));
+    }
+    c.append(...line);
+  });
+  return c;
+}
+
+function usage(percent) {
+  return [el("span", { className: "label" }, "Usage: "), bar(percent), " " + percent.toFixed(1) + "%"];
+}
+
+function label(text) {
+  return [el("span", { className: "label" }, text)];
+}
+
+function renderCards(m) {
+  let cores = "Cores: " + m.cpu.cores;
+  if (m.cpu.method === "utility") {
+    cores += " • processor utility";
+  } else if (m.cpu.method === "time") {
+    cores += " • busy time";
+  }
+  let cached = "Cached: " + bytes(m.memory.cached);
+  if (m.memory.reserved > 0) {
+    cached += " • " + bytes(m.memory.reserved) + " reserved";
+  }
+  document.getElementById("cards").replaceChildren(
+    card("CPU", m.cpu.model, usage(m.cpu.usage), label(cores)),
+    card("Memory", bytes(m.memory.inUse) + " / " + bytes(m.memory.total) + " in use",
+      usage(m.memory.percent),
+      label("Committed: " + bytes(m.memory.committed) + " / " + bytes(m.memory.commitLimit)),
+      label(cached)),
+    card("Disk (" + m.disk.path + ")", bytes(m.disk.used) + " / " + bytes(m.disk.total), usage(m.disk.percent)),
+    card("Network", "Traffic rates",
+      [el("span", { className: "label" }, "↑ Upload:   "), el("b", {}, bytes(m.network.sentRate) + "/s")],
+      [el("span", { className: "label" }, "↓ Download: "), el("b", {}, bytes(m.network.recvRate) + "/s")]),
+  );
+  document.getElementById("host").textContent = m.hostname ? "• " + m.hostname : "";
+  document.getElementById("updated").textContent = "updated " + new Date(m.collectedAt).toLocaleTimeString();
+}
+
+async function getJSON(url) {
+  const resp = await fetch(url, { cache: "no-store" });
+  if (!resp.ok) {
+    throw new Error((await resp.text()).trim() || resp.statusText);
+  }
+  return resp.json();
+}
+
+async function refreshCards() {
+  try {
+    renderCards(await getJSON("api/metrics"));
+  } catch (err) {
+    document.getElementById("updated").textContent = String(err.message);
+  }
+  setTimeout(refreshCards, 2000);
+}
+
+// follow takes the cards and scan progress from /api/stream. A dropped
+// stream is opened again; one that never opens falls back to polling.
+function follow() {
+  if (!("WebSocket" in window)) {
+    refreshCards();
+    return;
+  }
+  const url = new URL("api/stream", location.href);
+  url.protocol = location.protocol === "https:" ? "wss:" : "ws:";
+  const ws = new WebSocket(url);
+  let opened = false;
+  ws.onopen = () => {
+    opened = true;
+  };
+  ws.onmessage = (ev) => {
+    const msg = JSON.parse(ev.data);
+    switch (msg.type) {
+    case "metrics":
+      renderCards(msg.data);
+      break;
+    case "scan":
+      running.set(scanKey(msg.data), msg.data);
+      renderRunning();
+      break;
+    case "scanDone":
+      running.delete(scanKey(msg.data));
+      renderRunning();
+      // It has probably saved a snapshot.
+      loadScans();
+      break;
+    }
+  };
+  ws.onclose = () => {
+    running.clear();
+    renderRunning();
+    if (opened) {
+      document.getElementById("updated").textContent = "connection lost, reconnecting...";
+      setTimeout(follow, 5000);
+    } else {
+      refreshCards();
+    }
+  };
+}
+
+// running are the scans in progress, by scanKey.
+const running = new Map();
+
+function scanKey(s) {
+  return s.pid + "|" + s.root;
+}
+
+function elapsed(since) {
+  const s = Math.max(Math.round((Date.now() - new Date(since)) / 1000), 0);
+  return s < 60 ? s + "s" : Math.floor(s / 60) + "m" + String(s % 60).padStart(2, "0") + "s";
+}
+
+function renderRunning() {
+  const lines = [];
+  for (const s of running.values()) {
+    lines.push(el("p", { className: "running" },
+      "Scanning " + s.root + " • " + s.files.toLocaleString() + " files • " + bytes(s.bytes) + " • " + elapsed(s.started)));
+  }
+  document.getElementById("running").replaceChildren(...lines);
+}
+
+async function loadScans() {
+  const box = document.getElementById("scans");
+  let scans;
+  try {
+    scans = await getJSON("api/scans");
+  } catch (err) {
+    box.replaceChildren(el("p", { className: "dim" }, String(err.message)));
+    return;
+  }
+  if (scans.length === 0) {
+    box.replaceChildren(el("p", { className: "dim" }, "No snapshots yet: press S in winmole analyze, or run winmole analyze schedule."));
+    return;
+  }
+  const table = el("table");
+  for (const s of scans) {
+    const link = el("a", {}, s.root);
+    link.onclick = () => openFolder(s.root, s.root);
+    table.append(el("tr", {},
+      el("td", { className: "size" }, bytes(s.totalSize)),
+      el("td", {}, link),
+      el("td", { className: "dim" }, "scanned " + new Date(s.scannedAt).toLocaleString())));
+  }
+  box.replaceChildren(table);
+}
+
+function crumbs(root, path) {
+  const parts = [];
+  const add = (name, dir) => {
+    const a = el("a", {}, name);
+    a.onclick = () => openFolder(root, dir);
+    parts.push(a, " › ");
+  };
+  add(root, root);
+  if (path.length > root.length) {
+    let dir = root.replace(/\\$/, "");
+    for (const name of path.slice(root.length).split("\\").filter(Boolean)) {
+      dir += "\\" + name;
+      add(name, dir);
+    }
+  }
+  parts.pop();
+  return parts;
+}
+
+async function openFolder(root, path) {
+  let t;
+  try {
+    t = await getJSON("api/tree?root=" + encodeURIComponent(root) + "&path=" + encodeURIComponent(path));
+  } catch (err) {
+    document.getElementById("scanned").textContent = String(err.message);
+    return;
+  }
+  document.getElementById("tree").hidden = false;
+  document.getElementById("crumbs").replaceChildren(...crumbs(t.root, t.path));
+  document.getElementById("scanned").textContent = "• snapshot of " + new Date(t.scannedAt).toLocaleString();
+  const entries = t.entries || [];
+  const largest = entries.length ? Math.max(entries[0].size, 1) : 1;
+  const table = document.getElementById("entries");
+  table.replaceChildren();
+  for (const e of entries) {
+    const name = e.path.slice(e.path.lastIndexOf("\\") + 1);
+    let cell;
+    if (e.isDir && e.open) {
+      cell = el("a", {}, name + "\\");
+      cell.onclick = () => openFolder(t.root, e.path);
+    } else {
+      cell = el("span", {}, e.isDir ? name + "\\" : name);
+    }
+    const fill = el("span");
+    fill.style.width = (e.size / largest * 100) + "%";
+    table.append(el("tr", {},
+      el("td", { className: "size" }, bytes(e.size)),
+      el("td", {}, el("span", { className: "bar" }, fill)),
+      el("td", {}, cell),
+      el("td", { className: "dim" }, e.isDir ? e.files + " files" + (e.open ? "" : " • not opened in the snapshot") : "")));
+  }
+  if (entries.length === 0) {
+    table.append(el("tr", {}, el("td", { className: "dim" }, "(empty)")));
+  }
+}
+
+follow();
+loadScans();
@@ -0,0 +1,64 @@
+//go:build windows
+
+// Command embed shows the pkg/tui components inside another Bubble Tea
+// program: a status strip with a CPU history line above a disk-usage pane.
+//
+//	go run ./examples/embed C:\Users
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/pkg/tui"
+)
+
+type app struct {
+	strip tui.StatusStrip
+	cpu   tui.Sparkline
+	disk  tui.DiskPane
+	last  time.Time // when the last charted sample was taken
+}
+
+func (a app) Init() tea.Cmd {
+	return tea.Batch(a.strip.Init(), a.disk.Init())
+}
+
+func (a app) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok && (key.String() == "q" || key.String() == "ctrl+c") {
+		return a, tea.Quit
+	}
+
+	strip, stripCmd := a.strip.Update(msg)
+	disk, diskCmd := a.disk.Update(msg)
+	a.strip, a.disk = strip.(tui.StatusStrip), disk.(tui.DiskPane)
+
+	// Chart each new sample as it arrives.
+	if m, ok := a.strip.Metrics(); ok && m.CollectedAt.After(a.last) {
+		a.cpu = a.cpu.Push(m.CPUUsage)
+		a.last = m.CollectedAt
+	}
+	return a, tea.Batch(stripCmd, diskCmd)
+}
+
+func (a app) View() string {
+	return a.strip.View() + "\n CPU " + a.cpu.View() + "\n\n" + a.disk.View() + "\n\nq quit"
+}
+
+func main() {
+	path := `C:\`
+	if len(os.Args) > 1 {
+		path = os.Args[1]
+	}
+	a := app{
+		strip: tui.NewStatusStrip(tui.StatusStripCards(tui.CardCPU, tui.CardMemory, tui.CardDisk)),
+		cpu:   tui.NewSparkline(tui.SparklineWidth(60), tui.SparklineMax(100)),
+		disk:  tui.NewDiskPane(path, tui.DiskPaneRows(15)),
+	}
+	if _, err := tea.NewProgram(a, tea.WithAltScreen()).Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
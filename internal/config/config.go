@@ -0,0 +1,304 @@
+// Package config reads and writes the shared WinMole settings file
+// (~\.config\winmole\config.json). Each Go tool owns one top-level section
+// of the file so the tools can evolve their settings independently. The
+// "units" setting is shared: it picks the units every tool shows sizes in.
+//
+// Named profiles live under "profiles" and override individual sections:
+//
+//	{
+//	  "profile": "work",
+//	  "status": { ... },
+//	  "profiles": {
+//	    "work":   { "status": { ... } },
+//	    "server": { "status": { ... }, "analyze": { ... } }
+//	  }
+//	}
+//
+// The active profile is chosen by SetProfile (the --profile flag), then the
+// WINMOLE_PROFILE environment variable, then the "profile" key.
+//
+// In portable mode, signalled by a winmole.portable file in the WinMole
+// folder, settings and cache live in config\ and cache\ next to that file
+// instead of the user profile, so they travel with a USB stick.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/winmole/winmole/pkg/humanize"
+)
+
+// FileName is the name of the settings file inside the config directory.
+const FileName = "config.json"
+
+// Keys of the profile settings in the settings file.
+const (
+	profileKey  = "profile"
+	profilesKey = "profiles"
+)
+
+// UnitsKey is the setting shared by every tool: "binary", "iec" or "si",
+// as humanize.ParseUnits reads it. Profiles can override it like a section.
+const UnitsKey = "units"
+
+// TimesKey is the shared setting for how moments are shown: "absolute" or
+// "relative", as humanize.ParseTimes reads it.
+const TimesKey = "times"
+
+// PortableMarker is the file that switches WinMole into portable mode.
+const PortableMarker = "winmole.portable"
+
+// profileOverride is the profile selected with SetProfile, if any.
+var profileOverride *string
+
+var (
+	portableOnce sync.Once
+	portableRoot string
+)
+
+// PortableRoot returns the folder holding winmole.portable, or "" when not
+// running portable. The marker is looked for next to the executable and in
+// its parent, since the Go tools are built into bin\.
+func PortableRoot() string {
+	portableOnce.Do(func() {
+		exe, err := os.Executable()
+		if err != nil {
+			return
+		}
+		if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+			exe = resolved
+		}
+		dir := filepath.Dir(exe)
+		for _, candidate := range []string{dir, filepath.Dir(dir)} {
+			if _, err := os.Stat(filepath.Join(candidate, PortableMarker)); err == nil {
+				portableRoot = candidate
+				return
+			}
+		}
+	})
+	return portableRoot
+}
+
+// Dir returns the WinMole config directory, matching the PowerShell
+// scripts' $script:Config.ConfigPath.
+func Dir() string {
+	if root := PortableRoot(); root != "" {
+		return filepath.Join(root, "config")
+	}
+	return filepath.Join(homeDir(), ".config", "winmole")
+}
+
+// CacheDir returns the WinMole cache directory, matching the PowerShell
+// scripts' $script:Config.CachePath.
+func CacheDir() string {
+	if root := PortableRoot(); root != "" {
+		return filepath.Join(root, "cache")
+	}
+	return filepath.Join(homeDir(), ".cache", "winmole")
+}
+
+// Path returns the full path of the settings file.
+func Path() string {
+	return filepath.Join(Dir(), FileName)
+}
+
+// SetProfile selects the profile used by Load and Save for the rest of the
+// process. An empty name selects the base settings.
+func SetProfile(name string) {
+	profileOverride = &name
+}
+
+// Profile returns the name of the active profile, or "" for the base settings.
+func Profile() string {
+	if profileOverride != nil {
+		return *profileOverride
+	}
+	if name := os.Getenv("WINMOLE_PROFILE"); name != "" {
+		return name
+	}
+	sections, err := readSections()
+	if err != nil {
+		return ""
+	}
+	var name string
+	if raw, ok := sections[profileKey]; ok {
+		json.Unmarshal(raw, &name)
+	}
+	return name
+}
+
+// Profiles returns the names of the profiles defined in the settings file.
+func Profiles() ([]string, error) {
+	sections, err := readSections()
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := readProfiles(sections)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// NextProfile returns the profile after current, cycling through the base
+// settings ("") and every named profile in order.
+func NextProfile(current string) (string, error) {
+	names, err := Profiles()
+	if err != nil {
+		return current, err
+	}
+	cycle := append([]string{""}, names...)
+	for i, name := range cycle {
+		if name == current {
+			return cycle[(i+1)%len(cycle)], nil
+		}
+	}
+	return "", nil
+}
+
+// Load decodes the named section of the settings file into v, then applies
+// the active profile's version of the section on top. A missing file or
+// section leaves v untouched so callers can pre-fill defaults.
+func Load(section string, v any) error {
+	sections, err := readSections()
+	if err != nil {
+		return err
+	}
+	if raw, ok := sections[section]; ok {
+		if err := json.Unmarshal(raw, v); err != nil {
+			return fmt.Errorf("parse %s section %q: %w", FileName, section, err)
+		}
+	}
+
+	name := Profile()
+	if name == "" {
+		return nil
+	}
+	profiles, err := readProfiles(sections)
+	if err != nil {
+		return err
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+	if raw, ok := profile[section]; ok {
+		if err := json.Unmarshal(raw, v); err != nil {
+			return fmt.Errorf("parse profile %q section %q: %w", name, section, err)
+		}
+	}
+	return nil
+}
+
+// Save replaces the named section of the settings file with v, preserving
+// every other section. With a profile active, the profile's copy of the
+// section is written instead.
+func Save(section string, v any) error {
+	sections, err := readSections()
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode section %q: %w", section, err)
+	}
+
+	if name := Profile(); name != "" {
+		profiles, err := readProfiles(sections)
+		if err != nil {
+			return err
+		}
+		if profiles[name] == nil {
+			profiles[name] = make(map[string]json.RawMessage)
+		}
+		profiles[name][section] = raw
+		if sections[profilesKey], err = json.Marshal(profiles); err != nil {
+			return fmt.Errorf("encode profiles: %w", err)
+		}
+	} else {
+		sections[section] = raw
+	}
+
+	data, err := json.MarshalIndent(sections, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", FileName, err)
+	}
+	if err := os.MkdirAll(Dir(), 0o755); err != nil {
+		return fmt.Errorf("create config directory: %w", err)
+	}
+	return os.WriteFile(Path(), append(data, '\n'), 0o644)
+}
+
+// ApplyUnits makes humanize.Bytes use the units setting, with the active
+// profile's on top. A bad setting leaves the default binary units.
+func ApplyUnits() error {
+	var name string
+	if err := Load(UnitsKey, &name); err != nil {
+		humanize.SetUnits(humanize.Binary)
+		return err
+	}
+	units, err := humanize.ParseUnits(name)
+	humanize.SetUnits(units)
+	return err
+}
+
+// ApplyTimes makes humanize.Time and Date follow the times setting, with
+// the active profile's on top. A bad setting leaves absolute times.
+func ApplyTimes() error {
+	var name string
+	if err := Load(TimesKey, &name); err != nil {
+		humanize.SetTimes(humanize.Absolute)
+		return err
+	}
+	times, err := humanize.ParseTimes(name)
+	humanize.SetTimes(times)
+	return err
+}
+
+func readSections() (map[string]json.RawMessage, error) {
+	sections := make(map[string]json.RawMessage)
+
+	data, err := os.ReadFile(Path())
+	if errors.Is(err, os.ErrNotExist) {
+		return sections, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", FileName, err)
+	}
+	if len(data) == 0 {
+		return sections, nil
+	}
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", FileName, err)
+	}
+	return sections, nil
+}
+
+func readProfiles(sections map[string]json.RawMessage) (map[string]map[string]json.RawMessage, error) {
+	profiles := make(map[string]map[string]json.RawMessage)
+	if raw, ok := sections[profilesKey]; ok {
+		if err := json.Unmarshal(raw, &profiles); err != nil {
+			return nil, fmt.Errorf("parse %s profiles: %w", FileName, err)
+		}
+	}
+	return profiles, nil
+}
+
+func homeDir() string {
+	if home := os.Getenv("USERPROFILE"); home != "" {
+		return home
+	}
+	home, _ := os.UserHomeDir()
+	return home
+}
@@ -0,0 +1,187 @@
+//go:build windows
+
+// Package elevate runs privileged operations in a separate, elevated helper
+// process (helper.exe) so the TUIs themselves never need to run as
+// administrator.
+//
+// The client starts the helper through a UAC prompt and talks to it over a
+// named pipe that only the current user can open. The helper serves a fixed
+// set of named operations, accepts a single connection from the process that
+// started it, and exits when that connection closes. Every request is
+// written to an audit log.
+package elevate
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// HelperName is the helper executable, built next to the Go tools in bin\.
+const HelperName = "helper.exe"
+
+// startTimeout bounds how long the client waits for the helper's pipe,
+// which includes the time the user spends on the UAC prompt.
+const startTimeout = 2 * time.Minute
+
+// Request is one call from the client to the helper.
+type Request struct {
+	Op   string          `json:"op"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+// Response is the helper's answer to a Request.
+type Response struct {
+	Error  string          `json:"error,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// Client is a connection to a running helper.
+type Client struct {
+	mu     sync.Mutex
+	pipe   *os.File
+	reader *bufio.Reader
+}
+
+// IsElevated reports whether the current process already has admin rights,
+// in which case privileged work can be done in-process.
+func IsElevated() bool {
+	return windows.GetCurrentProcessToken().IsElevated()
+}
+
+// Start launches the helper with a UAC prompt and connects to it. The
+// helper writes its output files into a work directory of its own (see
+// NewWorkDir).
+func Start() (*Client, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	helper := filepath.Join(filepath.Dir(exe), HelperName)
+	if _, err := os.Stat(helper); err != nil {
+		return nil, fmt.Errorf("elevated helper not found: %w", err)
+	}
+
+	pipeName, err := newPipeName()
+	if err != nil {
+		return nil, err
+	}
+	args := fmt.Sprintf(`-pipe %s -parent %d`, pipeName, os.Getpid())
+
+	verb, _ := windows.UTF16PtrFromString("runas")
+	file, _ := windows.UTF16PtrFromString(helper)
+	params, _ := windows.UTF16PtrFromString(args)
+	if err := windows.ShellExecute(0, verb, file, params, nil, windows.SW_HIDE); err != nil {
+		if errors.Is(err, windows.ERROR_CANCELLED) {
+			return nil, errors.New("elevation was declined")
+		}
+		return nil, fmt.Errorf("start elevated helper: %w", err)
+	}
+
+	pipe, err := dialPipe(pipeName, startTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{pipe: pipe, reader: bufio.NewReader(pipe)}, nil
+}
+
+// Call runs op in the helper and decodes its result into result (which may
+// be nil).
+func (c *Client) Call(op string, args, result any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	req := Request{Op: op}
+	if args != nil {
+		raw, err := json.Marshal(args)
+		if err != nil {
+			return fmt.Errorf("encode %s arguments: %w", op, err)
+		}
+		req.Args = raw
+	}
+	if err := writeMessage(c.pipe, req); err != nil {
+		return fmt.Errorf("helper %s: %w", op, err)
+	}
+
+	var resp Response
+	if err := readMessage(c.reader, &resp); err != nil {
+		return fmt.Errorf("helper %s: %w", op, err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("helper %s: %s", op, resp.Error)
+	}
+	if result != nil && len(resp.Result) > 0 {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("decode %s result: %w", op, err)
+		}
+	}
+	return nil
+}
+
+// Close disconnects, which makes the helper exit.
+func (c *Client) Close() error {
+	return c.pipe.Close()
+}
+
+// newPipeName returns an unguessable pipe name so other processes cannot
+// connect to or pre-create it.
+func newPipeName() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`\\.\pipe\winmole-helper-%d-%s`, os.Getpid(), hex.EncodeToString(b[:])), nil
+}
+
+// dialPipe opens the client end of the pipe, waiting for the server to
+// create it.
+func dialPipe(name string, timeout time.Duration) (*os.File, error) {
+	name16, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		h, err := windows.CreateFile(name16,
+			windows.GENERIC_READ|windows.GENERIC_WRITE, 0, nil,
+			windows.OPEN_EXISTING, windows.SECURITY_SQOS_PRESENT|windows.SECURITY_IDENTIFICATION, 0)
+		if err == nil {
+			return os.NewFile(uintptr(h), name), nil
+		}
+		if !errors.Is(err, windows.ERROR_FILE_NOT_FOUND) && !errors.Is(err, windows.ERROR_PIPE_BUSY) {
+			return nil, fmt.Errorf("connect to helper: %w", err)
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("timed out waiting for the elevated helper")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// Messages are single lines of JSON.
+
+func writeMessage(f *os.File, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+func readMessage(r *bufio.Reader, v any) error {
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(line, v)
+}
@@ -0,0 +1,391 @@
+//go:build windows
+
+package elevate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/winmole/winmole/internal/msi"
+	"github.com/winmole/winmole/internal/policy"
+	"github.com/winmole/winmole/internal/smb"
+	"github.com/winmole/winmole/internal/vss"
+)
+
+// Operations served by the helper. Keep this list short: each one is part
+// of the privileged surface. Output files are written under fixed names in
+// the work directory, so a client cannot direct the helper to write
+// anywhere else.
+const (
+	// OpPing checks that the helper is alive.
+	OpPing = "ping"
+	// OpCopySRUM shadow-copies the SRUM database (SRUDB.dat).
+	OpCopySRUM = "copy-srum"
+	// OpEnergyReport exports SRUM energy estimates with powercfg /srumutil.
+	OpEnergyReport = "energy-report"
+	// OpRenewDHCP renews the DHCP lease of one adapter (AdapterArgs).
+	OpRenewDHCP = "renew-dhcp"
+	// OpRestartAdapter disables and re-enables one adapter (AdapterArgs).
+	OpRestartAdapter = "restart-adapter"
+	// OpResetWinsock resets the Winsock catalog; it takes effect after a
+	// restart.
+	OpResetWinsock = "reset-winsock"
+	// OpSMBStatus lists the shares, sessions and open files of this
+	// machine's file server, as JSON (smb.Status).
+	OpSMBStatus = "smb-status"
+	// OpCloseFile closes a file a client holds open through a share
+	// (FileArgs).
+	OpCloseFile = "close-file"
+	// OpAnalyzeComponentStore reports the size of the component store
+	// (WinSxS) with DISM /AnalyzeComponentStore, in English.
+	OpAnalyzeComponentStore = "analyze-component-store"
+	// OpComponentCleanup removes superseded components with DISM
+	// /StartComponentCleanup. It can take many minutes.
+	OpComponentCleanup = "component-cleanup"
+	// OpInstallerCache lists the Windows Installer cache with its
+	// orphaned packages, as JSON (msi.Cache).
+	OpInstallerCache = "installer-cache"
+	// OpRemoveInstallerOrphans deletes orphaned Installer packages
+	// (PackageArgs), as JSON (msi.Removal).
+	OpRemoveInstallerOrphans = "remove-installer-orphans"
+	// OpShadowStorage lists the shadow copy storage of each volume, as
+	// JSON ([]vss.Storage).
+	OpShadowStorage = "shadow-storage"
+	// OpResizeShadowStorage sets the maximum of one volume's shadow copy
+	// storage (ShadowArgs).
+	OpResizeShadowStorage = "resize-shadow-storage"
+	// OpDeleteOldestShadow deletes the oldest shadow copy of one volume
+	// (ShadowArgs).
+	OpDeleteOldestShadow = "delete-oldest-shadow"
+	// OpSetHibernation turns hibernation off or on, or switches the
+	// hibernation file between full and reduced (HibernationArgs).
+	OpSetHibernation = "set-hibernation"
+)
+
+// AdapterArgs names the network adapter an operation applies to, as in
+// the Network Connections folder ("Wi-Fi", "Ethernet 2").
+type AdapterArgs struct {
+	Name string `json:"name"`
+}
+
+// FileArgs identifies a file open through a share, by the ID in
+// smb.OpenFile.
+type FileArgs struct {
+	ID uint32 `json:"id"`
+}
+
+// PackageArgs lists Installer cache packages by path. Only those still
+// orphaned when the helper checks again are removed.
+type PackageArgs struct {
+	Paths []string `json:"paths"`
+}
+
+// ShadowArgs names a volume's shadow copy storage by drive ("C:") and the
+// drive holding it, with the new maximum for OpResizeShadowStorage ("10%",
+// "20GB" or "UNBOUNDED").
+type ShadowArgs struct {
+	Volume  string `json:"volume"`
+	On      string `json:"on,omitempty"`
+	MaxSize string `json:"maxSize,omitempty"`
+}
+
+// HibernationArgs picks the hibernation setting: "off", "full" (on, with
+// the full file) or "reduced" (on, with the smaller file that only serves
+// Fast Startup).
+type HibernationArgs struct {
+	Mode string `json:"mode"`
+}
+
+// Ops returns the handlers for every operation, writing into workDir,
+// which must come from NewWorkDir so the client cannot redirect the writes.
+func Ops(workDir string) map[string]Handler {
+	return map[string]Handler{
+		OpPing: func(json.RawMessage) (any, error) {
+			return "pong", nil
+		},
+		OpCopySRUM: func(json.RawMessage) (any, error) {
+			return copySRUM(workDir)
+		},
+		OpEnergyReport: func(json.RawMessage) (any, error) {
+			return exportEnergyReport(workDir)
+		},
+		OpRenewDHCP: func(args json.RawMessage) (any, error) {
+			name, err := adapterName(args)
+			if err != nil {
+				return nil, err
+			}
+			return runTool("ipconfig", "/renew", name)
+		},
+		OpRestartAdapter: func(args json.RawMessage) (any, error) {
+			name, err := adapterName(args)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := runTool("netsh", "interface", "set", "interface", name, "admin=disabled"); err != nil {
+				return nil, err
+			}
+			return runTool("netsh", "interface", "set", "interface", name, "admin=enabled")
+		},
+		OpResetWinsock: func(json.RawMessage) (any, error) {
+			return runTool("netsh", "winsock", "reset")
+		},
+		OpSMBStatus: func(json.RawMessage) (any, error) {
+			status, err := smb.Read()
+			if err != nil {
+				return nil, err
+			}
+			out, err := json.Marshal(status)
+			return string(out), err
+		},
+		OpCloseFile: func(args json.RawMessage) (any, error) {
+			var a FileArgs
+			if err := json.Unmarshal(args, &a); err != nil {
+				return nil, fmt.Errorf("file arguments: %w", err)
+			}
+			return "", smb.CloseFile(a.ID)
+		},
+		OpAnalyzeComponentStore: func(json.RawMessage) (any, error) {
+			return runTool("dism", "/Online", "/Cleanup-Image", "/AnalyzeComponentStore", "/English")
+		},
+		OpComponentCleanup: func(json.RawMessage) (any, error) {
+			if err := deletionAllowed(); err != nil {
+				return nil, err
+			}
+			return runTool("dism", "/Online", "/Cleanup-Image", "/StartComponentCleanup", "/English")
+		},
+		OpInstallerCache: func(json.RawMessage) (any, error) {
+			cache, err := msi.Read()
+			if err != nil {
+				return nil, err
+			}
+			out, err := json.Marshal(cache)
+			return string(out), err
+		},
+		OpRemoveInstallerOrphans: func(args json.RawMessage) (any, error) {
+			if err := deletionAllowed(); err != nil {
+				return nil, err
+			}
+			var a PackageArgs
+			if err := json.Unmarshal(args, &a); err != nil {
+				return nil, fmt.Errorf("package arguments: %w", err)
+			}
+			removal, err := msi.Remove(a.Paths)
+			if err != nil {
+				return nil, err
+			}
+			out, err := json.Marshal(removal)
+			return string(out), err
+		},
+		OpShadowStorage: func(json.RawMessage) (any, error) {
+			storage, err := vss.Read()
+			if err != nil {
+				return nil, err
+			}
+			out, err := json.Marshal(storage)
+			return string(out), err
+		},
+		OpResizeShadowStorage: func(args json.RawMessage) (any, error) {
+			var a ShadowArgs
+			if err := json.Unmarshal(args, &a); err != nil {
+				return nil, fmt.Errorf("shadow arguments: %w", err)
+			}
+			return "", vss.Resize(a.Volume, a.On, a.MaxSize)
+		},
+		OpDeleteOldestShadow: func(args json.RawMessage) (any, error) {
+			if err := deletionAllowed(); err != nil {
+				return nil, err
+			}
+			var a ShadowArgs
+			if err := json.Unmarshal(args, &a); err != nil {
+				return nil, fmt.Errorf("shadow arguments: %w", err)
+			}
+			return "", vss.DeleteOldest(a.Volume)
+		},
+		OpSetHibernation: func(args json.RawMessage) (any, error) {
+			var a HibernationArgs
+			if err := json.Unmarshal(args, &a); err != nil {
+				return nil, fmt.Errorf("hibernation arguments: %w", err)
+			}
+			switch a.Mode {
+			case "off":
+				return runTool("powercfg", "/hibernate", "off")
+			case "full", "reduced":
+				if _, err := runTool("powercfg", "/hibernate", "on"); err != nil {
+					return nil, err
+				}
+				return runTool("powercfg", "/hibernate", "/type", a.Mode)
+			}
+			return nil, fmt.Errorf("invalid hibernation mode %q", a.Mode)
+		},
+	}
+}
+
+// adapterName decodes AdapterArgs and checks that the name is an existing
+// adapter, so a client cannot pass anything else to the tools.
+func adapterName(args json.RawMessage) (string, error) {
+	var a AdapterArgs
+	if err := json.Unmarshal(args, &a); err != nil {
+		return "", fmt.Errorf("adapter arguments: %w", err)
+	}
+	if a.Name == "" || strings.ContainsAny(a.Name, `*?"`) {
+		return "", fmt.Errorf("invalid adapter name %q", a.Name)
+	}
+	if _, err := net.InterfaceByName(a.Name); err != nil {
+		return "", fmt.Errorf("adapter %q: %w", a.Name, err)
+	}
+	return a.Name, nil
+}
+
+// deletionAllowed refuses operations that delete files when Group Policy
+// turns deleting off. The helper checks again rather than trusting the
+// client, which runs unelevated.
+func deletionAllowed() error {
+	if policy.Get().DisableFileDeletion {
+		return errors.New("deleting is " + policy.DisabledMessage)
+	}
+	return nil
+}
+
+// runTool runs a Windows tool and returns its output.
+func runTool(name string, args ...string) (string, error) {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s: %v: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// copySRUM copies the SRUM database, which is locked by the Diagnostic
+// Policy Service and readable only by administrators, via a shadow copy.
+func copySRUM(workDir string) (string, error) {
+	src := filepath.Join(os.Getenv("SystemRoot"), "System32", "sru", "SRUDB.dat")
+	dst := filepath.Join(workDir, "SRUDB.dat")
+	os.Remove(dst)
+	if out, err := exec.Command("esentutl", "/y", src, "/vss", "/d", dst).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("esentutl: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return dst, nil
+}
+
+// exportEnergyReport dumps the SRUM energy estimates as CSV.
+func exportEnergyReport(workDir string) (string, error) {
+	dst := filepath.Join(workDir, "srum-energy.csv")
+	os.Remove(dst)
+	if out, err := exec.Command("powercfg", "/srumutil", "/output", dst, "/csv").CombinedOutput(); err != nil {
+		return "", fmt.Errorf("powercfg: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return dst, nil
+}
+
+// session is the helper shared by one tool run, started on first use.
+// workDir is the client's own temp directory, which output files are
+// copied into; opsDir is where the operations write when they run
+// in-process.
+var session struct {
+	mu      sync.Mutex
+	workDir string
+	opsDir  string
+	client  *Client
+}
+
+// RunFileOp performs an operation that produces a file and returns its
+// path: in-process when already elevated, otherwise through the helper,
+// which is started (with a UAC prompt) the first time it is needed. The
+// file is a copy in a temp directory of this process and stays valid
+// until Shutdown.
+func RunFileOp(op string) (string, error) {
+	out, err := run(op, nil)
+	if err != nil {
+		return "", err
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return copyOut(out, session.workDir)
+}
+
+// RunAction performs an operation that changes the system, such as
+// OpRestartAdapter, the same way as RunFileOp, and returns the output of
+// the tool that did it.
+func RunAction(op string, args any) (string, error) {
+	return run(op, args)
+}
+
+// run performs op with args, whose handler returns a string.
+func run(op string, args any) (string, error) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if session.workDir == "" {
+		dir, err := os.MkdirTemp("", "winmole-elevated")
+		if err != nil {
+			return "", err
+		}
+		session.workDir = dir
+	}
+
+	if IsElevated() {
+		if session.opsDir == "" {
+			dir, err := NewWorkDir(uint32(os.Getpid()))
+			if err != nil {
+				return "", err
+			}
+			session.opsDir = dir
+		}
+		handler, ok := Ops(session.opsDir)[op]
+		if !ok {
+			return "", fmt.Errorf("unknown operation %q", op)
+		}
+		var raw json.RawMessage
+		if args != nil {
+			var err error
+			if raw, err = json.Marshal(args); err != nil {
+				return "", fmt.Errorf("encode %s arguments: %w", op, err)
+			}
+		}
+		result, err := handler(raw)
+		if err != nil {
+			return "", err
+		}
+		out, _ := result.(string)
+		return out, nil
+	}
+
+	if session.client == nil {
+		client, err := Start()
+		if err != nil {
+			return "", err
+		}
+		session.client = client
+	}
+	var out string
+	if err := session.client.Call(op, args, &out); err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// Shutdown stops the helper, if one was started, and removes its files.
+func Shutdown() {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if session.client != nil {
+		session.client.Close()
+		session.client = nil
+	}
+	if session.workDir != "" {
+		os.RemoveAll(session.workDir)
+		session.workDir = ""
+	}
+	if session.opsDir != "" {
+		os.RemoveAll(session.opsDir)
+		session.opsDir = ""
+	}
+}
@@ -0,0 +1,216 @@
+//go:build windows
+
+package elevate
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// The helper runs elevated for an unelevated client, so it never writes
+// where the client could plant a junction, symlink or hard link first.
+// Its audit log and output files live in %ProgramData%\WinMole, which is
+// owned by Administrators and writable only by them and SYSTEM. Each
+// helper run gets its own work directory there that the client's user
+// can read but not change, and the client copies the files it needs out
+// of it.
+
+// dataDirSDDL locks DataDir: Administrators own it, SYSTEM and
+// Administrators have full control, users may read, and nothing is
+// inherited from ProgramData, which lets users create folders.
+const dataDirSDDL = "O:BAD:P(A;OICI;FA;;;SY)(A;OICI;FA;;;BA)(A;OICI;0x1200a9;;;BU)"
+
+// DataDir returns %ProgramData%\WinMole, creating it, or resetting its
+// security if it already exists. It refuses a folder that is a reparse
+// point or that an unelevated user created.
+func DataDir() (string, error) {
+	root, err := windows.KnownFolderPath(windows.FOLDERID_ProgramData, 0)
+	if err != nil {
+		return "", fmt.Errorf("ProgramData: %w", err)
+	}
+	dir := filepath.Join(root, "WinMole")
+	err = createDir(dir, dataDirSDDL)
+	if err == nil || !errors.Is(err, windows.ERROR_ALREADY_EXISTS) {
+		return dir, err
+	}
+	if err := relock(dir, dataDirSDDL); err != nil {
+		return "", fmt.Errorf("%s: %w", dir, err)
+	}
+	return dir, nil
+}
+
+// NewWorkDir creates a directory in DataDir for one helper run's output
+// files, readable by the user of process clientPID and writable only by
+// administrators.
+func NewWorkDir(clientPID uint32) (string, error) {
+	data, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	sid, err := processUser(clientPID)
+	if err != nil {
+		return "", fmt.Errorf("client user: %w", err)
+	}
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	dir := filepath.Join(data, "helper-"+hex.EncodeToString(b[:]))
+	sddl := fmt.Sprintf("O:BAD:P(A;OICI;FA;;;SY)(A;OICI;FA;;;BA)(A;OICI;0x1200a9;;;%s)", sid)
+	if err := createDir(dir, sddl); err != nil {
+		return "", fmt.Errorf("create work directory: %w", err)
+	}
+	return dir, nil
+}
+
+// OpenAppend opens path in DataDir for appending without following a
+// reparse point, and refuses a file with other hard links.
+func OpenAppend(path string) (*os.File, error) {
+	path16, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	h, err := windows.CreateFile(path16, windows.FILE_APPEND_DATA|windows.SYNCHRONIZE,
+		windows.FILE_SHARE_READ, nil, windows.OPEN_ALWAYS,
+		windows.FILE_ATTRIBUTE_NORMAL|windows.FILE_FLAG_OPEN_REPARSE_POINT, 0)
+	if err != nil {
+		return nil, err
+	}
+	var info windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(h, &info); err != nil {
+		windows.CloseHandle(h)
+		return nil, err
+	}
+	if info.FileAttributes&windows.FILE_ATTRIBUTE_REPARSE_POINT != 0 || info.NumberOfLinks > 1 {
+		windows.CloseHandle(h)
+		return nil, fmt.Errorf("%s is a link", path)
+	}
+	return os.NewFile(uintptr(h), path), nil
+}
+
+// copyOut copies a file from the helper's work directory into dir, where
+// the client may change and remove it.
+func copyOut(src, dir string) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	dst := filepath.Join(dir, filepath.Base(src))
+	out, err := os.Create(dst)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return "", err
+	}
+	return dst, nil
+}
+
+// createDir creates path with the security descriptor sddl. It fails with
+// ERROR_ALREADY_EXISTS rather than reusing a folder someone else made.
+func createDir(path, sddl string) error {
+	sd, err := windows.SecurityDescriptorFromString(sddl)
+	if err != nil {
+		return err
+	}
+	sa := &windows.SecurityAttributes{
+		Length:             uint32(unsafe.Sizeof(windows.SecurityAttributes{})),
+		SecurityDescriptor: sd,
+	}
+	path16, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	return windows.CreateDirectory(path16, sa)
+}
+
+// relock applies sddl to an existing folder through a handle that does
+// not follow reparse points, after checking that Administrators or
+// SYSTEM own it.
+func relock(path, sddl string) error {
+	path16, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	h, err := windows.CreateFile(path16,
+		windows.FILE_READ_ATTRIBUTES|windows.READ_CONTROL|windows.WRITE_DAC|windows.WRITE_OWNER,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE, nil,
+		windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS|windows.FILE_FLAG_OPEN_REPARSE_POINT, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	var info windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(h, &info); err != nil {
+		return err
+	}
+	if info.FileAttributes&windows.FILE_ATTRIBUTE_REPARSE_POINT != 0 {
+		return errors.New("is a reparse point")
+	}
+	if info.FileAttributes&windows.FILE_ATTRIBUTE_DIRECTORY == 0 {
+		return errors.New("is not a folder")
+	}
+
+	current, err := windows.GetSecurityInfo(h, windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION)
+	if err != nil {
+		return err
+	}
+	owner, _, err := current.Owner()
+	if err != nil {
+		return err
+	}
+	if !owner.IsWellKnown(windows.WinBuiltinAdministratorsSid) && !owner.IsWellKnown(windows.WinLocalSystemSid) {
+		return fmt.Errorf("is owned by %s, not by Administrators", owner)
+	}
+
+	sd, err := windows.SecurityDescriptorFromString(sddl)
+	if err != nil {
+		return err
+	}
+	newOwner, _, err := sd.Owner()
+	if err != nil {
+		return err
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return err
+	}
+	return windows.SetSecurityInfo(h, windows.SE_FILE_OBJECT,
+		windows.OWNER_SECURITY_INFORMATION|windows.DACL_SECURITY_INFORMATION|windows.PROTECTED_DACL_SECURITY_INFORMATION,
+		newOwner, nil, dacl, nil)
+}
+
+// processUser returns the SID of the user running process pid.
+func processUser(pid uint32) (string, error) {
+	p, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return "", err
+	}
+	defer windows.CloseHandle(p)
+	var token windows.Token
+	if err := windows.OpenProcessToken(p, windows.TOKEN_QUERY, &token); err != nil {
+		return "", err
+	}
+	defer token.Close()
+	user, err := token.GetTokenUser()
+	if err != nil {
+		return "", err
+	}
+	return user.User.Sid.String(), nil
+}
@@ -0,0 +1,115 @@
+//go:build windows
+
+package elevate
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Handler runs one privileged operation. It must validate its arguments:
+// they come from an unelevated process.
+type Handler func(args json.RawMessage) (any, error)
+
+// AuditFunc is called after every request with its outcome.
+type AuditFunc func(op string, args json.RawMessage, err error)
+
+var (
+	kernel32                        = windows.NewLazySystemDLL("kernel32.dll")
+	procGetNamedPipeClientProcessId = kernel32.NewProc("GetNamedPipeClientProcessId")
+)
+
+// Serve creates pipeName, accepts one connection from clientPID and answers
+// its requests with handlers until the client disconnects.
+func Serve(pipeName string, clientPID uint32, handlers map[string]Handler, audit AuditFunc) error {
+	pipe, err := createPipe(pipeName)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(pipe)
+
+	if err := windows.ConnectNamedPipe(pipe, nil); err != nil && !errors.Is(err, windows.ERROR_PIPE_CONNECTED) {
+		return fmt.Errorf("accept client: %w", err)
+	}
+
+	// Only the process that launched us may issue requests.
+	var pid uint32
+	if r, _, err := procGetNamedPipeClientProcessId.Call(uintptr(pipe), uintptr(unsafe.Pointer(&pid))); r == 0 {
+		return fmt.Errorf("identify client: %w", err)
+	}
+	if pid != clientPID {
+		return fmt.Errorf("rejecting client process %d (expected %d)", pid, clientPID)
+	}
+
+	f := os.NewFile(uintptr(pipe), pipeName)
+	r := bufio.NewReader(f)
+	for {
+		var req Request
+		if err := readMessage(r, &req); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, windows.ERROR_BROKEN_PIPE) {
+				return nil
+			}
+			return fmt.Errorf("read request: %w", err)
+		}
+
+		var resp Response
+		handler, ok := handlers[req.Op]
+		if !ok {
+			err = fmt.Errorf("unknown operation %q", req.Op)
+		} else {
+			var result any
+			result, err = handler(req.Args)
+			if err == nil && result != nil {
+				resp.Result, err = json.Marshal(result)
+			}
+		}
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		if audit != nil {
+			audit(req.Op, req.Args, err)
+		}
+		if err := writeMessage(f, resp); err != nil {
+			return fmt.Errorf("write response: %w", err)
+		}
+	}
+}
+
+// createPipe creates a single-instance, local-only pipe that only the
+// current user (and SYSTEM) can open. The medium integrity label lets the
+// unelevated client connect to a pipe created by an elevated process.
+func createPipe(name string) (windows.Handle, error) {
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return 0, fmt.Errorf("current user: %w", err)
+	}
+	sddl := fmt.Sprintf("D:P(A;;GA;;;%s)(A;;GA;;;SY)S:(ML;;NW;;;ME)", user.User.Sid.String())
+	sd, err := windows.SecurityDescriptorFromString(sddl)
+	if err != nil {
+		return 0, fmt.Errorf("pipe security: %w", err)
+	}
+	sa := &windows.SecurityAttributes{
+		Length:             uint32(unsafe.Sizeof(windows.SecurityAttributes{})),
+		SecurityDescriptor: sd,
+	}
+
+	name16, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return 0, err
+	}
+	h, err := windows.CreateNamedPipe(name16,
+		windows.PIPE_ACCESS_DUPLEX|windows.FILE_FLAG_FIRST_PIPE_INSTANCE,
+		windows.PIPE_TYPE_BYTE|windows.PIPE_READMODE_BYTE|windows.PIPE_WAIT|windows.PIPE_REJECT_REMOTE_CLIENTS,
+		1, 64*1024, 64*1024, 0, sa)
+	if err != nil {
+		return 0, fmt.Errorf("create pipe: %w", err)
+	}
+	return h, nil
+}
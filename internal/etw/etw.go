@@ -0,0 +1,100 @@
+//go:build windows
+
+// Package etw writes WinMole events to Event Tracing for Windows, so scan
+// results and user actions appear as marks on the Windows Performance
+// Analyzer timeline next to the rest of a trace. Nothing is formatted or
+// written unless a trace session has enabled the provider, for example the
+// profile in packaging\wpr:
+//
+//	wpr -start packaging\wpr\WinMole.wprp
+//	winmole analyze C:\
+//	wpr -stop winmole.etl
+//
+// Events are plain strings (EventWriteString), so no manifest has to be
+// registered for WPA to show them.
+package etw
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// ProviderID identifies the WinMole provider in trace profiles.
+var ProviderID = windows.GUID{
+	Data1: 0x6a1e3f2c,
+	Data2: 0x8b4d,
+	Data3: 0x4c71,
+	Data4: [8]byte{0x9e, 0x25, 0x3d, 0x7f, 0x0a, 0x9c, 0x41, 0xb6},
+}
+
+// Levels, as in evntrace.h.
+const (
+	LevelInfo    = 4
+	LevelVerbose = 5
+)
+
+// Keywords select which events a session receives.
+const (
+	KeywordScan   = 0x1 // scans and their largest entries
+	KeywordAction = 0x2 // what the user did: navigate, delete, export
+)
+
+var (
+	advapi32                 = windows.NewLazySystemDLL("advapi32.dll")
+	procEventRegister        = advapi32.NewProc("EventRegister")
+	procEventUnregister      = advapi32.NewProc("EventUnregister")
+	procEventProviderEnabled = advapi32.NewProc("EventProviderEnabled")
+	procEventWriteString     = advapi32.NewProc("EventWriteString")
+)
+
+var (
+	registerOnce sync.Once
+	handle       uint64 // REGHANDLE; 0 when registration failed
+)
+
+func provider() uint64 {
+	registerOnce.Do(func() {
+		if procEventRegister.Find() != nil {
+			return
+		}
+		r, _, _ := procEventRegister.Call(uintptr(unsafe.Pointer(&ProviderID)), 0, 0, uintptr(unsafe.Pointer(&handle)))
+		if r != 0 {
+			handle = 0
+		}
+	})
+	return handle
+}
+
+// Enabled reports whether a trace session is listening for events of level
+// and keyword.
+func Enabled(level uint8, keyword uint64) bool {
+	h := provider()
+	if h == 0 {
+		return false
+	}
+	r, _, _ := procEventProviderEnabled.Call(uintptr(h), uintptr(level), uintptr(keyword))
+	return byte(r) != 0
+}
+
+// Writef formats and writes an event if a session is listening for it.
+func Writef(level uint8, keyword uint64, format string, args ...any) {
+	if !Enabled(level, keyword) {
+		return
+	}
+	msg, err := windows.UTF16PtrFromString(fmt.Sprintf(format, args...))
+	if err != nil {
+		return
+	}
+	procEventWriteString.Call(uintptr(handle), uintptr(level), uintptr(keyword), uintptr(unsafe.Pointer(msg)))
+}
+
+// Close unregisters the provider. Call it once before exiting.
+func Close() {
+	if h := provider(); h != 0 {
+		procEventUnregister.Call(uintptr(h))
+		handle = 0
+	}
+}
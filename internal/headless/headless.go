@@ -0,0 +1,88 @@
+// Package headless gives the non-interactive modes of the tools (analyze
+// --no-tui and --export, status --oneline) a common deadline and Ctrl+C /
+// Ctrl+Break handling, so scripts never wait forever on a stuck network
+// share. A run that is cut short still writes what it gathered and then
+// exits with ExitTimeout or ExitInterrupted, which scripts can tell apart
+// from ordinary failures (1).
+package headless
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Exit codes for runs that were cut short, following the timeout(1) and
+// shell conventions.
+const (
+	ExitTimeout     = 124
+	ExitInterrupted = 130
+)
+
+// Grace is how long a stopped run gets to wind its workers down before its
+// partial results are used anyway. A worker blocked in a call to an
+// unresponsive share cannot be stopped, only left behind.
+const Grace = 2 * time.Second
+
+// Context returns a context that is cancelled on Ctrl+C, Ctrl+Break or the
+// console closing, and after timeout when it is positive. Call stop once
+// the run is over to restore the default signal handling.
+func Context(timeout time.Duration) (ctx context.Context, stop context.CancelFunc) {
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if timeout <= 0 {
+		return ctx, stopSignals
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stopSignals()
+	}
+}
+
+// Wait runs fn and returns its error. Once ctx is done, fn has Grace to
+// return; after that Wait returns ctx.Err() and leaves fn running.
+func Wait(ctx context.Context, fn func() error) error {
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(Grace):
+		return ctx.Err()
+	}
+}
+
+// Stopped reports whether err means the run was cut short by the deadline
+// or a signal rather than failing.
+func Stopped(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+// Reason describes why a stopped run ended: "timed out" or "interrupted".
+func Reason(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timed out"
+	}
+	return "interrupted"
+}
+
+// ExitCode is the process exit code for a run that ended with err.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, context.DeadlineExceeded):
+		return ExitTimeout
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	}
+	return 1
+}
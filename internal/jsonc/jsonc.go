@@ -0,0 +1,91 @@
+// Package jsonc turns JSON with comments, as Windows Terminal and VS Code
+// write their settings, into plain JSON that encoding/json accepts.
+package jsonc
+
+// Standardize returns data without a byte order mark, // and /* */
+// comments and commas before a closing } or ]. Text inside strings is kept
+// as it is, and line breaks are kept so errors point at the right line.
+func Standardize(data []byte) []byte {
+	if len(data) >= 3 && data[0] == 0xEF && data[1] == 0xBB && data[2] == 0xBF {
+		data = data[3:]
+	}
+	return dropTrailingCommas(stripComments(data))
+}
+
+// stripComments replaces comments with spaces, or the line breaks in them.
+func stripComments(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '"':
+			end := stringEnd(data, i)
+			out = append(out, data[i:end]...)
+			i = end - 1
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i < len(data) && !(data[i] == '*' && i+1 < len(data) && data[i+1] == '/') {
+				if data[i] == '\n' {
+					out = append(out, '\n')
+				}
+				i++
+			}
+			i++ // the closing slash
+			out = append(out, ' ')
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// dropTrailingCommas removes each comma followed only by white space and a
+// closing bracket.
+func dropTrailingCommas(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch c {
+		case '"':
+			end := stringEnd(data, i)
+			out = append(out, data[i:end]...)
+			i = end - 1
+			continue
+		case ',':
+			j := i + 1
+			for j < len(data) && isSpace(data[j]) {
+				j++
+			}
+			if j < len(data) && (data[j] == '}' || data[j] == ']') {
+				continue
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// stringEnd returns the index just past the string that starts at the
+// quote data[start], or len(data) if it is not closed.
+func stringEnd(data []byte, start int) int {
+	for i := start + 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(data)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,62 @@
+package jsonc
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestStandardize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want any
+	}{
+		{"plain", `{"a": 1}`, map[string]any{"a": 1.0}},
+		{"line comment", "{\n// note\n\"a\": 1 // after\n}", map[string]any{"a": 1.0}},
+		{"block comment", `{/* one */"a": /* two
+			lines */ 1}`, map[string]any{"a": 1.0}},
+		{"trailing commas", "{\"a\": [1, 2,\n],\n\"b\": {\"c\": 3,},\n}", map[string]any{"a": []any{1.0, 2.0}, "b": map[string]any{"c": 3.0}}},
+		{"comment before closing brace", "{\"a\": 1, // last\n}", map[string]any{"a": 1.0}},
+		{"slashes in strings", `{"url": "https://example.com/*x*/", "path": "C:\\//"}`, map[string]any{"url": "https://example.com/*x*/", "path": `C:\//`}},
+		{"escaped quote", `{"a": "say \"//hi\",}"}`, map[string]any{"a": `say "//hi",}`}},
+		{"byte order mark", "\xEF\xBB\xBF{\"a\": true}", map[string]any{"a": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got any
+			if err := json.Unmarshal(Standardize([]byte(tt.in)), &got); err != nil {
+				t.Fatalf("Unmarshal: %v\n%s", err, Standardize([]byte(tt.in)))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStandardizeKeepsLines(t *testing.T) {
+	in := "{\n/* a\nb */\n\"a\": 1\n}"
+	out := Standardize([]byte(in))
+	count := func(b []byte) (n int) {
+		for _, c := range b {
+			if c == '\n' {
+				n++
+			}
+		}
+		return n
+	}
+	if count(out) != count([]byte(in)) {
+		t.Errorf("line breaks: got %d, want %d in %q", count(out), count([]byte(in)), out)
+	}
+}
+
+func TestStandardizeUnterminated(t *testing.T) {
+	// Broken input must not panic; encoding/json reports the error.
+	for _, in := range []string{`{"a": "open`, `{/* open`, `{"a": 1,`, `"\`} {
+		var v any
+		if json.Unmarshal(Standardize([]byte(in)), &v) == nil {
+			t.Errorf("%q: want an error", in)
+		}
+	}
+}
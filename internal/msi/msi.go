@@ -0,0 +1,324 @@
+//go:build windows
+
+// Package msi finds the packages in the Windows Installer cache
+// (%SystemRoot%\Installer) that no installed product or patch refers to.
+// Windows Installer keeps a copy of every product's .msi and every patch's
+// .msp there to repair and uninstall them; when an uninstall goes wrong the
+// copies stay behind, and on long-lived machines the folder holds many
+// gigabytes nobody can account for. A package counts as registered when
+// the Installer API reports it as the local package of a product or patch
+// in any install context and for any user, which only administrators can
+// read, so the analyzer goes through the elevated helper.
+package msi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	msiDLL                        = windows.NewLazySystemDLL("msi.dll")
+	procMsiEnumProductsEx         = msiDLL.NewProc("MsiEnumProductsExW")
+	procMsiGetProductInfoEx       = msiDLL.NewProc("MsiGetProductInfoExW")
+	procMsiEnumPatchesEx          = msiDLL.NewProc("MsiEnumPatchesExW")
+	procMsiGetPatchInfoEx         = msiDLL.NewProc("MsiGetPatchInfoExW")
+	procMsiGetSummaryInformation  = msiDLL.NewProc("MsiGetSummaryInformationW")
+	procMsiSummaryInfoGetProperty = msiDLL.NewProc("MsiSummaryInfoGetPropertyW")
+	procMsiCloseHandle            = msiDLL.NewProc("MsiCloseHandle")
+	kernel32                      = windows.NewLazySystemDLL("kernel32.dll")
+	procOpenMutex                 = kernel32.NewProc("OpenMutexW")
+)
+
+// msi.h constants.
+const (
+	contextMachine = 4
+	contextAll     = 7
+	patchStateAll  = 15
+	allUsers       = "s-1-1-0"
+
+	pidTitle   = 2
+	pidSubject = 3
+	vtLPSTR    = 30
+
+	guidLength = 39
+	sidLength  = 256
+)
+
+// recentAge protects packages written this recently: an installation in
+// progress copies its package before registering it.
+const recentAge = 24 * time.Hour
+
+// Package is an .msi or .msp file in the Installer cache.
+type Package struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Patch    bool      `json:"patch"`
+	Name     string    `json:"name"` // the product or patch it installs
+	Modified time.Time `json:"modified"`
+}
+
+// Cache is what the Installer cache holds.
+type Cache struct {
+	Dir        string    `json:"dir"`
+	Registered []Package `json:"registered"`
+	Orphans    []Package `json:"orphans"` // largest first
+	Recent     int       `json:"recent"`  // unregistered but too new to call orphaned
+}
+
+// Total adds up the sizes of packages.
+func Total(packages []Package) int64 {
+	var n int64
+	for _, p := range packages {
+		n += p.Size
+	}
+	return n
+}
+
+// Dir is the Installer cache.
+func Dir() string {
+	return filepath.Join(os.Getenv("SystemRoot"), "Installer")
+}
+
+// Read lists the Installer cache and sorts its packages into registered
+// and orphaned. It needs admin rights; without them products installed
+// for other users would go unseen and their packages look orphaned, so it
+// fails instead.
+func Read() (Cache, error) {
+	c := Cache{Dir: Dir()}
+	if !windows.GetCurrentProcessToken().IsElevated() {
+		return c, errors.New("reading every user's installed products needs admin rights")
+	}
+	used, err := localPackages()
+	if err != nil {
+		return c, err
+	}
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return c, err
+	}
+	cutoff := time.Now().Add(-recentAge)
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || (ext != ".msi" && ext != ".msp") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		p := Package{
+			Path:     filepath.Join(c.Dir, e.Name()),
+			Size:     info.Size(),
+			Patch:    ext == ".msp",
+			Modified: info.ModTime(),
+		}
+		name, registered := used[strings.ToLower(p.Path)]
+		switch {
+		case registered:
+			p.Name = name
+			c.Registered = append(c.Registered, p)
+		case p.Modified.After(cutoff):
+			c.Recent++
+		default:
+			p.Name = summaryName(p.Path)
+			c.Orphans = append(c.Orphans, p)
+		}
+	}
+	sort.Slice(c.Orphans, func(i, j int) bool { return c.Orphans[i].Size > c.Orphans[j].Size })
+	return c, nil
+}
+
+// localPackages maps the lower-cased path of every registered local
+// package to the name of its product or patch. Any failure to enumerate
+// is an error, so a partial list never makes packages look orphaned.
+func localPackages() (map[string]string, error) {
+	used := map[string]string{}
+	sid, _ := windows.UTF16PtrFromString(allUsers)
+
+	for i := uint32(0); ; i++ {
+		var product [guidLength]uint16
+		var context uint32
+		var userSID [sidLength]uint16
+		sidLen := uint32(sidLength)
+		r, _, _ := procMsiEnumProductsEx.Call(0, uintptr(unsafe.Pointer(sid)), contextAll, uintptr(i),
+			uintptr(unsafe.Pointer(&product[0])), uintptr(unsafe.Pointer(&context)),
+			uintptr(unsafe.Pointer(&userSID[0])), uintptr(unsafe.Pointer(&sidLen)))
+		if r == uintptr(windows.ERROR_NO_MORE_ITEMS) {
+			break
+		}
+		if r != 0 {
+			return nil, fmt.Errorf("listing installed products: %w", windows.Errno(r))
+		}
+		user := contextUser(&userSID[0], context)
+		path, err := productInfo(&product[0], user, context, "LocalPackage")
+		if err != nil {
+			return nil, err
+		}
+		if path != "" {
+			name, _ := productInfo(&product[0], user, context, "ProductName")
+			used[strings.ToLower(path)] = name
+		}
+	}
+
+	for i := uint32(0); ; i++ {
+		var patch, product [guidLength]uint16
+		var context uint32
+		var userSID [sidLength]uint16
+		sidLen := uint32(sidLength)
+		r, _, _ := procMsiEnumPatchesEx.Call(0, uintptr(unsafe.Pointer(sid)), contextAll, patchStateAll, uintptr(i),
+			uintptr(unsafe.Pointer(&patch[0])), uintptr(unsafe.Pointer(&product[0])), uintptr(unsafe.Pointer(&context)),
+			uintptr(unsafe.Pointer(&userSID[0])), uintptr(unsafe.Pointer(&sidLen)))
+		if r == uintptr(windows.ERROR_NO_MORE_ITEMS) {
+			break
+		}
+		if r != 0 {
+			return nil, fmt.Errorf("listing installed patches: %w", windows.Errno(r))
+		}
+		user := contextUser(&userSID[0], context)
+		path, err := patchInfo(&patch[0], &product[0], user, context, "LocalPackage")
+		if err != nil {
+			return nil, err
+		}
+		if path != "" {
+			name, _ := patchInfo(&patch[0], &product[0], user, context, "DisplayName")
+			used[strings.ToLower(path)] = name
+		}
+	}
+	return used, nil
+}
+
+// contextUser is the user SID to pass back to the Installer: none for
+// per-machine installs.
+func contextUser(sid *uint16, context uint32) *uint16 {
+	if context == contextMachine {
+		return nil
+	}
+	return sid
+}
+
+func productInfo(product, user *uint16, context uint32, property string) (string, error) {
+	prop, _ := windows.UTF16PtrFromString(property)
+	return queryString(func(buf *uint16, n *uint32) uintptr {
+		r, _, _ := procMsiGetProductInfoEx.Call(uintptr(unsafe.Pointer(product)), uintptr(unsafe.Pointer(user)), uintptr(context),
+			uintptr(unsafe.Pointer(prop)), uintptr(unsafe.Pointer(buf)), uintptr(unsafe.Pointer(n)))
+		return r
+	})
+}
+
+func patchInfo(patch, product, user *uint16, context uint32, property string) (string, error) {
+	prop, _ := windows.UTF16PtrFromString(property)
+	return queryString(func(buf *uint16, n *uint32) uintptr {
+		r, _, _ := procMsiGetPatchInfoEx.Call(uintptr(unsafe.Pointer(patch)), uintptr(unsafe.Pointer(product)), uintptr(unsafe.Pointer(user)),
+			uintptr(context), uintptr(unsafe.Pointer(prop)), uintptr(unsafe.Pointer(buf)), uintptr(unsafe.Pointer(n)))
+		return r
+	})
+}
+
+// queryString calls an Msi*Info function, growing the buffer as asked. A
+// property the product or patch does not have reads as "".
+func queryString(call func(buf *uint16, n *uint32) uintptr) (string, error) {
+	buf := make([]uint16, windows.MAX_PATH)
+	for {
+		n := uint32(len(buf))
+		r := call(&buf[0], &n)
+		switch r {
+		case 0:
+			return windows.UTF16ToString(buf[:n]), nil
+		case uintptr(windows.ERROR_MORE_DATA):
+			buf = make([]uint16, n+1)
+		case uintptr(windows.ERROR_UNKNOWN_PROPERTY):
+			return "", nil
+		default:
+			return "", fmt.Errorf("reading installer property: %w", windows.Errno(r))
+		}
+	}
+}
+
+// summaryName is what an orphaned package says it installs, from its
+// summary information: the subject of an .msi, the title of an .msp.
+func summaryName(path string) string {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return ""
+	}
+	var h uint32
+	if r, _, _ := procMsiGetSummaryInformation.Call(0, uintptr(unsafe.Pointer(p)), 0, uintptr(unsafe.Pointer(&h))); r != 0 {
+		return ""
+	}
+	defer procMsiCloseHandle.Call(uintptr(h))
+	for _, pid := range []uintptr{pidSubject, pidTitle} {
+		var typ uint32
+		var value int32
+		var ft windows.Filetime
+		buf := make([]uint16, 256)
+		n := uint32(len(buf))
+		r, _, _ := procMsiSummaryInfoGetProperty.Call(uintptr(h), pid, uintptr(unsafe.Pointer(&typ)), uintptr(unsafe.Pointer(&value)),
+			uintptr(unsafe.Pointer(&ft)), uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&n)))
+		if r == 0 && typ == vtLPSTR {
+			if s := strings.TrimSpace(windows.UTF16ToString(buf[:n])); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// installing reports whether Windows Installer is running an installation,
+// which holds the _MSIExecute mutex.
+func installing() bool {
+	name, _ := windows.UTF16PtrFromString(`Global\_MSIExecute`)
+	h, _, _ := procOpenMutex.Call(windows.SYNCHRONIZE, 0, uintptr(unsafe.Pointer(name)))
+	if h == 0 {
+		return false
+	}
+	windows.CloseHandle(windows.Handle(h))
+	return true
+}
+
+// Removal is the outcome of Remove.
+type Removal struct {
+	Removed int      `json:"removed"`
+	Freed   int64    `json:"freed"`
+	Failed  []string `json:"failed"` // "name: reason"
+}
+
+// Remove deletes the given packages, but only those that are still
+// orphaned when it reads the cache again: it refuses during an
+// installation, and skips anything outside the cache or registered since
+// it was listed. It needs admin rights.
+func Remove(paths []string) (Removal, error) {
+	var out Removal
+	if installing() {
+		return out, errors.New("Windows Installer is installing something; try again when it is done")
+	}
+	c, err := Read()
+	if err != nil {
+		return out, err
+	}
+	orphans := map[string]Package{}
+	for _, p := range c.Orphans {
+		orphans[strings.ToLower(p.Path)] = p
+	}
+	for _, path := range paths {
+		p, ok := orphans[strings.ToLower(filepath.Clean(path))]
+		if !ok {
+			out.Failed = append(out.Failed, filepath.Base(path)+": no longer an orphaned package")
+			continue
+		}
+		if err := os.Remove(p.Path); err != nil {
+			out.Failed = append(out.Failed, filepath.Base(path)+": "+err.Error())
+			continue
+		}
+		out.Removed++
+		out.Freed += p.Size
+	}
+	return out, nil
+}
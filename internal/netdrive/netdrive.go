@@ -0,0 +1,254 @@
+//go:build windows
+
+// Package netdrive lists the network drives mapped in this logon session,
+// checks whether their servers answer, and connects, reconnects and
+// removes mappings. Mappings belong to the user's logon session, so unlike
+// package smb this must run unelevated, in the user's own process.
+package netdrive
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	mpr                       = windows.NewLazySystemDLL("mpr.dll")
+	procWNetOpenEnum          = mpr.NewProc("WNetOpenEnumW")
+	procWNetEnumResource      = mpr.NewProc("WNetEnumResourceW")
+	procWNetCloseEnum         = mpr.NewProc("WNetCloseEnum")
+	procWNetAddConnection2    = mpr.NewProc("WNetAddConnection2W")
+	procWNetCancelConnection2 = mpr.NewProc("WNetCancelConnection2W")
+)
+
+// winnetwk.h constants.
+const (
+	resourceConnected  = 1
+	resourceRemembered = 3
+	resourcetypeDisk   = 1
+
+	connectUpdateProfile = 0x1
+
+	errorNoMoreItems     = 259
+	errorNotConnected    = 2250
+	errorBadNetName      = 67
+	errorBadNetPath      = 53
+	errorSessionConflict = 1219
+)
+
+// Mapping is a drive letter mapped to a share.
+type Mapping struct {
+	Local      string // "Z:"
+	Remote     string // `\\server\share`
+	Provider   string // "Microsoft Windows Network"
+	Persistent bool   // restored at every sign-in
+	Connected  bool   // connected now, rather than only remembered
+}
+
+// Server is the host part of the mapping's remote path.
+func (m Mapping) Server() string {
+	host, _, _ := strings.Cut(strings.TrimPrefix(m.Remote, `\\`), `\`)
+	return host
+}
+
+// netResource mirrors NETRESOURCEW.
+type netResource struct {
+	scope       uint32
+	typ         uint32
+	displayType uint32
+	usage       uint32
+	localName   *uint16
+	remoteName  *uint16
+	comment     *uint16
+	provider    *uint16
+}
+
+// List returns the mapped drives, connected or only remembered, by drive
+// letter.
+func List() ([]Mapping, error) {
+	remembered, err := enumerate(resourceRemembered)
+	if err != nil {
+		return nil, err
+	}
+	connected, err := enumerate(resourceConnected)
+	if err != nil {
+		return nil, err
+	}
+
+	byLocal := make(map[string]*Mapping)
+	var list []*Mapping
+	for _, r := range remembered {
+		r.Persistent = true
+		byLocal[strings.ToUpper(r.Local)] = &r
+		list = append(list, &r)
+	}
+	for _, c := range connected {
+		if c.Local == "" {
+			continue // a connection without a drive letter, such as an open UNC path
+		}
+		if m, ok := byLocal[strings.ToUpper(c.Local)]; ok && strings.EqualFold(m.Remote, c.Remote) {
+			m.Connected = true
+			continue
+		}
+		c.Connected = true
+		list = append(list, &c)
+	}
+
+	mappings := make([]Mapping, 0, len(list))
+	for _, m := range list {
+		mappings = append(mappings, *m)
+	}
+	sort.Slice(mappings, func(i, j int) bool { return mappings[i].Local < mappings[j].Local })
+	return mappings, nil
+}
+
+// enumerate runs WNetOpenEnum over the disk resources in scope.
+func enumerate(scope uint32) ([]Mapping, error) {
+	var handle windows.Handle
+	if r, _, _ := procWNetOpenEnum.Call(uintptr(scope), resourcetypeDisk, 0, 0, uintptr(unsafe.Pointer(&handle))); r != 0 {
+		return nil, fmt.Errorf("WNetOpenEnum: %w", windows.Errno(r))
+	}
+	defer procWNetCloseEnum.Call(uintptr(handle))
+
+	// The strings are stored in the same buffer, after the structures.
+	buf := make([]netResource, 16*1024/unsafe.Sizeof(netResource{}))
+	var mappings []Mapping
+	for {
+		count := uint32(0xFFFFFFFF)
+		size := uint32(uintptr(len(buf)) * unsafe.Sizeof(buf[0]))
+		r, _, _ := procWNetEnumResource.Call(uintptr(handle), uintptr(unsafe.Pointer(&count)),
+			uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size)))
+		switch r {
+		case 0:
+		case errorNoMoreItems:
+			return mappings, nil
+		default:
+			return nil, fmt.Errorf("WNetEnumResource: %w", windows.Errno(r))
+		}
+		for _, nr := range buf[:count] {
+			mappings = append(mappings, Mapping{
+				Local:    windows.UTF16PtrToString(nr.localName),
+				Remote:   windows.UTF16PtrToString(nr.remoteName),
+				Provider: windows.UTF16PtrToString(nr.provider),
+			})
+		}
+	}
+}
+
+// Probe connects to the SMB port of the mapping's server and returns how
+// long that took. WebDAV mappings (`\\server@SSL\DavWWWRoot`) are probed
+// on their HTTP port.
+func Probe(m Mapping, timeout time.Duration) (time.Duration, error) {
+	host, port := m.Server(), "445"
+	if name, suffix, ok := strings.Cut(host, "@"); ok {
+		host, port = name, "80"
+		for _, part := range strings.Split(suffix, "@") {
+			if strings.EqualFold(part, "SSL") {
+				port = "443"
+			} else if _, err := strconv.Atoi(part); err == nil {
+				port = part
+			}
+		}
+	}
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), timeout)
+	if err != nil {
+		return 0, err
+	}
+	conn.Close()
+	return time.Since(start), nil
+}
+
+// Connect maps local (a drive letter, or "" for a connection without one)
+// to remote. An empty user signs in with the current credentials or the
+// ones saved in Credential Manager. persistent mappings are restored at
+// every sign-in.
+func Connect(local, remote, user, password string, persistent bool) error {
+	nr := netResource{typ: resourcetypeDisk}
+	var err error
+	if local != "" {
+		if nr.localName, err = windows.UTF16PtrFromString(local); err != nil {
+			return err
+		}
+	}
+	if nr.remoteName, err = windows.UTF16PtrFromString(remote); err != nil {
+		return err
+	}
+	var userPtr, passwordPtr *uint16
+	if user != "" {
+		if userPtr, err = windows.UTF16PtrFromString(user); err != nil {
+			return err
+		}
+		if passwordPtr, err = windows.UTF16PtrFromString(password); err != nil {
+			return err
+		}
+	}
+	var flags uintptr
+	if persistent {
+		flags = connectUpdateProfile
+	}
+	r, _, _ := procWNetAddConnection2.Call(uintptr(unsafe.Pointer(&nr)), uintptr(unsafe.Pointer(passwordPtr)),
+		uintptr(unsafe.Pointer(userPtr)), flags)
+	return connectError(r)
+}
+
+// Disconnect removes the connection of local (a drive letter or a remote
+// path), closing any files open on it. forget also drops a persistent
+// mapping so it is not restored at the next sign-in. A mapping that is
+// only remembered is not an error.
+func Disconnect(local string, forget bool) error {
+	name, err := windows.UTF16PtrFromString(local)
+	if err != nil {
+		return err
+	}
+	var flags uintptr
+	if forget {
+		flags = connectUpdateProfile
+	}
+	r, _, _ := procWNetCancelConnection2.Call(uintptr(unsafe.Pointer(name)), flags, 1)
+	if r == errorNotConnected {
+		return nil
+	}
+	return connectError(r)
+}
+
+// Reconnect drops m's connection and makes it again, with the same
+// persistence, which clears a mapping Explorer shows as unavailable once
+// the server is back.
+func Reconnect(m Mapping) error {
+	return Remap(m, m.Remote)
+}
+
+// Remap points m's drive letter at remote instead.
+func Remap(m Mapping, remote string) error {
+	if err := Disconnect(m.Local, m.Persistent); err != nil {
+		return err
+	}
+	return Connect(m.Local, remote, "", "", m.Persistent)
+}
+
+// ErrCredentials is returned when the server rejected the credentials.
+var ErrCredentials = errors.New("the server rejected the credentials")
+
+func connectError(r uintptr) error {
+	switch r {
+	case 0:
+		return nil
+	case uintptr(windows.ERROR_LOGON_FAILURE), uintptr(windows.ERROR_ACCESS_DENIED):
+		return ErrCredentials
+	case errorBadNetPath:
+		return errors.New("the server cannot be reached")
+	case errorBadNetName:
+		return errors.New("the server has no such share")
+	case errorSessionConflict:
+		return errors.New("this server is already connected under other credentials")
+	}
+	return windows.Errno(r)
+}
@@ -0,0 +1,271 @@
+//go:build windows
+
+// Package otlp exports metrics and traces to an OpenTelemetry collector,
+// or any backend that takes OTLP over HTTP, so WinMole's figures land
+// where the rest of the monitoring already is. It is off unless an
+// endpoint is set in the "otlp" section of config.json or in the standard
+// OTEL_EXPORTER_OTLP_ENDPOINT variable.
+//
+// Payloads use the JSON encoding of OTLP/HTTP and are POSTed to
+// <endpoint>/v1/metrics and <endpoint>/v1/traces, so no SDK is needed:
+//
+//	POST /v1/traces HTTP/1.1
+//	Content-Type: application/json
+//
+//	{"resourceSpans": [{"resource": {...}, "scopeSpans": [{"scope": {...}, "spans": [...]}]}]}
+//
+// Unlike the batches for the WinMole server (see internal/upload), nothing
+// is kept for later when the collector cannot be reached.
+package otlp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/winmole/winmole/internal/config"
+)
+
+// Key is the section of config.json with the exporter settings.
+const Key = "otlp"
+
+// The standard variables, which override config.json.
+const (
+	EndpointEnv = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	HeadersEnv  = "OTEL_EXPORTER_OTLP_HEADERS"
+)
+
+// ServiceName is the service.name of everything exported.
+const ServiceName = "winmole"
+
+// Settings is the otlp section.
+type Settings struct {
+	// Endpoint is the collector's base URL, such as
+	// http://collector:4318; the signal's path is appended.
+	Endpoint string `json:"endpoint"`
+
+	// Headers are sent with every request, for backends that want an
+	// API key.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// ErrNotConfigured is returned when no endpoint is set.
+var ErrNotConfigured = errors.New("no OTLP endpoint set: add \"otlp\": {\"endpoint\": \"http://collector:4318\"} to config.json or set " + EndpointEnv)
+
+// Load reads the exporter settings. OTEL_EXPORTER_OTLP_ENDPOINT and
+// OTEL_EXPORTER_OTLP_HEADERS (key=value,key=value) override them.
+func Load() (Settings, error) {
+	var s Settings
+	err := config.Load(Key, &s)
+	if endpoint := os.Getenv(EndpointEnv); endpoint != "" {
+		s.Endpoint = endpoint
+	}
+	if headers := os.Getenv(HeadersEnv); headers != "" {
+		if s.Headers == nil {
+			s.Headers = map[string]string{}
+		}
+		for _, pair := range strings.Split(headers, ",") {
+			if k, v, ok := strings.Cut(pair, "="); ok {
+				k, _ = url.QueryUnescape(strings.TrimSpace(k))
+				v, _ = url.QueryUnescape(strings.TrimSpace(v))
+				s.Headers[k] = v
+			}
+		}
+	}
+	s.Endpoint = strings.TrimRight(strings.TrimSpace(s.Endpoint), "/")
+	return s, err
+}
+
+// Configured reports whether there is somewhere to export to.
+func (s Settings) Configured() bool {
+	return s.Endpoint != ""
+}
+
+// Point is one value of a gauge.
+type Point struct {
+	At    time.Time
+	Value float64
+	Attrs map[string]any
+}
+
+// Gauge is a metric of sampled values, such as CPU use.
+type Gauge struct {
+	Name        string
+	Unit        string // UCUM, such as "By" or "1"
+	Description string
+	Points      []Point
+}
+
+// Span is one timed operation of a trace.
+type Span struct {
+	TraceID  TraceID
+	ID       SpanID
+	Parent   SpanID // zero for the root
+	Name     string
+	Start    time.Time
+	End      time.Time
+	Attrs    map[string]any
+	ErrorMsg string // set when the operation failed
+}
+
+// TraceID and SpanID identify a trace and a span in it.
+type (
+	TraceID [16]byte
+	SpanID  [8]byte
+)
+
+// NewTraceID returns a random trace ID.
+func NewTraceID() TraceID {
+	var id TraceID
+	rand.Read(id[:])
+	return id
+}
+
+// NewSpanID returns a random span ID.
+func NewSpanID() SpanID {
+	var id SpanID
+	rand.Read(id[:])
+	return id
+}
+
+// ExportGauges sends gauges reported by scope ("winmole/status").
+func (s Settings) ExportGauges(scope string, gauges []Gauge) error {
+	var metrics []any
+	for _, g := range gauges {
+		var points []any
+		for _, p := range g.Points {
+			points = append(points, map[string]any{
+				"timeUnixNano": nanos(p.At),
+				"asDouble":     p.Value,
+				"attributes":   attributes(p.Attrs),
+			})
+		}
+		metrics = append(metrics, map[string]any{
+			"name":        g.Name,
+			"unit":        g.Unit,
+			"description": g.Description,
+			"gauge":       map[string]any{"dataPoints": points},
+		})
+	}
+	return s.post("/v1/metrics", map[string]any{
+		"resourceMetrics": []any{map[string]any{
+			"resource":     resource(),
+			"scopeMetrics": []any{map[string]any{"scope": map[string]any{"name": scope}, "metrics": metrics}},
+		}},
+	})
+}
+
+// ExportSpans sends spans reported by scope ("winmole/analyze").
+func (s Settings) ExportSpans(scope string, spans []Span) error {
+	var list []any
+	for _, sp := range spans {
+		span := map[string]any{
+			"traceId":           hex.EncodeToString(sp.TraceID[:]),
+			"spanId":            hex.EncodeToString(sp.ID[:]),
+			"name":              sp.Name,
+			"kind":              1, // SPAN_KIND_INTERNAL
+			"startTimeUnixNano": nanos(sp.Start),
+			"endTimeUnixNano":   nanos(sp.End),
+			"attributes":        attributes(sp.Attrs),
+		}
+		if sp.Parent != (SpanID{}) {
+			span["parentSpanId"] = hex.EncodeToString(sp.Parent[:])
+		}
+		if sp.ErrorMsg != "" {
+			span["status"] = map[string]any{"code": 2, "message": sp.ErrorMsg} // STATUS_CODE_ERROR
+		}
+		list = append(list, span)
+	}
+	return s.post("/v1/traces", map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource":   resource(),
+			"scopeSpans": []any{map[string]any{"scope": map[string]any{"name": scope}, "spans": list}},
+		}},
+	})
+}
+
+// resource describes this machine.
+func resource() map[string]any {
+	host, _ := os.Hostname()
+	return map[string]any{"attributes": attributes(map[string]any{
+		"service.name": ServiceName,
+		"host.name":    host,
+		"os.type":      "windows",
+	})}
+}
+
+// nanos is a time as OTLP JSON has it: nanoseconds since 1970, as a
+// string since JSON numbers cannot hold them exactly.
+func nanos(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// attributes turns attrs into OTLP key-value pairs.
+func attributes(attrs map[string]any) []any {
+	list := []any{}
+	for k, v := range attrs {
+		var value map[string]any
+		switch v := v.(type) {
+		case string:
+			value = map[string]any{"stringValue": v}
+		case bool:
+			value = map[string]any{"boolValue": v}
+		case int:
+			value = map[string]any{"intValue": strconv.Itoa(v)}
+		case int64:
+			value = map[string]any{"intValue": strconv.FormatInt(v, 10)}
+		case uint64:
+			value = map[string]any{"intValue": strconv.FormatUint(v, 10)}
+		case float64:
+			value = map[string]any{"doubleValue": v}
+		default:
+			value = map[string]any{"stringValue": fmt.Sprint(v)}
+		}
+		list = append(list, map[string]any{"key": k, "value": value})
+	}
+	return list
+}
+
+// post sends one payload.
+func (s Settings) post(path string, payload any) error {
+	if !s.Configured() {
+		return ErrNotConfigured
+	}
+	endpoint, err := url.Parse(s.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("OTLP endpoint %q is not an http or https URL", s.Endpoint)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.Endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.Headers {
+		req.Header.Set(k, v)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send to %s: %w", endpoint.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s answered %s: %s", endpoint.Host, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
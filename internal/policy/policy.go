@@ -0,0 +1,142 @@
+//go:build windows
+
+// Package policy reads centrally managed settings that IT departments push
+// through Group Policy to HKLM\Software\Policies\WinMole. Policy values take
+// precedence over config.json and cannot be changed from the tools.
+//
+// Values under the key:
+//
+//	DisabledCommands     REG_MULTI_SZ  commands that refuse to run ("clean", "purge", ...)
+//	DisableFileDeletion  REG_DWORD     1 turns off deleting from the analyzer
+//	ExcludedPaths        REG_MULTI_SZ  paths never cleaned or deleted; wildcards and %VARS% allowed
+//	AgentEndpoint        REG_SZ        URL of the management agent endpoint
+package policy
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// KeyPath is the policy key under HKEY_LOCAL_MACHINE.
+const KeyPath = `Software\Policies\WinMole`
+
+// DisabledMessage is shown when a policy blocks an action.
+const DisabledMessage = "disabled by your administrator (Group Policy)"
+
+// Policy is the effective machine policy. The zero value means unmanaged.
+type Policy struct {
+	Managed             bool
+	DisabledCommands    []string
+	DisableFileDeletion bool
+	ExcludedPaths       []string
+	AgentEndpoint       string
+}
+
+var (
+	loadOnce sync.Once
+	current  Policy
+)
+
+// Get returns the machine policy, read once per process.
+func Get() Policy {
+	loadOnce.Do(func() {
+		current = read()
+	})
+	return current
+}
+
+func read() Policy {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, KeyPath, registry.QUERY_VALUE)
+	if err != nil {
+		return Policy{}
+	}
+	defer k.Close()
+
+	p := Policy{Managed: true}
+	if v, _, err := k.GetStringsValue("DisabledCommands"); err == nil {
+		for _, c := range v {
+			if c = strings.TrimSpace(c); c != "" {
+				p.DisabledCommands = append(p.DisabledCommands, strings.ToLower(c))
+			}
+		}
+	}
+	if v, _, err := k.GetIntegerValue("DisableFileDeletion"); err == nil {
+		p.DisableFileDeletion = v != 0
+	}
+	if v, _, err := k.GetStringsValue("ExcludedPaths"); err == nil {
+		for _, path := range v {
+			if path = strings.TrimSpace(path); path != "" {
+				if expanded, err := registry.ExpandString(path); err == nil {
+					path = expanded
+				}
+				p.ExcludedPaths = append(p.ExcludedPaths, path)
+			}
+		}
+	}
+	if v, _, err := k.GetStringValue("AgentEndpoint"); err == nil {
+		p.AgentEndpoint = strings.TrimSpace(v)
+	}
+	return p
+}
+
+// CommandDisabled reports whether the named command is turned off.
+func (p Policy) CommandDisabled(name string) bool {
+	name = strings.ToLower(name)
+	for _, c := range p.DisabledCommands {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Excluded reports whether removing path would touch a mandatory exclusion:
+// path or a folder containing it matches one, or an excluded path lies
+// inside it. Patterns use PowerShell -like wildcards (* and ?) and match
+// case-insensitively, as in the cleaners.
+func (p Policy) Excluded(path string) bool {
+	path = filepath.Clean(path)
+	for _, pattern := range p.ExcludedPaths {
+		pattern = filepath.Clean(pattern)
+		literal := pattern
+		if i := strings.IndexAny(pattern, "*?"); i >= 0 {
+			literal = pattern[:i]
+		}
+		if strings.HasPrefix(strings.ToLower(literal), strings.ToLower(strings.TrimSuffix(path, `\`)+`\`)) {
+			return true
+		}
+
+		re := wildcard(pattern)
+		for dir := path; ; dir = filepath.Dir(dir) {
+			if re.MatchString(dir) {
+				return true
+			}
+			if parent := filepath.Dir(dir); parent == dir {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// wildcard compiles a -like pattern into an anchored, case-insensitive regexp.
+func wildcard(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString(`(?i)^`)
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(`.*`)
+		case '?':
+			b.WriteString(`.`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString(`$`)
+	return regexp.MustCompile(b.String())
+}
@@ -0,0 +1,160 @@
+// Package redact masks identifying details (the computer name, user names
+// and IP addresses) in rendered views and exports, so screenshots and
+// reports can be shared publicly. Masks keep the original length so table
+// columns stay aligned.
+package redact
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Shared profile folders under C:\Users that are not user names.
+var sharedProfiles = map[string]bool{
+	"public":         true,
+	"default":        true,
+	"default user":   true,
+	"all users":      true,
+	"defaultapppool": true,
+}
+
+var (
+	ipv4Candidate = regexp.MustCompile(`\d{1,3}(?:\.\d{1,3}){3}`)
+	ansiSuffix    = regexp.MustCompile(`\x1b\[[0-9;]*m$`)
+	ipv6Candidate = regexp.MustCompile(`[0-9A-Fa-f]{0,4}(?::[0-9A-Fa-f]{0,4}){2,7}(?:%\w+)?`)
+)
+
+// Redactor masks identifying strings while enabled.
+type Redactor struct {
+	enabled bool
+	names   *regexp.Regexp // computer and user names, nil if none are known
+}
+
+// New returns a Redactor for the current machine and user.
+func New(enabled bool) *Redactor {
+	var names []string
+	add := func(name string) {
+		if len(name) >= 2 {
+			names = append(names, regexp.QuoteMeta(name))
+		}
+	}
+
+	if host, err := os.Hostname(); err == nil {
+		add(host)
+	}
+	add(os.Getenv("COMPUTERNAME"))
+	add(os.Getenv("USERNAME"))
+	add(os.Getenv("USERDOMAIN"))
+
+	// Every profile folder is a user name that can show up in paths.
+	if home, err := os.UserHomeDir(); err == nil {
+		add(filepath.Base(home))
+		if entries, err := os.ReadDir(filepath.Dir(home)); err == nil {
+			for _, e := range entries {
+				if e.IsDir() && !sharedProfiles[strings.ToLower(e.Name())] {
+					add(e.Name())
+				}
+			}
+		}
+	}
+
+	r := &Redactor{enabled: enabled}
+	if len(names) > 0 {
+		r.names = regexp.MustCompile(`(?i)` + strings.Join(names, "|"))
+	}
+	return r
+}
+
+// Enabled reports whether masking is on.
+func (r *Redactor) Enabled() bool {
+	return r != nil && r.enabled
+}
+
+// Toggle switches masking on or off and returns the new state.
+func (r *Redactor) Toggle() bool {
+	r.enabled = !r.enabled
+	return r.enabled
+}
+
+// String masks s if redaction is enabled.
+func (r *Redactor) String(s string) string {
+	if !r.Enabled() {
+		return s
+	}
+	s = r.maskNames(s)
+	s = ipv4Candidate.ReplaceAllStringFunc(s, maskIP)
+	s = ipv6Candidate.ReplaceAllStringFunc(s, maskIP)
+	return s
+}
+
+// maskNames masks whole-word occurrences of known names, so a user called
+// "ann" does not mask "annual".
+func (r *Redactor) maskNames(s string) string {
+	if r.names == nil {
+		return s
+	}
+	matches := r.names.FindAllStringIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		if !isBoundary(s, m[0], true) || !isBoundary(s, m[1], false) {
+			continue
+		}
+		b.WriteString(s[last:m[0]])
+		b.WriteString(mask(s[m[0]:m[1]]))
+		last = m[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// isBoundary reports whether the rune before (start) or at (end) index i is
+// not part of a word. A terminal color sequence ending before a word counts
+// as a boundary, so styled views can be masked after rendering.
+func isBoundary(s string, i int, start bool) bool {
+	var r rune
+	if start {
+		if i == 0 || ansiSuffix.MatchString(s[:i]) {
+			return true
+		}
+		r, _ = utf8.DecodeLastRuneInString(s[:i])
+	} else {
+		if i == len(s) {
+			return true
+		}
+		r, _ = utf8.DecodeRuneInString(s[i:])
+	}
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+}
+
+// maskIP masks text that parses as an IP address, leaving look-alikes
+// such as clock times untouched.
+func maskIP(s string) string {
+	addr := s
+	if i := strings.IndexByte(addr, '%'); i >= 0 {
+		addr = addr[:i]
+	}
+	if net.ParseIP(addr) == nil {
+		return s
+	}
+	return mask(s)
+}
+
+// mask replaces every rune except separators with '*'.
+func mask(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', ':', '\\', '/':
+			return r
+		}
+		return '*'
+	}, s)
+}
@@ -0,0 +1,65 @@
+package redact
+
+import (
+	"regexp"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestString(t *testing.T) {
+	r := &Redactor{enabled: true, names: regexp.MustCompile(`(?i)alice|WORKPC|ann`)}
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"profile path", `C:\Users\alice\AppData`, `C:\Users\*****\AppData`},
+		{"any case", `Alice's files on workpc`, `*****'s files on ******`},
+		{"whole words only", `annual report by Anna`, `annual report by Anna`},
+		{"underscore is part of a word", `ann_backup`, `ann_backup`},
+		{"after a color sequence", "\x1b[1malice\x1b[0m", "\x1b[1m*****\x1b[0m"},
+		{"IPv4", `from 192.168.1.20 to 10.0.0.1`, `from ***.***.*.** to **.*.*.*`},
+		{"not an IPv4 address", `999.1.1.1 and v1.2.3`, `999.1.1.1 and v1.2.3`},
+		{"IPv6", `fe80::1c2b:3d4e`, `****::****:****`},
+		{"IPv6 loopback", `[::1]:8741`, `[::*]:8741`},
+		{"IPv6 with a zone", `fe80::1%12`, `****::****`},
+		{"clock time", `at 10:30:15`, `at 10:30:15`},
+		{"nothing to mask", `C:\Windows\Temp`, `C:\Windows\Temp`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.String(tt.in)
+			if got != tt.want {
+				t.Errorf("String(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if utf8.RuneCountInString(got) != utf8.RuneCountInString(tt.in) {
+				t.Errorf("String(%q) changed the length to %d", tt.in, utf8.RuneCountInString(got))
+			}
+		})
+	}
+}
+
+func TestStringDisabled(t *testing.T) {
+	in := `C:\Users\alice on 192.168.1.20`
+	for _, r := range []*Redactor{nil, {names: regexp.MustCompile(`alice`)}} {
+		if got := r.String(in); got != in {
+			t.Errorf("disabled: got %q, want %q", got, in)
+		}
+	}
+	r := &Redactor{}
+	if !r.Toggle() || r.String("10.0.0.1") != "**.*.*.*" {
+		t.Error("Toggle did not turn masking on")
+	}
+	if r.Toggle() || r.String("10.0.0.1") != "10.0.0.1" {
+		t.Error("Toggle did not turn masking off")
+	}
+}
+
+func TestNewMasksUserName(t *testing.T) {
+	t.Setenv("USERNAME", "zq-test-user")
+	t.Setenv("COMPUTERNAME", "x") // too short to mask
+	r := New(true)
+	if got, want := r.String(`C:\Users\zq-test-user\x`), `C:\Users\************\x`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,111 @@
+// Package scanprogress lets other processes follow a scan as it runs.
+// While analyze scans without its TUI (--snapshot, --export and --no-tui,
+// so also the nightly schedule), it keeps a small JSON file in the cache
+// directory up to date with how far it got; status --web reads them and
+// streams the progress to the page.
+package scanprogress
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/winmole/winmole/internal/config"
+	"github.com/winmole/winmole/pkg/scan"
+)
+
+// Every is how often a running scan writes its progress.
+const Every = time.Second
+
+// staleAfter is how long a file may go unwritten before its scan counts as
+// gone: one killed before it could clean up.
+const staleAfter = 10 * Every
+
+// Scan is how far one running scan got.
+type Scan struct {
+	Root    string    `json:"root"`
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+	Updated time.Time `json:"updated"`
+	Files   int64     `json:"files"`
+	Dirs    int64     `json:"dirs"`
+	Bytes   int64     `json:"bytes"`
+}
+
+// Dir is where the progress files are kept.
+func Dir() string {
+	return filepath.Join(config.CacheDir(), "scanning")
+}
+
+// Publish writes the counters of the scan of root every second until the
+// returned function is called, which removes the file. Failing to write
+// only means no one can follow the scan, so errors are ignored.
+func Publish(root string, c *scan.Counters) (stop func()) {
+	if err := os.MkdirAll(Dir(), 0o755); err != nil {
+		return func() {}
+	}
+	file := filepath.Join(Dir(), strconv.Itoa(os.Getpid())+".json")
+	s := Scan{Root: root, PID: os.Getpid(), Started: time.Now()}
+	write := func() {
+		s.Updated = time.Now()
+		s.Files, s.Dirs, s.Bytes = c.Files.Load(), c.Dirs.Load(), c.Bytes.Load()
+		b, err := json.Marshal(s)
+		if err != nil {
+			return
+		}
+		tmp := file + ".tmp"
+		if os.WriteFile(tmp, b, 0o644) == nil {
+			os.Rename(tmp, file)
+		}
+	}
+	write()
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		t := time.NewTicker(Every)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				write()
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+		os.Remove(file)
+	}
+}
+
+// Running returns the scans in progress, by root. Files left by scans
+// that died are removed.
+func Running() []Scan {
+	files, _ := filepath.Glob(filepath.Join(Dir(), "*.json"))
+	var list []Scan
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			// Being replaced right now.
+			continue
+		}
+		var s Scan
+		if json.Unmarshal(b, &s) != nil || time.Since(s.Updated) > staleAfter {
+			os.Remove(file)
+			continue
+		}
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return strings.ToLower(list[i].Root) < strings.ToLower(list[j].Root) })
+	return list
+}
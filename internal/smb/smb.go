@@ -0,0 +1,254 @@
+//go:build windows
+
+// Package smb lists what this machine's file server exposes and who is
+// using it: shared folders, the sessions of clients connected to them and
+// the files they hold open. Sessions and open files can only be read by
+// administrators, so the status monitor reads them through the elevated
+// helper.
+package smb
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	netapi32           = windows.NewLazySystemDLL("netapi32.dll")
+	procNetShareEnum   = netapi32.NewProc("NetShareEnum")
+	procNetSessionEnum = netapi32.NewProc("NetSessionEnum")
+	procNetFileEnum    = netapi32.NewProc("NetFileEnum")
+	procNetFileClose   = netapi32.NewProc("NetFileClose")
+)
+
+// lmshare.h and lmaccess.h constants.
+const (
+	maxPreferredLength   = 0xFFFFFFFF
+	errorMoreData        = 234
+	nerrServerNotStarted = 2114
+	nerrFileIDNotFound   = 2314
+
+	stypeMask    = 0xFF
+	stypeDisk    = 0
+	stypePrint   = 1
+	stypeDevice  = 2
+	stypeIPC     = 3
+	stypeSpecial = 0x80000000
+
+	permFileWrite = 0x2
+)
+
+// Share is a shared folder, printer or pipe.
+type Share struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Remark  string `json:"remark"`
+	Kind    string `json:"kind"`    // "disk", "printer", "device" or "ipc"
+	Special bool   `json:"special"` // an administrative share such as C$ or ADMIN$
+	Users   int    `json:"users"`   // current connections
+}
+
+// Session is a client connected to this machine's file server.
+type Session struct {
+	Client string        `json:"client"` // the client's computer name or address
+	User   string        `json:"user"`
+	Opens  int           `json:"opens"`
+	Time   time.Duration `json:"time"` // connected for
+	Idle   time.Duration `json:"idle"`
+}
+
+// OpenFile is a file a client holds open through a share.
+type OpenFile struct {
+	ID    uint32 `json:"id"`
+	Path  string `json:"path"`
+	User  string `json:"user"`
+	Locks int    `json:"locks"`
+	Write bool   `json:"write"`
+}
+
+// Status is everything the file server reports at once.
+type Status struct {
+	Shares   []Share    `json:"shares"`
+	Sessions []Session  `json:"sessions"`
+	Files    []OpenFile `json:"files"`
+}
+
+// shareInfo2 mirrors SHARE_INFO_2.
+type shareInfo2 struct {
+	netname     *uint16
+	typ         uint32
+	remark      *uint16
+	permissions uint32
+	maxUses     uint32
+	currentUses uint32
+	path        *uint16
+	passwd      *uint16
+}
+
+// sessionInfo502 mirrors SESSION_INFO_502.
+type sessionInfo502 struct {
+	cname      *uint16
+	username   *uint16
+	numOpens   uint32
+	time       uint32
+	idleTime   uint32
+	userFlags  uint32
+	cltypeName *uint16
+	transport  *uint16
+}
+
+// fileInfo3 mirrors FILE_INFO_3.
+type fileInfo3 struct {
+	id          uint32
+	permissions uint32
+	numLocks    uint32
+	pathname    *uint16
+	username    *uint16
+}
+
+// Read returns the shares, sessions and open files. It needs admin rights.
+func Read() (Status, error) {
+	var s Status
+	var err error
+	if s.Shares, err = shares(); err != nil {
+		return s, err
+	}
+	if s.Sessions, err = sessions(); err != nil {
+		return s, err
+	}
+	s.Files, err = openFiles()
+	return s, err
+}
+
+func shares() ([]Share, error) {
+	var list []Share
+	err := enumerate(func(buf **byte, read, total, resume *uint32) uintptr {
+		r, _, _ := procNetShareEnum.Call(0, 2, uintptr(unsafe.Pointer(buf)), maxPreferredLength,
+			uintptr(unsafe.Pointer(read)), uintptr(unsafe.Pointer(total)), uintptr(unsafe.Pointer(resume)))
+		return r
+	}, func(buf *byte, n uint32) {
+		for _, si := range unsafe.Slice((*shareInfo2)(unsafe.Pointer(buf)), n) {
+			list = append(list, Share{
+				Name:    windows.UTF16PtrToString(si.netname),
+				Path:    windows.UTF16PtrToString(si.path),
+				Remark:  windows.UTF16PtrToString(si.remark),
+				Kind:    shareKind(si.typ),
+				Special: si.typ&stypeSpecial != 0,
+				Users:   int(si.currentUses),
+			})
+		}
+	})
+	return list, err
+}
+
+func shareKind(typ uint32) string {
+	switch typ & stypeMask {
+	case stypeDisk:
+		return "disk"
+	case stypePrint:
+		return "printer"
+	case stypeDevice:
+		return "device"
+	case stypeIPC:
+		return "ipc"
+	}
+	return "other"
+}
+
+func sessions() ([]Session, error) {
+	var list []Session
+	err := enumerate(func(buf **byte, read, total, resume *uint32) uintptr {
+		r, _, _ := procNetSessionEnum.Call(0, 0, 0, 502, uintptr(unsafe.Pointer(buf)), maxPreferredLength,
+			uintptr(unsafe.Pointer(read)), uintptr(unsafe.Pointer(total)), uintptr(unsafe.Pointer(resume)))
+		return r
+	}, func(buf *byte, n uint32) {
+		for _, si := range unsafe.Slice((*sessionInfo502)(unsafe.Pointer(buf)), n) {
+			list = append(list, Session{
+				Client: windows.UTF16PtrToString(si.cname),
+				User:   windows.UTF16PtrToString(si.username),
+				Opens:  int(si.numOpens),
+				Time:   time.Duration(si.time) * time.Second,
+				Idle:   time.Duration(si.idleTime) * time.Second,
+			})
+		}
+	})
+	return list, err
+}
+
+func openFiles() ([]OpenFile, error) {
+	var list []OpenFile
+	err := enumerate(func(buf **byte, read, total, resume *uint32) uintptr {
+		// The resume handle is a DWORD_PTR here.
+		handle := uintptr(*resume)
+		r, _, _ := procNetFileEnum.Call(0, 0, 0, 3, uintptr(unsafe.Pointer(buf)), maxPreferredLength,
+			uintptr(unsafe.Pointer(read)), uintptr(unsafe.Pointer(total)), uintptr(unsafe.Pointer(&handle)))
+		*resume = uint32(handle)
+		return r
+	}, func(buf *byte, n uint32) {
+		for _, fi := range unsafe.Slice((*fileInfo3)(unsafe.Pointer(buf)), n) {
+			list = append(list, OpenFile{
+				ID:    fi.id,
+				Path:  windows.UTF16PtrToString(fi.pathname),
+				User:  windows.UTF16PtrToString(fi.username),
+				Locks: int(fi.numLocks),
+				Write: fi.permissions&permFileWrite != 0,
+			})
+		}
+	})
+	return list, err
+}
+
+// enumerate runs a Net*Enum call until it has returned every entry,
+// passing each buffer to collect before freeing it.
+func enumerate(call func(buf **byte, read, total, resume *uint32) uintptr, collect func(buf *byte, n uint32)) error {
+	var resume uint32
+	for {
+		var buf *byte
+		var read, total uint32
+		r := call(&buf, &read, &total, &resume)
+		if r != 0 && r != errorMoreData {
+			return netError(r)
+		}
+		if buf != nil {
+			collect(buf, read)
+			windows.NetApiBufferFree(buf)
+		}
+		if r != errorMoreData {
+			return nil
+		}
+	}
+}
+
+// ErrFileNotOpen is returned by CloseFile when the file was closed since
+// it was listed.
+var ErrFileNotOpen = errors.New("the file is no longer open")
+
+// CloseFile closes a file a client holds open, releasing its locks. The
+// client loses any changes it has not saved. It needs admin rights.
+func CloseFile(id uint32) error {
+	r, _, _ := procNetFileClose.Call(0, uintptr(id))
+	switch r {
+	case 0:
+		return nil
+	case nerrFileIDNotFound:
+		return ErrFileNotOpen
+	}
+	return netError(r)
+}
+
+// ErrNoServer is returned when the Server service is not running, so
+// nothing is shared.
+var ErrNoServer = errors.New("the Server service is not running, so nothing is shared")
+
+func netError(r uintptr) error {
+	switch r {
+	case uintptr(windows.ERROR_ACCESS_DENIED):
+		return errors.New("access denied; this needs admin rights")
+	case nerrServerNotStarted:
+		return ErrNoServer
+	}
+	return fmt.Errorf("network API error %d: %w", r, windows.Errno(r))
+}
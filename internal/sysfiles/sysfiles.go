@@ -0,0 +1,182 @@
+//go:build windows
+
+// Package sysfiles reports the files Windows keeps at the root of drives
+// for its own memory management: the paging file (pagefile.sys), the
+// hibernation file (hiberfil.sys) and the swap file for suspended Store
+// apps (swapfile.sys). They are held open while Windows runs, so they
+// cannot be read or deleted, only resized or turned off through Windows'
+// own settings; Read says how they are set up so the analyzer can tell
+// what each setting would free.
+package sysfiles
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yusufpapurcu/wmi"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// Kinds of file.
+const (
+	KindPaging      = "paging"
+	KindHibernation = "hibernation"
+	KindSwap        = "swap"
+)
+
+// names maps each file's lower-case name to its kind.
+var names = map[string]string{
+	"pagefile.sys": KindPaging,
+	"hiberfil.sys": KindHibernation,
+	"swapfile.sys": KindSwap,
+}
+
+// Kind returns the kind of the file at path when it is one of the files
+// Read reports, at the root of a drive, and "" otherwise.
+func Kind(path string) string {
+	dir, name := filepath.Split(path)
+	if vol := filepath.VolumeName(dir); !strings.EqualFold(dir, vol+`\`) {
+		return ""
+	}
+	return names[strings.ToLower(name)]
+}
+
+// File is one of the files on a drive.
+type File struct {
+	Path string
+	Kind string
+	Size int64
+}
+
+// PagingFile is how one paging file is used, from Win32_PageFileUsage.
+type PagingFile struct {
+	Path      string
+	Allocated int64 // bytes
+	Current   int64 // in use now
+	Peak      int64 // most in use since boot
+}
+
+// Report is how the files are set up.
+type Report struct {
+	Files  []File
+	Paging []PagingFile
+	// AutomaticPaging is "Automatically manage paging file size for all
+	// drives" in System Properties.
+	AutomaticPaging bool
+	Memory          int64 // installed RAM, bytes
+
+	Hibernation bool // hibernation is on
+	// ReducedHibernation is the reduced hibernation file, which only
+	// serves Fast Startup and is about half the size of the full one.
+	ReducedHibernation bool
+	FastStartup        bool
+}
+
+type win32PageFileUsage struct {
+	Name              string
+	AllocatedBaseSize uint32 // MB
+	CurrentUsage      uint32 // MB
+	PeakUsage         uint32 // MB
+}
+
+type win32ComputerSystem struct {
+	AutomaticManagedPagefile bool
+	TotalPhysicalMemory      uint64
+}
+
+// Read finds the files on every fixed drive and reads their settings. It
+// needs no admin rights.
+func Read() (*Report, error) {
+	r := &Report{}
+	for _, root := range fixedDrives() {
+		for name, kind := range names {
+			path := filepath.Join(root, name)
+			// Stat falls back to the directory entry for a file that is
+			// open without sharing.
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				r.Files = append(r.Files, File{Path: path, Kind: kind, Size: info.Size()})
+			}
+		}
+	}
+
+	sort.Slice(r.Files, func(i, j int) bool { return r.Files[i].Path < r.Files[j].Path })
+
+	var usage []win32PageFileUsage
+	if err := wmi.Query("SELECT Name, AllocatedBaseSize, CurrentUsage, PeakUsage FROM Win32_PageFileUsage", &usage); err != nil {
+		return nil, err
+	}
+	for _, u := range usage {
+		r.Paging = append(r.Paging, PagingFile{
+			Path:      u.Name,
+			Allocated: int64(u.AllocatedBaseSize) << 20,
+			Current:   int64(u.CurrentUsage) << 20,
+			Peak:      int64(u.PeakUsage) << 20,
+		})
+	}
+	var system []win32ComputerSystem
+	if err := wmi.Query("SELECT AutomaticManagedPagefile, TotalPhysicalMemory FROM Win32_ComputerSystem", &system); err != nil {
+		return nil, err
+	}
+	if len(system) > 0 {
+		r.AutomaticPaging = system[0].AutomaticManagedPagefile
+		r.Memory = int64(system[0].TotalPhysicalMemory)
+	}
+
+	if k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control\Power`, registry.QUERY_VALUE); err == nil {
+		if v, _, err := k.GetIntegerValue("HibernateEnabled"); err == nil {
+			r.Hibernation = v != 0
+		}
+		// HiberFileType: 1 reduced, 2 full.
+		if v, _, err := k.GetIntegerValue("HiberFileType"); err == nil {
+			r.ReducedHibernation = v == 1
+		}
+		k.Close()
+	}
+	if k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control\Session Manager\Power`, registry.QUERY_VALUE); err == nil {
+		if v, _, err := k.GetIntegerValue("HiberbootEnabled"); err == nil {
+			r.FastStartup = v != 0 && r.Hibernation
+		}
+		k.Close()
+	}
+	return r, nil
+}
+
+// Size is the combined size of the files of kind.
+func (r *Report) Size(kind string) int64 {
+	var size int64
+	for _, f := range r.Files {
+		if f.Kind == kind {
+			size += f.Size
+		}
+	}
+	return size
+}
+
+// ReducedSize estimates the reduced hibernation file: a fifth of RAM,
+// where the full one defaults to two fifths.
+func (r *Report) ReducedSize() int64 {
+	return r.Memory / 5
+}
+
+// fixedDrives lists the roots of local fixed drives, such as `C:\`.
+func fixedDrives() []string {
+	var roots []string
+	mask, err := windows.GetLogicalDrives()
+	if err != nil {
+		return nil
+	}
+	for i := 0; i < 26; i++ {
+		if mask&(1<<i) == 0 {
+			continue
+		}
+		root := string(rune('A'+i)) + `:\`
+		p, _ := windows.UTF16PtrFromString(root)
+		if windows.GetDriveType(p) == windows.DRIVE_FIXED {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
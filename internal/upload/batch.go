@@ -0,0 +1,62 @@
+//go:build windows
+
+package upload
+
+import "time"
+
+// ScanDiff is the data of a scan-diff batch: what changed below Root
+// since its previous snapshot.
+type ScanDiff struct {
+	Root      string       `json:"root"`
+	ScannedAt time.Time    `json:"scannedAt"`
+	TotalSize int64        `json:"totalSize"`
+	Since     *time.Time   `json:"since,omitempty"` // the previous scan; absent for a first scan
+	SinceSize int64        `json:"sinceSize"`
+	Changes   []ScanChange `json:"changes"`
+	Truncated bool         `json:"truncated,omitempty"`
+}
+
+// ScanChange is one file or folder that grew, shrank, appeared or went.
+type ScanChange struct {
+	Path   string `json:"path"`
+	Before int64  `json:"before"`
+	After  int64  `json:"after"`
+	IsDir  bool   `json:"isDir"`
+	Status string `json:"status,omitempty"` // "new", "gone" or ""
+}
+
+// MetricBatch is the data of a metrics batch.
+type MetricBatch struct {
+	IntervalSeconds float64  `json:"intervalSeconds"`
+	Samples         []Sample `json:"samples"`
+}
+
+// Sample is one status sample: the rates and percentages plotted over
+// time, and what the dashboard cards show.
+type Sample struct {
+	At        time.Time `json:"at"`
+	CPU       float64   `json:"cpuPercent"`
+	Mem       float64   `json:"memPercent"`
+	DiskRead  float64   `json:"diskReadRate"`
+	DiskWrite float64   `json:"diskWriteRate"`
+	NetRecv   float64   `json:"netRecvRate"`
+	NetSent   float64   `json:"netSentRate"`
+	Disks     []Disk    `json:"disks"`
+
+	CPUModel       string `json:"cpuModel,omitempty"`
+	CPUCores       int    `json:"cpuCores,omitempty"`
+	MemTotal       uint64 `json:"memTotal,omitempty"`
+	MemInUse       uint64 `json:"memInUse,omitempty"`
+	MemCommitted   uint64 `json:"memCommitted,omitempty"`
+	MemCommitLimit uint64 `json:"memCommitLimit,omitempty"`
+	MemCached      uint64 `json:"memCached,omitempty"`
+	MemInstalled   uint64 `json:"memInstalled,omitempty"`
+	SystemDrive    string `json:"systemDrive,omitempty"`
+}
+
+// Disk is the space on one drive.
+type Disk struct {
+	Mount string `json:"mount"`
+	Total uint64 `json:"total"`
+	Used  uint64 `json:"used"`
+}
@@ -0,0 +1,209 @@
+//go:build windows
+
+// Package upload sends scan diffs and metric batches to a central WinMole
+// server, so the growth of a small fleet can be followed there without a
+// remote session to each machine. It is off unless a server is set in the
+// "server" section of config.json or by the AgentEndpoint policy.
+//
+// Each batch is one gzip-compressed JSON envelope, POSTed to the server's
+// /api/v1/<kind> with the token as a bearer token:
+//
+//	POST /api/v1/scan-diff HTTP/1.1
+//	Authorization: Bearer <token>
+//	Content-Type: application/json
+//	Content-Encoding: gzip
+//
+//	{"version": 1, "machine": "PC-042", "kind": "scan-diff", "createdAt": "...", "data": {...}}
+//
+// Batches are written to an outbox in the cache directory before they are
+// sent and deleted once the server accepts them, so a run while the server
+// is down or the laptop is offline is sent with the next one, oldest first.
+package upload
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/winmole/winmole/internal/config"
+	"github.com/winmole/winmole/internal/policy"
+)
+
+// Key is the section of config.json with the server settings.
+const Key = "server"
+
+// TokenEnv overrides the token in config.json, for machines where the
+// settings file is shared or synced.
+const TokenEnv = "WINMOLE_SERVER_TOKEN"
+
+// Kinds of batch.
+const (
+	KindScanDiff = "scan-diff" // what changed since the previous scan of a folder
+	KindMetrics  = "metrics"   // a run of status samples
+)
+
+// Version is the envelope format version.
+const Version = 1
+
+// Outbox limits: beyond them the oldest batches are dropped unsent.
+const (
+	maxQueued = 500
+	maxAge    = 30 * 24 * time.Hour
+)
+
+// Settings is the server section.
+type Settings struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// Envelope wraps every batch.
+type Envelope struct {
+	Version   int             `json:"version"`
+	Machine   string          `json:"machine"`
+	Kind      string          `json:"kind"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// ErrNotConfigured is returned when no server is set.
+var ErrNotConfigured = errors.New("no server set: add \"server\": {\"url\": ..., \"token\": ...} to config.json")
+
+// Load reads the server settings. The AgentEndpoint policy overrides the
+// URL and WINMOLE_SERVER_TOKEN the token.
+func Load() (Settings, error) {
+	var s Settings
+	err := config.Load(Key, &s)
+	if p := policy.Get(); p.AgentEndpoint != "" {
+		s.URL = p.AgentEndpoint
+	}
+	if token := os.Getenv(TokenEnv); token != "" {
+		s.Token = token
+	}
+	s.URL = strings.TrimRight(strings.TrimSpace(s.URL), "/")
+	return s, err
+}
+
+// Configured reports whether batches have somewhere to go.
+func (s Settings) Configured() bool {
+	return s.URL != ""
+}
+
+// Queue writes a batch of data to the outbox.
+func Queue(kind string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", kind, err)
+	}
+	machine, _ := os.Hostname()
+	env := Envelope{Version: Version, Machine: machine, Kind: kind, CreatedAt: time.Now().UTC(), Data: raw}
+	body, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", kind, err)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(body)
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("compress %s: %w", kind, err)
+	}
+	dir := outboxDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create outbox: %w", err)
+	}
+	name := fmt.Sprintf("%s_%s.json.gz", env.CreatedAt.Format("20060102-150405.000000000"), kind)
+	return os.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0o644)
+}
+
+// Flush sends the queued batches to the server, oldest first, and returns
+// how many it accepted. It stops at the first batch the server does not
+// answer or refuses for a reason other than the batch itself, leaving the
+// rest for next time; a batch the server rejects as malformed is dropped.
+func Flush(s Settings) (sent int, err error) {
+	if !s.Configured() {
+		return 0, ErrNotConfigured
+	}
+	endpoint, err := url.Parse(s.URL)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return 0, fmt.Errorf("server url %q is not an http or https URL", s.URL)
+	}
+
+	files := queued()
+	client := &http.Client{Timeout: 60 * time.Second}
+	for _, file := range files {
+		body, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		// The kind is the part of the name after the timestamp.
+		_, kind, _ := strings.Cut(strings.TrimSuffix(filepath.Base(file), ".json.gz"), "_")
+
+		req, err := http.NewRequest(http.MethodPost, s.URL+"/api/v1/"+kind, bytes.NewReader(body))
+		if err != nil {
+			return sent, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+		if s.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+s.Token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return sent, fmt.Errorf("send to %s: %w", endpoint.Host, err)
+		}
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		switch {
+		case resp.StatusCode/100 == 2:
+			os.Remove(file)
+			sent++
+		case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusRequestEntityTooLarge ||
+			resp.StatusCode == http.StatusUnprocessableEntity:
+			os.Remove(file)
+		default:
+			return sent, fmt.Errorf("%s answered %s: %s", endpoint.Host, resp.Status, strings.TrimSpace(string(msg)))
+		}
+	}
+	return sent, nil
+}
+
+// Pending returns how many batches wait in the outbox.
+func Pending() int {
+	return len(queued())
+}
+
+func outboxDir() string {
+	return filepath.Join(config.CacheDir(), "outbox")
+}
+
+// queued lists the outbox oldest first, dropping batches past its limits.
+func queued() []string {
+	files, _ := filepath.Glob(filepath.Join(outboxDir(), "*.json.gz"))
+	sort.Strings(files)
+	if len(files) > maxQueued {
+		for _, file := range files[:len(files)-maxQueued] {
+			os.Remove(file)
+		}
+		files = files[len(files)-maxQueued:]
+	}
+	var keep []string
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil && time.Since(info.ModTime()) > maxAge {
+			os.Remove(file)
+			continue
+		}
+		keep = append(keep, file)
+	}
+	return keep
+}
@@ -0,0 +1,68 @@
+// Package usage keeps purely local usage statistics: which features ran and
+// how much space they freed. Records are appended to usage.jsonl in the
+// config directory, one JSON object per line, shared with the PowerShell
+// side (lib\core\usage.ps1) and summarised by "winmole stats". Nothing is
+// ever sent over the network.
+package usage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/winmole/winmole/internal/config"
+)
+
+// FileName is the statistics file inside the config directory.
+const FileName = "usage.jsonl"
+
+// Kinds of record.
+const (
+	KindRun   = "run"   // a feature was used
+	KindFreed = "freed" // a feature freed Bytes of disk space
+)
+
+// Record is one line of usage.jsonl.
+type Record struct {
+	At      time.Time `json:"at"`
+	Kind    string    `json:"kind"`
+	Feature string    `json:"feature"`
+	Bytes   int64     `json:"bytes,omitempty"`
+}
+
+// Path returns the location of the statistics file.
+func Path() string {
+	return filepath.Join(config.Dir(), FileName)
+}
+
+// Run records one use of feature ("analyze.scan", "status", ...).
+func Run(feature string) {
+	write(Record{At: time.Now(), Kind: KindRun, Feature: feature})
+}
+
+// Freed records that feature freed bytes of disk space.
+func Freed(feature string, bytes int64) {
+	if bytes <= 0 {
+		return
+	}
+	write(Record{At: time.Now(), Kind: KindFreed, Feature: feature, Bytes: bytes})
+}
+
+// write appends r. Statistics are best effort: failures are ignored so they
+// never get in the way of the tools.
+func write(r Record) {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(config.Dir(), 0o755); err != nil {
+		return
+	}
+	f, err := os.OpenFile(Path(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(append(line, '\n'))
+}
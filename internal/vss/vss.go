@@ -0,0 +1,165 @@
+//go:build windows
+
+// Package vss reports the space Volume Shadow Copies take on each volume
+// and trims it. Restore points, previous versions of files and some backup
+// tools keep their copies in a hidden area of the volume, outside any
+// folder, so a scan of the drive never sees it; Windows grows it up to a
+// maximum, often 10% of the volume or more. Reading it and changing it
+// need admin rights, so the analyzer goes through the elevated helper.
+package vss
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yusufpapurcu/wmi"
+)
+
+// unbounded is the MaxSpace of a storage area with no maximum.
+const unbounded = ^uint64(0)
+
+// Storage is the shadow copy storage of one volume.
+type Storage struct {
+	Volume    string    `json:"volume"` // "C:", or the volume's GUID path when it has no letter
+	On        string    `json:"on"`     // the volume holding the copies, usually the same
+	Capacity  uint64    `json:"capacity"`
+	Used      uint64    `json:"used"`
+	Allocated uint64    `json:"allocated"`
+	Max       uint64    `json:"max"` // 0 when unbounded
+	Copies    int       `json:"copies"`
+	Oldest    time.Time `json:"oldest"`
+	Newest    time.Time `json:"newest"`
+}
+
+type win32ShadowStorage struct {
+	Volume         string
+	DiffVolume     string
+	UsedSpace      uint64
+	AllocatedSpace uint64
+	MaxSpace       uint64
+}
+
+type win32ShadowCopy struct {
+	VolumeName  string
+	InstallDate time.Time
+}
+
+type win32Volume struct {
+	DeviceID    string
+	DriveLetter string
+	Capacity    uint64
+}
+
+// deviceID matches the volume a Win32_ShadowStorage reference points to.
+var deviceID = regexp.MustCompile(`DeviceID="(.*)"`)
+
+// Read returns the shadow storage of every volume that has one, with how
+// many copies it holds and how old they are. It needs admin rights.
+func Read() ([]Storage, error) {
+	var volumes []win32Volume
+	if err := wmi.Query("SELECT DeviceID, DriveLetter, Capacity FROM Win32_Volume", &volumes); err != nil {
+		return nil, fmt.Errorf("volumes: %w", err)
+	}
+	var storages []win32ShadowStorage
+	if err := wmi.Query("SELECT Volume, DiffVolume, UsedSpace, AllocatedSpace, MaxSpace FROM Win32_ShadowStorage", &storages); err != nil {
+		return nil, fmt.Errorf("shadow storage: %w", err)
+	}
+	var copies []win32ShadowCopy
+	if err := wmi.Query("SELECT VolumeName, InstallDate FROM Win32_ShadowCopy", &copies); err != nil {
+		return nil, fmt.Errorf("shadow copies: %w", err)
+	}
+
+	byID := make(map[string]win32Volume)
+	for _, v := range volumes {
+		byID[strings.ToLower(v.DeviceID)] = v
+	}
+	// References escape backslashes: \\\\?\\Volume{...}\\
+	volume := func(ref string) (string, win32Volume) {
+		m := deviceID.FindStringSubmatch(ref)
+		if m == nil {
+			return ref, win32Volume{}
+		}
+		id := strings.ReplaceAll(m[1], `\\`, `\`)
+		v := byID[strings.ToLower(id)]
+		if v.DriveLetter != "" {
+			return v.DriveLetter, v
+		}
+		return id, v
+	}
+
+	var list []Storage
+	for _, s := range storages {
+		name, v := volume(s.Volume)
+		on, _ := volume(s.DiffVolume)
+		st := Storage{
+			Volume:    name,
+			On:        on,
+			Capacity:  v.Capacity,
+			Used:      s.UsedSpace,
+			Allocated: s.AllocatedSpace,
+		}
+		if s.MaxSpace != unbounded {
+			st.Max = s.MaxSpace
+		}
+		for _, c := range copies {
+			if !strings.EqualFold(c.VolumeName, v.DeviceID) {
+				continue
+			}
+			st.Copies++
+			if st.Oldest.IsZero() || c.InstallDate.Before(st.Oldest) {
+				st.Oldest = c.InstallDate
+			}
+			if c.InstallDate.After(st.Newest) {
+				st.Newest = c.InstallDate
+			}
+		}
+		list = append(list, st)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Volume < list[j].Volume })
+	return list, nil
+}
+
+var (
+	driveLetter = regexp.MustCompile(`^[A-Za-z]:$`)
+	maxSize     = regexp.MustCompile(`^(?i)(\d{1,3}%|\d+(MB|GB|TB)|UNBOUNDED)$`)
+)
+
+// ValidMaxSize reports whether size is a maximum Resize takes: a share of
+// the volume ("10%"), a size ("20GB") or "UNBOUNDED".
+func ValidMaxSize(size string) bool {
+	return maxSize.MatchString(size)
+}
+
+// Resize sets the most the copies of volume, kept on on, may take. When the
+// new maximum is below what they use, Windows deletes the oldest copies to
+// fit. It needs admin rights.
+func Resize(volume, on, size string) error {
+	if !driveLetter.MatchString(volume) || !driveLetter.MatchString(on) {
+		return fmt.Errorf("invalid volume %q on %q", volume, on)
+	}
+	if !ValidMaxSize(size) {
+		return fmt.Errorf("invalid maximum size %q (such as 10%%, 20GB or UNBOUNDED)", size)
+	}
+	return vssadmin("Resize", "ShadowStorage", "/For="+volume, "/On="+on, "/MaxSize="+strings.ToUpper(size))
+}
+
+// DeleteOldest deletes the oldest shadow copy of volume, which may be the
+// oldest restore point. It needs admin rights.
+func DeleteOldest(volume string) error {
+	if !driveLetter.MatchString(volume) {
+		return fmt.Errorf("invalid volume %q", volume)
+	}
+	return vssadmin("Delete", "Shadows", "/For="+volume, "/Oldest", "/Quiet")
+}
+
+func vssadmin(args ...string) error {
+	out, err := exec.Command("vssadmin", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("vssadmin: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
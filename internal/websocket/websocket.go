@@ -0,0 +1,229 @@
+// Package websocket is the server side of RFC 6455, as much of it as the
+// tools need to stream to a browser or a script: the handshake, text
+// messages to the client, and answers to its pings and close. Anything
+// else the client sends is read and dropped.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// acceptGUID is appended to the client's key to prove the handshake was
+// understood.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Frame opcodes.
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+// maxRead is the largest frame read from a client; it only sends control
+// frames, which are at most 125 bytes.
+const maxRead = 64 << 10
+
+// writeTimeout is how long a message may take to go out before the client
+// counts as gone.
+const writeTimeout = 10 * time.Second
+
+// ErrHandshake is returned by Upgrade for a request that is not a
+// WebSocket handshake; the response has been written.
+var ErrHandshake = errors.New("not a WebSocket handshake")
+
+// Conn is an upgraded connection. Writes may come from any goroutine.
+type Conn struct {
+	conn net.Conn
+	r    *bufio.Reader
+
+	mu   sync.Mutex // serializes writes
+	done chan struct{}
+	once sync.Once
+}
+
+// Upgrade answers a WebSocket handshake and takes over the connection.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if !headerHas(r.Header, "Connection", "upgrade") || !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		http.Error(w, "expected a WebSocket handshake", http.StatusBadRequest)
+		return nil, ErrHandshake
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, ErrHandshake
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, ErrHandshake
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "the connection cannot be upgraded", http.StatusInternalServerError)
+		return nil, ErrHandshake
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " +
+		base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c := &Conn{conn: conn, r: rw.Reader, done: make(chan struct{})}
+	go c.read()
+	return c, nil
+}
+
+// headerHas reports whether the comma-separated header name lists token.
+func headerHas(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Done is closed when the connection is.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// WriteJSON sends v as one text message.
+func (c *Conn) WriteJSON(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.write(opText, b)
+}
+
+// Close says goodbye to the client and closes the connection.
+func (c *Conn) Close() error {
+	c.write(opClose, []byte{0x03, 0xE8}) // 1000, normal closure
+	return c.shut()
+}
+
+func (c *Conn) shut() error {
+	var err error
+	c.once.Do(func() {
+		err = c.conn.Close()
+		close(c.done)
+	})
+	return err
+}
+
+// write sends one unmasked frame, as a server does.
+func (c *Conn) write(op byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.done:
+		return net.ErrClosed
+	default:
+	}
+
+	header := []byte{0x80 | op, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	n := len(payload)
+	switch {
+	case n < 126:
+		header[1] = byte(n)
+		header = header[:2]
+	case n <= 0xFFFF:
+		header[1] = 126
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+		header = header[:4]
+	default:
+		header[1] = 127
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		c.shut()
+		return err
+	}
+	return nil
+}
+
+// read answers pings and the client's close until the connection ends.
+func (c *Conn) read() {
+	defer c.shut()
+	for {
+		op, payload, err := c.readFrame()
+		if err != nil {
+			return
+		}
+		switch op {
+		case opPing:
+			c.write(opPong, payload)
+		case opClose:
+			if len(payload) >= 2 {
+				payload = payload[:2]
+			}
+			c.write(opClose, payload)
+			return
+		}
+	}
+}
+
+// readFrame reads one masked frame from the client.
+func (c *Conn) readFrame() (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	op := head[0] & 0x0F
+	if head[1]&0x80 == 0 {
+		return 0, nil, errors.New("unmasked frame from the client")
+	}
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > maxRead {
+		return 0, nil, errors.New("frame too large")
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.r, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return op, payload, nil
+}
@@ -20,29 +20,7 @@ function Clear-NodeCache {
     #>
     Start-Section "Node.js Caches"
     
-    # npm cache
-    $npmCache = "$env:APPDATA\npm-cache"
-    if (Test-Path $npmCache) {
-        Clear-DirectoryContents -Path $npmCache -Description "npm cache"
-    }
-    
-    # Alternative npm cache location
-    $npmCacheAlt = "$env:LOCALAPPDATA\npm-cache"
-    if (Test-Path $npmCacheAlt) {
-        Clear-DirectoryContents -Path $npmCacheAlt -Description "npm cache (local)"
-    }
-    
-    # Yarn cache
-    $yarnCache = "$env:LOCALAPPDATA\Yarn\Cache"
-    if (Test-Path $yarnCache) {
-        Clear-DirectoryContents -Path $yarnCache -Description "Yarn cache"
-    }
-    
-    # Yarn v2+ cache
-    $yarn2Cache = "$env:LOCALAPPDATA\Yarn\Berry\cache"
-    if (Test-Path $yarn2Cache) {
-        Clear-DirectoryContents -Path $yarn2Cache -Description "Yarn Berry cache"
-    }
+    # npm and Yarn caches are trimmed by age in packages.ps1
     
     # pnpm cache
     $pnpmCache = "$env:LOCALAPPDATA\pnpm-cache"
@@ -70,17 +48,7 @@ function Clear-PythonCache {
     #>
     Start-Section "Python Caches"
     
-    # pip cache
-    $pipCache = "$env:LOCALAPPDATA\pip\cache"
-    if (Test-Path $pipCache) {
-        Clear-DirectoryContents -Path $pipCache -Description "pip cache"
-    }
-    
-    # Alternative pip cache
-    $pipCacheAlt = "$env:APPDATA\pip\cache"
-    if (Test-Path $pipCacheAlt) {
-        Clear-DirectoryContents -Path $pipCacheAlt -Description "pip cache (roaming)"
-    }
+    # pip cache is trimmed by age in packages.ps1
     
     # pipx cache
     $pipxCache = "$env:LOCALAPPDATA\pipx"
@@ -137,11 +105,7 @@ function Clear-DotNetCache {
     #>
     Start-Section ".NET Caches"
     
-    # NuGet HTTP cache
-    $nugetHttpCache = "$env:LOCALAPPDATA\NuGet\v3-cache"
-    if (Test-Path $nugetHttpCache) {
-        Clear-DirectoryContents -Path $nugetHttpCache -Description "NuGet HTTP cache"
-    }
+    # NuGet HTTP cache and packages are trimmed by age in packages.ps1
     
     # NuGet plugins cache
     $nugetPlugins = "$env:LOCALAPPDATA\NuGet\plugins-cache"
@@ -166,12 +130,6 @@ function Clear-DotNetCache {
         }
     }
     
-    # Note: Not cleaning ~/.nuget/packages by default as it's the package cache
-    $nugetPackages = "$env:USERPROFILE\.nuget\packages"
-    if (Test-Path $nugetPackages) {
-        Write-Info "NuGet packages at $nugetPackages - run 'dotnet nuget locals all --clear' to clean"
-    }
-    
     Stop-Section
 }
 
@@ -186,18 +144,7 @@ function Clear-RustCache {
     #>
     Start-Section "Rust Caches"
     
-    # Cargo registry cache (index only, not the actual crates)
-    $cargoRegistry = "$env:USERPROFILE\.cargo\registry\cache"
-    if (Test-Path $cargoRegistry) {
-        # Only clean old cached .crate files
-        Remove-OldFiles -Path $cargoRegistry -DaysOld 60 -Filter "*.crate" -Description "Cargo crate cache"
-    }
-    
-    # Cargo git checkouts (temporary)
-    $cargoGit = "$env:USERPROFILE\.cargo\git\checkouts"
-    if (Test-Path $cargoGit) {
-        Write-Info "Cargo git checkouts found - run 'cargo cache --autoclean' if cargo-cache is installed"
-    }
+    # Cargo crate archives, sources and git checkouts are trimmed by age in packages.ps1
     
     # rustup downloads
     $rustupDownloads = "$env:USERPROFILE\.rustup\downloads"
@@ -258,8 +205,7 @@ function Clear-JavaCache {
     #>
     Start-Section "Java Caches"
     
-    # Note: NOT cleaning .m2/repository as it contains all Maven dependencies
-    Write-Info "Maven repo at ~/.m2/repository - clean manually if needed"
+    # Maven repository and Gradle caches are trimmed by age in packages.ps1
     
     # Gradle wrapper distributions (old versions)
     $gradleWrapper = "$env:USERPROFILE\.gradle\wrapper\dists"
@@ -273,12 +219,6 @@ function Clear-JavaCache {
         }
     }
     
-    # Gradle build cache
-    $gradleBuildCache = "$env:USERPROFILE\.gradle\caches\build-cache-1"
-    if (Test-Path $gradleBuildCache) {
-        Clear-DirectoryContents -Path $gradleBuildCache -Description "Gradle build cache"
-    }
-    
     # Gradle daemon logs
     $gradleDaemon = "$env:USERPROFILE\.gradle\daemon"
     if (Test-Path $gradleDaemon) {
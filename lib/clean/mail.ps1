@@ -0,0 +1,238 @@
+# WinMole - Mail Data Module
+# Finds Outlook data files and mail/chat caches, and clears what is rebuilt
+
+#Requires -Version 5.1
+Set-StrictMode -Version Latest
+
+# Import core
+$scriptDir = Split-Path -Parent $MyInvocation.MyCommand.Path
+$coreDir = Join-Path (Split-Path -Parent $scriptDir) "core"
+. "$coreDir\common.ps1"
+
+# ============================================================================
+# Outlook Data Files
+# ============================================================================
+
+# Outlook profiles by version; older versions keep them under Windows
+# Messaging Subsystem
+$script:OutlookProfileRoots = @(
+    "HKCU:\Software\Microsoft\Office\16.0\Outlook\Profiles"
+    "HKCU:\Software\Microsoft\Office\15.0\Outlook\Profiles"
+    "HKCU:\Software\Microsoft\Windows NT\CurrentVersion\Windows Messaging Subsystem\Profiles"
+)
+
+# An unattached cache untouched this long belongs to an account that is gone
+$script:OrphanedOstDays = 30
+
+function Get-OutlookProfileFiles {
+    <#
+    .SYNOPSIS
+        Map each data file an Outlook profile uses to the profile's name
+    .DESCRIPTION
+        Profiles store the paths as UTF-16 binary values: 001f6700 for a
+        PST and 001f6610 for an OST.
+    #>
+    $files = @{}
+    foreach ($root in $script:OutlookProfileRoots) {
+        foreach ($outlookProfile in @(Get-ChildItem -Path $root -ErrorAction SilentlyContinue)) {
+            foreach ($key in @(Get-ChildItem -Path $outlookProfile.PSPath -Recurse -ErrorAction SilentlyContinue)) {
+                foreach ($valueName in "001f6700", "001f6610") {
+                    $value = $key.GetValue($valueName)
+                    if ($value -is [byte[]]) {
+                        $path = [System.Text.Encoding]::Unicode.GetString($value).TrimEnd([char]0)
+                        if ($path) {
+                            $files[[Environment]::ExpandEnvironmentVariables($path).ToLower()] = $outlookProfile.PSChildName
+                        }
+                    }
+                }
+            }
+        }
+    }
+    return $files
+}
+
+function Get-MailDataFiles {
+    <#
+    .SYNOPSIS
+        Outlook data files on this PC with their size, age and profile
+    .DESCRIPTION
+        Files are found in Outlook's default folders and wherever a profile
+        points. Profile is $null for files no profile uses.
+    #>
+    $profileFiles = Get-OutlookProfileFiles
+    $found = @{}
+    $folders = @(
+        "$env:LOCALAPPDATA\Microsoft\Outlook"
+        (Join-Path ([Environment]::GetFolderPath("MyDocuments")) "Outlook Files")
+    )
+    foreach ($folder in $folders) {
+        foreach ($file in @(Get-ChildItem -Path $folder -File -ErrorAction SilentlyContinue | Where-Object { $_.Extension -in ".ost", ".pst", ".nst" })) {
+            $found[$file.FullName.ToLower()] = $file
+        }
+    }
+    foreach ($path in $profileFiles.Keys) {
+        if (-not $found.ContainsKey($path)) {
+            $file = Get-Item -LiteralPath $path -Force -ErrorAction SilentlyContinue
+            if ($file) {
+                $found[$path] = $file
+            }
+        }
+    }
+
+    return @($found.GetEnumerator() | ForEach-Object {
+        [pscustomobject]@{
+            Path      = $_.Value.FullName
+            Name      = $_.Value.Name
+            Kind      = $_.Value.Extension.TrimStart(".").ToUpper()
+            Size      = [long]$_.Value.Length
+            LastWrite = $_.Value.LastWriteTime
+            Profile   = $profileFiles[$_.Key]
+        }
+    } | Sort-Object Size -Descending)
+}
+
+function Get-CachedMailMonths {
+    <#
+    .SYNOPSIS
+        How many months of mail policy keeps in the OST, or $null if not set
+    #>
+    foreach ($version in "16.0", "15.0") {
+        $setting = Get-ItemProperty -Path "HKCU:\Software\Policies\Microsoft\Office\$version\Outlook\Cached Mode" -Name SyncWindowSetting -ErrorAction SilentlyContinue
+        if ($setting) {
+            return [int]$setting.SyncWindowSetting
+        }
+    }
+    return $null
+}
+
+function Format-Age {
+    param([datetime]$Time)
+
+    $days = [int]((Get-Date) - $Time).TotalDays
+    switch ($days) {
+        0 { return "today" }
+        1 { return "yesterday" }
+        default { return "$days days ago" }
+    }
+}
+
+# ============================================================================
+# Mail and Chat Caches
+# ============================================================================
+
+# Caches the apps download again; each is cleared only while its app is
+# closed. Clearing classic Teams signs you out of it.
+$script:MailCaches = @(
+    @{ Name = "Teams"; Path = "$env:LOCALAPPDATA\Packages\MSTeams_8wekyb3d8bbwe\LocalCache\Microsoft\MSTeams"; Process = "ms-teams" }
+    @{ Name = "Teams (classic)"; Path = "$env:APPDATA\Microsoft\Teams"; Process = "Teams" }
+    @{ Name = "New Outlook"; Path = "$env:LOCALAPPDATA\Microsoft\Olk"; Process = "olk" }
+    @{ Name = "Outlook attachment previews"; Path = "$env:LOCALAPPDATA\Microsoft\Windows\INetCache\Content.Outlook"; Process = "OUTLOOK" }
+    @{ Name = "Outlook offline address books"; Path = "$env:LOCALAPPDATA\Microsoft\Outlook\Offline Address Books"; Process = "OUTLOOK" }
+)
+
+# ============================================================================
+# Report and Cleanup
+# ============================================================================
+
+function Show-MailDataFiles {
+    <#
+    .SYNOPSIS
+        List the data files by Outlook profile, with guidance for large ones
+    #>
+    param([object[]]$Files)
+
+    $cyan = $script:Colors.Cyan
+    $gray = $script:Colors.Gray
+    $nc = $script:Colors.NC
+
+    if ($Files.Count -eq 0) {
+        Write-Info "No Outlook data files found"
+        return
+    }
+    Set-SectionActivity
+
+    $total = ($Files | Measure-Object -Property Size -Sum).Sum
+    Write-Host ""
+    Write-Host "  Outlook data files: ${cyan}$(Format-ByteSize $total)${nc} in $($Files.Count) files"
+    foreach ($group in @($Files | Group-Object { if ($_.Profile) { "Profile: $($_.Profile)" } else { "Not used by any Outlook profile" } })) {
+        Write-Host ""
+        Write-Host "  ${cyan}$($group.Name)${nc}"
+        foreach ($file in $group.Group) {
+            Write-Host ("    {0,10}  {1}  {2} ${gray}(changed {3})${nc}" -f (Format-ByteSize $file.Size), $file.Kind, $file.Path, (Format-Age $file.LastWrite))
+        }
+    }
+
+    # An OST is a copy of the mailbox; how much of it is kept is the
+    # "Download email for the past" setting of cached Exchange mode
+    $bigOst = @($Files | Where-Object { $_.Kind -eq "OST" -and $_.Profile -and $_.Size -ge 10GB })
+    if ($bigOst.Count -gt 0) {
+        Write-Host ""
+        $months = Get-CachedMailMonths
+        if ($null -ne $months) {
+            $kept = if ($months -eq 0) { "all mail" } else { "$months months of mail" }
+            Write-Info "Your organisation's policy keeps $kept offline"
+        }
+        else {
+            Write-Info "Large OST files shrink by keeping less mail offline: in Outlook, File > Account Settings >"
+            Write-Info "Account Settings > select the account > Change > 'Download email for the past' (e.g. 12 months)."
+            Write-Info "Older mail stays on the server and is still searchable."
+        }
+    }
+    $bigPst = @($Files | Where-Object { $_.Kind -eq "PST" -and $_.Size -ge 40GB })
+    foreach ($file in $bigPst) {
+        Write-Warning "$($file.Name) is close to the 50 GB limit of a PST file; archive old mail or split it"
+    }
+    if (@($Files | Where-Object { $_.Kind -eq "PST" -and $_.Profile }).Count -gt 0) {
+        Write-Info "PST files are never removed here. After deleting mail, compact one in Outlook: File > Account Settings >"
+        Write-Info "Data Files > select it > Settings > Compact Now. The Mail control panel (control mlcfg32.cpl) lists them too."
+    }
+}
+
+function Invoke-MailCleanup {
+    <#
+    .SYNOPSIS
+        Report Outlook data files and clear mail caches that are rebuilt
+    .DESCRIPTION
+        Only OST caches no profile uses and that have not changed for
+        $script:OrphanedOstDays days are removed; they belong to accounts
+        that were removed or profiles that were recreated. PST files may
+        hold the only copy of mail and are only reported. Caches are cleared
+        only while their app is closed.
+    #>
+    Start-Section "Mail Data"
+
+    $files = Get-MailDataFiles
+    Show-MailDataFiles -Files $files
+
+    $outlookRunning = [bool](Get-Process -Name OUTLOOK -ErrorAction SilentlyContinue)
+    $cutoff = (Get-Date).AddDays(-$script:OrphanedOstDays)
+    $orphans = @($files | Where-Object { $_.Kind -in "OST", "NST" -and -not $_.Profile -and $_.LastWrite -lt $cutoff })
+    if ($orphans.Count -gt 0) {
+        Write-Host ""
+        if ($outlookRunning) {
+            Write-Warning "Close Outlook to remove $($orphans.Count) unused OST files"
+        }
+        else {
+            foreach ($file in $orphans) {
+                $null = Remove-SafeItem -Path $file.Path -Description "Unused Outlook cache $($file.Name)"
+            }
+        }
+    }
+
+    foreach ($cache in $script:MailCaches) {
+        if (-not (Test-Path $cache.Path -ErrorAction SilentlyContinue)) {
+            continue
+        }
+        if (Get-Process -Name $cache.Process -ErrorAction SilentlyContinue) {
+            $size = Get-PathSize -Path $cache.Path
+            if ($size -gt 0) {
+                Write-Info "$($cache.Name) cache: $(Format-ByteSize $size), skipped while it is running"
+                Set-SectionActivity
+            }
+            continue
+        }
+        $null = Clear-DirectoryContents -Path $cache.Path -Description "$($cache.Name) cache"
+    }
+
+    Stop-Section
+}
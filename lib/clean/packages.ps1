@@ -0,0 +1,226 @@
+# WinMole - Package Cache Module
+# Trims package manager and installer download caches by age
+
+#Requires -Version 5.1
+Set-StrictMode -Version Latest
+
+# Import core
+$scriptDir = Split-Path -Parent $MyInvocation.MyCommand.Path
+$coreDir = Join-Path (Split-Path -Parent $scriptDir) "core"
+. "$coreDir\common.ps1"
+
+# ============================================================================
+# Package Caches
+# ============================================================================
+
+# Caches whose contents are downloaded again when needed. Only what has not
+# been written or read for KeepDays is removed, so packages in current use
+# stay and the next install or build does not fetch everything again.
+#   Unit File:   each file ages on its own (HTTP and archive caches)
+#   Unit Folder: each folder matching Paths is one package version and is
+#                removed whole, aged by its most recently used file
+#   Marker:      folders holding a file like this are the package versions
+$script:PackageCaches = @(
+    @{ Key = "pip"; Name = "pip cache"; Unit = "File"; KeepDays = 30; Paths = @(
+        "$env:LOCALAPPDATA\pip\cache"
+        "$env:APPDATA\pip\cache"
+    ) }
+    @{ Key = "npm"; Name = "npm cache"; Unit = "File"; KeepDays = 30; Paths = @(
+        "$env:LOCALAPPDATA\npm-cache\_cacache"
+        "$env:APPDATA\npm-cache\_cacache"
+    ) }
+    @{ Key = "yarn"; Name = "Yarn cache"; Unit = "File"; KeepDays = 30; Paths = @(
+        "$env:LOCALAPPDATA\Yarn\Cache"
+        "$env:LOCALAPPDATA\Yarn\Berry\cache"
+    ) }
+    @{ Key = "nuget-http"; Name = "NuGet HTTP cache"; Unit = "File"; KeepDays = 7; Paths = @(
+        "$env:LOCALAPPDATA\NuGet\v3-cache"
+    ) }
+    @{ Key = "nuget"; Name = "NuGet packages"; Unit = "Folder"; KeepDays = 90; Paths = @(
+        "$env:USERPROFILE\.nuget\packages\*\*"
+    ) }
+    @{ Key = "gradle"; Name = "Gradle build cache"; Unit = "File"; KeepDays = 30; Paths = @(
+        "$env:USERPROFILE\.gradle\caches\build-cache-1"
+    ) }
+    @{ Key = "gradle-deps"; Name = "Gradle dependencies"; Unit = "Folder"; KeepDays = 90; Paths = @(
+        "$env:USERPROFILE\.gradle\caches\modules-2\files-2.1\*\*\*"
+    ) }
+    @{ Key = "maven"; Name = "Maven repository"; Unit = "Folder"; Marker = "*.pom"; KeepDays = 180; Paths = @(
+        "$env:USERPROFILE\.m2\repository"
+    ) }
+    @{ Key = "cargo"; Name = "Cargo crate archives"; Unit = "File"; KeepDays = 90; Paths = @(
+        "$env:USERPROFILE\.cargo\registry\cache"
+    ) }
+    @{ Key = "cargo-src"; Name = "Cargo crate sources"; Unit = "Folder"; KeepDays = 90; Paths = @(
+        "$env:USERPROFILE\.cargo\registry\src\*\*"
+        "$env:USERPROFILE\.cargo\git\checkouts\*\*"
+    ) }
+    @{ Key = "chocolatey"; Name = "Chocolatey downloads"; Unit = "File"; KeepDays = 14; Paths = @(
+        "$env:TEMP\chocolatey"
+    ) }
+    @{ Key = "winget"; Name = "winget downloads"; Unit = "File"; KeepDays = 14; Paths = @(
+        "$env:TEMP\WinGet"
+    ) }
+)
+
+function Get-PackageCacheDays {
+    <#
+    .SYNOPSIS
+        Retention per cache key: the defaults above, then the clean.keepDays
+        section of config.json
+    #>
+    $days = @{}
+    foreach ($cache in $script:PackageCaches) {
+        $days[$cache.Key] = $cache.KeepDays
+    }
+    $days["msi"] = 90
+
+    $configFile = Join-Path $script:Config.ConfigPath "config.json"
+    if (-not (Test-Path $configFile)) {
+        return $days
+    }
+    try {
+        $config = Get-Content -Path $configFile -Raw | ConvertFrom-Json
+    }
+    catch {
+        Write-Warning "Ignoring $configFile - $($_.Exception.Message)"
+        return $days
+    }
+    $clean = $config.PSObject.Properties['clean']
+    if ($clean -and $clean.Value -and $clean.Value.PSObject.Properties['keepDays']) {
+        foreach ($setting in $clean.Value.keepDays.PSObject.Properties) {
+            if ($days.ContainsKey($setting.Name) -and "$($setting.Value)" -match '^\d+$') {
+                $days[$setting.Name] = [int]$setting.Value
+            }
+        }
+    }
+    return $days
+}
+
+function Get-LastUsed {
+    <#
+    .SYNOPSIS
+        When a file was last written or, if later, read
+    #>
+    param([System.IO.FileSystemInfo]$Item)
+
+    if ($Item.LastAccessTime -gt $Item.LastWriteTime) {
+        return $Item.LastAccessTime
+    }
+    return $Item.LastWriteTime
+}
+
+function Get-PackageCacheItems {
+    <#
+    .SYNOPSIS
+        The files or package folders of a cache with when each was last used
+    #>
+    param([hashtable]$Cache)
+
+    $items = @()
+    foreach ($path in $Cache.Paths) {
+        if ($Cache.Unit -eq "File") {
+            foreach ($file in @(Get-ChildItem -Path $path -File -Recurse -Force -ErrorAction SilentlyContinue)) {
+                $items += [pscustomobject]@{ Path = $file.FullName; LastUsed = Get-LastUsed $file }
+            }
+            continue
+        }
+
+        if ($Cache.ContainsKey("Marker")) {
+            $folders = @(Get-ChildItem -Path $path -Filter $Cache.Marker -File -Recurse -Force -ErrorAction SilentlyContinue |
+                         ForEach-Object { $_.DirectoryName } | Sort-Object -Unique)
+        }
+        else {
+            $folders = @(Get-Item -Path $path -Force -ErrorAction SilentlyContinue | Where-Object { $_.PSIsContainer } | ForEach-Object { $_.FullName })
+        }
+        foreach ($folder in $folders) {
+            $lastUsed = [datetime]::MinValue
+            foreach ($file in @(Get-ChildItem -LiteralPath $folder -File -Recurse -Force -ErrorAction SilentlyContinue)) {
+                $used = Get-LastUsed $file
+                if ($used -gt $lastUsed) {
+                    $lastUsed = $used
+                }
+            }
+            $items += [pscustomobject]@{ Path = $folder; LastUsed = $lastUsed }
+        }
+    }
+    return $items
+}
+
+function Show-MsiPatchCache {
+    <#
+    .SYNOPSIS
+        Report the Windows Installer baseline cache and how much of it is old
+    .DESCRIPTION
+        $PatchCache$ keeps a copy of the files of patched MSI products so a
+        patch can be removed without the original media. It lives under the
+        Windows folder, which is never cleaned directly; Disk Cleanup and
+        the MaxPatchCacheSize policy are the supported ways to shrink it.
+    #>
+    param([int]$KeepDays)
+
+    $patchCache = "$env:SystemRoot\Installer\`$PatchCache`$\Managed"
+    if (-not (Test-Path $patchCache -ErrorAction SilentlyContinue)) {
+        return
+    }
+    if (-not (Test-IsAdmin)) {
+        Write-Debug "Skipping Windows Installer patch cache - requires admin"
+        return
+    }
+
+    $cutoff = (Get-Date).AddDays(-$KeepDays)
+    $total = 0
+    $old = 0
+    foreach ($file in @(Get-ChildItem -Path $patchCache -File -Recurse -Force -ErrorAction SilentlyContinue)) {
+        $total += $file.Length
+        if ($file.LastWriteTime -lt $cutoff) {
+            $old += $file.Length
+        }
+    }
+    if ($total -eq 0) {
+        return
+    }
+    Write-Info "Windows Installer patch cache: $(Format-ByteSize $total), $(Format-ByteSize $old) older than $KeepDays days"
+    Write-Info "Removing it means patches ask for the original setup media when uninstalled. Disk Cleanup's"
+    Write-Info "'Windows Installer patch cache' option removes it; the MaxPatchCacheSize policy (0) stops it growing."
+    Set-SectionActivity
+}
+
+function Clear-PackageCaches {
+    <#
+    .SYNOPSIS
+        Remove package cache contents not used within each cache's retention
+    .PARAMETER KeepDays
+        Use this retention for every cache instead of the configured ones;
+        0 clears the caches completely
+    #>
+    param([int]$KeepDays = -1)
+
+    Start-Section "Package Caches"
+
+    $days = Get-PackageCacheDays
+    if ($KeepDays -ge 0) {
+        foreach ($key in @($days.Keys)) {
+            $days[$key] = $KeepDays
+        }
+    }
+
+    foreach ($cache in $script:PackageCaches) {
+        $keep = $days[$cache.Key]
+        $items = @(Get-PackageCacheItems -Cache $cache)
+        if ($items.Count -eq 0) {
+            continue
+        }
+        $cutoff = (Get-Date).AddDays(-$keep)
+        $old = @($items | Where-Object { $_.LastUsed -lt $cutoff } | ForEach-Object { $_.Path })
+        if ($old.Count -eq 0) {
+            continue
+        }
+        $description = if ($keep -gt 0) { "$($cache.Name) (unused >${keep}d)" } else { $cache.Name }
+        $null = Remove-SafeItems -Paths $old -Description $description
+    }
+
+    Show-MsiPatchCache -KeepDays $days["msi"]
+
+    Stop-Section
+}
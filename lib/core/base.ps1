@@ -47,6 +47,47 @@ $script:Icons = @{
     Trash    = [char]0x2718  # ✘ (trash substitute)
 }
 
+# ============================================================================
+# Portable Mode
+# ============================================================================
+# A winmole.portable file in the WinMole folder keeps config and cache next
+# to it (config\, cache\) instead of the user profile, e.g. on a USB stick.
+$script:WINMOLE_PORTABLE_ROOT = $null
+$portableRoot = Split-Path -Parent (Split-Path -Parent $PSScriptRoot)
+if (Test-Path (Join-Path $portableRoot "winmole.portable")) {
+    $script:WINMOLE_PORTABLE_ROOT = $portableRoot
+}
+
+# ============================================================================
+# Group Policy
+# ============================================================================
+# Settings pushed by IT to HKLM\Software\Policies\WinMole. They override the
+# user's configuration; internal\policy reads the same values for the Go tools.
+$script:Policy = @{
+    Managed             = $false
+    DisabledCommands    = @()     # REG_MULTI_SZ: commands that refuse to run
+    DisableFileDeletion = $false  # REG_DWORD: no deleting from the analyzer
+    ExcludedPaths       = @()     # REG_MULTI_SZ: never cleaned or deleted
+    AgentEndpoint       = $null   # REG_SZ: management agent URL
+}
+$policyKey = "HKLM:\Software\Policies\WinMole"
+if (Test-Path $policyKey) {
+    $script:Policy.Managed = $true
+    $policyValues = (Get-ItemProperty -Path $policyKey -ErrorAction SilentlyContinue).PSObject.Properties
+    if ($policyValues['DisabledCommands']) {
+        $script:Policy.DisabledCommands = @($policyValues['DisabledCommands'].Value | ForEach-Object { "$_".Trim().ToLower() } | Where-Object { $_ })
+    }
+    if ($policyValues['DisableFileDeletion']) {
+        $script:Policy.DisableFileDeletion = [int]$policyValues['DisableFileDeletion'].Value -ne 0
+    }
+    if ($policyValues['ExcludedPaths']) {
+        $script:Policy.ExcludedPaths = @($policyValues['ExcludedPaths'].Value | ForEach-Object { "$_".Trim() } | Where-Object { $_ })
+    }
+    if ($policyValues['AgentEndpoint']) {
+        $script:Policy.AgentEndpoint = "$($policyValues['AgentEndpoint'].Value)".Trim()
+    }
+}
+
 # ============================================================================
 # Global Configuration Constants
 # ============================================================================
@@ -62,6 +103,43 @@ $script:Config = @{
     WhitelistFile          = "$env:USERPROFILE\.config\winmole\whitelist.txt"
 }
 
+if ($script:WINMOLE_PORTABLE_ROOT) {
+    $script:Config.ConfigPath = Join-Path $script:WINMOLE_PORTABLE_ROOT "config"
+    $script:Config.CachePath = Join-Path $script:WINMOLE_PORTABLE_ROOT "cache"
+    $script:Config.WhitelistFile = Join-Path $script:Config.ConfigPath "whitelist.txt"
+}
+
+# ============================================================================
+# Size Units
+# ============================================================================
+# The "units" setting of config.json, shared with the Go tools: binary
+# (1024, labeled KB like Explorer), iec (1024, KiB) or si (1000, KB like
+# drive makers). The active profile's setting wins, as in internal\config.
+$script:SizeUnits = "binary"
+$settingsFile = Join-Path $script:Config.ConfigPath "config.json"
+if (Test-Path $settingsFile) {
+    try {
+        $settings = Get-Content -Path $settingsFile -Raw | ConvertFrom-Json
+        $properties = $settings.PSObject.Properties
+        if ($properties['units']) {
+            $script:SizeUnits = "$($properties['units'].Value)"
+        }
+        $profileName = if ($env:WINMOLE_PROFILE) { $env:WINMOLE_PROFILE } elseif ($properties['profile']) { "$($properties['profile'].Value)" } else { "" }
+        if ($profileName -and $properties['profiles'] -and $settings.profiles.PSObject.Properties[$profileName]) {
+            $profileUnits = $settings.profiles.$profileName.PSObject.Properties['units']
+            if ($profileUnits) {
+                $script:SizeUnits = "$($profileUnits.Value)"
+            }
+        }
+    }
+    catch {
+        # A broken file is reported by the tools that own its sections
+    }
+}
+if ($script:SizeUnits -notin "binary", "iec", "si") {
+    $script:SizeUnits = "binary"
+}
+
 # ============================================================================
 # Default Whitelist Patterns (paths to never clean)
 # ============================================================================
@@ -197,6 +275,14 @@ function Test-Whitelisted {
     #>
     param([string]$Path)
     
+    # Mandatory exclusions from Group Policy also cover everything below them
+    foreach ($pattern in $script:Policy.ExcludedPaths) {
+        $expandedPattern = [Environment]::ExpandEnvironmentVariables($pattern).TrimEnd('\')
+        if ($Path -like $expandedPattern -or $Path -like "$expandedPattern\*") {
+            return $true
+        }
+    }
+    
     # Check default patterns
     foreach ($pattern in $script:DefaultWhitelistPatterns) {
         $expandedPattern = [Environment]::ExpandEnvironmentVariables($pattern)
@@ -221,6 +307,30 @@ function Test-Whitelisted {
     return $false
 }
 
+function Test-CommandDisabled {
+    <#
+    .SYNOPSIS
+        Check if Group Policy turns off a command
+    #>
+    param([string]$Name)
+    
+    return $script:Policy.DisabledCommands -contains $Name.ToLower()
+}
+
+function Assert-CommandEnabled {
+    <#
+    .SYNOPSIS
+        Stop a bin script that Group Policy turns off, when it is run
+        directly rather than through winmole.ps1
+    #>
+    param([string]$Name)
+    
+    if (Test-CommandDisabled -Name $Name) {
+        Write-Error "The $Name command is disabled by your administrator (Group Policy)"
+        exit 1
+    }
+}
+
 function Resolve-SafePath {
     <#
     .SYNOPSIS
@@ -245,26 +355,49 @@ function Format-ByteSize {
     <#
     .SYNOPSIS
         Convert bytes to human-readable format
+    .PARAMETER Units
+        binary, iec or si; defaults to the units setting
     #>
-    param([long]$Bytes)
+    param(
+        [long]$Bytes,
+        [string]$Units = $script:SizeUnits
+    )
+    
+    $unit = if ($Units -eq "si") { 1000 } else { 1024 }
+    $suffix = if ($Units -eq "iec") { "iB" } else { "B" }
+    $tb = [Math]::Pow($unit, 4)
+    $gb = [Math]::Pow($unit, 3)
+    $mb = [Math]::Pow($unit, 2)
     
-    if ($Bytes -ge 1TB) {
-        return "{0:N2} TB" -f ($Bytes / 1TB)
+    if ($Bytes -ge $tb) {
+        return "{0:N2} T$suffix" -f ($Bytes / $tb)
     }
-    elseif ($Bytes -ge 1GB) {
-        return "{0:N1} GB" -f ($Bytes / 1GB)
+    elseif ($Bytes -ge $gb) {
+        return "{0:N1} G$suffix" -f ($Bytes / $gb)
     }
-    elseif ($Bytes -ge 1MB) {
-        return "{0:N1} MB" -f ($Bytes / 1MB)
+    elseif ($Bytes -ge $mb) {
+        return "{0:N1} M$suffix" -f ($Bytes / $mb)
     }
-    elseif ($Bytes -ge 1KB) {
-        return "{0:N1} KB" -f ($Bytes / 1KB)
+    elseif ($Bytes -ge $unit) {
+        return "{0:N1} K$suffix" -f ($Bytes / $unit)
     }
     else {
         return "{0} B" -f $Bytes
     }
 }
 
+function Get-SizeUnitsLabel {
+    <#
+    .SYNOPSIS
+        What a kilobyte is under the units setting, e.g. "1 KB = 1024 B"
+    #>
+    switch ($script:SizeUnits) {
+        "iec" { return "1 KiB = 1024 B" }
+        "si" { return "1 KB = 1000 B" }
+        default { return "1 KB = 1024 B" }
+    }
+}
+
 function Format-Number {
     <#
     .SYNOPSIS
@@ -32,6 +32,9 @@ $script:WINMOLE_ROOT_DIR = Split-Path -Parent $script:WINMOLE_LIB_DIR
 # UI components
 . "$script:WINMOLE_CORE_DIR\ui.ps1"
 
+# Local usage statistics
+. "$script:WINMOLE_CORE_DIR\usage.ps1"
+
 # ============================================================================
 # Version Information
 # ============================================================================
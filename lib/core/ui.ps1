@@ -389,13 +389,14 @@ function Show-Summary {
     
     $green = $script:Colors.Green
     $cyan = $script:Colors.Cyan
+    $gray = $script:Colors.Gray
     $nc = $script:Colors.NC
     
     $sizeHuman = Format-ByteSize -Bytes $SizeBytes
     
     Write-Host ""
     Write-Host "  $($green)━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━$($nc)"
-    Write-Host "  $($green)$($script:Icons.Success)$($nc) $($Action): $($cyan)$($sizeHuman)$($nc) across $($cyan)$($ItemCount)$($nc) items"
+    Write-Host "  $($green)$($script:Icons.Success)$($nc) $($Action): $($cyan)$($sizeHuman)$($nc) across $($cyan)$($ItemCount)$($nc) items $($gray)($(Get-SizeUnitsLabel))$($nc)"
     Write-Host "  $($green)━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━$($nc)"
     Write-Host ""
 }
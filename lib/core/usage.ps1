@@ -0,0 +1,93 @@
+# WinMole - Local Usage Statistics
+# Records which features ran and how much space they freed. Everything stays
+# in usage.jsonl in the config directory; nothing is sent over the network.
+# The Go tools append to the same file (internal\usage).
+
+#Requires -Version 5.1
+Set-StrictMode -Version Latest
+
+# Prevent multiple sourcing
+if ((Get-Variable -Name 'WINMOLE_USAGE_LOADED' -Scope Script -ErrorAction SilentlyContinue) -and $script:WINMOLE_USAGE_LOADED) { return }
+$script:WINMOLE_USAGE_LOADED = $true
+
+function Get-UsageFile {
+    <#
+    .SYNOPSIS
+        Get the path of the usage statistics file
+    #>
+    return Join-Path $script:Config.ConfigPath "usage.jsonl"
+}
+
+function Add-UsageRecord {
+    <#
+    .SYNOPSIS
+        Append a usage record; failures are ignored
+    .PARAMETER Kind
+        "run" when a feature was used, "freed" when it freed disk space
+    #>
+    param(
+        [Parameter(Mandatory)]
+        [string]$Feature,
+        
+        [ValidateSet("run", "freed")]
+        [string]$Kind = "run",
+        
+        [long]$Bytes = 0
+    )
+    
+    # Dry runs free nothing
+    if ($Kind -eq "freed" -and ($Bytes -le 0 -or (Test-DryRunMode))) {
+        return
+    }
+    
+    $record = [ordered]@{
+        at      = (Get-Date).ToString("o")
+        kind    = $Kind
+        feature = $Feature
+    }
+    if ($Bytes -gt 0) {
+        $record.bytes = $Bytes
+    }
+    
+    try {
+        $path = Get-UsageFile
+        $dir = Split-Path -Parent $path
+        if (-not (Test-Path $dir)) {
+            New-Item -ItemType Directory -Path $dir -Force | Out-Null
+        }
+        # AppendAllText writes UTF-8 without a BOM, which the Go side expects
+        [System.IO.File]::AppendAllText($path, ($record | ConvertTo-Json -Compress) + "`n")
+    }
+    catch {
+        Write-Debug "Could not record usage: $_"
+    }
+}
+
+function Get-UsageRecords {
+    <#
+    .SYNOPSIS
+        Read all usage records, skipping lines that do not parse
+    #>
+    $path = Get-UsageFile
+    if (-not (Test-Path $path)) {
+        return @()
+    }
+    
+    $records = foreach ($line in [System.IO.File]::ReadAllLines($path)) {
+        if (-not $line.Trim()) { continue }
+        try {
+            $raw = $line | ConvertFrom-Json
+            $props = $raw.PSObject.Properties
+            [PSCustomObject]@{
+                At      = [datetime]$raw.at
+                Kind    = $raw.kind
+                Feature = $raw.feature
+                Bytes   = if ($props['bytes']) { [long]$raw.bytes } else { 0 }
+            }
+        }
+        catch {
+            Write-Debug "Skipping bad usage record: $line"
+        }
+    }
+    return @($records)
+}
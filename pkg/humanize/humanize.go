@@ -0,0 +1,307 @@
+// Package humanize formats sizes, durations and labels the way the WinMole
+// tools show them.
+//
+//	humanize.Bytes(1536)                    // "1.5 KB"
+//	humanize.SetUnits(humanize.IEC)
+//	humanize.Bytes(1536)                    // "1.5 KiB"
+//	humanize.Count(412_000)                 // "412k"
+//	humanize.Duration(26 * time.Hour)       // "1d 2h 0m"
+//	humanize.Time(modTime)                  // "2024-03-09 14:05"
+//	humanize.SetTimes(humanize.Relative)
+//	humanize.Time(modTime)                  // "3 days ago"
+//	humanize.Truncate("node_modules", 8)    // "node_..."
+//
+// Packages under pkg/ follow semantic versioning with the module: exported
+// names and their behaviour only change incompatibly in a new major version.
+package humanize
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Integer is any integer type a size can be held in.
+type Integer interface {
+	~int | ~int32 | ~int64 | ~uint | ~uint32 | ~uint64
+}
+
+// Units is how Bytes counts and labels sizes.
+type Units int32
+
+const (
+	// Binary counts in powers of 1024 and labels them KB, MB, GB, as
+	// Explorer does. It is the default.
+	Binary Units = iota
+	// IEC counts in powers of 1024 and labels them KiB, MiB, GiB.
+	IEC
+	// SI counts in powers of 1000 and labels them KB, MB, GB, as drive
+	// makers do.
+	SI
+)
+
+var units atomic.Int32
+
+// SetUnits changes the units Bytes uses from then on, in every goroutine.
+func SetUnits(u Units) {
+	units.Store(int32(u))
+}
+
+// CurrentUnits returns the units Bytes uses.
+func CurrentUnits() Units {
+	return Units(units.Load())
+}
+
+// ParseUnits reads a units setting: "binary", "iec" or "si". An empty
+// string is Binary.
+func ParseUnits(s string) (Units, error) {
+	switch s {
+	case "", "binary":
+		return Binary, nil
+	case "iec":
+		return IEC, nil
+	case "si":
+		return SI, nil
+	}
+	return Binary, fmt.Errorf("unknown units %q (binary, iec or si)", s)
+}
+
+// String returns the setting ParseUnits reads back.
+func (u Units) String() string {
+	switch u {
+	case IEC:
+		return "iec"
+	case SI:
+		return "si"
+	}
+	return "binary"
+}
+
+// Label says what a kilobyte is under u, such as "1 KB = 1024 B", for
+// showing which convention sizes follow.
+func (u Units) Label() string {
+	switch u {
+	case IEC:
+		return "1 KiB = 1024 B"
+	case SI:
+		return "1 KB = 1000 B"
+	}
+	return "1 KB = 1024 B"
+}
+
+// Bytes formats a size with one decimal in the units set with SetUnits,
+// such as "1.5 KB" or "12.0 GB". Sizes under one kilobyte are shown
+// exactly; negative sizes are shown as 0 B.
+func Bytes[T Integer](n T) string {
+	if n < 0 {
+		n = 0
+	}
+	bytes := uint64(n)
+	u := CurrentUnits()
+	unit, suffix := uint64(1024), "B"
+	switch u {
+	case IEC:
+		suffix = "iB"
+	case SI:
+		unit = 1000
+	}
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := unit, 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %c%s", float64(bytes)/float64(div), "KMGTPE"[exp], suffix)
+}
+
+// Count formats a number of things in at most five characters: exactly
+// below 1,000, then with one decimal below ten of a unit ("1.2k", "3.4M")
+// and whole units above ("412k"). Negative counts are shown as 0.
+func Count[T Integer](n T) string {
+	if n < 0 {
+		n = 0
+	}
+	count := float64(n)
+	if count < 1000 {
+		return fmt.Sprintf("%d", uint64(n))
+	}
+	exp := 0
+	for count /= 1000; count >= 999.5 && exp < 3; count /= 1000 {
+		exp++
+	}
+	if count < 9.95 {
+		return fmt.Sprintf("%.1f%c", count, "kMGT"[exp])
+	}
+	return fmt.Sprintf("%.0f%c", count, "kMGT"[exp])
+}
+
+// Duration formats d in days, hours and minutes, leaving out leading zero
+// units: "3d 4h 5m", "4h 5m" or "5m".
+func Duration(d time.Duration) string {
+	days := int(d.Hours() / 24)
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	}
+	if hours > 0 {
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+// Times is how Time and Date show a moment.
+type Times int32
+
+const (
+	// Absolute shows the local date and time. It is the default.
+	Absolute Times = iota
+	// Relative shows how long ago, or how far ahead, a moment is.
+	Relative
+)
+
+var times atomic.Int32
+
+// SetTimes changes how Time and Date show moments from then on, in every
+// goroutine.
+func SetTimes(t Times) {
+	times.Store(int32(t))
+}
+
+// CurrentTimes returns how Time and Date show moments.
+func CurrentTimes() Times {
+	return Times(times.Load())
+}
+
+// ParseTimes reads a times setting: "absolute" or "relative". An empty
+// string is Absolute.
+func ParseTimes(s string) (Times, error) {
+	switch s {
+	case "", "absolute":
+		return Absolute, nil
+	case "relative":
+		return Relative, nil
+	}
+	return Absolute, fmt.Errorf("unknown times %q (absolute or relative)", s)
+}
+
+// String returns the setting ParseTimes reads back.
+func (t Times) String() string {
+	if t == Relative {
+		return "relative"
+	}
+	return "absolute"
+}
+
+// Time formats a moment in the local time zone as set with SetTimes:
+// "2024-03-09 14:05" or "3 h ago".
+func Time(t time.Time) string {
+	return Format(t, "2006-01-02 15:04")
+}
+
+// Date is Time to the day: "2024-03-09" or "12 days ago".
+func Date(t time.Time) string {
+	if !t.IsZero() && CurrentTimes() == Relative {
+		return relativeTime(t.Local(), time.Now().Local(), true)
+	}
+	return Format(t, "2006-01-02")
+}
+
+// Format is Time with the layout absolute times are shown in. An absolute
+// time that includes the hour carries its zone's abbreviation when the
+// zone's offset then differs from the current one, "2024-10-27 02:30
+// CEST", so the hour repeated when clocks go back can be told apart. The
+// zero time is "-".
+func Format(t time.Time, layout string) string {
+	if t.IsZero() {
+		return "-"
+	}
+	t, now := t.Local(), time.Now().Local()
+	if CurrentTimes() == Relative {
+		return relativeTime(t, now, false)
+	}
+	s := t.Format(layout)
+	if zone, offset := t.Zone(); offset != zoneOffset(now) && strings.Contains(layout, "15") {
+		s += " " + zone
+	}
+	return s
+}
+
+func zoneOffset(t time.Time) int {
+	_, offset := t.Zone()
+	return offset
+}
+
+// relativeTime says how far t is from now: in minutes and hours within a
+// day, then in calendar days, months and years, counted on the local
+// calendar so a day with a clock change is still one day.
+func relativeTime(t, now time.Time, day bool) string {
+	past := !t.After(now)
+	ago := func(s string) string {
+		if past {
+			return s + " ago"
+		}
+		return "in " + s
+	}
+	d := now.Sub(t)
+	if !past {
+		d = -d
+	}
+	if !day {
+		switch {
+		case d < time.Minute:
+			return "just now"
+		case d < time.Hour:
+			return ago(fmt.Sprintf("%d min", int(d.Minutes())))
+		case d < 24*time.Hour:
+			return ago(fmt.Sprintf("%d h", int(d.Hours())))
+		}
+	}
+
+	from, to := t, now
+	if !past {
+		from, to = now, t
+	}
+	days := calendarDays(from, to)
+	months := (to.Year()-from.Year())*12 + int(to.Month()-from.Month())
+	if to.Day() < from.Day() {
+		months--
+	}
+	switch {
+	case days == 0:
+		return "today"
+	case days == 1 && past:
+		return "yesterday"
+	case days == 1:
+		return "tomorrow"
+	case months < 2:
+		return ago(fmt.Sprintf("%d days", days))
+	case months < 24:
+		return ago(fmt.Sprintf("%d months", months))
+	}
+	return ago(fmt.Sprintf("%d years", months/12))
+}
+
+// calendarDays counts the midnights between from and to.
+func calendarDays(from, to time.Time) int {
+	y, m, d := from.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	y, m, d = to.Date()
+	end := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return int(end.Sub(start).Hours() / 24)
+}
+
+// Truncate shortens s to at most max bytes, ending in "..." when cut.
+func Truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	if max < 3 {
+		return s[:max]
+	}
+	return s[:max-3] + "..."
+}
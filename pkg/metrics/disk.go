@@ -0,0 +1,79 @@
+//go:build windows
+
+package metrics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// DiskInfo describes one mounted volume.
+type DiskInfo struct {
+	Mount   string
+	FSType  string
+	Total   uint64
+	Used    uint64
+	Percent float64
+
+	// Cumulative I/O counters and the rates derived from them
+	ReadBytes  uint64
+	WriteBytes uint64
+	ReadRate   float64
+	WriteRate  float64
+}
+
+func collectDisks(metrics *Metrics) error {
+	drive := SystemDrive()
+	metrics.DiskPath = drive
+
+	partitions, err := disk.Partitions(false)
+	if err != nil {
+		return fmt.Errorf("partitions: %w", err)
+	}
+
+	// IO counters are keyed by drive letter ("C:") on Windows; a failure
+	// here only costs the rate columns.
+	counters, ioErr := disk.IOCounters()
+
+	for _, p := range partitions {
+		info := DiskInfo{Mount: p.Mountpoint, FSType: p.Fstype}
+		if usage, err := disk.Usage(p.Mountpoint + "\\"); err == nil {
+			info.Total = usage.Total
+			info.Used = usage.Used
+			info.Percent = usage.UsedPercent
+		}
+		if c, ok := counters[p.Mountpoint]; ok {
+			info.ReadBytes = c.ReadBytes
+			info.WriteBytes = c.WriteBytes
+		}
+		metrics.Disks = append(metrics.Disks, info)
+
+		if strings.EqualFold(p.Mountpoint, drive) {
+			metrics.DiskTotal = info.Total
+			metrics.DiskUsed = info.Used
+			metrics.DiskPercent = info.Percent
+		}
+	}
+
+	if ioErr != nil {
+		return fmt.Errorf("disk counters: %w", ioErr)
+	}
+	return nil
+}
+
+// computeDiskRates derives per-volume read/write rates from the previous sample.
+func computeDiskRates(cur, prev *Metrics, elapsed float64) {
+	previous := make(map[string]DiskInfo, len(prev.Disks))
+	for _, d := range prev.Disks {
+		previous[d.Mount] = d
+	}
+	for i := range cur.Disks {
+		d := &cur.Disks[i]
+		if p, ok := previous[d.Mount]; ok && d.ReadBytes >= p.ReadBytes && d.WriteBytes >= p.WriteBytes {
+			d.ReadRate = float64(d.ReadBytes-p.ReadBytes) / elapsed
+			d.WriteRate = float64(d.WriteBytes-p.WriteBytes) / elapsed
+		}
+	}
+}
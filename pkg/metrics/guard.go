@@ -0,0 +1,220 @@
+//go:build windows
+
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A collector backed by WMI or a performance counter can hang for minutes
+// when its provider misbehaves, which is common on Windows. A Guard keeps
+// that from stalling a whole sample: collectors run side by side, each call
+// gets a deadline and a few retries, a collector still stuck in an earlier
+// call is skipped, and one that keeps failing is paused for a growing
+// cool-down (a circuit breaker) before it is tried again. Skipped collectors
+// are simply not in Metrics.Sampled, so Merge carries their previous values
+// forward and Stale reports them.
+
+// Errors recorded in Metrics.Errors for guarded collectors.
+var (
+	ErrTimeout = errors.New("no answer in time")
+	ErrPaused  = errors.New("paused after repeated failures")
+)
+
+// Policy bounds how a Guard calls one collector.
+type Policy struct {
+	Timeout     time.Duration // per attempt
+	Attempts    int           // attempts per sample, including the first
+	Backoff     time.Duration // wait before the second attempt, doubled for each further one
+	BreakAfter  int           // consecutive failed samples that pause the collector
+	Cooldown    time.Duration // first pause, doubled each time it pauses again
+	MaxCooldown time.Duration
+}
+
+// DefaultPolicy suits a dashboard refreshing about once a second.
+var DefaultPolicy = Policy{
+	Timeout:     3 * time.Second,
+	Attempts:    2,
+	Backoff:     100 * time.Millisecond,
+	BreakAfter:  3,
+	Cooldown:    10 * time.Second,
+	MaxCooldown: 5 * time.Minute,
+}
+
+// breaker is the failure record of one collector.
+type breaker struct {
+	policy   Policy
+	failures int // consecutive failed samples
+	cooldown time.Duration
+	until    time.Time // paused until
+	busy     bool      // an abandoned call has not returned yet
+}
+
+// Guard runs collectors under a Policy and remembers their failures from
+// one sample to the next. It is safe for concurrent use.
+type Guard struct {
+	mu       sync.Mutex
+	policy   Policy
+	breakers map[string]*breaker
+}
+
+// NewGuard returns a Guard applying p to every collector.
+func NewGuard(p Policy) *Guard {
+	return &Guard{policy: p, breakers: make(map[string]*breaker)}
+}
+
+// SetPolicy applies p to the collector called name instead of the Guard's
+// policy.
+func (g *Guard) SetPolicy(name string, p Policy) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.breaker(name).policy = p
+}
+
+// breaker returns the record for name. g.mu must be held.
+func (g *Guard) breaker(name string) *breaker {
+	b, ok := g.breakers[name]
+	if !ok {
+		b = &breaker{policy: g.policy, cooldown: g.policy.Cooldown}
+		g.breakers[name] = b
+	}
+	return b
+}
+
+// Paused returns how much longer the collector called name is paused, or 0.
+func (g *Guard) Paused(name string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if b, ok := g.breakers[name]; ok {
+		return max(time.Until(b.until), 0)
+	}
+	return 0
+}
+
+// Collect is like the package-level Collect, except that the collectors run
+// concurrently, each under its policy. It returns once every collector has
+// answered or run out of time, so a slow one delays the sample by at most
+// its own deadline.
+func (g *Guard) Collect(list []Collector) Metrics {
+	var (
+		metrics Metrics
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+	)
+	metrics.CollectedAt = time.Now()
+	metrics.Sampled = make(map[string]time.Time, len(list))
+	metrics.Took = make(map[string]time.Duration, len(list))
+
+	for _, c := range list {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var sample Metrics
+			start := time.Now()
+			ran, err := g.run(c, &sample)
+			took := time.Since(start)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if metrics.Errors == nil {
+					metrics.Errors = make(map[string]error)
+				}
+				metrics.Errors[c.name] = err
+			}
+			if ran {
+				c.carry(&metrics, &sample)
+				metrics.Sampled[c.name] = metrics.CollectedAt
+				metrics.Took[c.name] = took
+			}
+		}()
+	}
+	wg.Wait()
+	return metrics
+}
+
+// Stale reports whether the fields of the collector called name are left
+// over from an earlier sample, because it gave no answer in time or is
+// paused.
+func (m *Metrics) Stale(name string) bool {
+	err := m.Errors[name]
+	return errors.Is(err, ErrTimeout) || errors.Is(err, ErrPaused)
+}
+
+// run calls c into dst. ran is false when c was skipped or gave no answer,
+// in which case dst is left alone.
+func (g *Guard) run(c Collector, dst *Metrics) (ran bool, err error) {
+	g.mu.Lock()
+	b := g.breaker(c.name)
+	p := b.policy
+	switch {
+	case b.busy:
+		g.failLocked(b)
+		g.mu.Unlock()
+		return false, fmt.Errorf("%w: the previous call has not returned", ErrTimeout)
+	case time.Now().Before(b.until):
+		g.mu.Unlock()
+		return false, ErrPaused
+	}
+	b.busy = true
+	g.mu.Unlock()
+
+	for attempt := 0; attempt < max(p.Attempts, 1); attempt++ {
+		if attempt > 0 {
+			time.Sleep(p.Backoff << (attempt - 1))
+		}
+		// Collect into a scratch sample so a call that is given up on
+		// cannot write into dst later.
+		var scratch Metrics
+		done := make(chan error, 1)
+		go func() { done <- c.collect(&scratch) }()
+
+		select {
+		case err = <-done:
+		case <-time.After(p.Timeout):
+			go func() {
+				<-done
+				g.mu.Lock()
+				b.busy = false
+				g.mu.Unlock()
+			}()
+			g.mu.Lock()
+			g.failLocked(b)
+			g.mu.Unlock()
+			return false, fmt.Errorf("%w after %s", ErrTimeout, p.Timeout)
+		}
+
+		// Like Collect, keep what a failing collector did fill in.
+		c.carry(dst, &scratch)
+		if err == nil {
+			break
+		}
+	}
+
+	g.mu.Lock()
+	b.busy = false
+	if err != nil {
+		g.failLocked(b)
+	} else {
+		b.failures = 0
+		b.cooldown = p.Cooldown
+	}
+	g.mu.Unlock()
+	return true, err
+}
+
+// failLocked records a failed sample, pausing the collector once it has
+// failed BreakAfter times in a row. g.mu must be held.
+func (g *Guard) failLocked(b *breaker) {
+	b.failures++
+	if b.policy.BreakAfter <= 0 || b.failures < b.policy.BreakAfter || time.Now().Before(b.until) {
+		return
+	}
+	// After a pause one more failure pauses it again, for longer.
+	b.failures = b.policy.BreakAfter - 1
+	b.until = time.Now().Add(b.cooldown)
+	b.cooldown = min(b.cooldown*2, max(b.policy.MaxCooldown, b.policy.Cooldown))
+}
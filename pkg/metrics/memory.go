@@ -0,0 +1,81 @@
+//go:build windows
+
+package metrics
+
+import (
+	"math"
+	"slices"
+	"unsafe"
+
+	"github.com/shirou/gopsutil/v3/mem"
+	"golang.org/x/sys/windows"
+)
+
+// Task Manager splits physical memory into in use, modified, standby and
+// free, and counts only memory Windows can use in its total. gopsutil's
+// used memory is the total less available (standby and free), so it also
+// holds the modified pages waiting to be written out, and reads higher.
+// The Mem fields without Raw in their name follow Task Manager.
+
+var (
+	psapi                                  = windows.NewLazySystemDLL("psapi.dll")
+	procGetPerformanceInfo                 = psapi.NewProc("GetPerformanceInfo")
+	kernel32                               = windows.NewLazySystemDLL("kernel32.dll")
+	procGetPhysicallyInstalledSystemMemory = kernel32.NewProc("GetPhysicallyInstalledSystemMemory")
+)
+
+// performanceInformation mirrors PERFORMANCE_INFORMATION.
+type performanceInformation struct {
+	cb                uint32
+	commitTotal       uintptr
+	commitLimit       uintptr
+	commitPeak        uintptr
+	physicalTotal     uintptr
+	physicalAvailable uintptr
+	systemCache       uintptr
+	kernelTotal       uintptr
+	kernelPaged       uintptr
+	kernelNonpaged    uintptr
+	pageSize          uintptr
+	handleCount       uint32
+	processCount      uint32
+	threadCount       uint32
+}
+
+// memoryListQuery reads the page lists Task Manager shows as cached.
+var memoryListQuery = newCounterQuery(
+	`\Memory\Modified Page List Bytes`,
+	`\Memory\Standby Cache Core Bytes`,
+	`\Memory\Standby Cache Normal Priority Bytes`,
+	`\Memory\Standby Cache Reserve Bytes`,
+)
+
+// taskManagerMemory fills in the Task Manager figures from gopsutil's
+// memInfo and what Windows reports besides. Figures it cannot read are
+// left at gopsutil's values, or zero.
+func taskManagerMemory(metrics *Metrics, memInfo *mem.VirtualMemoryStat) {
+	metrics.MemInUse = memInfo.Used
+	if lists, err := memoryListQuery.read(); err == nil && !slices.ContainsFunc(lists, math.IsNaN) {
+		modified := uint64(lists[0])
+		metrics.MemCached = modified + uint64(lists[1]+lists[2]+lists[3])
+		if modified < metrics.MemInUse {
+			metrics.MemInUse -= modified
+		}
+	}
+	if memInfo.Total > 0 {
+		metrics.MemInUsePercent = float64(metrics.MemInUse) / float64(memInfo.Total) * 100
+	}
+
+	var perf performanceInformation
+	perf.cb = uint32(unsafe.Sizeof(perf))
+	if r, _, _ := procGetPerformanceInfo.Call(uintptr(unsafe.Pointer(&perf)), uintptr(perf.cb)); r != 0 {
+		page := uint64(perf.pageSize)
+		metrics.MemCommitted = uint64(perf.commitTotal) * page
+		metrics.MemCommitLimit = uint64(perf.commitLimit) * page
+	}
+
+	var installedKB uint64
+	if r, _, _ := procGetPhysicallyInstalledSystemMemory.Call(uintptr(unsafe.Pointer(&installedKB))); r != 0 {
+		metrics.MemInstalled = installedKB * 1024
+	}
+}
@@ -0,0 +1,342 @@
+//go:build windows
+
+// Package metrics samples the system figures the WinMole status dashboard
+// shows: CPU, memory, volumes, network, processes, host details and signs
+// of pressure, using gopsutil and performance counters. Collectors can run
+// on their own schedules; Merge completes a partial sample from the
+// previous one and turns counters into rates.
+//
+//	prev := metrics.Collect(metrics.All())
+//	time.Sleep(time.Second)
+//	cur := metrics.Collect(metrics.All())
+//	metrics.Merge(&cur, &prev)
+//	fmt.Printf("cpu %.0f%%  down %s/s\n", cur.CPUUsage, humanize.Bytes(uint64(cur.NetRecvRate)))
+//
+// Guard.Collect runs the collectors concurrently, each with a deadline,
+// retries and a circuit breaker, for programs that sample on a timer and
+// must not stall on a hung WMI provider.
+//
+// Packages under pkg/ follow semantic versioning with the module: exported
+// names and their behaviour only change incompatibly in a new major version.
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// Metrics holds all system metrics
+type Metrics struct {
+	// CPU
+	CPUUsage  float64
+	CPUCores  int
+	CPUModel  string
+	CPUMethod string // how CPUUsage was measured; see SetCPUMethod
+
+	// Memory, as gopsutil reports it: MemUsed is MemTotal, the memory
+	// Windows can use, less what is available.
+	MemTotal   uint64
+	MemUsed    uint64
+	MemPercent float64
+
+	// Memory as Task Manager reports it: MemInUse leaves out the modified
+	// pages MemUsed includes, and MemInUsePercent is its share of
+	// MemTotal. MemInstalled less MemTotal is reserved by hardware.
+	MemInUse        uint64
+	MemInUsePercent float64
+	MemCached       uint64 // standby and modified pages
+	MemCommitted    uint64
+	MemCommitLimit  uint64
+	MemInstalled    uint64
+
+	// Disk (system drive)
+	DiskTotal   uint64
+	DiskUsed    uint64
+	DiskPercent float64
+	DiskPath    string
+
+	// Disks holds every mounted volume, including the system drive
+	Disks []DiskInfo
+
+	// Network
+	NetSent     uint64
+	NetRecv     uint64
+	NetSentRate float64
+	NetRecvRate float64
+
+	// Interfaces and connection counts for the Network tab
+	Interfaces  []InterfaceInfo
+	Connections ConnectionCounts
+
+	// Processes
+	Processes []ProcessInfo
+
+	// Pressure: signs that work is held up, beyond how busy things are
+	CPUQueue     float64 // threads ready to run but waiting for a processor
+	MemPagesIn   float64 // pages read back from disk per second (hard faults)
+	DiskActivity []DiskActivity
+	Temperature  float64 // °C of the warmest thermal zone; 0 when unknown
+	Throttle     float64 // percent the processors are slowed to cool down
+	CPUClock     float64 // MHz the processors run at now, boost included; 0 when unknown
+
+	// System
+	Hostname string
+	OS       string
+	Uptime   time.Duration
+
+	// Errors maps collector names to the error they returned this round
+	Errors map[string]error
+
+	// Sampled maps collector names to when their fields were last taken
+	Sampled map[string]time.Time
+
+	// Took maps collector names to how long their last run took
+	Took map[string]time.Duration
+
+	// Timestamp
+	CollectedAt time.Time
+}
+
+// Collector fills one group of fields in Metrics.
+type Collector struct {
+	name    string
+	collect func(*Metrics) error
+
+	// carry copies this collector's fields from the previous sample when
+	// it was skipped this round.
+	carry func(dst, src *Metrics)
+
+	// rates, if set, derives per-second values from the previous sample
+	// taken by this collector.
+	rates func(cur, prev *Metrics, elapsed float64)
+}
+
+// Name identifies the collector in Metrics.Errors and Metrics.Sampled.
+func (c Collector) Name() string {
+	return c.name
+}
+
+// Only returns a sample holding just the fields c fills from m, to see
+// what one collector reported.
+func (c Collector) Only(m *Metrics) Metrics {
+	var only Metrics
+	c.carry(&only, m)
+	return only
+}
+
+// The collectors.
+var (
+	CPU       = Collector{name: "cpu", collect: collectCPU, carry: carryCPU}
+	Memory    = Collector{name: "memory", collect: collectMemory, carry: carryMemory}
+	Disk      = Collector{name: "disk", collect: collectDisks, carry: carryDisks, rates: computeDiskRates}
+	Network   = Collector{name: "network", collect: collectNetwork, carry: carryNetwork, rates: computeNetworkRates}
+	Processes = Collector{name: "processes", collect: collectProcesses, carry: carryProcesses, rates: computeProcessCPU}
+	Host      = Collector{name: "host", collect: collectHost, carry: carryHost}
+	Pressure  = Collector{name: "pressure", collect: collectPressure, carry: carryPressure}
+)
+
+// All returns every collector in run order.
+func All() []Collector {
+	return []Collector{CPU, Memory, Disk, Network, Processes, Host, Pressure}
+}
+
+// Collect takes one sample using the given collectors. Errors are
+// recorded per collector in Metrics.Errors; the other fields are still filled.
+func Collect(list []Collector) Metrics {
+	var metrics Metrics
+	metrics.CollectedAt = time.Now()
+	metrics.Sampled = make(map[string]time.Time, len(list))
+	metrics.Took = make(map[string]time.Duration, len(list))
+
+	for _, c := range list {
+		start := time.Now()
+		err := c.collect(&metrics)
+		metrics.Took[c.name] = time.Since(start)
+		if err != nil {
+			if metrics.Errors == nil {
+				metrics.Errors = make(map[string]error)
+			}
+			metrics.Errors[c.name] = err
+		}
+		metrics.Sampled[c.name] = metrics.CollectedAt
+	}
+	return metrics
+}
+
+// Merge completes a sample taken by a subset of collectors: skipped
+// collectors carry their previous values forward, and collectors that ran
+// derive rates against their own previous sample.
+func Merge(cur, prev *Metrics) {
+	if cur.Sampled == nil {
+		cur.Sampled = make(map[string]time.Time)
+	}
+	for _, c := range All() {
+		at, ran := cur.Sampled[c.name]
+		if !ran {
+			c.carry(cur, prev)
+			if err, failed := prev.Errors[c.name]; failed {
+				if cur.Errors == nil {
+					cur.Errors = make(map[string]error)
+				}
+				cur.Errors[c.name] = err
+			}
+			if before, ok := prev.Sampled[c.name]; ok {
+				cur.Sampled[c.name] = before
+			}
+			if took, ok := prev.Took[c.name]; ok {
+				if cur.Took == nil {
+					cur.Took = make(map[string]time.Duration)
+				}
+				cur.Took[c.name] = took
+			}
+			continue
+		}
+		if c.rates == nil {
+			continue
+		}
+		if before, ok := prev.Sampled[c.name]; ok {
+			if elapsed := at.Sub(before).Seconds(); elapsed > 0 {
+				c.rates(cur, prev, elapsed)
+			}
+		}
+	}
+}
+
+func carryCPU(dst, src *Metrics) {
+	dst.CPUUsage = src.CPUUsage
+	dst.CPUCores = src.CPUCores
+	dst.CPUModel = src.CPUModel
+	dst.CPUMethod = src.CPUMethod
+}
+
+func carryMemory(dst, src *Metrics) {
+	dst.MemTotal = src.MemTotal
+	dst.MemUsed = src.MemUsed
+	dst.MemPercent = src.MemPercent
+	dst.MemInUse = src.MemInUse
+	dst.MemInUsePercent = src.MemInUsePercent
+	dst.MemCached = src.MemCached
+	dst.MemCommitted = src.MemCommitted
+	dst.MemCommitLimit = src.MemCommitLimit
+	dst.MemInstalled = src.MemInstalled
+}
+
+func carryDisks(dst, src *Metrics) {
+	dst.DiskTotal = src.DiskTotal
+	dst.DiskUsed = src.DiskUsed
+	dst.DiskPercent = src.DiskPercent
+	dst.DiskPath = src.DiskPath
+	dst.Disks = src.Disks
+}
+
+func carryNetwork(dst, src *Metrics) {
+	dst.NetSent = src.NetSent
+	dst.NetRecv = src.NetRecv
+	dst.NetSentRate = src.NetSentRate
+	dst.NetRecvRate = src.NetRecvRate
+	dst.Interfaces = src.Interfaces
+	dst.Connections = src.Connections
+}
+
+func carryProcesses(dst, src *Metrics) {
+	dst.Processes = src.Processes
+}
+
+func carryHost(dst, src *Metrics) {
+	dst.Hostname = src.Hostname
+	dst.OS = src.OS
+	dst.Uptime = src.Uptime
+}
+
+// computeNetworkRates derives aggregate and per-interface rates.
+func computeNetworkRates(cur, prev *Metrics, elapsed float64) {
+	computeNetRates(cur, prev, elapsed)
+	computeInterfaceRates(cur, prev, elapsed)
+}
+
+// computeNetRates derives the aggregate network rates from the previous sample.
+func computeNetRates(cur, prev *Metrics, elapsed float64) {
+	if cur.NetSent >= prev.NetSent && cur.NetRecv >= prev.NetRecv {
+		cur.NetSentRate = float64(cur.NetSent-prev.NetSent) / elapsed
+		cur.NetRecvRate = float64(cur.NetRecv-prev.NetRecv) / elapsed
+	}
+}
+
+func collectCPU(metrics *Metrics) error {
+	metrics.CPUCores = runtime.NumCPU()
+
+	// Busy time is always sampled so it is ready to stand in for utility.
+	cpuPercent, err := cpu.Percent(0, false)
+	if err != nil {
+		return fmt.Errorf("cpu usage: %w", err)
+	}
+	if len(cpuPercent) > 0 {
+		metrics.CPUUsage = cpuPercent[0]
+	}
+	metrics.CPUMethod = CPUMethodTime
+	if currentCPUMethod() == CPUMethodUtility {
+		if utility, err := processorUtility(); err == nil {
+			metrics.CPUUsage = utility
+			metrics.CPUMethod = CPUMethodUtility
+		}
+	}
+
+	cpuInfo, err := cpu.Info()
+	if err != nil {
+		return fmt.Errorf("cpu info: %w", err)
+	}
+	if len(cpuInfo) > 0 {
+		metrics.CPUModel = cpuInfo[0].ModelName
+	}
+	return nil
+}
+
+func collectMemory(metrics *Metrics) error {
+	memInfo, err := mem.VirtualMemory()
+	if err != nil {
+		return fmt.Errorf("virtual memory: %w", err)
+	}
+	metrics.MemTotal = memInfo.Total
+	metrics.MemUsed = memInfo.Used
+	metrics.MemPercent = memInfo.UsedPercent
+	taskManagerMemory(metrics, memInfo)
+	return nil
+}
+
+func collectNetwork(metrics *Metrics) error {
+	netInfo, err := net.IOCounters(false)
+	if err != nil {
+		return fmt.Errorf("network counters: %w", err)
+	}
+	if len(netInfo) > 0 {
+		metrics.NetSent = netInfo[0].BytesSent
+		metrics.NetRecv = netInfo[0].BytesRecv
+	}
+	return collectInterfaces(metrics)
+}
+
+func collectHost(metrics *Metrics) error {
+	hostInfo, err := host.Info()
+	if err != nil {
+		return fmt.Errorf("host info: %w", err)
+	}
+	metrics.Hostname = hostInfo.Hostname
+	metrics.OS = fmt.Sprintf("%s %s", hostInfo.Platform, hostInfo.PlatformVersion)
+	metrics.Uptime = time.Duration(hostInfo.Uptime) * time.Second
+	return nil
+}
+
+// SystemDrive returns the drive letter Windows booted from, e.g. "C:".
+func SystemDrive() string {
+	if drive := os.Getenv("SystemDrive"); drive != "" {
+		return drive
+	}
+	return "C:"
+}
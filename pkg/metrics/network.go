@@ -0,0 +1,78 @@
+//go:build windows
+
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// InterfaceInfo holds traffic counters for one network interface.
+type InterfaceInfo struct {
+	Name     string
+	Sent     uint64
+	Recv     uint64
+	SentRate float64
+	RecvRate float64
+}
+
+// ConnectionCounts summarizes the socket table.
+type ConnectionCounts struct {
+	Established int
+	Listening   int
+	TimeWait    int
+	UDP         int
+}
+
+// collectInterfaces fills the per-interface counters and socket summary.
+func collectInterfaces(metrics *Metrics) error {
+	counters, err := net.IOCounters(true)
+	if err != nil {
+		return fmt.Errorf("interface counters: %w", err)
+	}
+	for _, c := range counters {
+		// Skip adapters that have never moved a byte (virtual, disabled)
+		if c.BytesSent == 0 && c.BytesRecv == 0 {
+			continue
+		}
+		metrics.Interfaces = append(metrics.Interfaces, InterfaceInfo{
+			Name: c.Name,
+			Sent: c.BytesSent,
+			Recv: c.BytesRecv,
+		})
+	}
+
+	conns, err := net.Connections("inet")
+	if err != nil {
+		return fmt.Errorf("connections: %w", err)
+	}
+	for _, c := range conns {
+		switch {
+		case c.Type == 2: // SOCK_DGRAM
+			metrics.Connections.UDP++
+		case c.Status == "ESTABLISHED":
+			metrics.Connections.Established++
+		case c.Status == "LISTEN":
+			metrics.Connections.Listening++
+		case c.Status == "TIME_WAIT":
+			metrics.Connections.TimeWait++
+		}
+	}
+	return nil
+}
+
+// computeInterfaceRates derives per-interface rates from the previous sample.
+func computeInterfaceRates(cur, prev *Metrics, elapsed float64) {
+	previous := make(map[string]InterfaceInfo, len(prev.Interfaces))
+	for _, iface := range prev.Interfaces {
+		previous[iface.Name] = iface
+	}
+	for i := range cur.Interfaces {
+		iface := &cur.Interfaces[i]
+		if p, ok := previous[iface.Name]; ok && iface.Sent >= p.Sent && iface.Recv >= p.Recv {
+			iface.SentRate = float64(iface.Sent-p.Sent) / elapsed
+			iface.RecvRate = float64(iface.Recv-p.Recv) / elapsed
+		}
+	}
+}
@@ -0,0 +1,159 @@
+//go:build windows
+
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	pdh                             = windows.NewLazySystemDLL("pdh.dll")
+	procPdhOpenQuery                = pdh.NewProc("PdhOpenQueryW")
+	procPdhAddEnglishCounter        = pdh.NewProc("PdhAddEnglishCounterW")
+	procPdhCollectQueryData         = pdh.NewProc("PdhCollectQueryData")
+	procPdhGetFormattedCounterValue = pdh.NewProc("PdhGetFormattedCounterValue")
+	procPdhGetFormattedCounterArray = pdh.NewProc("PdhGetFormattedCounterArrayW")
+)
+
+const (
+	pdhFmtDouble   = 0x00000200
+	pdhFmtNoCap100 = 0x00008000
+	pdhMoreData    = 0x800007D2
+	pdhNewData     = 0x00000001 // highest CStatus of a valid value
+)
+
+// pdhCounterValue mirrors PDH_FMT_COUNTERVALUE holding a double.
+type pdhCounterValue struct {
+	CStatus uint32
+	_       uint32
+	Value   float64
+}
+
+// pdhCounterItem mirrors PDH_FMT_COUNTERVALUE_ITEM_W.
+type pdhCounterItem struct {
+	Name  *uint16
+	Value pdhCounterValue
+}
+
+// counterQuery reads a fixed set of performance counters, opening the
+// query on first use. Counters missing on this machine, such as thermal
+// zones in a virtual machine, read as unknown rather than failing the
+// rest. Rates cover the time since the previous read, so the first read
+// of a rate counter is unknown too.
+type counterQuery struct {
+	paths []string
+
+	mu       sync.Mutex
+	query    uintptr
+	counters []uintptr // 0 where the counter could not be added
+	err      error
+	opened   bool
+}
+
+func newCounterQuery(paths ...string) *counterQuery {
+	return &counterQuery{paths: paths}
+}
+
+// read returns the value of each counter, in the order they were given,
+// or NaN where it is unknown.
+func (q *counterQuery) read() ([]float64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.collect(); err != nil {
+		return nil, err
+	}
+
+	values := make([]float64, len(q.counters))
+	for i, c := range q.counters {
+		values[i] = math.NaN()
+		if c == 0 {
+			continue
+		}
+		var v pdhCounterValue
+		r, _, _ := procPdhGetFormattedCounterValue.Call(c, pdhFmtDouble|pdhFmtNoCap100, 0, uintptr(unsafe.Pointer(&v)))
+		if r == 0 && v.CStatus <= pdhNewData {
+			values[i] = v.Value
+		}
+	}
+	return values, nil
+}
+
+// readInstances returns the values of each counter by instance name, for
+// paths with a (*) wildcard. Totals ("_Total") and unknown values are left
+// out.
+func (q *counterQuery) readInstances() ([]map[string]float64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.collect(); err != nil {
+		return nil, err
+	}
+
+	values := make([]map[string]float64, len(q.counters))
+	for i, c := range q.counters {
+		values[i] = make(map[string]float64)
+		if c == 0 {
+			continue
+		}
+		var size, count uint32
+		r, _, _ := procPdhGetFormattedCounterArray.Call(c, pdhFmtDouble|pdhFmtNoCap100,
+			uintptr(unsafe.Pointer(&size)), uintptr(unsafe.Pointer(&count)), 0)
+		if r != pdhMoreData || size == 0 {
+			continue
+		}
+		// The buffer holds the items followed by their names.
+		items := make([]pdhCounterItem, size/uint32(unsafe.Sizeof(pdhCounterItem{}))+1)
+		r, _, _ = procPdhGetFormattedCounterArray.Call(c, pdhFmtDouble|pdhFmtNoCap100,
+			uintptr(unsafe.Pointer(&size)), uintptr(unsafe.Pointer(&count)), uintptr(unsafe.Pointer(&items[0])))
+		if r != 0 {
+			continue
+		}
+		for _, item := range items[:count] {
+			name := windows.UTF16PtrToString(item.Name)
+			if item.Value.CStatus <= pdhNewData && !strings.EqualFold(name, "_Total") {
+				values[i][name] = item.Value.Value
+			}
+		}
+	}
+	return values, nil
+}
+
+// collect opens the query if needed and takes a sample. q.mu must be held.
+func (q *counterQuery) collect() error {
+	if !q.opened {
+		q.opened = true
+		q.err = q.open()
+	}
+	if q.err != nil {
+		return q.err
+	}
+	if r, _, _ := procPdhCollectQueryData.Call(q.query); r != 0 {
+		return fmt.Errorf("collect performance counters: PDH status %#x", r)
+	}
+	return nil
+}
+
+// open opens the query and adds the counters this machine has. It fails
+// where the performance counters are disabled or corrupt.
+func (q *counterQuery) open() error {
+	if err := pdh.Load(); err != nil {
+		return err
+	}
+	if r, _, _ := procPdhOpenQuery.Call(0, 0, uintptr(unsafe.Pointer(&q.query))); r != 0 {
+		return fmt.Errorf("open performance counter query: PDH status %#x", r)
+	}
+	q.counters = make([]uintptr, len(q.paths))
+	for i, path := range q.paths {
+		p, err := windows.UTF16PtrFromString(path)
+		if err != nil {
+			return err
+		}
+		procPdhAddEnglishCounter.Call(q.query, uintptr(unsafe.Pointer(p)), 0, uintptr(unsafe.Pointer(&q.counters[i])))
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+//go:build windows
+
+package metrics
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// How busy a resource is says little on its own: a CPU at 100% with no
+// queue is merely well used, while a disk at 30% throughput can be the
+// bottleneck if it is busy all the time. The pressure collector reads the
+// figures that show a resource holding work up, from performance counters.
+
+// DiskActivity is how busy one volume was over the last sample.
+type DiskActivity struct {
+	Mount  string  // "C:"
+	Active float64 // percent of the time the volume was busy
+	Queue  float64 // requests waiting
+}
+
+// pressureQuery holds the single-instance counters, pressureInstances the
+// per-volume and per-thermal-zone ones. The clock is read here too: a
+// processor held below its base clock under load is the other face of
+// throttling.
+var (
+	pressureQuery = newCounterQuery(
+		`\System\Processor Queue Length`,
+		`\Memory\Pages Input/sec`,
+		`\Processor Information(_Total)\Processor Frequency`,
+		`\Processor Information(_Total)\% Processor Performance`,
+	)
+	pressureInstances = newCounterQuery(
+		`\LogicalDisk(*)\% Idle Time`,
+		`\LogicalDisk(*)\Current Disk Queue Length`,
+		`\Thermal Zone Information(*)\Temperature`,
+		`\Thermal Zone Information(*)\% Passive Limit`,
+	)
+)
+
+func collectPressure(metrics *Metrics) error {
+	values, err := pressureQuery.read()
+	if err != nil {
+		return err
+	}
+	metrics.CPUQueue = zeroIfNaN(values[0])
+	metrics.MemPagesIn = zeroIfNaN(values[1])
+	// Performance is relative to the base frequency and passes 100% when
+	// boosting.
+	if base, perf := zeroIfNaN(values[2]), zeroIfNaN(values[3]); base > 0 {
+		metrics.CPUClock = base * perf / 100
+	}
+
+	instances, err := pressureInstances.readInstances()
+	if err != nil {
+		return err
+	}
+	idle, queue := instances[0], instances[1]
+	for mount, idleTime := range idle {
+		if !strings.HasSuffix(mount, ":") {
+			continue // volumes without a drive letter
+		}
+		metrics.DiskActivity = append(metrics.DiskActivity, DiskActivity{
+			Mount:  mount,
+			Active: max(100-idleTime, 0),
+			Queue:  queue[mount],
+		})
+	}
+	sort.Slice(metrics.DiskActivity, func(i, j int) bool {
+		return metrics.DiskActivity[i].Mount < metrics.DiskActivity[j].Mount
+	})
+
+	// Zone temperatures are in kelvin; a passive limit below 100% means the
+	// zone is slowing the processors down to cool them.
+	for _, kelvin := range instances[2] {
+		if kelvin > 0 {
+			metrics.Temperature = max(metrics.Temperature, kelvin-273.15)
+		}
+	}
+	for _, limit := range instances[3] {
+		if limit > 0 {
+			metrics.Throttle = max(metrics.Throttle, 100-limit)
+		}
+	}
+	return nil
+}
+
+func carryPressure(dst, src *Metrics) {
+	dst.CPUQueue = src.CPUQueue
+	dst.MemPagesIn = src.MemPagesIn
+	dst.DiskActivity = src.DiskActivity
+	dst.Temperature = src.Temperature
+	dst.Throttle = src.Throttle
+	dst.CPUClock = src.CPUClock
+}
+
+func zeroIfNaN(v float64) float64 {
+	if math.IsNaN(v) {
+		return 0
+	}
+	return v
+}
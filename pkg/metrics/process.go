@@ -0,0 +1,66 @@
+//go:build windows
+
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ProcessInfo is one running process.
+type ProcessInfo struct {
+	PID        int32
+	Name       string
+	CPUTime    float64 // user+system seconds since process start
+	CPUPercent float64 // share of total machine capacity since the last sample
+	Memory     uint64  // working set
+	ReadBytes  uint64  // disk and other I/O read since process start
+	WriteBytes uint64  // disk and other I/O written since process start
+}
+
+func collectProcesses(metrics *Metrics) error {
+	procs, err := process.Processes()
+	if err != nil {
+		return fmt.Errorf("process list: %w", err)
+	}
+
+	for _, p := range procs {
+		info := ProcessInfo{PID: p.Pid}
+		// Protected and exiting processes refuse queries; list what we can.
+		if name, err := p.Name(); err == nil {
+			info.Name = name
+		}
+		if times, err := p.Times(); err == nil {
+			info.CPUTime = times.User + times.System
+		}
+		if mem, err := p.MemoryInfo(); err == nil {
+			info.Memory = mem.RSS
+		}
+		if io, err := p.IOCounters(); err == nil {
+			info.ReadBytes = io.ReadBytes
+			info.WriteBytes = io.WriteBytes
+		}
+		metrics.Processes = append(metrics.Processes, info)
+	}
+	return nil
+}
+
+// computeProcessCPU converts CPU time deltas into Task Manager style
+// percentages, where 100% means every logical core is busy.
+func computeProcessCPU(cur, prev *Metrics, elapsed float64) {
+	cores := float64(cur.CPUCores)
+	if cores < 1 {
+		cores = 1
+	}
+	previous := make(map[int32]float64, len(prev.Processes))
+	for _, p := range prev.Processes {
+		previous[p.PID] = p.CPUTime
+	}
+	for i := range cur.Processes {
+		p := &cur.Processes[i]
+		if before, ok := previous[p.PID]; ok && p.CPUTime >= before {
+			p.CPUPercent = (p.CPUTime - before) / elapsed / cores * 100
+		}
+	}
+}
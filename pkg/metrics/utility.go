@@ -0,0 +1,58 @@
+//go:build windows
+
+package metrics
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+)
+
+// Since Windows 8 Task Manager shows CPU usage as processor utility: busy
+// time scaled by the clock speed the cores actually ran at, so a boosted
+// core counts for more than 100% of its time and a parked, slowed one for
+// less. gopsutil reports plain busy time, which can differ from Task
+// Manager by tens of points on laptops. SetCPUMethod picks which one the
+// CPU collector reports.
+
+// CPU usage methods for SetCPUMethod and Metrics.CPUMethod.
+const (
+	CPUMethodTime    = "time"    // share of time the cores were busy
+	CPUMethodUtility = "utility" // "% Processor Utility", as Task Manager shows
+)
+
+var cpuMethod atomic.Value // string
+
+// SetCPUMethod chooses how the CPU collector measures usage, CPUMethodTime
+// (the default) or CPUMethodUtility. Where the utility counter cannot be
+// read, busy time is reported and Metrics.CPUMethod says so.
+func SetCPUMethod(method string) {
+	if method != CPUMethodUtility {
+		method = CPUMethodTime
+	}
+	cpuMethod.Store(method)
+}
+
+func currentCPUMethod() string {
+	if method, ok := cpuMethod.Load().(string); ok {
+		return method
+	}
+	return CPUMethodTime
+}
+
+// utilityQuery reads the counter Task Manager shows.
+var utilityQuery = newCounterQuery(`\Processor Information(_Total)\% Processor Utility`)
+
+// processorUtility returns "% Processor Utility" since the last call, capped
+// at 100 as Task Manager does. The first call has nothing to compare with
+// and fails.
+func processorUtility() (float64, error) {
+	values, err := utilityQuery.read()
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(values[0]) {
+		return 0, errors.New("processor utility is not available yet")
+	}
+	return min(values[0], 100), nil
+}
@@ -0,0 +1,84 @@
+//go:build windows
+
+package scan
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	kernel32                  = windows.NewLazySystemDLL("kernel32.dll")
+	procGetCompressedFileSize = kernel32.NewProc("GetCompressedFileSizeW")
+	procGetDiskFreeSpace      = kernel32.NewProc("GetDiskFreeSpaceW")
+)
+
+// invalidFileSize is what GetCompressedFileSizeW returns on failure, or as
+// the low half of a size that happens to end in it.
+const invalidFileSize = 0xFFFFFFFF
+
+// Files whose allocation is not their size rounded up to a cluster:
+// NTFS-compressed, sparse, cloud placeholders not downloaded yet, and
+// files compressed with compact /exe, which are reparse points.
+const unevenAttributes = windows.FILE_ATTRIBUTE_COMPRESSED |
+	windows.FILE_ATTRIBUTE_SPARSE_FILE |
+	windows.FILE_ATTRIBUTE_RECALL_ON_DATA_ACCESS |
+	windows.FILE_ATTRIBUTE_REPARSE_POINT
+
+// allocated returns the bytes the file at path takes on disk, as
+// Explorer's "Size on disk" shows it. Files that opening could download are
+// taken to have nothing on disk.
+func allocated(path string, info fs.FileInfo) int64 {
+	switch attrs := attributes(info); {
+	case attrs&remoteAttributes != 0:
+		return 0
+	case attrs&unevenAttributes != 0:
+		if n, err := compressedFileSize(path); err == nil {
+			return n
+		}
+	}
+	cluster := clusterSize(path)
+	return (info.Size() + cluster - 1) / cluster * cluster
+}
+
+// compressedFileSize asks NTFS how much of the file is allocated.
+func compressedFileSize(path string) (int64, error) {
+	p, err := windows.UTF16PtrFromString(extendedPath(path))
+	if err != nil {
+		return 0, err
+	}
+	var high uint32
+	low, _, err := procGetCompressedFileSize.Call(uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&high)))
+	if uint32(low) == invalidFileSize && err != windows.ERROR_SUCCESS {
+		return 0, err
+	}
+	return int64(high)<<32 | int64(uint32(low)), nil
+}
+
+// clusters caches the cluster size of each volume by its root.
+var clusters sync.Map
+
+// clusterSize returns the allocation unit of the volume holding path,
+// assuming the NTFS default of 4 KB when it cannot be read.
+func clusterSize(path string) int64 {
+	root := filepath.VolumeName(path) + `\`
+	if v, ok := clusters.Load(root); ok {
+		return v.(int64)
+	}
+	size := int64(4096)
+	if p, err := windows.UTF16PtrFromString(root); err == nil {
+		var sectorsPerCluster, bytesPerSector, free, total uint32
+		r, _, _ := procGetDiskFreeSpace.Call(uintptr(unsafe.Pointer(p)),
+			uintptr(unsafe.Pointer(&sectorsPerCluster)), uintptr(unsafe.Pointer(&bytesPerSector)),
+			uintptr(unsafe.Pointer(&free)), uintptr(unsafe.Pointer(&total)))
+		if r != 0 && sectorsPerCluster*bytesPerSector > 0 {
+			size = int64(sectorsPerCluster) * int64(bytesPerSector)
+		}
+	}
+	clusters.Store(root, size)
+	return size
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package scan
+
+import (
+	"io/fs"
+	"time"
+)
+
+// AccessTime returns the zero time; access times are only read on Windows.
+func AccessTime(info fs.FileInfo) time.Time {
+	return time.Time{}
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package scan
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// AccessTime returns when the file info describes was last read, or the
+// zero time when info does not carry it. NTFS records it lazily, up to an
+// hour late, and not at all when last-access updates are turned off.
+func AccessTime(info fs.FileInfo) time.Time {
+	if d, ok := info.Sys().(*syscall.Win32FileAttributeData); ok && d.LastAccessTime.Nanoseconds() > 0 {
+		return time.Unix(0, d.LastAccessTime.Nanoseconds())
+	}
+	return time.Time{}
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package scan
+
+import "errors"
+
+// EnableBackupPrivilege is only supported on Windows.
+func EnableBackupPrivilege() error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,44 @@
+//go:build windows
+
+package scan
+
+import (
+	"errors"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procAdjustTokenPrivileges = windows.NewLazySystemDLL("advapi32.dll").NewProc("AdjustTokenPrivileges")
+
+// EnableBackupPrivilege lets this process read every folder, whatever its
+// permissions, as backup software does, so that folders closed even to
+// administrators (System Volume Information, other users' profiles) are
+// measured instead of reported by Unreadable. It needs an elevated process
+// and applies to the whole process for the rest of its life.
+func EnableBackupPrivilege() error {
+	var token windows.Token
+	if err := windows.OpenProcessToken(windows.CurrentProcess(), windows.TOKEN_ADJUST_PRIVILEGES|windows.TOKEN_QUERY, &token); err != nil {
+		return err
+	}
+	defer token.Close()
+
+	var luid windows.LUID
+	if err := windows.LookupPrivilegeValue(nil, windows.StringToUTF16Ptr("SeBackupPrivilege"), &luid); err != nil {
+		return err
+	}
+	privileges := windows.Tokenprivileges{
+		PrivilegeCount: 1,
+		Privileges:     [1]windows.LUIDAndAttributes{{Luid: luid, Attributes: windows.SE_PRIVILEGE_ENABLED}},
+	}
+	// AdjustTokenPrivileges succeeds without granting a privilege the token
+	// does not hold, and says so only in the last error.
+	r, _, err := procAdjustTokenPrivileges.Call(uintptr(token), 0, uintptr(unsafe.Pointer(&privileges)), 0, 0, 0)
+	switch {
+	case r == 0:
+		return err
+	case err == windows.ERROR_NOT_ALL_ASSIGNED:
+		return errors.New("SeBackupPrivilege is not held; run elevated")
+	}
+	return nil
+}
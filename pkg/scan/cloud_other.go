@@ -0,0 +1,11 @@
+//go:build !windows
+
+package scan
+
+import "io/fs"
+
+// placeholder reports no cloud placeholders; they are only recognised on
+// Windows.
+func placeholder(info fs.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,40 @@
+//go:build windows
+
+package scan
+
+import (
+	"io/fs"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// OneDrive and other sync clients leave files that are only in the cloud
+// as placeholders: full size, but little or nothing on disk until they are
+// read. A scan counts their size in Entry.Cloud and must never read them,
+// which would download them. Listing a folder and opening a file for its
+// attributes, as the scan does, leaves them alone; files that even an open
+// would fetch are not opened at all.
+
+// Attributes of a file whose contents are not all on disk.
+const placeholderAttributes = windows.FILE_ATTRIBUTE_RECALL_ON_DATA_ACCESS |
+	windows.FILE_ATTRIBUTE_RECALL_ON_OPEN |
+	windows.FILE_ATTRIBUTE_OFFLINE
+
+// Attributes of a file that opening may download or recall.
+const remoteAttributes = windows.FILE_ATTRIBUTE_RECALL_ON_OPEN |
+	windows.FILE_ATTRIBUTE_OFFLINE
+
+// attributes returns the file attributes info was read with, or 0.
+func attributes(info fs.FileInfo) uint32 {
+	if d, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		return d.FileAttributes
+	}
+	return 0
+}
+
+// placeholder reports whether info is a cloud placeholder not fully
+// downloaded.
+func placeholder(info fs.FileInfo) bool {
+	return attributes(info)&placeholderAttributes != 0
+}
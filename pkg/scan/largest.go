@@ -0,0 +1,74 @@
+package scan
+
+import (
+	"container/heap"
+	"path/filepath"
+	"sync"
+)
+
+// Largest keeps the largest files offered to it, up to a fixed number,
+// without holding on to the rest. Set it on Counters to collect the biggest
+// files anywhere below a folder while Dir or Size walks it:
+//
+//	progress := scan.Counters{Largest: scan.NewLargest(20)}
+//	scan.Dir(`D:\`, &progress)
+//	for _, f := range progress.Largest.Files() {
+//		fmt.Println(humanize.Bytes(f.Size), f.Path)
+//	}
+//
+// It is safe for concurrent use; a nil *Largest ignores files.
+type Largest struct {
+	mu    sync.Mutex
+	limit int
+	files fileHeap
+}
+
+// NewLargest returns a Largest that keeps the n largest files.
+func NewLargest(n int) *Largest {
+	return &Largest{limit: max(n, 0)}
+}
+
+// Add offers the file at path of the given size.
+func (l *Largest) Add(path string, size int64) {
+	if l == nil || l.limit == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.files) == l.limit {
+		if size <= l.files[0].Size {
+			return
+		}
+		heap.Pop(&l.files)
+	}
+	heap.Push(&l.files, Entry{Name: filepath.Base(path), Path: path, Size: size, Files: 1})
+}
+
+// Files returns the files kept so far, largest first.
+func (l *Largest) Files() []Entry {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	files := make([]Entry, len(l.files))
+	copy(files, l.files)
+	l.mu.Unlock()
+	SortBySize(files)
+	return files
+}
+
+// fileHeap is a min-heap on size, so the smallest kept file is evicted
+// first.
+type fileHeap []Entry
+
+func (h fileHeap) Len() int           { return len(h) }
+func (h fileHeap) Less(i, j int) bool { return h[i].Size < h[j].Size }
+func (h fileHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *fileHeap) Push(x any)        { *h = append(*h, x.(Entry)) }
+
+func (h *fileHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,54 @@
+package scan
+
+import "sync"
+
+// Links recognises further hard links to files a scan has already counted,
+// so a file linked from several folders (most of Windows' WinSxS store) can
+// be counted once. Set it on Counters and the scan records the size of the
+// extra links in Entry.Linked and Entry.LinkedAlloc:
+//
+//	progress := scan.Counters{Links: scan.NewLinks()}
+//	entries, _, _ := scan.Dir(`C:\Windows`, &progress)
+//	for _, e := range entries {
+//		fmt.Println(e.Name, humanize.Bytes(e.Size-e.Linked))
+//	}
+//
+// Which of a file's links is counted first depends on the walk order, but
+// the totals of a folder holding all of them do not. Checking links means
+// opening every file, which slows a scan down noticeably; files are only
+// opened to read their attributes, so cloud placeholders are not
+// downloaded. It is safe for concurrent use; a nil *Links counts every link.
+type Links struct {
+	mu   sync.Mutex
+	seen map[fileID]struct{}
+}
+
+// fileID identifies a file independently of the names linking to it.
+type fileID struct {
+	volume uint32
+	index  uint64
+}
+
+// NewLinks returns an empty Links.
+func NewLinks() *Links {
+	return &Links{seen: make(map[fileID]struct{})}
+}
+
+// counted reports whether the file at path is a further link to a file
+// already counted, and remembers it otherwise.
+func (l *Links) counted(path string) bool {
+	if l == nil {
+		return false
+	}
+	id, links, ok := fileLinks(path)
+	if !ok || links < 2 {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, seen := l.seen[id]; seen {
+		return true
+	}
+	l.seen[id] = struct{}{}
+	return false
+}
@@ -0,0 +1,80 @@
+package scan
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Symbolic links, junctions and mount points are listed with their target
+// in Entry.Target but not measured: what they lead to is usually counted
+// where it really lives (C:\Users\...\Application Data leads back into
+// AppData), and a link to one of its own parents would never end. Set
+// Counters.FollowLinks to measure them anyway; each target is then measured
+// once per scan, and never one that contains the link.
+
+// LinkTarget returns where the symbolic link, junction or mount point at
+// path leads, or "" when path is not one. Other reparse points, such as
+// cloud placeholders and deduplicated files, are not links.
+func LinkTarget(path string) string {
+	target, err := os.Readlink(path)
+	if err != nil || target == "" {
+		return ""
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	return filepath.Clean(target)
+}
+
+// maybeLink reports whether an entry of this type can be a link, which
+// saves asking about every file and folder.
+func maybeLink(mode fs.FileMode) bool {
+	return mode&(fs.ModeSymlink|fs.ModeIrregular) != 0
+}
+
+// mayFollow reports whether the link at path may be followed to target:
+// not into its own ancestors, and only the first time target is reached.
+// followed holds the lower-cased targets reached so far.
+func mayFollow(followed *sync.Map, path, target string) bool {
+	key := strings.ToLower(target)
+	link := strings.ToLower(path)
+	if link == key || strings.HasPrefix(link, strings.TrimSuffix(key, string(filepath.Separator))+string(filepath.Separator)) {
+		return false
+	}
+	_, seen := followed.LoadOrStore(key, true)
+	return !seen
+}
+
+// follow measures what the link at path leads to, if it may be followed.
+func (progress *Counters) follow(ctx context.Context, path, target string) (t Totals) {
+	if !mayFollow(&progress.followed, path, target) {
+		return t
+	}
+	info, err := os.Stat(target)
+	switch {
+	case err != nil:
+	case progress.excluded(target, info.IsDir()):
+	case info.IsDir():
+		t = Tree(ctx, target, progress)
+	default:
+		t.Files = 1
+		t.add(target, info, progress)
+	}
+	return t
+}
+
+// merge adds o to t.
+func (t *Totals) merge(o Totals) {
+	t.Size += o.Size
+	t.Alloc += o.Alloc
+	t.Cloud += o.Cloud
+	t.Linked += o.Linked
+	t.LinkedAlloc += o.LinkedAlloc
+	t.Streams += o.Streams
+	t.Files += o.Files
+	t.Dirs += o.Dirs
+}
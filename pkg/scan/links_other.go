@@ -0,0 +1,8 @@
+//go:build !windows
+
+package scan
+
+// fileLinks reports no links; hard links are only detected on Windows.
+func fileLinks(path string) (id fileID, links uint32, ok bool) {
+	return fileID{}, 0, false
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package scan
+
+import "golang.org/x/sys/windows"
+
+// fileLinks returns the identity and hard link count of the file at path.
+func fileLinks(path string) (id fileID, links uint32, ok bool) {
+	p, err := windows.UTF16PtrFromString(extendedPath(path))
+	if err != nil {
+		return fileID{}, 0, false
+	}
+	h, err := windows.CreateFile(p, windows.FILE_READ_ATTRIBUTES,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE, nil,
+		windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS|windows.FILE_FLAG_OPEN_REPARSE_POINT, 0)
+	if err != nil {
+		return fileID{}, 0, false
+	}
+	defer windows.CloseHandle(h)
+
+	var info windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(h, &info); err != nil {
+		return fileID{}, 0, false
+	}
+	id = fileID{
+		volume: info.VolumeSerialNumber,
+		index:  uint64(info.FileIndexHigh)<<32 | uint64(info.FileIndexLow),
+	}
+	return id, info.NumberOfLinks, true
+}
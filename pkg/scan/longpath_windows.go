@@ -0,0 +1,28 @@
+//go:build windows
+
+package scan
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// longPathLimit is the length from which the os package, and so this one,
+// switches to extended paths: MAX_PATH less room for an 8.3 file name,
+// the limit for directories.
+const longPathLimit = 248
+
+// extendedPath returns path in the \\?\ form that Win32 calls accept
+// beyond MAX_PATH, for the calls the scan makes itself; folders that deep
+// are common in node_modules. Short and relative paths are returned as
+// they are.
+func extendedPath(path string) string {
+	if len(path) < longPathLimit || strings.HasPrefix(path, `\\?\`) || !filepath.IsAbs(path) {
+		return path
+	}
+	path = filepath.Clean(path)
+	if strings.HasPrefix(path, `\\`) {
+		return `\\?\UNC\` + path[2:]
+	}
+	return `\\?\` + path
+}
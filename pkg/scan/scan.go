@@ -0,0 +1,329 @@
+// Package scan measures folders the way the WinMole analyzer does: each
+// entry of a folder with its total size and file and folder counts,
+// subfolders walked in parallel.
+//
+//	var progress scan.Counters
+//	entries, total, err := scan.Dir(`C:\Users`, &progress)
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Println(humanize.Bytes(total))
+//	for _, e := range entries[:min(5, len(entries))] {
+//		fmt.Println(e.Name, humanize.Bytes(e.Size), e.Files)
+//	}
+//
+// Unreadable files and folders are skipped rather than failing the scan;
+// Counters.Unreadable lists them. Counters.Exclude leaves paths out.
+// Packages under pkg/ follow semantic versioning with the module: exported
+// names and their behaviour only change incompatibly in a new major version.
+package scan
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Entry is a file or folder and what it holds.
+type Entry struct {
+	Name  string
+	Path  string
+	Size  int64
+	Alloc int64 // space taken on disk, which compression, sparse files and cluster slack make differ from Size
+
+	// Cloud is the part of Size in cloud placeholders whose contents are
+	// not all downloaded; Alloc counts only what of them is on disk.
+	Cloud int64
+
+	// Linked and LinkedAlloc are the parts of Size and Alloc that are
+	// further hard links to files counted elsewhere; 0 unless the scan had
+	// Counters.Links set.
+	Linked      int64
+	LinkedAlloc int64
+
+	// Streams is the part of Size in alternate data streams; 0 unless the
+	// scan had Counters.Streams set.
+	Streams int64
+
+	Files int64 // files inside a folder; 1 for a file
+	Dirs  int64 // folders inside a folder, at any depth
+	IsDir bool
+
+	// Target is where a symbolic link, junction or mount point leads; see
+	// LinkTarget. Its sizes and counts are those of the target only when
+	// the scan followed links, and zero otherwise.
+	Target string
+
+	// ModTime is when the file or folder itself was last modified; zero
+	// when unknown.
+	ModTime time.Time
+
+	// AccessTime is when a file was last read; zero when unknown, and only
+	// as current as the volume keeps it. See AccessTime.
+	AccessTime time.Time
+}
+
+// Counters report progress while a scan runs. They may be read from
+// another goroutine at any time.
+type Counters struct {
+	Files atomic.Int64
+	Dirs  atomic.Int64
+	Bytes atomic.Int64 // size of the files counted so far
+
+	// Largest, if set, is offered every file the scan measures.
+	Largest *Largest
+
+	// Links, if set, detects hard links to files already counted.
+	Links *Links
+
+	// Unreadable, if set, records what the scan could not read.
+	Unreadable *Unreadable
+
+	// FollowLinks makes the scan measure what symbolic links, junctions and
+	// mount points lead to.
+	FollowLinks bool
+
+	// Streams counts the alternate data streams of files in their sizes.
+	// Listing them means opening every file, which makes a scan slower.
+	Streams bool
+
+	// Exclude, if set, reports paths the scan leaves out as if they were
+	// not there. An excluded folder is not walked.
+	Exclude func(path string, isDir bool) bool
+
+	// Workers, if set, bounds how many subfolders DirContext walks at
+	// once instead of the default; file servers do better with fewer.
+	Workers int
+
+	followed sync.Map // lower-cased targets measured so far
+}
+
+// excluded reports whether c.Exclude leaves path out.
+func (c *Counters) excluded(path string, isDir bool) bool {
+	return c.Exclude != nil && c.Exclude(path, isDir)
+}
+
+// workers bounds how many subfolders of one folder are walked at once.
+const workers = 10
+
+// Dir lists the folder at path, largest entry first, with the total size
+// of everything in it. progress may be nil.
+func Dir(path string, progress *Counters) ([]Entry, int64, error) {
+	return DirContext(context.Background(), path, progress)
+}
+
+// DirContext is Dir that stops early when ctx is done, returning what was
+// measured so far together with ctx.Err(). Entries being measured at that
+// point are included with the part of their size seen.
+func DirContext(ctx context.Context, path string, progress *Counters) ([]Entry, int64, error) {
+	if progress == nil {
+		progress = new(Counters)
+	}
+	dirEntries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		entries   []Entry
+		totalSize int64
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+	)
+	n := workers
+	if progress.Workers > 0 {
+		n = progress.Workers
+	}
+	sem := make(chan struct{}, n)
+
+	for _, de := range dirEntries {
+		de := de
+		if ctx.Err() != nil {
+			break
+		}
+		if progress.excluded(filepath.Join(path, de.Name()), de.IsDir()) {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			fullPath := filepath.Join(path, de.Name())
+			var t Totals
+			var modTime, accessTime time.Time
+			info, infoErr := de.Info()
+			if infoErr == nil {
+				modTime, accessTime = info.ModTime(), AccessTime(info)
+			} else {
+				progress.Unreadable.add(fullPath, infoErr)
+			}
+			var target string
+			isDir := de.IsDir()
+			if maybeLink(de.Type()) {
+				target = LinkTarget(fullPath)
+			}
+
+			if target != "" {
+				if stat, err := os.Stat(fullPath); err == nil {
+					isDir = stat.IsDir()
+				}
+				if progress.FollowLinks {
+					t = progress.follow(ctx, fullPath, target)
+				}
+			} else if de.IsDir() {
+				progress.Dirs.Add(1)
+				t = Tree(ctx, fullPath, progress)
+			} else {
+				progress.Files.Add(1)
+				t.Files = 1
+				if infoErr == nil {
+					t.add(fullPath, info, progress)
+				}
+				progress.Largest.Add(fullPath, t.Size)
+			}
+
+			mu.Lock()
+			entries = append(entries, Entry{
+				Name:        de.Name(),
+				Path:        fullPath,
+				Size:        t.Size,
+				Alloc:       t.Alloc,
+				Cloud:       t.Cloud,
+				Linked:      t.Linked,
+				LinkedAlloc: t.LinkedAlloc,
+				Streams:     t.Streams,
+				Files:       t.Files,
+				Dirs:        t.Dirs,
+				IsDir:       isDir,
+				ModTime:     modTime,
+				AccessTime:  accessTime,
+				Target:      target,
+			})
+			totalSize += t.Size
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	SortBySize(entries)
+	return entries, totalSize, ctx.Err()
+}
+
+// Size returns the total size and number of files below the folder at path.
+// progress may be nil.
+func Size(path string, progress *Counters) (size, files int64) {
+	return SizeContext(context.Background(), path, progress)
+}
+
+// SizeContext is Size that stops walking when ctx is done and returns the
+// totals so far.
+func SizeContext(ctx context.Context, path string, progress *Counters) (size, files int64) {
+	t := Tree(ctx, path, progress)
+	return t.Size, t.Files
+}
+
+// Totals is what a folder holds at any depth.
+type Totals struct {
+	Size        int64
+	Alloc       int64 // see Entry.Alloc
+	Cloud       int64 // see Entry.Cloud
+	Linked      int64 // see Entry.Linked
+	LinkedAlloc int64
+	Streams     int64 // see Entry.Streams
+	Files       int64
+	Dirs        int64
+}
+
+// add counts the sizes of the file at path.
+func (t *Totals) add(path string, info fs.FileInfo, progress *Counters) {
+	size, alloc := info.Size(), allocated(path, info)
+	if progress.Streams {
+		s, a := streamTotals(path, info)
+		t.Streams += s
+		size, alloc = size+s, alloc+a
+	}
+	progress.Bytes.Add(size)
+	t.Size += size
+	t.Alloc += alloc
+	if placeholder(info) {
+		t.Cloud += size // not opened again to check for links
+	} else if progress.Links.counted(path) {
+		t.Linked += size
+		t.LinkedAlloc += alloc
+	}
+}
+
+// Tree is SizeContext that returns all of the folder's totals.
+func Tree(ctx context.Context, path string, progress *Counters) (t Totals) {
+	if progress == nil {
+		progress = new(Counters)
+	}
+	filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return filepath.SkipAll
+		}
+		if err != nil {
+			progress.Unreadable.add(p, err)
+			return nil // Skip what cannot be read
+		}
+		if p != path && progress.excluded(p, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if p != path && maybeLink(d.Type()) {
+			if target := LinkTarget(p); target != "" {
+				if progress.FollowLinks {
+					t.merge(progress.follow(ctx, p, target))
+				}
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
+		if d.IsDir() {
+			progress.Dirs.Add(1)
+			if p != path {
+				t.Dirs++
+			}
+		} else {
+			progress.Files.Add(1)
+			t.Files++
+			if info, err := d.Info(); err == nil {
+				t.add(p, info, progress)
+				progress.Largest.Add(p, info.Size())
+			} else {
+				progress.Unreadable.add(p, err)
+			}
+		}
+		return nil
+	})
+	return t
+}
+
+// Stream is an alternate data stream: named data NTFS keeps beside a
+// file's contents, such as the Zone.Identifier that marks a download.
+// Explorer and directory listings never show them, nor count their size.
+type Stream struct {
+	Name string // without the leading colon and the :$DATA type
+	Size int64
+}
+
+// SortBySize orders entries largest first.
+func SortBySize(entries []Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Size > entries[j].Size
+	})
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package scan
+
+import "io/fs"
+
+// Streams lists no streams; alternate data streams are only read on
+// Windows.
+func Streams(path string) ([]Stream, error) {
+	return nil, nil
+}
+
+func streamTotals(path string, info fs.FileInfo) (size, alloc int64) {
+	return 0, 0
+}
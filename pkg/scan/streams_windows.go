@@ -0,0 +1,80 @@
+//go:build windows
+
+package scan
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	procFindFirstStream = kernel32.NewProc("FindFirstStreamW")
+	procFindNextStream  = kernel32.NewProc("FindNextStreamW")
+)
+
+// findStreamData is WIN32_FIND_STREAM_DATA.
+type findStreamData struct {
+	size int64
+	name [windows.MAX_PATH + 36]uint16
+}
+
+// residentStream is the size below which NTFS keeps a stream in the file's
+// own MFT record, where it takes no clusters. The exact limit depends on
+// what else the record holds; Zone.Identifier streams are always below it.
+const residentStream = 700
+
+// Streams lists the alternate data streams of the file or folder at path,
+// not counting its unnamed contents. Volumes other than NTFS have none.
+func Streams(path string) ([]Stream, error) {
+	p, err := windows.UTF16PtrFromString(extendedPath(path))
+	if err != nil {
+		return nil, err
+	}
+	var d findStreamData
+	h, _, err := procFindFirstStream.Call(uintptr(unsafe.Pointer(p)), 0, uintptr(unsafe.Pointer(&d)), 0)
+	if windows.Handle(h) == windows.InvalidHandle {
+		if errors.Is(err, windows.ERROR_HANDLE_EOF) || errors.Is(err, windows.ERROR_INVALID_PARAMETER) {
+			// No streams at all, or a file system without them.
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer windows.FindClose(windows.Handle(h))
+
+	var list []Stream
+	for {
+		// Names come as ":name:$DATA"; the contents are "::$DATA".
+		name := strings.TrimSuffix(strings.TrimPrefix(windows.UTF16ToString(d.name[:]), ":"), ":$DATA")
+		if name != "" {
+			list = append(list, Stream{Name: name, Size: d.size})
+		}
+		if r, _, _ := procFindNextStream.Call(h, uintptr(unsafe.Pointer(&d))); r == 0 {
+			return list, nil
+		}
+	}
+}
+
+// streamTotals returns the size of the alternate data streams of the file
+// at path and the space they take on disk. Files that opening could
+// download are left alone.
+func streamTotals(path string, info fs.FileInfo) (size, alloc int64) {
+	if attributes(info)&remoteAttributes != 0 {
+		return 0, 0
+	}
+	streams, _ := Streams(path)
+	if len(streams) == 0 {
+		return 0, 0
+	}
+	cluster := clusterSize(path)
+	for _, s := range streams {
+		size += s.Size
+		if s.Size >= residentStream {
+			alloc += (s.Size + cluster - 1) / cluster * cluster
+		}
+	}
+	return size, alloc
+}
@@ -0,0 +1,78 @@
+package scan
+
+import (
+	"errors"
+	"io/fs"
+	"sort"
+	"sync"
+)
+
+// Unreadable records the files and folders a scan could not read, which it
+// otherwise skips, so a total can say what it leaves out. Most are folders
+// closed to the user, such as System Volume Information or other users'
+// profiles; an elevated process can read those after EnableBackupPrivilege.
+// Set it on Counters:
+//
+//	progress := scan.Counters{Unreadable: scan.NewUnreadable(100)}
+//	scan.Dir(`C:\`, &progress)
+//	fmt.Println(progress.Unreadable.Count(), "items could not be read")
+//	for _, f := range progress.Unreadable.Paths() {
+//		fmt.Println(f.Path, f.Err)
+//	}
+//
+// It keeps the first paths up to a limit and counts the rest. It is safe
+// for concurrent use; a nil *Unreadable ignores failures.
+type Unreadable struct {
+	mu    sync.Mutex
+	limit int
+	count int
+	paths []Failure
+}
+
+// Failure is a path a scan could not read and why.
+type Failure struct {
+	Path string
+	Err  error
+}
+
+// NewUnreadable returns an Unreadable that keeps up to n paths.
+func NewUnreadable(n int) *Unreadable {
+	return &Unreadable{limit: max(n, 0)}
+}
+
+// add records that path could not be read. Files removed while the scan
+// ran are not worth reporting.
+func (u *Unreadable) add(path string, err error) {
+	if u == nil || errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.count++
+	if len(u.paths) < u.limit {
+		u.paths = append(u.paths, Failure{Path: path, Err: err})
+	}
+}
+
+// Count returns how many paths could not be read, including those not kept.
+func (u *Unreadable) Count() int {
+	if u == nil {
+		return 0
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.count
+}
+
+// Paths returns the paths kept so far, in path order.
+func (u *Unreadable) Paths() []Failure {
+	if u == nil {
+		return nil
+	}
+	u.mu.Lock()
+	paths := make([]Failure, len(u.paths))
+	copy(paths, u.paths)
+	u.mu.Unlock()
+	sort.Slice(paths, func(i, j int) bool { return paths[i].Path < paths[j].Path })
+	return paths
+}
@@ -0,0 +1,101 @@
+package scan
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Walk calls fn for every file below the folder at path, at any depth, by
+// the same rules as DirContext: Counters.Exclude leaves paths out, and
+// links are only followed with Counters.FollowLinks, then each target once
+// per walk and never one that contains the link. What cannot be read is
+// skipped. Files and folders are counted in progress, and file sizes in
+// Bytes, so a caller can show how far it got. Views that look at every
+// file, such as totals by extension or owner, use it to see the same files
+// as the scan. A followed file is reported under its target's path. Walk
+// stops with ctx.Err() when ctx is done. progress may be nil.
+func Walk(ctx context.Context, path string, progress *Counters, fn func(path string, info fs.FileInfo)) error {
+	if progress == nil {
+		progress = new(Counters)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	w := &walker{ctx: ctx, progress: progress, fn: fn}
+	return w.walk(path)
+}
+
+// walker holds one Walk. It keeps its own set of followed targets, as the
+// Counters passed in may already have followed them for a scan.
+type walker struct {
+	ctx      context.Context
+	progress *Counters
+	fn       func(string, fs.FileInfo)
+	followed sync.Map
+}
+
+func (w *walker) walk(root string) error {
+	progress := w.progress
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if w.ctx.Err() != nil {
+			return w.ctx.Err()
+		}
+		if err != nil || p == root {
+			return nil
+		}
+		if progress.excluded(p, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if maybeLink(d.Type()) {
+			if target := LinkTarget(p); target != "" {
+				if err := w.walkLink(p, target); err != nil {
+					return err
+				}
+				// SkipDir on a file would skip the rest of its folder.
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+		if d.IsDir() {
+			progress.Dirs.Add(1)
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		progress.Files.Add(1)
+		progress.Bytes.Add(info.Size())
+		w.fn(p, info)
+		return nil
+	})
+}
+
+// walkLink walks what the link at path leads to, if links are followed and
+// this one may be.
+func (w *walker) walkLink(path, target string) error {
+	progress := w.progress
+	if !progress.FollowLinks || !mayFollow(&w.followed, path, target) {
+		return nil
+	}
+	info, err := os.Stat(target)
+	switch {
+	case err != nil, progress.excluded(target, info.IsDir()):
+	case info.IsDir():
+		progress.Dirs.Add(1)
+		return w.walk(target)
+	default:
+		progress.Files.Add(1)
+		progress.Bytes.Add(info.Size())
+		w.fn(target, info)
+	}
+	return nil
+}
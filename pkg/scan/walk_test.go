@@ -0,0 +1,116 @@
+package scan
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// walkTree makes a folder with files, an excluded folder and a link to a
+// folder outside it, and returns the folder and the link's target.
+func walkTree(t *testing.T) (root, outside string) {
+	t.Helper()
+	base := t.TempDir()
+	root = filepath.Join(base, "root")
+	outside = filepath.Join(base, "outside")
+	files := map[string]string{
+		"root/a.txt":         "aa",
+		"root/sub/b.log":     "bbbb",
+		"root/skip/c.txt":    "c",
+		"outside/d.bin":      "ddd",
+		"outside/deep/e.txt": "e",
+	}
+	for name, data := range files {
+		p := filepath.Join(base, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Skip("symbolic links:", err)
+	}
+	// A second link to the same target, and one back to the root.
+	if err := os.Symlink(outside, filepath.Join(root, "sub", "again")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(root, filepath.Join(root, "sub", "up")); err != nil {
+		t.Fatal(err)
+	}
+	return root, outside
+}
+
+func TestWalk(t *testing.T) {
+	root, outside := walkTree(t)
+	skip := func(path string, isDir bool) bool { return isDir && filepath.Base(path) == "skip" }
+	tests := []struct {
+		name     string
+		progress *Counters
+		want     []string
+		bytes    int64
+	}{
+		{"all", &Counters{}, []string{"a.txt", "skip/c.txt", "sub/b.log"}, 7},
+		{"excluded", &Counters{Exclude: skip}, []string{"a.txt", "sub/b.log"}, 6},
+		{"followed once", &Counters{Exclude: skip, FollowLinks: true},
+			[]string{"a.txt", "sub/b.log", "~/d.bin", "~/deep/e.txt"}, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := Walk(context.Background(), root, tt.progress, func(path string, info fs.FileInfo) {
+				if rel, err := filepath.Rel(outside, path); err == nil && !strings.HasPrefix(rel, "..") {
+					got = append(got, "~/"+filepath.ToSlash(rel))
+					return
+				}
+				rel, _ := filepath.Rel(root, path)
+				got = append(got, filepath.ToSlash(rel))
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("files: got %q, want %q", got, tt.want)
+			}
+			if n := tt.progress.Files.Load(); n != int64(len(tt.want)) {
+				t.Errorf("Files: got %d, want %d", n, len(tt.want))
+			}
+			if n := tt.progress.Bytes.Load(); n != tt.bytes {
+				t.Errorf("Bytes: got %d, want %d", n, tt.bytes)
+			}
+		})
+	}
+}
+
+func TestWalkFollowsAgainAfterScan(t *testing.T) {
+	root, outside := walkTree(t)
+	// Targets a scan followed with the same Counters are still walked.
+	progress := &Counters{FollowLinks: true}
+	mayFollow(&progress.followed, filepath.Join(root, "link"), outside)
+	var n int
+	if err := Walk(context.Background(), root, progress, func(string, fs.FileInfo) { n++ }); err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 {
+		t.Errorf("got %d files, want 5", n)
+	}
+}
+
+func TestWalkErrors(t *testing.T) {
+	if err := Walk(context.Background(), filepath.Join(t.TempDir(), "missing"), nil, func(string, fs.FileInfo) {}); err == nil {
+		t.Error("missing folder: want an error")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	root, _ := walkTree(t)
+	if err := Walk(ctx, root, nil, func(string, fs.FileInfo) {}); err != context.Canceled {
+		t.Errorf("canceled: got %v, want %v", err, context.Canceled)
+	}
+}
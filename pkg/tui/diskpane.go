@@ -0,0 +1,260 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/scan"
+)
+
+// DiskPane is the analyzer's folder list: the entries of a folder, largest
+// first, with their size and a bar against the folder total. While focused
+// it takes the analyzer's keys: ↑/↓ (k/j) move, Enter (→/l) opens a folder
+// and Backspace (←/h) goes back, never above the folder it started at.
+type DiskPane struct {
+	id       int64
+	root     string
+	path     string
+	entries  []scan.Entry
+	total    int64
+	selected int
+	offset   int
+	history  []string
+	rows     int
+	barWidth int
+	focused  bool
+	scanning bool
+	err      error
+	progress *scan.Counters
+}
+
+// DiskPaneOption configures a DiskPane.
+type DiskPaneOption func(*DiskPane)
+
+// DiskPaneRows sets how many entries are shown at once (default 10).
+func DiskPaneRows(n int) DiskPaneOption {
+	return func(p *DiskPane) { p.rows = max(n, 1) }
+}
+
+// DiskPaneBarWidth sets the width of the size bars (default 20; 0 hides
+// them).
+func DiskPaneBarWidth(n int) DiskPaneOption {
+	return func(p *DiskPane) { p.barWidth = max(n, 0) }
+}
+
+// DiskPaneFocused sets whether the pane starts out taking keys (default
+// true). Use Focus and Blur to move focus between panes later.
+func DiskPaneFocused(focused bool) DiskPaneOption {
+	return func(p *DiskPane) { p.focused = focused }
+}
+
+// NewDiskPane returns a pane for the folder at path; Init starts its scan.
+func NewDiskPane(path string, opts ...DiskPaneOption) DiskPane {
+	p := DiskPane{
+		id:       nextID(),
+		root:     path,
+		path:     path,
+		rows:     10,
+		barWidth: 20,
+		focused:  true,
+		scanning: true,
+		progress: new(scan.Counters),
+	}
+	for _, opt := range opts {
+		opt(&p)
+	}
+	return p
+}
+
+type diskScanMsg struct {
+	id      int64
+	path    string
+	entries []scan.Entry
+	total   int64
+	err     error
+}
+
+type diskTickMsg struct {
+	id int64
+}
+
+func (p DiskPane) scanCmd() tea.Cmd {
+	id, path, progress := p.id, p.path, p.progress
+	return func() tea.Msg {
+		entries, total, err := scan.Dir(path, progress)
+		return diskScanMsg{id: id, path: path, entries: entries, total: total, err: err}
+	}
+}
+
+func (p DiskPane) tickCmd() tea.Cmd {
+	id := p.id
+	return tea.Tick(100*time.Millisecond, func(time.Time) tea.Msg {
+		return diskTickMsg{id: id}
+	})
+}
+
+// Path returns the folder shown.
+func (p DiskPane) Path() string {
+	return p.path
+}
+
+// Entries returns the entries of the folder shown, largest first, and their
+// total size. Both are empty while scanning.
+func (p DiskPane) Entries() ([]scan.Entry, int64) {
+	return p.entries, p.total
+}
+
+// Selected returns the highlighted entry, if any.
+func (p DiskPane) Selected() (scan.Entry, bool) {
+	if p.scanning || p.selected >= len(p.entries) {
+		return scan.Entry{}, false
+	}
+	return p.entries[p.selected], true
+}
+
+// Scanning reports whether the folder is still being measured.
+func (p DiskPane) Scanning() bool {
+	return p.scanning
+}
+
+// Focus makes the pane take keys.
+func (p DiskPane) Focus() DiskPane {
+	p.focused = true
+	return p
+}
+
+// Blur stops the pane taking keys.
+func (p DiskPane) Blur() DiskPane {
+	p.focused = false
+	return p
+}
+
+// Focused reports whether the pane takes keys.
+func (p DiskPane) Focused() bool {
+	return p.focused
+}
+
+// Init implements tea.Model by starting the scan.
+func (p DiskPane) Init() tea.Cmd {
+	return tea.Batch(p.scanCmd(), p.tickCmd())
+}
+
+// Update implements tea.Model. The returned model is a DiskPane.
+func (p DiskPane) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case diskScanMsg:
+		if msg.id != p.id || msg.path != p.path {
+			return p, nil
+		}
+		p.scanning = false
+		p.entries, p.total, p.err = msg.entries, msg.total, msg.err
+		return p, nil
+
+	case diskTickMsg:
+		if msg.id != p.id || !p.scanning {
+			return p, nil
+		}
+		return p, p.tickCmd()
+
+	case tea.KeyMsg:
+		if !p.focused || p.scanning {
+			return p, nil
+		}
+		return p.handleKey(msg)
+	}
+	return p, nil
+}
+
+func (p DiskPane) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if p.selected > 0 {
+			p.selected--
+			p.offset = min(p.offset, p.selected)
+		}
+	case "down", "j":
+		if p.selected < len(p.entries)-1 {
+			p.selected++
+			if p.selected >= p.offset+p.rows {
+				p.offset = p.selected - p.rows + 1
+			}
+		}
+	case "enter", "right", "l":
+		if e, ok := p.Selected(); ok && e.IsDir {
+			p.history = append(p.history, p.path)
+			return p.open(e.Path)
+		}
+	case "backspace", "left", "h":
+		if len(p.history) > 0 {
+			parent := p.history[len(p.history)-1]
+			p.history = p.history[:len(p.history)-1]
+			return p.open(parent)
+		}
+	}
+	return p, nil
+}
+
+// open starts scanning path in place of the current folder.
+func (p DiskPane) open(path string) (tea.Model, tea.Cmd) {
+	p.path = path
+	p.entries, p.total, p.err = nil, 0, nil
+	p.selected, p.offset = 0, 0
+	p.scanning = true
+	p.progress = new(scan.Counters)
+	return p, tea.Batch(p.scanCmd(), p.tickCmd())
+}
+
+// View implements tea.Model.
+func (p DiskPane) View() string {
+	var b strings.Builder
+	b.WriteString(valueStyle.Render(p.path))
+	if p.root != p.path {
+		b.WriteString(labelStyle.Render(" • " + humanize.Truncate(p.root, 30)))
+	}
+	b.WriteString("\n")
+
+	switch {
+	case p.scanning:
+		b.WriteString(labelStyle.Render(fmt.Sprintf("Scanning... %d files, %d dirs",
+			p.progress.Files.Load(), p.progress.Dirs.Load())))
+		return b.String()
+	case p.err != nil:
+		b.WriteString(labelStyle.Render("Error: " + p.err.Error()))
+		return b.String()
+	case len(p.entries) == 0:
+		b.WriteString(labelStyle.Render("(empty directory)"))
+		return b.String()
+	}
+
+	end := min(p.offset+p.rows, len(p.entries))
+	for i := p.offset; i < end; i++ {
+		e := p.entries[i]
+		name := e.Name
+		if e.IsDir {
+			name += string(filepath.Separator)
+		}
+		line := sizeStyle.Render(humanize.Bytes(e.Size))
+		if p.barWidth > 0 {
+			filled := 0
+			if p.total > 0 {
+				filled = min(int(float64(e.Size)/float64(p.total)*float64(p.barWidth)), p.barWidth)
+			}
+			line += " " + usageBarStyle.Render(strings.Repeat("█", filled)+strings.Repeat("░", p.barWidth-filled))
+		}
+		line += " " + name
+		if i == p.selected && p.focused {
+			line = selectedStyle.Render(line)
+		}
+		b.WriteString(line)
+		if i < end-1 {
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString("\n")
+	b.WriteString(labelStyle.Render("Total: " + humanize.Bytes(p.total)))
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Sparkline is a one-line chart of the most recent values pushed to it.
+// It does not react to messages; the parent pushes values as they arrive.
+type Sparkline struct {
+	values []float64
+	width  int
+	max    float64
+	style  lipgloss.Style
+}
+
+// SparklineOption configures a Sparkline.
+type SparklineOption func(*Sparkline)
+
+// SparklineWidth sets how many values are kept and drawn (default 60).
+func SparklineWidth(n int) SparklineOption {
+	return func(s *Sparkline) { s.width = max(n, 1) }
+}
+
+// SparklineMax fixes the value drawn as a full block, for example 100 for
+// percentages. By default the largest value shown is.
+func SparklineMax(v float64) SparklineOption {
+	return func(s *Sparkline) { s.max = v }
+}
+
+// SparklineStyle sets the style the chart is rendered with.
+func SparklineStyle(style lipgloss.Style) SparklineOption {
+	return func(s *Sparkline) { s.style = style }
+}
+
+// NewSparkline returns an empty Sparkline.
+func NewSparkline(opts ...SparklineOption) Sparkline {
+	s := Sparkline{width: 60, style: barLowStyle}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
+
+// Push returns s with v added, dropping the oldest value when full.
+func (s Sparkline) Push(v float64) Sparkline {
+	values := append(make([]float64, 0, s.width), s.values...)
+	values = append(values, v)
+	if len(values) > s.width {
+		values = values[len(values)-s.width:]
+	}
+	s.values = values
+	return s
+}
+
+// Values returns the values shown, oldest first.
+func (s Sparkline) Values() []float64 {
+	return s.values
+}
+
+// Init implements tea.Model.
+func (s Sparkline) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model; a Sparkline only changes through Push.
+func (s Sparkline) Update(tea.Msg) (tea.Model, tea.Cmd) {
+	return s, nil
+}
+
+// View implements tea.Model.
+func (s Sparkline) View() string {
+	return s.style.Render(Spark(s.values, s.max))
+}
@@ -0,0 +1,266 @@
+//go:build windows
+
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/winmole/winmole/pkg/humanize"
+	"github.com/winmole/winmole/pkg/metrics"
+)
+
+// Card names accepted by StatusStripCards and Card.
+const (
+	CardCPU     = "cpu"
+	CardMemory  = "memory"
+	CardDisk    = "disk"
+	CardNetwork = "network"
+)
+
+// cardRenderers are the dashboard's Overview cards, in default order.
+var cardRenderers = []struct {
+	name   string
+	render func(metrics.Metrics) string
+}{
+	{CardCPU, CPUCard},
+	{CardMemory, MemoryCard},
+	{CardDisk, DiskCard},
+	{CardNetwork, NetworkCard},
+}
+
+// Card renders the dashboard card called name, or "" for an unknown name.
+func Card(name string, m metrics.Metrics) string {
+	for _, c := range cardRenderers {
+		if c.name == name {
+			return c.render(m)
+		}
+	}
+	return ""
+}
+
+// CPUCard renders CPU model, usage, core count and how usage was measured.
+func CPUCard(m metrics.Metrics) string {
+	var content strings.Builder
+
+	content.WriteString(valueStyle.Render("CPU"))
+	content.WriteString("\n")
+	content.WriteString(labelStyle.Render(humanize.Truncate(m.CPUModel, 30)))
+	content.WriteString("\n\n")
+
+	// Usage bar
+	content.WriteString(labelStyle.Render("Usage: "))
+	content.WriteString(Bar(m.CPUUsage, 20))
+	content.WriteString(fmt.Sprintf(" %.1f%%", m.CPUUsage))
+	content.WriteString("\n")
+
+	// Cores
+	cores := fmt.Sprintf("Cores: %d", m.CPUCores)
+	switch m.CPUMethod {
+	case metrics.CPUMethodUtility:
+		cores += " • processor utility"
+	case metrics.CPUMethodTime:
+		cores += " • busy time"
+	}
+	content.WriteString(labelStyle.Render(cores))
+
+	return cardStyle.Width(40).Render(content.String())
+}
+
+// MemoryCard renders memory as Task Manager shows it: in use against the
+// usable total, the commit charge, the cache and what hardware reserves.
+func MemoryCard(m metrics.Metrics) string {
+	var content strings.Builder
+
+	content.WriteString(valueStyle.Render("Memory"))
+	content.WriteString("\n")
+	content.WriteString(labelStyle.Render(fmt.Sprintf("%s / %s in use",
+		humanize.Bytes(m.MemInUse),
+		humanize.Bytes(m.MemTotal))))
+	content.WriteString("\n\n")
+
+	// Usage bar
+	content.WriteString(labelStyle.Render("Usage: "))
+	content.WriteString(Bar(m.MemInUsePercent, 20))
+	content.WriteString(fmt.Sprintf(" %.1f%%", m.MemInUsePercent))
+	content.WriteString("\n")
+
+	content.WriteString(labelStyle.Render(fmt.Sprintf("Committed: %s / %s",
+		humanize.Bytes(m.MemCommitted),
+		humanize.Bytes(m.MemCommitLimit))))
+	content.WriteString("\n")
+	details := "Cached: " + humanize.Bytes(m.MemCached)
+	if m.MemInstalled > m.MemTotal {
+		details += fmt.Sprintf(" • %s reserved", humanize.Bytes(m.MemInstalled-m.MemTotal))
+	}
+	content.WriteString(labelStyle.Render(details))
+
+	return cardStyle.Width(40).Render(content.String())
+}
+
+// RawMemoryCard renders memory as gopsutil reports it: used is the total
+// less available memory, and the percentage is Windows' memory load.
+func RawMemoryCard(m metrics.Metrics) string {
+	var content strings.Builder
+
+	content.WriteString(valueStyle.Render("Memory (raw)"))
+	content.WriteString("\n")
+	content.WriteString(labelStyle.Render(fmt.Sprintf("%s / %s",
+		humanize.Bytes(m.MemUsed),
+		humanize.Bytes(m.MemTotal))))
+	content.WriteString("\n\n")
+
+	// Usage bar
+	content.WriteString(labelStyle.Render("Usage: "))
+	content.WriteString(Bar(m.MemPercent, 20))
+	content.WriteString(fmt.Sprintf(" %.1f%%", m.MemPercent))
+
+	return cardStyle.Width(40).Render(content.String())
+}
+
+// DiskCard renders space used on the system drive.
+func DiskCard(m metrics.Metrics) string {
+	var content strings.Builder
+
+	content.WriteString(valueStyle.Render("Disk (" + m.DiskPath + ")"))
+	content.WriteString("\n")
+	content.WriteString(labelStyle.Render(fmt.Sprintf("%s / %s",
+		humanize.Bytes(m.DiskUsed),
+		humanize.Bytes(m.DiskTotal))))
+	content.WriteString("\n\n")
+
+	// Usage bar
+	content.WriteString(labelStyle.Render("Usage: "))
+	content.WriteString(Bar(m.DiskPercent, 20))
+	content.WriteString(fmt.Sprintf(" %.1f%%", m.DiskPercent))
+
+	return cardStyle.Width(40).Render(content.String())
+}
+
+// NetworkCard renders upload and download rates.
+func NetworkCard(m metrics.Metrics) string {
+	var content strings.Builder
+
+	content.WriteString(valueStyle.Render("Network"))
+	content.WriteString("\n")
+	content.WriteString(labelStyle.Render("Traffic rates"))
+	content.WriteString("\n\n")
+
+	// Upload/Download rates
+	content.WriteString(labelStyle.Render("↑ Upload:   "))
+	content.WriteString(valueStyle.Render(fmt.Sprintf("%s/s", humanize.Bytes(uint64(m.NetSentRate)))))
+	content.WriteString("\n")
+	content.WriteString(labelStyle.Render("↓ Download: "))
+	content.WriteString(valueStyle.Render(fmt.Sprintf("%s/s", humanize.Bytes(uint64(m.NetRecvRate)))))
+
+	return cardStyle.Width(40).Render(content.String())
+}
+
+// StatusStrip is a row of the dashboard's cards that samples the system on
+// its own schedule.
+type StatusStrip struct {
+	id       int64
+	cards    []string
+	interval time.Duration
+	guard    *metrics.Guard
+	metrics  *metrics.Metrics // nil until the first sample
+}
+
+// StatusStripOption configures a StatusStrip.
+type StatusStripOption func(*StatusStrip)
+
+// StatusStripCards sets which cards are shown, in order (default all four).
+func StatusStripCards(names ...string) StatusStripOption {
+	return func(s *StatusStrip) { s.cards = names }
+}
+
+// StatusStripInterval sets how often the system is sampled (default 2s).
+func StatusStripInterval(d time.Duration) StatusStripOption {
+	return func(s *StatusStrip) { s.interval = max(d, 100*time.Millisecond) }
+}
+
+// NewStatusStrip returns a strip; Init takes the first sample.
+func NewStatusStrip(opts ...StatusStripOption) StatusStrip {
+	s := StatusStrip{
+		id:       nextID(),
+		cards:    []string{CardCPU, CardMemory, CardDisk, CardNetwork},
+		interval: 2 * time.Second,
+		guard:    metrics.NewGuard(metrics.DefaultPolicy),
+	}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
+
+type stripMetricsMsg struct {
+	id      int64
+	metrics metrics.Metrics
+}
+
+type stripTickMsg struct {
+	id int64
+}
+
+func (s StatusStrip) collectCmd() tea.Cmd {
+	id, guard := s.id, s.guard
+	return func() tea.Msg {
+		return stripMetricsMsg{id: id, metrics: guard.Collect(metrics.All())}
+	}
+}
+
+// Metrics returns the latest sample, or false before the first one.
+func (s StatusStrip) Metrics() (metrics.Metrics, bool) {
+	if s.metrics == nil {
+		return metrics.Metrics{}, false
+	}
+	return *s.metrics, true
+}
+
+// Init implements tea.Model by taking the first sample.
+func (s StatusStrip) Init() tea.Cmd {
+	return s.collectCmd()
+}
+
+// Update implements tea.Model. The returned model is a StatusStrip.
+func (s StatusStrip) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case stripMetricsMsg:
+		if msg.id != s.id {
+			return s, nil
+		}
+		cur := msg.metrics
+		if s.metrics != nil {
+			metrics.Merge(&cur, s.metrics)
+		}
+		s.metrics = &cur
+		id := s.id
+		return s, tea.Tick(s.interval, func(time.Time) tea.Msg {
+			return stripTickMsg{id: id}
+		})
+
+	case stripTickMsg:
+		if msg.id != s.id {
+			return s, nil
+		}
+		return s, s.collectCmd()
+	}
+	return s, nil
+}
+
+// View implements tea.Model.
+func (s StatusStrip) View() string {
+	if s.metrics == nil {
+		return labelStyle.Render("Collecting metrics...")
+	}
+	var rendered []string
+	for _, name := range s.cards {
+		if card := Card(name, *s.metrics); card != "" {
+			rendered = append(rendered, card)
+		}
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, rendered...)
+}
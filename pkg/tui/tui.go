@@ -0,0 +1,140 @@
+// Package tui provides the WinMole analyzer and dashboard widgets as Bubble
+// Tea components, so other terminal apps can embed a disk-usage pane or a
+// system status strip:
+//
+//	type app struct {
+//		disk  tui.DiskPane
+//		strip tui.StatusStrip
+//	}
+//
+//	func (a app) Init() tea.Cmd { return tea.Batch(a.disk.Init(), a.strip.Init()) }
+//
+//	func (a app) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+//		disk, c1 := a.disk.Update(msg)
+//		strip, c2 := a.strip.Update(msg)
+//		a.disk, a.strip = disk.(tui.DiskPane), strip.(tui.StatusStrip)
+//		return a, tea.Batch(c1, c2)
+//	}
+//
+//	func (a app) View() string { return a.strip.View() + "\n" + a.disk.View() }
+//
+// Components are tea.Model values configured with options when created.
+// Update returns the same concrete type, so a parent model can keep them as
+// fields; each component ignores messages meant for other instances.
+// Sparkline and the Bar and Spark helpers are plain rendering; StatusStrip
+// is Windows only, like the metrics package it draws from.
+//
+// Packages under pkg/ follow semantic versioning with the module: exported
+// names and their behaviour only change incompatibly in a new major version.
+package tui
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Styles shared by the components, matching the WinMole tools.
+var (
+	cardStyle = lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("62")).
+			Padding(0, 1).
+			MarginRight(1)
+
+	labelStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241"))
+
+	valueStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("229")).
+			Bold(true)
+
+	selectedStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("229")).
+			Background(lipgloss.Color("57")).
+			Bold(true)
+
+	sizeStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("39")).
+			Width(10).
+			Align(lipgloss.Right)
+
+	usageBarStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("205"))
+
+	barEmptyStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240"))
+
+	barLowStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("42"))
+
+	barMedStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("226"))
+
+	barHighStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196"))
+)
+
+// lastID numbers component instances so their messages can be told apart.
+var lastID atomic.Int64
+
+func nextID() int64 {
+	return lastID.Add(1)
+}
+
+// Bar renders percent (0-100) as a meter width cells wide, green below
+// 70%, yellow below 90% and red above.
+func Bar(percent float64, width int) string {
+	filled := int(percent / 100.0 * float64(width))
+	if filled > width {
+		filled = width
+	}
+	if filled < 0 {
+		filled = 0
+	}
+	empty := width - filled
+
+	var style lipgloss.Style
+	switch {
+	case percent >= 90:
+		style = barHighStyle
+	case percent >= 70:
+		style = barMedStyle
+	default:
+		style = barLowStyle
+	}
+
+	bar := style.Render(strings.Repeat("█", filled))
+	bar += barEmptyStyle.Render(strings.Repeat("░", empty))
+	return bar
+}
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Spark renders values as block characters scaled to max, or to the
+// largest value when max <= 0.
+func Spark(values []float64, max float64) string {
+	if max <= 0 {
+		for _, v := range values {
+			if v > max {
+				max = v
+			}
+		}
+	}
+	var b strings.Builder
+	for _, v := range values {
+		idx := 0
+		if max > 0 {
+			idx = int(v / max * float64(len(sparkBlocks)-1))
+		}
+		if idx < 0 {
+			idx = 0
+		}
+		if idx >= len(sparkBlocks) {
+			idx = len(sparkBlocks) - 1
+		}
+		b.WriteRune(sparkBlocks[idx])
+	}
+	return b.String()
+}
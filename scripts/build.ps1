@@ -5,7 +5,7 @@
 #Requires -Version 5.1
 param(
     [Parameter(Position = 0)]
-    [ValidateSet("all", "go", "validate", "clean", "test")]
+    [ValidateSet("all", "go", "validate", "clean", "test", "msi", "msix")]
     [string]$Target = "all",
     
     [switch]$Release,
@@ -25,8 +25,10 @@ $script:BIN_DIR = Join-Path $script:ROOT "bin"
 $script:CMD_DIR = Join-Path $script:ROOT "cmd"
 $script:LIB_DIR = Join-Path $script:ROOT "lib"
 $script:TESTS_DIR = Join-Path $script:ROOT "tests"
+$script:DIST_DIR = Join-Path $script:ROOT "dist"
+$script:PACKAGING_DIR = Join-Path $script:ROOT "packaging"
 
-$script:GO_TOOLS = @("analyze", "status")
+$script:GO_TOOLS = @("analyze", "status", "helper", "server")
 $script:VERSION = "1.0.0"
 
 # Colors
@@ -84,6 +86,8 @@ function Show-Help {
     Write-Host "    $($c.Cyan)go$($c.NC)         Build Go binaries only"
     Write-Host "    $($c.Cyan)validate$($c.NC)   Validate PowerShell scripts"
     Write-Host "    $($c.Cyan)test$($c.NC)       Run tests"
+    Write-Host "    $($c.Cyan)msi$($c.NC)        Build the per-machine MSI installer (needs WiX v5)"
+    Write-Host "    $($c.Cyan)msix$($c.NC)       Build the MSIX package (needs the Windows SDK)"
     Write-Host "    $($c.Cyan)clean$($c.NC)      Remove build artifacts"
     Write-Host ""
     Write-Host "  $($c.Green)OPTIONS:$($c.NC)"
@@ -99,6 +103,8 @@ function Show-Help {
     Write-Host "    $($c.Gray).\build.ps1 validate$($c.NC)     # Validate scripts"
     Write-Host "    $($c.Gray).\build.ps1 test$($c.NC)         # Run tests"
     Write-Host "    $($c.Gray).\build.ps1 clean$($c.NC)        # Clean artifacts"
+    Write-Host "    $($c.Gray).\build.ps1 msi$($c.NC)          # Build dist\WinMole-<version>-x64.msi"
+    Write-Host "    $($c.Gray).\build.ps1 msix$($c.NC)         # Build dist\WinMole-<version>-x64.msix"
     Write-Host ""
 }
 
@@ -233,6 +239,135 @@ function Build-AllGo {
     return $allSuccess
 }
 
+function New-PackageStage {
+    # Installed copies cannot rebuild themselves under Program Files, so
+    # always package freshly built release binaries.
+    $script:Release = $true
+    if (-not (Build-AllGo)) {
+        return $null
+    }
+    
+    # Stage exactly what gets installed; no sources and no portable marker
+    $stage = Join-Path $script:DIST_DIR "stage"
+    if (Test-Path $stage) {
+        Remove-Item $stage -Recurse -Force
+    }
+    $stageBin = Join-Path $stage "bin"
+    New-Item -ItemType Directory -Path $stageBin -Force | Out-Null
+    
+    Copy-Item (Join-Path $script:ROOT "winmole.ps1") $stage
+    Copy-Item $script:LIB_DIR (Join-Path $stage "lib") -Recurse
+    Copy-Item (Join-Path $script:BIN_DIR "*.ps1") $stageBin
+    foreach ($tool in $script:GO_TOOLS) {
+        Copy-Item (Join-Path $script:BIN_DIR "$tool.exe") $stageBin
+    }
+    
+    # Same launcher install.ps1 creates
+    $launcher = @"
+@echo off
+powershell.exe -ExecutionPolicy Bypass -NoLogo -File "%~dp0winmole.ps1" %*
+"@
+    Set-Content -Path (Join-Path $stage "winmole.cmd") -Value $launcher -Encoding ASCII
+    return $stage
+}
+
+function Build-Msi {
+    Write-Host ""
+    Write-Info "Building MSI installer..."
+    Write-Host ""
+    
+    $wix = Get-Command "wix" -ErrorAction SilentlyContinue
+    if (-not $wix) {
+        Write-Fail "WiX Toolset not found"
+        Write-Host "    Install it with: dotnet tool install --global wix"
+        Write-Host "    Then add:        wix extension add --global WixToolset.Util.wixext WixToolset.Firewall.wixext"
+        return $false
+    }
+    
+    $stage = New-PackageStage
+    if (-not $stage) {
+        return $false
+    }
+    
+    $msiPath = Join-Path $script:DIST_DIR "WinMole-$script:VERSION-x64.msi"
+    $wxs = Join-Path $script:PACKAGING_DIR "msi\WinMole.wxs"
+    $wixArgs = @(
+        "build", $wxs,
+        "-arch", "x64",
+        "-d", "Version=$script:VERSION",
+        "-bindpath", "stage=$stage",
+        "-ext", "WixToolset.Util.wixext",
+        "-ext", "WixToolset.Firewall.wixext",
+        "-o", $msiPath
+    )
+    
+    if ($ShowDetails) {
+        Write-Host "    wix $($wixArgs -join ' ')"
+    }
+    
+    $output = & wix @wixArgs 2>&1
+    if ($LASTEXITCODE -ne 0) {
+        Write-Fail "MSI build failed"
+        Write-Host $output
+        return $false
+    }
+    
+    $size = (Get-Item $msiPath).Length / 1MB
+    Write-Success "Built $msiPath ($([math]::Round($size, 2)) MB)"
+    return $true
+}
+
+function Build-Msix {
+    Write-Host ""
+    Write-Info "Building MSIX package..."
+    Write-Host ""
+    
+    $makeappx = Get-Command "makeappx" -ErrorAction SilentlyContinue
+    if (-not $makeappx) {
+        # Not on PATH outside a developer prompt; take the newest SDK
+        $kits = Join-Path ${env:ProgramFiles(x86)} "Windows Kits\10\bin"
+        $makeappx = Get-ChildItem $kits -Filter "makeappx.exe" -Recurse -ErrorAction SilentlyContinue |
+            Where-Object { $_.Directory.Name -eq "x64" } |
+            Sort-Object FullName -Descending |
+            Select-Object -First 1
+    }
+    if (-not $makeappx) {
+        Write-Fail "makeappx.exe not found"
+        Write-Host "    Install the Windows SDK: winget install Microsoft.WindowsSDK.10.0.22621"
+        return $false
+    }
+    
+    $stage = New-PackageStage
+    if (-not $stage) {
+        return $false
+    }
+    
+    # MSIX versions have four parts
+    $msixDir = Join-Path $script:PACKAGING_DIR "msix"
+    $manifest = (Get-Content (Join-Path $msixDir "AppxManifest.xml") -Raw) -replace '\$\(var\.Version\)', "$script:VERSION.0"
+    Set-Content -Path (Join-Path $stage "AppxManifest.xml") -Value $manifest -Encoding UTF8
+    Copy-Item (Join-Path $msixDir "Assets") (Join-Path $stage "Assets") -Recurse
+    
+    $msixPath = Join-Path $script:DIST_DIR "WinMole-$script:VERSION-x64.msix"
+    $makeappxArgs = @("pack", "/d", $stage, "/p", $msixPath, "/o")
+    
+    if ($ShowDetails) {
+        Write-Host "    makeappx $($makeappxArgs -join ' ')"
+    }
+    
+    $output = & $makeappx.Source @makeappxArgs 2>&1
+    if ($LASTEXITCODE -ne 0) {
+        Write-Fail "MSIX build failed"
+        Write-Host $output
+        return $false
+    }
+    
+    $size = (Get-Item $msixPath).Length / 1MB
+    Write-Success "Built $msixPath ($([math]::Round($size, 2)) MB)"
+    Write-Host "    Sign it before deploying: signtool sign /fd SHA256 /f <cert.pfx> $msixPath"
+    return $true
+}
+
 function Test-PowerShellScripts {
     Write-Host ""
     Write-Info "Validating PowerShell scripts..."
@@ -340,13 +475,16 @@ function Clear-BuildArtifacts {
     $artifacts = @(
         "bin\analyze.exe"
         "bin\status.exe"
+        "bin\helper.exe"
+        "bin\server.exe"
+        "dist"
         "go.sum"
     )
     
     foreach ($artifact in $artifacts) {
         $path = Join-Path $script:ROOT $artifact
         if (Test-Path $path) {
-            Remove-Item $path -Force
+            Remove-Item $path -Recurse -Force
             Write-Success "Removed: $artifact"
         }
     }
@@ -385,6 +523,12 @@ function Main {
         "clean" {
             if (-not (Clear-BuildArtifacts)) { $success = $false }
         }
+        "msi" {
+            if (-not (Build-Msi)) { $success = $false }
+        }
+        "msix" {
+            if (-not (Build-Msix)) { $success = $false }
+        }
     }
     
     Write-Host ""
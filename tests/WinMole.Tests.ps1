@@ -58,6 +58,23 @@ Describe "Core Module - base.ps1" {
         }
     }
     
+    Context "Format-ByteSize units" {
+        It "counts in thousands for si" {
+            Format-ByteSize 1000 -Units si | Should -Be "1.0 KB"
+            Format-ByteSize 1500000000 -Units si | Should -Be "1.5 GB"
+        }
+        
+        It "labels binary multiples for iec" {
+            Format-ByteSize 1024 -Units iec | Should -Be "1.0 KiB"
+            Format-ByteSize (1024 * 1024 * 5.5) -Units iec | Should -Be "5.5 MiB"
+        }
+        
+        It "shows bytes exactly under a kilobyte" {
+            Format-ByteSize 999 -Units si | Should -Be "999 B"
+            Format-ByteSize 1023 -Units iec | Should -Be "1023 B"
+        }
+    }
+    
     Context "Test-ProtectedPath" {
         It "protects Windows directory" {
             Test-ProtectedPath "C:\Windows" | Should -Be $true
@@ -93,6 +110,29 @@ Describe "Core Module - base.ps1" {
             $info.Build | Should -Not -BeNullOrEmpty
         }
     }
+    
+    Context "Group Policy" {
+        BeforeEach {
+            $script:SavedPolicy = $script:Policy.Clone()
+        }
+        
+        AfterEach {
+            $script:Policy = $script:SavedPolicy
+        }
+        
+        It "disables commands case-insensitively" {
+            $script:Policy.DisabledCommands = @("uninstall")
+            Test-CommandDisabled "Uninstall" | Should -Be $true
+            Test-CommandDisabled "clean" | Should -Be $false
+        }
+        
+        It "treats excluded paths and their contents as whitelisted" {
+            $script:Policy.ExcludedPaths = @("%TEMP%\WinMolePolicy*")
+            Test-Whitelisted (Join-Path $env:TEMP "WinMolePolicyTest") | Should -Be $true
+            Test-Whitelisted (Join-Path $env:TEMP "WinMolePolicyTest\cache.bin") | Should -Be $true
+            Test-Whitelisted (Join-Path $env:TEMP "Other") | Should -Be $false
+        }
+    }
 }
 
 # ============================================================================
@@ -195,6 +235,251 @@ Describe "File Operations - file_ops.ps1" {
     }
 }
 
+# ============================================================================
+# Clean Module Tests
+# ============================================================================
+
+Describe "Package Caches - packages.ps1" {
+    
+    BeforeAll {
+        . "$script:LIB_DIR\clean\packages.ps1"
+        
+        # Backdate a file's last write and last read
+        function Set-TestAge {
+            param([string]$Path, [int]$Days)
+            $item = Get-Item -LiteralPath $Path
+            $item.LastWriteTime = (Get-Date).AddDays(-$Days)
+            $item.LastAccessTime = (Get-Date).AddDays(-$Days)
+        }
+        
+        function New-TestFile {
+            param([string]$Path, [int]$Days = 0)
+            New-Item -ItemType Directory -Path (Split-Path -Parent $Path) -Force | Out-Null
+            Set-Content -Path $Path -Value "cache"
+            Set-TestAge -Path $Path -Days $Days
+        }
+    }
+    
+    BeforeEach {
+        $script:SavedCaches = $script:PackageCaches
+        $script:SavedConfigPath = $script:Config.ConfigPath
+        $script:Config.ConfigPath = Join-Path $TestDrive "config_$(Get-Random)"
+        New-Item -ItemType Directory -Path $script:Config.ConfigPath -Force | Out-Null
+        Mock Show-MsiPatchCache { }
+    }
+    
+    AfterEach {
+        $script:PackageCaches = $script:SavedCaches
+        $script:Config.ConfigPath = $script:SavedConfigPath
+    }
+    
+    Context "Get-PackageCacheDays" {
+        It "uses the defaults without a config file" {
+            $days = Get-PackageCacheDays
+            $days["nuget-http"] | Should -Be 7
+            $days["maven"] | Should -Be 180
+            $days["msi"] | Should -Be 90
+        }
+        
+        It "takes clean.keepDays from config.json" {
+            $config = '{ "clean": { "keepDays": { "npm": 5, "msi": 0, "maven": "soon", "unknown": 3 } } }'
+            Set-Content -Path (Join-Path $script:Config.ConfigPath "config.json") -Value $config
+            
+            $days = Get-PackageCacheDays
+            
+            $days["npm"] | Should -Be 5
+            $days["msi"] | Should -Be 0
+            $days["maven"] | Should -Be 180
+            $days.ContainsKey("unknown") | Should -Be $false
+        }
+    }
+    
+    Context "Get-PackageCacheItems" {
+        It "lists every file of a File cache with when it was last used" {
+            $root = Join-Path $TestDrive "pip_$(Get-Random)"
+            New-TestFile -Path (Join-Path $root "http\a.bin") -Days 40
+            New-TestFile -Path (Join-Path $root "http\b\c.bin") -Days 2
+            
+            $items = @(Get-PackageCacheItems -Cache @{ Key = "pip"; Unit = "File"; Paths = @($root) })
+            
+            $items.Count | Should -Be 2
+            ($items | Where-Object { $_.Path -like "*a.bin" }).LastUsed | Should -BeLessThan (Get-Date).AddDays(-39)
+            ($items | Where-Object { $_.Path -like "*c.bin" }).LastUsed | Should -BeGreaterThan (Get-Date).AddDays(-3)
+        }
+        
+        It "ages a Folder cache's package versions by their newest file" {
+            $root = Join-Path $TestDrive "nuget_$(Get-Random)"
+            New-TestFile -Path (Join-Path $root "pkg\1.0.0\lib.dll") -Days 200
+            New-TestFile -Path (Join-Path $root "pkg\1.0.0\pkg.nuspec") -Days 10
+            New-TestFile -Path (Join-Path $root "pkg\2.0.0\lib.dll") -Days 120
+            New-TestFile -Path (Join-Path $root "pkg\readme.txt") -Days 300
+            
+            $items = @(Get-PackageCacheItems -Cache @{ Key = "nuget"; Unit = "Folder"; Paths = @("$root\*\*") })
+            
+            $items.Count | Should -Be 2
+            $v1 = $items | Where-Object { $_.Path -like "*1.0.0" }
+            $v1.LastUsed | Should -BeGreaterThan (Get-Date).AddDays(-11)
+            ($items | Where-Object { $_.Path -like "*2.0.0" }).LastUsed | Should -BeLessThan (Get-Date).AddDays(-119)
+        }
+        
+        It "takes the folders holding a Marker file as the package versions" {
+            $root = Join-Path $TestDrive "m2_$(Get-Random)"
+            New-TestFile -Path (Join-Path $root "org\lib\1.0\lib-1.0.pom") -Days 200
+            New-TestFile -Path (Join-Path $root "org\lib\1.0\lib-1.0.jar") -Days 200
+            New-TestFile -Path (Join-Path $root "org\lib\2.0\lib-2.0.pom") -Days 5
+            New-TestFile -Path (Join-Path $root "org\lib\maven-metadata.xml") -Days 1
+            
+            $items = @(Get-PackageCacheItems -Cache @{ Key = "maven"; Unit = "Folder"; Marker = "*.pom"; Paths = @($root) })
+            
+            $items.Count | Should -Be 2
+            ($items | ForEach-Object { Split-Path -Leaf $_.Path } | Sort-Object) | Should -Be @("1.0", "2.0")
+            ($items | Where-Object { $_.Path -like "*1.0" }).LastUsed | Should -BeLessThan (Get-Date).AddDays(-199)
+        }
+    }
+    
+    Context "Clear-PackageCaches" {
+        BeforeEach {
+            $script:cacheRoot = Join-Path $TestDrive "npm_$(Get-Random)"
+            $script:oldFile = Join-Path $script:cacheRoot "old.bin"
+            $script:weekFile = Join-Path $script:cacheRoot "week.bin"
+            $script:newFile = Join-Path $script:cacheRoot "new.bin"
+            New-TestFile -Path $script:oldFile -Days 40
+            New-TestFile -Path $script:weekFile -Days 7
+            New-TestFile -Path $script:newFile
+            $script:PackageCaches = @(
+                @{ Key = "npm"; Name = "npm cache"; Unit = "File"; KeepDays = 30; Paths = @($script:cacheRoot) }
+            )
+        }
+        
+        It "removes only what is older than the cache's retention" {
+            Clear-PackageCaches
+            
+            Test-Path $script:oldFile | Should -Be $false
+            Test-Path $script:weekFile | Should -Be $true
+            Test-Path $script:newFile | Should -Be $true
+        }
+        
+        It "uses the clean.keepDays override" {
+            Set-Content -Path (Join-Path $script:Config.ConfigPath "config.json") -Value '{ "clean": { "keepDays": { "npm": 3 } } }'
+            
+            Clear-PackageCaches
+            
+            Test-Path $script:oldFile | Should -Be $false
+            Test-Path $script:weekFile | Should -Be $false
+            Test-Path $script:newFile | Should -Be $true
+        }
+        
+        It "empties the caches with -KeepDays 0" {
+            Clear-PackageCaches -KeepDays 0
+            
+            Test-Path $script:oldFile | Should -Be $false
+            Test-Path $script:weekFile | Should -Be $false
+            Test-Path $script:newFile | Should -Be $false
+            Should -Invoke Show-MsiPatchCache -ParameterFilter { $KeepDays -eq 0 }
+        }
+    }
+}
+
+Describe "Mail Data - mail.ps1" {
+    
+    BeforeAll {
+        . "$script:LIB_DIR\clean\mail.ps1"
+    }
+    
+    BeforeEach {
+        $script:SavedMailCaches = $script:MailCaches
+        $script:mailDir = Join-Path $TestDrive "mail_$(Get-Random)"
+        New-Item -ItemType Directory -Path $script:mailDir -Force | Out-Null
+        $script:MailCaches = @()
+        Mock Get-Process { }
+        Mock Show-MailDataFiles { }
+    }
+    
+    AfterEach {
+        $script:MailCaches = $script:SavedMailCaches
+    }
+    
+    Context "Format-Age" {
+        It "says today, yesterday or how many days ago" {
+            Format-Age (Get-Date) | Should -Be "today"
+            Format-Age (Get-Date).AddDays(-1) | Should -Be "yesterday"
+            Format-Age (Get-Date).AddDays(-12) | Should -Be "12 days ago"
+        }
+    }
+    
+    Context "Get-MailDataFiles" {
+        It "finds the files a profile points to with their profile" {
+            $script:ost = Join-Path $script:mailDir "work.ost"
+            Set-Content -Path $script:ost -Value "mailbox"
+            Mock Get-OutlookProfileFiles { @{ ($script:ost.ToLower()) = "Work" } }
+            
+            $file = Get-MailDataFiles | Where-Object { $_.Path -eq $script:ost }
+            
+            $file.Kind | Should -Be "OST"
+            $file.Profile | Should -Be "Work"
+        }
+    }
+    
+    Context "Invoke-MailCleanup" {
+        BeforeEach {
+            $script:files = foreach ($f in @(
+                    @{ Name = "orphan.ost"; Profile = $null; Days = 60 }
+                    @{ Name = "recent.ost"; Profile = $null; Days = 5 }
+                    @{ Name = "used.ost"; Profile = "Work"; Days = 60 }
+                    @{ Name = "archive.pst"; Profile = $null; Days = 400 }
+                )) {
+                $path = Join-Path $script:mailDir $f.Name
+                Set-Content -Path $path -Value "mail"
+                [pscustomobject]@{
+                    Path      = $path
+                    Name      = $f.Name
+                    Kind      = [System.IO.Path]::GetExtension($f.Name).TrimStart(".").ToUpper()
+                    Size      = 4L
+                    LastWrite = (Get-Date).AddDays(-$f.Days)
+                    Profile   = $f.Profile
+                }
+            }
+            Mock Get-MailDataFiles { $script:files }
+        }
+        
+        It "removes only OST files no profile uses that are old" {
+            Invoke-MailCleanup
+            
+            Test-Path (Join-Path $script:mailDir "orphan.ost") | Should -Be $false
+            Test-Path (Join-Path $script:mailDir "recent.ost") | Should -Be $true
+            Test-Path (Join-Path $script:mailDir "used.ost") | Should -Be $true
+            Test-Path (Join-Path $script:mailDir "archive.pst") | Should -Be $true
+        }
+        
+        It "keeps every data file while Outlook runs" {
+            Mock Get-Process { [pscustomobject]@{ Name = "OUTLOOK" } } -ParameterFilter { $Name -eq "OUTLOOK" }
+            
+            Invoke-MailCleanup
+            
+            Test-Path (Join-Path $script:mailDir "orphan.ost") | Should -Be $true
+        }
+        
+        It "clears a cache only while its app is closed" {
+            $closed = Join-Path $script:mailDir "olk"
+            $running = Join-Path $script:mailDir "teams"
+            New-Item -ItemType Directory -Path $closed, $running -Force | Out-Null
+            Set-Content -Path (Join-Path $closed "cache.bin") -Value "cache"
+            Set-Content -Path (Join-Path $running "cache.bin") -Value "cache"
+            $script:MailCaches = @(
+                @{ Name = "New Outlook"; Path = $closed; Process = "olk" }
+                @{ Name = "Teams"; Path = $running; Process = "ms-teams" }
+            )
+            Mock Get-Process { [pscustomobject]@{ Name = "ms-teams" } } -ParameterFilter { $Name -eq "ms-teams" }
+            
+            Invoke-MailCleanup
+            
+            Test-Path (Join-Path $closed "cache.bin") | Should -Be $false
+            Test-Path $closed | Should -Be $true
+            Test-Path (Join-Path $running "cache.bin") | Should -Be $true
+        }
+    }
+}
+
 # ============================================================================
 # Script Validation Tests
 # ============================================================================
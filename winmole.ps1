@@ -62,7 +62,10 @@ function Show-MainHelp {
     Write-Host "    ${cyan}analyze${nc}     Visual disk space analyzer"
     Write-Host "    ${cyan}status${nc}      Real-time system monitor"
     Write-Host "    ${cyan}optimize${nc}    System optimization tasks"
+    Write-Host "    ${cyan}doctor${nc}      Find what slows the PC down, with one-key fixes"
     Write-Host "    ${cyan}purge${nc}       Clean project build artifacts"
+    Write-Host "    ${cyan}stats${nc}       Space reclaimed and most-used features (local only)"
+    Write-Host "    ${cyan}server${nc}      Collect scans and metrics pushed by other PCs"
     Write-Host ""
     Write-Host "  ${green}OPTIONS:${nc}"
     Write-Host ""
@@ -78,7 +81,10 @@ function Show-MainHelp {
     Write-Host "    ${gray}winmole analyze${nc}          ${gray}# Disk analyzer${nc}"
     Write-Host "    ${gray}winmole status${nc}           ${gray}# System monitor${nc}"
     Write-Host "    ${gray}winmole optimize${nc}         ${gray}# Optimize system${nc}"
+    Write-Host "    ${gray}winmole doctor${nc}           ${gray}# Guided troubleshooting${nc}"
     Write-Host "    ${gray}winmole purge${nc}            ${gray}# Clean dev artifacts${nc}"
+    Write-Host "    ${gray}winmole stats${nc}            ${gray}# Usage statistics${nc}"
+    Write-Host "    ${gray}winmole server${nc}           ${gray}# Fleet server${nc}"
     Write-Host ""
     Write-Host "  ${green}ENVIRONMENT:${nc}"
     Write-Host ""
@@ -125,13 +131,26 @@ function Show-MainMenu {
             Command = "optimize"
             Icon = $script:Icons.Arrow
         }
+        @{ 
+            Name = "Doctor" 
+            Description = "Guided troubleshooting" 
+            Command = "doctor"
+            Icon = $script:Icons.Warning
+        }
         @{ 
             Name = "Purge" 
             Description = "Clean dev artifacts" 
             Command = "purge"
             Icon = $script:Icons.List
         }
+        @{ 
+            Name = "Stats" 
+            Description = "Space reclaimed so far" 
+            Command = "stats"
+            Icon = $script:Icons.Success
+        }
     )
+    $options = @($options | Where-Object { -not (Test-CommandDisabled -Name $_.Command) })
     
     $selected = Show-Menu -Title "What would you like to do?" -Options $options -AllowBack
     
@@ -152,6 +171,11 @@ function Invoke-Command {
         [string[]]$Arguments
     )
     
+    if (Test-CommandDisabled -Name $CommandName) {
+        Write-Error "The $CommandName command is disabled by your administrator (Group Policy)"
+        return
+    }
+    
     $scriptPath = Join-Path $script:WINMOLE_BIN "$CommandName.ps1"
     
     if (-not (Test-Path $scriptPath)) {
@@ -161,8 +185,17 @@ function Invoke-Command {
         return
     }
     
+    # Local usage statistics only; see lib\core\usage.ps1
+    Add-UsageRecord -Feature $CommandName
+    
     # Execute the command script with arguments
+    $global:LASTEXITCODE = 0
     & $scriptPath @Arguments
+    
+    # Headless runs that were cut short report it to the calling script
+    if ($LASTEXITCODE -in 124, 130) {
+        exit $LASTEXITCODE
+    }
 }
 
 # ============================================================================
@@ -183,6 +216,15 @@ function Show-SystemInfo {
     Write-Host "  ${gray}System:${nc} $($winInfo.Name)"
     Write-Host "  ${gray}Free Space:${nc} $freeSpace on $($env:SystemDrive)"
     Write-Host "  ${gray}Admin:${nc} $isAdmin"
+    if ($script:WINMOLE_PORTABLE_ROOT) {
+        Write-Host "  ${gray}Portable:${nc} settings in $($script:WINMOLE_PORTABLE_ROOT)"
+    }
+    if ($script:Policy.Managed) {
+        Write-Host "  ${gray}Managed:${nc} settings enforced by Group Policy"
+        if ($script:Policy.AgentEndpoint) {
+            Write-Host "  ${gray}Agent:${nc} $($script:Policy.AgentEndpoint)"
+        }
+    }
     Write-Host ""
 }
 
@@ -208,7 +250,7 @@ function Main {
     
     # If command specified, route to it
     if ($Command) {
-        $validCommands = @("clean", "uninstall", "analyze", "status", "optimize", "purge")
+        $validCommands = @("clean", "uninstall", "analyze", "status", "optimize", "doctor", "purge", "stats", "server")
         
         if ($Command -in $validCommands) {
             Invoke-Command -CommandName $Command -Arguments $CommandArgs